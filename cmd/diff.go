@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"assets_discovery/internal/assets"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <before.json> <after.json>",
+	Short: "比较两份资产清单",
+	Long: `比较两份资产清单文件（assets.json或快照），按资产ID匹配，
+输出新增资产、移除资产，以及端口、操作系统、活跃状态等字段的变化。`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		if format != "text" && format != "json" {
+			fmt.Printf("不支持的输出格式: %s\n", format)
+			os.Exit(1)
+		}
+
+		before, err := loadInventory(args[0])
+		if err != nil {
+			fmt.Printf("读取资产清单失败 %s: %v\n", args[0], err)
+			os.Exit(1)
+		}
+		after, err := loadInventory(args[1])
+		if err != nil {
+			fmt.Printf("读取资产清单失败 %s: %v\n", args[1], err)
+			os.Exit(1)
+		}
+
+		diff := assets.DiffInventories(before, after)
+
+		if format == "json" {
+			data, err := json.MarshalIndent(diff, "", "  ")
+			if err != nil {
+				fmt.Printf("序列化差异失败: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(data))
+			return
+		}
+
+		printDiff(diff)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().String("format", "text", "输出格式: text, json")
+}
+
+// loadInventory 读取资产清单，返回以资产ID为键的资产
+func loadInventory(path string) (map[string]*assets.Asset, error) {
+	records, _, err := loadImportRecords(path)
+	if err != nil {
+		return nil, err
+	}
+
+	inventory := make(map[string]*assets.Asset, len(records))
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+
+		asset := &assets.Asset{}
+		if err := json.Unmarshal(data, asset); err != nil {
+			return nil, fmt.Errorf("解析资产失败: %v", err)
+		}
+		inventory[asset.ID] = asset
+	}
+
+	return inventory, nil
+}
+
+// printDiff 以文本形式输出差异
+func printDiff(diff *assets.InventoryDiff) {
+	if diff.IsEmpty() {
+		fmt.Println("两份资产清单没有差异")
+		return
+	}
+
+	fmt.Printf("新增资产 (%d):\n", len(diff.Added))
+	for _, asset := range diff.Added {
+		fmt.Printf("  + %s\n", describeAsset(asset))
+	}
+
+	fmt.Printf("移除资产 (%d):\n", len(diff.Removed))
+	for _, asset := range diff.Removed {
+		fmt.Printf("  - %s\n", describeAsset(asset))
+	}
+
+	fmt.Printf("变更资产 (%d):\n", len(diff.Changed))
+	for _, change := range diff.Changed {
+		fmt.Printf("  * %s (%s)\n", change.ID, change.IPAddress)
+		for _, fc := range change.Changes {
+			oldList, isList := fc.OldValue.([]string)
+			if isList {
+				added, removed := listDelta(oldList, fc.NewValue.([]string))
+				fmt.Printf("      %s: 新增 [%s] 移除 [%s]\n", fc.Field,
+					strings.Join(added, ", "), strings.Join(removed, ", "))
+				continue
+			}
+			fmt.Printf("      %s: %v -> %v\n", fc.Field, fc.OldValue, fc.NewValue)
+		}
+	}
+}
+
+// describeAsset 资产的单行描述
+func describeAsset(asset *assets.Asset) string {
+	desc := fmt.Sprintf("%s  %s", asset.ID, asset.IPAddress)
+	if asset.Hostname != "" {
+		desc += "  " + asset.Hostname
+	}
+	if asset.DeviceType != "" {
+		desc += "  " + asset.DeviceType
+	}
+	return desc
+}
+
+// listDelta 计算两个列表之间新增和移除的元素
+func listDelta(oldList, newList []string) ([]string, []string) {
+	oldSet := make(map[string]bool, len(oldList))
+	for _, v := range oldList {
+		oldSet[v] = true
+	}
+	newSet := make(map[string]bool, len(newList))
+	for _, v := range newList {
+		newSet[v] = true
+	}
+
+	var added, removed []string
+	for _, v := range newList {
+		if !oldSet[v] {
+			added = append(added, v)
+		}
+	}
+	for _, v := range oldList {
+		if !newSet[v] {
+			removed = append(removed, v)
+		}
+	}
+	return added, removed
+}
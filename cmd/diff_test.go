@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"assets_discovery/internal/assets"
+)
+
+// changedFields 返回每个变更资产的字段名列表
+func changedFields(diff *assets.InventoryDiff) map[string][]string {
+	fields := make(map[string][]string, len(diff.Changed))
+	for _, change := range diff.Changed {
+		for _, fc := range change.Changes {
+			fields[change.ID] = append(fields[change.ID], fc.Field)
+		}
+	}
+	return fields
+}
+
+// fieldChange 查找资产的指定字段变化
+func fieldChange(diff *assets.InventoryDiff, id, field string) (assets.FieldChange, bool) {
+	for _, change := range diff.Changed {
+		if change.ID != id {
+			continue
+		}
+		for _, fc := range change.Changes {
+			if fc.Field == field {
+				return fc, true
+			}
+		}
+	}
+	return assets.FieldChange{}, false
+}
+
+func TestDiffInventoryFiles(t *testing.T) {
+	// before.json为assets.json的对象格式，after.json为数组格式
+	before, err := loadInventory(filepath.Join("testdata", "diff", "before.json"))
+	if err != nil {
+		t.Fatalf("loadInventory(before) error = %v", err)
+	}
+	after, err := loadInventory(filepath.Join("testdata", "diff", "after.json"))
+	if err != nil {
+		t.Fatalf("loadInventory(after) error = %v", err)
+	}
+
+	diff := assets.DiffInventories(before, after)
+	fields := changedFields(diff)
+
+	var added, removed []string
+	for _, asset := range diff.Added {
+		added = append(added, asset.ID)
+	}
+	for _, asset := range diff.Removed {
+		removed = append(removed, asset.ID)
+	}
+	if want := []string{"mac_00:1a:2b:00:00:05"}; !reflect.DeepEqual(added, want) {
+		t.Errorf("added = %v, want %v", added, want)
+	}
+	if want := []string{"mac_00:1a:2b:00:00:03"}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed = %v, want %v", removed, want)
+	}
+
+	tests := []struct {
+		name       string
+		id         string
+		wantFields []string
+	}{
+		{"os upgrade and ports", "mac_00:1a:2b:00:00:01", []string{"os_version", "open_ports"}},
+		{"moved and went inactive", "mac_00:1a:2b:00:00:02", []string{"ip_address", "is_active", "services"}},
+		{"unchanged", "mac_00:1a:2b:00:00:04", nil},
+		{"added asset not in changed", "mac_00:1a:2b:00:00:05", nil},
+		{"removed asset not in changed", "mac_00:1a:2b:00:00:03", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fields[tt.id]; !reflect.DeepEqual(got, tt.wantFields) {
+				t.Errorf("changed fields of %s = %v, want %v", tt.id, got, tt.wantFields)
+			}
+		})
+	}
+
+	// 关闭的22端口视为移除，443为新增
+	fc, ok := fieldChange(diff, "mac_00:1a:2b:00:00:01", "open_ports")
+	if !ok {
+		t.Fatalf("open_ports change missing")
+	}
+	portsAdded, portsRemoved := listDelta(fc.OldValue.([]string), fc.NewValue.([]string))
+	if !reflect.DeepEqual(portsAdded, []string{"443/tcp"}) || !reflect.DeepEqual(portsRemoved, []string{"22/tcp"}) {
+		t.Errorf("port delta = +%v -%v, want +[443/tcp] -[22/tcp]", portsAdded, portsRemoved)
+	}
+
+	if fc, _ := fieldChange(diff, "mac_00:1a:2b:00:00:02", "services"); !reflect.DeepEqual(fc.NewValue, []string{"dns:53 outbound"}) {
+		t.Errorf("services = %v, want [dns:53 outbound]", fc.NewValue)
+	}
+}
+
+func TestListDelta(t *testing.T) {
+	tests := []struct {
+		name        string
+		old, new    []string
+		wantAdded   []string
+		wantRemoved []string
+	}{
+		{"identical", []string{"22/tcp", "80/tcp"}, []string{"22/tcp", "80/tcp"}, nil, nil},
+		{"added only", []string{"22/tcp"}, []string{"22/tcp", "443/tcp"}, []string{"443/tcp"}, nil},
+		{"removed only", []string{"22/tcp", "80/tcp"}, []string{"80/tcp"}, nil, []string{"22/tcp"}},
+		{"replaced", []string{"53/udp"}, []string{"53/tcp"}, []string{"53/tcp"}, []string{"53/udp"}},
+		{"from empty", nil, []string{"ssh:22"}, []string{"ssh:22"}, nil},
+		{"to empty", []string{"ssh:22"}, []string{}, nil, []string{"ssh:22"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed := listDelta(tt.old, tt.new)
+			if !reflect.DeepEqual(added, tt.wantAdded) || !reflect.DeepEqual(removed, tt.wantRemoved) {
+				t.Errorf("listDelta() = +%v -%v, want +%v -%v", added, removed, tt.wantAdded, tt.wantRemoved)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"assets_discovery/internal/config"
+	"assets_discovery/internal/storage"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "导出存储中的全部资产",
+	Long: `从配置的存储中逐个读取资产并写出为JSON数组、NDJSON（每行一个资产）、CSV或STIX 2.1 bundle。
+
+--flatten 将os_info、open_ports等嵌套结构展开为os_family、port_22_tcp这样的扁平字段，
+CSV格式总是展开。展开后的文件不能再用于import和diff命令。
+
+资产逐个序列化写出，Elasticsearch存储按页滚动读取，导出大量资产时内存占用保持平稳。
+导出的文件可直接用于import和diff命令（NDJSON和STIX除外）。
+
+--format stix 输出STIX 2.1网络可观测对象：IP地址为ipv4-addr/ipv6-addr，MAC地址为mac-addr，
+主机名为domain-name，开放端口为network-traffic，供威胁情报平台和SIEM导入。`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := config.GetConfig()
+
+		output, _ := cmd.Flags().GetString("output")
+		format, _ := cmd.Flags().GetString("format")
+
+		if cmd.Flags().Changed("flatten") {
+			cfg.Export.Flatten, _ = cmd.Flags().GetBool("flatten")
+		}
+
+		stor, err := storage.NewStorage(&cfg.Storage)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "初始化存储失败: %v\n", err)
+			os.Exit(1)
+		}
+		defer stor.Close()
+
+		var out io.Writer = os.Stdout
+		if output != "" && output != "-" {
+			f, err := os.Create(output)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "创建输出文件失败: %v\n", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			out = f
+		}
+
+		var flatten *storage.Flattener
+		if (cfg.Export.Flatten || format == "csv") && format != "stix" {
+			flatten = storage.NewFlattener(&cfg.Export)
+		}
+
+		count, err := exportAssets(stor, out, format, flatten)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "导出失败（已写出 %d 个资产）: %v\n", count, err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "导出完成: %d 个资产\n", count)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringP("output", "o", "", "输出文件路径，为空或\"-\"时输出到标准输出")
+	exportCmd.Flags().String("format", "json", "输出格式: json, ndjson, csv, stix")
+	exportCmd.Flags().Bool("flatten", false, "将嵌套结构展开为扁平字段，字段选择和重命名见配置export，csv格式总是展开")
+}
+
+// exportAssets 将存储中的资产逐个写入out，flatten不为nil时展开为扁平字段，返回写出的资产数量
+func exportAssets(stor storage.Storage, out io.Writer, format string, flatten *storage.Flattener) (int, error) {
+	buffered := bufio.NewWriter(out)
+	aw, err := storage.NewAssetWriter(buffered, format, flatten)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := storage.EachAsset(stor, aw.Write); err != nil {
+		return aw.Count(), err
+	}
+	if err := aw.Close(); err != nil {
+		return aw.Count(), err
+	}
+	return aw.Count(), buffered.Flush()
+}
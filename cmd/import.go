@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"assets_discovery/internal/config"
+	"assets_discovery/internal/storage"
+)
+
+// importBatchSize 批量写入时每批的资产数量
+const importBatchSize = 500
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "导入资产数据到配置的存储",
+	Long: `读取assets.json或快照文件，将其中的资产写入配置的存储中。
+
+可用于从文件存储迁移到Elasticsearch，无需重新捕获流量。`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := config.GetConfig()
+
+		source, _ := cmd.Flags().GetString("source")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		records, skipped, err := loadImportRecords(source)
+		if err != nil {
+			fmt.Printf("读取导入文件失败: %v\n", err)
+			os.Exit(1)
+		}
+
+		if dryRun {
+			fmt.Printf("试运行: 可导入 %d 个资产，跳过 %d 条无效记录\n", len(records), skipped)
+			return
+		}
+
+		stor, err := storage.NewStorage(&cfg.Storage)
+		if err != nil {
+			fmt.Printf("初始化存储失败: %v\n", err)
+			os.Exit(1)
+		}
+		defer stor.Close()
+
+		imported, failed := importRecords(stor, records)
+		fmt.Printf("导入完成: 成功 %d 个，失败 %d 个，跳过 %d 条无效记录\n", imported, failed, skipped)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringP("source", "s", "", "资产文件路径 (例如: ./output/assets.json)")
+	importCmd.Flags().Bool("dry-run", false, "只校验文件内容，不写入存储")
+	importCmd.MarkFlagRequired("source")
+}
+
+// loadImportRecords 读取资产文件，支持以ID为键的对象或资产数组
+func loadImportRecords(path string) ([]interface{}, int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var raws []json.RawMessage
+	var byID map[string]json.RawMessage
+	if err := json.Unmarshal(data, &byID); err == nil {
+		for _, raw := range byID {
+			raws = append(raws, raw)
+		}
+	} else if err := json.Unmarshal(data, &raws); err != nil {
+		return nil, 0, fmt.Errorf("无法识别的文件格式: %v", err)
+	}
+
+	records := make([]interface{}, 0, len(raws))
+	skipped := 0
+	for i, raw := range raws {
+		var record map[string]interface{}
+		if err := json.Unmarshal(raw, &record); err != nil {
+			log.Printf("警告: 跳过第 %d 条无效记录: %v", i+1, err)
+			skipped++
+			continue
+		}
+		if id, _ := record["id"].(string); id == "" {
+			log.Printf("警告: 跳过第 %d 条缺少ID的记录", i+1)
+			skipped++
+			continue
+		}
+		records = append(records, record)
+	}
+
+	return records, skipped, nil
+}
+
+// importRecords 将记录写入存储，支持批量写入时优先使用批量接口
+func importRecords(stor storage.Storage, records []interface{}) (int, int) {
+	imported := 0
+
+	if bulk, ok := stor.(storage.BulkStorage); ok {
+		for start := 0; start < len(records); start += importBatchSize {
+			end := start + importBatchSize
+			if end > len(records) {
+				end = len(records)
+			}
+
+			saved, err := bulk.SaveAssets(records[start:end])
+			if err != nil {
+				log.Printf("批量写入失败: %v", err)
+			}
+			imported += saved
+		}
+
+		return imported, len(records) - imported
+	}
+
+	for _, record := range records {
+		if err := stor.SaveAsset(record); err != nil {
+			log.Printf("保存资产失败: %v", err)
+			continue
+		}
+		imported++
+	}
+
+	return imported, len(records) - imported
+}
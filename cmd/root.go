@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -56,6 +60,38 @@ func initConfig() {
 	}
 }
 
+// signalContext 返回收到Ctrl+C或SIGTERM时取消的上下文，使捕获正常停止并保存资产
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// reloadOnHangup 收到SIGHUP时重新读取配置文件并加载告警的已知资产列表，ctx取消时退出
+func reloadOnHangup(ctx context.Context, ce *capture.CaptureEngine) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+
+		if cfgFile == "" {
+			log.Printf("收到SIGHUP，但未指定配置文件，无需重新加载")
+			continue
+		}
+		if err := viper.ReadInConfig(); err != nil {
+			log.Printf("重新读取配置文件失败: %v", err)
+			continue
+		}
+		if err := ce.ReloadKnownAssets(viper.GetStringSlice("alerting.known_assets")); err != nil {
+			log.Printf("重新加载已知资产列表: %v", err)
+		}
+	}
+}
+
 // liveCmd represents the live command
 var liveCmd = &cobra.Command{
 	Use:   "live",
@@ -69,8 +105,33 @@ var liveCmd = &cobra.Command{
 			cfg.Capture.Interface = iface
 		}
 
+		if cmd.Flags().Changed("duration") {
+			cfg.Capture.Duration, _ = cmd.Flags().GetDuration("duration")
+		}
+
+		if cmd.Flags().Changed("promiscuous") {
+			cfg.Capture.Promiscuous, _ = cmd.Flags().GetBool("promiscuous")
+		}
+
+		if cmd.Flags().Changed("vlan") {
+			cfg.Capture.VLAN, _ = cmd.Flags().GetBool("vlan")
+		}
+
+		if cmd.Flags().Changed("no-store") {
+			cfg.Storage.NoStore, _ = cmd.Flags().GetBool("no-store")
+		}
+
+		if cmd.Flags().Changed("debug-dump") {
+			cfg.Parser.DebugDump, _ = cmd.Flags().GetString("debug-dump")
+		}
+
 		captureEngine := capture.NewCaptureEngine(cfg)
-		if err := captureEngine.StartLiveCapture(); err != nil {
+		ctx, stop := signalContext()
+		defer stop()
+
+		go reloadOnHangup(ctx, captureEngine)
+
+		if err := captureEngine.StartLiveCapture(ctx); err != nil {
 			fmt.Printf("启动实时捕获失败: %v\n", err)
 			os.Exit(1)
 		}
@@ -81,7 +142,10 @@ var liveCmd = &cobra.Command{
 var offlineCmd = &cobra.Command{
 	Use:   "offline",
 	Short: "离线分析pcap文件",
-	Long:  `分析离线的pcap文件，识别其中的网络资产`,
+	Long: `分析离线的pcap文件，识别其中的网络资产
+
+使用 -f - 从标准输入读取pcap/pcapng数据流，例如:
+  tcpdump -i eth0 -w - | assets_discovery offline -f -`,
 	Run: func(cmd *cobra.Command, args []string) {
 		cfg := config.GetConfig()
 
@@ -91,8 +155,31 @@ var offlineCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		if cmd.Flags().Changed("promiscuous") {
+			fmt.Fprintln(os.Stderr, "警告: 离线分析不涉及网络接口，--promiscuous 参数将被忽略")
+		}
+
+		if cmd.Flags().Changed("no-store") {
+			cfg.Storage.NoStore, _ = cmd.Flags().GetBool("no-store")
+		}
+
+		if cmd.Flags().Changed("debug-dump") {
+			cfg.Parser.DebugDump, _ = cmd.Flags().GetString("debug-dump")
+		}
+
+		// 只输出计数时不写入存储，也不启动API服务
+		countOnly, _ := cmd.Flags().GetBool("count-only")
+		if countOnly {
+			cfg.Storage.NoStore = true
+			cfg.Server.Enabled = false
+		}
+
 		captureEngine := capture.NewCaptureEngine(cfg)
-		if err := captureEngine.StartOfflineCapture(pcapFile); err != nil {
+		captureEngine.SetCountOnly(countOnly)
+		ctx, stop := signalContext()
+		defer stop()
+
+		if err := captureEngine.StartOfflineCapture(ctx, pcapFile); err != nil {
 			fmt.Printf("离线分析失败: %v\n", err)
 			os.Exit(1)
 		}
@@ -102,8 +189,17 @@ var offlineCmd = &cobra.Command{
 func init() {
 	// live命令标志
 	liveCmd.Flags().StringP("interface", "i", "", "网络接口名称 (例如: eth0)")
+	liveCmd.Flags().DurationP("duration", "d", 0, "捕获时长 (例如: 10m)，0表示持续运行")
+	liveCmd.Flags().Bool("promiscuous", true, "是否开启混杂模式，部分网卡/虚拟机需设置为 --promiscuous=false")
+	liveCmd.Flags().Bool("vlan", false, "trunk端口上的流量带VLAN标签时使用，BPF过滤器同时匹配带标签和不带标签的数据包")
+	liveCmd.Flags().Bool("no-store", false, "只分析不保存，结束时输出资产汇总")
+	liveCmd.Flags().String("debug-dump", "", "将解析失败的数据包写入指定的pcap文件，用于排查协议未被识别的问题")
 
 	// offline命令标志
-	offlineCmd.Flags().StringP("file", "f", "", "pcap文件路径")
+	offlineCmd.Flags().StringP("file", "f", "", "pcap文件路径，\"-\" 表示从标准输入读取")
+	offlineCmd.Flags().Bool("promiscuous", true, "离线模式下无效，仅为与live命令保持一致")
+	offlineCmd.Flags().Bool("no-store", false, "只分析不保存，结束时输出资产汇总")
+	offlineCmd.Flags().String("debug-dump", "", "将解析失败的数据包写入指定的pcap文件，用于排查协议未被识别的问题")
+	offlineCmd.Flags().Bool("count-only", false, "只输出IP、MAC、设备类型、服务和协议的计数汇总，不保存资产")
 	offlineCmd.MarkFlagRequired("file")
 }
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"assets_discovery/internal/capture"
+	"assets_discovery/internal/config"
+)
+
+// simulateCmd represents the simulate command
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "生成模拟流量，用于在没有真实流量时测试",
+	Long: `按指定速率生成模拟资产的ARP、DHCP和TCP服务数据包，经解析器和资产管理器写入配置的存储，
+结束后输出处理速率和资产统计。可用于压测存储和API。
+
+模拟资产的地址位于10.0.0.0/8，主机名为sim-host-NNNNNN，请勿写入生产环境的存储。`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := config.GetConfig()
+
+		var opts capture.SimulateOptions
+		opts.Assets, _ = cmd.Flags().GetInt("assets")
+		opts.Rate, _ = cmd.Flags().GetInt("rate")
+		opts.Count, _ = cmd.Flags().GetInt("count")
+
+		if cmd.Flags().Changed("duration") {
+			cfg.Capture.Duration, _ = cmd.Flags().GetDuration("duration")
+		}
+
+		if cmd.Flags().Changed("no-store") {
+			cfg.Storage.NoStore, _ = cmd.Flags().GetBool("no-store")
+		}
+
+		captureEngine := capture.NewCaptureEngine(cfg)
+		ctx, stop := signalContext()
+		defer stop()
+
+		if err := captureEngine.StartSimulation(ctx, opts); err != nil {
+			fmt.Printf("模拟运行失败: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(simulateCmd)
+
+	simulateCmd.Flags().Int("assets", 100, "模拟的资产数量")
+	simulateCmd.Flags().Int("rate", 1000, "每秒生成的数据包数，0表示不限速")
+	simulateCmd.Flags().Int("count", 0, "生成的数据包总数，0表示持续到时长结束或按Ctrl+C停止")
+	simulateCmd.Flags().DurationP("duration", "d", 0, "模拟时长 (例如: 1m)，0表示持续运行")
+	simulateCmd.Flags().Bool("no-store", false, "只分析不保存，用于单独测试解析和资产管理的性能")
+}
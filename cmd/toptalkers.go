@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"assets_discovery/internal/assets"
+	"assets_discovery/internal/config"
+	"assets_discovery/internal/storage"
+)
+
+// topTalkersCmd represents the top-talkers command
+var topTalkersCmd = &cobra.Command{
+	Use:   "top-talkers",
+	Short: "按流量排名存储中的资产",
+	Long: `读取配置的存储中的资产，按资产作为源地址发出的字节数或数据包数排名。
+
+排名使用资产保存的累计计数，覆盖资产被观测到的整个捕获期间，不重新扫描数据包；
+按时间窗口排名请在运行中使用 /api/top-talkers?window=15m。启用抽样时计数只包含抽样保留的数据包。`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := config.GetConfig()
+
+		top, _ := cmd.Flags().GetInt("top")
+		by, _ := cmd.Flags().GetString("by")
+		format, _ := cmd.Flags().GetString("format")
+
+		if err := assets.ValidRankBy(by); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if format != "text" && format != "json" {
+			fmt.Fprintf(os.Stderr, "不支持的输出格式: %s\n", format)
+			os.Exit(1)
+		}
+
+		stor, err := storage.NewStorage(&cfg.Storage)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "初始化存储失败: %v\n", err)
+			os.Exit(1)
+		}
+		defer stor.Close()
+
+		talkers, err := loadTalkers(stor)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "读取资产失败: %v\n", err)
+			os.Exit(1)
+		}
+		talkers = assets.RankTalkers(talkers, top, by)
+
+		if format == "json" {
+			data, err := json.MarshalIndent(talkers, "", "  ")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "序列化排名失败: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(data))
+			return
+		}
+
+		printTalkers(talkers)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(topTalkersCmd)
+
+	topTalkersCmd.Flags().IntP("top", "n", 10, "输出的资产数量，0表示全部")
+	topTalkersCmd.Flags().String("by", assets.RankByBytes, "排名依据: bytes, packets")
+	topTalkersCmd.Flags().String("format", "text", "输出格式: text, json")
+}
+
+// loadTalkers 逐个读取存储中的资产，只保留有流量计数的资产
+func loadTalkers(stor storage.Storage) ([]assets.TopTalker, error) {
+	var talkers []assets.TopTalker
+	err := storage.EachAsset(stor, func(record interface{}) error {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		var talker assets.TopTalker
+		if err := json.Unmarshal(data, &talker); err != nil {
+			return fmt.Errorf("解析资产失败: %v", err)
+		}
+		// 存储中的资产ID字段为id
+		var id struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(data, &id); err != nil {
+			return fmt.Errorf("解析资产失败: %v", err)
+		}
+		talker.AssetID = id.ID
+
+		if talker.Packets > 0 {
+			talkers = append(talkers, talker)
+		}
+		return nil
+	})
+	return talkers, err
+}
+
+// printTalkers 以表格输出流量排名
+func printTalkers(talkers []assets.TopTalker) {
+	if len(talkers) == 0 {
+		fmt.Println("存储中的资产没有流量计数")
+		return
+	}
+
+	fmt.Printf("%-4s %-40s %-39s %-17s %12s %14s  %s\n", "排名", "资产ID", "IP地址", "MAC地址", "数据包", "字节", "主机名")
+	for i, t := range talkers {
+		fmt.Printf("%-4d %-40s %-39s %-17s %12d %14s  %s\n", i+1, t.AssetID, t.IPAddress, t.MACAddress, t.Packets, formatBytes(t.Bytes), t.Hostname)
+	}
+}
+
+// formatBytes 以1024为进制输出可读的字节数
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"assets_discovery/internal/assets"
+	"assets_discovery/internal/storage"
+)
+
+func TestLoadTalkersRanking(t *testing.T) {
+	stor := storage.NewMemoryStorage()
+	for _, asset := range []map[string]interface{}{
+		{"id": "mac_00:1a:2b:3c:4d:01", "ip_address": "10.0.0.1", "packets": 2, "bytes": 3000},
+		{"id": "mac_00:1a:2b:3c:4d:02", "ip_address": "10.0.0.2", "packets": 20, "bytes": 1280},
+		{"id": "mac_00:1a:2b:3c:4d:03", "ip_address": "10.0.0.3", "packets": 1, "bytes": 2000},
+		// 早期版本保存的资产没有流量计数，不参与排名
+		{"id": "mac_00:1a:2b:3c:4d:04", "ip_address": "10.0.0.4"},
+	} {
+		if err := stor.SaveAsset(asset); err != nil {
+			t.Fatalf("SaveAsset() error = %v", err)
+		}
+	}
+
+	talkers, err := loadTalkers(stor)
+	if err != nil {
+		t.Fatalf("loadTalkers() error = %v", err)
+	}
+
+	tests := []struct {
+		by   string
+		n    int
+		want []string
+	}{
+		{assets.RankByBytes, 0, []string{"mac_00:1a:2b:3c:4d:01", "mac_00:1a:2b:3c:4d:03", "mac_00:1a:2b:3c:4d:02"}},
+		{assets.RankByPackets, 0, []string{"mac_00:1a:2b:3c:4d:02", "mac_00:1a:2b:3c:4d:01", "mac_00:1a:2b:3c:4d:03"}},
+		{assets.RankByBytes, 1, []string{"mac_00:1a:2b:3c:4d:01"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			ranked := assets.RankTalkers(append([]assets.TopTalker(nil), talkers...), tt.n, tt.by)
+			var got []string
+			for _, talker := range ranked {
+				got = append(got, talker.AssetID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RankTalkers(%s, %d) = %v, want %v", tt.by, tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    uint64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 << 20, "5.0 MiB"},
+		{3 << 40, "3.0 TiB"},
+	}
+
+	for _, tt := range tests {
+		if got := formatBytes(tt.n); got != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"assets_discovery/internal/config"
+)
+
+// 告警事件类型
+const (
+	EventNewAsset      = "new_asset"
+	EventRuleMatch     = "rule_match"
+	EventIPMACConflict = "ip_mac_conflict"
+	EventMACChange     = "mac_change"
+	EventFinding       = "security_finding"
+)
+
+// Event 告警事件
+type Event struct {
+	Type        string    `json:"type"`
+	Title       string    `json:"title"`
+	AssetID     string    `json:"asset_id"`
+	IPAddress   string    `json:"ip_address"`
+	MACAddress  string    `json:"mac_address"`
+	DeviceType  string    `json:"device_type"`
+	FirstSeen   time.Time `json:"first_seen"`
+	Description string    `json:"description,omitempty"`
+}
+
+// Notifier 告警通知渠道
+type Notifier interface {
+	// 渠道名称
+	Name() string
+
+	// 发送告警
+	Send(event *Event) error
+}
+
+// Dispatcher 将告警分发到所有已配置的渠道
+type Dispatcher struct {
+	notifiers []Notifier
+}
+
+// NewDispatcher 根据配置创建告警分发器
+func NewDispatcher(cfg *config.AlertingConfig) *Dispatcher {
+	client := &http.Client{Timeout: 10 * time.Second}
+	d := &Dispatcher{}
+
+	if cfg.WebhookURL != "" {
+		d.notifiers = append(d.notifiers, &WebhookNotifier{url: cfg.WebhookURL, client: client})
+	}
+	if cfg.SlackWebhookURL != "" {
+		d.notifiers = append(d.notifiers, &SlackNotifier{url: cfg.SlackWebhookURL, client: client})
+	}
+	if cfg.TeamsWebhookURL != "" {
+		d.notifiers = append(d.notifiers, &TeamsNotifier{url: cfg.TeamsWebhookURL, client: client})
+	}
+
+	return d
+}
+
+// Dispatch 异步发送告警，失败只记录日志
+func (d *Dispatcher) Dispatch(event *Event) {
+	for _, notifier := range d.notifiers {
+		go func(n Notifier) {
+			if err := n.Send(event); err != nil {
+				log.Printf("发送%s告警失败: %v", n.Name(), err)
+			}
+		}(notifier)
+	}
+}
+
+// postJSON 以JSON格式POST请求
+func postJSON(client *http.Client, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("序列化告警失败: %v", err)
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("响应状态异常: %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package alert
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Allowlist 已知资产列表，匹配的资产不再产生新资产和规则告警
+// 条目可以是MAC地址、IP地址或CIDR网段
+type Allowlist struct {
+	mu   sync.RWMutex
+	macs map[string]bool
+	ips  map[string]bool
+	nets []*net.IPNet
+}
+
+// NewAllowlist 根据配置条目创建已知资产列表，无效条目被忽略并在错误中列出
+func NewAllowlist(entries []string) (*Allowlist, error) {
+	a := &Allowlist{}
+	err := a.Load(entries)
+	return a, err
+}
+
+// Load 替换列表内容，用于重新加载配置
+// 有效条目总会生效，无效条目被忽略并在返回的错误中列出
+func (a *Allowlist) Load(entries []string) error {
+	macs := make(map[string]bool)
+	ips := make(map[string]bool)
+	var nets []*net.IPNet
+	var invalid []string
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			nets = append(nets, network)
+		} else if ip := net.ParseIP(entry); ip != nil {
+			ips[ip.String()] = true
+		} else if mac, err := net.ParseMAC(entry); err == nil {
+			macs[mac.String()] = true
+		} else {
+			invalid = append(invalid, entry)
+		}
+	}
+
+	a.mu.Lock()
+	a.macs, a.ips, a.nets = macs, ips, nets
+	a.mu.Unlock()
+
+	if len(invalid) > 0 {
+		return fmt.Errorf("无效的已知资产条目: %s", strings.Join(invalid, ", "))
+	}
+	return nil
+}
+
+// Len 返回有效条目数量
+func (a *Allowlist) Len() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return len(a.macs) + len(a.ips) + len(a.nets)
+}
+
+// Contains 检查IP或MAC是否属于已知资产
+func (a *Allowlist) Contains(ipAddress, macAddress string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if mac, err := net.ParseMAC(macAddress); err == nil && a.macs[mac.String()] {
+		return true
+	}
+
+	ip := net.ParseIP(ipAddress)
+	if ip == nil {
+		return false
+	}
+	if a.ips[ip.String()] {
+		return true
+	}
+	for _, network := range a.nets {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
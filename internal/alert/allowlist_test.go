@@ -0,0 +1,75 @@
+package alert
+
+import "testing"
+
+func TestAllowlistContains(t *testing.T) {
+	a, err := NewAllowlist([]string{
+		"00:11:22:33:44:55",
+		"AA-BB-CC-DD-EE-FF",
+		"192.168.1.10",
+		"2001:db8:0:0::1",
+		"10.20.0.0/16",
+		"fd00:1::/64",
+		" ",
+	})
+	if err != nil {
+		t.Fatalf("NewAllowlist() error = %v", err)
+	}
+	if a.Len() != 6 {
+		t.Errorf("Len() = %d, want 6", a.Len())
+	}
+
+	tests := []struct {
+		name string
+		ip   string
+		mac  string
+		want bool
+	}{
+		{"mac", "", "00:11:22:33:44:55", true},
+		{"mac upper case", "", "AA:BB:CC:DD:EE:FF", true},
+		{"mac entry with dashes", "", "aa:bb:cc:dd:ee:ff", true},
+		{"mac with dashes", "", "00-11-22-33-44-55", true},
+		{"mac not listed", "", "00:11:22:33:44:56", false},
+		{"ip", "192.168.1.10", "", true},
+		{"ip not listed", "192.168.1.11", "", false},
+		{"ipv6 normalized", "2001:db8::1", "", true},
+		{"cidr", "10.20.255.1", "", true},
+		{"cidr boundary", "10.21.0.0", "", false},
+		{"ipv6 cidr", "fd00:1::abcd", "", true},
+		{"ipv6 outside cidr", "fd00:2::1", "", false},
+		{"unknown ip known mac", "172.16.0.1", "00:11:22:33:44:55", true},
+		{"known ip unknown mac", "192.168.1.10", "00:11:22:33:44:99", true},
+		{"empty", "", "", false},
+		{"invalid ip", "not-an-ip", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.Contains(tt.ip, tt.mac); got != tt.want {
+				t.Errorf("Contains(%q, %q) = %v, want %v", tt.ip, tt.mac, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowlistLoad(t *testing.T) {
+	// 无效条目被忽略并报告，有效条目仍然生效
+	a, err := NewAllowlist([]string{"192.168.1.10", "bogus", "10.0.0.0/33"})
+	if err == nil {
+		t.Error("NewAllowlist() error = nil, want invalid entries")
+	}
+	if a.Len() != 1 || !a.Contains("192.168.1.10", "") {
+		t.Errorf("Len() = %d, want valid entry kept", a.Len())
+	}
+
+	// 重新加载替换原有条目
+	if err := a.Load([]string{"00:11:22:33:44:55"}); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if a.Contains("192.168.1.10", "") {
+		t.Error("Contains() after reload = true for removed entry")
+	}
+	if !a.Contains("", "00:11:22:33:44:55") {
+		t.Error("Contains() after reload = false for new entry")
+	}
+}
@@ -0,0 +1,121 @@
+package alert
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WebhookNotifier 通用Webhook，直接POST事件JSON
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// Name 渠道名称
+func (w *WebhookNotifier) Name() string {
+	return "Webhook"
+}
+
+// Send 发送告警
+func (w *WebhookNotifier) Send(event *Event) error {
+	return postJSON(w.client, w.url, event)
+}
+
+// SlackNotifier Slack Incoming Webhook，使用Block Kit格式
+type SlackNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// Name 渠道名称
+func (s *SlackNotifier) Name() string {
+	return "Slack"
+}
+
+// Send 发送告警
+func (s *SlackNotifier) Send(event *Event) error {
+	field := func(label, value string) map[string]string {
+		return map[string]string{
+			"type": "mrkdwn",
+			"text": fmt.Sprintf("*%s*\n%s", label, valueOrDash(value)),
+		}
+	}
+
+	blocks := []map[string]interface{}{
+		{
+			"type": "header",
+			"text": map[string]string{
+				"type": "plain_text",
+				"text": event.Title,
+			},
+		},
+		{
+			"type": "section",
+			"fields": []map[string]string{
+				field("IP地址", event.IPAddress),
+				field("MAC地址", event.MACAddress),
+				field("设备类型", event.DeviceType),
+				field("首次发现", event.FirstSeen.Format(time.RFC3339)),
+			},
+		},
+	}
+
+	if event.Description != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "context",
+			"elements": []map[string]string{
+				{"type": "mrkdwn", "text": event.Description},
+			},
+		})
+	}
+
+	return postJSON(s.client, s.url, map[string]interface{}{
+		"text":   event.Title,
+		"blocks": blocks,
+	})
+}
+
+// TeamsNotifier Microsoft Teams Incoming Webhook，使用MessageCard格式
+type TeamsNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// Name 渠道名称
+func (t *TeamsNotifier) Name() string {
+	return "Teams"
+}
+
+// Send 发送告警
+func (t *TeamsNotifier) Send(event *Event) error {
+	fact := func(name, value string) map[string]string {
+		return map[string]string{"name": name, "value": valueOrDash(value)}
+	}
+
+	return postJSON(t.client, t.url, map[string]interface{}{
+		"@type":    "MessageCard",
+		"@context": "https://schema.org/extensions",
+		"summary":  event.Title,
+		"title":    event.Title,
+		"text":     event.Description,
+		"sections": []map[string]interface{}{
+			{
+				"facts": []map[string]string{
+					fact("IP地址", event.IPAddress),
+					fact("MAC地址", event.MACAddress),
+					fact("设备类型", event.DeviceType),
+					fact("首次发现", event.FirstSeen.Format(time.RFC3339)),
+				},
+			},
+		},
+	})
+}
+
+// valueOrDash 空值显示为"-"
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
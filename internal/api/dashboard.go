@@ -0,0 +1,22 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// webFiles 内嵌的资产面板静态文件，页面只调用现有的查询接口
+//
+//go:embed web
+var webFiles embed.FS
+
+// dashboardHandler 在根路径提供资产面板
+func dashboardHandler() http.Handler {
+	content, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		// 内嵌目录在编译时确定，不会出错
+		panic(err)
+	}
+	return http.FileServer(http.FS(content))
+}
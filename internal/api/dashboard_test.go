@@ -0,0 +1,89 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"assets_discovery/internal/assets"
+	"assets_discovery/internal/config"
+	"assets_discovery/internal/storage"
+)
+
+// newTestServer 创建使用内存存储、不监听端口的API服务
+func newTestServer(authToken string) *Server {
+	cfg := &config.Config{}
+	cfg.Storage.NoStore = true
+	cfg.Server.AuthToken = authToken
+	return NewServer(cfg, assets.NewAssetManager(cfg, storage.NewMemoryStorage()))
+}
+
+// serve 通过服务的路由处理请求
+func serve(s *Server, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestDashboardRoutes(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		wantStatus  int
+		wantType    string
+		wantContent string
+	}{
+		{"index", "/", http.StatusOK, "text/html", "<title>资产发现</title>"},
+		{"script", "/app.js", http.StatusOK, "javascript", ""},
+		{"stylesheet", "/style.css", http.StatusOK, "text/css", ""},
+		{"index.html redirects to root", "/index.html", http.StatusMovedPermanently, "", ""},
+		{"missing file", "/missing.js", http.StatusNotFound, "", ""},
+	}
+
+	// 配置了访问令牌时面板页面仍无需认证
+	for _, token := range []string{"", "secret"} {
+		s := newTestServer(token)
+		for _, tt := range tests {
+			t.Run(tt.name+"/token="+token, func(t *testing.T) {
+				rec := serve(s, http.MethodGet, tt.path, "")
+				if rec.Code != tt.wantStatus {
+					t.Fatalf("GET %s status = %d, want %d", tt.path, rec.Code, tt.wantStatus)
+				}
+				if got := rec.Header().Get("Content-Type"); !strings.Contains(got, tt.wantType) {
+					t.Errorf("Content-Type = %q, want %q", got, tt.wantType)
+				}
+				if !strings.Contains(rec.Body.String(), tt.wantContent) {
+					t.Errorf("body missing %q", tt.wantContent)
+				}
+			})
+		}
+	}
+}
+
+func TestDashboardAPIRequiresToken(t *testing.T) {
+	s := newTestServer("secret")
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"wrong token", "guess", http.StatusUnauthorized},
+		{"valid token", "secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 面板页面通过 /api/stats 获取数据
+			if rec := serve(s, http.MethodGet, "/api/stats", tt.token); rec.Code != tt.wantStatus {
+				t.Errorf("GET /api/stats status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package grpcapi
+
+import (
+	"encoding/json"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"assets_discovery/internal/api/grpcapi/pb"
+	"assets_discovery/internal/assets"
+)
+
+// unmarshalOptions 资产JSON中消息未定义的字段被忽略
+var unmarshalOptions = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+// fromJSON 经由JSON表示将v转换为消息，消息字段名与资产JSON的字段名一致，
+// 资产新增字段只需在assets.proto中添加同名字段
+func fromJSON(v interface{}, msg proto.Message) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return unmarshalOptions.Unmarshal(data, msg)
+}
+
+// toProtoAsset 资产的MarshalJSON在读锁下序列化，与并发的更新不会产生数据竞争
+func toProtoAsset(asset *assets.Asset) (*pb.Asset, error) {
+	msg := &pb.Asset{}
+	if err := fromJSON(asset, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func toProtoStats(stats assets.AssetStats) (*pb.Stats, error) {
+	msg := &pb.Stats{}
+	if err := fromJSON(stats, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func toProtoEvent(event assets.AssetEvent) (*pb.AssetEvent, error) {
+	msg := &pb.AssetEvent{
+		Type:      event.Type,
+		AssetId:   event.AssetID,
+		Timestamp: timestamppb.New(event.Timestamp),
+	}
+
+	if event.Asset != nil {
+		asset, err := toProtoAsset(event.Asset)
+		if err != nil {
+			return nil, err
+		}
+		msg.Asset = asset
+	}
+	if event.Change != nil {
+		msg.Change = &pb.ChangeRecord{}
+		if err := fromJSON(event.Change, msg.Change); err != nil {
+			return nil, err
+		}
+	}
+	if event.Finding != nil {
+		msg.Finding = &pb.Finding{}
+		if err := fromJSON(event.Finding, msg.Finding); err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
@@ -0,0 +1,4 @@
+package pb
+
+// 修改assets.proto后重新生成消息和服务代码，需要安装protoc、protoc-gen-go和protoc-gen-go-grpc
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative assets.proto
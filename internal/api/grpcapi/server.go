@@ -0,0 +1,247 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"assets_discovery/internal/api/grpcapi/pb"
+	"assets_discovery/internal/assets"
+	"assets_discovery/internal/config"
+)
+
+// eventBuffer 每个事件流缓冲的事件数，客户端接收不及时、缓冲已满时后续事件被丢弃
+const eventBuffer = 256
+
+// eventTypes WatchEvents支持过滤的事件类型
+var eventTypes = map[string]bool{
+	assets.EventNewAsset: true,
+	assets.EventChange:   true,
+	assets.EventFinding:  true,
+	assets.EventRemoved:  true,
+}
+
+// Server 资产查询gRPC服务，与REST API共用监听地址和访问令牌
+type Server struct {
+	pb.UnimplementedAssetServiceServer
+
+	config       *config.Config
+	assetManager *assets.AssetManager
+	server       *grpc.Server
+	addr         string
+
+	// 停止时关闭，结束所有事件流，使GracefulStop不必等待客户端断开
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewServer 创建gRPC服务
+func NewServer(cfg *config.Config, assetManager *assets.AssetManager) *Server {
+	s := &Server{
+		config:       cfg,
+		assetManager: assetManager,
+		addr:         net.JoinHostPort(cfg.Server.Bind, strconv.Itoa(cfg.Server.GRPC.Port)),
+		done:         make(chan struct{}),
+	}
+
+	s.server = grpc.NewServer(
+		grpc.UnaryInterceptor(s.unaryAuth),
+		grpc.StreamInterceptor(s.streamAuth),
+	)
+	pb.RegisterAssetServiceServer(s.server, s)
+
+	return s
+}
+
+// Start 在后台启动gRPC服务
+func (s *Server) Start() {
+	lis, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		log.Printf("gRPC服务启动失败: %v", err)
+		return
+	}
+
+	log.Printf("gRPC服务启动，监听地址: %s", lis.Addr())
+	if s.config.Server.AuthToken == "" {
+		log.Printf("警告: 未配置 server.auth_token，gRPC接口无需认证即可访问")
+	}
+
+	go func() {
+		if err := s.Serve(lis); err != nil {
+			log.Printf("gRPC服务异常退出: %v", err)
+		}
+	}()
+}
+
+// Serve 在指定的监听器上提供服务，直到调用Stop
+func (s *Server) Serve(lis net.Listener) error {
+	return s.server.Serve(lis)
+}
+
+// Stop 结束事件流并停止gRPC服务，等待进行中的查询完成
+func (s *Server) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+		s.server.GracefulStop()
+	})
+}
+
+// authorize 校验请求元数据中的访问令牌，未配置令牌时直接放行
+// 令牌可通过 authorization: Bearer <token> 或 x-api-key 元数据传递
+func (s *Server) authorize(ctx context.Context) error {
+	expected := s.config.Server.AuthToken
+	if expected == "" {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var token string
+	if values := md.Get("x-api-key"); len(values) > 0 {
+		token = values[0]
+	}
+	if values := md.Get("authorization"); len(values) > 0 && strings.HasPrefix(values[0], "Bearer ") {
+		token = strings.TrimPrefix(values[0], "Bearer ")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		return status.Error(codes.Unauthenticated, "缺少或无效的访问令牌")
+	}
+	return nil
+}
+
+func (s *Server) unaryAuth(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) streamAuth(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.authorize(stream.Context()); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// GetAsset 按ID查询资产，内存中不存在时回退到存储
+func (s *Server) GetAsset(_ context.Context, req *pb.GetAssetRequest) (*pb.Asset, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "缺少资产ID")
+	}
+
+	asset, ok := s.assetManager.LookupAsset(req.GetId())
+	if !ok {
+		return nil, status.Error(codes.NotFound, "资产不存在: "+req.GetId())
+	}
+
+	msg, err := toProtoAsset(asset)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "转换资产失败: %v", err)
+	}
+	return msg, nil
+}
+
+// SearchAssets 按条件查询资产，条件的优先级与 GET /api/assets 相同
+func (s *Server) SearchAssets(_ context.Context, req *pb.SearchAssetsRequest) (*pb.SearchAssetsResponse, error) {
+	if req.GetPort() < 0 || req.GetPort() > 65535 {
+		return nil, status.Error(codes.InvalidArgument, "无效的端口号")
+	}
+	if req.MinRisk != nil && req.GetMinRisk() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "无效的风险评分")
+	}
+	scope := req.GetScope()
+	if scope != "" && scope != "internal" && scope != "external" {
+		return nil, status.Error(codes.InvalidArgument, "无效的网络范围: "+scope+"，可选值为internal、external")
+	}
+
+	var result []*assets.Asset
+	switch {
+	case req.GetPort() != 0:
+		result = s.assetManager.GetAssetsByPort(int(req.GetPort()), req.GetProto())
+	case req.GetDeviceType() != "":
+		result = s.assetManager.GetAssetsByType(req.GetDeviceType())
+	case req.GetOs() != "":
+		result = s.assetManager.GetAssetsByOS(req.GetOs())
+	case req.GetVendor() != "":
+		result = s.assetManager.GetAssetsByVendor(req.GetVendor())
+	case req.GetQuery() != "":
+		result = s.assetManager.SearchAllAssets(req.GetQuery())
+	default:
+		for _, asset := range s.assetManager.GetAllAssets() {
+			result = append(result, asset)
+		}
+	}
+
+	if req.MinRisk != nil {
+		result = assets.FilterMinRisk(result, req.GetMinRisk())
+	}
+	if scope != "" {
+		result = assets.FilterScope(result, scope)
+	}
+
+	resp := &pb.SearchAssetsResponse{
+		Total:  int32(len(result)),
+		Assets: make([]*pb.Asset, 0, len(result)),
+	}
+	for _, asset := range result {
+		msg, err := toProtoAsset(asset)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "转换资产失败: %v", err)
+		}
+		resp.Assets = append(resp.Assets, msg)
+	}
+	return resp, nil
+}
+
+// GetStats 资产统计信息
+func (s *Server) GetStats(_ context.Context, _ *pb.GetStatsRequest) (*pb.Stats, error) {
+	msg, err := toProtoStats(s.assetManager.GetStats())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "转换统计信息失败: %v", err)
+	}
+	return msg, nil
+}
+
+// WatchEvents 订阅资产事件并推送给客户端，直到客户端断开或服务停止
+func (s *Server) WatchEvents(req *pb.WatchEventsRequest, stream pb.AssetService_WatchEventsServer) error {
+	types := make(map[string]bool, len(req.GetTypes()))
+	for _, t := range req.GetTypes() {
+		if !eventTypes[t] {
+			return status.Error(codes.InvalidArgument, "不支持的事件类型: "+t)
+		}
+		types[t] = true
+	}
+
+	events, cancel := s.assetManager.Subscribe(eventBuffer)
+	defer cancel()
+
+	for {
+		select {
+		case event := <-events:
+			if len(types) > 0 && !types[event.Type] {
+				continue
+			}
+			msg, err := toProtoEvent(event)
+			if err != nil {
+				log.Printf("转换资产事件失败: %v", err)
+				continue
+			}
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-s.done:
+			return status.Error(codes.Unavailable, "gRPC服务正在停止")
+		}
+	}
+}
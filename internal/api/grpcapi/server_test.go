@@ -0,0 +1,181 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"assets_discovery/internal/api/grpcapi/pb"
+	"assets_discovery/internal/assets"
+	"assets_discovery/internal/config"
+	"assets_discovery/internal/storage"
+)
+
+// newTestClient 在内存监听器上启动gRPC服务，返回使用生成代码的客户端
+func newTestClient(t *testing.T, authToken string) (pb.AssetServiceClient, *assets.AssetManager) {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Storage.NoStore = true
+	cfg.Server.AuthToken = authToken
+	am := assets.NewAssetManager(cfg, storage.NewMemoryStorage())
+
+	s := NewServer(cfg, am)
+	lis := bufconn.Listen(1 << 20)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewAssetServiceClient(conn), am
+}
+
+// testAssetInfo 带MAC、IP和开放端口的资产信息
+func testAssetInfo(i int) *assets.AssetInfo {
+	now := time.Now()
+	return &assets.AssetInfo{
+		IPAddress:  fmt.Sprintf("10.0.0.%d", i),
+		MACAddress: fmt.Sprintf("00:11:22:33:44:%02x", i),
+		Hostname:   fmt.Sprintf("host-%d", i),
+		OpenPorts:  []int{22},
+		Timestamp:  now,
+		FirstSeen:  now,
+		LastSeen:   now,
+		IsActive:   true,
+	}
+}
+
+func TestUnaryRPCs(t *testing.T) {
+	client, am := newTestClient(t, "")
+	am.UpdateAsset(testAssetInfo(1))
+	am.UpdateAsset(testAssetInfo(2))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	asset, err := client.GetAsset(ctx, &pb.GetAssetRequest{Id: "mac_00:11:22:33:44:01"})
+	if err != nil {
+		t.Fatalf("GetAsset() error = %v", err)
+	}
+	if asset.GetIpAddress() != "10.0.0.1" || asset.GetHostname() != "host-1" {
+		t.Errorf("GetAsset() = %s/%s, want 10.0.0.1/host-1", asset.GetIpAddress(), asset.GetHostname())
+	}
+
+	if _, err := client.GetAsset(ctx, &pb.GetAssetRequest{Id: "mac_ff:ff:ff:ff:ff:ff"}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetAsset(missing) code = %v, want NotFound", status.Code(err))
+	}
+	if _, err := client.GetAsset(ctx, &pb.GetAssetRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GetAsset(empty) code = %v, want InvalidArgument", status.Code(err))
+	}
+
+	all, err := client.SearchAssets(ctx, &pb.SearchAssetsRequest{})
+	if err != nil {
+		t.Fatalf("SearchAssets() error = %v", err)
+	}
+	if all.GetTotal() != 2 || len(all.GetAssets()) != 2 {
+		t.Errorf("SearchAssets() total = %d, assets = %d, want 2", all.GetTotal(), len(all.GetAssets()))
+	}
+
+	found, err := client.SearchAssets(ctx, &pb.SearchAssetsRequest{Query: "host-2"})
+	if err != nil {
+		t.Fatalf("SearchAssets(query) error = %v", err)
+	}
+	if found.GetTotal() != 1 || found.GetAssets()[0].GetId() != "mac_00:11:22:33:44:02" {
+		t.Errorf("SearchAssets(host-2) = %v, want mac_00:11:22:33:44:02", found.GetAssets())
+	}
+
+	if _, err := client.SearchAssets(ctx, &pb.SearchAssetsRequest{Port: 70000}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("SearchAssets(port 70000) code = %v, want InvalidArgument", status.Code(err))
+	}
+
+	stats, err := client.GetStats(ctx, &pb.GetStatsRequest{})
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if stats.GetTotalAssets() != 2 || stats.GetActiveAssets() != 2 {
+		t.Errorf("GetStats() total = %d, active = %d, want 2, 2", stats.GetTotalAssets(), stats.GetActiveAssets())
+	}
+}
+
+func TestWatchEvents(t *testing.T) {
+	client, am := newTestClient(t, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.WatchEvents(ctx, &pb.WatchEventsRequest{Types: []string{assets.EventNewAsset}})
+	if err != nil {
+		t.Fatalf("WatchEvents() error = %v", err)
+	}
+
+	// 服务端订阅事件总线与客户端建立流是异步的，持续产生新资产直到收到事件
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for i := 1; i < 255; i++ {
+			am.UpdateAsset(testAssetInfo(i))
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+	if event.GetType() != assets.EventNewAsset {
+		t.Errorf("event type = %q, want %q", event.GetType(), assets.EventNewAsset)
+	}
+	if event.GetAsset().GetId() != event.GetAssetId() || event.GetAssetId() == "" {
+		t.Errorf("event asset id = %q, asset = %q", event.GetAssetId(), event.GetAsset().GetId())
+	}
+
+	// 服务端流的错误在第一次Recv时返回
+	bad, err := client.WatchEvents(ctx, &pb.WatchEventsRequest{Types: []string{"bogus"}})
+	if err != nil {
+		t.Fatalf("WatchEvents(bogus) error = %v", err)
+	}
+	if _, err := bad.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("WatchEvents(bogus) code = %v, want InvalidArgument", status.Code(err))
+	}
+}
+
+func TestAuthToken(t *testing.T) {
+	client, am := newTestClient(t, "secret")
+	am.UpdateAsset(testAssetInfo(1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := client.GetStats(ctx, &pb.GetStatsRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("GetStats() without token code = %v, want Unauthenticated", status.Code(err))
+	}
+
+	authed := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+	if _, err := client.GetStats(authed, &pb.GetStatsRequest{}); err != nil {
+		t.Errorf("GetStats() with token error = %v", err)
+	}
+}
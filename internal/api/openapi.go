@@ -0,0 +1,479 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"assets_discovery/internal/assets"
+)
+
+// openAPISchemas 需要在规范中描述的数据结构，字段定义通过反射从结构体和json标签生成
+var openAPISchemas = []interface{}{
+	assets.Asset{},
+	assets.AssetStats{},
+	assets.IPMACConflict{},
+	assets.Host{},
+	assets.TopTalker{},
+}
+
+// handleOpenAPI 输出描述REST接口和资产数据结构的OpenAPI 3规范
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "不支持的请求方法")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.openAPISpec())
+}
+
+// openAPISpec 构建OpenAPI 3规范
+func (s *Server) openAPISpec() map[string]interface{} {
+	schemas := map[string]interface{}{}
+	for _, v := range openAPISchemas {
+		schemaFor(reflect.TypeOf(v), schemas)
+	}
+	schemas["AssetSummary"] = assetSummarySchema(schemas)
+	schemas["Error"] = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"error": map[string]interface{}{"type": "string"},
+		},
+	}
+
+	spec := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "assets_discovery API",
+			"description": "被动式网络资产识别与分析系统的资产查询接口",
+			"version":     "1.0.0",
+		},
+		"paths": openAPIPaths(),
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer"},
+				"apiKey":     map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+	}
+
+	// 配置了访问令牌时所有接口都需要认证
+	if s.config.Server.AuthToken != "" {
+		spec["security"] = []interface{}{
+			map[string]interface{}{"bearerAuth": []string{}},
+			map[string]interface{}{"apiKey": []string{}},
+		}
+	}
+
+	return spec
+}
+
+// openAPIPaths 各接口的描述，新增接口时需同步更新
+func openAPIPaths() map[string]interface{} {
+	assetList := jsonResponse("资产列表", map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"total":  map[string]interface{}{"type": "integer"},
+			"assets": map[string]interface{}{"type": "array", "items": schemaRef("AssetSummary")},
+		},
+	})
+	deleted := jsonResponse("删除的资产数量", map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"deleted": map[string]interface{}{"type": "integer"},
+		},
+	})
+
+	return map[string]interface{}{
+		"/api/assets": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary": "查询资产列表",
+				"description": "port、type、os、vendor、q 按优先级只使用其中一个，first_seen_since、min_risk、scope、virtual、randomized_mac 可与其他参数组合；" +
+					"q 同时搜索内存和存储中的资产，支持IP、CIDR、MAC、主机名等",
+				"parameters": []interface{}{
+					queryParam("port", "开放端口", "integer"),
+					queryParam("proto", "端口协议，与port配合使用，如tcp、udp", "string"),
+					queryParam("type", "设备类型", "string"),
+					queryParam("os", "操作系统类别", "string"),
+					queryParam("vendor", "MAC厂商，规范化后比较，如Cisco可匹配Cisco Systems, Inc", "string"),
+					queryParam("q", "关键字搜索", "string"),
+					queryParam("first_seen_since", "首次发现时间下限，支持24h、7d、2025-01-01或RFC3339", "string"),
+					queryParam("min_risk", "风险评分下限(0-10)", "number"),
+					queryParam("scope", "内外网：internal为私有、ULA、链路本地地址，external为公网地址", "string"),
+					queryParam("virtual", "按是否为虚拟机（虚拟化平台的OUI或厂商）筛选", "boolean"),
+					queryParam("randomized_mac", "按是否使用随机MAC（设置了本地管理位）筛选", "boolean"),
+				},
+				"responses": map[string]interface{}{
+					"200": assetList,
+					"400": errorResponse("参数无效"),
+				},
+			},
+			"delete": map[string]interface{}{
+				"summary":     "批量删除资产",
+				"description": "从内存和存储中删除资产，必须指定 inactive=true 或 all=true 其中之一",
+				"parameters": []interface{}{
+					queryParam("inactive", "为true时删除所有非活跃资产", "boolean"),
+					queryParam("all", "为true时删除全部资产，用于清除测试数据", "boolean"),
+				},
+				"responses": map[string]interface{}{
+					"200": deleted,
+					"400": errorResponse("未指定删除范围"),
+					"500": errorResponse("从存储中删除失败"),
+				},
+			},
+		},
+		"/api/assets/{id}": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "查询单个资产",
+				"description": "返回资产摘要，不包括协议详情、变更历史及端口和服务的详细信息，完整数据见 /api/assets/{id}/raw",
+				"parameters": []interface{}{
+					map[string]interface{}{
+						"name":     "id",
+						"in":       "path",
+						"required": true,
+						"schema":   map[string]interface{}{"type": "string"},
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": jsonResponse("资产摘要", schemaRef("AssetSummary")),
+					"404": errorResponse("资产不存在"),
+				},
+			},
+			"delete": map[string]interface{}{
+				"summary": "从内存和存储中删除资产",
+				"parameters": []interface{}{
+					map[string]interface{}{
+						"name":     "id",
+						"in":       "path",
+						"required": true,
+						"schema":   map[string]interface{}{"type": "string"},
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": deleted,
+					"404": errorResponse("资产不存在"),
+					"500": errorResponse("从存储中删除失败"),
+				},
+			},
+		},
+		"/api/assets/{id}/raw": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "查询资产捕获到的全部数据",
+				"description": "始终返回完整资产，包括protocols中的协议详情、changes变更历史及端口和服务的详细信息",
+				"parameters": []interface{}{
+					map[string]interface{}{
+						"name":     "id",
+						"in":       "path",
+						"required": true,
+						"schema":   map[string]interface{}{"type": "string"},
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": jsonResponse("完整资产", schemaRef("Asset")),
+					"404": errorResponse("资产不存在"),
+				},
+			},
+		},
+		"/api/assets/{id}/notes": map[string]interface{}{
+			"put": map[string]interface{}{
+				"summary":     "设置资产备注",
+				"description": "备注由运维人员维护，资产更新时不会被覆盖；notes为空字符串时清除备注",
+				"parameters": []interface{}{
+					map[string]interface{}{
+						"name":     "id",
+						"in":       "path",
+						"required": true,
+						"schema":   map[string]interface{}{"type": "string"},
+					},
+				},
+				"requestBody": map[string]interface{}{
+					"required": true,
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{
+							"schema": map[string]interface{}{
+								"type":     "object",
+								"required": []string{"notes"},
+								"properties": map[string]interface{}{
+									"notes": map[string]interface{}{"type": "string", "maxLength": assets.MaxNotesLength},
+								},
+							},
+						},
+					},
+				},
+				"responses": map[string]interface{}{
+					"200": jsonResponse("更新后的资产摘要", schemaRef("AssetSummary")),
+					"400": errorResponse("请求体无效或备注过长"),
+					"404": errorResponse("资产不存在"),
+					"500": errorResponse("保存到存储失败"),
+				},
+			},
+		},
+		"/api/assets/export": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "流式导出全部资产",
+				"description": "资产逐个写出并以分块传输发送，json为资产数组，ndjson每行一个资产，csv每行一个展开后的资产，stix为STIX 2.1 bundle",
+				"parameters": []interface{}{
+					map[string]interface{}{
+						"name":        "format",
+						"in":          "query",
+						"description": "导出格式，默认json",
+						"schema": map[string]interface{}{
+							"type": "string",
+							"enum": []string{"json", "ndjson", "csv", "stix"},
+						},
+					},
+					queryParam("flatten", "为true时将嵌套结构展开为扁平字段，字段选择和重命名见配置export", "boolean"),
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "资产清单",
+						"content": map[string]interface{}{
+							"application/json":      map[string]interface{}{"schema": map[string]interface{}{"type": "array", "items": schemaRef("Asset")}},
+							"application/x-ndjson":  map[string]interface{}{"schema": schemaRef("Asset")},
+							"text/csv":              map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+							"application/stix+json": map[string]interface{}{"schema": map[string]interface{}{"type": "object"}},
+						},
+					},
+					"400": errorResponse("不支持的导出格式"),
+				},
+			},
+		},
+		"/api/stats": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary": "资产统计信息",
+				"responses": map[string]interface{}{
+					"200": jsonResponse("统计信息", schemaRef("AssetStats")),
+				},
+			},
+		},
+		"/api/aggregate": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary": "按字段分组统计资产数量",
+				"parameters": []interface{}{
+					map[string]interface{}{
+						"name":        "by",
+						"in":          "query",
+						"description": "聚合字段，默认device_type",
+						"schema": map[string]interface{}{
+							"type": "string",
+							"enum": assets.AggregateFields,
+						},
+					},
+					queryParam("active", "为true时只统计活跃资产", "boolean"),
+				},
+				"responses": map[string]interface{}{
+					"200": jsonResponse("分组计数", map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"by":          map[string]interface{}{"type": "string"},
+							"active_only": map[string]interface{}{"type": "boolean"},
+							"counts": map[string]interface{}{
+								"type":                 "object",
+								"additionalProperties": map[string]interface{}{"type": "integer"},
+							},
+						},
+					}),
+					"400": errorResponse("不支持的聚合字段"),
+				},
+			},
+		},
+		"/api/hosts": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "主机视图",
+				"description": "按parser.host_correlation.key（hostname或duid）将同一台主机的多个资产归并，没有关联键的资产单独作为一台主机",
+				"parameters": []interface{}{
+					queryParam("active", "为true时只返回包含活跃资产的主机", "boolean"),
+					queryParam("multi", "为true时只返回由多个资产组成的主机", "boolean"),
+				},
+				"responses": map[string]interface{}{
+					"200": jsonResponse("主机列表", map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"key":   map[string]interface{}{"type": "string"},
+							"total": map[string]interface{}{"type": "integer"},
+							"hosts": map[string]interface{}{"type": "array", "items": schemaRef("Host")},
+						},
+					}),
+					"404": errorResponse("未启用主机关联"),
+				},
+			},
+		},
+		"/api/top-talkers": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "流量排名",
+				"description": "按资产作为源地址发出的数据包数或字节数排名，由资产的流量计数得出，不重新扫描数据包",
+				"parameters": []interface{}{
+					queryParam("n", "返回的资产数量，默认10", "integer"),
+					queryParam("window", "时间窗口，如15m，最长1h；为空时按整个捕获期间的累计值排名", "string"),
+					queryParam("by", "排名依据：bytes（默认）或packets", "string"),
+				},
+				"responses": map[string]interface{}{
+					"200": jsonResponse("流量排名", map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"by":      map[string]interface{}{"type": "string"},
+							"window":  map[string]interface{}{"type": "string"},
+							"total":   map[string]interface{}{"type": "integer"},
+							"talkers": map[string]interface{}{"type": "array", "items": schemaRef("TopTalker")},
+						},
+					}),
+					"400": errorResponse("无效的参数"),
+				},
+			},
+		},
+		"/api/conflicts": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary": "ARP中检测到的IP-MAC绑定冲突",
+				"responses": map[string]interface{}{
+					"200": jsonResponse("冲突列表", map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"total":     map[string]interface{}{"type": "integer"},
+							"conflicts": map[string]interface{}{"type": "array", "items": schemaRef("IPMACConflict")},
+						},
+					}),
+				},
+			},
+		},
+	}
+}
+
+// timeType time.Time按RFC3339字符串序列化
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaFor 根据Go类型生成JSON Schema，具名结构体注册到components中并返回引用
+func schemaFor(t reflect.Type, components map[string]interface{}) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct:
+		if _, ok := components[t.Name()]; !ok {
+			// 先占位，避免结构体自引用时无限递归
+			components[t.Name()] = nil
+			components[t.Name()] = structSchema(t, components)
+		}
+		return schemaRef(t.Name())
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaFor(t.Elem(), components)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaFor(t.Elem(), components)}
+	}
+
+	// interface{}等无法确定结构的字段允许任意值
+	return map[string]interface{}{}
+}
+
+// structSchema 根据导出字段的json标签生成对象的属性定义
+func structSchema(t reflect.Type, components map[string]interface{}) map[string]interface{} {
+	properties := map[string]interface{}{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if n := strings.Split(tag, ",")[0]; n != "" {
+				name = n
+			}
+		}
+		properties[name] = schemaFor(field.Type, components)
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+}
+
+// assetSummarySchema Asset.GetSummary返回的摘要，字段需与GetSummary保持一致
+func assetSummarySchema(components map[string]interface{}) map[string]interface{} {
+	str := map[string]interface{}{"type": "string"}
+	strList := map[string]interface{}{"type": "array", "items": str}
+	boolean := map[string]interface{}{"type": "boolean"}
+	integer := map[string]interface{}{"type": "integer"}
+	number := map[string]interface{}{"type": "number"}
+	timestamp := schemaFor(timeType, components)
+
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":                      str,
+			"ip_address":              str,
+			"mac_address":             str,
+			"scope":                   str,
+			"is_virtual":              boolean,
+			"is_randomized_mac":       boolean,
+			"hostname":                str,
+			"vendor":                  str,
+			"device_type":             str,
+			"device_type_source":      str,
+			"ip_history":              strList,
+			"interfaces":              strList,
+			"os_family":               str,
+			"ports":                   strList,
+			"ports_count":             integer,
+			"services_count":          integer,
+			"outbound_services_count": integer,
+			"first_seen":              timestamp,
+			"last_seen":               timestamp,
+			"is_active":               boolean,
+			"confidence":              number,
+			"risk_score":              number,
+			"risk_factors":            strList,
+			"findings":                map[string]interface{}{"type": "array", "items": schemaFor(reflect.TypeOf(assets.Finding{}), components)},
+			"wpad_query":              boolean,
+			"provenance":              map[string]interface{}{"type": "object", "additionalProperties": schemaFor(reflect.TypeOf(assets.FieldSource{}), components)},
+			"notes":                   str,
+		},
+	}
+}
+
+func schemaRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+func queryParam(name, description, typ string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        name,
+		"in":          "query",
+		"description": description,
+		"schema":      map[string]interface{}{"type": typ},
+	}
+}
+
+func jsonResponse(description string, schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": schema},
+		},
+	}
+}
+
+func errorResponse(description string) map[string]interface{} {
+	return jsonResponse(description, schemaRef("Error"))
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// collectRefs 收集规范中所有 $ref 引用
+func collectRefs(v interface{}, refs map[string]bool) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if ref, ok := child.(string); ok && key == "$ref" {
+				refs[ref] = true
+				continue
+			}
+			collectRefs(child, refs)
+		}
+	case []interface{}:
+		for _, child := range value {
+			collectRefs(child, refs)
+		}
+	}
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	// 需要认证时接口规范仍可直接获取
+	rec := serve(newTestServer("secret"), http.MethodGet, "/openapi.json", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json status = %d, want 200", rec.Code)
+	}
+
+	var spec struct {
+		OpenAPI    string                            `json:"openapi"`
+		Paths      map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+		Security []interface{} `json:"security"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("openapi = %q, want 3.x", spec.OpenAPI)
+	}
+	if len(spec.Security) == 0 {
+		t.Errorf("security missing although auth_token is set")
+	}
+
+	// README中记录的接口
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{"get", "/api/assets"},
+		{"delete", "/api/assets"},
+		{"get", "/api/assets/{id}"},
+		{"delete", "/api/assets/{id}"},
+		{"get", "/api/assets/{id}/raw"},
+		{"put", "/api/assets/{id}/notes"},
+		{"get", "/api/assets/export"},
+		{"get", "/api/top-talkers"},
+		{"get", "/api/stats"},
+		{"get", "/api/aggregate"},
+		{"get", "/api/conflicts"},
+		{"get", "/api/hosts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			operation, ok := spec.Paths[tt.path][tt.method].(map[string]interface{})
+			if !ok {
+				t.Fatalf("operation not documented")
+			}
+			if summary, _ := operation["summary"].(string); summary == "" {
+				t.Errorf("summary missing")
+			}
+			if responses, _ := operation["responses"].(map[string]interface{}); responses["200"] == nil {
+				t.Errorf("200 response missing")
+			}
+		})
+	}
+
+	// 所有引用的数据结构都已定义
+	refs := make(map[string]bool)
+	var raw interface{}
+	json.Unmarshal(rec.Body.Bytes(), &raw)
+	collectRefs(raw, refs)
+	if len(refs) == 0 {
+		t.Fatalf("no schema references found")
+	}
+	for ref := range refs {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		if _, ok := spec.Components.Schemas[name]; !ok {
+			t.Errorf("unresolved reference %s", ref)
+		}
+	}
+}
+
+func TestOpenAPIMethodNotAllowed(t *testing.T) {
+	if rec := serve(newTestServer(""), http.MethodPost, "/openapi.json", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /openapi.json status = %d, want 405", rec.Code)
+	}
+}
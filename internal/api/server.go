@@ -0,0 +1,543 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"assets_discovery/internal/assets"
+	"assets_discovery/internal/config"
+	"assets_discovery/internal/metrics"
+)
+
+// Server 资产查询API服务
+type Server struct {
+	config       *config.Config
+	assetManager *assets.AssetManager
+	server       *http.Server
+}
+
+// NewServer 创建API服务
+func NewServer(cfg *config.Config, assetManager *assets.AssetManager) *Server {
+	s := &Server{
+		config:       cfg,
+		assetManager: assetManager,
+	}
+
+	apiMux := http.NewServeMux()
+	apiMux.HandleFunc("/api/assets", s.handleAssets)
+	apiMux.HandleFunc("/api/assets/", s.handleAsset)
+	apiMux.HandleFunc("/api/assets/export", s.handleExport)
+	apiMux.HandleFunc("/api/stats", s.handleStats)
+	apiMux.HandleFunc("/api/conflicts", s.handleConflicts)
+	apiMux.HandleFunc("/api/aggregate", s.handleAggregate)
+	apiMux.HandleFunc("/api/hosts", s.handleHosts)
+	apiMux.HandleFunc("/api/top-talkers", s.handleTopTalkers)
+
+	// 所有 /api 接口经过令牌认证，面板页面和接口规范不包含资产数据，不需要认证
+	mux := http.NewServeMux()
+	mux.Handle("/api/", s.requireToken(apiMux))
+	mux.HandleFunc("/openapi.json", s.handleOpenAPI)
+	mux.Handle("/metrics", s.requireToken(http.HandlerFunc(s.handleMetrics)))
+	mux.Handle("/", dashboardHandler())
+
+	s.server = &http.Server{
+		Addr:    net.JoinHostPort(cfg.Server.Bind, strconv.Itoa(cfg.Server.Port)),
+		Handler: mux,
+	}
+
+	return s
+}
+
+// Start 在后台启动API服务
+func (s *Server) Start() {
+	log.Printf("API服务启动，监听地址: %s", s.server.Addr)
+	if s.config.Server.AuthToken == "" {
+		log.Printf("警告: 未配置 server.auth_token，API接口无需认证即可访问")
+	}
+
+	go func() {
+		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("API服务异常退出: %v", err)
+		}
+	}()
+}
+
+// Stop 停止API服务
+func (s *Server) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		log.Printf("API服务停止失败: %v", err)
+	}
+}
+
+// requireToken 校验请求携带的访问令牌，未配置令牌时直接放行
+// 令牌可通过 Authorization: Bearer <token> 或 X-API-Key 头部传递
+func (s *Server) requireToken(next http.Handler) http.Handler {
+	expected := s.config.Server.AuthToken
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if expected == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := r.Header.Get("X-API-Key")
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			token = strings.TrimPrefix(auth, "Bearer ")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="assets_discovery"`)
+			writeError(w, http.StatusUnauthorized, "缺少或无效的访问令牌")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleAssets 处理资产列表查询，返回资产摘要，DELETE请求批量删除资产
+// 支持的查询参数: port, proto, type, os, vendor, q，以及可与其他条件组合的 first_seen_since、min_risk、scope、virtual、randomized_mac
+func (s *Server) handleAssets(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodDelete:
+		s.deleteAssets(w, r)
+		return
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "不支持的请求方法")
+		return
+	}
+
+	query := r.URL.Query()
+	var result []*assets.Asset
+
+	var since time.Time
+	if value := query.Get("first_seen_since"); value != "" {
+		t, err := s.assetManager.ParseFirstSeenSince(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		since = t
+	}
+
+	minRisk := -1.0
+	if value := query.Get("min_risk"); value != "" {
+		score, err := strconv.ParseFloat(value, 64)
+		if err != nil || score < 0 {
+			writeError(w, http.StatusBadRequest, "无效的风险评分: "+value)
+			return
+		}
+		minRisk = score
+	}
+
+	scope := query.Get("scope")
+	if scope != "" && scope != "internal" && scope != "external" {
+		writeError(w, http.StatusBadRequest, "无效的网络范围: "+scope+"，可选值为internal、external")
+		return
+	}
+
+	virtual, filterVirtual, err := boolParam(query, "virtual")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	randomized, filterRandomized, err := boolParam(query, "randomized_mac")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch {
+	case query.Get("port") != "":
+		port, err := strconv.Atoi(query.Get("port"))
+		if err != nil || port <= 0 || port > 65535 {
+			writeError(w, http.StatusBadRequest, "无效的端口号")
+			return
+		}
+		result = s.assetManager.GetAssetsByPort(port, query.Get("proto"))
+	case query.Get("type") != "":
+		result = s.assetManager.GetAssetsByType(query.Get("type"))
+	case query.Get("os") != "":
+		result = s.assetManager.GetAssetsByOS(query.Get("os"))
+	case query.Get("vendor") != "":
+		result = s.assetManager.GetAssetsByVendor(query.Get("vendor"))
+	case query.Get("q") != "":
+		result = s.assetManager.SearchAllAssets(query.Get("q"))
+	default:
+		for _, asset := range s.assetManager.GetAllAssets() {
+			result = append(result, asset)
+		}
+	}
+
+	if !since.IsZero() {
+		result = assets.FilterFirstSeenSince(result, since)
+	}
+	if minRisk >= 0 {
+		result = assets.FilterMinRisk(result, minRisk)
+	}
+	if scope != "" {
+		result = assets.FilterScope(result, scope)
+	}
+	if filterVirtual {
+		result = assets.FilterVirtual(result, virtual)
+	}
+	if filterRandomized {
+		result = assets.FilterRandomizedMAC(result, randomized)
+	}
+
+	// 列表只返回摘要，完整数据通过 /api/assets/{id}/raw 获取
+	summaries := make([]map[string]interface{}, 0, len(result))
+	for _, asset := range result {
+		summaries = append(summaries, asset.GetSummary())
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total":  len(summaries),
+		"assets": summaries,
+	})
+}
+
+// boolParam 解析可选的布尔查询参数，返回参数值及是否指定了该参数
+func boolParam(query url.Values, name string) (value, set bool, err error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return false, false, nil
+	}
+	value, err = strconv.ParseBool(raw)
+	if err != nil {
+		return false, false, fmt.Errorf("无效的%s参数: %s，可选值为true、false", name, raw)
+	}
+	return value, true, nil
+}
+
+// deleteAssets 批量删除资产 DELETE /api/assets?inactive=true|all=true
+// 不带参数的DELETE请求被拒绝，避免误删全部资产
+func (s *Server) deleteAssets(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var deleted int
+	var err error
+	switch {
+	case query.Get("inactive") == "true":
+		deleted, err = s.assetManager.ClearInactive()
+	case query.Get("all") == "true":
+		deleted, err = s.assetManager.Clear()
+	default:
+		writeError(w, http.StatusBadRequest, "批量删除需要指定 inactive=true 或 all=true")
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"deleted": deleted})
+}
+
+// handleAsset 处理单个资产查询 /api/assets/{id}，返回资产摘要，DELETE请求删除该资产
+func (s *Server) handleAsset(w http.ResponseWriter, r *http.Request) {
+	assetID := strings.TrimPrefix(r.URL.Path, "/api/assets/")
+	if id, ok := strings.CutSuffix(assetID, "/notes"); ok && id != "" {
+		s.handleAssetNotes(w, r, id)
+		return
+	}
+	if id, ok := strings.CutSuffix(assetID, "/raw"); ok && id != "" {
+		s.handleAssetRaw(w, r, id)
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "不支持的请求方法")
+		return
+	}
+
+	if assetID == "" {
+		s.handleAssets(w, r)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		found, err := s.assetManager.DeleteAsset(assetID)
+		switch {
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, err.Error())
+		case !found:
+			writeError(w, http.StatusNotFound, "资产不存在: "+assetID)
+		default:
+			writeJSON(w, http.StatusOK, map[string]int{"deleted": 1})
+		}
+		return
+	}
+
+	asset, ok := s.assetManager.LookupAsset(assetID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "资产不存在: "+assetID)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, asset.GetSummary())
+}
+
+// handleAssetRaw 返回资产捕获到的全部数据 GET /api/assets/{id}/raw
+// 包括协议信息（HTTP头部、DHCP选项、TLS信息等）、变更历史及端口和服务的详细信息，
+// 供分析人员查看完整细节；资产列表和 GET /api/assets/{id} 只返回GetSummary生成的摘要
+func (s *Server) handleAssetRaw(w http.ResponseWriter, r *http.Request, assetID string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "不支持的请求方法")
+		return
+	}
+
+	asset, ok := s.assetManager.LookupAsset(assetID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "资产不存在: "+assetID)
+		return
+	}
+
+	// 资产的MarshalJSON在读锁下序列化，与并发的更新不会产生数据竞争
+	writeJSON(w, http.StatusOK, asset)
+}
+
+// handleAssetNotes 设置资产备注 PUT /api/assets/{id}/notes，请求体为 {"notes": "..."}，备注为空时清除，返回资产摘要
+func (s *Server) handleAssetNotes(w http.ResponseWriter, r *http.Request, assetID string) {
+	if r.Method != http.MethodPut {
+		writeError(w, http.StatusMethodNotAllowed, "不支持的请求方法")
+		return
+	}
+
+	var body struct {
+		Notes *string `json:"notes"`
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, assets.MaxNotesLength+1024)
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Notes == nil {
+		writeError(w, http.StatusBadRequest, "请求体应为 {\"notes\": \"...\"}")
+		return
+	}
+	if len(*body.Notes) > assets.MaxNotesLength {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("备注过长，最多 %d 字节", assets.MaxNotesLength))
+		return
+	}
+
+	asset, found, err := s.assetManager.SetNotes(assetID, *body.Notes)
+	switch {
+	case !found:
+		writeError(w, http.StatusNotFound, "资产不存在: "+assetID)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		writeJSON(w, http.StatusOK, asset.GetSummary())
+	}
+}
+
+// exportContentTypes 导出格式对应的Content-Type
+var exportContentTypes = map[string]string{
+	"json":   "application/json; charset=utf-8",
+	"ndjson": "application/x-ndjson",
+	"csv":    "text/csv; charset=utf-8",
+	"stix":   "application/stix+json;version=2.1",
+}
+
+// handleExport 流式导出全部资产 /api/assets/export?format=json|ndjson|csv|stix，flatten=true 展开为扁平字段
+// 响应不设置Content-Length，资产逐个写出并以分块传输编码发送，不在内存中缓冲整个清单
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "不支持的请求方法")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "不支持的导出格式: "+format)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"assets.%s\"", format))
+
+	// 开始写出后已无法修改状态码，中途失败只能记录日志
+	flatten := r.URL.Query().Get("flatten") == "true"
+	if count, err := s.assetManager.StreamAssets(w, format, flatten); err != nil {
+		log.Printf("导出资产中断，已写出 %d 个: %v", count, err)
+	}
+}
+
+// handleStats 处理统计信息查询
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "不支持的请求方法")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.assetManager.GetStats())
+}
+
+// handleMetrics 以Prometheus文本格式输出资产统计，供Prometheus抓取
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "不支持的请求方法")
+		return
+	}
+
+	w.Header().Set("Content-Type", metrics.ContentType)
+	if err := metrics.Write(w, s.assetManager.GetStats()); err != nil {
+		log.Printf("输出指标失败: %v", err)
+	}
+}
+
+// handleAggregate 处理聚合统计查询
+// 查询参数: by 聚合字段(device_type, os_family, vendor, subnet)，active=true 只统计活跃资产
+func (s *Server) handleAggregate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "不支持的请求方法")
+		return
+	}
+
+	query := r.URL.Query()
+	field := query.Get("by")
+	if field == "" {
+		field = "device_type"
+	}
+	activeOnly := query.Get("active") == "true"
+
+	counts, err := s.assetManager.AggregateAssets(field, activeOnly)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"by":          field,
+		"active_only": activeOnly,
+		"counts":      counts,
+	})
+}
+
+// handleHosts 处理主机视图查询，按parser.host_correlation.key将同一主机的多个资产归并
+// 查询参数: active=true 只返回包含活跃资产的主机，multi=true 只返回由多个资产组成的主机
+func (s *Server) handleHosts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "不支持的请求方法")
+		return
+	}
+
+	correlation := s.config.Parser.HostCorrelation
+	if !correlation.Enabled {
+		writeError(w, http.StatusNotFound, "未启用主机关联，请配置 parser.host_correlation.enabled")
+		return
+	}
+
+	hosts, err := s.assetManager.GetHosts(correlation.Key)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	activeOnly := query.Get("active") == "true"
+	multiOnly := query.Get("multi") == "true"
+	result := make([]*assets.Host, 0, len(hosts))
+	for _, host := range hosts {
+		if activeOnly && !host.IsActive || multiOnly && len(host.AssetIDs) < 2 {
+			continue
+		}
+		result = append(result, host)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"key":   correlation.Key,
+		"total": len(result),
+		"hosts": result,
+	})
+}
+
+// handleTopTalkers 按资产发出的流量排名 /api/top-talkers?n=10&window=15m&by=bytes
+// window为空时按整个捕获期间的累计值排名，by为bytes（默认）或packets
+func (s *Server) handleTopTalkers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "不支持的请求方法")
+		return
+	}
+
+	query := r.URL.Query()
+
+	n := 10
+	if value := query.Get("n"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			writeError(w, http.StatusBadRequest, "无效的数量: "+value)
+			return
+		}
+		n = parsed
+	}
+
+	var window time.Duration
+	if value := query.Get("window"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "无效的时间窗口: "+value)
+			return
+		}
+		window = parsed
+	}
+
+	by := query.Get("by")
+	if by == "" {
+		by = assets.RankByBytes
+	}
+
+	talkers, err := s.assetManager.TopTalkers(n, window, by)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"by":      by,
+		"window":  window.String(),
+		"total":   len(talkers),
+		"talkers": talkers,
+	})
+}
+
+// handleConflicts 处理IP-MAC冲突查询
+func (s *Server) handleConflicts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "不支持的请求方法")
+		return
+	}
+
+	conflicts := s.assetManager.GetIPConflicts()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total":     len(conflicts),
+		"conflicts": conflicts,
+	})
+}
+
+// writeJSON 输出JSON响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("写入API响应失败: %v", err)
+	}
+}
+
+// writeError 输出错误响应
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
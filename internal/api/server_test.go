@@ -0,0 +1,491 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"assets_discovery/internal/assets"
+	"assets_discovery/internal/config"
+	"assets_discovery/internal/storage"
+)
+
+// decodeBody 解析JSON响应体
+func decodeBody(t *testing.T, body []byte, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(body, v); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, body)
+	}
+}
+
+func TestAssetRawVsSummary(t *testing.T) {
+	s := newTestServer("")
+	now := time.Now()
+	s.assetManager.UpdateAsset(&assets.AssetInfo{
+		IPAddress:  "10.0.0.5",
+		MACAddress: "00:11:22:33:44:55",
+		Hostname:   "web-1",
+		OpenPorts:  []int{80},
+		Services:   map[string]interface{}{"80/tcp": "nginx 1.24"},
+		Protocols: map[string]interface{}{
+			"http": map[string]interface{}{"server": "nginx/1.24", "x-powered-by": "PHP/8.2"},
+		},
+		Timestamp: now,
+	})
+	const id = "mac_00:11:22:33:44:55"
+
+	// 完整资产独有的字段
+	rawOnly := []string{"protocols", "changes", "services", "open_ports"}
+
+	rec := serve(s, http.MethodGet, "/api/assets/"+id+"/raw", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET raw status = %d, want 200", rec.Code)
+	}
+	var raw map[string]interface{}
+	decodeBody(t, rec.Body.Bytes(), &raw)
+	for _, field := range rawOnly {
+		if _, ok := raw[field]; !ok {
+			t.Errorf("raw asset missing %q", field)
+		}
+	}
+	protocols, _ := raw["protocols"].(map[string]interface{})
+	httpInfo, _ := protocols["http"].(map[string]interface{})
+	if httpInfo["x-powered-by"] != "PHP/8.2" {
+		t.Errorf("raw protocols.http = %v, want captured headers", protocols["http"])
+	}
+
+	rec = serve(s, http.MethodGet, "/api/assets/"+id, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET asset status = %d, want 200", rec.Code)
+	}
+	var summary map[string]interface{}
+	decodeBody(t, rec.Body.Bytes(), &summary)
+	for _, field := range rawOnly {
+		if _, ok := summary[field]; ok {
+			t.Errorf("summary includes %q", field)
+		}
+	}
+	if summary["hostname"] != "web-1" || summary["ports_count"] != float64(1) {
+		t.Errorf("summary hostname = %v, ports_count = %v, want web-1, 1", summary["hostname"], summary["ports_count"])
+	}
+
+	rec = serve(s, http.MethodGet, "/api/assets", "")
+	var list struct {
+		Total  int                      `json:"total"`
+		Assets []map[string]interface{} `json:"assets"`
+	}
+	decodeBody(t, rec.Body.Bytes(), &list)
+	if list.Total != 1 || len(list.Assets) != 1 {
+		t.Fatalf("GET /api/assets total = %d, assets = %d, want 1", list.Total, len(list.Assets))
+	}
+	if _, ok := list.Assets[0]["protocols"]; ok {
+		t.Errorf("list entry includes protocols")
+	}
+	if ports, _ := list.Assets[0]["ports"].([]interface{}); len(ports) != 1 || ports[0] != "80/tcp" {
+		t.Errorf("list entry ports = %v, want [80/tcp]", list.Assets[0]["ports"])
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"raw missing asset", http.MethodGet, "/api/assets/mac_ff:ff:ff:ff:ff:ff/raw", http.StatusNotFound},
+		{"raw read only", http.MethodDelete, "/api/assets/" + id + "/raw", http.StatusMethodNotAllowed},
+		{"summary missing asset", http.MethodGet, "/api/assets/mac_ff:ff:ff:ff:ff:ff", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serve(s, tt.method, tt.path, ""); rec.Code != tt.wantStatus {
+				t.Errorf("%s %s status = %d, want %d", tt.method, tt.path, rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAssetStorageFallback(t *testing.T) {
+	cfg := &config.Config{}
+	store := storage.NewMemoryStorage()
+	s := NewServer(cfg, assets.NewAssetManager(cfg, store))
+
+	// 资产已不在内存中（如被清除），只能从存储中读取
+	seen := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	evicted := map[string]interface{}{
+		"id":          "mac_00:11:22:33:44:66",
+		"ip_address":  "10.0.0.6",
+		"mac_address": "00:11:22:33:44:66",
+		"hostname":    "printer",
+		"first_seen":  seen,
+		"last_seen":   seen,
+		"protocols":   map[string]interface{}{"ipp": map[string]interface{}{"model": "LaserJet"}},
+	}
+	if err := store.SaveAsset(evicted); err != nil {
+		t.Fatalf("SaveAsset() error = %v", err)
+	}
+	s.assetManager.UpdateAsset(&assets.AssetInfo{
+		IPAddress:  "10.0.0.5",
+		MACAddress: "00:11:22:33:44:55",
+		Hostname:   "printer",
+		Timestamp:  time.Now(),
+	})
+
+	live := serve(s, http.MethodGet, "/api/assets/mac_00:11:22:33:44:55", "")
+	stored := serve(s, http.MethodGet, "/api/assets/mac_00:11:22:33:44:66", "")
+	if live.Code != http.StatusOK || stored.Code != http.StatusOK {
+		t.Fatalf("GET status live = %d, stored = %d, want 200", live.Code, stored.Code)
+	}
+
+	var fromMemory, fromStorage map[string]interface{}
+	decodeBody(t, live.Body.Bytes(), &fromMemory)
+	decodeBody(t, stored.Body.Bytes(), &fromStorage)
+	if fromStorage["ip_address"] != "10.0.0.6" {
+		t.Errorf("stored asset ip_address = %v, want 10.0.0.6", fromStorage["ip_address"])
+	}
+
+	// 内存和存储中的资产返回相同的字段
+	for key := range fromMemory {
+		if _, ok := fromStorage[key]; !ok {
+			t.Errorf("stored asset missing field %q", key)
+		}
+	}
+	for key := range fromStorage {
+		if _, ok := fromMemory[key]; !ok {
+			t.Errorf("stored asset has extra field %q", key)
+		}
+	}
+
+	// 完整数据同样回退到存储
+	rec := serve(s, http.MethodGet, "/api/assets/mac_00:11:22:33:44:66/raw", "")
+	var raw map[string]interface{}
+	decodeBody(t, rec.Body.Bytes(), &raw)
+	if protocols, _ := raw["protocols"].(map[string]interface{}); protocols["ipp"] == nil {
+		t.Errorf("raw stored asset protocols = %v, want ipp", raw["protocols"])
+	}
+
+	// 搜索合并内存和存储中的资产，同一资产只出现一次
+	rec = serve(s, http.MethodGet, "/api/assets?q=printer", "")
+	var list struct {
+		Assets []map[string]interface{} `json:"assets"`
+	}
+	decodeBody(t, rec.Body.Bytes(), &list)
+	ids := map[interface{}]bool{}
+	for _, asset := range list.Assets {
+		ids[asset["id"]] = true
+	}
+	if len(list.Assets) != 2 || !ids["mac_00:11:22:33:44:55"] || !ids["mac_00:11:22:33:44:66"] {
+		t.Errorf("search ids = %v, want live and stored asset", ids)
+	}
+}
+
+func TestListAssetsByVendor(t *testing.T) {
+	s := newTestServer("")
+	now := time.Now()
+	for _, info := range []*assets.AssetInfo{
+		{IPAddress: "10.0.0.1", MACAddress: "00:00:0c:00:00:01", Vendor: "Cisco Systems, Inc", Timestamp: now},
+		{IPAddress: "10.0.0.2", MACAddress: "00:00:0c:00:00:02", Vendor: "Cisco", Timestamp: now},
+		{IPAddress: "10.0.0.3", MACAddress: "00:1b:21:00:00:03", Vendor: "Intel Corporate", Timestamp: now},
+	} {
+		s.assetManager.UpdateAsset(info)
+	}
+
+	tests := []struct {
+		name      string
+		vendor    string
+		wantTotal int
+	}{
+		{"canonical name", "Cisco", 2},
+		{"full name with suffix", url.QueryEscape("Cisco Systems, Inc."), 2},
+		{"case insensitive", "intel", 1},
+		{"unknown vendor", "Dell", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(s, http.MethodGet, "/api/assets?vendor="+tt.vendor, "")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("GET status = %d, want 200", rec.Code)
+			}
+			var list struct {
+				Total int `json:"total"`
+			}
+			decodeBody(t, rec.Body.Bytes(), &list)
+			if list.Total != tt.wantTotal {
+				t.Errorf("GET /api/assets?vendor=%s total = %d, want %d", tt.vendor, list.Total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestListAssetsByScope(t *testing.T) {
+	s := newTestServer("")
+	now := time.Now()
+	for _, info := range []*assets.AssetInfo{
+		{IPAddress: "192.168.1.10", MACAddress: "00:11:22:33:44:01", Timestamp: now},
+		{IPAddress: "fd00::2", MACAddress: "00:11:22:33:44:02", Timestamp: now},
+		{IPAddress: "203.0.113.5", MACAddress: "00:11:22:33:44:03", Timestamp: now},
+	} {
+		s.assetManager.UpdateAsset(info)
+	}
+
+	tests := []struct {
+		query      string
+		wantStatus int
+		wantTotal  int
+	}{
+		{"scope=internal", http.StatusOK, 2},
+		{"scope=external", http.StatusOK, 1},
+		{"scope=external&q=203.0.113.5", http.StatusOK, 1},
+		{"scope=internal&q=203.0.113.5", http.StatusOK, 0},
+		{"scope=dmz", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := serve(s, http.MethodGet, "/api/assets?"+tt.query, "")
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("GET /api/assets?%s status = %d, want %d", tt.query, rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var list struct {
+				Total  int                      `json:"total"`
+				Assets []map[string]interface{} `json:"assets"`
+			}
+			decodeBody(t, rec.Body.Bytes(), &list)
+			if list.Total != tt.wantTotal {
+				t.Errorf("GET /api/assets?%s total = %d, want %d", tt.query, list.Total, tt.wantTotal)
+			}
+			want := strings.Split(strings.Split(tt.query, "&")[0], "=")[1]
+			for _, asset := range list.Assets {
+				if asset["scope"] != want {
+					t.Errorf("asset %v scope = %v, want %s", asset["id"], asset["scope"], want)
+				}
+			}
+		})
+	}
+}
+
+func TestAssetNotes(t *testing.T) {
+	s := newTestServer("")
+	const id = "mac_00:11:22:33:44:55"
+	s.assetManager.UpdateAsset(&assets.AssetInfo{IPAddress: "10.0.0.5", MACAddress: "00:11:22:33:44:55", Timestamp: time.Now()})
+
+	put := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"missing notes field", "/api/assets/" + id + "/notes", `{}`, http.StatusBadRequest},
+		{"invalid json", "/api/assets/" + id + "/notes", `notes`, http.StatusBadRequest},
+		{"too long", "/api/assets/" + id + "/notes", `{"notes": "` + strings.Repeat("x", assets.MaxNotesLength+1) + `"}`, http.StatusBadRequest},
+		{"missing asset", "/api/assets/mac_ff:ff:ff:ff:ff:ff/notes", `{"notes": "x"}`, http.StatusNotFound},
+		{"set notes", "/api/assets/" + id + "/notes", `{"notes": "decommissioning next week"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := put(tt.path, tt.body); rec.Code != tt.wantStatus {
+				t.Errorf("PUT %s status = %d, want %d", tt.path, rec.Code, tt.wantStatus)
+			}
+		})
+	}
+	if rec := serve(s, http.MethodGet, "/api/assets/"+id+"/notes", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET notes status = %d, want 405", rec.Code)
+	}
+
+	// 之后的发现结果不覆盖备注
+	s.assetManager.UpdateAsset(&assets.AssetInfo{IPAddress: "10.0.0.6", MACAddress: "00:11:22:33:44:55", Hostname: "web-1", Timestamp: time.Now()})
+	var summary map[string]interface{}
+	decodeBody(t, serve(s, http.MethodGet, "/api/assets/"+id, "").Body.Bytes(), &summary)
+	if summary["notes"] != "decommissioning next week" || summary["ip_address"] != "10.0.0.6" {
+		t.Errorf("summary notes = %v, ip = %v, want note kept after update", summary["notes"], summary["ip_address"])
+	}
+
+	// 空备注清除备注，与摘要接口一样只返回摘要，不含协议详情和变更历史
+	rec := put("/api/assets/"+id+"/notes", `{"notes": ""}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT empty notes status = %d, want 200", rec.Code)
+	}
+	var updated map[string]interface{}
+	decodeBody(t, rec.Body.Bytes(), &updated)
+	if updated["notes"] != "" || updated["id"] != id {
+		t.Errorf("PUT response notes = %v, id = %v, want cleared notes for %s", updated["notes"], updated["id"], id)
+	}
+	for _, key := range []string{"protocols", "changes"} {
+		if _, ok := updated[key]; ok {
+			t.Errorf("PUT response contains %s, want summary only", key)
+		}
+	}
+	decodeBody(t, serve(s, http.MethodGet, "/api/assets/"+id, "").Body.Bytes(), &summary)
+	if summary["notes"] != "" {
+		t.Errorf("summary notes after clear = %v, want empty", summary["notes"])
+	}
+}
+
+func TestListAssetsByMACFlags(t *testing.T) {
+	s := newTestServer("")
+	now := time.Now()
+	for _, info := range []*assets.AssetInfo{
+		{IPAddress: "10.0.0.1", MACAddress: "00:50:56:9a:00:01", IsVirtual: true, Timestamp: now},
+		{IPAddress: "10.0.0.2", MACAddress: "da:a1:19:00:00:01", IsRandomizedMAC: true, Timestamp: now},
+		{IPAddress: "10.0.0.3", MACAddress: "d4:be:d9:00:00:01", Timestamp: now},
+	} {
+		s.assetManager.UpdateAsset(info)
+	}
+
+	tests := []struct {
+		query      string
+		wantStatus int
+		wantTotal  int
+	}{
+		{"virtual=true", http.StatusOK, 1},
+		{"virtual=false", http.StatusOK, 2},
+		{"randomized_mac=true", http.StatusOK, 1},
+		{"randomized_mac=false&virtual=false", http.StatusOK, 1},
+		{"virtual=maybe", http.StatusBadRequest, 0},
+		{"randomized_mac=2", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := serve(s, http.MethodGet, "/api/assets?"+tt.query, "")
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("GET /api/assets?%s status = %d, want %d", tt.query, rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var list struct {
+				Total int `json:"total"`
+			}
+			decodeBody(t, rec.Body.Bytes(), &list)
+			if list.Total != tt.wantTotal {
+				t.Errorf("GET /api/assets?%s total = %d, want %d", tt.query, list.Total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestListHosts(t *testing.T) {
+	s := newTestServer("")
+	now := time.Now()
+	for _, info := range []*assets.AssetInfo{
+		{IPAddress: "10.0.0.10", MACAddress: "00:1a:2b:3c:4d:01", Hostname: "DB01", Timestamp: now},
+		{IPAddress: "10.0.1.10", MACAddress: "00:1a:2b:3c:4d:02", Hostname: "db01.corp.example", Timestamp: now},
+		{IPAddress: "10.0.0.20", MACAddress: "00:1a:2b:3c:4d:03", Hostname: "laptop", Timestamp: now},
+	} {
+		s.assetManager.UpdateAsset(info)
+	}
+
+	// 未启用主机关联时接口不可用
+	if rec := serve(s, http.MethodGet, "/api/hosts", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("GET /api/hosts disabled status = %d, want 404", rec.Code)
+	}
+
+	s.config.Parser.HostCorrelation.Enabled = true
+	s.config.Parser.HostCorrelation.Key = assets.HostKeyHostname
+
+	tests := []struct {
+		query     string
+		wantTotal int
+		wantFirst []string
+	}{
+		{"", 2, []string{"mac_00:1a:2b:3c:4d:01", "mac_00:1a:2b:3c:4d:02"}},
+		{"multi=true", 1, []string{"mac_00:1a:2b:3c:4d:01", "mac_00:1a:2b:3c:4d:02"}},
+		{"active=true", 2, []string{"mac_00:1a:2b:3c:4d:01", "mac_00:1a:2b:3c:4d:02"}},
+	}
+
+	for _, tt := range tests {
+		t.Run("query "+tt.query, func(t *testing.T) {
+			rec := serve(s, http.MethodGet, "/api/hosts?"+tt.query, "")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("GET /api/hosts?%s status = %d, want 200", tt.query, rec.Code)
+			}
+			var list struct {
+				Key   string         `json:"key"`
+				Total int            `json:"total"`
+				Hosts []*assets.Host `json:"hosts"`
+			}
+			decodeBody(t, rec.Body.Bytes(), &list)
+			if list.Key != assets.HostKeyHostname || list.Total != tt.wantTotal || len(list.Hosts) != tt.wantTotal {
+				t.Fatalf("GET /api/hosts?%s = %s total %d, want hostname total %d", tt.query, list.Key, list.Total, tt.wantTotal)
+			}
+			if got := list.Hosts[0].AssetIDs; !reflect.DeepEqual(got, tt.wantFirst) {
+				t.Errorf("hosts[0].asset_ids = %v, want %v", got, tt.wantFirst)
+			}
+		})
+	}
+
+	if rec := serve(s, http.MethodPost, "/api/hosts", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /api/hosts status = %d, want 405", rec.Code)
+	}
+}
+
+func TestTopTalkers(t *testing.T) {
+	s := newTestServer("")
+	now := time.Now()
+	for _, traffic := range []struct {
+		ip, mac string
+		count   int
+		length  int
+	}{
+		{"10.0.0.1", "00:1a:2b:3c:4d:01", 2, 1500},
+		{"10.0.0.2", "00:1a:2b:3c:4d:02", 20, 64},
+		{"10.0.0.3", "00:1a:2b:3c:4d:03", 1, 2000},
+	} {
+		for i := 0; i < traffic.count; i++ {
+			s.assetManager.UpdateAsset(&assets.AssetInfo{IPAddress: traffic.ip, MACAddress: traffic.mac, Length: traffic.length, Timestamp: now})
+		}
+	}
+
+	tests := []struct {
+		query      string
+		wantStatus int
+		want       []string
+	}{
+		{"", http.StatusOK, []string{"10.0.0.1", "10.0.0.3", "10.0.0.2"}},
+		{"by=packets", http.StatusOK, []string{"10.0.0.2", "10.0.0.1", "10.0.0.3"}},
+		{"n=1&window=15m", http.StatusOK, []string{"10.0.0.1"}},
+		{"n=0", http.StatusBadRequest, nil},
+		{"window=soon", http.StatusBadRequest, nil},
+		{"window=2h", http.StatusBadRequest, nil},
+		{"by=flows", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run("query "+tt.query, func(t *testing.T) {
+			rec := serve(s, http.MethodGet, "/api/top-talkers?"+tt.query, "")
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("GET /api/top-talkers?%s status = %d, want %d", tt.query, rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var result struct {
+				Talkers []assets.TopTalker `json:"talkers"`
+			}
+			decodeBody(t, rec.Body.Bytes(), &result)
+			var got []string
+			for _, talker := range result.Talkers {
+				got = append(got, talker.IPAddress)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GET /api/top-talkers?%s = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+// 资产发现面板：定期调用 /api/stats 和 /api/assets 刷新页面
+(function () {
+  "use strict";
+
+  var REFRESH_INTERVAL = 10000;
+
+  function $(id) {
+    return document.getElementById(id);
+  }
+
+  function text(value) {
+    return value === undefined || value === null ? "" : String(value);
+  }
+
+  function cell(row, value) {
+    var td = document.createElement("td");
+    td.textContent = text(value);
+    row.appendChild(td);
+  }
+
+  function formatTime(value) {
+    var t = new Date(value);
+    return isNaN(t.getTime()) || t.getFullYear() < 2000 ? "" : t.toLocaleString();
+  }
+
+  function showError(message) {
+    var el = $("error");
+    el.textContent = message;
+    el.hidden = !message;
+  }
+
+  var TOKEN_KEY = "assets_discovery.token";
+
+  // 服务端配置了 server.auth_token 时，首次收到401后提示输入令牌并保存在本地
+  function getJSON(url) {
+    var headers = {};
+    var token = localStorage.getItem(TOKEN_KEY);
+    if (token) {
+      headers["Authorization"] = "Bearer " + token;
+    }
+
+    return fetch(url, { headers: headers }).then(function (res) {
+      if (res.status === 401) {
+        var input = window.prompt("请输入API访问令牌");
+        if (input) {
+          localStorage.setItem(TOKEN_KEY, input);
+          return getJSON(url);
+        }
+      }
+      return res.json().then(function (body) {
+        if (!res.ok) {
+          throw new Error(body.error || res.statusText);
+        }
+        return body;
+      });
+    });
+  }
+
+  function renderBars(container, counts) {
+    container.textContent = "";
+    var entries = Object.keys(counts || {}).map(function (k) {
+      return [k, counts[k]];
+    });
+    entries.sort(function (a, b) {
+      return b[1] - a[1];
+    });
+
+    var max = entries.length ? entries[0][1] : 0;
+    entries.forEach(function (entry) {
+      var bar = document.createElement("div");
+      bar.className = "bar";
+
+      var name = document.createElement("span");
+      name.className = "name";
+      name.textContent = entry[0];
+      name.title = entry[0];
+
+      var fill = document.createElement("span");
+      fill.className = "fill";
+      fill.style.width = Math.max(2, Math.round((entry[1] / max) * 200)) + "px";
+
+      var count = document.createElement("span");
+      count.textContent = entry[1];
+
+      bar.appendChild(name);
+      bar.appendChild(fill);
+      bar.appendChild(count);
+      container.appendChild(bar);
+    });
+  }
+
+  function loadStats() {
+    return getJSON("/api/stats").then(function (stats) {
+      $("total").textContent = stats.total_assets;
+      $("active").textContent = stats.active_assets;
+      $("new").textContent = stats.new_assets;
+      $("uptime").textContent = stats.uptime ? "已运行 " + stats.uptime : "";
+      renderBars($("device-types"), stats.device_types);
+      renderBars($("os-distribution"), stats.os_distribution);
+    });
+  }
+
+  function loadAssets() {
+    var params = new URLSearchParams();
+    var query = $("query").value.trim();
+    var since = $("since").value;
+    if (query) {
+      params.set("q", query);
+    }
+    if (since) {
+      params.set("first_seen_since", since);
+    }
+
+    return getJSON("/api/assets?" + params.toString()).then(function (result) {
+      var assets = result.assets || [];
+      assets.sort(function (a, b) {
+        return text(a.ip_address).localeCompare(text(b.ip_address), undefined, { numeric: true });
+      });
+
+      var tbody = $("assets");
+      tbody.textContent = "";
+      assets.forEach(function (asset) {
+        var row = document.createElement("tr");
+        if (!asset.is_active) {
+          row.className = "inactive";
+        }
+        cell(row, asset.ip_address);
+        cell(row, asset.mac_address);
+        cell(row, asset.hostname);
+        cell(row, asset.vendor);
+        cell(row, asset.device_type);
+        cell(row, asset.os_family);
+        cell(row, (asset.ports || []).join(", "));
+        cell(row, asset.risk_score ? asset.risk_score.toFixed(1) : "");
+        if (asset.risk_factors && asset.risk_factors.length) {
+          row.lastChild.title = asset.risk_factors.join("\n");
+        }
+        cell(row, formatTime(asset.last_seen));
+        cell(row, asset.is_active ? "活跃" : "非活跃");
+        tbody.appendChild(row);
+      });
+    });
+  }
+
+  function refresh() {
+    Promise.all([loadStats(), loadAssets()])
+      .then(function () {
+        showError("");
+      })
+      .catch(function (err) {
+        showError("加载失败: " + err.message);
+      });
+  }
+
+  $("search").addEventListener("submit", function (e) {
+    e.preventDefault();
+    refresh();
+  });
+  $("since").addEventListener("change", refresh);
+
+  refresh();
+  setInterval(refresh, REFRESH_INTERVAL);
+})();
@@ -0,0 +1,221 @@
+package assets
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// 同一设备可能先只以IP被发现（如非以太网链路、MAC未知），之后才获得MAC地址。
+// 以MAC地址生成的ID为规范ID，IP生成的ID作为别名指向规范ID，
+// 仅有IP的资产在获得MAC后并入MAC资产，避免同一设备永久存在两条记录。
+
+// canonicalAssetID 返回资产信息对应的规范ID，调用方需持有am.mutex写锁
+// 资产信息带MAC时，将同一IP的仅IP资产并入MAC资产，并记录IP到MAC资产的别名
+func (am *AssetManager) canonicalAssetID(assetInfo *AssetInfo) string {
+	assetID := generateAssetID(assetInfo)
+
+	if assetInfo.MACAddress == "" {
+		if target, ok := am.aliases[assetID]; ok {
+			if _, exists := am.assets[target]; exists {
+				return target
+			}
+		}
+		return assetID
+	}
+
+	if assetInfo.IPAddress != "" {
+		ipID := "ip_" + assetInfo.IPAddress
+		if ipAsset, exists := am.assets[ipID]; exists {
+			am.collapseAsset(ipAsset, assetID, assetInfo.MACAddress)
+		}
+		am.aliases[ipID] = assetID
+	}
+
+	return assetID
+}
+
+// collapseAsset 将仅IP的资产并入MAC资产，MAC资产不存在时直接改用MAC资产的ID
+// 调用方需持有am.mutex写锁，存储中的旧记录异步删除
+func (am *AssetManager) collapseAsset(ipAsset *Asset, macID, mac string) {
+	ipID := ipAsset.ID
+	delete(am.assets, ipID)
+	am.index.remove(ipID)
+	am.counts.untrack(ipID)
+
+	if target, exists := am.assets[macID]; exists {
+		target.absorb(ipAsset)
+		am.counts.track(target)
+		log.Printf("合并重复资产: %s -> %s", ipID, macID)
+	} else {
+		ipAsset.mu.Lock()
+		ipAsset.ID = macID
+		ipAsset.MACAddress = mac
+		ipAsset.mu.Unlock()
+		am.assets[macID] = ipAsset
+		am.index.add(ipAsset)
+		am.counts.track(ipAsset)
+		log.Printf("资产获得MAC地址，更换ID: %s -> %s", ipID, macID)
+	}
+
+	go am.deleteStoredAsset(ipID)
+}
+
+// collapseDuplicates 合并存储中已存在的重复资产，加载现有资产后调用，调用方需持有am.mutex写锁
+// 每个IP归属最后一次出现该IP的MAC资产，同IP的仅IP资产并入其中
+func (am *AssetManager) collapseDuplicates() int {
+	owners := make(map[string]*Asset)
+	for id, asset := range am.assets {
+		if !strings.HasPrefix(id, "mac_") || asset.IPAddress == "" {
+			continue
+		}
+		if owner, ok := owners[asset.IPAddress]; !ok || asset.LastSeen.After(owner.LastSeen) {
+			owners[asset.IPAddress] = asset
+		}
+	}
+
+	merged := 0
+	for ip, owner := range owners {
+		ipID := "ip_" + ip
+		am.aliases[ipID] = owner.ID
+
+		if ipAsset, exists := am.assets[ipID]; exists {
+			am.collapseAsset(ipAsset, owner.ID, owner.MACAddress)
+			am.saveAsync(owner.ID)
+			merged++
+		}
+	}
+
+	return merged
+}
+
+// deleteStoredAsset 从存储中删除已被合并或清除的资产
+func (am *AssetManager) deleteStoredAsset(assetID string) {
+	if am.config.Storage.NoStore {
+		return
+	}
+
+	if err := am.storage.DeleteAsset(assetID); err != nil {
+		log.Printf("从存储中删除资产失败 %s: %v", assetID, err)
+	}
+}
+
+// resolveAssetID 通过别名表解析资产ID，调用方需持有am.mutex读锁
+func (am *AssetManager) resolveAssetID(assetID string) string {
+	if _, exists := am.assets[assetID]; exists {
+		return assetID
+	}
+	if target, ok := am.aliases[assetID]; ok {
+		return target
+	}
+	return assetID
+}
+
+// GetAssetByIP 按IP地址获取资产，IP已关联到MAC资产时返回MAC资产
+// 多个资产使用同一IP时优先返回别名指向的资产，其次返回最近出现的资产
+func (am *AssetManager) GetAssetByIP(ip string) (*Asset, bool) {
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+
+	owners := am.index.byIP(ip)
+	if len(owners) == 0 {
+		return nil, false
+	}
+
+	preferred := am.resolveAssetID("ip_" + ip)
+	var latest *Asset
+	var latestSeen time.Time
+	for _, asset := range owners {
+		if asset.ID == preferred {
+			return asset, true
+		}
+		asset.mu.RLock()
+		seen := asset.LastSeen
+		asset.mu.RUnlock()
+		if latest == nil || seen.After(latestSeen) {
+			latest, latestSeen = asset, seen
+		}
+	}
+	return latest, true
+}
+
+// GetAssetByMAC 按MAC地址获取资产
+func (am *AssetManager) GetAssetByMAC(mac string) (*Asset, bool) {
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+
+	return am.index.byMAC(mac)
+}
+
+// absorb 将同一设备的另一条资产记录合并到当前资产，当前资产的IP、MAC等标识保持不变
+func (a *Asset) absorb(other *Asset) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+
+	if other.FirstSeen.Before(a.FirstSeen) {
+		a.FirstSeen = other.FirstSeen
+	}
+	if other.LastSeen.After(a.LastSeen) {
+		a.LastSeen = other.LastSeen
+	}
+	a.IsActive = a.IsActive || other.IsActive
+
+	if change, changed := a.updateHostname(other.Hostname, other.HostnameSource); changed {
+		change.Timestamp = other.LastSeen
+		a.Changes = append(a.Changes, change)
+	}
+	if a.Vendor == "" {
+		a.Vendor = other.Vendor
+	}
+	if a.DeviceType == "" {
+		a.DeviceType = other.DeviceType
+		a.DeviceTypeSource = other.DeviceTypeSource
+		a.DeviceTypeConfidence = other.DeviceTypeConfidence
+	}
+	a.OSInfo, _ = mergeOSInfo(a.OSInfo, other.OSInfo)
+
+	a.OpenPorts = mergePorts(a.OpenPorts, other.OpenPorts)
+	a.Services = mergeServices(a.Services, other.Services)
+
+	// 协议信息的结构各不相同，只补充当前资产没有的协议
+	for name, value := range other.Protocols {
+		if _, exists := a.Protocols[name]; !exists {
+			if a.Protocols == nil {
+				a.Protocols = make(map[string]interface{})
+			}
+			a.Protocols[name] = value
+		}
+	}
+
+	// 合并后当前IP仍排在历史末尾
+	history := other.IPHistory
+	for _, ip := range a.IPHistory {
+		history = appendIPHistory(history, ip)
+	}
+	a.IPHistory = appendIPHistory(history, a.IPAddress)
+	for _, iface := range other.Interfaces {
+		a.Interfaces = addInterface(a.Interfaces, iface)
+	}
+
+	if other.Confidence > a.Confidence {
+		a.Confidence = other.Confidence
+	}
+	a.tentative = a.tentative && other.tentative
+	a.adoptProvenance(other)
+	a.adoptNotes(other)
+	a.adoptFindings(other)
+	a.adoptTraffic(other)
+
+	a.Changes = append(a.Changes, other.Changes...)
+	a.Changes = append(a.Changes, ChangeRecord{
+		Timestamp:   time.Now(),
+		ChangeType:  "asset_merge",
+		OldValue:    other.ID,
+		NewValue:    a.ID,
+		Description: fmt.Sprintf("合并了同一设备的资产记录 %s", other.ID),
+	})
+	a.LastUpdate = time.Now()
+}
@@ -0,0 +1,127 @@
+package assets
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// waitFor 在超时前反复检查条件，用于等待异步的保存和删除
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitStored 等待存储中的资产ID变为want
+func waitStored(t *testing.T, am *AssetManager, want ...string) {
+	t.Helper()
+
+	var got []string
+	waitFor(t, "stored assets", func() bool {
+		got = storedIDs(t, am)
+		return reflect.DeepEqual(got, want)
+	})
+}
+
+func TestFirstIPThenMAC(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Storage.NoStore = false
+	am := newTestManager(cfg)
+
+	const (
+		ip    = "10.0.0.5"
+		mac   = "00:11:22:33:44:55"
+		ipID  = "ip_" + ip
+		macID = "mac_" + mac
+	)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// 先只以IP出现（如经路由转发的流量），资产以IP为ID保存
+	am.UpdateAsset(&AssetInfo{IPAddress: ip, Hostname: "printer", Timestamp: now})
+	waitStored(t, am, ipID)
+
+	// 之后以IP和MAC出现，仅IP资产并入MAC资产
+	am.UpdateAsset(&AssetInfo{IPAddress: ip, MACAddress: mac, Timestamp: now.Add(time.Minute)})
+
+	if got := memoryIDs(am); !reflect.DeepEqual(got, []string{macID}) {
+		t.Fatalf("assets = %v, want [%s]", got, macID)
+	}
+
+	asset, ok := am.GetAsset(ipID)
+	if !ok || asset.ID != macID {
+		t.Fatalf("GetAsset(%s) = %v, %v, want %s", ipID, asset, ok, macID)
+	}
+	if asset.Hostname != "printer" || !asset.FirstSeen.Equal(now) {
+		t.Errorf("merged asset hostname = %q, first_seen = %v, want printer, %v", asset.Hostname, asset.FirstSeen, now)
+	}
+	if byIP, ok := am.GetAssetByIP(ip); !ok || byIP != asset {
+		t.Errorf("GetAssetByIP(%s) = %v, %v, want %s", ip, byIP, ok, macID)
+	}
+	if byMAC, ok := am.GetAssetByMAC(mac); !ok || byMAC != asset {
+		t.Errorf("GetAssetByMAC(%s) = %v, %v, want %s", mac, byMAC, ok, macID)
+	}
+
+	// 再次只以IP出现时通过别名更新MAC资产，不再产生仅IP资产
+	am.UpdateAsset(&AssetInfo{IPAddress: ip, Timestamp: now.Add(2 * time.Minute)})
+	if got := memoryIDs(am); !reflect.DeepEqual(got, []string{macID}) {
+		t.Errorf("assets after IP-only update = %v, want [%s]", got, macID)
+	}
+
+	// 存储中的仅IP记录被删除
+	waitStored(t, am, macID)
+}
+
+func TestCollapseStoredDuplicates(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Storage.NoStore = false
+	am := newTestManager(cfg)
+
+	seen := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	stored := []map[string]interface{}{
+		// 早期版本为同一设备保存的两条记录
+		{"id": "ip_10.0.0.7", "ip_address": "10.0.0.7", "hostname": "nas",
+			"first_seen": seen.Add(-time.Hour), "last_seen": seen.Add(-time.Hour)},
+		{"id": "mac_00:11:22:33:44:77", "ip_address": "10.0.0.7", "mac_address": "00:11:22:33:44:77",
+			"first_seen": seen, "last_seen": seen},
+		// 没有对应MAC资产的仅IP资产保持不变
+		{"id": "ip_10.0.0.8", "ip_address": "10.0.0.8", "first_seen": seen, "last_seen": seen},
+	}
+	for _, asset := range stored {
+		if err := am.storage.SaveAsset(asset); err != nil {
+			t.Fatalf("SaveAsset(%s) error = %v", asset["id"], err)
+		}
+	}
+
+	am.loadExistingAssets()
+
+	want := []string{"ip_10.0.0.8", "mac_00:11:22:33:44:77"}
+	if got := memoryIDs(am); !reflect.DeepEqual(got, want) {
+		t.Fatalf("assets = %v, want %v", got, want)
+	}
+	if asset, ok := am.GetAsset("ip_10.0.0.7"); !ok || asset.ID != "mac_00:11:22:33:44:77" {
+		t.Errorf("GetAsset(ip_10.0.0.7) = %v, %v, want mac_00:11:22:33:44:77", asset, ok)
+	}
+
+	waitStored(t, am, want...)
+
+	// 合并后的MAC资产重新保存，带有仅IP记录的主机名和更早的首次发现时间
+	var merged *Asset
+	waitFor(t, "merged asset saved", func() bool {
+		item, err := am.storage.GetAsset("mac_00:11:22:33:44:77")
+		if err != nil {
+			return false
+		}
+		merged, err = decodeStoredAsset(item)
+		return err == nil && merged.Hostname == "nas"
+	})
+	if !merged.FirstSeen.Equal(seen.Add(-time.Hour)) {
+		t.Errorf("merged first_seen = %v, want %v", merged.FirstSeen, seen.Add(-time.Hour))
+	}
+}
@@ -1,10 +1,20 @@
 package assets
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
+// maxIPHistory 每个资产保留的历史IP数量
+const maxIPHistory = 10
+
+// maxIGMPGroups 每个资产保留的组播组数量，超出时丢弃最早加入的组
+const maxIGMPGroups = 32
+
 // AssetInfo 资产信息结构
 type AssetInfo struct {
 	// 基本信息
@@ -16,16 +26,35 @@ type AssetInfo struct {
 	OSGuess    string    `json:"os_guess"`
 	Timestamp  time.Time `json:"timestamp"`
 
+	// 操作系统推测的检测方法，决定该推测的置信度
+	OSSource string `json:"os_source,omitempty"`
+
+	// 主机名来源协议，用于多个来源不一致时选择更可信的主机名
+	HostnameSource string `json:"hostname_source,omitempty"`
+
+	// 捕获到该数据包的网络接口，离线分析时为空
+	Interface string `json:"interface,omitempty"`
+
+	// 解析时根据MAC地址和厂商判断：虚拟化平台的OUI或厂商，设置了本地管理位的随机MAC
+	IsVirtual       bool `json:"is_virtual,omitempty"`
+	IsRandomizedMAC bool `json:"is_randomized_mac,omitempty"`
+
 	// 网络信息
 	OpenPorts []int                  `json:"open_ports"`
 	Services  map[string]interface{} `json:"services"`
-	Protocols map[string]interface{} `json:"protocols"`
+
+	// 资产作为客户端发起连接（SYN）时使用的外部服务，key为"443/tcp"这样的端口，值为服务名称
+	OutboundServices map[string]interface{} `json:"outbound_services,omitempty"`
+	Protocols        map[string]interface{} `json:"protocols"`
 
 	// 状态信息
 	FirstSeen  time.Time `json:"first_seen"`
 	LastSeen   time.Time `json:"last_seen"`
 	IsActive   bool      `json:"is_active"`
 	Confidence float64   `json:"confidence"` // 识别置信度
+
+	// 数据包在链路上的长度(字节)，用于统计资产的流量
+	Length int `json:"length,omitempty"`
 }
 
 // Asset 完整的资产信息
@@ -38,6 +67,15 @@ type Asset struct {
 	DeviceType string `json:"device_type"`
 	OSInfo     OSInfo `json:"os_info"`
 
+	// 决定设备类型的分类器及其置信度
+	DeviceTypeSource     string  `json:"device_type_source"`
+	DeviceTypeConfidence float64 `json:"device_type_confidence"`
+
+	// 主机名拆分出的短名称和域名，以及主机名的来源协议
+	ShortName      string `json:"short_name"`
+	Domain         string `json:"domain"`
+	HostnameSource string `json:"hostname_source"`
+
 	// 网络服务信息
 	OpenPorts []PortInfo    `json:"open_ports"`
 	Services  []ServiceInfo `json:"services"`
@@ -52,8 +90,49 @@ type Asset struct {
 	IsActive   bool      `json:"is_active"`
 	Confidence float64   `json:"confidence"`
 
+	// 风险评分(0-10)及评分依据
+	RiskScore   float64  `json:"risk_score"`
+	RiskFactors []string `json:"risk_factors"`
+
 	// 变更历史
-	Changes []ChangeRecord `json:"changes"`
+	Changes   []ChangeRecord `json:"changes"`
+	IPHistory []string       `json:"ip_history"` // 最近使用过的IP，按时间先后排列
+
+	// 观测到该资产的网络接口，用于区分DMZ、内网等网段
+	Interfaces []string `json:"interfaces"`
+
+	// IP不在监控网段内，仅在out_of_scope为tag时出现
+	OutOfScope bool `json:"out_of_scope,omitempty"`
+
+	// 按IP划分的内外网：internal为私有、ULA、链路本地等地址，external为公网地址，没有IP时为空
+	Scope string `json:"scope,omitempty"`
+
+	// MAC地址属于虚拟化平台的OUI或厂商为虚拟化平台；MAC地址设置了本地管理位（手机、笔记本的MAC随机化）
+	IsVirtual       bool `json:"is_virtual"`
+	IsRandomizedMAC bool `json:"is_randomized_mac"`
+
+	// 主机名、操作系统、厂商、设备类型当前取值的来源，key为字段名
+	Provenance map[string]FieldSource `json:"provenance,omitempty"`
+
+	// 被动观测到的安全暴露，如未启用认证的Redis、无需登录的Telnet
+	Findings []Finding `json:"findings,omitempty"`
+
+	// 运维人员通过API填写的备注及修改时间，Update不会修改
+	Notes        string     `json:"notes,omitempty"`
+	NotesUpdated *time.Time `json:"notes_updated,omitempty"`
+
+	// 资产作为源地址发出的数据包数和字节数，从首次发现起累计
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+
+	// 最近一小时按分钟累计的流量，用于按时间窗口统计top-talkers
+	traffic *trafficWindow
+
+	// 置信度尚未达到parser.min_confidence，不保存也不告警
+	tentative bool
+
+	// 已从管理器中删除（过期清理或手动删除），删除前发起的异步保存不再写入存储
+	removed bool
 
 	mu sync.RWMutex `json:"-"`
 }
@@ -70,11 +149,12 @@ type OSInfo struct {
 // PortInfo 端口信息
 type PortInfo struct {
 	Port      int       `json:"port"`
-	Protocol  string    `json:"protocol"` // tcp, udp
-	State     string    `json:"state"`    // open, closed, filtered
-	Service   string    `json:"service"`  // 服务名称
-	Version   string    `json:"version"`  // 服务版本
-	Banner    string    `json:"banner"`   // 服务横幅
+	Protocol  string    `json:"protocol"`  // tcp, udp
+	State     string    `json:"state"`     // open, closed, filtered
+	Direction string    `json:"direction"` // listening: 资产提供的服务端口
+	Service   string    `json:"service"`   // 服务名称
+	Version   string    `json:"version"`   // 服务版本
+	Banner    string    `json:"banner"`    // 服务横幅
 	FirstSeen time.Time `json:"first_seen"`
 	LastSeen  time.Time `json:"last_seen"`
 }
@@ -85,6 +165,7 @@ type ServiceInfo struct {
 	Version   string                 `json:"version"`
 	Port      int                    `json:"port"`
 	Protocol  string                 `json:"protocol"`
+	Direction string                 `json:"direction"` // listening: 资产提供的服务；outbound: 资产作为客户端使用的服务
 	Banner    string                 `json:"banner"`
 	Headers   map[string]interface{} `json:"headers"`
 	FirstSeen time.Time              `json:"first_seen"`
@@ -102,27 +183,48 @@ type ChangeRecord struct {
 
 // NewAsset 创建新资产
 func NewAsset(assetInfo *AssetInfo) *Asset {
-	now := time.Now()
+	seen := seenTime(assetInfo)
+
+	// 只有MAC地址的观测（如只发送ARP的二层设备）没有分类依据
+	classification := classifyDeviceType(assetInfo)
+	if classification.DeviceType == "" {
+		classification.DeviceType = unknownDeviceType
+	}
 
 	asset := &Asset{
 		ID:         generateAssetID(assetInfo),
 		IPAddress:  assetInfo.IPAddress,
 		MACAddress: assetInfo.MACAddress,
-		Hostname:   assetInfo.Hostname,
+		Scope:      ipScope(assetInfo.IPAddress),
 		Vendor:     assetInfo.Vendor,
-		DeviceType: classifyDeviceType(assetInfo),
+		DeviceType: classification.DeviceType,
 		OSInfo:     extractOSInfo(assetInfo),
-		OpenPorts:  convertPorts(assetInfo.OpenPorts),
-		Services:   convertServices(assetInfo.Services),
-		Protocols:  assetInfo.Protocols,
-		FirstSeen:  now,
-		LastSeen:   now,
-		LastUpdate: now,
+
+		DeviceTypeSource:     classification.Source,
+		DeviceTypeConfidence: classification.Confidence,
+
+		OpenPorts:  convertPorts(assetInfo.OpenPorts, seen),
+		Services:   mergeServices(convertServices(assetInfo.Services, "listening", seen), convertServices(assetInfo.OutboundServices, "outbound", seen)),
+		Protocols:  mergeProtocols(nil, assetInfo.Protocols, seen),
+		FirstSeen:  seen,
+		LastSeen:   seen,
+		LastUpdate: time.Now(),
 		IsActive:   true,
 		Confidence: calculateConfidence(assetInfo),
 		Changes:    []ChangeRecord{},
+		IPHistory:  appendIPHistory(nil, assetInfo.IPAddress),
+		Interfaces: addInterface(nil, assetInfo.Interface),
+
+		IsVirtual:       assetInfo.IsVirtual,
+		IsRandomizedMAC: assetInfo.IsRandomizedMAC,
 	}
 
+	if hostname := NormalizeHostname(assetInfo.Hostname); hostname != "" {
+		asset.setHostname(hostname, assetInfo.HostnameSource)
+	}
+	asset.trackProvenance(osGuessSource(assetInfo), seen)
+	asset.countTraffic(assetInfo)
+
 	return asset
 }
 
@@ -131,9 +233,11 @@ func (a *Asset) Update(assetInfo *AssetInfo) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	now := time.Now()
+	now := seenTime(assetInfo)
 	changes := []ChangeRecord{}
 
+	a.countTraffic(assetInfo)
+
 	// 检查IP地址变更
 	if assetInfo.IPAddress != "" && assetInfo.IPAddress != a.IPAddress {
 		changes = append(changes, ChangeRecord{
@@ -144,24 +248,30 @@ func (a *Asset) Update(assetInfo *AssetInfo) {
 			Description: "IP地址发生变更",
 		})
 		a.IPAddress = assetInfo.IPAddress
+		a.IPHistory = appendIPHistory(a.IPHistory, assetInfo.IPAddress)
+		a.Scope = ipScope(a.IPAddress)
 	}
 
-	// 检查主机名变更
-	if assetInfo.Hostname != "" && assetInfo.Hostname != a.Hostname {
-		changes = append(changes, ChangeRecord{
-			Timestamp:   now,
-			ChangeType:  "hostname_change",
-			OldValue:    a.Hostname,
-			NewValue:    assetInfo.Hostname,
-			Description: "主机名发生变更",
-		})
-		a.Hostname = assetInfo.Hostname
+	a.Interfaces = addInterface(a.Interfaces, assetInfo.Interface)
+
+	// 先只以IP发现的资产获得MAC后补充厂商信息
+	if a.Vendor == "" && assetInfo.Vendor != "" {
+		a.Vendor = assetInfo.Vendor
+	}
+	a.IsVirtual = a.IsVirtual || assetInfo.IsVirtual
+	a.IsRandomizedMAC = a.IsRandomizedMAC || assetInfo.IsRandomizedMAC
+
+	// 检查主机名变更，仅大小写或是否带域名不同时不记录
+	if change, changed := a.updateHostname(assetInfo.Hostname, assetInfo.HostnameSource); changed {
+		change.Timestamp = now
+		changes = append(changes, change)
 	}
 
-	// 更新端口信息
+	// 更新端口信息，出现新端口或已关闭的端口重新出现时记录变更
 	if len(assetInfo.OpenPorts) > 0 {
-		newPorts := convertPorts(assetInfo.OpenPorts)
-		if !equalPorts(a.OpenPorts, newPorts) {
+		newPorts := convertPorts(assetInfo.OpenPorts, now)
+		merged := mergePorts(a.OpenPorts, newPorts)
+		if !equalPorts(a.OpenPorts, merged) {
 			changes = append(changes, ChangeRecord{
 				Timestamp:   now,
 				ChangeType:  "ports_change",
@@ -169,25 +279,29 @@ func (a *Asset) Update(assetInfo *AssetInfo) {
 				NewValue:    newPorts,
 				Description: "开放端口发生变更",
 			})
-			a.OpenPorts = mergePorts(a.OpenPorts, newPorts)
 		}
+		a.OpenPorts = merged
 	}
 
-	// 更新服务信息
+	// 更新服务信息，包括资产提供的服务和作为客户端使用的服务
 	if len(assetInfo.Services) > 0 {
-		newServices := convertServices(assetInfo.Services)
+		newServices := convertServices(assetInfo.Services, "listening", now)
 		a.Services = mergeServices(a.Services, newServices)
 	}
+	if len(assetInfo.OutboundServices) > 0 {
+		a.Services = mergeServices(a.Services, convertServices(assetInfo.OutboundServices, "outbound", now))
+	}
 
 	// 更新协议信息
 	if len(assetInfo.Protocols) > 0 {
-		a.Protocols = mergeProtocols(a.Protocols, assetInfo.Protocols)
+		a.Protocols = mergeProtocols(a.Protocols, assetInfo.Protocols, now)
 	}
 
 	// 更新操作系统信息
 	if assetInfo.OSGuess != "" {
 		newOSInfo := extractOSInfo(assetInfo)
-		if newOSInfo.Family != "" && newOSInfo.Family != a.OSInfo.Family {
+		merged, changed := mergeOSInfo(a.OSInfo, newOSInfo)
+		if changed {
 			changes = append(changes, ChangeRecord{
 				Timestamp:   now,
 				ChangeType:  "os_change",
@@ -195,32 +309,87 @@ func (a *Asset) Update(assetInfo *AssetInfo) {
 				NewValue:    newOSInfo,
 				Description: "操作系统信息发生变更",
 			})
-			a.OSInfo = mergeOSInfo(a.OSInfo, newOSInfo)
 		}
+		a.OSInfo = merged
 	}
 
-	// 更新设备类型
-	if newDeviceType := classifyDeviceType(assetInfo); newDeviceType != "" && newDeviceType != a.DeviceType {
-		changes = append(changes, ChangeRecord{
-			Timestamp:   now,
-			ChangeType:  "device_type_change",
-			OldValue:    a.DeviceType,
-			NewValue:    newDeviceType,
-			Description: "设备类型发生变更",
-		})
-		a.DeviceType = newDeviceType
+	// 更新设备类型，曾通过STUN识别为VoIP设备的资产不会被其他流量改判
+	classification := classifyDeviceType(assetInfo)
+	if isVoIPDevice(a.Protocols) && classification.DeviceType != voipDeviceType {
+		classification = DeviceClassification{DeviceType: voipDeviceType, Source: "stun", Confidence: 0.8}
+	}
+	// 单次观测只有置信度不低于当前结果或来自同一分类器时才替换，避免低置信度的分类覆盖可靠的判断
+	if classification.DeviceType != "" &&
+		(classification.Confidence >= a.DeviceTypeConfidence || classification.Source == a.DeviceTypeSource) {
+		if classification.DeviceType != a.DeviceType {
+			changes = append(changes, ChangeRecord{
+				Timestamp:   now,
+				ChangeType:  "device_type_change",
+				OldValue:    a.DeviceType,
+				NewValue:    classification.DeviceType,
+				Description: "设备类型发生变更",
+			})
+			a.DeviceType = classification.DeviceType
+		}
+		a.DeviceTypeSource = classification.Source
+		a.DeviceTypeConfidence = classification.Confidence
 	}
 
 	// 添加变更记录
 	a.Changes = append(a.Changes, changes...)
 
-	// 更新时间戳
-	a.LastSeen = now
-	a.LastUpdate = now
+	// 更新时间戳，多个工作协程处理时数据包可能乱序到达
+	if now.After(a.LastSeen) {
+		a.LastSeen = now
+	}
+	if now.Before(a.FirstSeen) {
+		a.FirstSeen = now
+	}
+	a.LastUpdate = time.Now()
 	a.IsActive = true
+	a.trackProvenance(osGuessSource(assetInfo), now)
+
+	// 按累计的信息重新计算置信度
+	a.Confidence = a.accumulatedConfidence()
+}
+
+// expirePorts 将超过window未再观测到的开放端口标记为closed并记录ports_change，返回是否有端口被关闭
+// 被动发现只能看到有流量的端口，端口重新出现时由mergePorts恢复为open
+func (a *Asset) expirePorts(now time.Time, window time.Duration) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cutoff := now.Add(-window)
+	var closed []int
+	for _, port := range a.OpenPorts {
+		if port.State == "open" && port.LastSeen.Before(cutoff) {
+			closed = append(closed, port.Port)
+		}
+	}
+	if len(closed) == 0 {
+		return false
+	}
+	sort.Ints(closed)
+
+	old := a.OpenPorts
+	ports := make([]PortInfo, len(old))
+	copy(ports, old)
+	for i := range ports {
+		if ports[i].State == "open" && ports[i].LastSeen.Before(cutoff) {
+			ports[i].State = "closed"
+		}
+	}
+	a.OpenPorts = ports
 
-	// 重新计算置信度
-	a.Confidence = calculateConfidence(assetInfo)
+	a.Changes = append(a.Changes, ChangeRecord{
+		Timestamp:   now,
+		ChangeType:  "ports_change",
+		OldValue:    old,
+		NewValue:    ports,
+		Description: fmt.Sprintf("端口 %v 超过 %v 未再出现，标记为关闭", closed, window),
+	})
+	a.LastUpdate = time.Now()
+	return true
 }
 
 // SetInactive 设置资产为非活跃状态
@@ -242,26 +411,102 @@ func (a *Asset) SetInactive() {
 	}
 }
 
-// GetSummary 获取资产摘要信息
+// recordMACChange 记录资产的IP原先由另一个MAC声明
+func (a *Asset) recordMACChange(oldMAC string, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.Changes = append(a.Changes, ChangeRecord{
+		Timestamp:   now,
+		ChangeType:  "mac_change",
+		OldValue:    oldMAC,
+		NewValue:    a.MACAddress,
+		Description: "IP地址 " + a.IPAddress + " 对应的MAC地址发生变更",
+	})
+}
+
+// setOutOfScope 按当前IP更新是否在监控网段外
+func (a *Asset) setOutOfScope(outOfScope bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.OutOfScope = outOfScope
+}
+
+// GetSummary 获取资产摘要信息，不包含协议详情、变更历史及端口和服务的详细信息
 func (a *Asset) GetSummary() map[string]interface{} {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
+	// 端口只列出未关闭的"端口/协议"
+	ports := make([]string, 0, len(a.OpenPorts))
+	for _, p := range a.OpenPorts {
+		if p.State != "closed" {
+			ports = append(ports, fmt.Sprintf("%d/%s", p.Port, p.Protocol))
+		}
+	}
+
 	return map[string]interface{}{
-		"id":             a.ID,
-		"ip_address":     a.IPAddress,
-		"mac_address":    a.MACAddress,
-		"hostname":       a.Hostname,
-		"vendor":         a.Vendor,
-		"device_type":    a.DeviceType,
-		"os_family":      a.OSInfo.Family,
-		"ports_count":    len(a.OpenPorts),
-		"services_count": len(a.Services),
-		"first_seen":     a.FirstSeen,
-		"last_seen":      a.LastSeen,
-		"is_active":      a.IsActive,
-		"confidence":     a.Confidence,
+		"id":                      a.ID,
+		"ip_address":              a.IPAddress,
+		"mac_address":             a.MACAddress,
+		"scope":                   a.Scope,
+		"is_virtual":              a.IsVirtual,
+		"is_randomized_mac":       a.IsRandomizedMAC,
+		"hostname":                a.Hostname,
+		"vendor":                  a.Vendor,
+		"device_type":             a.DeviceType,
+		"device_type_source":      a.DeviceTypeSource,
+		"ip_history":              append([]string(nil), a.IPHistory...),
+		"interfaces":              append([]string(nil), a.Interfaces...),
+		"os_family":               a.OSInfo.Family,
+		"ports":                   ports,
+		"ports_count":             len(a.OpenPorts),
+		"services_count":          a.countServices("listening"),
+		"outbound_services_count": a.countServices("outbound"),
+		"first_seen":              a.FirstSeen,
+		"last_seen":               a.LastSeen,
+		"is_active":               a.IsActive,
+		"confidence":              a.Confidence,
+		"risk_score":              a.RiskScore,
+		"risk_factors":            append([]string(nil), a.RiskFactors...),
+		"findings":                append([]Finding(nil), a.Findings...),
+		"wpad_query":              a.hasProtocol("wpad"),
+		"provenance":              a.copyProvenance(),
+		"notes":                   a.Notes,
+	}
+}
+
+// hasProtocol 检查资产是否记录了指定协议的信息，调用方需持有锁
+func (a *Asset) hasProtocol(name string) bool {
+	_, ok := a.Protocols[name]
+	return ok
+}
+
+// HasOpenPort 检查资产是否开放了指定端口，proto为空时匹配任意协议，已标记为关闭的端口不算开放
+func (a *Asset) HasOpenPort(port int, proto string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for _, p := range a.OpenPorts {
+		if p.Port == port && (proto == "" || p.Protocol == proto) && p.State != "closed" {
+			return true
+		}
 	}
+
+	return false
+}
+
+// assetJSON 与Asset字段相同但不带MarshalJSON方法，避免递归调用
+type assetJSON Asset
+
+// MarshalJSON 在读锁保护下序列化资产，避免与并发的Update产生数据竞争
+// 存储后端和API均通过json.Marshal序列化*Asset，因此都会经过此方法
+func (a *Asset) MarshalJSON() ([]byte, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return json.Marshal((*assetJSON)(a))
 }
 
 // 辅助函数
@@ -276,95 +521,105 @@ func generateAssetID(assetInfo *AssetInfo) string {
 	return "unknown_" + time.Now().Format("20060102150405")
 }
 
-func classifyDeviceType(assetInfo *AssetInfo) string {
-	// 基于厂商信息判断设备类型
-	switch assetInfo.Vendor {
-	case "VMware":
-		return "虚拟机"
-	case "VirtualBox", "QEMU/KVM", "Microsoft Hyper-V", "Xen":
-		return "虚拟机"
-	}
+// unknownDeviceType 没有任何分类依据的资产的设备类型
+const unknownDeviceType = "未知设备"
 
-	// 基于开放端口判断设备类型
-	hasWebPorts := false
-	hasServerPorts := false
+// voipDeviceType STUN识别出的VoIP话机和会议终端的设备类型
+const voipDeviceType = "VoIP设备"
+
+// iotDeviceType Telnet横幅识别出的摄像头、录像机等嵌入式设备的设备类型
+const iotDeviceType = "IoT设备"
+
+// voipSoftwareKeywords STUN SOFTWARE属性中表明VoIP话机或会议终端的关键字（小写）
+// 浏览器的WebRTC通话同样使用STUN，因此仅凭STUN报文不判定为VoIP设备
+var voipSoftwareKeywords = []string{
+	"polycom", "poly ", "yealink", "grandstream", "snom", "cisco", "avaya",
+	"mitel", "fanvil", "htek", "gigaset", "obihai", "linphone", "zoom rooms",
+}
 
-	for _, port := range assetInfo.OpenPorts {
-		switch port {
-		case 80, 443, 8080, 8443:
-			hasWebPorts = true
-		case 22, 23, 3389:
-			hasServerPorts = true
-		case 21, 25, 53, 110, 143:
-			hasServerPorts = true
+// isVoIPDevice 根据STUN报文中的SOFTWARE属性判断是否为VoIP设备
+func isVoIPDevice(protocols map[string]interface{}) bool {
+	stunInfo, ok := protocols["stun"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	software, _ := stunInfo["software"].(string)
+	software = strings.ToLower(software)
+	for _, keyword := range voipSoftwareKeywords {
+		if strings.Contains(software, keyword) {
+			return true
 		}
 	}
+	return false
+}
 
-	if hasWebPorts && hasServerPorts {
-		return "服务器"
-	} else if hasWebPorts {
-		return "Web设备"
-	} else if hasServerPorts {
-		return "服务器"
-	}
+// osDetectionConfidence 各操作系统检测方法的置信度
+// TTL只能粗略区分系统类别，User-Agent可能被伪造，DHCP厂商标识由系统DHCP客户端发送
+var osDetectionConfidence = map[string]float64{
+	"ttl_analysis":      0.3,
+	"user_agent":        0.6,
+	"dhcp_vendor_class": 0.8,
+}
 
-	// 基于操作系统判断
-	switch assetInfo.OSGuess {
-	case "Linux/Unix":
-		return "服务器"
-	case "Windows":
-		return "工作站"
-	case "Cisco/Network Device":
-		return "网络设备"
+// OSDetectionConfidence 返回检测方法的置信度，未知方法视为与TTL推测相同
+func OSDetectionConfidence(source string) float64 {
+	if confidence, ok := osDetectionConfidence[source]; ok {
+		return confidence
 	}
+	return osDetectionConfidence["ttl_analysis"]
+}
 
-	return "未知设备"
+// osGuessSource 操作系统推测的检测方法，未注明时为TTL推测，没有推测时返回空
+func osGuessSource(assetInfo *AssetInfo) string {
+	if assetInfo.OSGuess == "" {
+		return ""
+	}
+	if assetInfo.OSSource == "" {
+		return "ttl_analysis"
+	}
+	return assetInfo.OSSource
 }
 
 func extractOSInfo(assetInfo *AssetInfo) OSInfo {
 	osInfo := OSInfo{
-		Family:     assetInfo.OSGuess,
-		Detection:  []string{},
-		Confidence: 0.5,
+		Family:    assetInfo.OSGuess,
+		Detection: []string{},
 	}
-
-	// 从不同来源提取操作系统信息
-	if assetInfo.OSGuess != "" {
-		osInfo.Detection = append(osInfo.Detection, "ttl_analysis")
+	if assetInfo.OSGuess == "" {
+		return osInfo
 	}
 
-	// 从HTTP User-Agent提取
-	if protocols, ok := assetInfo.Protocols["http"]; ok {
-		if headers, ok := protocols.(map[string]interface{}); ok {
-			if _, ok := headers["user-agent"]; ok {
-				osInfo.Detection = append(osInfo.Detection, "user_agent")
-				// 可以进一步解析User-Agent获取更详细的版本信息
-			}
-		}
-	}
+	source := osGuessSource(assetInfo)
+	osInfo.Detection = append(osInfo.Detection, source)
+	osInfo.Confidence = OSDetectionConfidence(source)
 
 	// 从DHCP信息提取
-	if protocols, ok := assetInfo.Protocols["dhcp"]; ok {
-		if dhcp, ok := protocols.(map[string]interface{}); ok {
-			if vendorClass, ok := dhcp["vendor_class"]; ok {
-				osInfo.Detection = append(osInfo.Detection, "dhcp_vendor_class")
-				osInfo.Version = vendorClass.(string)
-			}
+	if source == "dhcp_vendor_class" {
+		if dhcp, ok := assetInfo.Protocols["dhcp"].(map[string]interface{}); ok {
+			osInfo.Version, _ = dhcp["vendor_class"].(string)
 		}
 	}
 
 	return osInfo
 }
 
-func convertPorts(ports []int) []PortInfo {
+// seenTime 获取资产信息的观测时间，离线分析时为数据包的捕获时间
+func seenTime(assetInfo *AssetInfo) time.Time {
+	if assetInfo.Timestamp.IsZero() {
+		return time.Now()
+	}
+	return assetInfo.Timestamp
+}
+
+func convertPorts(ports []int, now time.Time) []PortInfo {
 	result := make([]PortInfo, 0, len(ports))
-	now := time.Now()
 
 	for _, port := range ports {
 		result = append(result, PortInfo{
 			Port:      port,
 			Protocol:  "tcp",
 			State:     "open",
+			Direction: "listening",
 			FirstSeen: now,
 			LastSeen:  now,
 		})
@@ -373,19 +628,26 @@ func convertPorts(ports []int) []PortInfo {
 	return result
 }
 
-func convertServices(services map[string]interface{}) []ServiceInfo {
+// convertServices 转换解析器记录的服务，direction为listening（资产提供的服务）或outbound（资产使用的服务）
+func convertServices(services map[string]interface{}, direction string, now time.Time) []ServiceInfo {
 	result := make([]ServiceInfo, 0, len(services))
-	now := time.Now()
 
 	for name, info := range services {
 		serviceInfo := ServiceInfo{
 			Name:      name,
+			Direction: direction,
 			FirstSeen: now,
 			LastSeen:  now,
 		}
 
-		if infoStr, ok := info.(string); ok {
-			serviceInfo.Version = infoStr
+		switch v := info.(type) {
+		case string:
+			serviceInfo.Version = v
+		case map[string]interface{}:
+			// 带有协议头部等详细信息的服务
+			serviceInfo.Version, _ = v["version"].(string)
+			serviceInfo.Banner, _ = v["banner"].(string)
+			serviceInfo.Headers, _ = v["headers"].(map[string]interface{})
 		}
 
 		result = append(result, serviceInfo)
@@ -395,33 +657,42 @@ func convertServices(services map[string]interface{}) []ServiceInfo {
 }
 
 func calculateConfidence(assetInfo *AssetInfo) float64 {
-	confidence := 0.0
+	return confidenceScore(assetInfo.MACAddress, assetInfo.IPAddress, assetInfo.Hostname, assetInfo.OSGuess,
+		len(assetInfo.OpenPorts), len(assetInfo.Services))
+}
 
-	// 基于可用信息计算置信度
-	if assetInfo.MACAddress != "" {
-		confidence += 0.3
-	}
-	if assetInfo.IPAddress != "" {
-		confidence += 0.2
-	}
-	if assetInfo.Hostname != "" {
-		confidence += 0.2
+// appendIPHistory 将IP追加到历史末尾，已存在的IP会移到末尾，超出上限时丢弃最旧的
+func appendIPHistory(history []string, ip string) []string {
+	if ip == "" {
+		return history
 	}
-	if len(assetInfo.OpenPorts) > 0 {
-		confidence += 0.1
-	}
-	if len(assetInfo.Services) > 0 {
-		confidence += 0.1
-	}
-	if assetInfo.OSGuess != "" {
-		confidence += 0.1
+
+	result := make([]string, 0, len(history)+1)
+	for _, existing := range history {
+		if existing != ip {
+			result = append(result, existing)
+		}
 	}
+	result = append(result, ip)
 
-	if confidence > 1.0 {
-		confidence = 1.0
+	if len(result) > maxIPHistory {
+		result = result[len(result)-maxIPHistory:]
 	}
 
-	return confidence
+	return result
+}
+
+// addInterface 将网络接口加入集合，已存在时不重复添加
+func addInterface(interfaces []string, iface string) []string {
+	if iface == "" {
+		return interfaces
+	}
+	for _, existing := range interfaces {
+		if existing == iface {
+			return interfaces
+		}
+	}
+	return append(append([]string(nil), interfaces...), iface)
 }
 
 // 合并函数
@@ -430,13 +701,13 @@ func equalPorts(a, b []PortInfo) bool {
 		return false
 	}
 
-	portMapA := make(map[int]bool)
+	portMapA := make(map[int]string)
 	for _, port := range a {
-		portMapA[port.Port] = true
+		portMapA[port.Port] = port.State
 	}
 
 	for _, port := range b {
-		if !portMapA[port.Port] {
+		if state, ok := portMapA[port.Port]; !ok || state != port.State {
 			return false
 		}
 	}
@@ -452,10 +723,13 @@ func mergePorts(existing, new []PortInfo) []PortInfo {
 		portMap[port.Port] = port
 	}
 
-	// 合并新端口
+	// 合并新端口，端口状态以最近一次观测为准，已关闭的端口重新出现时恢复为open
 	for _, port := range new {
 		if existingPort, exists := portMap[port.Port]; exists {
-			existingPort.LastSeen = port.LastSeen
+			if port.LastSeen.After(existingPort.LastSeen) {
+				existingPort.LastSeen = port.LastSeen
+				existingPort.State = port.State
+			}
 			portMap[port.Port] = existingPort
 		} else {
 			portMap[port.Port] = port
@@ -471,24 +745,47 @@ func mergePorts(existing, new []PortInfo) []PortInfo {
 	return result
 }
 
+// serviceKey 合并服务时使用的键，同一端口既提供又使用时分别记录
+func serviceKey(service ServiceInfo) string {
+	if service.Direction == "outbound" {
+		return service.Name + " outbound"
+	}
+	return service.Name
+}
+
 func mergeServices(existing, new []ServiceInfo) []ServiceInfo {
 	serviceMap := make(map[string]ServiceInfo)
 
 	// 添加现有服务
 	for _, service := range existing {
-		serviceMap[service.Name] = service
+		serviceMap[serviceKey(service)] = service
 	}
 
 	// 合并新服务
 	for _, service := range new {
-		if existingService, exists := serviceMap[service.Name]; exists {
-			existingService.LastSeen = service.LastSeen
+		if existingService, exists := serviceMap[serviceKey(service)]; exists {
+			if service.LastSeen.After(existingService.LastSeen) {
+				existingService.LastSeen = service.LastSeen
+			}
 			if service.Version != "" {
 				existingService.Version = service.Version
 			}
-			serviceMap[service.Name] = existingService
+			if service.Banner != "" {
+				existingService.Banner = service.Banner
+			}
+			if len(service.Headers) > 0 {
+				merged := make(map[string]interface{}, len(existingService.Headers)+len(service.Headers))
+				for k, v := range existingService.Headers {
+					merged[k] = v
+				}
+				for k, v := range service.Headers {
+					merged[k] = v
+				}
+				existingService.Headers = merged
+			}
+			serviceMap[serviceKey(service)] = existingService
 		} else {
-			serviceMap[service.Name] = service
+			serviceMap[serviceKey(service)] = service
 		}
 	}
 
@@ -501,27 +798,148 @@ func mergeServices(existing, new []ServiceInfo) []ServiceInfo {
 	return result
 }
 
-func mergeProtocols(existing, new map[string]interface{}) map[string]interface{} {
+func mergeProtocols(existing, new map[string]interface{}, now time.Time) map[string]interface{} {
 	if existing == nil {
 		existing = make(map[string]interface{})
 	}
 
 	for key, value := range new {
+		switch key {
+		case "igmp":
+			value = mergeIGMP(existing[key], value)
+		case "dns":
+			value = mergeDNS(existing[key], value, now)
+		case "telnet":
+			value = mergeTelnet(existing[key], value)
+		case "ipv4":
+			value = mergeIPv4(existing[key], value)
+		}
 		existing[key] = value
 	}
 
 	return existing
 }
 
-func mergeOSInfo(existing, new OSInfo) OSInfo {
-	if new.Family != "" {
-		existing.Family = new.Family
+// mergeIPv4 累计资产发送的分片数量并保留最小的MTU提示，其他字段以最新报文为准
+func mergeIPv4(existing, new interface{}) interface{} {
+	newInfo, ok := new.(map[string]interface{})
+	if !ok {
+		return new
+	}
+	oldInfo, _ := existing.(map[string]interface{})
+
+	fragments := toInt(oldInfo["fragments_seen"])
+	if newInfo["fragmented"] == true {
+		fragments++
+	}
+	if fragments > 0 {
+		newInfo["fragments_seen"] = fragments
+	}
+
+	mtu := toInt(newInfo["mtu_hint"])
+	if old := toInt(oldInfo["mtu_hint"]); old > 0 && (mtu == 0 || old < mtu) {
+		mtu = old
+	}
+	if mtu > 0 {
+		newInfo["mtu_hint"] = mtu
+	}
+
+	return newInfo
+}
+
+// mergeTelnet 只有选项协商、没有横幅的报文不覆盖已记录的横幅
+func mergeTelnet(existing, new interface{}) interface{} {
+	newInfo, ok := new.(map[string]interface{})
+	if !ok {
+		return new
+	}
+	oldInfo, _ := existing.(map[string]interface{})
+	if _, has := newInfo["banner"]; !has && oldInfo != nil {
+		if banner, ok := oldInfo["banner"]; ok {
+			newInfo["banner"] = banner
+		}
+	}
+	return newInfo
+}
+
+// mergeIGMP 累积主机加入的组播组，移除已离开的组
+func mergeIGMP(existing, new interface{}) interface{} {
+	newInfo, ok := new.(map[string]interface{})
+	if !ok {
+		return new
+	}
+	oldInfo, _ := existing.(map[string]interface{})
+
+	left := make(map[string]bool)
+	for _, group := range stringList(newInfo["left"]) {
+		left[group] = true
+	}
+
+	groups := []string{}
+	seen := make(map[string]bool)
+	for _, group := range append(stringList(oldInfo["groups"]), stringList(newInfo["groups"])...) {
+		if left[group] || seen[group] {
+			continue
+		}
+		seen[group] = true
+		groups = append(groups, group)
+	}
+	if len(groups) > maxIGMPGroups {
+		groups = groups[len(groups)-maxIGMPGroups:]
+	}
+
+	merged := make(map[string]interface{}, len(newInfo))
+	for key, value := range newInfo {
+		if key != "left" {
+			merged[key] = value
+		}
+	}
+	if oldInfo["querier"] == true {
+		merged["querier"] = true
+	}
+	merged["groups"] = groups
+	return merged
+}
+
+// stringList 将字符串列表或从存储加载的JSON数组转换为[]string
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
 	}
-	if new.Version != "" {
-		existing.Version = new.Version
+	return nil
+}
+
+// mergeOSInfo 合并新的操作系统判断，返回合并结果及操作系统类别是否变更
+// 类别不一致时只有新判断的置信度更高才会替换，避免低置信度的TTL推测覆盖可靠的检测结果
+func mergeOSInfo(existing, new OSInfo) (OSInfo, bool) {
+	if new.Family == "" {
+		return existing, false
 	}
-	if new.Kernel != "" {
-		existing.Kernel = new.Kernel
+
+	if existing.Family != "" && new.Family != existing.Family {
+		if new.Confidence <= existing.Confidence {
+			return existing, false
+		}
+		return new, true
+	}
+
+	existing.Family = new.Family
+	if new.Confidence >= existing.Confidence {
+		if new.Version != "" {
+			existing.Version = new.Version
+		}
+		if new.Kernel != "" {
+			existing.Kernel = new.Kernel
+		}
 	}
 
 	// 合并检测方法
@@ -537,6 +955,7 @@ func mergeOSInfo(existing, new OSInfo) OSInfo {
 	for method := range detectionMap {
 		detection = append(detection, method)
 	}
+	sort.Strings(detection)
 	existing.Detection = detection
 
 	// 使用较高的置信度
@@ -544,5 +963,5 @@ func mergeOSInfo(existing, new OSInfo) OSInfo {
 		existing.Confidence = new.Confidence
 	}
 
-	return existing
+	return existing, false
 }
@@ -0,0 +1,262 @@
+package assets
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testMAC 测试资产使用的MAC地址
+const testMAC = "00:1a:2b:3c:4d:5e"
+
+func TestIPHistory(t *testing.T) {
+	many := make([]string, 0, maxIPHistory+3)
+	for i := 1; i <= maxIPHistory+3; i++ {
+		many = append(many, fmt.Sprintf("10.0.0.%d", i))
+	}
+
+	tests := []struct {
+		name string
+		ips  []string
+		want []string
+	}{
+		{
+			name: "single ip",
+			ips:  []string{"10.0.0.1"},
+			want: []string{"10.0.0.1"},
+		},
+		{
+			name: "roaming between wired and wireless",
+			ips:  []string{"10.0.0.1", "10.0.1.1", "10.0.0.1", "10.0.1.1"},
+			want: []string{"10.0.0.1", "10.0.1.1"},
+		},
+		{
+			name: "reused ip moves to end",
+			ips:  []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.1"},
+			want: []string{"10.0.0.2", "10.0.0.3", "10.0.0.1"},
+		},
+		{
+			name: "capped keeps most recent",
+			ips:  many,
+			want: many[len(many)-maxIPHistory:],
+		},
+		{
+			name: "packets without ip ignored",
+			ips:  []string{"10.0.0.1", "", "10.0.0.2"},
+			want: []string{"10.0.0.1", "10.0.0.2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asset := NewAsset(&AssetInfo{IPAddress: tt.ips[0], MACAddress: testMAC})
+			for _, ip := range tt.ips[1:] {
+				asset.Update(&AssetInfo{IPAddress: ip, MACAddress: testMAC})
+			}
+
+			if !reflect.DeepEqual(asset.IPHistory, tt.want) {
+				t.Errorf("IPHistory = %v, want %v", asset.IPHistory, tt.want)
+			}
+			if got := asset.GetSummary()["ip_history"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("summary ip_history = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPHistoryRecordsChanges(t *testing.T) {
+	asset := NewAsset(&AssetInfo{IPAddress: "10.0.0.1", MACAddress: testMAC, Timestamp: time.Unix(100, 0)})
+	asset.Update(&AssetInfo{IPAddress: "10.0.0.2", MACAddress: testMAC, Timestamp: time.Unix(200, 0)})
+	asset.Update(&AssetInfo{IPAddress: "10.0.0.2", MACAddress: testMAC, Timestamp: time.Unix(300, 0)})
+
+	changes := 0
+	for _, change := range asset.Changes {
+		if change.ChangeType == "ip_change" {
+			changes++
+		}
+	}
+	if changes != 1 {
+		t.Errorf("ip_change records = %d, want 1", changes)
+	}
+}
+
+func TestUpdateDeviceTypeConfidence(t *testing.T) {
+	tests := []struct {
+		name           string
+		deviceType     string
+		source         string
+		confidence     float64
+		observation    AssetInfo
+		wantType       string
+		wantSource     string
+		wantConfidence float64
+	}{
+		{
+			name:       "unknown replaced by any classification",
+			deviceType: unknownDeviceType, source: "", confidence: 0,
+			observation: AssetInfo{OSGuess: "Windows"},
+			wantType:    "工作站", wantSource: "os", wantConfidence: 0.3,
+		},
+		{
+			name:       "virtual machine kept when ssh seen",
+			deviceType: "虚拟机", source: "vendor", confidence: 0.9,
+			observation: AssetInfo{OpenPorts: []int{22}},
+			wantType:    "虚拟机", wantSource: "vendor", wantConfidence: 0.9,
+		},
+		{
+			name:       "telnet network device kept over ttl guess",
+			deviceType: "网络设备", source: "telnet", confidence: 0.7,
+			observation: AssetInfo{OSGuess: "Linux/Unix"},
+			wantType:    "网络设备", wantSource: "telnet", wantConfidence: 0.7,
+		},
+		{
+			name:       "higher confidence replaces",
+			deviceType: "工作站", source: "os", confidence: 0.3,
+			observation: AssetInfo{OpenPorts: []int{22, 443}},
+			wantType:    "服务器", wantSource: "ports", wantConfidence: 0.6,
+		},
+		{
+			name:       "equal confidence replaces",
+			deviceType: "Web设备", source: "ports", confidence: 0.5,
+			observation: AssetInfo{OpenPorts: []int{3389}},
+			wantType:    "服务器", wantSource: "ports", wantConfidence: 0.5,
+		},
+		{
+			name:       "same source may lower confidence",
+			deviceType: "服务器", source: "ports", confidence: 0.6,
+			observation: AssetInfo{OpenPorts: []int{8080}},
+			wantType:    "Web设备", wantSource: "ports", wantConfidence: 0.5,
+		},
+		{
+			name:       "no evidence keeps type",
+			deviceType: "服务器", source: "ports", confidence: 0.6,
+			observation: AssetInfo{MACAddress: testMAC},
+			wantType:    "服务器", wantSource: "ports", wantConfidence: 0.6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Asset{
+				ID:                   "mac_" + testMAC,
+				MACAddress:           testMAC,
+				DeviceType:           tt.deviceType,
+				DeviceTypeSource:     tt.source,
+				DeviceTypeConfidence: tt.confidence,
+				Protocols:            map[string]interface{}{},
+			}
+			a.Update(&tt.observation)
+
+			if a.DeviceType != tt.wantType || a.DeviceTypeSource != tt.wantSource || a.DeviceTypeConfidence != tt.wantConfidence {
+				t.Errorf("device type = (%q, %q, %v), want (%q, %q, %v)",
+					a.DeviceType, a.DeviceTypeSource, a.DeviceTypeConfidence, tt.wantType, tt.wantSource, tt.wantConfidence)
+			}
+
+			changed := false
+			for _, change := range a.Changes {
+				if change.ChangeType == "device_type_change" {
+					changed = true
+				}
+			}
+			if want := tt.wantType != tt.deviceType; changed != want {
+				t.Errorf("device_type_change recorded = %v, want %v", changed, want)
+			}
+		})
+	}
+}
+
+func TestOSInfoConflictingSources(t *testing.T) {
+	type guess struct {
+		family string
+		source string
+	}
+
+	tests := []struct {
+		name           string
+		guesses        []guess
+		wantFamily     string
+		wantConfidence float64
+		wantDetection  []string
+		wantChanges    int
+	}{
+		{
+			name:           "ttl does not clobber user agent",
+			guesses:        []guess{{"Windows", "user_agent"}, {"Linux", "ttl_analysis"}},
+			wantFamily:     "Windows",
+			wantConfidence: 0.6,
+			wantDetection:  []string{"user_agent"},
+		},
+		{
+			name:           "ttl does not clobber dhcp",
+			guesses:        []guess{{"Windows", "dhcp_vendor_class"}, {"Linux", "ttl_analysis"}, {"Linux", "ttl_analysis"}},
+			wantFamily:     "Windows",
+			wantConfidence: 0.8,
+			wantDetection:  []string{"dhcp_vendor_class"},
+		},
+		{
+			name:           "user agent does not clobber dhcp",
+			guesses:        []guess{{"Windows", "dhcp_vendor_class"}, {"macOS", "user_agent"}},
+			wantFamily:     "Windows",
+			wantConfidence: 0.8,
+			wantDetection:  []string{"dhcp_vendor_class"},
+		},
+		{
+			name:           "more confident source replaces ttl",
+			guesses:        []guess{{"Linux", "ttl_analysis"}, {"Android", "dhcp_vendor_class"}},
+			wantFamily:     "Android",
+			wantConfidence: 0.8,
+			wantDetection:  []string{"dhcp_vendor_class"},
+			wantChanges:    1,
+		},
+		{
+			name:           "equal confidence keeps existing",
+			guesses:        []guess{{"Windows", "user_agent"}, {"macOS", "user_agent"}},
+			wantFamily:     "Windows",
+			wantConfidence: 0.6,
+			wantDetection:  []string{"user_agent"},
+		},
+		{
+			name:           "agreeing sources raise confidence",
+			guesses:        []guess{{"Windows", "ttl_analysis"}, {"Windows", "user_agent"}},
+			wantFamily:     "Windows",
+			wantConfidence: 0.6,
+			wantDetection:  []string{"ttl_analysis", "user_agent"},
+		},
+		{
+			name:           "no flapping between sources",
+			guesses:        []guess{{"Windows", "user_agent"}, {"Linux", "ttl_analysis"}, {"Windows", "user_agent"}, {"Linux", "ttl_analysis"}},
+			wantFamily:     "Windows",
+			wantConfidence: 0.6,
+			wantDetection:  []string{"user_agent"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := tt.guesses[0]
+			asset := NewAsset(&AssetInfo{IPAddress: "10.0.0.1", MACAddress: testMAC})
+			asset.Update(&AssetInfo{IPAddress: "10.0.0.1", MACAddress: testMAC, OSGuess: first.family, OSSource: first.source})
+			for _, g := range tt.guesses[1:] {
+				asset.Update(&AssetInfo{IPAddress: "10.0.0.1", MACAddress: testMAC, OSGuess: g.family, OSSource: g.source})
+			}
+
+			if asset.OSInfo.Family != tt.wantFamily || asset.OSInfo.Confidence != tt.wantConfidence {
+				t.Errorf("OSInfo = %s (%v), want %s (%v)", asset.OSInfo.Family, asset.OSInfo.Confidence, tt.wantFamily, tt.wantConfidence)
+			}
+			if !reflect.DeepEqual(asset.OSInfo.Detection, tt.wantDetection) {
+				t.Errorf("Detection = %v, want %v", asset.OSInfo.Detection, tt.wantDetection)
+			}
+
+			changes := 0
+			for _, change := range asset.Changes {
+				if change.ChangeType == "os_change" {
+					changes++
+				}
+			}
+			if changes != tt.wantChanges {
+				t.Errorf("os changes = %d, want %d", changes, tt.wantChanges)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package assets
+
+import (
+	"strings"
+	"sync"
+)
+
+// Classifier 设备类型分类插件
+type Classifier interface {
+	// 分类器名称，记录在资产的device_type_source中说明设备类型的判断依据
+	Name() string
+
+	// 根据单次观测判断设备类型及置信度(0-1)，没有分类依据时返回空类型
+	Classify(assetInfo *AssetInfo) (deviceType string, confidence float64)
+}
+
+// DeviceClassification 设备类型分类结果
+type DeviceClassification struct {
+	DeviceType string
+	Source     string // 给出该结果的分类器
+	Confidence float64
+}
+
+var (
+	classifierMu sync.RWMutex
+	classifiers  []Classifier
+)
+
+// RegisterClassifier 注册设备类型分类器，与内置分类器一起参与分类
+func RegisterClassifier(c Classifier) {
+	classifierMu.Lock()
+	defer classifierMu.Unlock()
+
+	classifiers = append(classifiers, c)
+}
+
+// builtinClassifiers 内置的设备类型分类器，置信度相同时排在前面的优先
+var builtinClassifiers = []Classifier{
+	vendorClassifier{},
+	voipClassifier{},
+	telnetClassifier{},
+	portClassifier{},
+	osClassifier{},
+}
+
+// classifyDeviceType 依次调用所有分类器，取置信度最高的结果；没有分类依据时返回空类型，更新资产时保持原有类型
+func classifyDeviceType(assetInfo *AssetInfo) DeviceClassification {
+	classifierMu.RLock()
+	chain := append(append([]Classifier{}, builtinClassifiers...), classifiers...)
+	classifierMu.RUnlock()
+
+	var best DeviceClassification
+	for _, c := range chain {
+		deviceType, confidence := c.Classify(assetInfo)
+		if deviceType != "" && (best.DeviceType == "" || confidence > best.Confidence) {
+			best = DeviceClassification{DeviceType: deviceType, Source: c.Name(), Confidence: confidence}
+		}
+	}
+	return best
+}
+
+// vendorClassifier 根据MAC厂商识别虚拟机
+type vendorClassifier struct{}
+
+func (vendorClassifier) Name() string {
+	return "vendor"
+}
+
+func (vendorClassifier) Classify(assetInfo *AssetInfo) (string, float64) {
+	if assetInfo.IsVirtual || IsVirtualVendor(assetInfo.Vendor) {
+		return "虚拟机", 0.9
+	}
+	return "", 0
+}
+
+// voipClassifier STUN的SOFTWARE属性表明是VoIP话机或会议终端
+type voipClassifier struct{}
+
+func (voipClassifier) Name() string {
+	return "stun"
+}
+
+func (voipClassifier) Classify(assetInfo *AssetInfo) (string, float64) {
+	if isVoIPDevice(assetInfo.Protocols) {
+		return voipDeviceType, 0.8
+	}
+	return "", 0
+}
+
+// telnetNetworkKeywords Telnet横幅中表明路由器、交换机等网络设备的关键字（小写）
+var telnetNetworkKeywords = []string{
+	"user access verification", "cisco", "huawei", "h3c", "juniper", "junos", "mikrotik", "routeros",
+	"zyxel", "procurve", "aruba", "fortigate", "ruijie", "tp-link", "netgear", "switch", "router",
+}
+
+// telnetIoTKeywords Telnet横幅中表明摄像头、录像机等嵌入式设备的关键字（小写）
+var telnetIoTKeywords = []string{
+	"busybox", "camera", "ipcam", "dvr", "nvr", "hikvision", "dahua", "embedded", "dreambox",
+}
+
+// telnetClassifier 根据Telnet登录横幅识别网络设备和IoT设备
+type telnetClassifier struct{}
+
+func (telnetClassifier) Name() string {
+	return "telnet"
+}
+
+func (telnetClassifier) Classify(assetInfo *AssetInfo) (string, float64) {
+	telnetInfo, ok := assetInfo.Protocols["telnet"].(map[string]interface{})
+	if !ok {
+		return "", 0
+	}
+
+	banner, _ := telnetInfo["banner"].(string)
+	banner = strings.ToLower(banner)
+	for _, keyword := range telnetNetworkKeywords {
+		if strings.Contains(banner, keyword) {
+			return "网络设备", 0.7
+		}
+	}
+	for _, keyword := range telnetIoTKeywords {
+		if strings.Contains(banner, keyword) {
+			return iotDeviceType, 0.7
+		}
+	}
+
+	// 只开放Telnet而没有其他特征的多为老旧网络设备，低于端口分类的置信度
+	return "网络设备", 0.4
+}
+
+// portClassifier 根据开放端口和提供的Web服务判断设备类型
+type portClassifier struct{}
+
+func (portClassifier) Name() string {
+	return "ports"
+}
+
+func (portClassifier) Classify(assetInfo *AssetInfo) (string, float64) {
+	hasWebPorts := false
+	hasServerPorts := false
+
+	for _, port := range assetInfo.OpenPorts {
+		switch port {
+		case 80, 443, 8080, 8443:
+			hasWebPorts = true
+		case 22, 23, 3389:
+			hasServerPorts = true
+		case 21, 25, 53, 110, 143:
+			hasServerPorts = true
+		}
+	}
+
+	// 通过Set-Cookie识别出Web框架说明该资产提供Web服务
+	if httpInfo, ok := assetInfo.Protocols["http"].(map[string]interface{}); ok {
+		if _, ok := httpInfo["framework"]; ok {
+			hasWebPorts = true
+		}
+	}
+
+	switch {
+	case hasWebPorts && hasServerPorts:
+		return "服务器", 0.6
+	case hasWebPorts:
+		return "Web设备", 0.5
+	case hasServerPorts:
+		return "服务器", 0.5
+	}
+	return "", 0
+}
+
+// osClassifier 根据操作系统推测判断设备类型，推测本身置信度不高
+type osClassifier struct{}
+
+func (osClassifier) Name() string {
+	return "os"
+}
+
+func (osClassifier) Classify(assetInfo *AssetInfo) (string, float64) {
+	switch assetInfo.OSGuess {
+	case "Linux/Unix":
+		return "服务器", 0.3
+	case "Windows":
+		return "工作站", 0.3
+	case "Cisco/Network Device":
+		return "网络设备", 0.3
+	}
+	return "", 0
+}
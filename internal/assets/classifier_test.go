@@ -0,0 +1,165 @@
+package assets
+
+import (
+	"testing"
+)
+
+// stubClassifier 返回固定结果的分类器
+type stubClassifier struct {
+	name       string
+	deviceType string
+	confidence float64
+}
+
+func (c stubClassifier) Name() string {
+	return c.name
+}
+
+func (c stubClassifier) Classify(*AssetInfo) (string, float64) {
+	return c.deviceType, c.confidence
+}
+
+// registerTestClassifier 注册分类器，测试结束后恢复原有的分类器列表
+func registerTestClassifier(t *testing.T, c Classifier) {
+	t.Helper()
+
+	classifierMu.RLock()
+	saved := append([]Classifier{}, classifiers...)
+	classifierMu.RUnlock()
+
+	RegisterClassifier(c)
+	t.Cleanup(func() {
+		classifierMu.Lock()
+		defer classifierMu.Unlock()
+		classifiers = saved
+	})
+}
+
+// telnetInfo 携带Telnet横幅的协议信息
+func telnetInfo(banner string) map[string]interface{} {
+	return map[string]interface{}{"telnet": map[string]interface{}{"banner": banner}}
+}
+
+// stunInfo 携带STUN SOFTWARE属性的协议信息
+func stunInfo(software string) map[string]interface{} {
+	return map[string]interface{}{"stun": map[string]interface{}{"software": software}}
+}
+
+func TestClassifiers(t *testing.T) {
+	tests := []struct {
+		name           string
+		classifier     Classifier
+		info           AssetInfo
+		wantType       string
+		wantConfidence float64
+	}{
+		{"vendor virtual mac", vendorClassifier{}, AssetInfo{IsVirtual: true}, "虚拟机", 0.9},
+		{"vendor virtual vendor name", vendorClassifier{}, AssetInfo{Vendor: "VMware, Inc."}, "虚拟机", 0.9},
+		{"vendor physical", vendorClassifier{}, AssetInfo{Vendor: "Dell Inc."}, "", 0},
+
+		{"stun voip phone", voipClassifier{}, AssetInfo{Protocols: stunInfo("Yealink SIP-T46S 66.85.0.5")}, voipDeviceType, 0.8},
+		{"stun browser", voipClassifier{}, AssetInfo{Protocols: stunInfo("Chrome WebRTC")}, "", 0},
+		{"stun missing", voipClassifier{}, AssetInfo{}, "", 0},
+
+		{"telnet cisco banner", telnetClassifier{}, AssetInfo{Protocols: telnetInfo("User Access Verification")}, "网络设备", 0.7},
+		{"telnet iot banner", telnetClassifier{}, AssetInfo{Protocols: telnetInfo("BusyBox v1.19.4 built-in shell")}, iotDeviceType, 0.7},
+		{"telnet plain banner", telnetClassifier{}, AssetInfo{Protocols: telnetInfo("login:")}, "网络设备", 0.4},
+		{"telnet missing", telnetClassifier{}, AssetInfo{}, "", 0},
+
+		{"ports web and server", portClassifier{}, AssetInfo{OpenPorts: []int{22, 443}}, "服务器", 0.6},
+		{"ports web only", portClassifier{}, AssetInfo{OpenPorts: []int{8080}}, "Web设备", 0.5},
+		{"ports server only", portClassifier{}, AssetInfo{OpenPorts: []int{3389}}, "服务器", 0.5},
+		{"ports web framework cookie", portClassifier{}, AssetInfo{Protocols: map[string]interface{}{"http": map[string]interface{}{"framework": "PHP"}}}, "Web设备", 0.5},
+		{"ports unknown", portClassifier{}, AssetInfo{OpenPorts: []int{9100}}, "", 0},
+
+		{"os linux", osClassifier{}, AssetInfo{OSGuess: "Linux/Unix"}, "服务器", 0.3},
+		{"os windows", osClassifier{}, AssetInfo{OSGuess: "Windows"}, "工作站", 0.3},
+		{"os network", osClassifier{}, AssetInfo{OSGuess: "Cisco/Network Device"}, "网络设备", 0.3},
+		{"os unknown", osClassifier{}, AssetInfo{OSGuess: "macOS"}, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotType, gotConfidence := tt.classifier.Classify(&tt.info)
+			if gotType != tt.wantType || gotConfidence != tt.wantConfidence {
+				t.Errorf("%s.Classify() = (%q, %v), want (%q, %v)",
+					tt.classifier.Name(), gotType, gotConfidence, tt.wantType, tt.wantConfidence)
+			}
+		})
+	}
+}
+
+func TestClassifyDeviceTypeChain(t *testing.T) {
+	tests := []struct {
+		name       string
+		info       AssetInfo
+		register   []Classifier
+		wantType   string
+		wantSource string
+	}{
+		{
+			name:       "no evidence",
+			info:       AssetInfo{MACAddress: testMAC},
+			wantType:   "",
+			wantSource: "",
+		},
+		{
+			name:       "highest confidence wins over earlier classifier",
+			info:       AssetInfo{OpenPorts: []int{22, 443}, OSGuess: "Windows"},
+			wantType:   "服务器",
+			wantSource: "ports",
+		},
+		{
+			name:       "vendor beats ports",
+			info:       AssetInfo{IsVirtual: true, OpenPorts: []int{22, 443}},
+			wantType:   "虚拟机",
+			wantSource: "vendor",
+		},
+		{
+			name:       "telnet keyword beats ports",
+			info:       AssetInfo{OpenPorts: []int{23, 80}, Protocols: telnetInfo("Huawei Versatile Routing Platform")},
+			wantType:   "网络设备",
+			wantSource: "telnet",
+		},
+		{
+			name:       "bare telnet loses to ports",
+			info:       AssetInfo{OpenPorts: []int{23, 80}, Protocols: telnetInfo("login:")},
+			wantType:   "服务器",
+			wantSource: "ports",
+		},
+		{
+			name:       "registered classifier with higher confidence",
+			info:       AssetInfo{OpenPorts: []int{22, 443}},
+			register:   []Classifier{stubClassifier{"model", "NAS", 0.95}},
+			wantType:   "NAS",
+			wantSource: "model",
+		},
+		{
+			name:       "tie keeps builtin classifier",
+			info:       AssetInfo{OpenPorts: []int{22, 443}},
+			register:   []Classifier{stubClassifier{"model", "NAS", 0.6}},
+			wantType:   "服务器",
+			wantSource: "ports",
+		},
+		{
+			name:       "registered classifier without result is skipped",
+			info:       AssetInfo{OSGuess: "Windows"},
+			register:   []Classifier{stubClassifier{"model", "", 1}},
+			wantType:   "工作站",
+			wantSource: "os",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, c := range tt.register {
+				registerTestClassifier(t, c)
+			}
+
+			got := classifyDeviceType(&tt.info)
+			if got.DeviceType != tt.wantType || got.Source != tt.wantSource {
+				t.Errorf("classifyDeviceType() = %+v, want type %q from %q", got, tt.wantType, tt.wantSource)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package assets
+
+// 置信度门槛：置信度低于parser.min_confidence的资产（如只出现过一个零散或伪造的数据包）
+// 只保留在内存中，不保存到存储也不产生告警，累计的观测使置信度达到门槛后再按新资产处理
+
+// confidenceScore 按已知信息计算识别置信度
+func confidenceScore(mac, ip, hostname, osFamily string, ports, services int) float64 {
+	confidence := 0.0
+
+	// 基于可用信息计算置信度
+	if mac != "" {
+		confidence += 0.3
+	}
+	if ip != "" {
+		confidence += 0.2
+	}
+	if hostname != "" {
+		confidence += 0.2
+	}
+	if ports > 0 {
+		confidence += 0.1
+	}
+	if services > 0 {
+		confidence += 0.1
+	}
+	if osFamily != "" {
+		confidence += 0.1
+	}
+
+	if confidence > 1.0 {
+		confidence = 1.0
+	}
+
+	return confidence
+}
+
+// accumulatedConfidence 按资产累计观测到的信息计算置信度，单个数据包通常只提供部分信息，调用方需持有锁
+func (a *Asset) accumulatedConfidence() float64 {
+	ports := 0
+	for _, port := range a.OpenPorts {
+		if port.State != "closed" {
+			ports++
+		}
+	}
+	return confidenceScore(a.MACAddress, a.IPAddress, a.Hostname, a.OSInfo.Family, ports, a.countServices("listening"))
+}
+
+// isTentative 资产置信度是否尚未达到门槛
+func (a *Asset) isTentative() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.tentative
+}
+
+// confirmAsset 检查资产置信度是否达到门槛，返回是否已达到及是否为本次首次达到
+func (am *AssetManager) confirmAsset(asset *Asset) (confirmed, newly bool) {
+	asset.mu.Lock()
+	defer asset.mu.Unlock()
+
+	if !asset.tentative {
+		return true, false
+	}
+	if asset.Confidence < am.config.Parser.MinConfidence {
+		return false, false
+	}
+	asset.tentative = false
+	return true, true
+}
@@ -0,0 +1,76 @@
+package assets
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMinConfidenceThreshold(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	arp := AssetInfo{MACAddress: testMAC, IPAddress: "10.0.0.1"}
+	withHostname := AssetInfo{MACAddress: testMAC, IPAddress: "10.0.0.1", Hostname: "nas-01"}
+	withPort := AssetInfo{MACAddress: testMAC, IPAddress: "10.0.0.1", OpenPorts: []int{22}}
+	withOS := AssetInfo{MACAddress: testMAC, IPAddress: "10.0.0.1", OSGuess: "Linux/Unix"}
+
+	tests := []struct {
+		name          string
+		minConfidence float64
+		observations  []AssetInfo
+		confirmedAt   int // 首次达到门槛的观测序号，-1表示始终未达到
+	}{
+		{"threshold disabled", 0, []AssetInfo{{MACAddress: testMAC}}, 0},
+		{"first packet suffices", 0.5, []AssetInfo{arp}, 0},
+		{"hostname crosses threshold", 0.6, []AssetInfo{arp, arp, withHostname, arp}, 2},
+		{"ports and os accumulate", 0.7, []AssetInfo{arp, withPort, withOS}, 2},
+		{"never reaches threshold", 0.9, []AssetInfo{arp, withPort, arp}, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.Storage.NoStore = false
+			cfg.Parser.MinConfidence = tt.minConfidence
+			am := newTestManager(cfg)
+
+			events, cancel := am.Subscribe(64)
+			defer cancel()
+
+			for i, observation := range tt.observations {
+				observation := observation
+				observation.Timestamp = start.Add(time.Duration(i) * time.Minute)
+				am.UpdateAsset(&observation)
+
+				asset, _ := am.GetAsset("mac_" + testMAC)
+				wantTentative := tt.confirmedAt < 0 || i < tt.confirmedAt
+				if got := asset.isTentative(); got != wantTentative {
+					t.Fatalf("after observation %d: tentative = %v, want %v", i, got, wantTentative)
+				}
+			}
+
+			// 只在首次达到门槛时计为新资产并发送一次new_asset事件
+			wantNew := 1
+			if tt.confirmedAt < 0 {
+				wantNew = 0
+			}
+			if got := am.GetStats().NewAssets; got != wantNew {
+				t.Errorf("NewAssets = %d, want %d", got, wantNew)
+			}
+			newEvents := 0
+			for len(events) > 0 {
+				if event := <-events; event.Type == EventNewAsset {
+					newEvents++
+				}
+			}
+			if newEvents != wantNew {
+				t.Errorf("new_asset events = %d, want %d", newEvents, wantNew)
+			}
+
+			// 低置信度资产不写入存储
+			am.saveAllAssets()
+			_, err := am.storage.GetAsset("mac_" + testMAC)
+			if stored := err == nil; stored != (wantNew == 1) {
+				t.Errorf("stored = %v, want %v", stored, wantNew == 1)
+			}
+		})
+	}
+}
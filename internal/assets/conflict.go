@@ -0,0 +1,92 @@
+package assets
+
+import (
+	"time"
+)
+
+const (
+	// bindingWindow 旧绑定在该时间内仍活跃时才认为出现冲突，超过则视为正常的IP重新分配
+	bindingWindow = 5 * time.Minute
+	// maxConflicts 保留的冲突记录数量
+	maxConflicts = 100
+)
+
+// IPMACConflict IP-MAC绑定冲突，可能是ARP欺骗或IP地址冲突
+type IPMACConflict struct {
+	IPAddress  string    `json:"ip_address"`
+	OldMAC     string    `json:"old_mac"`
+	NewMAC     string    `json:"new_mac"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// ipBinding 某个IP最近一次通过ARP声明的MAC
+type ipBinding struct {
+	mac      string
+	lastSeen time.Time
+}
+
+// bindingTracker 跟踪ARP中的IP-MAC绑定关系
+type bindingTracker struct {
+	bindings  map[string]ipBinding
+	conflicts []IPMACConflict
+	// 已告警的冲突，key为IP+两个MAC，避免重复告警
+	reported map[string]time.Time
+}
+
+func newBindingTracker() *bindingTracker {
+	return &bindingTracker{
+		bindings: make(map[string]ipBinding),
+		reported: make(map[string]time.Time),
+	}
+}
+
+// observe 记录一次绑定，出现需要告警的新冲突时返回冲突信息
+func (bt *bindingTracker) observe(ip, mac string, seen time.Time) *IPMACConflict {
+	if ip == "" || mac == "" || ip == "0.0.0.0" {
+		return nil
+	}
+
+	previous, exists := bt.bindings[ip]
+	bt.bindings[ip] = ipBinding{mac: mac, lastSeen: seen}
+
+	if !exists || previous.mac == mac || seen.Sub(previous.lastSeen) > bindingWindow {
+		return nil
+	}
+
+	if !bt.reportOnce("conflict", ip, previous.mac, mac, seen) {
+		return nil
+	}
+
+	conflict := IPMACConflict{
+		IPAddress:  ip,
+		OldMAC:     previous.mac,
+		NewMAC:     mac,
+		DetectedAt: seen,
+	}
+
+	bt.conflicts = append(bt.conflicts, conflict)
+	if len(bt.conflicts) > maxConflicts {
+		bt.conflicts = bt.conflicts[len(bt.conflicts)-maxConflicts:]
+	}
+
+	return &conflict
+}
+
+// reportOnce 同一IP在两个MAC之间的同类事件在bindingWindow内只告警一次，
+// 避免ARP欺骗时绑定反复切换产生大量告警
+func (bt *bindingTracker) reportOnce(kind, ip, macA, macB string, seen time.Time) bool {
+	key := kind + "|" + conflictKey(ip, macA, macB)
+	if last, ok := bt.reported[key]; ok && seen.Sub(last) < bindingWindow {
+		return false
+	}
+	bt.reported[key] = seen
+	return true
+}
+
+// conflictKey 生成与MAC顺序无关的冲突标识
+func conflictKey(ip, macA, macB string) string {
+	if macA > macB {
+		macA, macB = macB, macA
+	}
+	return ip + "|" + macA + "|" + macB
+}
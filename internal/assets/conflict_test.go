@@ -0,0 +1,112 @@
+package assets
+
+import (
+	"testing"
+	"time"
+
+	"assets_discovery/internal/alert"
+)
+
+// arpUpdate 以ARP应答中的IP-MAC绑定更新资产
+func arpUpdate(am *AssetManager, ip, mac string, ts time.Time) {
+	am.UpdateAsset(&AssetInfo{
+		IPAddress:  ip,
+		MACAddress: mac,
+		Protocols: map[string]interface{}{
+			"arp": map[string]interface{}{"src_ip": ip, "src_mac": mac},
+		},
+		Timestamp: ts,
+	})
+}
+
+func TestMACChangeAlert(t *testing.T) {
+	const (
+		ip     = "10.0.0.9"
+		oldMAC = "00:11:22:33:44:01"
+		newMAC = "00:11:22:33:44:02"
+	)
+
+	tests := []struct {
+		name       string
+		known      []string
+		wantAlerts int
+	}{
+		{"unknown asset alerts", nil, 1},
+		{"allowlisted ip", []string{ip}, 0},
+		{"allowlisted new mac", []string{newMAC}, 0},
+		{"allowlisted subnet", []string{"10.0.0.0/24"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.Alerting.KnownAssets = tt.known
+			alerts := newAlertRecorder(t, cfg)
+			am := newTestManager(cfg)
+
+			now := time.Now()
+			arpUpdate(am, ip, oldMAC, now)
+			arpUpdate(am, ip, oldMAC, now.Add(time.Second))
+			arpUpdate(am, ip, newMAC, now.Add(2*time.Second))
+			// 窗口内重复的变更只告警一次
+			arpUpdate(am, ip, newMAC, now.Add(3*time.Second))
+
+			// 不论是否在白名单中，变更都记录在新资产上
+			asset, ok := am.GetAsset("mac_" + newMAC)
+			if !ok {
+				t.Fatalf("GetAsset(mac_%s) not found", newMAC)
+			}
+			var changes []ChangeRecord
+			for _, change := range asset.Changes {
+				if change.ChangeType == "mac_change" {
+					changes = append(changes, change)
+				}
+			}
+			if len(changes) != 1 || changes[0].OldValue != oldMAC || changes[0].NewValue != newMAC {
+				t.Errorf("mac_change records = %+v, want one %s -> %s", changes, oldMAC, newMAC)
+			}
+			if old, _ := am.GetAsset("mac_" + oldMAC); old == nil || len(old.Changes) != 0 {
+				t.Errorf("previous owner changes = %v, want none", old)
+			}
+
+			if tt.wantAlerts > 0 {
+				waitFor(t, "mac_change alert", func() bool {
+					return len(alerts.ofType(alert.EventMACChange)) >= tt.wantAlerts
+				})
+			}
+			time.Sleep(50 * time.Millisecond)
+
+			events := alerts.ofType(alert.EventMACChange)
+			if len(events) != tt.wantAlerts {
+				t.Fatalf("mac_change alerts = %d, want %d", len(events), tt.wantAlerts)
+			}
+			if tt.wantAlerts > 0 && (events[0].IPAddress != ip || events[0].MACAddress != newMAC || events[0].AssetID != "mac_"+newMAC) {
+				t.Errorf("alert = %s %s %s, want %s mac_%s", events[0].AssetID, events[0].IPAddress, events[0].MACAddress, ip, newMAC)
+			}
+		})
+	}
+}
+
+func TestMACChangeIgnoresProbes(t *testing.T) {
+	cfg := newTestConfig()
+	alerts := newAlertRecorder(t, cfg)
+	am := newTestManager(cfg)
+
+	now := time.Now()
+	arpUpdate(am, "10.0.0.9", "00:11:22:33:44:01", now)
+	// ARP探测和非ARP流量不代表IP绑定变更
+	am.UpdateAsset(&AssetInfo{
+		IPAddress:  "10.0.0.9",
+		MACAddress: "00:11:22:33:44:02",
+		Protocols: map[string]interface{}{
+			"arp": map[string]interface{}{"src_ip": "0.0.0.0", "src_mac": "00:11:22:33:44:02", "probe": true},
+		},
+		Timestamp: now.Add(time.Second),
+	})
+	am.UpdateAsset(&AssetInfo{IPAddress: "10.0.0.9", MACAddress: "00:11:22:33:44:03", Timestamp: now.Add(2 * time.Second)})
+
+	time.Sleep(50 * time.Millisecond)
+	if events := alerts.ofType(alert.EventMACChange); len(events) != 0 {
+		t.Errorf("mac_change alerts = %v, want none", events)
+	}
+}
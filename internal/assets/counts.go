@@ -0,0 +1,94 @@
+package assets
+
+// assetCounts 资产总数、活跃资产数及设备类型、操作系统分布的增量统计
+// 记录每个资产计入统计时的状态，资产创建、更新、标记为非活跃或删除时只调整变化的部分，
+// 获取统计时不必遍历全部资产。所有方法都要求调用方持有am.mutex写锁
+type assetCounts struct {
+	counted     map[string]countedState // 资产ID -> 计入统计时的状态
+	active      int
+	deviceTypes map[string]int
+	osFamilies  map[string]int
+}
+
+type countedState struct {
+	active     bool
+	deviceType string
+	osFamily   string
+}
+
+func newAssetCounts() *assetCounts {
+	return &assetCounts{
+		counted:     make(map[string]countedState),
+		deviceTypes: make(map[string]int),
+		osFamilies:  make(map[string]int),
+	}
+}
+
+// track 按资产当前的状态计入统计，资产已计入时只调整变化的部分，资产插入或状态变化后调用
+func (c *assetCounts) track(asset *Asset) {
+	asset.mu.RLock()
+	id := asset.ID
+	state := countedState{active: asset.IsActive, deviceType: asset.DeviceType, osFamily: asset.OSInfo.Family}
+	asset.mu.RUnlock()
+
+	if old, ok := c.counted[id]; ok {
+		if old == state {
+			return
+		}
+		c.untrack(id)
+	}
+
+	c.counted[id] = state
+	if state.active {
+		c.active++
+	}
+	if state.deviceType != "" {
+		c.deviceTypes[state.deviceType]++
+	}
+	if state.osFamily != "" {
+		c.osFamilies[state.osFamily]++
+	}
+}
+
+// untrack 从统计中移除资产ID，资产被删除、合并或更换ID前调用
+func (c *assetCounts) untrack(id string) {
+	state, ok := c.counted[id]
+	if !ok {
+		return
+	}
+	delete(c.counted, id)
+
+	if state.active {
+		c.active--
+	}
+	decrement(c.deviceTypes, state.deviceType)
+	decrement(c.osFamilies, state.osFamily)
+}
+
+// fill 将当前统计写入stats，分布使用副本，调用方可在锁外读取
+func (c *assetCounts) fill(stats *AssetStats) {
+	stats.TotalAssets = len(c.counted)
+	stats.ActiveAssets = c.active
+	stats.DeviceTypes = copyCounts(c.deviceTypes)
+	stats.OSDistribution = copyCounts(c.osFamilies)
+}
+
+// decrement 计数减一，减到0时删除该键
+func decrement(counts map[string]int, key string) {
+	if key == "" {
+		return
+	}
+	if counts[key] <= 1 {
+		delete(counts, key)
+		return
+	}
+	counts[key]--
+}
+
+func copyCounts(counts map[string]int) map[string]int {
+	result := make(map[string]int, len(counts))
+	for k, v := range counts {
+		result[k] = v
+	}
+	return result
+}
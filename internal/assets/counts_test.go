@@ -0,0 +1,118 @@
+package assets
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// recomputeCounts 遍历全部资产重新计算统计，作为增量统计的对照
+func recomputeCounts(am *AssetManager) AssetStats {
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+
+	stats := AssetStats{DeviceTypes: map[string]int{}, OSDistribution: map[string]int{}}
+	for _, asset := range am.assets {
+		asset.mu.RLock()
+		stats.TotalAssets++
+		if asset.IsActive {
+			stats.ActiveAssets++
+		}
+		if asset.DeviceType != "" {
+			stats.DeviceTypes[asset.DeviceType]++
+		}
+		if asset.OSInfo.Family != "" {
+			stats.OSDistribution[asset.OSInfo.Family]++
+		}
+		asset.mu.RUnlock()
+	}
+	return stats
+}
+
+func TestIncrementalCountsMatchRecompute(t *testing.T) {
+	start := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	observe := func(info AssetInfo, at time.Duration) func(am *AssetManager) {
+		return func(am *AssetManager) {
+			info.Timestamp = start.Add(at)
+			am.UpdateAsset(&info)
+		}
+	}
+	cleanupAt := func(at time.Duration) func(am *AssetManager) {
+		return func(am *AssetManager) {
+			setClock(am, start.Add(at))
+			am.cleanupInactiveAssets()
+		}
+	}
+
+	server := AssetInfo{MACAddress: "00:1a:2b:00:00:01", IPAddress: "10.0.0.1", OpenPorts: []int{22, 443}, OSGuess: "Linux/Unix"}
+	workstation := AssetInfo{MACAddress: "00:1a:2b:00:00:02", IPAddress: "10.0.0.2", OSGuess: "Windows"}
+	printer := AssetInfo{MACAddress: "00:1a:2b:00:00:03", IPAddress: "10.0.0.3", OpenPorts: []int{80}}
+
+	tests := []struct {
+		name  string
+		steps []func(am *AssetManager)
+	}{
+		{
+			name:  "new assets",
+			steps: []func(am *AssetManager){observe(server, 0), observe(workstation, 0), observe(printer, 0)},
+		},
+		{
+			name: "device type and os change",
+			steps: []func(am *AssetManager){
+				observe(workstation, 0),
+				observe(AssetInfo{MACAddress: "00:1a:2b:00:00:02", IPAddress: "10.0.0.2", OpenPorts: []int{3389, 443}}, time.Minute),
+				observe(AssetInfo{MACAddress: "00:1a:2b:00:00:02", IPAddress: "10.0.0.2", OSGuess: "Linux/Unix"}, 2*time.Minute),
+			},
+		},
+		{
+			name: "inactive and reactivated",
+			steps: []func(am *AssetManager){
+				observe(server, 0), observe(printer, 50*time.Minute),
+				cleanupAt(time.Hour),
+				observe(server, 2*time.Hour),
+			},
+		},
+		{
+			name: "ip-only asset merged into mac asset",
+			steps: []func(am *AssetManager){
+				observe(AssetInfo{IPAddress: "10.0.0.9", OpenPorts: []int{22}}, 0),
+				observe(AssetInfo{MACAddress: "00:1a:2b:00:00:09", IPAddress: "10.0.0.9", OSGuess: "Windows"}, time.Minute),
+			},
+		},
+		{
+			name: "deletes and purge",
+			steps: []func(am *AssetManager){
+				observe(server, 0), observe(workstation, 0), observe(printer, 3*24*time.Hour),
+				func(am *AssetManager) { am.DeleteAsset("mac_00:1a:2b:00:00:01") },
+				cleanupAt(3*24*time.Hour + time.Minute),
+				func(am *AssetManager) { am.ClearInactive() },
+				func(am *AssetManager) { am.Clear() },
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.Parser.AssetTimeout = 30
+			cfg.Parser.PurgeAfter = 1
+			am := newTestManager(cfg)
+
+			for i, step := range tt.steps {
+				step(am)
+
+				got, want := am.GetStats(), recomputeCounts(am)
+				if got.TotalAssets != want.TotalAssets || got.ActiveAssets != want.ActiveAssets {
+					t.Fatalf("step %d: total/active = %d/%d, recomputed %d/%d",
+						i, got.TotalAssets, got.ActiveAssets, want.TotalAssets, want.ActiveAssets)
+				}
+				if !reflect.DeepEqual(got.DeviceTypes, want.DeviceTypes) {
+					t.Fatalf("step %d: device types = %v, recomputed %v", i, got.DeviceTypes, want.DeviceTypes)
+				}
+				if !reflect.DeepEqual(got.OSDistribution, want.OSDistribution) {
+					t.Fatalf("step %d: os distribution = %v, recomputed %v", i, got.OSDistribution, want.OSDistribution)
+				}
+			}
+		})
+	}
+}
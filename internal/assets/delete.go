@@ -0,0 +1,153 @@
+package assets
+
+import (
+	"fmt"
+	"log"
+)
+
+// DeleteAsset 从内存和存储中删除资产，同时删除指向它的别名，返回资产是否存在
+// 资产只存在于存储中时（如未加载到内存）同样会被删除
+func (am *AssetManager) DeleteAsset(assetID string) (bool, error) {
+	am.mutex.Lock()
+	_, inMemory := am.assets[assetID]
+	if inMemory {
+		am.removeAssets([]string{assetID})
+	}
+	am.mutex.Unlock()
+
+	stored, err := am.deleteFromStorage(assetID)
+	if inMemory {
+		am.updateStats()
+		log.Printf("删除资产: %s", assetID)
+	}
+	return inMemory || stored, err
+}
+
+// ClearInactive 删除内存和存储中所有非活跃的资产，返回删除的数量
+func (am *AssetManager) ClearInactive() (int, error) {
+	am.mutex.Lock()
+	var ids []string
+	for id, asset := range am.assets {
+		asset.mu.RLock()
+		inactive := !asset.IsActive
+		asset.mu.RUnlock()
+
+		if inactive {
+			ids = append(ids, id)
+		}
+	}
+	am.removeAssets(ids)
+	am.mutex.Unlock()
+
+	return am.finishClear(ids, "非活跃")
+}
+
+// Clear 删除内存和存储中的全部资产，用于清除测试数据，返回删除的数量
+// 存储中不在内存里的资产（如加载后被清除的）同样会被删除
+func (am *AssetManager) Clear() (int, error) {
+	am.mutex.Lock()
+	ids := make([]string, 0, len(am.assets))
+	for id := range am.assets {
+		ids = append(ids, id)
+	}
+	am.removeAssets(ids)
+	am.mutex.Unlock()
+
+	if !am.config.Storage.NoStore {
+		stored, err := am.storage.GetAllAssets()
+		if err != nil {
+			return 0, fmt.Errorf("读取存储中的资产失败: %v", err)
+		}
+
+		seen := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			seen[id] = true
+		}
+		for _, item := range stored {
+			if asset, err := decodeStoredAsset(item); err == nil && !seen[asset.ID] {
+				ids = append(ids, asset.ID)
+				seen[asset.ID] = true
+			}
+		}
+	}
+
+	return am.finishClear(ids, "全部")
+}
+
+// finishClear 从存储中删除已从内存移除的资产并更新统计，kind用于日志
+func (am *AssetManager) finishClear(ids []string, kind string) (int, error) {
+	var firstErr error
+	failed := 0
+	for _, id := range ids {
+		if _, err := am.deleteFromStorage(id); err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	am.updateStats()
+	log.Printf("删除了 %d 个%s资产", len(ids), kind)
+
+	if firstErr != nil {
+		return len(ids), fmt.Errorf("%d 个资产从存储中删除失败: %v", failed, firstErr)
+	}
+	return len(ids), nil
+}
+
+// removeAssets 从内存、索引、统计、别名表和保存重试队列中移除资产，调用方需持有am.mutex写锁
+func (am *AssetManager) removeAssets(ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+
+	removed := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if asset, exists := am.assets[id]; exists {
+			asset.markRemoved()
+		}
+		delete(am.assets, id)
+		am.retries.done(id)
+		am.index.remove(id)
+		am.counts.untrack(id)
+		removed[id] = true
+		am.publishEvent(AssetEvent{Type: EventRemoved, AssetID: id})
+	}
+
+	for alias, target := range am.aliases {
+		if removed[alias] || removed[target] {
+			delete(am.aliases, alias)
+		}
+	}
+}
+
+// deleteFromStorage 同步地从存储中删除资产，返回存储中是否存在该资产
+// 资产可能尚未保存到存储，因此先查询再删除，不存在时不视为错误
+func (am *AssetManager) deleteFromStorage(assetID string) (bool, error) {
+	if am.config.Storage.NoStore {
+		return false, nil
+	}
+
+	if _, err := am.storage.GetAsset(assetID); err != nil {
+		return false, nil
+	}
+	if err := am.storage.DeleteAsset(assetID); err != nil {
+		return true, fmt.Errorf("从存储中删除资产失败 %s: %v", assetID, err)
+	}
+	return true, nil
+}
+
+// markRemoved 标记资产已从管理器中删除
+func (a *Asset) markRemoved() {
+	a.mu.Lock()
+	a.removed = true
+	a.mu.Unlock()
+}
+
+// isRemoved 资产是否已从管理器中删除
+func (a *Asset) isRemoved() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.removed
+}
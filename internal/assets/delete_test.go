@@ -0,0 +1,153 @@
+package assets
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// newStoringManager 创建写入内存存储的管理器，active中的资产为活跃资产，inactive中的为非活跃资产，
+// storedOnly中的资产只存在于存储中
+func newStoringManager(t *testing.T, active, inactive, storedOnly []string) *AssetManager {
+	t.Helper()
+
+	cfg := newTestConfig()
+	cfg.Storage.NoStore = false
+	am := newTestManager(cfg)
+
+	for i, id := range append(append([]string{}, active...), inactive...) {
+		addTestAsset(am, &Asset{ID: id, IPAddress: fmt.Sprintf("10.0.0.%d", i+1), IsActive: i < len(active)})
+	}
+	am.saveAllAssets()
+
+	for _, id := range storedOnly {
+		if err := am.storage.SaveAsset(map[string]interface{}{"id": id, "is_active": false}); err != nil {
+			t.Fatalf("SaveAsset(%s) error = %v", id, err)
+		}
+	}
+	return am
+}
+
+// storedIDs 返回存储中排序后的资产ID
+func storedIDs(t *testing.T, am *AssetManager) []string {
+	t.Helper()
+
+	stored, err := am.storage.GetAllAssets()
+	if err != nil {
+		t.Fatalf("GetAllAssets() error = %v", err)
+	}
+	ids := make([]string, 0, len(stored))
+	for _, item := range stored {
+		asset, err := decodeStoredAsset(item)
+		if err != nil {
+			t.Fatalf("decodeStoredAsset() error = %v", err)
+		}
+		ids = append(ids, asset.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// memoryIDs 返回内存中排序后的资产ID
+func memoryIDs(am *AssetManager) []string {
+	ids := make([]string, 0)
+	for id := range am.GetAllAssets() {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// deleteOne 以ClearInactive的形式包装DeleteAsset，资产存在时返回删除数量1
+func deleteOne(id string) func(am *AssetManager) (int, error) {
+	return func(am *AssetManager) (int, error) {
+		found, err := am.DeleteAsset(id)
+		if !found {
+			return 0, err
+		}
+		return 1, err
+	}
+}
+
+func TestDeleteKeepsMemoryAndStorageInSync(t *testing.T) {
+	tests := []struct {
+		name       string
+		op         func(am *AssetManager) (int, error)
+		wantCount  int
+		wantMemory []string
+		wantStored []string
+	}{
+		{
+			name:       "delete active asset",
+			op:         deleteOne("a1"),
+			wantCount:  1,
+			wantMemory: []string{"a2", "i1"},
+			wantStored: []string{"a2", "i1", "s1"},
+		},
+		{
+			name:       "delete storage-only asset",
+			op:         deleteOne("s1"),
+			wantCount:  1,
+			wantMemory: []string{"a1", "a2", "i1"},
+			wantStored: []string{"a1", "a2", "i1"},
+		},
+		{
+			name:       "delete missing asset",
+			op:         deleteOne("nope"),
+			wantCount:  0,
+			wantMemory: []string{"a1", "a2", "i1"},
+			wantStored: []string{"a1", "a2", "i1", "s1"},
+		},
+		{
+			name:       "clear inactive",
+			op:         (*AssetManager).ClearInactive,
+			wantCount:  1,
+			wantMemory: []string{"a1", "a2"},
+			wantStored: []string{"a1", "a2", "s1"},
+		},
+		{
+			name:       "clear all includes storage-only assets",
+			op:         (*AssetManager).Clear,
+			wantCount:  4,
+			wantMemory: []string{},
+			wantStored: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			am := newStoringManager(t, []string{"a1", "a2"}, []string{"i1"}, []string{"s1"})
+
+			count, err := tt.op(am)
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if count != tt.wantCount {
+				t.Errorf("deleted = %d, want %d", count, tt.wantCount)
+			}
+
+			memory, stored := memoryIDs(am), storedIDs(t, am)
+			if !equalStrings(memory, tt.wantMemory) {
+				t.Errorf("memory = %v, want %v", memory, tt.wantMemory)
+			}
+			if !equalStrings(stored, tt.wantStored) {
+				t.Errorf("storage = %v, want %v", stored, tt.wantStored)
+			}
+
+			// 统计和索引与内存中的资产一致
+			if total := am.GetStats().TotalAssets; total != len(tt.wantMemory) {
+				t.Errorf("TotalAssets = %d, want %d", total, len(tt.wantMemory))
+			}
+			for _, id := range []string{"a1", "a2", "i1"} {
+				_, inMemory := am.GetAsset(id)
+				kept := false
+				for _, want := range tt.wantMemory {
+					kept = kept || want == id
+				}
+				if inMemory != kept {
+					t.Errorf("GetAsset(%s) found = %v, want %v", id, inMemory, kept)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package assets
+
+import (
+	"fmt"
+	"sort"
+)
+
+// InventoryDiff 两份资产清单之间的差异
+type InventoryDiff struct {
+	Added   []*Asset      `json:"added"`
+	Removed []*Asset      `json:"removed"`
+	Changed []AssetChange `json:"changed"`
+}
+
+// AssetChange 同一资产在两份清单之间的字段变化
+type AssetChange struct {
+	ID        string        `json:"id"`
+	IPAddress string        `json:"ip_address"`
+	Changes   []FieldChange `json:"changes"`
+}
+
+// FieldChange 单个字段的变化
+type FieldChange struct {
+	Field    string      `json:"field"`
+	OldValue interface{} `json:"old_value"`
+	NewValue interface{} `json:"new_value"`
+}
+
+// IsEmpty 两份清单是否没有差异
+func (d *InventoryDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffInventories 比较两份以资产ID为键的资产清单
+func DiffInventories(before, after map[string]*Asset) *InventoryDiff {
+	diff := &InventoryDiff{
+		Added:   []*Asset{},
+		Removed: []*Asset{},
+		Changed: []AssetChange{},
+	}
+
+	for _, id := range sortedAssetIDs(after) {
+		old, exists := before[id]
+		if !exists {
+			diff.Added = append(diff.Added, after[id])
+			continue
+		}
+
+		if changes := diffAsset(old, after[id]); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, AssetChange{
+				ID:        id,
+				IPAddress: after[id].IPAddress,
+				Changes:   changes,
+			})
+		}
+	}
+
+	for _, id := range sortedAssetIDs(before) {
+		if _, exists := after[id]; !exists {
+			diff.Removed = append(diff.Removed, before[id])
+		}
+	}
+
+	return diff
+}
+
+// diffAsset 比较同一资产的关键字段
+func diffAsset(prev, curr *Asset) []FieldChange {
+	var changes []FieldChange
+	compare := func(field string, oldValue, newValue interface{}) {
+		if oldValue != newValue {
+			changes = append(changes, FieldChange{Field: field, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+
+	compare("ip_address", prev.IPAddress, curr.IPAddress)
+	compare("mac_address", prev.MACAddress, curr.MACAddress)
+	compare("hostname", prev.Hostname, curr.Hostname)
+	compare("vendor", prev.Vendor, curr.Vendor)
+	compare("device_type", prev.DeviceType, curr.DeviceType)
+	compare("os_family", prev.OSInfo.Family, curr.OSInfo.Family)
+	compare("os_version", prev.OSInfo.Version, curr.OSInfo.Version)
+	compare("is_active", prev.IsActive, curr.IsActive)
+
+	oldPorts, newPorts := portKeys(prev.OpenPorts), portKeys(curr.OpenPorts)
+	if !equalStrings(oldPorts, newPorts) {
+		changes = append(changes, FieldChange{Field: "open_ports", OldValue: oldPorts, NewValue: newPorts})
+	}
+
+	oldServices, newServices := serviceKeys(prev.Services), serviceKeys(curr.Services)
+	if !equalStrings(oldServices, newServices) {
+		changes = append(changes, FieldChange{Field: "services", OldValue: oldServices, NewValue: newServices})
+	}
+
+	return changes
+}
+
+// sortedAssetIDs 按ID排序，保证输出稳定
+func sortedAssetIDs(inventory map[string]*Asset) []string {
+	ids := make([]string, 0, len(inventory))
+	for id := range inventory {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// portKeys 开放端口列表转换为排序后的 端口/协议 列表，已关闭的端口不计入
+func portKeys(ports []PortInfo) []string {
+	keys := make([]string, 0, len(ports))
+	for _, p := range ports {
+		if p.State == "closed" {
+			continue
+		}
+		keys = append(keys, fmt.Sprintf("%d/%s", p.Port, p.Protocol))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// serviceKeys 服务列表转换为排序后的 名称:端口 列表
+func serviceKeys(services []ServiceInfo) []string {
+	keys := make([]string, 0, len(services))
+	for _, s := range services {
+		key := fmt.Sprintf("%s:%d", s.Name, s.Port)
+		if serviceDirection(s) == "outbound" {
+			key += " outbound"
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+package assets
+
+// 端口和服务的方向：listening为资产提供的服务（观测到资产作为服务端应答），
+// outbound为资产作为客户端发起连接时使用的外部服务
+
+// serviceDirection 服务的方向，早期版本保存的服务没有方向，均为资产提供的服务
+func serviceDirection(service ServiceInfo) string {
+	if service.Direction == "" {
+		return "listening"
+	}
+	return service.Direction
+}
+
+// countServices 统计指定方向的服务数量，调用方需持有读锁
+func (a *Asset) countServices(direction string) int {
+	count := 0
+	for _, service := range a.Services {
+		if serviceDirection(service) == direction {
+			count++
+		}
+	}
+	return count
+}
+
+// fillDirections 为早期版本保存的端口和服务补充方向
+func (a *Asset) fillDirections() {
+	for i := range a.OpenPorts {
+		if a.OpenPorts[i].Direction == "" {
+			a.OpenPorts[i].Direction = "listening"
+		}
+	}
+	for i := range a.Services {
+		a.Services[i].Direction = serviceDirection(a.Services[i])
+	}
+}
@@ -0,0 +1,128 @@
+package assets
+
+import "time"
+
+const (
+	// maxDNSQueryTypes 每个资产单独计数的查询类型数量，其余类型计入OTHER
+	maxDNSQueryTypes = 16
+
+	// maxDoHResolvers 每个资产记录的DoH解析服务数量
+	maxDoHResolvers = 8
+
+	// dnsHeavyQueriesPerMinute 每分钟查询数达到该值的客户端视为高频DNS用户
+	dnsHeavyQueriesPerMinute = 300
+)
+
+// mergeDNS 合并DNS协议信息，并在 behavior 中累积查询类型分布、查询速率以及DoT/DoH使用情况
+// 新报文中的查询列表等字段覆盖旧值，behavior中的计数器跨报文累积且数量有上限
+func mergeDNS(existing, new interface{}, now time.Time) interface{} {
+	newInfo, ok := new.(map[string]interface{})
+	if !ok {
+		return new
+	}
+	oldInfo, _ := existing.(map[string]interface{})
+
+	merged := make(map[string]interface{}, len(oldInfo)+len(newInfo))
+	for key, value := range oldInfo {
+		merged[key] = value
+	}
+	for key, value := range newInfo {
+		merged[key] = value
+	}
+
+	oldBehavior, _ := oldInfo["behavior"].(map[string]interface{})
+	observed, _ := newInfo["behavior"].(map[string]interface{})
+	merged["behavior"] = mergeDNSBehavior(oldBehavior, observed, stringList(newInfo["qtypes"]), now)
+
+	return merged
+}
+
+// mergeDNSBehavior 将单个报文观测到的行为合并到资产累积的DNS行为中
+func mergeDNSBehavior(behavior, observed map[string]interface{}, qtypes []string, now time.Time) map[string]interface{} {
+	result := make(map[string]interface{}, len(behavior)+4)
+	for key, value := range behavior {
+		result[key] = value
+	}
+
+	// 解析服务器和DoT只需出现一次即可确认
+	for _, flag := range []string{"resolver", "dot"} {
+		if observed[flag] == true {
+			result[flag] = true
+		}
+	}
+
+	if resolver, ok := observed["doh_resolver"].(string); ok && resolver != "" {
+		resolvers := stringList(result["doh_resolvers"])
+		if !containsString(resolvers, resolver) && len(resolvers) < maxDoHResolvers {
+			resolvers = append(resolvers, resolver)
+		}
+		result["doh"] = true
+		result["doh_resolvers"] = resolvers
+	}
+
+	if len(qtypes) == 0 {
+		return result
+	}
+
+	// 查询类型分布
+	counts := make(map[string]int)
+	if old, ok := result["qtypes"].(map[string]interface{}); ok {
+		for qtype, count := range old {
+			counts[qtype] = toInt(count)
+		}
+	} else if old, ok := result["qtypes"].(map[string]int); ok {
+		for qtype, count := range old {
+			counts[qtype] = count
+		}
+	}
+	for _, qtype := range qtypes {
+		if _, ok := counts[qtype]; !ok && len(counts) >= maxDNSQueryTypes {
+			qtype = "OTHER"
+		}
+		counts[qtype]++
+	}
+	result["qtypes"] = counts
+	result["queries"] = toInt(result["queries"]) + len(qtypes)
+
+	// 按分钟统计查询速率，记录峰值
+	windowStart := time.Unix(int64(toInt(result["window_start"])), 0)
+	windowQueries := toInt(result["window_queries"])
+	if now.Sub(windowStart) >= time.Minute || now.Before(windowStart) {
+		windowStart = now
+		windowQueries = 0
+	}
+	windowQueries += len(qtypes)
+
+	peak := toInt(result["peak_qpm"])
+	if windowQueries > peak {
+		peak = windowQueries
+	}
+	result["window_start"] = windowStart.Unix()
+	result["window_queries"] = windowQueries
+	result["peak_qpm"] = peak
+	result["heavy_user"] = peak >= dnsHeavyQueriesPerMinute
+
+	return result
+}
+
+// toInt 将整数或从存储加载的JSON数值转换为int
+func toInt(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
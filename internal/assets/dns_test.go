@@ -0,0 +1,118 @@
+package assets
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// dnsQuery 构造单个DNS查询报文的协议信息
+func dnsQuery(qtypes ...string) map[string]interface{} {
+	queries := make([]string, len(qtypes))
+	for i := range qtypes {
+		queries[i] = "example.com"
+	}
+	return map[string]interface{}{"queries": queries, "qtypes": qtypes}
+}
+
+func TestMergeDNSQueryTypes(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	var info interface{}
+	info = mergeDNS(info, dnsQuery("A", "AAAA"), now)
+	info = mergeDNS(info, dnsQuery("A"), now)
+	info = mergeDNS(info, dnsQuery("HTTPS"), now)
+
+	behavior := info.(map[string]interface{})["behavior"].(map[string]interface{})
+	if want := map[string]int{"A": 2, "AAAA": 1, "HTTPS": 1}; !reflect.DeepEqual(behavior["qtypes"], want) {
+		t.Errorf("qtypes = %v, want %v", behavior["qtypes"], want)
+	}
+	if behavior["queries"] != 4 {
+		t.Errorf("queries = %v, want 4", behavior["queries"])
+	}
+
+	// 超出上限的新查询类型计入OTHER，已计数的类型继续累积
+	for i := 0; i < maxDNSQueryTypes+5; i++ {
+		info = mergeDNS(info, dnsQuery(fmt.Sprintf("TYPE%d", i), "A"), now)
+	}
+	behavior = info.(map[string]interface{})["behavior"].(map[string]interface{})
+	counts := behavior["qtypes"].(map[string]int)
+	if len(counts) != maxDNSQueryTypes+1 {
+		t.Errorf("qtype count = %d, want %d", len(counts), maxDNSQueryTypes+1)
+	}
+	// 已有3种类型，新增的前13种单独计数，其余8种计入OTHER
+	if counts["A"] != 2+maxDNSQueryTypes+5 || counts["OTHER"] != 8 {
+		t.Errorf("A = %d, OTHER = %d, want %d, 8", counts["A"], counts["OTHER"], 2+maxDNSQueryTypes+5)
+	}
+
+	// 从存储加载的计数为JSON数值
+	loaded := map[string]interface{}{"behavior": map[string]interface{}{
+		"qtypes":  map[string]interface{}{"A": float64(5)},
+		"queries": float64(5),
+	}}
+	behavior = mergeDNS(loaded, dnsQuery("A"), now).(map[string]interface{})["behavior"].(map[string]interface{})
+	if counts := behavior["qtypes"].(map[string]int); counts["A"] != 6 || behavior["queries"] != 6 {
+		t.Errorf("loaded qtypes = %v, queries = %v, want A=6, 6", behavior["qtypes"], behavior["queries"])
+	}
+}
+
+func TestMergeDNSHeavyUser(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	burst := make([]string, dnsHeavyQueriesPerMinute/10)
+	for i := range burst {
+		burst[i] = "A"
+	}
+
+	var info interface{}
+	for i := 0; i < 9; i++ {
+		info = mergeDNS(info, dnsQuery(burst...), start.Add(time.Duration(i)*time.Second))
+	}
+	behavior := info.(map[string]interface{})["behavior"].(map[string]interface{})
+	if behavior["heavy_user"] != false {
+		t.Errorf("heavy_user after %d queries = %v, want false", behavior["queries"], behavior["heavy_user"])
+	}
+
+	// 新的一分钟重新计数，不会累计为高频
+	info = mergeDNS(info, dnsQuery(burst...), start.Add(2*time.Minute))
+	behavior = info.(map[string]interface{})["behavior"].(map[string]interface{})
+	if behavior["heavy_user"] != false || behavior["window_queries"] != len(burst) {
+		t.Errorf("new window heavy_user = %v, window_queries = %v, want false, %d", behavior["heavy_user"], behavior["window_queries"], len(burst))
+	}
+
+	for i := 0; i < 9; i++ {
+		info = mergeDNS(info, dnsQuery(burst...), start.Add(2*time.Minute+time.Duration(i)*time.Second))
+	}
+	behavior = info.(map[string]interface{})["behavior"].(map[string]interface{})
+	if behavior["heavy_user"] != true || behavior["peak_qpm"] != dnsHeavyQueriesPerMinute {
+		t.Errorf("heavy_user = %v, peak_qpm = %v, want true, %d", behavior["heavy_user"], behavior["peak_qpm"], dnsHeavyQueriesPerMinute)
+	}
+}
+
+func TestMergeDNSEncryptedFlags(t *testing.T) {
+	now := time.Now()
+	observe := func(behavior map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"behavior": behavior}
+	}
+
+	var info interface{}
+	info = mergeDNS(info, observe(map[string]interface{}{"dot": true}), now)
+	for i := 0; i < maxDoHResolvers+3; i++ {
+		info = mergeDNS(info, observe(map[string]interface{}{"doh_resolver": fmt.Sprintf("doh%d.example", i)}), now)
+	}
+	info = mergeDNS(info, observe(map[string]interface{}{"doh_resolver": "doh0.example"}), now)
+	// 之后的普通查询不清除已确认的标志
+	info = mergeDNS(info, dnsQuery("A"), now)
+
+	behavior := info.(map[string]interface{})["behavior"].(map[string]interface{})
+	if behavior["dot"] != true || behavior["doh"] != true {
+		t.Errorf("dot = %v, doh = %v, want true, true", behavior["dot"], behavior["doh"])
+	}
+	if _, ok := behavior["resolver"]; ok {
+		t.Errorf("resolver = %v, want unset", behavior["resolver"])
+	}
+	resolvers := stringList(behavior["doh_resolvers"])
+	if len(resolvers) != maxDoHResolvers || resolvers[0] != "doh0.example" {
+		t.Errorf("doh_resolvers = %v, want first %d", resolvers, maxDoHResolvers)
+	}
+}
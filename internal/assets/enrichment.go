@@ -0,0 +1,72 @@
+package assets
+
+import (
+	"log"
+	"time"
+
+	"assets_discovery/internal/enrich"
+)
+
+// requestEnrichment 将资产的IP提交到补充流水线，未启用流水线时为空操作
+func (am *AssetManager) requestEnrichment(asset *Asset) {
+	if am.pipeline == nil {
+		return
+	}
+
+	asset.mu.RLock()
+	ip := asset.IPAddress
+	asset.mu.RUnlock()
+
+	if ip != "" {
+		am.pipeline.Submit(ip)
+	}
+}
+
+// applyEnrichment 将补充流水线的步骤结果写回使用该IP的资产
+// reverse_dns的结果只补全空缺的主机名，其他步骤的结果合并到protocols.enrichment
+func (am *AssetManager) applyEnrichment(ip, stage string, result map[string]interface{}) {
+	if stage == enrich.ReverseDNSStageName {
+		if hostname, ok := result["hostname"].(string); ok {
+			am.applyReverseDNS(ip, hostname)
+		}
+		return
+	}
+
+	am.mutex.RLock()
+	matched := am.index.byIP(ip)
+	am.mutex.RUnlock()
+
+	for _, asset := range matched {
+		asset.mergeEnrichment(result)
+		am.logs.Printf("enrich", "信息补充步骤 %s 补全资产: %s (%s)", stage, asset.ID, ip)
+		am.saveAsync(asset.ID)
+	}
+}
+
+// mergeEnrichment 将补充信息合并到protocols.enrichment，同名字段以新结果为准
+func (a *Asset) mergeEnrichment(result map[string]interface{}) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.Protocols == nil {
+		a.Protocols = make(map[string]interface{})
+	}
+	merged, _ := a.Protocols["enrichment"].(map[string]interface{})
+	if merged == nil {
+		merged = make(map[string]interface{}, len(result))
+	}
+	for key, value := range result {
+		merged[key] = value
+	}
+	a.Protocols["enrichment"] = merged
+	a.LastUpdate = time.Now()
+}
+
+// logPipeline 输出补充流水线的步骤顺序
+func (am *AssetManager) logPipeline() {
+	if stages := am.pipeline.Stages(); len(stages) > 0 {
+		log.Printf("已启用信息补充流水线，步骤: %v", stages)
+	} else {
+		log.Printf("信息补充流水线没有启用的步骤")
+	}
+}
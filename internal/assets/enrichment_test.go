@@ -0,0 +1,113 @@
+package assets
+
+import (
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"assets_discovery/internal/enrich"
+)
+
+func TestApplyEnrichment(t *testing.T) {
+	am := newTestManager(newTestConfig())
+	now := time.Now()
+	am.UpdateAsset(&AssetInfo{IPAddress: "203.0.113.5", MACAddress: testMAC, Timestamp: now})
+	am.UpdateAsset(&AssetInfo{IPAddress: "203.0.113.6", MACAddress: "00:1a:2b:3c:4d:02", Hostname: "observed", Timestamp: now})
+
+	am.applyEnrichment("203.0.113.5", enrich.GeoIPStageName, map[string]interface{}{"asn": 64500, "country": "NL"})
+	am.applyEnrichment("203.0.113.5", "tags", map[string]interface{}{"country": "DE", "tag": "lab"})
+	am.applyEnrichment("203.0.113.5", enrich.ReverseDNSStageName, map[string]interface{}{"hostname": "edge.example.net"})
+	am.applyEnrichment("203.0.113.6", enrich.ReverseDNSStageName, map[string]interface{}{"hostname": "ptr.example.net"})
+
+	asset, _ := am.GetAsset("mac_" + testMAC)
+	// 同名字段以后执行的步骤为准
+	want := map[string]interface{}{"asn": 64500, "country": "DE", "tag": "lab"}
+	if got := asset.Protocols["enrichment"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("protocols.enrichment = %v, want %v", got, want)
+	}
+	if asset.Hostname != "edge.example.net" {
+		t.Errorf("Hostname = %q, want edge.example.net", asset.Hostname)
+	}
+
+	// 反向解析不覆盖被动观测到的主机名
+	if observed, _ := am.GetAsset("mac_00:1a:2b:3c:4d:02"); observed.Hostname != "observed" {
+		t.Errorf("observed Hostname = %q, want observed", observed.Hostname)
+	}
+}
+
+// blockingEnricher 测试用的信息补充，每次查询阻塞到release关闭，模拟慢速的外部数据源
+type blockingEnricher struct {
+	calls   int32
+	started chan string
+	release chan struct{}
+}
+
+func (e *blockingEnricher) Enrich(ip string) (map[string]interface{}, error) {
+	atomic.AddInt32(&e.calls, 1)
+	e.started <- ip
+	<-e.release
+	return map[string]interface{}{"asn": 64500}, nil
+}
+
+func (e *blockingEnricher) Close() error {
+	return nil
+}
+
+func TestEnrichOutsideLock(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Enrichment.Enabled = true
+	am := newTestManager(cfg)
+	enricher := &blockingEnricher{started: make(chan string, 2), release: make(chan struct{})}
+	am.enricher = enricher
+
+	now := time.Now()
+	am.UpdateAsset(&AssetInfo{IPAddress: "192.168.1.10", MACAddress: "00:1a:2b:3c:4d:02", Timestamp: now})
+
+	updated := make(chan struct{})
+	go func() {
+		am.UpdateAsset(&AssetInfo{IPAddress: "203.0.113.5", MACAddress: testMAC, Timestamp: now})
+		close(updated)
+	}()
+
+	select {
+	case ip := <-enricher.started:
+		if ip != "203.0.113.5" {
+			t.Errorf("Enrich(%s), want 203.0.113.5", ip)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Enrich not called for public IP")
+	}
+
+	// 查询进行中，其他资产的读写不被阻塞
+	done := make(chan struct{})
+	go func() {
+		am.UpdateAsset(&AssetInfo{IPAddress: "192.168.1.11", MACAddress: "00:1a:2b:3c:4d:03", Timestamp: now})
+		am.GetAllAssets()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		close(enricher.release)
+		t.Fatalf("manager blocked while Enrich was running")
+	}
+
+	close(enricher.release)
+	<-updated
+
+	asset, ok := am.GetAsset("mac_" + testMAC)
+	if !ok {
+		t.Fatalf("enriched asset not created")
+	}
+	want := map[string]interface{}{"asn": 64500}
+	if got := asset.Protocols["enrichment"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("protocols.enrichment = %v, want %v", got, want)
+	}
+
+	// 结果按IP缓存，再次出现时不重复查询
+	am.UpdateAsset(&AssetInfo{IPAddress: "203.0.113.5", MACAddress: testMAC, Timestamp: now})
+	if calls := atomic.LoadInt32(&enricher.calls); calls != 1 {
+		t.Errorf("Enrich calls = %d, want 1", calls)
+	}
+}
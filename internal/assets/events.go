@@ -0,0 +1,104 @@
+package assets
+
+import (
+	"sync"
+	"time"
+)
+
+// 资产事件类型
+const (
+	EventNewAsset = "new_asset" // 资产首次被确认（置信度达到门槛）
+	EventChange   = "change"    // 资产新增了变更记录
+	EventFinding  = "finding"   // 资产首次出现安全暴露
+	EventRemoved  = "removed"   // 资产被删除或清除
+)
+
+// AssetEvent 资产事件，删除事件不带资产
+// Asset指向内存中的资产，订阅者读取到的是处理事件时的最新状态，可能已包含后续的更新
+type AssetEvent struct {
+	Type      string
+	AssetID   string
+	Timestamp time.Time
+	Asset     *Asset
+	Change    *ChangeRecord
+	Finding   *Finding
+}
+
+// eventBus 将资产事件分发给订阅者，订阅者的缓冲已满时丢弃事件，不阻塞资产更新
+type eventBus struct {
+	mu      sync.Mutex
+	subs    map[chan AssetEvent]struct{}
+	dropped uint64
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subs: make(map[chan AssetEvent]struct{})}
+}
+
+// Subscribe 订阅资产事件，buffer为订阅者缓冲的事件数，返回的函数取消订阅并关闭通道
+// 订阅者处理不及时、缓冲已满时后续事件被丢弃
+func (am *AssetManager) Subscribe(buffer int) (<-chan AssetEvent, func()) {
+	ch := make(chan AssetEvent, buffer)
+
+	bus := am.events
+	bus.mu.Lock()
+	bus.subs[ch] = struct{}{}
+	bus.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			bus.mu.Lock()
+			delete(bus.subs, ch)
+			bus.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// publishEvent 向所有订阅者发送事件，没有订阅者时为空操作
+func (am *AssetManager) publishEvent(event AssetEvent) {
+	bus := am.events
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	if len(bus.subs) == 0 {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	for ch := range bus.subs {
+		select {
+		case ch <- event:
+		default:
+			bus.dropped++
+			am.logs.Printf("event-dropped", "事件订阅者处理不及时，已丢弃 %d 个资产事件", bus.dropped)
+		}
+	}
+}
+
+// publishChanges 发送资产自第from条起新增的变更记录，低置信度资产不发送
+func (am *AssetManager) publishChanges(asset *Asset, from int) {
+	if asset.isTentative() {
+		return
+	}
+
+	asset.mu.RLock()
+	var changes []ChangeRecord
+	if from < len(asset.Changes) {
+		changes = append(changes, asset.Changes[from:]...)
+	}
+	asset.mu.RUnlock()
+
+	for i := range changes {
+		am.publishEvent(AssetEvent{
+			Type:      EventChange,
+			AssetID:   asset.ID,
+			Timestamp: changes[i].Timestamp,
+			Asset:     asset,
+			Change:    &changes[i],
+		})
+	}
+}
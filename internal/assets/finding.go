@@ -0,0 +1,242 @@
+package assets
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Finding 被动观测到的安全暴露，如未启用认证的服务、出厂默认配置的登录提示
+type Finding struct {
+	ID          string    `json:"id"`       // 发现类型，如redis_no_auth
+	Severity    string    `json:"severity"` // low, medium, high
+	Port        int       `json:"port,omitempty"`
+	Description string    `json:"description"`
+	Evidence    string    `json:"evidence,omitempty"` // 触发该发现的横幅或响应内容
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// key 同一资产上同一端口的同类发现只记录一次
+func (f Finding) key() string {
+	return fmt.Sprintf("%s/%d", f.ID, f.Port)
+}
+
+// FindingDetector 安全暴露检测插件
+type FindingDetector interface {
+	// 检测器名称
+	Name() string
+
+	// 根据单次观测检测安全暴露，时间字段由调用方填写
+	Detect(assetInfo *AssetInfo) []Finding
+}
+
+var (
+	detectorMu sync.RWMutex
+	detectors  []FindingDetector
+)
+
+// RegisterFindingDetector 注册安全暴露检测器，与内置检测器一起参与检测
+func RegisterFindingDetector(d FindingDetector) {
+	detectorMu.Lock()
+	defer detectorMu.Unlock()
+
+	detectors = append(detectors, d)
+}
+
+// builtinDetectors 内置的安全暴露检测器
+var builtinDetectors = []FindingDetector{
+	redisDetector{},
+	telnetDetector{},
+}
+
+// detectFindings 依次调用所有检测器，返回本次观测到的安全暴露
+func detectFindings(assetInfo *AssetInfo) []Finding {
+	detectorMu.RLock()
+	chain := append(append([]FindingDetector{}, builtinDetectors...), detectors...)
+	detectorMu.RUnlock()
+
+	var found []Finding
+	for _, d := range chain {
+		found = append(found, d.Detect(assetInfo)...)
+	}
+	return found
+}
+
+// recordFindings 将观测到的安全暴露合并到资产中，返回首次出现的发现
+func (a *Asset) recordFindings(found []Finding, seen time.Time) []Finding {
+	if len(found) == 0 {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var added []Finding
+	for _, f := range found {
+		f.FirstSeen, f.LastSeen = seen, seen
+		if i := a.findingIndex(f.key()); i >= 0 {
+			a.Findings[i].LastSeen = seen
+			a.Findings[i].Evidence = f.Evidence
+			continue
+		}
+		a.Findings = append(a.Findings, f)
+		added = append(added, f)
+	}
+	return added
+}
+
+// findingIndex 返回指定发现在资产中的位置，不存在时返回-1，调用方需持有锁
+func (a *Asset) findingIndex(key string) int {
+	for i, f := range a.Findings {
+		if f.key() == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// copyFindings 返回资产当前发现的副本
+func (a *Asset) copyFindings() []Finding {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return append([]Finding(nil), a.Findings...)
+}
+
+// adoptFindings 合并资产记录时保留双方的发现，调用方需持有两个资产的锁
+func (a *Asset) adoptFindings(other *Asset) {
+	for _, f := range other.Findings {
+		i := a.findingIndex(f.key())
+		if i < 0 {
+			a.Findings = append(a.Findings, f)
+			continue
+		}
+		if f.FirstSeen.Before(a.Findings[i].FirstSeen) {
+			a.Findings[i].FirstSeen = f.FirstSeen
+		}
+		if f.LastSeen.After(a.Findings[i].LastSeen) {
+			a.Findings[i].LastSeen = f.LastSeen
+		}
+	}
+}
+
+// mergeStoredFindings 保存前合并存储中其他采集器记录的发现
+func mergeStoredFindings(current, updated map[string]interface{}) {
+	stored, _ := current["findings"].([]interface{})
+	findings, _ := updated["findings"].([]interface{})
+	seen := make(map[string]bool, len(findings))
+	for _, f := range findings {
+		seen[storedFindingKey(f)] = true
+	}
+	for _, f := range stored {
+		if key := storedFindingKey(f); !seen[key] {
+			seen[key] = true
+			findings = append(findings, f)
+		}
+	}
+	if len(findings) > 0 {
+		updated["findings"] = findings
+	}
+}
+
+// storedFindingKey 存储中发现的键，端口为JSON数字
+func storedFindingKey(f interface{}) string {
+	m, _ := f.(map[string]interface{})
+	port, _ := m["port"].(float64)
+	return fmt.Sprintf("%v/%d", m["id"], int(port))
+}
+
+// redisDetector Redis服务端在连接未认证时响应了INFO命令，说明未设置密码
+type redisDetector struct{}
+
+func (redisDetector) Name() string {
+	return "redis"
+}
+
+func (redisDetector) Detect(assetInfo *AssetInfo) []Finding {
+	redisInfo, ok := assetInfo.Protocols["redis"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	info, _ := redisInfo["info"].(bool)
+	authenticated, _ := redisInfo["authenticated"].(bool)
+	if !info || authenticated {
+		return nil
+	}
+
+	port, _ := redisInfo["port"].(int)
+	evidence := "INFO响应"
+	if version, _ := redisInfo["version"].(string); version != "" {
+		evidence = "INFO响应 redis_version:" + version
+	}
+	return []Finding{{
+		ID:          "redis_no_auth",
+		Severity:    "high",
+		Port:        port,
+		Description: "Redis未启用认证，连接未发送AUTH即可执行INFO命令",
+		Evidence:    evidence,
+	}}
+}
+
+// telnetDefaultKeywords Telnet横幅中表明出厂默认口令或默认固件的提示（小写），
+// "(none) login:"是未设置主机名的嵌入式Linux固件的登录提示，常见于使用默认口令的IoT设备
+var telnetDefaultKeywords = []string{
+	"default password", "default credentials", "default login", "default username", "(none) login:",
+}
+
+// telnetAuthKeywords 表明Telnet服务要求登录的提示（小写）
+var telnetAuthKeywords = []string{"login", "password", "username", "user name", "user access verification"}
+
+// telnetPromptSuffixes 命令行提示符的结尾，横幅以提示符结尾且没有登录提示时说明无需登录即可执行命令
+var telnetPromptSuffixes = []string{"#", "$", ">"}
+
+// telnetDetector 根据Telnet登录横幅检测无需登录的命令行和默认口令提示
+type telnetDetector struct{}
+
+func (telnetDetector) Name() string {
+	return "telnet"
+}
+
+func (telnetDetector) Detect(assetInfo *AssetInfo) []Finding {
+	telnetInfo, ok := assetInfo.Protocols["telnet"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	banner, _ := telnetInfo["banner"].(string)
+	if banner == "" {
+		return nil
+	}
+	port, _ := telnetInfo["port"].(int)
+	lower := strings.ToLower(banner)
+
+	for _, keyword := range telnetDefaultKeywords {
+		if strings.Contains(lower, keyword) {
+			return []Finding{{
+				ID:          "telnet_default_credentials",
+				Severity:    "medium",
+				Port:        port,
+				Description: "Telnet登录提示表明设备可能使用出厂默认口令",
+				Evidence:    banner,
+			}}
+		}
+	}
+
+	for _, keyword := range telnetAuthKeywords {
+		if strings.Contains(lower, keyword) {
+			return nil
+		}
+	}
+	for _, suffix := range telnetPromptSuffixes {
+		if strings.HasSuffix(strings.TrimSpace(banner), suffix) {
+			return []Finding{{
+				ID:          "telnet_no_auth",
+				Severity:    "high",
+				Port:        port,
+				Description: "Telnet无需登录即提供命令行",
+				Evidence:    banner,
+			}}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package assets
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"assets_discovery/internal/alert"
+)
+
+// findingIDs 返回发现的ID列表
+func findingIDs(findings []Finding) []string {
+	var ids []string
+	for _, f := range findings {
+		ids = append(ids, f.ID)
+	}
+	return ids
+}
+
+func TestDetectFindings(t *testing.T) {
+	tests := []struct {
+		name      string
+		protocols map[string]interface{}
+		want      []string
+	}{
+		{
+			name:      "redis info without auth",
+			protocols: map[string]interface{}{"redis": map[string]interface{}{"port": 6379, "info": true, "authenticated": false, "version": "7.2.4"}},
+			want:      []string{"redis_no_auth"},
+		},
+		{
+			name:      "redis info after auth",
+			protocols: map[string]interface{}{"redis": map[string]interface{}{"port": 6379, "info": true, "authenticated": true}},
+		},
+		{
+			name:      "redis noauth error",
+			protocols: map[string]interface{}{"redis": map[string]interface{}{"port": 6379, "auth_required": true}},
+		},
+		{
+			name:      "telnet shell without login",
+			protocols: map[string]interface{}{"telnet": map[string]interface{}{"port": 23, "banner": "BusyBox v1.31.1 built-in shell (ash)\n# "}},
+			want:      []string{"telnet_no_auth"},
+		},
+		{
+			name:      "telnet unconfigured firmware",
+			protocols: map[string]interface{}{"telnet": map[string]interface{}{"port": 23, "banner": "(none) login: "}},
+			want:      []string{"telnet_default_credentials"},
+		},
+		{
+			name:      "telnet default password hint",
+			protocols: map[string]interface{}{"telnet": map[string]interface{}{"port": 2323, "banner": "Router\nDefault password is admin\nLogin: "}},
+			want:      []string{"telnet_default_credentials"},
+		},
+		{
+			name:      "telnet login prompt",
+			protocols: map[string]interface{}{"telnet": map[string]interface{}{"port": 23, "banner": "User Access Verification\nPassword: "}},
+		},
+		{
+			name:      "telnet prompt after login banner",
+			protocols: map[string]interface{}{"telnet": map[string]interface{}{"port": 23, "banner": "Last login: Mon Jun 3\nrouter>"}},
+		},
+		{
+			name:      "no indicators",
+			protocols: map[string]interface{}{"http": map[string]interface{}{"server": "nginx"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findingIDs(detectFindings(&AssetInfo{IPAddress: "10.0.0.5", Protocols: tt.protocols}))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("detectFindings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// ftpAnonymousDetector 测试用的自定义检测器，FTP横幅允许匿名登录时记录发现
+type ftpAnonymousDetector struct{}
+
+func (ftpAnonymousDetector) Name() string {
+	return "ftp_anonymous"
+}
+
+func (ftpAnonymousDetector) Detect(assetInfo *AssetInfo) []Finding {
+	ftpInfo, ok := assetInfo.Protocols["ftp"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if banner, _ := ftpInfo["banner"].(string); strings.Contains(banner, "anonymous") {
+		return []Finding{{ID: "ftp_anonymous", Severity: "medium", Port: 21, Evidence: banner}}
+	}
+	return nil
+}
+
+func TestRegisterFindingDetector(t *testing.T) {
+	detectorMu.Lock()
+	saved := detectors
+	detectorMu.Unlock()
+	t.Cleanup(func() {
+		detectorMu.Lock()
+		detectors = saved
+		detectorMu.Unlock()
+	})
+
+	RegisterFindingDetector(ftpAnonymousDetector{})
+
+	assetInfo := &AssetInfo{
+		IPAddress: "10.0.0.5",
+		Protocols: map[string]interface{}{
+			"ftp":   map[string]interface{}{"banner": "220 anonymous access allowed"},
+			"redis": map[string]interface{}{"port": 6379, "info": true},
+		},
+	}
+	// 自定义检测器在内置检测器之后执行
+	if got, want := findingIDs(detectFindings(assetInfo)), []string{"redis_no_auth", "ftp_anonymous"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("detectFindings() = %v, want %v", got, want)
+	}
+}
+
+func TestFindingAlert(t *testing.T) {
+	cfg := newTestConfig()
+	alerts := newAlertRecorder(t, cfg)
+	am := newTestManager(cfg)
+
+	redisInfo := func(ts time.Time) *AssetInfo {
+		return &AssetInfo{
+			IPAddress:  "10.0.0.5",
+			MACAddress: testMAC,
+			OpenPorts:  []int{6379},
+			Protocols: map[string]interface{}{
+				"redis": map[string]interface{}{"port": 6379, "info": true, "authenticated": false, "version": "7.2.4"},
+			},
+			Timestamp: ts,
+		}
+	}
+
+	now := time.Now()
+	am.UpdateAsset(redisInfo(now))
+	// 同一暴露再次出现只更新时间，不重复告警
+	am.UpdateAsset(redisInfo(now.Add(time.Minute)))
+
+	asset, ok := am.GetAsset("mac_" + testMAC)
+	if !ok {
+		t.Fatalf("GetAsset(mac_%s) not found", testMAC)
+	}
+	if len(asset.Findings) != 1 {
+		t.Fatalf("Findings = %+v, want one redis_no_auth", asset.Findings)
+	}
+	f := asset.Findings[0]
+	if f.ID != "redis_no_auth" || f.Port != 6379 || f.Evidence != "INFO响应 redis_version:7.2.4" {
+		t.Errorf("finding = %+v, want redis_no_auth on 6379", f)
+	}
+	if !f.FirstSeen.Equal(now) || !f.LastSeen.Equal(now.Add(time.Minute)) {
+		t.Errorf("finding seen = %v - %v, want %v - %v", f.FirstSeen, f.LastSeen, now, now.Add(time.Minute))
+	}
+
+	waitFor(t, "security_finding alert", func() bool {
+		return len(alerts.ofType(alert.EventFinding)) > 0
+	})
+	time.Sleep(50 * time.Millisecond)
+
+	events := alerts.ofType(alert.EventFinding)
+	if len(events) != 1 {
+		t.Fatalf("security_finding alerts = %d, want 1", len(events))
+	}
+	if events[0].AssetID != "mac_"+testMAC || !strings.Contains(events[0].Title, "redis_no_auth") {
+		t.Errorf("alert = %s %q, want mac_%s redis_no_auth", events[0].AssetID, events[0].Title, testMAC)
+	}
+}
@@ -0,0 +1,127 @@
+package assets
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 主机关联键
+const (
+	HostKeyHostname = "hostname" // 按主机名（不含域名，不区分大小写）关联
+	HostKeyDUID     = "duid"     // 按DHCP客户端标识中的DUID关联，同一主机的各网卡使用相同的DUID
+)
+
+// Host 由同一台物理主机的多个资产（绑定网卡、多宿主服务器的各个接口）组成的主机视图
+// 不单独保存，按当前资产计算；没有关联键的资产单独作为一台主机
+type Host struct {
+	ID           string    `json:"id"`
+	Key          string    `json:"key,omitempty"`   // 关联键类型，单独成为主机的资产为空
+	Value        string    `json:"value,omitempty"` // 关联键的取值
+	Hostname     string    `json:"hostname"`
+	DeviceType   string    `json:"device_type"`
+	OSFamily     string    `json:"os_family"`
+	IPAddresses  []string  `json:"ip_addresses"`
+	MACAddresses []string  `json:"mac_addresses"`
+	AssetIDs     []string  `json:"asset_ids"`
+	FirstSeen    time.Time `json:"first_seen"`
+	LastSeen     time.Time `json:"last_seen"`
+	IsActive     bool      `json:"is_active"`
+}
+
+// ValidHostKey 检查主机关联键是否受支持
+func ValidHostKey(key string) error {
+	switch key {
+	case HostKeyHostname, HostKeyDUID:
+		return nil
+	}
+	return fmt.Errorf("不支持的主机关联键: %s，可选值为hostname、duid", key)
+}
+
+// GetHosts 按关联键将资产归并为主机，结果按主机ID排序
+func (am *AssetManager) GetHosts(key string) ([]*Host, error) {
+	if err := ValidHostKey(key); err != nil {
+		return nil, err
+	}
+
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+
+	groups := make(map[string]*Host)
+	for _, asset := range am.assets {
+		asset.mu.RLock()
+		value := asset.hostKey(key)
+		id := "host_" + asset.ID
+		if value != "" {
+			id = "host_" + key + "_" + value
+		}
+
+		host, ok := groups[id]
+		if !ok {
+			host = &Host{ID: id, FirstSeen: asset.FirstSeen}
+			if value != "" {
+				host.Key, host.Value = key, value
+			}
+			groups[id] = host
+		}
+		host.add(asset)
+		asset.mu.RUnlock()
+	}
+
+	hosts := make([]*Host, 0, len(groups))
+	for _, host := range groups {
+		sort.Strings(host.AssetIDs)
+		sort.Strings(host.IPAddresses)
+		sort.Strings(host.MACAddresses)
+		hosts = append(hosts, host)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].ID < hosts[j].ID })
+	return hosts, nil
+}
+
+// hostKey 返回资产的主机关联键取值，没有时返回空，调用方需持有读锁
+func (a *Asset) hostKey(key string) string {
+	switch key {
+	case HostKeyHostname:
+		name := a.ShortName
+		if name == "" {
+			name, _, _ = strings.Cut(a.Hostname, ".")
+		}
+		return strings.ToLower(name)
+	case HostKeyDUID:
+		dhcpInfo, _ := a.Protocols["dhcp"].(map[string]interface{})
+		duid, _ := dhcpInfo["duid"].(string)
+		return duid
+	}
+	return ""
+}
+
+// add 将资产并入主机，主机名、设备类型和操作系统取最近出现的资产的值，调用方需持有资产的读锁
+func (h *Host) add(asset *Asset) {
+	h.AssetIDs = append(h.AssetIDs, asset.ID)
+	if asset.IPAddress != "" && !containsString(h.IPAddresses, asset.IPAddress) {
+		h.IPAddresses = append(h.IPAddresses, asset.IPAddress)
+	}
+	if asset.MACAddress != "" && !containsString(h.MACAddresses, asset.MACAddress) {
+		h.MACAddresses = append(h.MACAddresses, asset.MACAddress)
+	}
+	if asset.IsActive {
+		h.IsActive = true
+	}
+	if asset.FirstSeen.Before(h.FirstSeen) {
+		h.FirstSeen = asset.FirstSeen
+	}
+	if !asset.LastSeen.Before(h.LastSeen) {
+		h.LastSeen = asset.LastSeen
+		if asset.Hostname != "" {
+			h.Hostname = asset.Hostname
+		}
+		if asset.DeviceType != "" {
+			h.DeviceType = asset.DeviceType
+		}
+		if asset.OSInfo.Family != "" {
+			h.OSFamily = asset.OSInfo.Family
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package assets
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetHosts(t *testing.T) {
+	const (
+		serverDUID = "000100012d6b1c2e001a2b3c4d01"
+		laptopDUID = "000400a1b2c3d4e5f60718293a4b5c6d7e8f"
+	)
+
+	am := newTestManager(newTestConfig())
+	now := time.Now()
+	observe := func(ip, mac, hostname, iaid, duid string, ts time.Time) {
+		protocols := map[string]interface{}{}
+		if duid != "" {
+			protocols["dhcp"] = map[string]interface{}{"iaid": iaid, "duid": duid}
+		}
+		am.UpdateAsset(&AssetInfo{IPAddress: ip, MACAddress: mac, Hostname: hostname, Protocols: protocols, Timestamp: ts})
+	}
+
+	// 多宿主数据库服务器的两块网卡：主机名大小写和域名不同，DUID相同、IAID不同
+	observe("10.0.0.10", "00:1a:2b:3c:4d:01", "DB01.corp.example", "00000001", serverDUID, now.Add(-time.Hour))
+	observe("10.0.1.10", "00:1a:2b:3c:4d:02", "db01", "00000002", serverDUID, now)
+	observe("10.0.0.20", "00:1a:2b:3c:4d:03", "laptop", "00000001", laptopDUID, now)
+	// 没有主机名和DUID的资产单独作为一台主机
+	observe("10.0.0.30", "00:1a:2b:3c:4d:04", "", "", "", now)
+
+	type wantHost struct {
+		id       string
+		assetIDs []string
+		ips      []string
+	}
+	tests := []struct {
+		key  string
+		want []wantHost
+	}{
+		{
+			key: HostKeyHostname,
+			want: []wantHost{
+				{"host_hostname_db01", []string{"mac_00:1a:2b:3c:4d:01", "mac_00:1a:2b:3c:4d:02"}, []string{"10.0.0.10", "10.0.1.10"}},
+				{"host_hostname_laptop", []string{"mac_00:1a:2b:3c:4d:03"}, []string{"10.0.0.20"}},
+				{"host_mac_00:1a:2b:3c:4d:04", []string{"mac_00:1a:2b:3c:4d:04"}, []string{"10.0.0.30"}},
+			},
+		},
+		{
+			key: HostKeyDUID,
+			want: []wantHost{
+				{"host_duid_" + serverDUID, []string{"mac_00:1a:2b:3c:4d:01", "mac_00:1a:2b:3c:4d:02"}, []string{"10.0.0.10", "10.0.1.10"}},
+				{"host_duid_" + laptopDUID, []string{"mac_00:1a:2b:3c:4d:03"}, []string{"10.0.0.20"}},
+				{"host_mac_00:1a:2b:3c:4d:04", []string{"mac_00:1a:2b:3c:4d:04"}, []string{"10.0.0.30"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			hosts, err := am.GetHosts(tt.key)
+			if err != nil {
+				t.Fatalf("GetHosts(%s) error = %v", tt.key, err)
+			}
+
+			var got []wantHost
+			for _, h := range hosts {
+				got = append(got, wantHost{h.ID, h.AssetIDs, h.IPAddresses})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("GetHosts(%s) = %+v, want %+v", tt.key, got, tt.want)
+			}
+
+			// 主机的时间范围覆盖所有网卡，主机名取最近出现的网卡
+			server := hosts[0]
+			if !server.FirstSeen.Equal(now.Add(-time.Hour)) || !server.LastSeen.Equal(now) {
+				t.Errorf("server seen = %v - %v, want %v - %v", server.FirstSeen, server.LastSeen, now.Add(-time.Hour), now)
+			}
+			if server.Hostname != "db01" || !server.IsActive {
+				t.Errorf("server hostname = %q, active = %v, want db01, true", server.Hostname, server.IsActive)
+			}
+		})
+	}
+
+	if _, err := am.GetHosts("serial"); err == nil {
+		t.Errorf("GetHosts(serial) error = nil, want error")
+	}
+}
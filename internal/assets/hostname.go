@@ -0,0 +1,104 @@
+package assets
+
+import (
+	"net"
+	"strings"
+	"time"
+)
+
+// hostnameSourcePriority 主机名来源的可信度，数值越大越可信
+// DHCP和名称服务响应是主机声明的自身名称，HTTP Host等只是间接推测
+var hostnameSourcePriority = map[string]int{
+	"dhcp":  4,
+	"nbns":  3,
+	"llmnr": 3,
+	"mdns":  3,
+	"rdp":   2,
+	"http":  1,
+	// reverse_dns和pcapng名称解析记录只用于补全空缺的主机名，任何被动观测到的名称都优先
+	"reverse_dns": 0,
+	"pcapng_nrb":  0,
+}
+
+// NormalizeHostname 规范化主机名：去掉空白和末尾的点并转为小写，IP地址不是主机名时返回空
+func NormalizeHostname(name string) string {
+	name = strings.ToLower(strings.TrimRight(strings.TrimSpace(name), "."))
+	if name == "" || net.ParseIP(name) != nil {
+		return ""
+	}
+	return name
+}
+
+// SplitHostname 将规范化后的主机名拆分为短名称和域名，例如 pc01.corp.example -> pc01, corp.example
+func SplitHostname(name string) (string, string) {
+	if i := strings.Index(name, "."); i > 0 {
+		return name[:i], name[i+1:]
+	}
+	return name, ""
+}
+
+// setHostname 设置主机名及拆分出的短名称和域名，调用方需持有锁
+func (a *Asset) setHostname(name, source string) {
+	a.Hostname = name
+	a.ShortName, a.Domain = SplitHostname(name)
+	a.HostnameSource = source
+}
+
+// fillHostname 资产没有主机名时设置主机名，返回是否设置
+func (a *Asset) fillHostname(name, source string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	name = NormalizeHostname(name)
+	if name == "" || a.Hostname != "" {
+		return false
+	}
+	a.setHostname(name, source)
+	a.LastUpdate = time.Now()
+	a.noteSource("hostname", a.Hostname, source, a.LastUpdate)
+	return true
+}
+
+// updateHostname 合并新观测到的主机名，只有短名称不同时才视为主机名变更
+// 短名称相同时保留更具体（带域名）的形式；来源可信度更低的不同名称被忽略
+func (a *Asset) updateHostname(name, source string) (ChangeRecord, bool) {
+	name = NormalizeHostname(name)
+	if name == "" || name == a.Hostname {
+		return ChangeRecord{}, false
+	}
+
+	current := NormalizeHostname(a.Hostname)
+	if current == "" {
+		a.setHostname(name, source)
+		return ChangeRecord{}, false
+	}
+
+	newPriority := hostnameSourcePriority[source]
+	oldPriority := hostnameSourcePriority[a.HostnameSource]
+
+	newShort, newDomain := SplitHostname(name)
+	oldShort, oldDomain := SplitHostname(current)
+	if newShort == oldShort {
+		// 仅大小写、末尾的点或是否带域名不同，不记录变更
+		switch {
+		case newDomain != "" && (oldDomain == "" || newPriority > oldPriority):
+			a.setHostname(name, source)
+		case current != a.Hostname:
+			a.setHostname(current, a.HostnameSource)
+		}
+		return ChangeRecord{}, false
+	}
+
+	if newPriority < oldPriority {
+		return ChangeRecord{}, false
+	}
+
+	change := ChangeRecord{
+		ChangeType:  "hostname_change",
+		OldValue:    a.Hostname,
+		NewValue:    name,
+		Description: "主机名发生变更",
+	}
+	a.setHostname(name, source)
+	return change, true
+}
@@ -0,0 +1,128 @@
+package assets
+
+import "testing"
+
+func TestNormalizeHostname(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		want       string
+		wantShort  string
+		wantDomain string
+	}{
+		{"dhcp option 12", "PC01", "pc01", "pc01", ""},
+		{"dhcp fqdn", "PC01.Corp.Example.", "pc01.corp.example", "pc01", "corp.example"},
+		{"http host", "WWW.Example.com", "www.example.com", "www", "example.com"},
+		{"mdns local", "MacBook-Pro.local.", "macbook-pro.local", "macbook-pro", "local"},
+		{"nbns padded", "  WORKSTATION7 ", "workstation7", "workstation7", ""},
+		{"llmnr trailing dots", "printer..", "printer", "printer", ""},
+		{"ipv4 literal", "192.168.1.10", "", "", ""},
+		{"ipv6 literal", "fe80::1", "", "", ""},
+		{"empty", " . ", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizeHostname(tt.raw)
+			if got != tt.want {
+				t.Fatalf("NormalizeHostname(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+			if short, domain := SplitHostname(got); short != tt.wantShort || domain != tt.wantDomain {
+				t.Errorf("SplitHostname(%q) = %q, %q, want %q, %q", got, short, domain, tt.wantShort, tt.wantDomain)
+			}
+		})
+	}
+}
+
+func TestUpdateHostname(t *testing.T) {
+	type observation struct {
+		hostname string
+		source   string
+	}
+
+	tests := []struct {
+		name         string
+		observations []observation
+		wantHostname string
+		wantSource   string
+		wantChanges  int
+	}{
+		{
+			name:         "case and trailing dot",
+			observations: []observation{{"PC01", "dhcp"}, {"pc01.", "nbns"}, {"Pc01", "llmnr"}},
+			wantHostname: "pc01",
+			wantSource:   "dhcp",
+		},
+		{
+			name:         "fqdn replaces short name",
+			observations: []observation{{"PC01", "nbns"}, {"pc01.corp.example", "http"}},
+			wantHostname: "pc01.corp.example",
+			wantSource:   "http",
+		},
+		{
+			name:         "short name keeps fqdn",
+			observations: []observation{{"pc01.corp.example", "dhcp"}, {"PC01", "nbns"}},
+			wantHostname: "pc01.corp.example",
+			wantSource:   "dhcp",
+		},
+		{
+			name:         "more authoritative domain wins",
+			observations: []observation{{"macbook.local", "mdns"}, {"macbook.corp.example", "dhcp"}},
+			wantHostname: "macbook.corp.example",
+			wantSource:   "dhcp",
+		},
+		{
+			name:         "less authoritative domain ignored",
+			observations: []observation{{"macbook.corp.example", "dhcp"}, {"macbook.local", "mdns"}},
+			wantHostname: "macbook.corp.example",
+			wantSource:   "dhcp",
+		},
+		{
+			name:         "less authoritative different name ignored",
+			observations: []observation{{"pc01", "dhcp"}, {"www.example.com", "http"}},
+			wantHostname: "pc01",
+			wantSource:   "dhcp",
+		},
+		{
+			name:         "renamed host",
+			observations: []observation{{"pc01", "nbns"}, {"pc02.corp.example", "dhcp"}},
+			wantHostname: "pc02.corp.example",
+			wantSource:   "dhcp",
+			wantChanges:  1,
+		},
+		{
+			name:         "ip literal ignored",
+			observations: []observation{{"pc01", "http"}, {"10.0.0.1", "http"}},
+			wantHostname: "pc01",
+			wantSource:   "http",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := tt.observations[0]
+			asset := NewAsset(&AssetInfo{IPAddress: "10.0.0.1", MACAddress: testMAC, Hostname: first.hostname, HostnameSource: first.source})
+			for _, obs := range tt.observations[1:] {
+				asset.Update(&AssetInfo{IPAddress: "10.0.0.1", MACAddress: testMAC, Hostname: obs.hostname, HostnameSource: obs.source})
+			}
+
+			if asset.Hostname != tt.wantHostname || asset.HostnameSource != tt.wantSource {
+				t.Errorf("hostname = %q (%s), want %q (%s)", asset.Hostname, asset.HostnameSource, tt.wantHostname, tt.wantSource)
+			}
+			wantShort, wantDomain := SplitHostname(tt.wantHostname)
+			if asset.ShortName != wantShort || asset.Domain != wantDomain {
+				t.Errorf("short_name, domain = %q, %q, want %q, %q", asset.ShortName, asset.Domain, wantShort, wantDomain)
+			}
+
+			changes := 0
+			for _, change := range asset.Changes {
+				if change.ChangeType == "hostname_change" {
+					changes++
+				}
+			}
+			if changes != tt.wantChanges {
+				t.Errorf("hostname changes = %d, want %d", changes, tt.wantChanges)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package assets
+
+import (
+	"net"
+	"strings"
+)
+
+// assetIndex 内存资产按IP和MAC地址的二级索引，按地址查询时不必遍历全部资产
+// 同一IP可能同时属于多个资产（IP冲突、IP被重新分配后旧资产仍在内存中），因此IP索引保存资产集合
+// 所有方法都要求调用方持有am.mutex：查询需读锁，修改需写锁
+type assetIndex struct {
+	ipIndex  map[string]map[string]*Asset // IP -> 资产ID -> 资产
+	macIndex map[string]*Asset            // 小写MAC -> 资产
+
+	// 资产ID到建立索引时的IP和MAC，资产地址变化或被删除时据此移除旧索引
+	indexed map[string]indexedAddr
+}
+
+type indexedAddr struct {
+	ip  string
+	mac string
+}
+
+func newAssetIndex() *assetIndex {
+	return &assetIndex{
+		ipIndex:  make(map[string]map[string]*Asset),
+		macIndex: make(map[string]*Asset),
+		indexed:  make(map[string]indexedAddr),
+	}
+}
+
+// add 按资产当前的ID、IP和MAC建立索引，资产已有索引时先移除旧索引，资产插入或更新后调用
+func (idx *assetIndex) add(asset *Asset) {
+	asset.mu.RLock()
+	id := asset.ID
+	addr := indexedAddr{ip: asset.IPAddress, mac: strings.ToLower(asset.MACAddress)}
+	asset.mu.RUnlock()
+
+	if old, ok := idx.indexed[id]; ok {
+		if old == addr {
+			return
+		}
+		idx.remove(id)
+	}
+
+	if addr.ip != "" {
+		owners := idx.ipIndex[addr.ip]
+		if owners == nil {
+			owners = make(map[string]*Asset)
+			idx.ipIndex[addr.ip] = owners
+		}
+		owners[id] = asset
+	}
+	if addr.mac != "" {
+		idx.macIndex[addr.mac] = asset
+	}
+	idx.indexed[id] = addr
+}
+
+// remove 移除资产ID的索引，资产被删除、合并或更换ID前调用
+func (idx *assetIndex) remove(id string) {
+	addr, ok := idx.indexed[id]
+	if !ok {
+		return
+	}
+	delete(idx.indexed, id)
+
+	if owners := idx.ipIndex[addr.ip]; owners != nil {
+		delete(owners, id)
+		if len(owners) == 0 {
+			delete(idx.ipIndex, addr.ip)
+		}
+	}
+	if asset, ok := idx.macIndex[addr.mac]; ok && asset.ID == id {
+		delete(idx.macIndex, addr.mac)
+	}
+}
+
+// byIP 返回当前使用该IP的全部资产
+func (idx *assetIndex) byIP(ip string) []*Asset {
+	owners := idx.ipIndex[ip]
+	list := make([]*Asset, 0, len(owners))
+	for _, asset := range owners {
+		list = append(list, asset)
+	}
+	return list
+}
+
+// byMAC 按MAC地址查找资产，MAC不区分大小写，支持连字符等net.ParseMAC可识别的格式
+func (idx *assetIndex) byMAC(mac string) (*Asset, bool) {
+	if hw, err := net.ParseMAC(mac); err == nil {
+		mac = hw.String()
+	}
+	asset, ok := idx.macIndex[strings.ToLower(mac)]
+	return asset, ok
+}
@@ -0,0 +1,92 @@
+package assets
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// newBenchmarkManager 创建包含n个资产的管理器，第i个资产的IP为10.x.y.z、MAC以00:1a:2b开头
+func newBenchmarkManager(n int) *AssetManager {
+	am := newTestManager(newTestConfig())
+	for i := 0; i < n; i++ {
+		mac := fmt.Sprintf("00:1a:2b:%02x:%02x:%02x", i>>16&0xff, i>>8&0xff, i&0xff)
+		addTestAsset(am, &Asset{
+			ID:         "mac_" + mac,
+			IPAddress:  fmt.Sprintf("10.%d.%d.%d", i>>16&0xff, i>>8&0xff, i&0xff),
+			MACAddress: mac,
+			Hostname:   fmt.Sprintf("host-%d", i),
+			IsActive:   true,
+		})
+	}
+	return am
+}
+
+// scanByIP 遍历全部资产查找IP，建立索引之前的查询方式
+func scanByIP(am *AssetManager, ip string) []*Asset {
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+
+	var results []*Asset
+	for _, asset := range am.assets {
+		asset.mu.RLock()
+		match := asset.IPAddress == ip
+		asset.mu.RUnlock()
+		if match {
+			results = append(results, asset)
+		}
+	}
+	return results
+}
+
+// scanByMAC 遍历全部资产查找MAC
+func scanByMAC(am *AssetManager, mac string) (*Asset, bool) {
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+
+	for _, asset := range am.assets {
+		asset.mu.RLock()
+		match := strings.EqualFold(asset.MACAddress, mac)
+		asset.mu.RUnlock()
+		if match {
+			return asset, true
+		}
+	}
+	return nil, false
+}
+
+// BenchmarkAssetLookup 比较按IP、MAC查询时使用索引和遍历全部资产的耗时，
+// 索引查询的耗时不随资产数量增长，遍历则与资产数量成正比
+func BenchmarkAssetLookup(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		am := newBenchmarkManager(n)
+		// MAC使用大写，同时验证两种查询方式都不区分大小写
+		last := n - 1
+		ip := fmt.Sprintf("10.%d.%d.%d", last>>16&0xff, last>>8&0xff, last&0xff)
+		mac := fmt.Sprintf("00:1A:2B:%02X:%02X:%02X", last>>16&0xff, last>>8&0xff, last&0xff)
+
+		lookups := []struct {
+			name   string
+			lookup func() bool
+		}{
+			{"index/ip", func() bool { _, ok := am.GetAssetByIP(ip); return ok }},
+			{"scan/ip", func() bool { return len(scanByIP(am, ip)) == 1 }},
+			{"index/mac", func() bool { _, ok := am.GetAssetByMAC(mac); return ok }},
+			{"scan/mac", func() bool { _, ok := scanByMAC(am, mac); return ok }},
+			{"index/search-ip", func() bool { return len(am.SearchAssets(ip)) == 1 }},
+		}
+
+		for _, l := range lookups {
+			l := l
+			b.Run(fmt.Sprintf("%s/%d", l.name, n), func(b *testing.B) {
+				if !l.lookup() {
+					b.Fatalf("%s did not find the asset", l.name)
+				}
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					l.lookup()
+				}
+			})
+		}
+	}
+}
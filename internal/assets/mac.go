@@ -0,0 +1,93 @@
+package assets
+
+import (
+	"fmt"
+	"net"
+)
+
+// virtualOUIs 虚拟化平台为虚拟网卡分配MAC地址使用的OUI
+var virtualOUIs = map[string]bool{
+	"00:50:56": true, // VMware
+	"00:0c:29": true, // VMware
+	"00:05:69": true, // VMware
+	"00:1c:14": true, // VMware
+	"08:00:27": true, // VirtualBox
+	"00:15:5d": true, // Microsoft Hyper-V
+	"52:54:00": true, // QEMU/KVM
+	"00:16:3e": true, // Xen
+	"00:1c:42": true, // Parallels
+}
+
+// virtualVendors 表明设备是虚拟机的规范厂商名称
+var virtualVendors = map[string]bool{
+	"VMware":            true,
+	"VirtualBox":        true,
+	"QEMU/KVM":          true,
+	"Microsoft Hyper-V": true,
+	"Xen":               true,
+	"XenSource":         true,
+	"Parallels":         true,
+}
+
+// IsVirtualVendor 判断厂商名称是否为虚拟化平台
+func IsVirtualVendor(vendor string) bool {
+	return virtualVendors[NormalizeVendor(vendor)]
+}
+
+// IsVirtualMAC 判断MAC地址是否属于虚拟化平台的OUI
+func IsVirtualMAC(mac string) bool {
+	oui, ok := macOUI(mac)
+	return ok && virtualOUIs[oui]
+}
+
+// IsRandomizedMAC 判断MAC地址是否为随机MAC：设置了本地管理位(U/L位)的单播地址，
+// 如手机和笔记本的MAC地址随机化；QEMU/KVM等虚拟化平台使用的本地管理OUI不算随机MAC
+func IsRandomizedMAC(mac string) bool {
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) != 6 {
+		return false
+	}
+	if hw[0]&0x01 != 0 || hw[0]&0x02 == 0 {
+		return false
+	}
+	return !IsVirtualMAC(mac)
+}
+
+// macOUI 返回MAC地址的OUI（前三个字节，小写冒号分隔）
+func macOUI(mac string) (string, bool) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) < 3 {
+		return "", false
+	}
+	return fmt.Sprintf("%02x:%02x:%02x", hw[0], hw[1], hw[2]), true
+}
+
+// FilterVirtual 筛选出是否为虚拟机与virtual一致的资产
+func FilterVirtual(assets []*Asset, virtual bool) []*Asset {
+	var result []*Asset
+	for _, asset := range assets {
+		asset.mu.RLock()
+		matched := asset.IsVirtual == virtual
+		asset.mu.RUnlock()
+
+		if matched {
+			result = append(result, asset)
+		}
+	}
+	return result
+}
+
+// FilterRandomizedMAC 筛选出是否使用随机MAC与randomized一致的资产
+func FilterRandomizedMAC(assets []*Asset, randomized bool) []*Asset {
+	var result []*Asset
+	for _, asset := range assets {
+		asset.mu.RLock()
+		matched := asset.IsRandomizedMAC == randomized
+		asset.mu.RUnlock()
+
+		if matched {
+			result = append(result, asset)
+		}
+	}
+	return result
+}
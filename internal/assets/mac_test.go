@@ -0,0 +1,99 @@
+package assets
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMACFlags(t *testing.T) {
+	tests := []struct {
+		name           string
+		mac            string
+		wantVirtual    bool
+		wantRandomized bool
+	}{
+		{"vmware", "00:50:56:9a:00:01", true, false},
+		{"vmware uppercase", "00:0C:29:AB:CD:EF", true, false},
+		{"virtualbox", "08:00:27:00:00:01", true, false},
+		{"qemu locally administered", "52:54:00:12:34:56", true, false},
+		{"randomized", "da:a1:19:00:00:01", false, true},
+		{"randomized 02 prefix", "02:00:00:00:00:01", false, true},
+		{"multicast not randomized", "03:00:00:00:00:01", false, false},
+		{"normal", "d4:be:d9:00:00:01", false, false},
+		{"invalid", "not-a-mac", false, false},
+		{"empty", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsVirtualMAC(tt.mac); got != tt.wantVirtual {
+				t.Errorf("IsVirtualMAC(%q) = %v, want %v", tt.mac, got, tt.wantVirtual)
+			}
+			if got := IsRandomizedMAC(tt.mac); got != tt.wantRandomized {
+				t.Errorf("IsRandomizedMAC(%q) = %v, want %v", tt.mac, got, tt.wantRandomized)
+			}
+		})
+	}
+}
+
+func TestIsVirtualVendor(t *testing.T) {
+	tests := []struct {
+		vendor string
+		want   bool
+	}{
+		{"VMware, Inc.", true},
+		{"VMware", true},
+		{"Parallels", true},
+		{"Dell Inc.", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsVirtualVendor(tt.vendor); got != tt.want {
+			t.Errorf("IsVirtualVendor(%q) = %v, want %v", tt.vendor, got, tt.want)
+		}
+	}
+}
+
+func TestFilterMACFlags(t *testing.T) {
+	am := newTestManager(newTestConfig())
+	now := time.Now()
+	for _, info := range []*AssetInfo{
+		{IPAddress: "10.0.0.1", MACAddress: "00:50:56:9a:00:01", IsVirtual: true, Timestamp: now},
+		{IPAddress: "10.0.0.2", MACAddress: "da:a1:19:00:00:01", IsRandomizedMAC: true, Timestamp: now},
+		{IPAddress: "10.0.0.3", MACAddress: "d4:be:d9:00:00:01", Timestamp: now},
+	} {
+		am.UpdateAsset(info)
+	}
+
+	all := make([]*Asset, 0, 3)
+	for _, asset := range am.GetAllAssets() {
+		all = append(all, asset)
+	}
+
+	tests := []struct {
+		name string
+		got  []*Asset
+		want []string
+	}{
+		{"virtual", FilterVirtual(all, true), []string{"mac_00:50:56:9a:00:01"}},
+		{"physical", FilterVirtual(all, false), []string{"mac_d4:be:d9:00:00:01", "mac_da:a1:19:00:00:01"}},
+		{"randomized", FilterRandomizedMAC(all, true), []string{"mac_da:a1:19:00:00:01"}},
+		{"not randomized", FilterRandomizedMAC(all, false), []string{"mac_00:50:56:9a:00:01", "mac_d4:be:d9:00:00:01"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := assetIDs(tt.got); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// 之后没有标记的观测不会清除标记
+	am.UpdateAsset(&AssetInfo{IPAddress: "10.0.0.1", MACAddress: "00:50:56:9a:00:01", Timestamp: now.Add(time.Second)})
+	asset, _ := am.GetAsset("mac_00:50:56:9a:00:01")
+	if summary := asset.GetSummary(); summary["is_virtual"] != true || summary["is_randomized_mac"] != false {
+		t.Errorf("summary is_virtual = %v, is_randomized_mac = %v, want true, false", summary["is_virtual"], summary["is_randomized_mac"])
+	}
+}
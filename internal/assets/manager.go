@@ -1,11 +1,22 @@
 package assets
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"assets_discovery/internal/alert"
 	"assets_discovery/internal/config"
+	"assets_discovery/internal/enrich"
+	"assets_discovery/internal/logging"
 	"assets_discovery/internal/storage"
 )
 
@@ -13,14 +24,69 @@ import (
 type AssetManager struct {
 	config  *config.Config
 	storage storage.Storage
+	alerts  *alert.Dispatcher
+	known   *alert.Allowlist  // 已知资产列表，匹配的资产不产生新资产和规则告警
+	scope   *networkScope     // 监控网段，网段外的IP不产生资产或被标记为范围外
 	assets  map[string]*Asset // key为资产ID
-	mutex   sync.RWMutex
-	stopCh  chan struct{}
+	aliases map[string]string // 仅IP资产的ID到MAC资产ID的别名
+	index   *assetIndex       // 按IP和MAC的二级索引
+	counts  *assetCounts      // 资产数量及分布的增量统计
+	logs    *logging.Limiter  // 高频日志限流
+
+	// 风险评分及告警规则中的风险阈值
+	risk      *RiskScorer
+	riskRules []riskRule
+	mutex     sync.RWMutex
+
+	// 取消后台任务，Start之前调用Stop时为空操作
+	cancel context.CancelFunc
+
+	// ARP绑定跟踪，用于检测IP-MAC冲突
+	bindings *bindingTracker
+
+	// 公网IP信息补充及按IP缓存的结果，缓存由enrichMu保护，查询不持有mutex
+	enricher enrich.Enricher
+	enrichMu sync.Mutex
+	enriched map[string]map[string]interface{}
+
+	// 可选的反向DNS查询，为没有主机名的资产补全主机名
+	reverseDNS *enrich.ReverseDNS
+
+	// 信息补充流水线，启用时取代同步的信息补充和reverseDNS
+	pipeline *enrich.Pipeline
+
+	// 离线文件中预先记录的IP与主机名映射（pcapng名称解析块）
+	seedNames map[string]string
+
+	// 最近处理的数据包时间及处理时的系统时间，用于推算离线分析时的当前时间
+	lastPacketTime time.Time
+	lastPacketWall time.Time
+
+	// 捕获开始时间，离线模式下为分析开始时间
+	startTime time.Time
+
+	// 数据包解析器的协议解析统计
+	parseStats func() map[string]ProtocolParseStats
+
+	// 数据包抽样统计，未启用抽样时为空
+	samplingStats func() SamplingStats
+
+	// 保存失败、等待重试的资产
+	retries *saveRetryQueue
+
+	// 写入存储的后台任务及进行中的异步保存，Stop等待其结束后再做最后一次保存，之后可以安全关闭存储
+	background sync.WaitGroup
+
+	// 资产事件的订阅者，如gRPC的事件流
+	events *eventBus
 
 	// 统计信息
 	stats AssetStats
 }
 
+// maxEnrichCache 信息补充结果缓存的最大IP数量，超出后清空重建
+const maxEnrichCache = 10000
+
 // AssetStats 资产统计信息
 type AssetStats struct {
 	TotalAssets    int            `json:"total_assets"`
@@ -29,43 +95,160 @@ type AssetStats struct {
 	LastUpdate     time.Time      `json:"last_update"`
 	DeviceTypes    map[string]int `json:"device_types"`
 	OSDistribution map[string]int `json:"os_distribution"`
+	StartTime      time.Time      `json:"start_time"`     // 捕获开始时间
+	UptimeSeconds  int64          `json:"uptime_seconds"` // 捕获已运行的秒数
+	Uptime         string         `json:"uptime"`
+
+	// 各协议的解析次数和解析失败次数，由数据包解析器提供
+	ParseStats map[string]ProtocolParseStats `json:"parse_stats,omitempty"`
+
+	// 保存失败等待重试的资产数，及重试队列已满时丢弃的保存次数
+	PendingWrites int    `json:"pending_writes"`
+	DroppedWrites uint64 `json:"dropped_writes"`
+
+	// 数据包抽样统计，仅在配置了capture.sample_rate时出现
+	Sampling *SamplingStats `json:"sampling,omitempty"`
+
+	// 信息补充流水线各步骤的统计，仅在启用流水线时出现
+	Enrichment map[string]enrich.StageStats `json:"enrichment,omitempty"`
+}
+
+// SamplingStats 数据包抽样比例(1/rate)及收到和实际处理的数据包数
+type SamplingStats struct {
+	Rate    int    `json:"rate"`
+	Total   uint64 `json:"total_packets"`
+	Sampled uint64 `json:"sampled_packets"`
+}
+
+// ProtocolParseStats 单个协议的解析次数和失败次数，失败包括数据格式错误和解析器panic
+type ProtocolParseStats struct {
+	Attempts uint64 `json:"attempts"`
+	Errors   uint64 `json:"errors"`
 }
 
 // NewAssetManager 创建新的资产管理器
 func NewAssetManager(cfg *config.Config, storage storage.Storage) *AssetManager {
-	return &AssetManager{
-		config:  cfg,
-		storage: storage,
-		assets:  make(map[string]*Asset),
-		stopCh:  make(chan struct{}),
+	enricher, err := enrich.NewEnricher(&cfg.Enrichment)
+	if err != nil {
+		log.Printf("初始化信息补充失败，已禁用: %v", err)
+		enricher = enrich.NoopEnricher{}
+	}
+
+	known, err := alert.NewAllowlist(cfg.Alerting.KnownAssets)
+	if err != nil {
+		log.Printf("加载已知资产列表: %v", err)
+	}
+
+	scope, err := newNetworkScope(cfg.Parser.MonitoredNetworks)
+	if err != nil {
+		log.Printf("加载监控网段: %v", err)
+	}
+
+	if cfg.Parser.HostCorrelation.Enabled {
+		if err := ValidHostKey(cfg.Parser.HostCorrelation.Key); err != nil {
+			log.Printf("主机关联: %v", err)
+		}
+	}
+
+	am := &AssetManager{
+		config:   cfg,
+		storage:  storage,
+		alerts:   alert.NewDispatcher(&cfg.Alerting),
+		known:    known,
+		scope:    scope,
+		logs:     logging.NewLimiter(cfg.Logging.SummaryInterval),
+		risk:     NewRiskScorer(&cfg.Risk),
+		enricher: enricher,
+		enriched: make(map[string]map[string]interface{}),
+		assets:   make(map[string]*Asset),
+		aliases:  make(map[string]string),
+		index:    newAssetIndex(),
+		counts:   newAssetCounts(),
+		retries:  newSaveRetryQueue(),
+		events:   newEventBus(),
+		cancel:   func() {},
+
+		bindings: newBindingTracker(),
+
 		stats: AssetStats{
 			DeviceTypes:    make(map[string]int),
 			OSDistribution: make(map[string]int),
 		},
 	}
+
+	for _, rule := range cfg.Alerting.AlertRules {
+		if r, ok := parseRiskRule(rule); ok {
+			am.riskRules = append(am.riskRules, r)
+		}
+	}
+
+	if cfg.Enrichment.Pipeline.Enabled {
+		am.pipeline = enrich.NewPipelineFromConfig(&cfg.Enrichment, enricher, am.applyEnrichment)
+	} else if cfg.Enrichment.ReverseDNS {
+		am.reverseDNS = enrich.NewReverseDNS(nil, cfg.Enrichment.ReverseDNSRate, am.applyReverseDNS)
+	}
+
+	return am
 }
 
-// Start 启动资产管理器
-func (am *AssetManager) Start() {
+// Start 启动资产管理器，ctx取消或调用Stop时后台任务退出
+func (am *AssetManager) Start(ctx context.Context) {
 	log.Println("资产管理器启动")
 
+	ctx, am.cancel = context.WithCancel(ctx)
+
 	// 从存储中加载现有资产
 	am.loadExistingAssets()
 
 	// 启动定期清理任务
-	go am.cleanupRoutine()
+	am.goBackground(func() { am.cleanupRoutine(ctx) })
 
 	// 启动统计更新任务
-	go am.statsUpdateRoutine()
+	go am.statsUpdateRoutine(ctx)
+
+	// 定期输出被限流日志的汇总
+	go am.logs.Run(ctx)
+
+	// 重新保存失败的资产
+	if !am.config.Storage.NoStore {
+		am.goBackground(func() { am.retryRoutine(ctx) })
+	}
+
+	// 启动反向DNS查询
+	if am.reverseDNS != nil {
+		log.Println("已启用反向DNS查询，将对没有主机名的资产发起PTR查询")
+		am.goBackground(func() { am.reverseDNS.Run(ctx) })
+	}
+
+	// 启动信息补充流水线
+	if am.pipeline != nil {
+		am.logPipeline()
+		am.goBackground(func() { am.pipeline.Run(ctx) })
+	}
+}
+
+// goBackground 启动会写入存储的后台任务，Stop时等待其退出
+func (am *AssetManager) goBackground(fn func()) {
+	am.background.Add(1)
+	go func() {
+		defer am.background.Done()
+		fn()
+	}()
 }
 
 // Stop 停止资产管理器
 func (am *AssetManager) Stop() {
 	log.Println("资产管理器停止")
-	close(am.stopCh)
+	am.cancel()
 
 	// 保存当前资产状态
+	am.background.Wait()
 	am.saveAllAssets()
+	am.logs.Flush()
+
+	if err := am.enricher.Close(); err != nil {
+		log.Printf("关闭信息补充失败: %v", err)
+	}
 }
 
 // UpdateAsset 更新资产信息
@@ -74,36 +257,205 @@ func (am *AssetManager) UpdateAsset(assetInfo *AssetInfo) {
 		return
 	}
 
+	// 监控网段外的IP（如镜像端口上的互联网流量）默认不产生资产
+	inScope := am.scope.contains(assetInfo.IPAddress)
+	if inScope || am.config.Parser.OutOfScope == "tag" {
+		// 信息补充可能查询外部数据源，在加锁前完成，避免阻塞其他读写
+		am.enrichAssetInfo(assetInfo)
+	}
+
 	am.mutex.Lock()
 	defer am.mutex.Unlock()
 
-	assetID := generateAssetID(assetInfo)
+	am.checkIPBinding(assetInfo)
+
+	if assetInfo.Timestamp.After(am.lastPacketTime) {
+		am.lastPacketTime = assetInfo.Timestamp
+		am.lastPacketWall = time.Now()
+	}
+
+	if !inScope && am.config.Parser.OutOfScope != "tag" {
+		return
+	}
+
+	// 合并前记录IP原先归属的资产，用于发现IP改由其他MAC声明
+	previousID := am.arpIPOwner(assetInfo)
+	assetID := am.canonicalAssetID(assetInfo)
+
+	_, wpad := assetInfo.Protocols["wpad"]
+
+	// 本次更新前的变更记录数，更新后新增的变更记录作为事件发送
+	changesBefore := 0
 
 	if existingAsset, exists := am.assets[assetID]; exists {
+		existingAsset.mu.RLock()
+		wpadSeen := existingAsset.hasProtocol("wpad")
+		changesBefore = len(existingAsset.Changes)
+		existingAsset.mu.RUnlock()
+
 		// 更新现有资产
 		existingAsset.Update(assetInfo)
-		log.Printf("更新资产: %s (%s)", assetID, assetInfo.IPAddress)
+		existingAsset.setOutOfScope(!inScope)
+		am.index.add(existingAsset)
+		am.counts.track(existingAsset)
+		am.logs.Printf("update:"+assetID, "更新资产: %s (%s)", assetID, assetInfo.IPAddress)
+		am.applySeedHostname(existingAsset)
+		am.requestReverseDNS(existingAsset)
+		am.requestEnrichment(existingAsset)
+
+		// 低置信度资产累计的观测达到门槛时按新资产处理
+		confirmed, newly := am.confirmAsset(existingAsset)
+		if newly {
+			am.stats.NewAssets++
+			log.Printf("资产置信度达到门槛: %s (%s)", assetID, assetInfo.IPAddress)
+			am.notifyNewAsset(existingAsset)
+		}
+		am.checkRisk(existingAsset)
+
+		// 置信度首次达到门槛时对此前记录的发现一并告警
+		added := existingAsset.recordFindings(detectFindings(assetInfo), seenTime(assetInfo))
+		if newly {
+			added = existingAsset.copyFindings()
+		}
+		if confirmed {
+			am.notifyFindings(existingAsset, added)
+		}
+
+		// 首次发现WPAD查询时告警
+		if confirmed && (wpad && !wpadSeen || newly && wpadSeen) {
+			am.notifyWPAD(existingAsset.ID, assetInfo)
+		}
 	} else {
 		// 创建新资产
 		newAsset := NewAsset(assetInfo)
+		newAsset.OutOfScope = !inScope
+		newAsset.tentative = true
 		am.assets[assetID] = newAsset
-		am.stats.NewAssets++
-		log.Printf("发现新资产: %s (%s)", assetID, assetInfo.IPAddress)
-
-		// 发送新资产告警
-		am.notifyNewAsset(newAsset)
+		am.index.add(newAsset)
+		am.counts.track(newAsset)
+
+		am.applySeedHostname(newAsset)
+		am.requestReverseDNS(newAsset)
+		am.requestEnrichment(newAsset)
+		added := newAsset.recordFindings(detectFindings(assetInfo), seenTime(assetInfo))
+
+		if _, newly := am.confirmAsset(newAsset); newly {
+			am.stats.NewAssets++
+			log.Printf("发现新资产: %s (%s)", assetID, assetInfo.IPAddress)
+			am.checkRisk(newAsset)
+
+			// 发送新资产告警
+			am.notifyNewAsset(newAsset)
+			am.notifyFindings(newAsset, added)
+			if wpad {
+				am.notifyWPAD(newAsset.ID, assetInfo)
+			}
+		} else {
+			am.logs.Printf("tentative", "发现低置信度资产: %s (%s)，置信度 %.2f 低于 %.2f，暂不保存和告警",
+				assetID, assetInfo.IPAddress, newAsset.Confidence, am.config.Parser.MinConfidence)
+		}
 	}
 
+	am.checkMACChange(assetID, previousID, seenTime(assetInfo))
+	am.publishChanges(am.assets[assetID], changesBefore)
+
 	// 异步保存到存储
-	go am.saveAsset(assetID)
+	am.saveAsync(assetID)
 }
 
-// GetAsset 获取资产信息
+// SeedHostnames 设置离线文件中预先记录的IP与主机名映射，用于补全没有主机名的资产
+func (am *AssetManager) SeedHostnames(names map[string]string) {
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+
+	am.seedNames = names
+}
+
+// applySeedHostname 用预先记录的主机名补全资产，调用方需持有am.mutex
+func (am *AssetManager) applySeedHostname(asset *Asset) {
+	if len(am.seedNames) == 0 {
+		return
+	}
+
+	asset.mu.RLock()
+	name, ok := am.seedNames[asset.IPAddress]
+	asset.mu.RUnlock()
+
+	if ok {
+		asset.fillHostname(name, "pcapng_nrb")
+	}
+}
+
+// requestReverseDNS 资产没有主机名时请求反向解析其IP，每个IP只查询一次
+func (am *AssetManager) requestReverseDNS(asset *Asset) {
+	if am.reverseDNS == nil {
+		return
+	}
+
+	asset.mu.RLock()
+	ip, hostname := asset.IPAddress, asset.Hostname
+	asset.mu.RUnlock()
+
+	if ip != "" && hostname == "" {
+		am.reverseDNS.Lookup(ip)
+	}
+}
+
+// applyReverseDNS 用反向解析结果补全使用该IP且没有主机名的资产
+func (am *AssetManager) applyReverseDNS(ip, hostname string) {
+	am.mutex.RLock()
+	matched := am.index.byIP(ip)
+	am.mutex.RUnlock()
+
+	for _, asset := range matched {
+		if asset.fillHostname(hostname, "reverse_dns") {
+			log.Printf("反向解析补全主机名: %s (%s) -> %s", asset.ID, ip, hostname)
+			am.saveAsync(asset.ID)
+		}
+	}
+}
+
+// enrichAssetInfo 为公网IP补充ASN、地理位置等信息，结果按IP缓存，调用方不能持有mutex
+// 启用补充流水线时由流水线异步查询
+func (am *AssetManager) enrichAssetInfo(assetInfo *AssetInfo) {
+	if am.pipeline != nil || !am.config.Enrichment.Enabled || !enrich.IsPublicIP(assetInfo.IPAddress) {
+		return
+	}
+
+	am.enrichMu.Lock()
+	result, cached := am.enriched[assetInfo.IPAddress]
+	am.enrichMu.Unlock()
+
+	if !cached {
+		var err error
+		result, err = am.enricher.Enrich(assetInfo.IPAddress)
+		if err != nil {
+			am.logs.Printf("enrich", "查询IP补充信息失败 %s: %v", assetInfo.IPAddress, err)
+		}
+
+		am.enrichMu.Lock()
+		if len(am.enriched) >= maxEnrichCache {
+			am.enriched = make(map[string]map[string]interface{})
+		}
+		am.enriched[assetInfo.IPAddress] = result
+		am.enrichMu.Unlock()
+	}
+
+	if result == nil {
+		return
+	}
+	if assetInfo.Protocols == nil {
+		assetInfo.Protocols = make(map[string]interface{})
+	}
+	assetInfo.Protocols["enrichment"] = result
+}
+
+// GetAsset 获取资产信息，已并入MAC资产的仅IP资产ID通过别名解析
 func (am *AssetManager) GetAsset(assetID string) (*Asset, bool) {
 	am.mutex.RLock()
 	defer am.mutex.RUnlock()
 
-	asset, exists := am.assets[assetID]
+	asset, exists := am.assets[am.resolveAssetID(assetID)]
 	return asset, exists
 }
 
@@ -166,19 +518,245 @@ func (am *AssetManager) GetAssetsByOS(osFamily string) []*Asset {
 	return assets
 }
 
-// GetStats 获取统计信息
+// GetAssetsByVendor 根据厂商获取资产，厂商名称规范化后不区分大小写比较
+func (am *AssetManager) GetAssetsByVendor(vendor string) []*Asset {
+	vendor = NormalizeVendor(vendor)
+
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+
+	var assets []*Asset
+	for _, asset := range am.assets {
+		if asset.Vendor != "" && strings.EqualFold(NormalizeVendor(asset.Vendor), vendor) {
+			assets = append(assets, asset)
+		}
+	}
+
+	return assets
+}
+
+// GetAssetsByPort 根据开放端口获取资产，proto为空时匹配任意协议
+func (am *AssetManager) GetAssetsByPort(port int, proto string) []*Asset {
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+
+	var assets []*Asset
+	for _, asset := range am.assets {
+		if asset.HasOpenPort(port, proto) {
+			assets = append(assets, asset)
+		}
+	}
+
+	return assets
+}
+
+// GetIPConflicts 获取检测到的IP-MAC冲突记录
+func (am *AssetManager) GetIPConflicts() []IPMACConflict {
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+
+	return append([]IPMACConflict{}, am.bindings.conflicts...)
+}
+
+// AggregateFields 支持的聚合字段
+var AggregateFields = []string{"device_type", "os_family", "vendor", "subnet"}
+
+// AggregateAssets 按字段统计资产数量
+// 存储支持聚合时优先下推到存储计算，失败时使用内存中的资产计算
+func (am *AssetManager) AggregateAssets(field string, activeOnly bool) (map[string]int, error) {
+	valid := false
+	for _, f := range AggregateFields {
+		if f == field {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, fmt.Errorf("不支持的聚合字段: %s", field)
+	}
+
+	if aggregator, ok := am.storage.(storage.AggregateStorage); ok {
+		if counts, err := aggregator.AggregateAssets(field, activeOnly); err == nil {
+			return counts, nil
+		}
+	}
+
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+
+	counts := make(map[string]int)
+	for _, asset := range am.assets {
+		asset.mu.RLock()
+		if !activeOnly || asset.IsActive {
+			counts[aggregateKey(asset, field)]++
+		}
+		asset.mu.RUnlock()
+	}
+
+	return counts, nil
+}
+
+// aggregateKey 获取资产在聚合字段上的取值，调用方需持有资产的读锁
+func aggregateKey(asset *Asset, field string) string {
+	var key string
+	switch field {
+	case "device_type":
+		key = asset.DeviceType
+	case "os_family":
+		key = asset.OSInfo.Family
+	case "vendor":
+		key = asset.Vendor
+	case "subnet":
+		key = subnetOf(asset.IPAddress)
+	}
+
+	if key == "" {
+		return "unknown"
+	}
+	return key
+}
+
+// subnetOf 获取IP所在的网段，IPv4按/24、IPv6按/64划分
+func subnetOf(ipAddress string) string {
+	ip := net.ParseIP(ipAddress)
+	if ip == nil {
+		return ""
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return (&net.IPNet{IP: ip4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: ip.Mask(net.CIDRMask(64, 128)), Mask: net.CIDRMask(64, 128)}).String()
+}
+
+// SetStartTime 设置捕获开始时间，用于统计运行时长
+func (am *AssetManager) SetStartTime(t time.Time) {
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+
+	am.startTime = t
+}
+
+// SetParseStatsSource 设置协议解析统计的来源，GetStats返回的统计中会包含该统计
+func (am *AssetManager) SetParseStatsSource(source func() map[string]ProtocolParseStats) {
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+
+	am.parseStats = source
+}
+
+// SetSamplingStatsSource 设置数据包抽样统计的来源，GetStats返回的统计中会包含该统计
+func (am *AssetManager) SetSamplingStatsSource(source func() SamplingStats) {
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+
+	am.samplingStats = source
+}
+
+// GetStats 获取统计信息，返回前按当前资产重新计算
 func (am *AssetManager) GetStats() AssetStats {
+	am.updateStats()
+
 	am.mutex.RLock()
 	defer am.mutex.RUnlock()
 
-	return am.stats
+	stats := am.stats
+	if am.parseStats != nil {
+		stats.ParseStats = am.parseStats()
+	}
+	if am.samplingStats != nil {
+		sampling := am.samplingStats()
+		stats.Sampling = &sampling
+	}
+	if am.pipeline != nil {
+		stats.Enrichment = am.pipeline.Stats()
+	}
+	stats.PendingWrites, stats.DroppedWrites = am.retries.counts()
+	if !am.startTime.IsZero() {
+		uptime := time.Since(am.startTime).Round(time.Second)
+		stats.StartTime = am.startTime
+		stats.UptimeSeconds = int64(uptime / time.Second)
+		stats.Uptime = uptime.String()
+	}
+	return stats
+}
+
+// AssetTally 当前资产中出现的地址、服务和协议计数，服务和协议按出现的资产数统计
+type AssetTally struct {
+	UniqueIPs  int
+	UniqueMACs int
+	Services   map[string]int
+	Protocols  map[string]int
+}
+
+// Tally 统计当前资产中的地址、服务和协议，用于离线分析的快速汇总
+func (am *AssetManager) Tally() AssetTally {
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+
+	ips := make(map[string]bool)
+	macs := make(map[string]bool)
+	tally := AssetTally{
+		Services:  make(map[string]int),
+		Protocols: make(map[string]int),
+	}
+
+	for _, asset := range am.assets {
+		asset.mu.RLock()
+		if asset.IPAddress != "" {
+			ips[asset.IPAddress] = true
+		}
+		for _, ip := range asset.IPHistory {
+			ips[ip] = true
+		}
+		if asset.MACAddress != "" {
+			macs[asset.MACAddress] = true
+		}
+
+		services := make(map[string]bool)
+		for _, service := range asset.Services {
+			if serviceDirection(service) == "listening" {
+				services[service.Name] = true
+			}
+		}
+		for _, port := range asset.OpenPorts {
+			services[port.Service] = true
+		}
+		for name := range services {
+			if name != "" {
+				tally.Services[name]++
+			}
+		}
+
+		// enrichment是补充的公网IP信息，不是观测到的协议
+		for name := range asset.Protocols {
+			if name != "enrichment" {
+				tally.Protocols[name]++
+			}
+		}
+		asset.mu.RUnlock()
+	}
+
+	tally.UniqueIPs = len(ips)
+	tally.UniqueMACs = len(macs)
+	return tally
 }
 
-// SearchAssets 搜索资产
+// SearchAssets 搜索资产，查询为IP或MAC地址时直接使用索引
 func (am *AssetManager) SearchAssets(query string) []*Asset {
 	am.mutex.RLock()
 	defer am.mutex.RUnlock()
 
+	if net.ParseIP(query) != nil {
+		return am.index.byIP(query)
+	}
+	if _, err := net.ParseMAC(query); err == nil {
+		if asset, ok := am.index.byMAC(query); ok {
+			return []*Asset{asset}
+		}
+		return nil
+	}
+
 	var results []*Asset
 
 	for _, asset := range am.assets {
@@ -190,8 +768,97 @@ func (am *AssetManager) SearchAssets(query string) []*Asset {
 	return results
 }
 
+// ParseFirstSeenSince 解析首次发现时间的过滤条件
+// 支持相对时长（如 24h、90m、7d，相对于数据包时间轴上的当前时间）和绝对时间（RFC3339或 2006-01-02）
+func (am *AssetManager) ParseFirstSeenSince(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+
+	var window time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("无效的时间条件: %s", value)
+		}
+		window = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("无效的时间条件: %s", value)
+		}
+		window = d
+	}
+	if window <= 0 {
+		return time.Time{}, fmt.Errorf("时间范围必须大于0: %s", value)
+	}
+
+	am.mutex.RLock()
+	now := am.currentTime()
+	am.mutex.RUnlock()
+
+	return now.Add(-window), nil
+}
+
+// FilterFirstSeenSince 筛选出在since及之后首次发现的资产
+func FilterFirstSeenSince(assets []*Asset, since time.Time) []*Asset {
+	var result []*Asset
+	for _, asset := range assets {
+		asset.mu.RLock()
+		firstSeen := asset.FirstSeen
+		asset.mu.RUnlock()
+
+		if !firstSeen.Before(since) {
+			result = append(result, asset)
+		}
+	}
+	return result
+}
+
+// FilterMinRisk 筛选出风险评分不低于minScore的资产
+func FilterMinRisk(assets []*Asset, minScore float64) []*Asset {
+	var result []*Asset
+	for _, asset := range assets {
+		asset.mu.RLock()
+		score := asset.RiskScore
+		asset.mu.RUnlock()
+
+		if score >= minScore {
+			result = append(result, asset)
+		}
+	}
+	return result
+}
+
+// FilterScope 筛选出内外网标签为scope(internal或external)的资产
+func FilterScope(assets []*Asset, scope string) []*Asset {
+	var result []*Asset
+	for _, asset := range assets {
+		asset.mu.RLock()
+		matched := asset.Scope == scope
+		asset.mu.RUnlock()
+
+		if matched {
+			result = append(result, asset)
+		}
+	}
+	return result
+}
+
 // loadExistingAssets 从存储加载现有资产
 func (am *AssetManager) loadExistingAssets() {
+	if am.config.Storage.Pseudonymize.Enabled {
+		// 存储中是假名化的资产，无法与实时观测到的MAC地址对应，加载后会与新观测重复计数；
+		// 保存时仍按假名ID与存储中的记录合并，首次发现时间等不会丢失
+		log.Println("存储已启用假名化，不加载现有资产")
+		return
+	}
+
 	assets, err := am.storage.GetAllAssets()
 	if err != nil {
 		log.Printf("加载现有资产失败: %v", err)
@@ -201,50 +868,299 @@ func (am *AssetManager) loadExistingAssets() {
 	am.mutex.Lock()
 	defer am.mutex.Unlock()
 
+	loaded := 0
 	for _, assetInterface := range assets {
-		if asset, ok := assetInterface.(*Asset); ok {
-			am.assets[asset.ID] = asset
+		asset, err := decodeStoredAsset(assetInterface)
+		if err != nil {
+			log.Printf("跳过无法解析的资产: %v", err)
+			continue
 		}
+		am.assets[asset.ID] = asset
+		am.index.add(asset)
+		am.counts.track(asset)
+		loaded++
 	}
 
-	log.Printf("加载了 %d 个现有资产", len(assets))
+	log.Printf("加载了 %d 个现有资产", loaded)
+
+	if merged := am.collapseDuplicates(); merged > 0 {
+		log.Printf("合并了 %d 个重复的仅IP资产", merged)
+	}
+}
+
+// GetStoredAsset 从存储中读取资产，用于内存中不存在的资产
+func (am *AssetManager) GetStoredAsset(assetID string) (*Asset, error) {
+	stored, err := am.storage.GetAsset(assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeStoredAsset(stored)
+}
+
+// SearchStoredAssets 在存储中搜索资产
+func (am *AssetManager) SearchStoredAssets(query string) ([]*Asset, error) {
+	stored, err := am.storage.SearchAssets(query)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*Asset, 0, len(stored))
+	for _, item := range stored {
+		asset, err := decodeStoredAsset(item)
+		if err != nil {
+			continue
+		}
+		results = append(results, asset)
+	}
+
+	return results, nil
+}
+
+// LookupAsset 查找资产，优先使用内存中的实时数据，不存在时（如已被清理）回退到存储
+func (am *AssetManager) LookupAsset(assetID string) (*Asset, bool) {
+	if asset, exists := am.GetAsset(assetID); exists {
+		return asset, true
+	}
+
+	stored, err := am.GetStoredAsset(assetID)
+	if err != nil {
+		return nil, false
+	}
+	return stored, true
+}
+
+// SearchAllAssets 合并内存和存储中的搜索结果，同一资产以内存中的为准
+func (am *AssetManager) SearchAllAssets(query string) []*Asset {
+	result := am.SearchAssets(query)
+
+	stored, err := am.SearchStoredAssets(query)
+	if err != nil {
+		log.Printf("搜索存储中的资产失败: %v", err)
+		return result
+	}
+
+	seen := make(map[string]bool, len(result))
+	for _, asset := range result {
+		seen[asset.ID] = true
+	}
+	for _, asset := range stored {
+		if !seen[asset.ID] {
+			seen[asset.ID] = true
+			result = append(result, asset)
+		}
+	}
+
+	return result
+}
+
+// decodeStoredAsset 将存储返回的数据转换为资产，存储通常返回map形式的JSON对象
+func decodeStoredAsset(stored interface{}) (*Asset, error) {
+	if asset, ok := stored.(*Asset); ok {
+		return asset, nil
+	}
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return nil, fmt.Errorf("序列化资产失败: %v", err)
+	}
+
+	asset := &Asset{}
+	if err := json.Unmarshal(data, asset); err != nil {
+		return nil, fmt.Errorf("解析资产失败: %v", err)
+	}
+	if asset.ID == "" {
+		return nil, fmt.Errorf("资产缺少ID")
+	}
+	// 早期版本保存的资产没有内外网标签、虚拟机和随机MAC标记以及端口和服务的方向
+	if asset.Scope == "" {
+		asset.Scope = ipScope(asset.IPAddress)
+	}
+	asset.IsVirtual = asset.IsVirtual || IsVirtualMAC(asset.MACAddress) || IsVirtualVendor(asset.Vendor)
+	asset.IsRandomizedMAC = asset.IsRandomizedMAC || IsRandomizedMAC(asset.MACAddress)
+	asset.fillDirections()
+
+	return asset, nil
+}
+
+// saveAsync 在后台保存单个资产
+func (am *AssetManager) saveAsync(assetID string) {
+	am.goBackground(func() { am.saveAsset(assetID) })
 }
 
 // saveAsset 保存单个资产
 func (am *AssetManager) saveAsset(assetID string) {
+	if am.config.Storage.NoStore {
+		return
+	}
+
 	am.mutex.RLock()
 	asset, exists := am.assets[assetID]
 	am.mutex.RUnlock()
 
-	if !exists {
+	if !exists || asset.isTentative() {
+		return
+	}
+
+	if err := am.storeAsset(asset); err != nil {
+		am.logs.Printf("save", "保存资产失败 %s: %v，稍后重试", assetID, err)
+		am.queueRetry(assetID)
+		return
+	}
+	am.retries.done(assetID)
+}
+
+// storeAsset 保存资产，存储支持原子更新时与其他写入方的观测结果合并
+// 资产在保存前已被删除时跳过，原子更新在存储的锁内检查，避免与删除交错时把资产重新写回存储
+func (am *AssetManager) storeAsset(asset *Asset) error {
+	if asset.isRemoved() {
+		return nil
+	}
+
+	atomicStorage, ok := am.storage.(storage.AtomicStorage)
+	if !ok {
+		return am.storage.SaveAsset(asset)
+	}
+
+	err := atomicStorage.UpdateAsset(asset.ID, func(current map[string]interface{}) (map[string]interface{}, error) {
+		if asset.isRemoved() {
+			return nil, nil
+		}
+		updated, err := assetToMap(asset)
+		if err != nil {
+			return nil, err
+		}
+		mergeStoredAsset(current, updated)
+		return updated, nil
+	})
+	// 冲突过多等失败不退回普通保存，否则会覆盖其他写入方的结果；由重试队列稍后重新合并
+	return err
+}
+
+// assetToMap 将资产转换为存储使用的map
+func assetToMap(asset *Asset) (map[string]interface{}, error) {
+	data, err := json.Marshal(asset)
+	if err != nil {
+		return nil, fmt.Errorf("序列化资产失败: %v", err)
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("转换资产失败: %v", err)
+	}
+	return m, nil
+}
+
+// mergeStoredAsset 将存储中其他写入方的观测合并到待保存的资产中：
+// 保留更早的首次发现时间、更晚的最后活跃时间、最后修改的备注，并合并开放端口
+func mergeStoredAsset(current, updated map[string]interface{}) {
+	if current == nil {
 		return
 	}
 
-	if err := am.storage.SaveAsset(asset); err != nil {
-		log.Printf("保存资产失败 %s: %v", assetID, err)
+	if storedFirst, ok := parseStoredTime(current["first_seen"]); ok {
+		if first, ok := parseStoredTime(updated["first_seen"]); !ok || storedFirst.Before(first) {
+			updated["first_seen"] = current["first_seen"]
+		}
+	}
+
+	if storedLast, ok := parseStoredTime(current["last_seen"]); ok {
+		if last, ok := parseStoredTime(updated["last_seen"]); !ok || storedLast.After(last) {
+			updated["last_seen"] = current["last_seen"]
+			updated["is_active"] = current["is_active"]
+		}
+	}
+
+	mergeStoredNotes(current, updated)
+	mergeStoredFindings(current, updated)
+
+	storedPorts, _ := current["open_ports"].([]interface{})
+	ports, _ := updated["open_ports"].([]interface{})
+	seen := make(map[string]bool, len(ports))
+	for _, p := range ports {
+		seen[storedPortKey(p)] = true
+	}
+	for _, p := range storedPorts {
+		if key := storedPortKey(p); !seen[key] {
+			seen[key] = true
+			ports = append(ports, p)
+		}
+	}
+	if len(ports) > 0 {
+		updated["open_ports"] = ports
+	}
+
+	// 其他采集器可能在不同的网络接口上观测到同一资产
+	storedIfaces, _ := current["interfaces"].([]interface{})
+	ifaces, _ := updated["interfaces"].([]interface{})
+	for _, iface := range storedIfaces {
+		found := false
+		for _, existing := range ifaces {
+			if existing == iface {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ifaces = append(ifaces, iface)
+		}
 	}
+	if len(ifaces) > 0 {
+		updated["interfaces"] = ifaces
+	}
+}
+
+// parseStoredTime 解析存储中的时间字段
+func parseStoredTime(v interface{}) (time.Time, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	return t, err == nil && !t.IsZero()
+}
+
+// storedPortKey 存储中端口记录的去重键
+func storedPortKey(p interface{}) string {
+	m, ok := p.(map[string]interface{})
+	if !ok {
+		return fmt.Sprint(p)
+	}
+	return fmt.Sprintf("%v/%v", m["port"], m["protocol"])
 }
 
 // saveAllAssets 保存所有资产
 func (am *AssetManager) saveAllAssets() {
+	if am.config.Storage.NoStore {
+		return
+	}
+
 	am.mutex.RLock()
 	assets := make([]*Asset, 0, len(am.assets))
 	for _, asset := range am.assets {
-		assets = append(assets, asset)
+		if !asset.isTentative() {
+			assets = append(assets, asset)
+		}
 	}
 	am.mutex.RUnlock()
 
 	for _, asset := range assets {
-		if err := am.storage.SaveAsset(asset); err != nil {
+		if err := am.storeAsset(asset); err != nil {
 			log.Printf("保存资产失败 %s: %v", asset.ID, err)
+			am.queueRetry(asset.ID)
+			continue
 		}
+		am.retries.done(asset.ID)
 	}
 
 	log.Printf("保存了 %d 个资产", len(assets))
+	if pending, _ := am.retries.counts(); pending > 0 {
+		log.Printf("仍有 %d 个资产未能保存", pending)
+	}
 }
 
 // cleanupRoutine 定期清理例程
-func (am *AssetManager) cleanupRoutine() {
+func (am *AssetManager) cleanupRoutine(ctx context.Context) {
 	ticker := time.NewTicker(5 * time.Minute) // 每5分钟执行一次清理
 	defer ticker.Stop()
 
@@ -252,7 +1168,7 @@ func (am *AssetManager) cleanupRoutine() {
 		select {
 		case <-ticker.C:
 			am.cleanupInactiveAssets()
-		case <-am.stopCh:
+		case <-ctx.Done():
 			return
 		}
 	}
@@ -263,27 +1179,98 @@ func (am *AssetManager) cleanupInactiveAssets() {
 	am.mutex.Lock()
 	defer am.mutex.Unlock()
 
-	timeout := time.Duration(am.config.Parser.AssetTimeout) * time.Minute
-	cutoff := time.Now().Add(-timeout)
+	now := am.currentTime()
+
+	portTimeout := time.Duration(am.config.Parser.PortTimeout) * time.Minute
 
-	inactiveCount := 0
+	inactiveCount, portsClosed := 0, 0
 	for _, asset := range am.assets {
-		if asset.IsActive && asset.LastSeen.Before(cutoff) {
+		asset.mu.RLock()
+		cutoff := now.Add(-am.inactivityTimeout(asset.DeviceType))
+		inactive := asset.IsActive && asset.LastSeen.Before(cutoff)
+		asset.mu.RUnlock()
+
+		changed := false
+		if inactive {
 			asset.SetInactive()
+			am.counts.track(asset)
 			inactiveCount++
+			changed = true
+		}
+
+		// 长时间未再出现的端口标记为关闭，并按关闭后的端口重新计算风险评分
+		if portTimeout > 0 && asset.expirePorts(now, portTimeout) {
+			am.checkRisk(asset)
+			portsClosed++
+			changed = true
+		}
 
+		if changed {
 			// 保存状态变更
-			go am.saveAsset(asset.ID)
+			am.saveAsync(asset.ID)
 		}
 	}
 
 	if inactiveCount > 0 {
 		log.Printf("标记了 %d 个资产为非活跃状态", inactiveCount)
 	}
+	if portsClosed > 0 {
+		log.Printf("%d 个资产的部分端口超过 %v 未再出现，已标记为关闭", portsClosed, portTimeout)
+	}
+
+	if purged := am.purgeInactiveAssets(now); purged > 0 {
+		log.Printf("清除了 %d 个超过 %d 天未出现的非活跃资产", purged, am.config.Parser.PurgeAfter)
+	}
+}
+
+// purgeInactiveAssets 从内存和存储中删除非活跃时间超过purge_after天的资产，调用方需持有am.mutex写锁
+// 活跃资产不会被清除，purge_after为0时不清除任何资产
+func (am *AssetManager) purgeInactiveAssets(now time.Time) int {
+	if am.config.Parser.PurgeAfter <= 0 {
+		return 0
+	}
+	cutoff := now.AddDate(0, 0, -am.config.Parser.PurgeAfter)
+
+	var expired []string
+	for id, asset := range am.assets {
+		asset.mu.RLock()
+		if !asset.IsActive && asset.LastSeen.Before(cutoff) {
+			expired = append(expired, id)
+		}
+		asset.mu.RUnlock()
+	}
+
+	am.removeAssets(expired)
+	for _, id := range expired {
+		go am.deleteStoredAsset(id)
+	}
+
+	return len(expired)
+}
+
+// inactivityTimeout 获取设备类型对应的资产超时时间，未单独配置时使用全局超时
+// viper会将map的键转换为小写，因此按不区分大小写匹配设备类型
+func (am *AssetManager) inactivityTimeout(deviceType string) time.Duration {
+	for name, minutes := range am.config.Parser.DeviceTimeouts {
+		if minutes > 0 && strings.EqualFold(name, deviceType) {
+			return time.Duration(minutes) * time.Minute
+		}
+	}
+	return time.Duration(am.config.Parser.AssetTimeout) * time.Minute
 }
 
-// statsUpdateRoutine 统计信息更新例程
-func (am *AssetManager) statsUpdateRoutine() {
+// currentTime 获取数据包时间轴上的当前时间
+// 实时捕获时接近系统时间，离线分析时为pcap中的时间加上已流逝的处理时间，
+// 避免历史流量中的资产被立即判定为超时
+func (am *AssetManager) currentTime() time.Time {
+	if am.lastPacketTime.IsZero() {
+		return time.Now()
+	}
+	return am.lastPacketTime.Add(time.Since(am.lastPacketWall))
+}
+
+// statsUpdateRoutine 统计信息更新例程，资产数量和分布由增量统计维护，这里只更新时间
+func (am *AssetManager) statsUpdateRoutine(ctx context.Context) {
 	ticker := time.NewTicker(1 * time.Minute) // 每分钟更新统计
 	defer ticker.Stop()
 
@@ -291,63 +1278,303 @@ func (am *AssetManager) statsUpdateRoutine() {
 		select {
 		case <-ticker.C:
 			am.updateStats()
-		case <-am.stopCh:
+		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-// updateStats 更新统计信息
+// updateStats 按增量统计更新统计信息，不遍历资产
 func (am *AssetManager) updateStats() {
 	am.mutex.Lock()
 	defer am.mutex.Unlock()
 
-	am.stats.TotalAssets = len(am.assets)
-	am.stats.ActiveAssets = 0
-	am.stats.DeviceTypes = make(map[string]int)
-	am.stats.OSDistribution = make(map[string]int)
+	am.counts.fill(&am.stats)
 	am.stats.LastUpdate = time.Now()
+}
 
-	for _, asset := range am.assets {
-		if asset.IsActive {
-			am.stats.ActiveAssets++
+// notifyNewAsset 新资产通知
+func (am *AssetManager) notifyNewAsset(asset *Asset) {
+	am.publishEvent(AssetEvent{Type: EventNewAsset, AssetID: asset.ID, Asset: asset})
+
+	if !am.config.Alerting.Enabled || am.known.Contains(asset.IPAddress, asset.MACAddress) {
+		return
+	}
+
+	log.Printf("新资产告警: %s - %s (%s)", asset.ID, asset.IPAddress, asset.DeviceType)
+
+	am.alerts.Dispatch(&alert.Event{
+		Type:       alert.EventNewAsset,
+		Title:      "发现新资产",
+		AssetID:    asset.ID,
+		IPAddress:  asset.IPAddress,
+		MACAddress: asset.MACAddress,
+		DeviceType: asset.DeviceType,
+		FirstSeen:  asset.FirstSeen,
+	})
+
+	// TODO: 实现邮件告警
+}
+
+// checkIPBinding 根据ARP报文检查IP-MAC绑定是否冲突
+// 只使用ARP中的绑定，经路由器转发的IP流量源MAC都是网关，不能用于判断
+func (am *AssetManager) checkIPBinding(assetInfo *AssetInfo) {
+	arpInfo, ok := assetInfo.Protocols["arp"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	// ARP探测的源IP为0.0.0.0，不代表IP绑定
+	if probe, _ := arpInfo["probe"].(bool); probe {
+		return
+	}
+
+	ip, _ := arpInfo["src_ip"].(string)
+	mac, _ := arpInfo["src_mac"].(string)
+
+	seen := assetInfo.Timestamp
+	if seen.IsZero() {
+		seen = time.Now()
+	}
+
+	if conflict := am.bindings.observe(ip, mac, seen); conflict != nil {
+		am.notifyIPConflict(conflict)
+	}
+}
+
+// arpIPOwner 返回ARP报文中的IP此前归属的资产ID，不是ARP绑定时返回空
+// 与IP-MAC冲突检测相同，只使用ARP中的绑定，经路由器转发的IP流量源MAC都是网关
+func (am *AssetManager) arpIPOwner(assetInfo *AssetInfo) string {
+	arpInfo, ok := assetInfo.Protocols["arp"].(map[string]interface{})
+	if !ok || assetInfo.IPAddress == "" || assetInfo.MACAddress == "" {
+		return ""
+	}
+	if probe, _ := arpInfo["probe"].(bool); probe {
+		return ""
+	}
+	return am.aliases["ip_"+assetInfo.IPAddress]
+}
+
+// checkMACChange 已知IP改由其他MAC声明时在新资产上记录mac_change变更并告警，可能是ARP欺骗或设备更换
+// 调用方需持有am.mutex写锁
+func (am *AssetManager) checkMACChange(assetID, previousID string, seen time.Time) {
+	asset, exists := am.assets[assetID]
+	if previousID == "" || previousID == assetID || !exists {
+		return
+	}
+
+	oldMAC := strings.TrimPrefix(previousID, "mac_")
+	if previous, ok := am.assets[previousID]; ok {
+		previous.mu.RLock()
+		oldMAC = previous.MACAddress
+		previous.mu.RUnlock()
+	}
+
+	asset.recordMACChange(oldMAC, seen)
+
+	asset.mu.RLock()
+	ip, mac, deviceType := asset.IPAddress, asset.MACAddress, asset.DeviceType
+	asset.mu.RUnlock()
+
+	log.Printf("检测到MAC地址变更: %s 由 %s 变为 %s", ip, oldMAC, mac)
+
+	if !am.config.Alerting.Enabled || am.known.Contains(ip, mac) || asset.isTentative() ||
+		!am.bindings.reportOnce("mac_change", ip, oldMAC, mac, seen) {
+		return
+	}
+
+	am.alerts.Dispatch(&alert.Event{
+		Type:        alert.EventMACChange,
+		Title:       "IP地址对应的MAC地址变更",
+		AssetID:     assetID,
+		IPAddress:   ip,
+		MACAddress:  mac,
+		DeviceType:  deviceType,
+		FirstSeen:   seen,
+		Description: "IP地址 " + ip + " 原先由 " + oldMAC + " 声明，现在由 " + mac + " 声明，可能存在ARP欺骗或设备更换",
+	})
+}
+
+// notifyWPAD WPAD代理自动发现查询通知，需要在alert_rules中启用"wpad"规则
+func (am *AssetManager) notifyWPAD(assetID string, assetInfo *AssetInfo) {
+	if !am.config.Alerting.Enabled || !am.alertRuleEnabled("wpad") ||
+		am.known.Contains(assetInfo.IPAddress, assetInfo.MACAddress) {
+		return
+	}
+
+	am.alerts.Dispatch(&alert.Event{
+		Type:        alert.EventRuleMatch,
+		Title:       "检测到WPAD代理自动发现查询",
+		AssetID:     assetID,
+		IPAddress:   assetInfo.IPAddress,
+		MACAddress:  assetInfo.MACAddress,
+		FirstSeen:   seenTime(assetInfo),
+		Description: "该主机会自动查找代理配置，可能遭受WPAD劫持",
+	})
+}
+
+// notifyFindings 资产首次出现安全暴露时告警，已知资产不告警
+func (am *AssetManager) notifyFindings(asset *Asset, findings []Finding) {
+	if len(findings) == 0 {
+		return
+	}
+
+	asset.mu.RLock()
+	ip, mac, deviceType := asset.IPAddress, asset.MACAddress, asset.DeviceType
+	asset.mu.RUnlock()
+
+	for i, f := range findings {
+		log.Printf("发现安全暴露: %s (%s) %s", asset.ID, ip, f.ID)
+		am.publishEvent(AssetEvent{
+			Type:      EventFinding,
+			AssetID:   asset.ID,
+			Timestamp: f.FirstSeen,
+			Asset:     asset,
+			Finding:   &findings[i],
+		})
+	}
+
+	if !am.config.Alerting.Enabled || am.known.Contains(ip, mac) {
+		return
+	}
+
+	for _, f := range findings {
+		description := f.Description
+		if f.Evidence != "" {
+			description += "：" + f.Evidence
 		}
+		am.alerts.Dispatch(&alert.Event{
+			Type:        alert.EventFinding,
+			Title:       fmt.Sprintf("资产存在安全暴露 %s (%s)", f.ID, f.Severity),
+			AssetID:     asset.ID,
+			IPAddress:   ip,
+			MACAddress:  mac,
+			DeviceType:  deviceType,
+			FirstSeen:   f.FirstSeen,
+			Description: description,
+		})
+	}
+}
+
+// ReloadKnownAssets 重新加载已知资产列表，无效条目被忽略并在错误中列出
+func (am *AssetManager) ReloadKnownAssets(entries []string) error {
+	err := am.known.Load(entries)
+	log.Printf("已重新加载已知资产列表，共 %d 个有效条目", am.known.Len())
+	return err
+}
+
+// checkRisk 重新计算资产风险评分，评分首次超过告警规则中的阈值时告警
+// 置信度未达到门槛的资产不评分，达到门槛后再评分
+func (am *AssetManager) checkRisk(asset *Asset) {
+	if asset.isTentative() {
+		return
+	}
+
+	old, score := asset.updateRisk(am.risk)
+	if !am.config.Alerting.Enabled || score == old {
+		return
+	}
+
+	asset.mu.RLock()
+	ip, mac, firstSeen := asset.IPAddress, asset.MACAddress, asset.FirstSeen
+	factors := strings.Join(asset.RiskFactors, "；")
+	asset.mu.RUnlock()
+
+	if am.known.Contains(ip, mac) {
+		return
+	}
 
-		if asset.DeviceType != "" {
-			am.stats.DeviceTypes[asset.DeviceType]++
+	for _, rule := range am.riskRules {
+		if rule.matches(old) || !rule.matches(score) {
+			continue
 		}
+		am.alerts.Dispatch(&alert.Event{
+			Type:        alert.EventRuleMatch,
+			Title:       fmt.Sprintf("资产风险评分 %.1f 满足规则 %s", score, rule),
+			AssetID:     asset.ID,
+			IPAddress:   ip,
+			MACAddress:  mac,
+			FirstSeen:   firstSeen,
+			Description: factors,
+		})
+	}
+}
 
-		if asset.OSInfo.Family != "" {
-			am.stats.OSDistribution[asset.OSInfo.Family]++
+// alertRuleEnabled 检查告警规则是否启用
+func (am *AssetManager) alertRuleEnabled(rule string) bool {
+	for _, r := range am.config.Alerting.AlertRules {
+		if r == rule {
+			return true
 		}
 	}
+	return false
 }
 
-// notifyNewAsset 新资产通知
-func (am *AssetManager) notifyNewAsset(asset *Asset) {
+// notifyIPConflict IP-MAC冲突通知
+func (am *AssetManager) notifyIPConflict(conflict *IPMACConflict) {
+	log.Printf("检测到IP-MAC冲突: %s 由 %s 和 %s 同时声明", conflict.IPAddress, conflict.OldMAC, conflict.NewMAC)
+
 	if !am.config.Alerting.Enabled {
 		return
 	}
 
-	// 这里可以实现各种通知方式：邮件、Webhook、日志等
-	log.Printf("新资产告警: %s - %s (%s)", asset.ID, asset.IPAddress, asset.DeviceType)
-
-	// TODO: 实现具体的告警逻辑
-	// - 发送邮件
-	// - 调用Webhook
-	// - 写入告警日志
+	am.alerts.Dispatch(&alert.Event{
+		Type:        alert.EventIPMACConflict,
+		Title:       "IP-MAC绑定冲突",
+		IPAddress:   conflict.IPAddress,
+		MACAddress:  conflict.NewMAC,
+		FirstSeen:   conflict.DetectedAt,
+		Description: "IP地址 " + conflict.IPAddress + " 同时被 " + conflict.OldMAC + " 和 " + conflict.NewMAC + " 声明，可能存在ARP欺骗或IP冲突",
+	})
 }
 
-// matchesQuery 检查资产是否匹配查询
+// matchesQuery 检查资产是否匹配查询，主机名可能由反向解析在不持有管理器锁时补全，需持有资产的锁读取
 func (am *AssetManager) matchesQuery(asset *Asset, query string) bool {
+	asset.mu.RLock()
+	defer asset.mu.RUnlock()
+
 	// 简单的字符串匹配，可以扩展为更复杂的查询语法
 	return asset.IPAddress == query ||
 		asset.MACAddress == query ||
 		asset.Hostname == query ||
+		asset.ShortName == query ||
 		asset.DeviceType == query ||
 		asset.OSInfo.Family == query
 }
 
+// StreamAssets 按资产ID顺序将内存中的资产逐个写入w，format为json、ndjson、csv或stix，返回写出的资产数量
+// flatten或配置了export.flatten时按导出配置展开为扁平字段，csv格式总是展开
+// 只复制资产引用，每次序列化一个资产，导出大量资产时内存占用保持平稳
+func (am *AssetManager) StreamAssets(w io.Writer, format string, flatten bool) (int, error) {
+	var flattener *storage.Flattener
+	if flatten || am.config.Export.Flatten || format == "csv" {
+		flattener = storage.NewFlattener(&am.config.Export)
+	}
+
+	aw, err := storage.NewAssetWriter(w, format, flattener)
+	if err != nil {
+		return 0, err
+	}
+
+	am.mutex.RLock()
+	list := make([]*Asset, 0, len(am.assets))
+	for _, asset := range am.assets {
+		list = append(list, asset)
+	}
+	am.mutex.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+
+	for _, asset := range list {
+		if err := aw.Write(asset); err != nil {
+			return aw.Count(), err
+		}
+	}
+	return aw.Count(), aw.Close()
+}
+
 // ExportAssets 导出资产数据
 func (am *AssetManager) ExportAssets(format string) ([]byte, error) {
 	assets := am.GetAllAssets()
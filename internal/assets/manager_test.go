@@ -0,0 +1,793 @@
+package assets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"assets_discovery/internal/alert"
+	"assets_discovery/internal/config"
+	"assets_discovery/internal/storage"
+)
+
+// newTestConfig 创建不写入存储的最小配置
+func newTestConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Storage.NoStore = true
+	return cfg
+}
+
+// newTestManager 创建使用内存存储的资产管理器，不启动后台任务
+func newTestManager(cfg *config.Config) *AssetManager {
+	return NewAssetManager(cfg, storage.NewMemoryStorage())
+}
+
+// addTestAsset 直接向管理器加入资产，用于构造UpdateAsset无法产生的状态
+func addTestAsset(am *AssetManager, asset *Asset) {
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+
+	am.assets[asset.ID] = asset
+	am.index.add(asset)
+	am.counts.track(asset)
+}
+
+// setClock 将管理器在数据包时间轴上的当前时间设为now
+func setClock(am *AssetManager, now time.Time) {
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+
+	am.lastPacketTime = now
+	am.lastPacketWall = time.Now()
+}
+
+// isActive 在读锁下获取资产的活跃状态
+func isActive(asset *Asset) bool {
+	asset.mu.RLock()
+	defer asset.mu.RUnlock()
+	return asset.IsActive
+}
+
+// assetIDs 返回排序后的资产ID，便于比较查询结果
+func assetIDs(assets []*Asset) []string {
+	ids := make([]string, 0, len(assets))
+	for _, asset := range assets {
+		ids = append(ids, asset.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestGetAssetsByPort(t *testing.T) {
+	am := newTestManager(newTestConfig())
+
+	ports := map[string][]PortInfo{
+		"web":  {{Port: 80, Protocol: "tcp", State: "open"}, {Port: 443, Protocol: "tcp", State: "open"}},
+		"rdp":  {{Port: 3389, Protocol: "tcp", State: "open"}, {Port: 443, Protocol: "tcp", State: "open"}},
+		"dns":  {{Port: 53, Protocol: "udp", State: "open"}, {Port: 53, Protocol: "tcp", State: "open"}},
+		"snmp": {{Port: 161, Protocol: "udp", State: "open"}, {Port: 3389, Protocol: "tcp", State: "closed"}},
+	}
+	for id, open := range ports {
+		addTestAsset(am, &Asset{ID: id, OpenPorts: open})
+	}
+
+	tests := []struct {
+		name  string
+		port  int
+		proto string
+		want  []string
+	}{
+		{"shared port any proto", 443, "", []string{"rdp", "web"}},
+		{"rdp skips closed port", 3389, "", []string{"rdp"}},
+		{"udp only", 53, "udp", []string{"dns"}},
+		{"tcp only", 53, "tcp", []string{"dns"}},
+		{"proto mismatch", 161, "tcp", []string{}},
+		{"no asset", 22, "", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := assetIDs(am.GetAssetsByPort(tt.port, tt.proto))
+			if len(got) != len(tt.want) {
+				t.Fatalf("GetAssetsByPort(%d, %q) = %v, want %v", tt.port, tt.proto, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("GetAssetsByPort(%d, %q) = %v, want %v", tt.port, tt.proto, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestInactivityTimeoutPerDeviceType(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Parser.AssetTimeout = 30
+	cfg.Parser.DeviceTimeouts = map[string]int{"服务器": 24 * 60, "网络设备": 10}
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		deviceType string
+		idle       time.Duration
+		wantActive bool
+	}{
+		{"server quiet for an hour stays active", "服务器", time.Hour, true},
+		{"server gone for two days", "服务器", 48 * time.Hour, false},
+		{"network device idle past its timeout", "网络设备", 15 * time.Minute, false},
+		{"network device within its timeout", "网络设备", 5 * time.Minute, true},
+		{"other type uses global timeout", "工作站", 20 * time.Minute, true},
+		{"other type past global timeout", "工作站", 45 * time.Minute, false},
+	}
+
+	am := newTestManager(cfg)
+	for i, tt := range tests {
+		addTestAsset(am, &Asset{
+			ID:         tt.name,
+			IPAddress:  fmt.Sprintf("10.0.0.%d", i+1),
+			DeviceType: tt.deviceType,
+			LastSeen:   now.Add(-tt.idle),
+			IsActive:   true,
+		})
+	}
+
+	setClock(am, now)
+	am.cleanupInactiveAssets()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asset, _ := am.GetAsset(tt.name)
+			if got := isActive(asset); got != tt.wantActive {
+				t.Errorf("IsActive = %v, want %v", got, tt.wantActive)
+			}
+		})
+	}
+}
+
+func TestInactivityTimeoutCaseInsensitive(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Parser.AssetTimeout = 30
+	// viper读取配置时会将键名转换为小写
+	cfg.Parser.DeviceTimeouts = map[string]int{"router": 120, "printer": 0}
+
+	am := newTestManager(cfg)
+	tests := []struct {
+		deviceType string
+		want       time.Duration
+	}{
+		{"Router", 2 * time.Hour},
+		{"router", 2 * time.Hour},
+		{"Printer", 30 * time.Minute}, // 0表示不覆盖全局超时
+		{"", 30 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := am.inactivityTimeout(tt.deviceType); got != tt.want {
+			t.Errorf("inactivityTimeout(%q) = %v, want %v", tt.deviceType, got, tt.want)
+		}
+	}
+}
+
+func TestConcurrentUpdateAndMarshal(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Storage.NoStore = false
+	am := newTestManager(cfg)
+
+	const writers, updates = 8, 50
+	macs := []string{"00:1a:2b:00:00:01", "00:1a:2b:00:00:02", "00:1a:2b:00:00:03"}
+	stop := make(chan struct{})
+
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				for _, asset := range am.GetAllAssets() {
+					if _, err := json.Marshal(asset); err != nil {
+						t.Errorf("Marshal() error = %v", err)
+						return
+					}
+					asset.GetSummary()
+				}
+				am.GetStats()
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < updates; i++ {
+				am.UpdateAsset(&AssetInfo{
+					IPAddress:  fmt.Sprintf("10.0.0.%d", (w+i)%len(macs)+1),
+					MACAddress: macs[(w+i)%len(macs)],
+					Hostname:   fmt.Sprintf("host-%d", w),
+					OpenPorts:  []int{22, 80 + i%5},
+					Services:   map[string]interface{}{"ssh": "OpenSSH_8.9"},
+					Protocols:  map[string]interface{}{"http": map[string]interface{}{"path": fmt.Sprint(i)}},
+				})
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(stop)
+	readers.Wait()
+
+	am.saveAllAssets()
+
+	if got := len(am.GetAllAssets()); got != len(macs) {
+		t.Fatalf("assets = %d, want %d", got, len(macs))
+	}
+	stored, err := am.storage.GetAllAssets()
+	if err != nil {
+		t.Fatalf("GetAllAssets() error = %v", err)
+	}
+	if len(stored) != len(macs) {
+		t.Errorf("stored assets = %d, want %d", len(stored), len(macs))
+	}
+	for _, asset := range am.GetAllAssets() {
+		data, err := json.Marshal(asset)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		var decoded Asset
+		if err := json.Unmarshal(data, &decoded); err != nil || decoded.ID != asset.ID {
+			t.Errorf("round trip of %s failed: %v", asset.ID, err)
+		}
+	}
+}
+
+func TestParseFirstSeenSince(t *testing.T) {
+	now := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	am := newTestManager(newTestConfig())
+	setClock(am, now)
+
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"hours relative to packet time", "24h", now.Add(-24 * time.Hour), false},
+		{"minutes", "90m", now.Add(-90 * time.Minute), false},
+		{"days suffix", "7d", now.Add(-7 * 24 * time.Hour), false},
+		{"surrounding spaces", " 2h ", now.Add(-2 * time.Hour), false},
+		{"rfc3339", "2019-02-28T08:30:00Z", time.Date(2019, 2, 28, 8, 30, 0, 0, time.UTC), false},
+		{"rfc3339 with offset", "2019-02-28T16:30:00+08:00", time.Date(2019, 2, 28, 8, 30, 0, 0, time.UTC), false},
+		{"date in local time", "2019-02-01", time.Date(2019, 2, 1, 0, 0, 0, 0, time.Local), false},
+		{"zero window", "0h", time.Time{}, true},
+		{"negative days", "-3d", time.Time{}, true},
+		{"bad days", "xd", time.Time{}, true},
+		{"garbage", "yesterday", time.Time{}, true},
+		{"empty", "", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := am.ParseFirstSeenSince(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFirstSeenSince(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			// 相对时长以管理器时钟为准，允许测试运行期间时钟前进
+			if got.Before(tt.want) || got.After(tt.want.Add(time.Second)) {
+				t.Errorf("ParseFirstSeenSince(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterFirstSeenSince(t *testing.T) {
+	since := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	inventory := []*Asset{
+		{ID: "old", FirstSeen: since.Add(-48 * time.Hour)},
+		{ID: "just-before", FirstSeen: since.Add(-time.Second)},
+		{ID: "boundary", FirstSeen: since},
+		{ID: "new", FirstSeen: since.Add(time.Hour)},
+	}
+
+	tests := []struct {
+		name  string
+		since time.Time
+		want  []string
+	}{
+		{"boundary inclusive", since, []string{"boundary", "new"}},
+		{"zero time keeps all", time.Time{}, []string{"boundary", "just-before", "new", "old"}},
+		{"after newest", since.Add(2 * time.Hour), []string{}},
+		{"between", since.Add(-24 * time.Hour), []string{"boundary", "just-before", "new"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := assetIDs(FilterFirstSeenSince(inventory, tt.since))
+			if len(got) != len(tt.want) {
+				t.Fatalf("FilterFirstSeenSince() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("FilterFirstSeenSince() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// liveHeap 返回fn执行后仍被引用的堆内存，fn的返回值在测量期间保持存活
+func liveHeap(fn func() interface{}) int64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	result := fn()
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(result)
+	return int64(after.HeapAlloc) - int64(before.HeapAlloc)
+}
+
+// BenchmarkStreamAssets 比较流式导出和一次性序列化的内存占用，
+// 流式导出的live-B不随资产数量增长，一次性序列化则与清单大小成正比
+func BenchmarkStreamAssets(b *testing.B) {
+	for _, n := range []int{1000, 10000} {
+		am := newTestManager(newTestConfig())
+		for i := 0; i < n; i++ {
+			addTestAsset(am, &Asset{
+				ID:         fmt.Sprintf("mac_00:1a:2b:%02x:%02x:%02x", i>>16&0xff, i>>8&0xff, i&0xff),
+				IPAddress:  fmt.Sprintf("10.%d.%d.%d", i>>16&0xff, i>>8&0xff, i&0xff),
+				Hostname:   fmt.Sprintf("host-%d.corp.example", i),
+				Vendor:     "Dell Inc.",
+				DeviceType: "服务器",
+				OSInfo:     OSInfo{Family: "Linux", Version: "5.15"},
+				OpenPorts:  []PortInfo{{Port: 22, Protocol: "tcp", State: "open"}, {Port: 443, Protocol: "tcp", State: "open"}},
+				Services:   []ServiceInfo{{Name: "ssh", Port: 22, Protocol: "tcp", Version: "OpenSSH_8.9"}},
+				IsActive:   true,
+			})
+		}
+
+		for _, format := range []string{"json", "ndjson", "csv"} {
+			format := format
+			b.Run(fmt.Sprintf("stream/%s/%d", format, n), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					count, err := am.StreamAssets(io.Discard, format, false)
+					if err != nil || count != n {
+						b.Fatalf("StreamAssets() = %d, %v, want %d", count, err, n)
+					}
+				}
+				b.StopTimer()
+				b.ReportMetric(float64(liveHeap(func() interface{} {
+					am.StreamAssets(io.Discard, format, false)
+					return nil
+				})), "live-B")
+			})
+		}
+
+		b.Run(fmt.Sprintf("buffered/json/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := am.ExportAssets("json"); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(liveHeap(func() interface{} {
+				data, _ := am.ExportAssets("json")
+				return data
+			})), "live-B")
+		})
+	}
+}
+
+func TestPurgeInactiveAssets(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name       string
+		purgeAfter int
+		active     bool
+		idle       time.Duration
+		wantPurged bool
+	}{
+		{"inactive past retention", 7, false, 10 * day, true},
+		{"inactive within retention", 7, false, 3 * day, false},
+		{"inactive exactly at retention", 7, false, 7 * day, false},
+		{"active not yet timed out is never purged", 7, true, 10 * day, false},
+		{"active recently seen", 7, true, time.Minute, false},
+		{"purge disabled", 0, false, 365 * day, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.Parser.PurgeAfter = tt.purgeAfter
+			am := newTestManager(cfg)
+			addTestAsset(am, &Asset{ID: "a1", IPAddress: "10.0.0.1", LastSeen: now.Add(-tt.idle), IsActive: tt.active})
+
+			am.mutex.Lock()
+			purged := am.purgeInactiveAssets(now)
+			am.mutex.Unlock()
+
+			wantCount := 0
+			if tt.wantPurged {
+				wantCount = 1
+			}
+			if purged != wantCount {
+				t.Errorf("purged = %d, want %d", purged, wantCount)
+			}
+			if _, exists := am.GetAsset("a1"); exists == tt.wantPurged {
+				t.Errorf("asset exists = %v, want %v", exists, !tt.wantPurged)
+			}
+			if _, exists := am.GetAssetByIP("10.0.0.1"); exists == tt.wantPurged {
+				t.Errorf("IP index exists = %v, want %v", exists, !tt.wantPurged)
+			}
+		})
+	}
+}
+
+func TestCleanupPurgesWithPacketClock(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Parser.AssetTimeout = 30
+	cfg.Parser.PurgeAfter = 7
+	am := newTestManager(cfg)
+
+	// 离线分析2019年的流量时按数据包时间判断保留期限，而不是按系统时间全部清除
+	now := time.Date(2019, 3, 10, 0, 0, 0, 0, time.UTC)
+	addTestAsset(am, &Asset{ID: "stale", LastSeen: now.AddDate(0, 0, -8), IsActive: false})
+	addTestAsset(am, &Asset{ID: "recent", LastSeen: now.AddDate(0, 0, -2), IsActive: false})
+	addTestAsset(am, &Asset{ID: "live", LastSeen: now.Add(-time.Minute), IsActive: true})
+
+	setClock(am, now)
+	am.cleanupInactiveAssets()
+
+	for id, wantKept := range map[string]bool{"stale": false, "recent": true, "live": true} {
+		if _, exists := am.GetAsset(id); exists != wantKept {
+			t.Errorf("asset %s exists = %v, want %v", id, exists, wantKept)
+		}
+	}
+}
+
+func TestPurgeDropsPendingSaves(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	am, flaky := newFlakyManager(1)
+	am.config.Parser.PurgeAfter = 7
+	addTestAsset(am, &Asset{ID: "stale", IPAddress: "10.0.0.1", LastSeen: now.AddDate(0, 0, -10)})
+
+	// 保存失败的资产在重试队列中等待
+	am.saveAsset("stale")
+	if pending := am.GetStats().PendingWrites; pending != 1 {
+		t.Fatalf("pending writes = %d, want 1", pending)
+	}
+
+	am.mutex.Lock()
+	purged := am.purgeInactiveAssets(now)
+	am.mutex.Unlock()
+	if purged != 1 {
+		t.Fatalf("purged = %d, want 1", purged)
+	}
+
+	if pending := am.GetStats().PendingWrites; pending != 0 {
+		t.Errorf("pending writes after purge = %d, want 0", pending)
+	}
+	if !am.retryPendingSaves() {
+		t.Errorf("retryPendingSaves() = false, want true")
+	}
+	if flaky.calls != 1 {
+		t.Errorf("SaveAsset calls = %d, want 1 (purged asset not retried)", flaky.calls)
+	}
+}
+
+func TestSaveAfterPurgeSkipped(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		storage storage.Storage
+	}{
+		{"atomic storage", storage.NewMemoryStorage()},
+		// 只嵌入Storage接口，不支持原子更新
+		{"plain storage", &flakyStorage{Storage: storage.NewMemoryStorage()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.Storage.NoStore = false
+			cfg.Parser.PurgeAfter = 7
+			am := NewAssetManager(cfg, tt.storage)
+			stale := &Asset{ID: "stale", IPAddress: "10.0.0.1", LastSeen: now.AddDate(0, 0, -10)}
+			addTestAsset(am, stale)
+			am.saveAsset("stale")
+			waitStored(t, am, "stale")
+
+			am.mutex.Lock()
+			am.purgeInactiveAssets(now)
+			am.mutex.Unlock()
+			waitFor(t, "purged asset deleted from storage", func() bool {
+				return len(storedIDs(t, am)) == 0
+			})
+
+			// 清理前已取得资产的异步保存不会把资产写回存储
+			if err := am.storeAsset(stale); err != nil {
+				t.Fatalf("storeAsset() error = %v", err)
+			}
+			am.saveAsset("stale")
+			if got := storedIDs(t, am); len(got) != 0 {
+				t.Errorf("stored assets after purge = %v, want none", got)
+			}
+		})
+	}
+}
+
+func TestStalePortsClosed(t *testing.T) {
+	now := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		portTimeout int
+		port        int
+		state       string
+		age         time.Duration
+		wantState   string
+	}{
+		{"stale port closed", 60, 22, "open", 2 * time.Hour, "closed"},
+		{"recent port kept", 60, 80, "open", 10 * time.Minute, "open"},
+		{"port just within timeout kept", 60, 443, "open", 59 * time.Minute, "open"},
+		{"closed port unchanged", 60, 3389, "closed", 3 * time.Hour, "closed"},
+		{"expiry disabled", 0, 22, "open", 48 * time.Hour, "open"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.Parser.AssetTimeout = 7 * 24 * 60
+			cfg.Parser.PortTimeout = tt.portTimeout
+			am := newTestManager(cfg)
+			addTestAsset(am, &Asset{
+				ID:        "a1",
+				IPAddress: "10.0.0.1",
+				LastSeen:  now,
+				IsActive:  true,
+				OpenPorts: []PortInfo{{Port: tt.port, Protocol: "tcp", State: tt.state, LastSeen: now.Add(-tt.age)}},
+			})
+
+			setClock(am, now)
+			am.cleanupInactiveAssets()
+
+			asset, _ := am.GetAsset("a1")
+			asset.mu.RLock()
+			state := asset.OpenPorts[0].State
+			changes := len(asset.Changes)
+			asset.mu.RUnlock()
+
+			if state != tt.wantState {
+				t.Errorf("port %d state = %q, want %q", tt.port, state, tt.wantState)
+			}
+			wantChanges := 0
+			if tt.state != tt.wantState {
+				wantChanges = 1
+			}
+			if changes != wantChanges {
+				t.Errorf("changes = %d, want %d", changes, wantChanges)
+			}
+			if !isActive(asset) {
+				t.Errorf("asset marked inactive by port expiry")
+			}
+		})
+	}
+}
+
+func TestClosedPortReopens(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Parser.AssetTimeout = 7 * 24 * 60
+	cfg.Parser.PortTimeout = 60
+	am := newTestManager(cfg)
+
+	start := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	am.UpdateAsset(&AssetInfo{MACAddress: testMAC, IPAddress: "10.0.0.1", OpenPorts: []int{22}, Timestamp: start})
+
+	setClock(am, start.Add(2*time.Hour))
+	am.cleanupInactiveAssets()
+	if got := am.GetAssetsByPort(22, "tcp"); len(got) != 0 {
+		t.Fatalf("stale port still listed as open")
+	}
+
+	am.UpdateAsset(&AssetInfo{MACAddress: testMAC, IPAddress: "10.0.0.1", OpenPorts: []int{22}, Timestamp: start.Add(3 * time.Hour)})
+	if got := am.GetAssetsByPort(22, "tcp"); len(got) != 1 {
+		t.Errorf("port seen again not reopened")
+	}
+}
+
+// aggregateStorage 支持存储端聚合的内存存储，unsupported中的字段返回错误
+type aggregateStorage struct {
+	storage.Storage
+	counts      map[string]int
+	unsupported string
+}
+
+func (s *aggregateStorage) AggregateAssets(field string, activeOnly bool) (map[string]int, error) {
+	if field == s.unsupported {
+		return nil, fmt.Errorf("不支持的聚合字段: %s", field)
+	}
+	return s.counts, nil
+}
+
+func TestAggregateAssets(t *testing.T) {
+	am := newTestManager(newTestConfig())
+	addTestAsset(am, &Asset{ID: "a1", IPAddress: "10.0.1.5", Vendor: "Cisco", DeviceType: "网络设备", OSInfo: OSInfo{Family: "IOS"}, IsActive: true})
+	addTestAsset(am, &Asset{ID: "a2", IPAddress: "10.0.1.9", Vendor: "Dell", DeviceType: "服务器", OSInfo: OSInfo{Family: "Linux"}, IsActive: true})
+	addTestAsset(am, &Asset{ID: "a3", IPAddress: "10.0.2.7", Vendor: "Dell", DeviceType: "服务器", OSInfo: OSInfo{Family: "Linux"}})
+	addTestAsset(am, &Asset{ID: "a4", IPAddress: "2001:db8:0:1::20", IsActive: true})
+
+	tests := []struct {
+		field      string
+		activeOnly bool
+		want       map[string]int
+	}{
+		{"device_type", false, map[string]int{"网络设备": 1, "服务器": 2, "unknown": 1}},
+		{"device_type", true, map[string]int{"网络设备": 1, "服务器": 1, "unknown": 1}},
+		{"os_family", false, map[string]int{"IOS": 1, "Linux": 2, "unknown": 1}},
+		{"os_family", true, map[string]int{"IOS": 1, "Linux": 1, "unknown": 1}},
+		{"vendor", false, map[string]int{"Cisco": 1, "Dell": 2, "unknown": 1}},
+		{"vendor", true, map[string]int{"Cisco": 1, "Dell": 1, "unknown": 1}},
+		{"subnet", false, map[string]int{"10.0.1.0/24": 2, "10.0.2.0/24": 1, "2001:db8:0:1::/64": 1}},
+		{"subnet", true, map[string]int{"10.0.1.0/24": 2, "2001:db8:0:1::/64": 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/active=%v", tt.field, tt.activeOnly), func(t *testing.T) {
+			got, err := am.AggregateAssets(tt.field, tt.activeOnly)
+			if err != nil {
+				t.Fatalf("AggregateAssets() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AggregateAssets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := am.AggregateAssets("hostname", false); err == nil {
+		t.Errorf("AggregateAssets(hostname) error = nil, want unsupported field")
+	}
+}
+
+func TestAggregateAssetsPushdown(t *testing.T) {
+	store := &aggregateStorage{
+		Storage:     storage.NewMemoryStorage(),
+		counts:      map[string]int{"服务器": 42},
+		unsupported: "subnet",
+	}
+	am := NewAssetManager(newTestConfig(), store)
+	addTestAsset(am, &Asset{ID: "a1", IPAddress: "10.0.1.5", DeviceType: "服务器", IsActive: true})
+
+	// 存储支持的字段使用存储端的结果
+	got, err := am.AggregateAssets("device_type", false)
+	if err != nil || !reflect.DeepEqual(got, map[string]int{"服务器": 42}) {
+		t.Errorf("AggregateAssets(device_type) = %v, %v, want storage counts", got, err)
+	}
+
+	// 存储不支持时回退到内存计算
+	got, err = am.AggregateAssets("subnet", false)
+	if err != nil || !reflect.DeepEqual(got, map[string]int{"10.0.1.0/24": 1}) {
+		t.Errorf("AggregateAssets(subnet) = %v, %v, want in-memory counts", got, err)
+	}
+}
+
+func TestApplyReverseDNS(t *testing.T) {
+	am := newTestManager(newTestConfig())
+	now := time.Now()
+	am.UpdateAsset(&AssetInfo{IPAddress: "10.0.0.1", MACAddress: "00:11:22:33:44:01", Timestamp: now})
+	am.UpdateAsset(&AssetInfo{IPAddress: "10.0.0.2", MACAddress: "00:11:22:33:44:02", Hostname: "nas", HostnameSource: "nbns", Timestamp: now})
+
+	am.applyReverseDNS("10.0.0.1", "Printer.Corp.Example")
+	am.applyReverseDNS("10.0.0.2", "storage.corp.example")
+	am.applyReverseDNS("10.0.0.9", "unknown.corp.example")
+
+	// 只补全空缺的主机名，来源记为reverse_dns
+	filled, _ := am.GetAsset("mac_00:11:22:33:44:01")
+	if filled.Hostname != "printer.corp.example" || filled.HostnameSource != "reverse_dns" {
+		t.Errorf("filled hostname = %q (%s), want printer.corp.example (reverse_dns)", filled.Hostname, filled.HostnameSource)
+	}
+	if source := filled.Provenance["hostname"]; source.Source != "reverse_dns" || source.Value != "printer.corp.example" {
+		t.Errorf("hostname provenance = %+v, want reverse_dns", source)
+	}
+
+	kept, _ := am.GetAsset("mac_00:11:22:33:44:02")
+	if kept.Hostname != "nas" || kept.HostnameSource != "nbns" {
+		t.Errorf("observed hostname = %q (%s), want nas (nbns)", kept.Hostname, kept.HostnameSource)
+	}
+
+	// 被动观测到的主机名覆盖反向解析结果
+	am.UpdateAsset(&AssetInfo{IPAddress: "10.0.0.1", MACAddress: "00:11:22:33:44:01", Hostname: "laserjet", HostnameSource: "http", Timestamp: now})
+	if filled.Hostname != "laserjet" || filled.HostnameSource != "http" {
+		t.Errorf("hostname after observation = %q (%s), want laserjet (http)", filled.Hostname, filled.HostnameSource)
+	}
+}
+
+func TestSearchDuringReverseDNS(t *testing.T) {
+	const n = 50
+	am := newTestManager(newTestConfig())
+	now := time.Now()
+	for i := 0; i < n; i++ {
+		am.UpdateAsset(&AssetInfo{IPAddress: fmt.Sprintf("10.0.1.%d", i), MACAddress: fmt.Sprintf("00:11:22:33:55:%02x", i), Timestamp: now})
+	}
+
+	// 反向解析在不持有管理器锁时补全主机名，与按主机名搜索并发执行，由-race检查数据竞争
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < n; i++ {
+			am.applyReverseDNS(fmt.Sprintf("10.0.1.%d", i), fmt.Sprintf("host-%d.corp.example", i))
+		}
+	}()
+	for i := 0; i < n; i++ {
+		am.SearchAssets(fmt.Sprintf("host-%d", i))
+	}
+	<-done
+
+	if got := am.SearchAssets("host-7"); len(got) != 1 || got[0].IPAddress != "10.0.1.7" {
+		t.Errorf("SearchAssets(host-7) = %v, want asset 10.0.1.7", got)
+	}
+}
+
+// alertRecorder 记录发送到测试Webhook的告警事件
+type alertRecorder struct {
+	mu     sync.Mutex
+	events []alert.Event
+}
+
+// newAlertRecorder 启动接收告警的Webhook服务，并在cfg中启用告警
+func newAlertRecorder(t *testing.T, cfg *config.Config) *alertRecorder {
+	t.Helper()
+
+	rec := &alertRecorder{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event alert.Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("invalid alert payload: %v", err)
+			return
+		}
+		rec.mu.Lock()
+		rec.events = append(rec.events, event)
+		rec.mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+
+	cfg.Alerting.Enabled = true
+	cfg.Alerting.WebhookURL = server.URL
+	return rec
+}
+
+// ofType 返回已收到的指定类型的告警
+func (r *alertRecorder) ofType(eventType string) []alert.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []alert.Event
+	for _, event := range r.events {
+		if event.Type == eventType {
+			events = append(events, event)
+		}
+	}
+	return events
+}
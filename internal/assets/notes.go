@@ -0,0 +1,77 @@
+package assets
+
+import (
+	"log"
+	"time"
+)
+
+// MaxNotesLength 备注的最大字节数
+const MaxNotesLength = 4096
+
+// SetNotes 设置运维人员填写的资产备注，notes为空时清除备注，返回更新后的资产
+// 资产只存在于存储中时（如已被清理出内存）直接更新存储中的记录；资产不存在时返回false
+func (am *AssetManager) SetNotes(assetID, notes string) (*Asset, bool, error) {
+	am.mutex.RLock()
+	asset, inMemory := am.assets[am.resolveAssetID(assetID)]
+	am.mutex.RUnlock()
+
+	if !inMemory {
+		if am.config.Storage.NoStore {
+			return nil, false, nil
+		}
+		stored, err := am.GetStoredAsset(assetID)
+		if err != nil {
+			return nil, false, nil
+		}
+		asset = stored
+	}
+
+	now := time.Now()
+	asset.mu.Lock()
+	asset.Notes = notes
+	asset.NotesUpdated = &now
+	asset.mu.Unlock()
+
+	log.Printf("更新资产备注: %s", asset.ID)
+
+	if am.config.Storage.NoStore {
+		return asset, true, nil
+	}
+	if err := am.storeAsset(asset); err != nil {
+		if inMemory {
+			am.queueRetry(asset.ID)
+		}
+		return asset, true, err
+	}
+	return asset, true, nil
+}
+
+// adoptNotes 合并资产记录时保留最后修改的备注，调用方需持有两个资产的锁
+func (a *Asset) adoptNotes(other *Asset) {
+	if other.NotesUpdated == nil {
+		return
+	}
+	if a.NotesUpdated == nil || other.NotesUpdated.After(*a.NotesUpdated) {
+		a.Notes = other.Notes
+		updated := *other.NotesUpdated
+		a.NotesUpdated = &updated
+	}
+}
+
+// mergeStoredNotes 保存前与存储中的备注合并：备注可能由其他采集器的API修改，保留最后修改的备注
+func mergeStoredNotes(current, updated map[string]interface{}) {
+	storedAt, ok := parseStoredTime(current["notes_updated"])
+	if !ok {
+		return
+	}
+	if at, ok := parseStoredTime(updated["notes_updated"]); ok && !storedAt.After(at) {
+		return
+	}
+
+	updated["notes_updated"] = current["notes_updated"]
+	if notes, ok := current["notes"]; ok {
+		updated["notes"] = notes
+	} else {
+		delete(updated, "notes")
+	}
+}
@@ -0,0 +1,125 @@
+package assets
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNotesSurviveUpdate(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Storage.NoStore = false
+	am := newTestManager(cfg)
+	const id = "mac_" + testMAC
+	now := time.Now()
+
+	am.UpdateAsset(&AssetInfo{IPAddress: "10.0.0.5", MACAddress: testMAC, Hostname: "web-1", Timestamp: now})
+	asset, found, err := am.SetNotes(id, "decommissioning next week")
+	if !found || err != nil {
+		t.Fatalf("SetNotes() = %v, %v, want found", found, err)
+	}
+	if asset.NotesUpdated == nil {
+		t.Fatal("NotesUpdated = nil after SetNotes")
+	}
+
+	// 自动发现的字段变化不会覆盖备注
+	am.UpdateAsset(&AssetInfo{
+		IPAddress:  "10.0.0.6",
+		MACAddress: testMAC,
+		Hostname:   "web-2",
+		OSGuess:    "Linux",
+		OpenPorts:  []int{22, 80},
+		Timestamp:  now.Add(time.Minute),
+	})
+	if asset.Notes != "decommissioning next week" || asset.IPAddress != "10.0.0.6" {
+		t.Errorf("after update notes = %q, ip = %s, want note kept and ip updated", asset.Notes, asset.IPAddress)
+	}
+
+	waitFor(t, "stored notes", func() bool {
+		stored, err := am.GetStoredAsset(id)
+		return err == nil && stored.Notes == "decommissioning next week" && stored.IPAddress == "10.0.0.6"
+	})
+
+	// 空备注清除备注
+	if _, _, err := am.SetNotes(id, ""); err != nil {
+		t.Fatalf("SetNotes(\"\") error = %v", err)
+	}
+	am.UpdateAsset(&AssetInfo{IPAddress: "10.0.0.6", MACAddress: testMAC, Timestamp: now.Add(2 * time.Minute)})
+	if asset.Notes != "" {
+		t.Errorf("notes after clear = %q, want empty", asset.Notes)
+	}
+
+	if _, found, _ := am.SetNotes("mac_ff:ff:ff:ff:ff:ff", "x"); found {
+		t.Error("SetNotes(missing) found = true, want false")
+	}
+}
+
+func TestNotesSurviveMerge(t *testing.T) {
+	am := newTestManager(newTestConfig())
+	const ip = "10.0.0.5"
+	now := time.Now()
+
+	// 先只以IP出现并添加备注，之后并入MAC资产时保留备注
+	am.UpdateAsset(&AssetInfo{IPAddress: ip, Timestamp: now})
+	if _, found, _ := am.SetNotes("ip_"+ip, "owned by finance"); !found {
+		t.Fatal("SetNotes(ip asset) found = false")
+	}
+	am.UpdateAsset(&AssetInfo{IPAddress: ip, MACAddress: testMAC, Timestamp: now.Add(time.Minute)})
+
+	asset, ok := am.GetAsset("mac_" + testMAC)
+	if !ok || asset.Notes != "owned by finance" || asset.NotesUpdated == nil {
+		t.Fatalf("merged asset notes = %v, want owned by finance", asset)
+	}
+}
+
+func TestMergeStoredNotes(t *testing.T) {
+	// 存储中的时间是JSON序列化后的字符串
+	older := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC).Format(time.RFC3339Nano)
+	newer := time.Date(2024, 6, 1, 13, 0, 0, 0, time.UTC).Format(time.RFC3339Nano)
+
+	tests := []struct {
+		name      string
+		current   map[string]interface{}
+		updated   map[string]interface{}
+		wantNotes interface{}
+	}{
+		{
+			name:      "no stored notes",
+			current:   map[string]interface{}{},
+			updated:   map[string]interface{}{"notes": "local", "notes_updated": older},
+			wantNotes: "local",
+		},
+		{
+			name:      "stored notes kept when local has none",
+			current:   map[string]interface{}{"notes": "remote", "notes_updated": older},
+			updated:   map[string]interface{}{},
+			wantNotes: "remote",
+		},
+		{
+			name:      "newer stored notes win",
+			current:   map[string]interface{}{"notes": "remote", "notes_updated": newer},
+			updated:   map[string]interface{}{"notes": "local", "notes_updated": older},
+			wantNotes: "remote",
+		},
+		{
+			name:      "newer local notes win",
+			current:   map[string]interface{}{"notes": "remote", "notes_updated": older},
+			updated:   map[string]interface{}{"notes": "local", "notes_updated": newer},
+			wantNotes: "local",
+		},
+		{
+			name:      "newer stored clear wins",
+			current:   map[string]interface{}{"notes_updated": newer},
+			updated:   map[string]interface{}{"notes": "local", "notes_updated": older},
+			wantNotes: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mergeStoredNotes(tt.current, tt.updated)
+			if got := tt.updated["notes"]; got != tt.wantNotes {
+				t.Errorf("notes = %v, want %v", got, tt.wantNotes)
+			}
+		})
+	}
+}
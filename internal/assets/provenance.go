@@ -0,0 +1,77 @@
+package assets
+
+import "time"
+
+// 资产字段的来源记录，用于解释资产为何被识别为某个主机名、操作系统、厂商或设备类型，
+// 例如操作系统来自TTL推测还是DHCP厂商标识。只记录决定当前取值的来源，取值或来源变化时更新。
+
+// FieldSource 字段当前取值的来源
+type FieldSource struct {
+	Value     string    `json:"value"`     // 记录时的字段取值
+	Source    string    `json:"source"`    // 来源协议或检测方法，如dhcp、ttl_analysis、oui
+	Timestamp time.Time `json:"timestamp"` // 取值被设置的时间，离线分析时为数据包时间
+}
+
+// trackProvenance 按资产当前的取值更新各字段的来源，调用方需持有写锁
+// osSource为本次观测中操作系统推测的检测方法，没有推测时为空
+func (a *Asset) trackProvenance(osSource string, now time.Time) {
+	a.noteSource("hostname", a.Hostname, a.HostnameSource, now)
+	a.noteSource("vendor", a.Vendor, "oui", now)
+	a.noteSource("device_type", a.DeviceType, a.DeviceTypeSource, now)
+
+	// 同一操作系统被更可信的方法再次识别时改记为该方法
+	if osSource != "" && a.OSInfo.Family != "" {
+		current, ok := a.Provenance["os_family"]
+		if !ok || current.Value != a.OSInfo.Family ||
+			OSDetectionConfidence(osSource) > OSDetectionConfidence(current.Source) {
+			a.noteSource("os_family", a.OSInfo.Family, osSource, now)
+		}
+	}
+}
+
+// noteSource 字段取值或来源与记录不同时更新记录，取值或来源为空时不记录，调用方需持有写锁
+func (a *Asset) noteSource(field, value, source string, now time.Time) {
+	if value == "" || source == "" {
+		return
+	}
+	if current, ok := a.Provenance[field]; ok && current.Value == value && current.Source == source {
+		return
+	}
+
+	if a.Provenance == nil {
+		a.Provenance = make(map[string]FieldSource)
+	}
+	a.Provenance[field] = FieldSource{Value: value, Source: source, Timestamp: now}
+}
+
+// adoptProvenance 合并资产记录后，取值来自other的字段沿用other记录的来源，调用方需持有两个资产的锁
+func (a *Asset) adoptProvenance(other *Asset) {
+	current := map[string]string{
+		"hostname":    a.Hostname,
+		"vendor":      a.Vendor,
+		"device_type": a.DeviceType,
+		"os_family":   a.OSInfo.Family,
+	}
+
+	for field, source := range other.Provenance {
+		if source.Value != current[field] {
+			continue
+		}
+		if mine, ok := a.Provenance[field]; ok && mine.Value == source.Value {
+			continue
+		}
+		if a.Provenance == nil {
+			a.Provenance = make(map[string]FieldSource)
+		}
+		a.Provenance[field] = source
+	}
+}
+
+// copyProvenance 复制来源记录，调用方需持有读锁
+func (a *Asset) copyProvenance() map[string]FieldSource {
+	provenance := make(map[string]FieldSource, len(a.Provenance))
+	for field, source := range a.Provenance {
+		provenance[field] = source
+	}
+	return provenance
+}
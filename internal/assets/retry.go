@@ -0,0 +1,134 @@
+package assets
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	saveRetryMinBackoff = 5 * time.Second // 重试保存的初始间隔
+	saveRetryMaxBackoff = 5 * time.Minute // 存储持续不可用时重试间隔的上限
+	maxPendingSaves     = 10000           // 等待重试保存的资产上限
+)
+
+// saveRetryQueue 保存失败、等待重试的资产ID
+// 只记录ID，重试时保存资产在内存中的最新状态，同一资产多次失败只占一个位置
+type saveRetryQueue struct {
+	mu      sync.Mutex
+	pending map[string]bool
+	dropped uint64 // 队列已满时丢弃的保存次数
+}
+
+func newSaveRetryQueue() *saveRetryQueue {
+	return &saveRetryQueue{pending: make(map[string]bool)}
+}
+
+// add 将资产加入重试队列，队列已满时丢弃并返回false
+func (q *saveRetryQueue) add(assetID string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.pending[assetID] && len(q.pending) >= maxPendingSaves {
+		q.dropped++
+		return false
+	}
+	q.pending[assetID] = true
+	return true
+}
+
+// done 资产已成功保存，从重试队列中移除
+func (q *saveRetryQueue) done(assetID string) {
+	q.mu.Lock()
+	delete(q.pending, assetID)
+	q.mu.Unlock()
+}
+
+// take 取出全部等待重试的资产ID
+func (q *saveRetryQueue) take() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ids := make([]string, 0, len(q.pending))
+	for id := range q.pending {
+		ids = append(ids, id)
+	}
+	q.pending = make(map[string]bool)
+	return ids
+}
+
+// counts 返回等待重试的资产数和累计丢弃的保存次数
+func (q *saveRetryQueue) counts() (int, uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending), q.dropped
+}
+
+// queueRetry 资产保存失败后加入重试队列，队列已满时丢弃并记录累计丢弃次数
+func (am *AssetManager) queueRetry(assetID string) {
+	if am.retries.add(assetID) {
+		return
+	}
+	_, dropped := am.retries.counts()
+	am.logs.Printf("save-dropped", "保存重试队列已满（上限 %d），丢弃资产 %s 的更新，累计丢弃 %d 次", maxPendingSaves, assetID, dropped)
+}
+
+// retryRoutine 定期重新保存失败的资产，存储持续不可用时重试间隔逐次加倍，恢复后重置
+func (am *AssetManager) retryRoutine(ctx context.Context) {
+	backoff := saveRetryMinBackoff
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			if am.retryPendingSaves() {
+				backoff = saveRetryMinBackoff
+			} else if backoff *= 2; backoff > saveRetryMaxBackoff {
+				backoff = saveRetryMaxBackoff
+			}
+			timer.Reset(backoff)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// retryPendingSaves 重新保存重试队列中的资产，返回是否全部成功
+// 遇到第一个失败后不再访问存储，剩余资产留到下次重试；已从内存中删除的资产直接丢弃
+func (am *AssetManager) retryPendingSaves() bool {
+	ids := am.retries.take()
+	if len(ids) == 0 {
+		return true
+	}
+
+	saved := 0
+	var lastErr error
+	for _, id := range ids {
+		am.mutex.RLock()
+		asset, exists := am.assets[id]
+		am.mutex.RUnlock()
+		if !exists {
+			continue
+		}
+
+		if lastErr == nil {
+			if lastErr = am.storeAsset(asset); lastErr == nil {
+				saved++
+				continue
+			}
+		}
+		am.queueRetry(id)
+	}
+
+	if lastErr != nil {
+		pending, _ := am.retries.counts()
+		am.logs.Printf("save-retry", "重试保存资产失败，成功 %d 个，剩余 %d 个: %v", saved, pending, lastErr)
+		return false
+	}
+	if saved > 0 {
+		log.Printf("重试保存资产成功 %d 个", saved)
+	}
+	return true
+}
@@ -0,0 +1,191 @@
+package assets
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"assets_discovery/internal/storage"
+)
+
+// flakyStorage 前failures次SaveAsset返回错误，之后写入内存存储
+// 只嵌入Storage接口，不支持原子更新，保存时直接调用SaveAsset
+type flakyStorage struct {
+	storage.Storage
+
+	mu       sync.Mutex
+	failures int
+	calls    int
+}
+
+func (s *flakyStorage) SaveAsset(asset interface{}) error {
+	s.mu.Lock()
+	s.calls++
+	fail := s.calls <= s.failures
+	s.mu.Unlock()
+
+	if fail {
+		return errors.New("storage unavailable")
+	}
+	return s.Storage.SaveAsset(asset)
+}
+
+// newFlakyManager 创建使用前failures次保存失败的存储的管理器
+func newFlakyManager(failures int) (*AssetManager, *flakyStorage) {
+	cfg := newTestConfig()
+	cfg.Storage.NoStore = false
+	flaky := &flakyStorage{Storage: storage.NewMemoryStorage(), failures: failures}
+	return NewAssetManager(cfg, flaky), flaky
+}
+
+func TestSaveRetryEventuallyPersists(t *testing.T) {
+	const failures = 3
+	am, _ := newFlakyManager(failures)
+
+	am.UpdateAsset(&AssetInfo{IPAddress: "10.0.0.1", MACAddress: "00:11:22:33:44:55", Timestamp: time.Now()})
+	waitFor(t, "failed save queued", func() bool {
+		return am.GetStats().PendingWrites == 1
+	})
+
+	// 第一次异步保存已失败，剩余的失败由重试消耗
+	for i := 1; i < failures; i++ {
+		if am.retryPendingSaves() {
+			t.Fatalf("retry %d succeeded, want failure", i)
+		}
+		if pending := am.GetStats().PendingWrites; pending != 1 {
+			t.Fatalf("pending writes after failed retry %d = %d, want 1", i, pending)
+		}
+	}
+	if !am.retryPendingSaves() {
+		t.Fatal("retry after storage recovered failed")
+	}
+
+	stats := am.GetStats()
+	if stats.PendingWrites != 0 || stats.DroppedWrites != 0 {
+		t.Errorf("pending = %d, dropped = %d, want 0, 0", stats.PendingWrites, stats.DroppedWrites)
+	}
+	if _, err := am.storage.GetAsset("mac_00:11:22:33:44:55"); err != nil {
+		t.Errorf("GetAsset() from storage error = %v", err)
+	}
+}
+
+func TestSaveRetrySkipsDeletedAsset(t *testing.T) {
+	am, flaky := newFlakyManager(1)
+	addTestAsset(am, &Asset{ID: "gone", IPAddress: "10.0.0.2"})
+
+	am.saveAsset("gone")
+	if pending := am.GetStats().PendingWrites; pending != 1 {
+		t.Fatalf("pending writes = %d, want 1", pending)
+	}
+
+	am.removeAssets([]string{"gone"})
+	if !am.retryPendingSaves() {
+		t.Fatal("retryPendingSaves() = false, want true")
+	}
+	if pending := am.GetStats().PendingWrites; pending != 0 {
+		t.Errorf("pending writes = %d, want 0", pending)
+	}
+	if flaky.calls != 1 {
+		t.Errorf("SaveAsset calls = %d, want 1 (deleted asset not retried)", flaky.calls)
+	}
+}
+
+func TestSaveRetryQueueFull(t *testing.T) {
+	am, _ := newFlakyManager(1)
+
+	// 队列中已有其他资产占满全部位置
+	for i := 0; i < maxPendingSaves; i++ {
+		am.retries.add(fmt.Sprintf("pending-%d", i))
+	}
+
+	addTestAsset(am, &Asset{ID: "overflow", IPAddress: "10.0.0.3"})
+	am.saveAsset("overflow")
+
+	stats := am.GetStats()
+	if stats.PendingWrites != maxPendingSaves || stats.DroppedWrites != 1 {
+		t.Errorf("pending = %d, dropped = %d, want %d, 1", stats.PendingWrites, stats.DroppedWrites, maxPendingSaves)
+	}
+
+	// 已在队列中的资产再次失败不占用新位置，也不计为丢弃
+	am.queueRetry("pending-0")
+	if _, dropped := am.retries.counts(); dropped != 1 {
+		t.Errorf("dropped after requeue = %d, want 1", dropped)
+	}
+}
+
+// conflictStorage 前conflicts次原子更新返回冲突错误，记录普通保存的调用次数
+type conflictStorage struct {
+	*storage.MemoryStorage
+
+	mu        sync.Mutex
+	conflicts int
+	saves     int
+}
+
+func (s *conflictStorage) UpdateAsset(id string, mutate storage.AssetMutator) error {
+	s.mu.Lock()
+	conflict := s.conflicts > 0
+	if conflict {
+		s.conflicts--
+	}
+	s.mu.Unlock()
+
+	if conflict {
+		return fmt.Errorf("更新资产冲突次数过多: %s", id)
+	}
+	return s.MemoryStorage.UpdateAsset(id, mutate)
+}
+
+func (s *conflictStorage) SaveAsset(asset interface{}) error {
+	s.mu.Lock()
+	s.saves++
+	s.mu.Unlock()
+	return s.MemoryStorage.SaveAsset(asset)
+}
+
+func TestAtomicUpdateFailureRetried(t *testing.T) {
+	const id = "mac_00:11:22:33:44:55"
+	now := time.Now()
+
+	store := &conflictStorage{MemoryStorage: storage.NewMemoryStorage(), conflicts: 1}
+	// 其他采集器已写入的观测
+	other := &Asset{ID: id, OpenPorts: []PortInfo{{Port: 443, Protocol: "tcp", State: "open"}}, FirstSeen: now, LastSeen: now}
+	if err := store.MemoryStorage.SaveAsset(other); err != nil {
+		t.Fatalf("SaveAsset() error = %v", err)
+	}
+
+	cfg := newTestConfig()
+	cfg.Storage.NoStore = false
+	am := NewAssetManager(cfg, store)
+	addTestAsset(am, &Asset{ID: id, OpenPorts: []PortInfo{{Port: 22, Protocol: "tcp", State: "open"}}, FirstSeen: now, LastSeen: now})
+
+	// 冲突时不退回普通保存覆盖其他写入方的结果，而是进入重试队列
+	am.saveAsset(id)
+	if pending := am.GetStats().PendingWrites; pending != 1 {
+		t.Fatalf("pending writes after conflict = %d, want 1", pending)
+	}
+	if store.saves != 0 {
+		t.Errorf("SaveAsset calls = %d, want 0", store.saves)
+	}
+
+	if !am.retryPendingSaves() {
+		t.Fatal("retry after conflict failed")
+	}
+	item, err := store.GetAsset(id)
+	if err != nil {
+		t.Fatalf("GetAsset() error = %v", err)
+	}
+	stored, err := decodeStoredAsset(item)
+	if err != nil {
+		t.Fatalf("decodeStoredAsset() error = %v", err)
+	}
+	var ports []int
+	for _, p := range stored.OpenPorts {
+		ports = append(ports, p.Port)
+	}
+	if len(ports) != 2 || ports[0] != 22 || ports[1] != 443 {
+		t.Errorf("stored ports = %v, want [22 443] merged from both writers", ports)
+	}
+}
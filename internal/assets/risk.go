@@ -0,0 +1,151 @@
+package assets
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"assets_discovery/internal/config"
+)
+
+// maxRiskScore 风险评分上限
+const maxRiskScore = 10
+
+// RiskScorer 根据开放的高风险端口和已停止支持的操作系统计算资产风险评分
+type RiskScorer struct {
+	ports       map[int]float64
+	eolOS       []string
+	eolOSScore  float64
+	telnetScore float64
+}
+
+// NewRiskScorer 根据配置创建风险评分器，无效的端口号被忽略
+func NewRiskScorer(cfg *config.RiskConfig) *RiskScorer {
+	s := &RiskScorer{
+		ports:       make(map[int]float64, len(cfg.PortScores)),
+		eolOSScore:  cfg.EOLOSScore,
+		telnetScore: cfg.TelnetScore,
+	}
+
+	for key, score := range cfg.PortScores {
+		port, err := strconv.Atoi(strings.TrimSpace(key))
+		if err != nil || port <= 0 || port > 65535 {
+			continue
+		}
+		s.ports[port] = score
+	}
+	for _, keyword := range cfg.EOLOS {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+			s.eolOS = append(s.eolOS, keyword)
+		}
+	}
+
+	return s
+}
+
+// score 计算资产的风险评分及评分依据，调用方需持有资产的锁
+func (s *RiskScorer) score(a *Asset) (float64, []string) {
+	total := 0.0
+	factors := []string{}
+
+	for _, p := range a.OpenPorts {
+		score, ok := s.ports[p.Port]
+		if !ok || p.State == "closed" {
+			continue
+		}
+		total += score
+		if p.Service != "" {
+			factors = append(factors, fmt.Sprintf("开放高风险端口 %d/%s (%s)", p.Port, p.Protocol, p.Service))
+		} else {
+			factors = append(factors, fmt.Sprintf("开放高风险端口 %d/%s", p.Port, p.Protocol))
+		}
+	}
+
+	// 非标准端口上的Telnet不在端口分值表中，按识别出的协议计分
+	if port, ok := telnetPort(a.Protocols); ok && s.telnetScore > 0 {
+		if _, scored := s.ports[port]; !scored {
+			total += s.telnetScore
+			factors = append(factors, fmt.Sprintf("开放明文Telnet服务 %d/tcp", port))
+		}
+	}
+
+	if s.eolOSScore > 0 && a.OSInfo.Family != "" {
+		osName := strings.ToLower(a.OSInfo.Family + " " + a.OSInfo.Version)
+		for _, keyword := range s.eolOS {
+			if strings.Contains(osName, keyword) {
+				total += s.eolOSScore
+				factors = append(factors, "操作系统已停止支持: "+strings.TrimSpace(a.OSInfo.Family+" "+a.OSInfo.Version))
+				break
+			}
+		}
+	}
+
+	return math.Min(total, maxRiskScore), factors
+}
+
+// telnetPort 返回识别出Telnet协商的端口，从存储加载的资产中端口为浮点数
+func telnetPort(protocols map[string]interface{}) (int, bool) {
+	telnetInfo, ok := protocols["telnet"].(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	switch port := telnetInfo["port"].(type) {
+	case int:
+		return port, port > 0
+	case float64:
+		return int(port), port > 0
+	}
+	return 0, false
+}
+
+// updateRisk 重新计算资产的风险评分，返回更新前后的评分
+func (a *Asset) updateRisk(s *RiskScorer) (float64, float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	old := a.RiskScore
+	a.RiskScore, a.RiskFactors = s.score(a)
+	return old, a.RiskScore
+}
+
+// riskRule 告警规则中的风险阈值条件，例如 "risk > 7"
+type riskRule struct {
+	op        string
+	threshold float64
+}
+
+// parseRiskRule 解析风险阈值规则，支持 >、>= 两种比较
+func parseRiskRule(rule string) (riskRule, bool) {
+	rule = strings.ReplaceAll(strings.ToLower(rule), " ", "")
+	if !strings.HasPrefix(rule, "risk") {
+		return riskRule{}, false
+	}
+	rule = strings.TrimPrefix(rule, "risk")
+
+	op := ">"
+	if strings.HasPrefix(rule, ">=") {
+		op = ">="
+	} else if !strings.HasPrefix(rule, ">") {
+		return riskRule{}, false
+	}
+
+	threshold, err := strconv.ParseFloat(strings.TrimPrefix(rule, op), 64)
+	if err != nil {
+		return riskRule{}, false
+	}
+	return riskRule{op: op, threshold: threshold}, true
+}
+
+// matches 检查评分是否满足规则
+func (r riskRule) matches(score float64) bool {
+	if r.op == ">=" {
+		return score >= r.threshold
+	}
+	return score > r.threshold
+}
+
+// String 返回规则的规范形式
+func (r riskRule) String() string {
+	return fmt.Sprintf("risk %s %g", r.op, r.threshold)
+}
@@ -0,0 +1,194 @@
+package assets
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"assets_discovery/internal/alert"
+	"assets_discovery/internal/config"
+)
+
+// testRiskConfig 与默认配置相近的风险评分表，包含被忽略的无效端口
+func testRiskConfig() *config.RiskConfig {
+	return &config.RiskConfig{
+		PortScores: map[string]float64{
+			"23": 4, "445": 3, "3306": 2, "3389": 3, "6379": 4, "27017": 4,
+			"bogus": 9, "70000": 9,
+		},
+		EOLOS:       []string{"Windows XP", "windows 7", " "},
+		EOLOSScore:  3,
+		TelnetScore: 4,
+	}
+}
+
+// openPort 构造开放的TCP端口
+func openPort(port int, service string) PortInfo {
+	return PortInfo{Port: port, Protocol: "tcp", State: "open", Service: service}
+}
+
+func TestRiskScoreProfiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		asset       *Asset
+		wantScore   float64
+		wantFactors []string
+	}{
+		{
+			name:        "web server",
+			asset:       &Asset{OpenPorts: []PortInfo{openPort(80, "http"), openPort(443, "https")}, OSInfo: OSInfo{Family: "Linux"}},
+			wantScore:   0,
+			wantFactors: []string{},
+		},
+		{
+			name:      "legacy windows file server",
+			asset:     &Asset{OpenPorts: []PortInfo{openPort(445, "smb"), openPort(3389, "")}, OSInfo: OSInfo{Family: "Windows", Version: "XP"}},
+			wantScore: 9,
+			wantFactors: []string{
+				"开放高风险端口 445/tcp (smb)",
+				"开放高风险端口 3389/tcp",
+				"操作系统已停止支持: Windows XP",
+			},
+		},
+		{
+			name:      "unauthenticated database",
+			asset:     &Asset{OpenPorts: []PortInfo{openPort(6379, "redis"), openPort(3306, "mysql")}},
+			wantScore: 6,
+			wantFactors: []string{
+				"开放高风险端口 6379/tcp (redis)",
+				"开放高风险端口 3306/tcp (mysql)",
+			},
+		},
+		{
+			name: "score capped",
+			asset: &Asset{OpenPorts: []PortInfo{
+				openPort(23, "telnet"), openPort(6379, "redis"), openPort(27017, "mongodb"), openPort(445, "smb"),
+			}},
+			wantScore: maxRiskScore,
+			wantFactors: []string{
+				"开放高风险端口 23/tcp (telnet)",
+				"开放高风险端口 6379/tcp (redis)",
+				"开放高风险端口 27017/tcp (mongodb)",
+				"开放高风险端口 445/tcp (smb)",
+			},
+		},
+		{
+			name:        "closed risky port",
+			asset:       &Asset{OpenPorts: []PortInfo{{Port: 23, Protocol: "tcp", State: "closed"}}},
+			wantScore:   0,
+			wantFactors: []string{},
+		},
+		{
+			name: "telnet on nonstandard port",
+			asset: &Asset{
+				OpenPorts: []PortInfo{openPort(2323, "")},
+				Protocols: map[string]interface{}{"telnet": map[string]interface{}{"port": 2323}},
+			},
+			wantScore:   4,
+			wantFactors: []string{"开放明文Telnet服务 2323/tcp"},
+		},
+		{
+			name: "telnet on scored port counted once",
+			asset: &Asset{
+				OpenPorts: []PortInfo{openPort(23, "")},
+				Protocols: map[string]interface{}{"telnet": map[string]interface{}{"port": float64(23)}},
+			},
+			wantScore:   4,
+			wantFactors: []string{"开放高风险端口 23/tcp"},
+		},
+		{
+			name:        "eol os from dhcp version",
+			asset:       &Asset{OSInfo: OSInfo{Family: "Windows", Version: "Windows 7 Professional"}},
+			wantScore:   3,
+			wantFactors: []string{"操作系统已停止支持: Windows Windows 7 Professional"},
+		},
+	}
+
+	scorer := NewRiskScorer(testRiskConfig())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, factors := scorer.score(tt.asset)
+			if score != tt.wantScore {
+				t.Errorf("score() = %v, want %v", score, tt.wantScore)
+			}
+			if !reflect.DeepEqual(factors, tt.wantFactors) {
+				t.Errorf("factors = %q, want %q", factors, tt.wantFactors)
+			}
+		})
+	}
+}
+
+func TestParseRiskRule(t *testing.T) {
+	tests := []struct {
+		rule   string
+		want   string
+		wantOK bool
+	}{
+		{"risk > 7", "risk > 7", true},
+		{"RISK>=5.5", "risk >= 5.5", true},
+		{" risk >  0 ", "risk > 0", true},
+		{"risk < 3", "", false},
+		{"risk = 3", "", false},
+		{"risk > high", "", false},
+		{"new_asset", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rule, ok := parseRiskRule(tt.rule)
+			if ok != tt.wantOK {
+				t.Fatalf("parseRiskRule(%q) ok = %v, want %v", tt.rule, ok, tt.wantOK)
+			}
+			if ok && rule.String() != tt.want {
+				t.Errorf("parseRiskRule(%q) = %s, want %s", tt.rule, rule, tt.want)
+			}
+		})
+	}
+
+	rule, _ := parseRiskRule("risk > 7")
+	inclusive, _ := parseRiskRule("risk >= 7")
+	if rule.matches(7) || !rule.matches(7.5) || !inclusive.matches(7) {
+		t.Errorf("matches(7) = %v, %v, want false for >, true for >=", rule.matches(7), inclusive.matches(7))
+	}
+}
+
+func TestRiskThresholdAlert(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Risk = *testRiskConfig()
+	cfg.Alerting.AlertRules = []string{"risk > 7"}
+	alerts := newAlertRecorder(t, cfg)
+	am := newTestManager(cfg)
+
+	update := func(ports ...int) {
+		am.UpdateAsset(&AssetInfo{IPAddress: "10.0.0.9", MACAddress: testMAC, OpenPorts: ports, Timestamp: time.Now()})
+	}
+
+	// 6分未超过阈值，不告警
+	update(445)
+	update(3389)
+	time.Sleep(50 * time.Millisecond)
+	if events := alerts.ofType(alert.EventRuleMatch); len(events) != 0 {
+		t.Fatalf("rule alerts below threshold = %v, want none", events)
+	}
+
+	// 首次超过阈值时告警，之后再次更新不重复告警
+	update(6379)
+	waitFor(t, "risk alert", func() bool {
+		return len(alerts.ofType(alert.EventRuleMatch)) > 0
+	})
+	update(27017)
+
+	asset, _ := am.GetAsset("mac_" + testMAC)
+	if asset.RiskScore != maxRiskScore || len(asset.RiskFactors) != 4 {
+		t.Errorf("RiskScore = %v, factors = %v, want %v with 4 factors", asset.RiskScore, asset.RiskFactors, maxRiskScore)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	events := alerts.ofType(alert.EventRuleMatch)
+	if len(events) != 1 {
+		t.Fatalf("rule alerts = %d, want 1", len(events))
+	}
+	if want := "资产风险评分 10.0 满足规则 risk > 7"; events[0].Title != want || events[0].AssetID != "mac_"+testMAC {
+		t.Errorf("alert = %q for %s, want %q for mac_%s", events[0].Title, events[0].AssetID, want, testMAC)
+	}
+}
@@ -0,0 +1,75 @@
+package assets
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"assets_discovery/internal/enrich"
+)
+
+// networkScope 监控的网段，只为网段内的IP创建和更新资产
+// 没有配置网段时所有IP都在范围内
+type networkScope struct {
+	nets []*net.IPNet
+}
+
+// newNetworkScope 解析监控网段，单个IP按/32或/128处理，无效条目被忽略并在错误中列出
+func newNetworkScope(entries []string) (*networkScope, error) {
+	s := &networkScope{}
+	var invalid []string
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			s.nets = append(s.nets, network)
+		} else if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			s.nets = append(s.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		} else {
+			invalid = append(invalid, entry)
+		}
+	}
+
+	if len(invalid) > 0 {
+		return s, fmt.Errorf("无效的监控网段: %s", strings.Join(invalid, ", "))
+	}
+	return s, nil
+}
+
+// ipScope 按IP划分内外网：公网地址为external，私有(RFC1918)、ULA、链路本地、环回等地址为internal
+// 不依赖GeoIP数据库，IP为空或无效时返回空字符串
+func ipScope(ipAddress string) string {
+	if net.ParseIP(ipAddress) == nil {
+		return ""
+	}
+	if enrich.IsPublicIP(ipAddress) {
+		return "external"
+	}
+	return "internal"
+}
+
+// contains 检查IP是否在监控范围内，没有IP的资产（如只有MAC的二层设备）总在范围内
+func (s *networkScope) contains(ipAddress string) bool {
+	if len(s.nets) == 0 || ipAddress == "" {
+		return true
+	}
+
+	ip := net.ParseIP(ipAddress)
+	if ip == nil {
+		return false
+	}
+	for _, network := range s.nets {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package assets
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNetworkScope(t *testing.T) {
+	scope, err := newNetworkScope([]string{"192.168.0.0/16", "10.1.2.3", "2001:db8:100::/48", "fd00::10", " "})
+	if err != nil {
+		t.Fatalf("newNetworkScope() error = %v", err)
+	}
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"192.168.1.10", true},
+		{"192.168.255.255", true},
+		{"192.169.0.1", false},
+		{"10.1.2.3", true},
+		{"10.1.2.4", false},
+		{"8.8.8.8", false},
+		{"2001:db8:100::1", true},
+		{"2001:db8:100:ffff::1", true},
+		{"2001:db8:101::1", false},
+		{"fd00::10", true},
+		{"fd00::11", false},
+		{"::ffff:192.168.1.10", true}, // IPv4映射的IPv6地址按IPv4匹配
+		{"2606:4700::1111", false},
+		{"", true}, // 只有MAC的资产总在范围内
+		{"not-an-ip", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := scope.contains(tt.ip); got != tt.want {
+				t.Errorf("contains(%q) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetworkScopeUnconfigured(t *testing.T) {
+	scope, err := newNetworkScope(nil)
+	if err != nil {
+		t.Fatalf("newNetworkScope(nil) error = %v", err)
+	}
+	for _, ip := range []string{"8.8.8.8", "2606:4700::1111", "192.168.1.1"} {
+		if !scope.contains(ip) {
+			t.Errorf("contains(%q) = false, want true without monitored networks", ip)
+		}
+	}
+
+	// 无效条目被忽略并报告，有效条目仍然生效
+	scope, err = newNetworkScope([]string{"10.0.0.0/8", "10.0.0.0/40", "bogus"})
+	if err == nil {
+		t.Error("newNetworkScope() error = nil, want invalid entries")
+	}
+	if !scope.contains("10.9.9.9") || scope.contains("11.0.0.1") {
+		t.Errorf("scope with invalid entries = %v, want only 10.0.0.0/8", scope.nets)
+	}
+}
+
+func TestOutOfScopeAssets(t *testing.T) {
+	tests := []struct {
+		mode       string
+		wantIDs    []string
+		wantTagged bool
+	}{
+		{"ignore", []string{"mac_00:11:22:33:44:01", "mac_00:11:22:33:44:03"}, false},
+		{"tag", []string{"mac_00:11:22:33:44:01", "mac_00:11:22:33:44:02", "mac_00:11:22:33:44:03"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.Parser.MonitoredNetworks = []string{"192.168.1.0/24", "2001:db8::/32"}
+			cfg.Parser.OutOfScope = tt.mode
+			am := newTestManager(cfg)
+
+			now := time.Now()
+			am.UpdateAsset(&AssetInfo{IPAddress: "192.168.1.10", MACAddress: "00:11:22:33:44:01", Timestamp: now})
+			am.UpdateAsset(&AssetInfo{IPAddress: "2606:4700::1111", MACAddress: "00:11:22:33:44:02", Timestamp: now})
+			am.UpdateAsset(&AssetInfo{IPAddress: "2001:db8::5", MACAddress: "00:11:22:33:44:03", Timestamp: now})
+
+			if got := memoryIDs(am); !reflect.DeepEqual(got, tt.wantIDs) {
+				t.Errorf("assets = %v, want %v", got, tt.wantIDs)
+			}
+
+			if asset, ok := am.GetAsset("mac_00:11:22:33:44:01"); !ok || asset.OutOfScope {
+				t.Errorf("in-scope asset OutOfScope = %v, want false", ok && asset.OutOfScope)
+			}
+			if tt.wantTagged {
+				asset, _ := am.GetAsset("mac_00:11:22:33:44:02")
+				if !asset.OutOfScope {
+					t.Error("out-of-scope asset not tagged")
+				}
+
+				// 资产换到监控网段内的IP后不再标记为范围外
+				am.UpdateAsset(&AssetInfo{IPAddress: "2001:db8::9", MACAddress: "00:11:22:33:44:02", Timestamp: now})
+				if asset.OutOfScope {
+					t.Error("asset still tagged after moving into scope")
+				}
+			}
+		})
+	}
+}
+
+func TestIPScope(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"10.1.2.3", "internal"},
+		{"172.16.0.1", "internal"},
+		{"172.31.255.254", "internal"},
+		{"192.168.1.1", "internal"},
+		{"169.254.10.20", "internal"},
+		{"127.0.0.1", "internal"},
+		{"224.0.0.251", "internal"},
+		{"255.255.255.255", "internal"},
+		{"0.0.0.0", "internal"},
+		{"172.32.0.1", "external"},
+		{"8.8.8.8", "external"},
+		{"203.0.113.5", "external"},
+		{"fd12:3456::1", "internal"},
+		{"fc00::1", "internal"},
+		{"fe80::1", "internal"},
+		{"ff02::fb", "internal"},
+		{"::1", "internal"},
+		{"::ffff:192.168.1.1", "internal"},
+		{"2001:4860:4860::8888", "external"},
+		{"::ffff:8.8.8.8", "external"},
+		{"", ""},
+		{"bogus", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := ipScope(tt.ip); got != tt.want {
+				t.Errorf("ipScope(%q) = %q, want %q", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssetScope(t *testing.T) {
+	am := newTestManager(newTestConfig())
+	now := time.Now()
+	am.UpdateAsset(&AssetInfo{IPAddress: "192.168.1.10", MACAddress: "00:11:22:33:44:01", Timestamp: now})
+	am.UpdateAsset(&AssetInfo{IPAddress: "2001:4860:4860::8888", MACAddress: "00:11:22:33:44:02", Timestamp: now})
+	am.UpdateAsset(&AssetInfo{MACAddress: "00:11:22:33:44:03", Timestamp: now})
+
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"mac_00:11:22:33:44:01", "internal"},
+		{"mac_00:11:22:33:44:02", "external"},
+		{"mac_00:11:22:33:44:03", ""},
+	}
+	for _, tt := range tests {
+		asset, _ := am.GetAsset(tt.id)
+		if asset.Scope != tt.want {
+			t.Errorf("%s Scope = %q, want %q", tt.id, asset.Scope, tt.want)
+		}
+		if got, _ := asset.GetSummary()["scope"].(string); got != tt.want {
+			t.Errorf("%s summary scope = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+
+	all := make([]*Asset, 0, 3)
+	for _, asset := range am.GetAllAssets() {
+		all = append(all, asset)
+	}
+	if got := assetIDs(FilterScope(all, "external")); !reflect.DeepEqual(got, []string{"mac_00:11:22:33:44:02"}) {
+		t.Errorf("FilterScope(external) = %v, want [mac_00:11:22:33:44:02]", got)
+	}
+
+	// IP变更后重新计算，只有MAC的资产获得IP后也会打上标签
+	am.UpdateAsset(&AssetInfo{IPAddress: "8.8.4.4", MACAddress: "00:11:22:33:44:01", Timestamp: now.Add(time.Second)})
+	am.UpdateAsset(&AssetInfo{IPAddress: "fd00::3", MACAddress: "00:11:22:33:44:03", Timestamp: now.Add(time.Second)})
+	if got := assetIDs(FilterScope(all, "external")); !reflect.DeepEqual(got, []string{"mac_00:11:22:33:44:01", "mac_00:11:22:33:44:02"}) {
+		t.Errorf("FilterScope(external) after ip change = %v", got)
+	}
+	if got := assetIDs(FilterScope(all, "internal")); !reflect.DeepEqual(got, []string{"mac_00:11:22:33:44:03"}) {
+		t.Errorf("FilterScope(internal) after ip change = %v", got)
+	}
+}
@@ -0,0 +1,180 @@
+package assets
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// trafficBuckets 每个资产按分钟保存的流量桶数量，决定了top-talkers时间窗口的上限
+	trafficBuckets = 60
+
+	// MaxTrafficWindow top-talkers支持的最长时间窗口，更长的范围使用整个捕获期间的累计值
+	MaxTrafficWindow = trafficBuckets * time.Minute
+)
+
+// 流量排名依据
+const (
+	RankByBytes   = "bytes"
+	RankByPackets = "packets"
+)
+
+// trafficWindow 资产最近一小时按分钟累计的流量，桶按分钟序号循环使用，不持久化
+type trafficWindow struct {
+	buckets [trafficBuckets]trafficBucket
+}
+
+type trafficBucket struct {
+	minute  int64 // Unix分钟序号，与当前分钟不同时桶中是过期数据
+	packets uint64
+	bytes   uint64
+}
+
+// add 将一个数据包计入t所在分钟的桶
+func (w *trafficWindow) add(t time.Time, length int) {
+	minute := t.Unix() / 60
+	b := &w.buckets[minute%trafficBuckets]
+	if b.minute != minute {
+		*b = trafficBucket{minute: minute}
+	}
+	b.packets++
+	b.bytes += uint64(length)
+}
+
+// sum 统计now之前window时间内（按整分钟）的数据包数和字节数
+func (w *trafficWindow) sum(now time.Time, window time.Duration) (packets, bytes uint64) {
+	current := now.Unix() / 60
+	oldest := current - int64((window+time.Minute-1)/time.Minute) + 1
+	for _, b := range w.buckets {
+		if b.minute >= oldest && b.minute <= current {
+			packets += b.packets
+			bytes += b.bytes
+		}
+	}
+	return packets, bytes
+}
+
+// countTraffic 计入资产作为源地址发出的一个数据包，调用方需持有写锁
+// 启用抽样时只计入抽样保留的数据包
+func (a *Asset) countTraffic(assetInfo *AssetInfo) {
+	a.Packets++
+	a.Bytes += uint64(assetInfo.Length)
+
+	if a.traffic == nil {
+		a.traffic = &trafficWindow{}
+	}
+	a.traffic.add(seenTime(assetInfo), assetInfo.Length)
+}
+
+// adoptTraffic 合并同一设备另一条资产记录的流量计数，调用方需持有两个资产的锁
+func (a *Asset) adoptTraffic(other *Asset) {
+	a.Packets += other.Packets
+	a.Bytes += other.Bytes
+
+	if other.traffic == nil {
+		return
+	}
+	if a.traffic == nil {
+		a.traffic = &trafficWindow{}
+	}
+	for _, b := range other.traffic.buckets {
+		mine := &a.traffic.buckets[b.minute%trafficBuckets]
+		switch {
+		case b.minute == mine.minute:
+			mine.packets += b.packets
+			mine.bytes += b.bytes
+		case b.minute > mine.minute:
+			*mine = b
+		}
+	}
+}
+
+// TopTalker 流量排名中的一个资产
+type TopTalker struct {
+	AssetID    string `json:"asset_id"`
+	IPAddress  string `json:"ip_address"`
+	MACAddress string `json:"mac_address"`
+	Hostname   string `json:"hostname"`
+	DeviceType string `json:"device_type"`
+	Packets    uint64 `json:"packets"`
+	Bytes      uint64 `json:"bytes"`
+}
+
+// ValidRankBy 检查流量排名依据是否受支持
+func ValidRankBy(by string) error {
+	switch by {
+	case RankByBytes, RankByPackets:
+		return nil
+	}
+	return fmt.Errorf("不支持的排名依据: %s，可选值为bytes、packets", by)
+}
+
+// TopTalkers 按资产作为源地址发出的流量排名，返回前n个，n不大于0时返回全部
+// window为0时使用整个捕获期间的累计计数，否则只统计最近window时间内的流量，window不能超过MaxTrafficWindow
+func (am *AssetManager) TopTalkers(n int, window time.Duration, by string) ([]TopTalker, error) {
+	if err := ValidRankBy(by); err != nil {
+		return nil, err
+	}
+	if window < 0 || window > MaxTrafficWindow {
+		return nil, fmt.Errorf("时间窗口必须在0到%v之间", MaxTrafficWindow)
+	}
+
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+
+	now := am.currentTime()
+	talkers := make([]TopTalker, 0, len(am.assets))
+	for _, asset := range am.assets {
+		asset.mu.RLock()
+		talker := TopTalker{
+			AssetID:    asset.ID,
+			IPAddress:  asset.IPAddress,
+			MACAddress: asset.MACAddress,
+			Hostname:   asset.Hostname,
+			DeviceType: asset.DeviceType,
+			Packets:    asset.Packets,
+			Bytes:      asset.Bytes,
+		}
+		if window > 0 {
+			talker.Packets, talker.Bytes = 0, 0
+			if asset.traffic != nil {
+				talker.Packets, talker.Bytes = asset.traffic.sum(now, window)
+			}
+		}
+		asset.mu.RUnlock()
+
+		if talker.Packets > 0 {
+			talkers = append(talkers, talker)
+		}
+	}
+
+	return RankTalkers(talkers, n, by), nil
+}
+
+// RankTalkers 按by降序排列，取值相同时按另一项计数降序、再按资产ID排列，返回前n个，n不大于0时返回全部
+func RankTalkers(talkers []TopTalker, n int, by string) []TopTalker {
+	key := func(t TopTalker) (uint64, uint64) {
+		if by == RankByPackets {
+			return t.Packets, t.Bytes
+		}
+		return t.Bytes, t.Packets
+	}
+
+	sort.Slice(talkers, func(i, j int) bool {
+		pi, si := key(talkers[i])
+		pj, sj := key(talkers[j])
+		if pi != pj {
+			return pi > pj
+		}
+		if si != sj {
+			return si > sj
+		}
+		return talkers[i].AssetID < talkers[j].AssetID
+	})
+
+	if n > 0 && len(talkers) > n {
+		talkers = talkers[:n]
+	}
+	return talkers
+}
@@ -0,0 +1,78 @@
+package assets
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// sendTraffic 以资产ip作为源地址在ts时刻发出count个长度为length的数据包，MAC地址的最后一段与IP相同
+func sendTraffic(am *AssetManager, ip string, count, length int, ts time.Time) {
+	mac := fmt.Sprintf("00:1a:2b:3c:4d:%02x", net.ParseIP(ip).To4()[3])
+	for i := 0; i < count; i++ {
+		am.UpdateAsset(&AssetInfo{IPAddress: ip, MACAddress: mac, Length: length, Timestamp: ts})
+	}
+}
+
+func TestTopTalkers(t *testing.T) {
+	am := newTestManager(newTestConfig())
+	now := time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC)
+
+	sendTraffic(am, "10.0.0.1", 2, 1500, now.Add(-50*time.Minute)) // 3000字节，在15分钟窗口之外
+	sendTraffic(am, "10.0.0.2", 20, 64, now.Add(-5*time.Minute))   // 1280字节，数据包最多
+	sendTraffic(am, "10.0.0.3", 1, 2000, now.Add(-2*time.Minute))  // 2000字节
+	sendTraffic(am, "10.0.0.5", 2, 1000, now.Add(-time.Minute))    // 2000字节，与10.0.0.3字节数相同、数据包更多
+	setClock(am, now)
+
+	tests := []struct {
+		name   string
+		n      int
+		window time.Duration
+		by     string
+		want   []string
+	}{
+		{"all time by bytes", 0, 0, RankByBytes, []string{"10.0.0.1", "10.0.0.5", "10.0.0.3", "10.0.0.2"}},
+		{"all time by packets", 0, 0, RankByPackets, []string{"10.0.0.2", "10.0.0.1", "10.0.0.5", "10.0.0.3"}},
+		{"top two", 2, 0, RankByBytes, []string{"10.0.0.1", "10.0.0.5"}},
+		{"window by bytes", 0, 15 * time.Minute, RankByBytes, []string{"10.0.0.5", "10.0.0.3", "10.0.0.2"}},
+		{"window by packets", 0, 15 * time.Minute, RankByPackets, []string{"10.0.0.2", "10.0.0.5", "10.0.0.3"}},
+		{"short window", 0, 3 * time.Minute, RankByBytes, []string{"10.0.0.5", "10.0.0.3"}},
+		{"max window", 0, MaxTrafficWindow, RankByBytes, []string{"10.0.0.1", "10.0.0.5", "10.0.0.3", "10.0.0.2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			talkers, err := am.TopTalkers(tt.n, tt.window, tt.by)
+			if err != nil {
+				t.Fatalf("TopTalkers() error = %v", err)
+			}
+			var got []string
+			for _, talker := range talkers {
+				got = append(got, talker.IPAddress)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TopTalkers(%d, %v, %s) = %v, want %v", tt.n, tt.window, tt.by, got, tt.want)
+			}
+		})
+	}
+
+	talkers, _ := am.TopTalkers(1, 0, RankByPackets)
+	if talkers[0].Packets != 20 || talkers[0].Bytes != 1280 {
+		t.Errorf("top talker counters = %d packets, %d bytes, want 20, 1280", talkers[0].Packets, talkers[0].Bytes)
+	}
+
+	for _, bad := range []struct {
+		window time.Duration
+		by     string
+	}{
+		{MaxTrafficWindow + time.Minute, RankByBytes},
+		{-time.Minute, RankByBytes},
+		{0, "flows"},
+	} {
+		if _, err := am.TopTalkers(10, bad.window, bad.by); err == nil {
+			t.Errorf("TopTalkers(%v, %s) error = nil, want error", bad.window, bad.by)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package assets
+
+import (
+	"strings"
+	"sync"
+)
+
+// vendorSuffixes 厂商名称末尾的公司类型后缀，规范化时去除，可重复去除如"Co., Ltd."
+var vendorSuffixes = []string{
+	"corporation", "corporate", "corp", "incorporated", "inc", "limited", "ltd",
+	"co", "company", "llc", "gmbh", "ag", "s.a", "sa", "plc", "bv", "oy", "ab",
+}
+
+var (
+	vendorAliasMu sync.RWMutex
+
+	// vendorAliases 去除后缀后的小写名称到规范名称的映射
+	vendorAliases = map[string]string{
+		"cisco systems":                         "Cisco",
+		"cisco":                                 "Cisco",
+		"intel":                                 "Intel",
+		"dell":                                  "Dell",
+		"dell technologies":                     "Dell",
+		"hewlett packard":                       "HP",
+		"hewlett-packard":                       "HP",
+		"hp":                                    "HP",
+		"hewlett packard enterprise":            "HPE",
+		"apple":                                 "Apple",
+		"huawei technologies":                   "Huawei",
+		"huawei":                                "Huawei",
+		"hon hai precision ind":                 "Foxconn",
+		"foxconn":                               "Foxconn",
+		"vmware":                                "VMware",
+		"microsoft":                             "Microsoft",
+		"juniper networks":                      "Juniper",
+		"tp-link technologies":                  "TP-Link",
+		"samsung electronics":                   "Samsung",
+		"super micro computer":                  "Supermicro",
+		"netapp":                                "NetApp",
+		"raspberry pi trading":                  "Raspberry Pi",
+		"raspberry pi (trading)":                "Raspberry Pi",
+		"hangzhou hikvision digital technology": "Hikvision",
+	}
+)
+
+// RegisterVendorAlias 注册厂商名称的规范写法，alias不区分大小写，匹配去除公司后缀后的名称
+func RegisterVendorAlias(alias, canonical string) {
+	vendorAliasMu.Lock()
+	defer vendorAliasMu.Unlock()
+
+	vendorAliases[strings.ToLower(trimVendorSuffixes(alias))] = canonical
+}
+
+// NormalizeVendor 规范化厂商名称：去除首尾空白和公司类型后缀，再按别名表统一写法
+// 例如"Intel Corp"、"Intel Corporate"都规范为"Intel"
+func NormalizeVendor(vendor string) string {
+	name := trimVendorSuffixes(vendor)
+	if name == "" {
+		return ""
+	}
+
+	vendorAliasMu.RLock()
+	canonical, ok := vendorAliases[strings.ToLower(name)]
+	vendorAliasMu.RUnlock()
+
+	if ok {
+		return canonical
+	}
+	return name
+}
+
+// trimVendorSuffixes 合并空白并去除末尾的公司类型后缀及标点
+func trimVendorSuffixes(vendor string) string {
+	words := strings.Fields(strings.ReplaceAll(vendor, ",", ", "))
+
+	for len(words) > 1 {
+		last := strings.ToLower(strings.TrimRight(words[len(words)-1], ".,"))
+		if !isVendorSuffix(last) {
+			break
+		}
+		words = words[:len(words)-1]
+	}
+
+	return strings.TrimRight(strings.Join(words, " "), " .,")
+}
+
+func isVendorSuffix(word string) bool {
+	for _, suffix := range vendorSuffixes {
+		if word == suffix {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package assets
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNormalizeVendor(t *testing.T) {
+	tests := []struct {
+		vendor string
+		want   string
+	}{
+		{"Intel Corp", "Intel"},
+		{"Intel Corporate", "Intel"},
+		{"  intel   corporation ", "Intel"},
+		{"Cisco Systems, Inc", "Cisco"},
+		{"Cisco Systems, Inc.", "Cisco"},
+		{"HUAWEI TECHNOLOGIES CO.,LTD", "Huawei"},
+		{"Hon Hai Precision Ind. Co.,Ltd.", "Foxconn"},
+		{"Raspberry Pi (Trading) Ltd", "Raspberry Pi"},
+		{"Hewlett Packard Enterprise", "HPE"},
+		{"Acme Widgets GmbH", "Acme Widgets"},
+		{"Acme  Widgets", "Acme Widgets"},
+		{"Inc", "Inc"}, // 只剩后缀时保留
+		{"   ", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.vendor, func(t *testing.T) {
+			if got := NormalizeVendor(tt.vendor); got != tt.want {
+				t.Errorf("NormalizeVendor(%q) = %q, want %q", tt.vendor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterVendorAlias(t *testing.T) {
+	t.Cleanup(func() {
+		vendorAliasMu.Lock()
+		delete(vendorAliases, "ubiquiti networks")
+		delete(vendorAliases, "ubiquiti")
+		vendorAliasMu.Unlock()
+	})
+
+	if got := NormalizeVendor("Ubiquiti Networks Inc."); got != "Ubiquiti Networks" {
+		t.Fatalf("NormalizeVendor() before alias = %q, want %q", got, "Ubiquiti Networks")
+	}
+
+	// 别名本身带后缀时同样去除后缀再注册
+	RegisterVendorAlias("Ubiquiti Networks Inc.", "Ubiquiti")
+	RegisterVendorAlias("UBIQUITI", "Ubiquiti")
+	for _, vendor := range []string{"Ubiquiti Networks Inc.", "ubiquiti networks", "Ubiquiti Inc"} {
+		if got := NormalizeVendor(vendor); got != "Ubiquiti" {
+			t.Errorf("NormalizeVendor(%q) = %q, want Ubiquiti", vendor, got)
+		}
+	}
+}
+
+func TestGetAssetsByVendor(t *testing.T) {
+	am := newTestManager(newTestConfig())
+	now := time.Now()
+	am.UpdateAsset(&AssetInfo{IPAddress: "10.0.0.1", MACAddress: "00:00:0c:00:00:01", Vendor: "Cisco Systems, Inc", Timestamp: now})
+	am.UpdateAsset(&AssetInfo{IPAddress: "10.0.0.2", MACAddress: "00:00:0c:00:00:02", Vendor: "Cisco", Timestamp: now})
+	am.UpdateAsset(&AssetInfo{IPAddress: "10.0.0.3", MACAddress: "00:1b:21:00:00:03", Vendor: "Intel Corporate", Timestamp: now})
+	am.UpdateAsset(&AssetInfo{IPAddress: "10.0.0.4", MACAddress: "00:1b:21:00:00:04", Timestamp: now})
+
+	cisco := []string{"mac_00:00:0c:00:00:01", "mac_00:00:0c:00:00:02"}
+	tests := []struct {
+		vendor string
+		want   []string
+	}{
+		{"Cisco", cisco},
+		{"cisco systems", cisco},
+		{"CISCO SYSTEMS, INC.", cisco},
+		{"Intel Corp", []string{"mac_00:1b:21:00:00:03"}},
+		{"Dell", []string{}},
+		{"", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.vendor, func(t *testing.T) {
+			if got := assetIDs(am.GetAssetsByVendor(tt.vendor)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetAssetsByVendor(%q) = %v, want %v", tt.vendor, got, tt.want)
+			}
+		})
+	}
+}
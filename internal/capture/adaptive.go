@@ -0,0 +1,138 @@
+package capture
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/gopacket/pcap"
+
+	"assets_discovery/internal/config"
+)
+
+// filterHandle 自适应过滤需要的捕获句柄操作，*pcap.Handle实现了该接口
+type filterHandle interface {
+	Stats() (*pcap.Stats, error)
+	SetBPFFilter(expr string) error
+}
+
+// defaultAdaptiveInterval 未配置检查间隔时使用的默认值
+const defaultAdaptiveInterval = 10 * time.Second
+
+// adaptiveFilter 根据网卡丢包统计在完整过滤器和只包含核心协议的过滤器之间切换
+// 丢包率连续sustain个间隔高于上限时收紧，收紧后连续sustain个间隔低于下限时恢复，避免在阈值附近反复切换
+type adaptiveFilter struct {
+	cfg    config.AdaptiveFilterConfig
+	handle filterHandle
+	full   string // 完整的过滤器，为空表示捕获所有流量
+	shed   string // 只包含核心协议的过滤器
+
+	shedding bool
+	streak   int // 连续满足切换条件的间隔数
+	last     pcap.Stats
+}
+
+// newAdaptiveFilter 创建自适应过滤器，已启用的协议中没有核心协议时返回nil
+func newAdaptiveFilter(cfg config.AdaptiveFilterConfig, handle filterHandle, full string, protocols []string, vlan bool) *adaptiveFilter {
+	enabled := make(map[string]bool, len(protocols))
+	for _, protocol := range protocols {
+		enabled[protocol] = true
+	}
+	var core []string
+	for _, protocol := range cfg.CoreProtocols {
+		if enabled[protocol] {
+			core = append(core, protocol)
+		}
+	}
+
+	shed := buildBPFFilter(core, vlan, false)
+	if shed == "" {
+		log.Printf("自适应BPF过滤器未启用：已启用的协议中没有核心协议 %v", cfg.CoreProtocols)
+		return nil
+	}
+	if shed == full {
+		log.Printf("自适应BPF过滤器未启用：只启用了核心协议，无法进一步收紧")
+		return nil
+	}
+	if cfg.Sustain < 1 {
+		cfg.Sustain = 1
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultAdaptiveInterval
+	}
+
+	af := &adaptiveFilter{cfg: cfg, handle: handle, full: full, shed: shed}
+	if stats, err := handle.Stats(); err == nil {
+		af.last = *stats
+	}
+	return af
+}
+
+// run 定期检查丢包统计，ctx取消时退出
+func (af *adaptiveFilter) run(ctx context.Context) {
+	log.Printf("自适应BPF过滤器已启用：丢包率持续高于 %.1f%% 时只捕获 %s", af.cfg.HighDropRate*100, af.shed)
+
+	ticker := time.NewTicker(af.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			stats, err := af.handle.Stats()
+			if err != nil {
+				log.Printf("读取捕获统计失败: %v", err)
+				continue
+			}
+			af.observe(stats)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// observe 根据本次统计与上次统计的差值计算丢包率，达到切换条件时替换过滤器，返回是否发生了切换
+// 丢包率为丢弃数（内核缓冲区和网卡丢弃）占接收数与丢弃数之和的比例
+func (af *adaptiveFilter) observe(stats *pcap.Stats) bool {
+	received := stats.PacketsReceived - af.last.PacketsReceived
+	dropped := stats.PacketsDropped - af.last.PacketsDropped + stats.PacketsIfDropped - af.last.PacketsIfDropped
+	af.last = *stats
+	if received < 0 || dropped < 0 {
+		// 计数器回绕或被重置，跳过本次
+		return false
+	}
+
+	rate := 0.0
+	if received+dropped > 0 {
+		rate = float64(dropped) / float64(received+dropped)
+	}
+
+	if af.shedding && rate < af.cfg.LowDropRate || !af.shedding && rate > af.cfg.HighDropRate {
+		af.streak++
+	} else {
+		af.streak = 0
+	}
+	if af.streak < af.cfg.Sustain {
+		return false
+	}
+	af.streak = 0
+
+	if !af.shedding {
+		if err := af.handle.SetBPFFilter(af.shed); err != nil {
+			log.Printf("收紧BPF过滤器失败: %v", err)
+			return false
+		}
+		af.shedding = true
+		log.Printf("丢包率 %.1f%% 连续 %d 个周期高于 %.1f%%，收紧BPF过滤器: %s",
+			rate*100, af.cfg.Sustain, af.cfg.HighDropRate*100, af.shed)
+		return true
+	}
+
+	if err := af.handle.SetBPFFilter(af.full); err != nil {
+		log.Printf("恢复BPF过滤器失败: %v", err)
+		return false
+	}
+	af.shedding = false
+	log.Printf("丢包率 %.1f%% 连续 %d 个周期低于 %.1f%%，恢复BPF过滤器: %s",
+		rate*100, af.cfg.Sustain, af.cfg.LowDropRate*100, af.full)
+	return true
+}
@@ -0,0 +1,171 @@
+package capture
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/gopacket/pcap"
+
+	"assets_discovery/internal/config"
+)
+
+// fakeFilterHandle 记录过滤器设置、按测试给出的增量累计丢包统计
+type fakeFilterHandle struct {
+	stats   pcap.Stats
+	filters []string
+	setErr  error
+}
+
+func (h *fakeFilterHandle) Stats() (*pcap.Stats, error) {
+	stats := h.stats
+	return &stats, nil
+}
+
+func (h *fakeFilterHandle) SetBPFFilter(expr string) error {
+	if h.setErr != nil {
+		return h.setErr
+	}
+	h.filters = append(h.filters, expr)
+	return nil
+}
+
+// interval 一个检查周期内新增的接收数和丢包数
+type interval struct {
+	received, dropped, ifDropped int
+}
+
+func newTestAdaptiveConfig() config.AdaptiveFilterConfig {
+	return config.AdaptiveFilterConfig{
+		HighDropRate:  0.1,
+		LowDropRate:   0.02,
+		Sustain:       2,
+		CoreProtocols: []string{"arp", "dhcp"},
+	}
+}
+
+func TestAdaptiveFilterDropRates(t *testing.T) {
+	const full = "(arp or port 67 or port 68 or port 80)"
+
+	tests := []struct {
+		name      string
+		intervals []interval
+		setErr    error
+		wantShed  []bool // 每个周期之后是否处于收紧状态
+	}{
+		{
+			name:      "rising drops tighten after sustain",
+			intervals: []interval{{1000, 10, 0}, {850, 150, 0}, {800, 200, 0}, {700, 300, 0}},
+			wantShed:  []bool{false, false, true, true},
+		},
+		{
+			name:      "interface drops count",
+			intervals: []interval{{800, 0, 200}, {800, 100, 100}},
+			wantShed:  []bool{false, true},
+		},
+		{
+			name:      "single spike ignored",
+			intervals: []interval{{800, 200, 0}, {1000, 0, 0}, {800, 200, 0}, {1000, 0, 0}},
+			wantShed:  []bool{false, false, false, false},
+		},
+		{
+			name: "recovery needs rate below low mark",
+			intervals: []interval{
+				{800, 200, 0}, {800, 200, 0}, // 收紧
+				{950, 50, 0}, {950, 50, 0}, // 5%在两个阈值之间，保持收紧
+				{990, 10, 0}, {990, 10, 0}, // 1%低于下限，恢复
+			},
+			wantShed: []bool{false, true, true, true, true, false},
+		},
+		{
+			name:      "idle interval counts as no drops",
+			intervals: []interval{{800, 200, 0}, {800, 200, 0}, {0, 0, 0}, {0, 0, 0}},
+			wantShed:  []bool{false, true, true, false},
+		},
+		{
+			name:      "set filter failure keeps full filter",
+			intervals: []interval{{800, 200, 0}, {800, 200, 0}, {800, 200, 0}},
+			setErr:    errors.New("bpf error"),
+			wantShed:  []bool{false, false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handle := &fakeFilterHandle{setErr: tt.setErr}
+			af := newAdaptiveFilter(newTestAdaptiveConfig(), handle, full, []string{"arp", "dhcp", "http"}, false)
+			if af == nil {
+				t.Fatalf("newAdaptiveFilter() = nil")
+			}
+
+			for i, iv := range tt.intervals {
+				handle.stats.PacketsReceived += iv.received
+				handle.stats.PacketsDropped += iv.dropped
+				handle.stats.PacketsIfDropped += iv.ifDropped
+				af.observe(&handle.stats)
+
+				if af.shedding != tt.wantShed[i] {
+					t.Fatalf("interval %d: shedding = %v, want %v", i, af.shedding, tt.wantShed[i])
+				}
+			}
+
+			// 过滤器只在状态切换时设置，收紧用核心协议过滤器，恢复用完整过滤器
+			for i, filter := range handle.filters {
+				want := af.shed
+				if i%2 == 1 {
+					want = af.full
+				}
+				if filter != want {
+					t.Errorf("filter %d = %q, want %q", i, filter, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAdaptiveFilterCounterReset(t *testing.T) {
+	handle := &fakeFilterHandle{stats: pcap.Stats{PacketsReceived: 100000, PacketsDropped: 5000}}
+	af := newAdaptiveFilter(newTestAdaptiveConfig(), handle, "", []string{"arp", "dhcp", "http"}, false)
+
+	// 计数器被重置后的第一个周期跳过，不计入连续周期
+	handle.stats = pcap.Stats{PacketsReceived: 800, PacketsDropped: 200}
+	if af.observe(&handle.stats) || af.streak != 0 {
+		t.Fatalf("counter reset counted as drop interval (streak %d)", af.streak)
+	}
+
+	handle.stats.PacketsReceived += 800
+	handle.stats.PacketsDropped += 200
+	af.observe(&handle.stats)
+	handle.stats.PacketsReceived += 800
+	handle.stats.PacketsDropped += 200
+	if !af.observe(&handle.stats) || !af.shedding {
+		t.Errorf("filter not tightened after sustained drops following reset")
+	}
+}
+
+func TestNewAdaptiveFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		full      string
+		protocols []string
+		vlan      bool
+		wantNil   bool
+		wantShed  string
+	}{
+		{"core subset", "(arp or port 80)", []string{"arp", "http"}, false, false, "(arp)"},
+		{"vlan", "(arp or port 80) or (vlan and (arp or port 80))", []string{"arp", "http"}, true, false, "(arp) or (vlan and (arp))"},
+		{"no core protocol enabled", "(port 80)", []string{"http"}, false, true, ""},
+		{"only core protocols", "(arp)", []string{"arp"}, false, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			af := newAdaptiveFilter(newTestAdaptiveConfig(), &fakeFilterHandle{}, tt.full, tt.protocols, tt.vlan)
+			if (af == nil) != tt.wantNil {
+				t.Fatalf("newAdaptiveFilter() nil = %v, want %v", af == nil, tt.wantNil)
+			}
+			if af != nil && af.shed != tt.wantShed {
+				t.Errorf("shed filter = %q, want %q", af.shed, tt.wantShed)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+)
+
+// 实时捕获后端
+const (
+	enginePcap     = "pcap"
+	engineAFPacket = "afpacket"
+)
+
+// errAFPacketUnsupported 当前系统不支持AF_PACKET
+var errAFPacketUnsupported = errors.New("当前系统不支持AF_PACKET")
+
+// startAFPacketCapture 使用AF_PACKET fanout开始实时捕获，每个最少工作协程对应一个套接字
+// 各套接字的数据包汇入同一通道，交给与pcap相同的工作协程池解析
+func (ce *CaptureEngine) startAFPacketCapture(ctx context.Context) error {
+	ctx, cancel := ce.runContext(ctx)
+	defer cancel()
+
+	// AF_PACKET套接字收到的是以太网帧，过滤器按以太网链路类型编译
+	filter := ce.bpfFilter(func(expr string) ([]pcap.BPFInstruction, error) {
+		return pcap.CompileBPFFilter(layers.LinkTypeEthernet, ce.config.Capture.SnapLen, expr)
+	})
+	if filter != "" {
+		log.Printf("设置BPF过滤器: %s", filter)
+	}
+
+	if ce.config.Capture.AdaptiveFilter.Enabled {
+		log.Printf("自适应BPF过滤器只支持pcap捕获后端，afpacket下不生效")
+	}
+
+	sockets := ce.config.Capture.Workers
+	if sockets < 1 {
+		sockets = 1
+	}
+
+	packets, err := openAFPacket(ctx, ce.config.Capture, filter, sockets)
+	if err != nil {
+		return ce.openLiveError(err)
+	}
+	log.Printf("已使用AF_PACKET打开接口 %s，fanout组包含 %d 个套接字", ce.config.Capture.Interface, sockets)
+
+	return ce.runLive(ctx, packets)
+}
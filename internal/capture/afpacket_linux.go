@@ -0,0 +1,181 @@
+//go:build linux
+
+package capture
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/afpacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+	"golang.org/x/net/bpf"
+	"golang.org/x/sys/unix"
+
+	"assets_discovery/internal/config"
+)
+
+// afpacketSupported Linux上可以使用AF_PACKET捕获
+const afpacketSupported = true
+
+const (
+	// afpacketPollTimeout 读取数据包时等待的最长时间，超时后检查是否已停止捕获
+	afpacketPollTimeout = 100 * time.Millisecond
+	// afpacketMinBlocks 每个套接字环形缓冲区的最少块数
+	afpacketMinBlocks = 8
+)
+
+// openAFPacket 打开sockets个加入同一fanout组的AF_PACKET套接字，所有套接字的数据包汇入返回的通道
+// 内核按流哈希分配数据包，同一连接的数据包总是由同一个套接字接收；ctx取消后套接字关闭，通道随之关闭
+func openAFPacket(ctx context.Context, cfg config.CaptureConfig, filter string, sockets int) (chan gopacket.Packet, error) {
+	var insns []bpf.RawInstruction
+	if filter != "" {
+		compiled, err := pcap.CompileBPFFilter(layers.LinkTypeEthernet, cfg.SnapLen, filter)
+		if err != nil {
+			return nil, fmt.Errorf("编译BPF过滤器失败: %v", err)
+		}
+		insns = make([]bpf.RawInstruction, len(compiled))
+		for i, ins := range compiled {
+			insns[i] = bpf.RawInstruction{Op: ins.Code, Jt: ins.Jt, Jf: ins.Jf, K: ins.K}
+		}
+	}
+
+	var closers []func()
+	closeAll := func() {
+		for _, c := range closers {
+			c()
+		}
+	}
+
+	if cfg.Promiscuous {
+		closePromisc, err := enablePromisc(cfg.Interface)
+		if err != nil {
+			return nil, fmt.Errorf("开启混杂模式失败: %v", err)
+		}
+		closers = append(closers, closePromisc)
+	}
+
+	frameSize, blockSize, numBlocks := afpacketRing(cfg.SnapLen, cfg.BufferSize)
+	id := fanoutID(cfg.FanoutID)
+
+	handles := make([]*afpacket.TPacket, 0, sockets)
+	for i := 0; i < sockets; i++ {
+		handle, err := afpacket.NewTPacket(
+			afpacket.OptInterface(cfg.Interface),
+			afpacket.OptFrameSize(frameSize),
+			afpacket.OptBlockSize(blockSize),
+			afpacket.OptNumBlocks(numBlocks),
+			afpacket.OptPollTimeout(afpacketPollTimeout),
+			// 网卡剥离的802.1Q标签需要补回，VLAN筛选和带vlan的过滤器才能生效
+			afpacket.OptAddVLANHeader(cfg.VLAN),
+		)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		handles = append(handles, handle)
+		closers = append(closers, handle.Close)
+
+		if insns != nil {
+			if err := handle.SetBPF(insns); err != nil {
+				closeAll()
+				return nil, fmt.Errorf("设置BPF过滤器失败: %v", err)
+			}
+		}
+		if err := handle.SetFanout(afpacket.FanoutHash, id); err != nil {
+			closeAll()
+			return nil, fmt.Errorf("加入fanout组 %d 失败: %v（同一主机上的其他实例可能使用了相同的fanout_id）", id, err)
+		}
+	}
+
+	packets := make(chan gopacket.Packet, 1000)
+	var wg sync.WaitGroup
+	for _, handle := range handles {
+		wg.Add(1)
+		go func(handle *afpacket.TPacket) {
+			defer wg.Done()
+			readAFPacket(ctx, handle, packets)
+		}(handle)
+	}
+	go func() {
+		wg.Wait()
+		closeAll()
+		close(packets)
+	}()
+
+	return packets, nil
+}
+
+// readAFPacket 从单个套接字读取数据包写入通道，ctx取消或读取出错时返回
+func readAFPacket(ctx context.Context, handle *afpacket.TPacket, packets chan<- gopacket.Packet) {
+	for ctx.Err() == nil {
+		data, ci, err := handle.ReadPacketData()
+		if err == afpacket.ErrTimeout {
+			continue
+		}
+		if err != nil {
+			log.Printf("读取AF_PACKET数据包失败: %v", err)
+			return
+		}
+
+		packet := gopacket.NewPacket(data, layers.LinkTypeEthernet, gopacket.Default)
+		m := packet.Metadata()
+		m.CaptureInfo = ci
+		m.Truncated = m.Truncated || ci.CaptureLength < ci.Length
+
+		select {
+		case packets <- packet:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// afpacketRing 计算每个套接字的环形缓冲区：块大小至少容纳一个snap_len长度的数据包，块数按buffer_size计算
+func afpacketRing(snapLen, bufferSize int) (frameSize, blockSize, numBlocks int) {
+	frameSize = afpacket.DefaultFrameSize
+	blockSize = afpacket.DefaultBlockSize
+	if snapLen > blockSize {
+		blockSize = (snapLen + frameSize - 1) / frameSize * frameSize
+	}
+
+	numBlocks = bufferSize / blockSize
+	if numBlocks < afpacketMinBlocks {
+		numBlocks = afpacketMinBlocks
+	}
+	return frameSize, blockSize, numBlocks
+}
+
+// fanoutID 配置的fanout组ID，未配置时按进程号生成，避免与同一主机上的其他实例冲突
+func fanoutID(id int) uint16 {
+	if id > 0 {
+		return uint16(id)
+	}
+	return uint16(os.Getpid() & 0xffff)
+}
+
+// enablePromisc 通过单独的AF_PACKET套接字开启网卡混杂模式，返回的函数关闭套接字，内核随之恢复网卡设置
+func enablePromisc(iface string) (func(), error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	mreq := unix.PacketMreq{Ifindex: int32(ifi.Index), Type: unix.PACKET_MR_PROMISC}
+	if err := unix.SetsockoptPacketMreq(fd, unix.SOL_PACKET, unix.PACKET_ADD_MEMBERSHIP, &mreq); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	return func() { unix.Close(fd) }, nil
+}
@@ -0,0 +1,58 @@
+//go:build linux
+
+package capture
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/gopacket/afpacket"
+)
+
+func TestFanoutID(t *testing.T) {
+	tests := []struct {
+		name string
+		id   int
+		want uint16
+	}{
+		{"configured", 42, 42},
+		{"max", 0xffff, 0xffff},
+		{"unset uses pid", 0, uint16(os.Getpid() & 0xffff)},
+		{"negative uses pid", -1, uint16(os.Getpid() & 0xffff)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fanoutID(tt.id); got != tt.want {
+				t.Errorf("fanoutID(%d) = %d, want %d", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAFPacketRing(t *testing.T) {
+	tests := []struct {
+		name          string
+		snapLen       int
+		bufferSize    int
+		wantBlockSize int
+		wantBlocks    int
+	}{
+		{"defaults", 1600, 2 * 1024 * 1024, afpacket.DefaultBlockSize, afpacketMinBlocks},
+		{"large buffer", 1600, 64 * afpacket.DefaultBlockSize, afpacket.DefaultBlockSize, 64},
+		{"jumbo snaplen", afpacket.DefaultBlockSize + 1, 0, afpacket.DefaultBlockSize + afpacket.DefaultFrameSize, afpacketMinBlocks},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frameSize, blockSize, numBlocks := afpacketRing(tt.snapLen, tt.bufferSize)
+			if frameSize != afpacket.DefaultFrameSize {
+				t.Errorf("frameSize = %d, want %d", frameSize, afpacket.DefaultFrameSize)
+			}
+			if blockSize != tt.wantBlockSize || numBlocks != tt.wantBlocks {
+				t.Errorf("afpacketRing(%d, %d) = (%d, %d), want (%d, %d)",
+					tt.snapLen, tt.bufferSize, blockSize, numBlocks, tt.wantBlockSize, tt.wantBlocks)
+			}
+		})
+	}
+}
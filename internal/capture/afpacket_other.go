@@ -0,0 +1,19 @@
+//go:build !linux
+
+package capture
+
+import (
+	"context"
+
+	"github.com/google/gopacket"
+
+	"assets_discovery/internal/config"
+)
+
+// afpacketSupported 非Linux系统没有AF_PACKET，capture.engine为afpacket时回退到pcap
+const afpacketSupported = false
+
+// openAFPacket 非Linux系统不支持AF_PACKET
+func openAFPacket(ctx context.Context, cfg config.CaptureConfig, filter string, sockets int) (chan gopacket.Packet, error) {
+	return nil, errAFPacketUnsupported
+}
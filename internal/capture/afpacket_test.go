@@ -0,0 +1,75 @@
+package capture
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/gopacket/pcap"
+
+	"assets_discovery/internal/config"
+)
+
+func TestLiveEngine(t *testing.T) {
+	// 非Linux系统不支持AF_PACKET，回退到pcap
+	afpacket := enginePcap
+	if afpacketSupported {
+		afpacket = engineAFPacket
+	}
+
+	tests := []struct {
+		engine string
+		want   string
+	}{
+		{"", enginePcap},
+		{"pcap", enginePcap},
+		{"afpacket", afpacket},
+		{"netmap", enginePcap},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.engine, func(t *testing.T) {
+			ce := &CaptureEngine{config: &config.Config{Capture: config.CaptureConfig{Engine: tt.engine}}}
+			if got := ce.liveEngine(); got != tt.want {
+				t.Errorf("liveEngine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBPFFilter(t *testing.T) {
+	compiled := func(n int) func(string) ([]pcap.BPFInstruction, error) {
+		return func(string) ([]pcap.BPFInstruction, error) {
+			return make([]pcap.BPFInstruction, n), nil
+		}
+	}
+	failing := func(string) ([]pcap.BPFInstruction, error) {
+		return nil, errors.New("syntax error")
+	}
+
+	tests := []struct {
+		name      string
+		protocols []string
+		vlan      bool
+		compile   func(string) ([]pcap.BPFInstruction, error)
+		want      string
+	}{
+		{"full filter", []string{"arp", "http", "https"}, false, compiled(20), "(arp or port 80 or port 443)"},
+		{"vlan", []string{"arp"}, true, compiled(20), "(arp) or (vlan and (arp))"},
+		{"too many instructions", []string{"arp", "http", "https"}, false, compiled(pcap.MaxBpfInstructions + 1), "(arp or tcp)"},
+		{"at instruction limit", []string{"arp", "http"}, false, compiled(pcap.MaxBpfInstructions), "(arp or port 80)"},
+		{"compile error", []string{"arp", "dhcp", "dns"}, false, failing, "(arp or udp or tcp)"},
+		{"no filterable protocol", []string{"unknown"}, false, failing, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ce := &CaptureEngine{config: &config.Config{
+				Capture: config.CaptureConfig{VLAN: tt.vlan},
+				Parser:  config.ParserConfig{EnabledProtocols: tt.protocols},
+			}}
+			if got := ce.bpfFilter(tt.compile); got != tt.want {
+				t.Errorf("bpfFilter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
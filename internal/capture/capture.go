@@ -1,13 +1,22 @@
 package capture
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"sync"
+	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/pcap"
+	"github.com/google/gopacket/pcapgo"
 
+	"assets_discovery/internal/api"
+	"assets_discovery/internal/api/grpcapi"
 	"assets_discovery/internal/assets"
 	"assets_discovery/internal/config"
 	"assets_discovery/internal/parser"
@@ -19,44 +28,79 @@ type CaptureEngine struct {
 	config       *config.Config
 	parser       *parser.PacketParser
 	assetManager *assets.AssetManager
+	apiServer    *api.Server
+	grpcServer   *grpcapi.Server
 	storage      storage.Storage
-	wg           sync.WaitGroup
-	stopCh       chan struct{}
+
+	// Stop通过取消该上下文结束捕获，与调用方传入的上下文合并使用
+	stopCtx    context.Context
+	stopCancel context.CancelFunc
+
+	// 只输出计数汇总，用于快速了解pcap内容
+	countOnly bool
+
+	// 数据包抽样，未配置capture.sample_rate时为空
+	sampler *packetSampler
+
+	// 运行统计
+	startTime    time.Time
+	totalPackets uint64
 }
 
 // NewCaptureEngine 创建新的捕获引擎
 func NewCaptureEngine(cfg *config.Config) *CaptureEngine {
-	// 初始化存储
+	// 初始化存储，只分析不保存时不连接任何外部存储
 	var stor storage.Storage
-	var err error
-
-	switch cfg.Storage.Type {
-	case "elasticsearch":
-		stor, err = storage.NewElasticsearchStorage(&cfg.Storage.Elasticsearch)
-	case "file":
-		stor, err = storage.NewFileStorage(&cfg.Storage.File)
-	default:
+	if cfg.Storage.NoStore {
+		log.Println("已启用只分析模式，资产不会被保存")
 		stor = storage.NewMemoryStorage()
+	} else {
+		var err error
+		stor, err = storage.NewStorage(&cfg.Storage)
+		if err != nil {
+			log.Printf("初始化存储失败，使用内存存储: %v", err)
+			stor = storage.NewMemoryStorage()
+		}
 	}
 
-	if err != nil {
-		log.Printf("初始化存储失败，使用内存存储: %v", err)
-		stor = storage.NewMemoryStorage()
+	assetMgr := assets.NewAssetManager(cfg, stor)
+
+	var apiServer *api.Server
+	if cfg.Server.Enabled {
+		apiServer = api.NewServer(cfg, assetMgr)
 	}
 
-	assetMgr := assets.NewAssetManager(cfg, stor)
+	var grpcServer *grpcapi.Server
+	if cfg.Server.GRPC.Enabled {
+		grpcServer = grpcapi.NewServer(cfg, assetMgr)
+	}
+
+	packetParser := parser.NewPacketParser(cfg)
+	assetMgr.SetParseStatsSource(packetParser.ParseStats)
+
+	sampler := newPacketSampler(cfg.Capture.SampleRate)
+	if sampler != nil {
+		log.Printf("已启用数据包抽样：每 %d 个数据包处理1个，ARP、DHCP、mDNS数据包始终处理", cfg.Capture.SampleRate)
+		assetMgr.SetSamplingStatsSource(sampler.stats)
+	}
+
+	stopCtx, stopCancel := context.WithCancel(context.Background())
 
 	return &CaptureEngine{
 		config:       cfg,
-		parser:       parser.NewPacketParser(cfg),
+		parser:       packetParser,
 		assetManager: assetMgr,
+		apiServer:    apiServer,
+		grpcServer:   grpcServer,
+		sampler:      sampler,
 		storage:      stor,
-		stopCh:       make(chan struct{}),
+		stopCtx:      stopCtx,
+		stopCancel:   stopCancel,
 	}
 }
 
-// StartLiveCapture 开始实时流量捕获
-func (ce *CaptureEngine) StartLiveCapture() error {
+// StartLiveCapture 开始实时流量捕获，ctx取消或调用Stop时停止
+func (ce *CaptureEngine) StartLiveCapture(ctx context.Context) error {
 	if ce.config.Capture.Interface == "" {
 		// 如果没有指定接口，列出可用接口
 		return ce.listInterfaces()
@@ -64,6 +108,12 @@ func (ce *CaptureEngine) StartLiveCapture() error {
 
 	log.Printf("开始监听网络接口: %s", ce.config.Capture.Interface)
 
+	ce.checkSnapLen()
+
+	if ce.liveEngine() == engineAFPacket {
+		return ce.startAFPacketCapture(ctx)
+	}
+
 	// 打开网络接口
 	handle, err := pcap.OpenLive(
 		ce.config.Capture.Interface,
@@ -72,101 +122,277 @@ func (ce *CaptureEngine) StartLiveCapture() error {
 		ce.config.Capture.Timeout,
 	)
 	if err != nil {
-		return fmt.Errorf("打开网络接口失败: %v", err)
+		return ce.openLiveError(err)
 	}
 	defer handle.Close()
 
 	// 设置BPF过滤器（可选）
-	if err := ce.setBPFFilter(handle); err != nil {
+	filter, err := ce.setBPFFilter(handle)
+	if err != nil {
 		log.Printf("设置BPF过滤器失败: %v", err)
 	}
 
-	// 启动资产管理器
-	ce.assetManager.Start()
+	ctx, cancel := ce.runContext(ctx)
+	defer cancel()
+
+	// 丢包率持续偏高时临时只捕获核心协议
+	if cfg := ce.config.Capture.AdaptiveFilter; cfg.Enabled && err == nil {
+		if af := newAdaptiveFilter(cfg, handle, filter, ce.config.Parser.EnabledProtocols, ce.config.Capture.VLAN); af != nil {
+			go af.run(ctx)
+		}
+	}
+
+	// 启动数据包处理，any接口等的链路类型为Linux SLL而不是以太网
+	log.Printf("接口 %s 的链路类型: %s", ce.config.Capture.Interface, handle.LinkType())
+	packets := gopacket.NewPacketSource(handle, handle.LinkType()).Packets()
+	return ce.runLive(ctx, packets)
+}
+
+// liveEngine 按capture.engine选择实时捕获后端，不支持或未知的后端回退到pcap
+func (ce *CaptureEngine) liveEngine() string {
+	switch ce.config.Capture.Engine {
+	case "", enginePcap:
+	case engineAFPacket:
+		if afpacketSupported {
+			return engineAFPacket
+		}
+		log.Printf("AF_PACKET只在Linux上可用，回退到pcap捕获")
+	default:
+		log.Printf("未知的捕获后端 %q，使用pcap捕获", ce.config.Capture.Engine)
+	}
+	return enginePcap
+}
+
+// runLive 启动资产管理器和API服务并处理实时捕获的数据包，设置了捕获时长时到时后停止
+func (ce *CaptureEngine) runLive(ctx context.Context, packets chan gopacket.Packet) error {
+	// 设置了捕获时长时，到时后走正常的停止流程
+	if ce.config.Capture.Duration > 0 {
+		log.Printf("捕获将在 %v 后自动停止", ce.config.Capture.Duration)
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, ce.config.Capture.Duration)
+		defer cancelTimeout()
+	}
+
+	// 记录开始时间并启动资产管理器
+	ce.markStart()
+	defer ce.closeStorage()
+	ce.assetManager.Start(ctx)
 	defer ce.assetManager.Stop()
 
-	// 启动数据包处理
-	return ce.processPackets(handle)
+	// 启动API服务
+	if ce.apiServer != nil {
+		ce.apiServer.Start()
+		defer ce.apiServer.Stop()
+	}
+	if ce.grpcServer != nil {
+		ce.grpcServer.Start()
+		defer ce.grpcServer.Stop()
+	}
+
+	return ce.runCapture(ctx, packets, ce.config.Capture.Interface)
 }
 
-// StartOfflineCapture 开始离线pcap文件分析
-func (ce *CaptureEngine) StartOfflineCapture(pcapFile string) error {
+// StartOfflineCapture 开始离线pcap文件分析，文件读完、ctx取消或调用Stop时结束
+func (ce *CaptureEngine) StartOfflineCapture(ctx context.Context, pcapFile string) error {
+	ctx, cancel := ce.runContext(ctx)
+	defer cancel()
+
+	// 标准输入无法重复读取，直接按数据流解析，不读取pcapng名称解析记录
+	var packets chan gopacket.Packet
+	if pcapFile == stdinFile {
+		log.Printf("开始分析标准输入中的pcap数据流")
+
+		var err error
+		packets, err = streamPackets(ctx, os.Stdin)
+		if err != nil {
+			return fmt.Errorf("读取标准输入失败: %v", err)
+		}
+		return ce.runOffline(ctx, packets)
+	}
+
 	log.Printf("开始分析pcap文件: %s", pcapFile)
 
-	// 打开pcap文件
-	handle, err := pcap.OpenOffline(pcapFile)
+	ng, err := isPcapNG(pcapFile)
 	if err != nil {
 		return fmt.Errorf("打开pcap文件失败: %v", err)
 	}
-	defer handle.Close()
 
-	// 启动资产管理器
-	ce.assetManager.Start()
+	// pcapng使用pcapgo读取，以获得所有接口的数据包及接口名称；其他格式交给libpcap
+	if ng {
+		f, err := os.Open(pcapFile)
+		if err != nil {
+			return fmt.Errorf("打开pcap文件失败: %v", err)
+		}
+		defer f.Close()
+
+		reader, err := pcapgo.NewNgReader(f, pcapgo.NgReaderOptions{WantMixedLinkType: true, SkipUnknownVersion: true})
+		if err != nil {
+			return fmt.Errorf("解析pcapng文件失败: %v", err)
+		}
+
+		names, err := readNameResolution(pcapFile)
+		if err != nil {
+			log.Printf("读取pcapng名称解析记录失败: %v", err)
+		}
+		if len(names) > 0 {
+			log.Printf("从pcapng名称解析记录中读取到 %d 个主机名", len(names))
+			ce.assetManager.SeedHostnames(names)
+		}
+
+		packets = ngPackets(ctx, reader)
+	} else {
+		handle, err := pcap.OpenOffline(pcapFile)
+		if err != nil {
+			return fmt.Errorf("打开pcap文件失败: %v", err)
+		}
+		defer handle.Close()
+
+		packets = gopacket.NewPacketSource(handle, handle.LinkType()).Packets()
+	}
+
+	return ce.runOffline(ctx, packets)
+}
+
+// runOffline 启动资产管理器和API服务并处理离线数据包，数据包通道关闭后返回
+func (ce *CaptureEngine) runOffline(ctx context.Context, packets chan gopacket.Packet) error {
+	// 记录开始时间并启动资产管理器
+	ce.markStart()
+	defer ce.closeStorage()
+	ce.assetManager.Start(ctx)
 	defer ce.assetManager.Stop()
 
-	// 处理数据包
-	return ce.processPackets(handle)
+	// 启动API服务
+	if ce.apiServer != nil {
+		ce.apiServer.Start()
+		defer ce.apiServer.Stop()
+	}
+	if ce.grpcServer != nil {
+		ce.grpcServer.Start()
+		defer ce.grpcServer.Stop()
+	}
+
+	// 处理数据包，pcapng中的接口名称由每个数据包携带
+	return ce.runCapture(ctx, packets, "")
+}
+
+// closeStorage 关闭存储，写出缓冲的数据，须在资产管理器停止、完成最后一次保存之后调用
+func (ce *CaptureEngine) closeStorage() {
+	if err := ce.storage.Close(); err != nil {
+		log.Printf("关闭存储失败: %v", err)
+	}
+}
+
+// runContext 合并调用方的上下文和Stop，任一结束时返回的上下文被取消
+func (ce *CaptureEngine) runContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	go func() {
+		select {
+		case <-ce.stopCtx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
 }
 
-// processPackets 处理数据包
-func (ce *CaptureEngine) processPackets(handle *pcap.Handle) error {
-	packetSource := gopacket.NewPacketSource(handle, handle.LinkType())
-	packetChan := packetSource.Packets()
+// SetCountOnly 设置结束后只输出计数汇总而不是逐条资产列表，应与只分析不保存模式一起使用
+func (ce *CaptureEngine) SetCountOnly(countOnly bool) {
+	ce.countOnly = countOnly
+}
 
-	// 启动多个工作协程处理数据包
-	for i := 0; i < ce.config.Capture.Workers; i++ {
-		ce.wg.Add(1)
-		go ce.packetWorker(packetChan)
+// runCapture 处理数据包，只分析不保存时在结束后输出资产汇总
+func (ce *CaptureEngine) runCapture(ctx context.Context, packets chan gopacket.Packet, iface string) error {
+	err := ce.processPackets(ctx, packets, iface)
+	if closeErr := ce.parser.Close(); closeErr != nil {
+		log.Printf("关闭调试转储文件失败: %v", closeErr)
+	}
+	ce.pushMetrics()
+	if ce.countOnly {
+		ce.printCounts()
+	} else if ce.config.Storage.NoStore {
+		ce.printSummary()
 	}
+	return err
+}
 
-	log.Printf("流量捕获已启动，使用 %d 个工作协程", ce.config.Capture.Workers)
+// processPackets 处理数据包，iface为捕获数据包的网络接口，离线分析时为空
+func (ce *CaptureEngine) processPackets(ctx context.Context, packets chan gopacket.Packet, iface string) error {
+	// 启动工作协程池，积压时在workers和max_workers之间伸缩
+	pool := newWorkerPool(ctx, ce, packets, iface, ce.config.Capture.Workers, ce.maxWorkers())
+	pool.start()
 
-	// 等待停止信号或工作协程结束
-	<-ce.stopCh
-	log.Println("收到停止信号")
+	if pool.max > pool.min {
+		log.Printf("流量捕获已启动，使用 %d 个工作协程（最多 %d 个）", pool.min, pool.max)
+	} else {
+		log.Printf("流量捕获已启动，使用 %d 个工作协程", pool.min)
+	}
 
-	ce.wg.Wait()
-	log.Println("流量捕获已停止")
+	// 等待停止信号或数据包读完（离线文件）
+	select {
+	case <-ctx.Done():
+		log.Println("收到停止信号")
+	case <-pool.drained:
+		log.Println("数据包已全部读取")
+	}
+
+	pool.wait()
+	log.Printf("流量捕获已停止，运行时长 %v，共处理 %d 个数据包，峰值工作协程数 %d",
+		time.Since(ce.startTime).Round(time.Second), atomic.LoadUint64(&ce.totalPackets), pool.peakWorkers())
+	if ce.sampler != nil {
+		stats := ce.sampler.stats()
+		log.Printf("数据包抽样：共收到 %d 个数据包，处理了其中 %d 个", stats.Total, stats.Sampled)
+	}
 	return nil
 }
 
-// packetWorker 数据包处理工作协程
-func (ce *CaptureEngine) packetWorker(packetChan chan gopacket.Packet) {
-	defer ce.wg.Done()
-
-	packetsProcessed := 0
-	for {
-		select {
-		case packet, ok := <-packetChan:
-			if !ok {
-				log.Printf("数据包通道已关闭，工作协程退出. 已处理 %d 个数据包", packetsProcessed)
-				return
-			}
+// maxWorkers 工作协程数上限，未配置时按CPU核心数
+func (ce *CaptureEngine) maxWorkers() int {
+	if ce.config.Capture.MaxWorkers > 0 {
+		return ce.config.Capture.MaxWorkers
+	}
+	return runtime.NumCPU()
+}
 
-			// 解析数据包
-			if assetInfo := ce.parser.ParsePacket(packet); assetInfo != nil {
-				// 更新资产信息
-				ce.assetManager.UpdateAsset(assetInfo)
-			}
+// processPacket 解析单个数据包并更新资产，达到最大处理包数时停止捕获，抽样丢弃的数据包不计入处理包数
+func (ce *CaptureEngine) processPacket(packet gopacket.Packet, iface string) {
+	if ce.sampler != nil && !ce.sampler.keep(packet) {
+		return
+	}
 
-			packetsProcessed++
+	if iface == "" {
+		iface = packetInterface(packet)
+	}
 
-			// 检查是否达到最大处理包数
-			if ce.config.Parser.MaxPackets > 0 && packetsProcessed >= ce.config.Parser.MaxPackets {
-				log.Printf("已达到最大处理包数 %d，工作协程退出", ce.config.Parser.MaxPackets)
-				return
-			}
+	if assetInfo := ce.parser.ParsePacketFrom(packet, iface); assetInfo != nil {
+		ce.assetManager.UpdateAsset(assetInfo)
+	}
 
-		case <-ce.stopCh:
-			log.Printf("工作协程收到停止信号，已处理 %d 个数据包", packetsProcessed)
-			return
-		}
+	total := atomic.AddUint64(&ce.totalPackets, 1)
+	if limit := ce.config.Parser.MaxPackets; limit > 0 && total == uint64(limit) {
+		log.Printf("已达到最大处理包数 %d，停止捕获", limit)
+		ce.Stop()
 	}
 }
 
-// Stop 停止捕获
+// markStart 记录捕获开始时间并同步到资产管理器的统计中
+func (ce *CaptureEngine) markStart() {
+	ce.startTime = time.Now()
+	ce.assetManager.SetStartTime(ce.startTime)
+}
+
+// StartTime 捕获开始时间，离线模式下为分析开始时间
+func (ce *CaptureEngine) StartTime() time.Time {
+	return ce.startTime
+}
+
+// ReloadKnownAssets 重新加载告警的已知资产列表
+func (ce *CaptureEngine) ReloadKnownAssets(entries []string) error {
+	return ce.assetManager.ReloadKnownAssets(entries)
+}
+
+// Stop 停止捕获，可安全地重复调用
 func (ce *CaptureEngine) Stop() {
-	close(ce.stopCh)
+	ce.stopCancel()
 }
 
 // listInterfaces 列出可用的网络接口
@@ -203,52 +429,87 @@ func (ce *CaptureEngine) listInterfaces() error {
 	return nil
 }
 
-// setBPFFilter 设置BPF过滤器
-func (ce *CaptureEngine) setBPFFilter(handle *pcap.Handle) error {
-	// 构建BPF过滤器，只捕获我们关心的协议
-	filters := []string{}
-
-	for _, protocol := range ce.config.Parser.EnabledProtocols {
-		switch protocol {
-		case "arp":
-			filters = append(filters, "arp")
-		case "dhcp":
-			filters = append(filters, "port 67 or port 68")
-		case "dns":
-			filters = append(filters, "port 53")
-		case "http":
-			filters = append(filters, "port 80")
-		case "https":
-			filters = append(filters, "port 443")
-		case "smb":
-			filters = append(filters, "port 445 or port 139")
-		case "mdns":
-			filters = append(filters, "port 5353")
+// protocolSnapLen 各协议解析所需的最小捕获长度，应用层协议需要完整的以太网帧
+var protocolSnapLen = map[string]int{
+	"arp":   64,
+	"igmp":  590, // IGMPv3成员报告可能包含多个组记录
+	"nbns":  128,
+	"stun":  590,
+	"rdp":   256,
+	"dhcp":  590,
+	"dns":   590,
+	"llmnr": 590,
+	"http":  1514,
+	"https": 1514,
+	"smb":   1514,
+	"mdns":  1514,
+	"vxlan": 1564, // 内层以太网帧加上50字节封装开销
+	"gre":   1550, // 内层IP报文加上外层以太网、IP和最长16字节的GRE头部
+}
+
+// checkSnapLen 检查snap_len是否满足已启用协议的需求，不满足时告警或自动调大
+func (ce *CaptureEngine) checkSnapLen() {
+	required := 0
+	var needy []string
+	for _, proto := range ce.config.Parser.EnabledProtocols {
+		if n := protocolSnapLen[proto]; n > ce.config.Capture.SnapLen {
+			needy = append(needy, proto)
+			if n > required {
+				required = n
+			}
 		}
 	}
 
-	if len(filters) > 0 {
-		// 如果有过滤器，就设置；否则捕获所有流量
-		filter := fmt.Sprintf("(%s)", joinFilters(filters))
-		log.Printf("设置BPF过滤器: %s", filter)
-		return handle.SetBPFFilter(filter)
+	if len(needy) == 0 {
+		return
 	}
 
-	return nil
+	if ce.config.Capture.AutoSnapLen {
+		log.Printf("警告: snap_len=%d 不足以解析 %s，已自动调整为 %d",
+			ce.config.Capture.SnapLen, strings.Join(needy, ", "), required)
+		ce.config.Capture.SnapLen = required
+		return
+	}
+
+	log.Printf("警告: snap_len=%d 小于 %s 解析所需的 %d 字节，数据包载荷会被截断导致解析结果为空，"+
+		"建议调大snap_len或设置auto_snap_len: true", ce.config.Capture.SnapLen, strings.Join(needy, ", "), required)
 }
 
-// joinFilters 连接过滤器
-func joinFilters(filters []string) string {
-	if len(filters) == 0 {
-		return ""
+// openLiveError 为打开网络接口失败补充常见原因的提示
+func (ce *CaptureEngine) openLiveError(err error) error {
+	msg := strings.ToLower(err.Error())
+
+	switch {
+	case ce.config.Capture.Promiscuous && strings.Contains(msg, "promisc"):
+		return fmt.Errorf("打开网络接口失败: %v（系统拒绝开启混杂模式，可使用 --promiscuous=false 重试）", err)
+	case isPermissionError(err):
+		hint := "没有抓包权限，请以root身份运行，或为程序授予抓包能力: " + setcapCommand()
+		if ce.config.Capture.Promiscuous {
+			hint += "；若只是不允许开启混杂模式，可使用 --promiscuous=false 重试"
+		}
+		return fmt.Errorf("打开网络接口失败: %v（%s）", err, hint)
+	case strings.Contains(msg, "no such device") || strings.Contains(msg, "no such network interface"):
+		return fmt.Errorf("打开网络接口失败: %v（接口不存在，可不带 -i 参数运行以列出可用接口）", err)
+	default:
+		return fmt.Errorf("打开网络接口失败: %v", err)
 	}
-	if len(filters) == 1 {
-		return filters[0]
+}
+
+// isPermissionError 判断打开接口失败是否因为缺少root权限或CAP_NET_RAW能力。
+// AF_PACKET返回EPERM/EACCES，libpcap只返回错误文本，两种形式都需要识别
+func isPermissionError(err error) bool {
+	if errors.Is(err, os.ErrPermission) {
+		return true
 	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "permission") || strings.Contains(msg, "not permitted")
+}
 
-	result := filters[0]
-	for i := 1; i < len(filters); i++ {
-		result += " or " + filters[i]
+// setcapCommand 返回为当前程序授予抓包能力的命令
+func setcapCommand() string {
+	exe, err := os.Executable()
+	if err != nil {
+		exe = os.Args[0]
 	}
-	return result
+	return "sudo setcap cap_net_raw,cap_net_admin=eip " + exe
 }
@@ -0,0 +1,80 @@
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"assets_discovery/internal/config"
+)
+
+func TestOpenLiveError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		promiscuous bool
+		wantSetcap  bool
+		wantHint    string // 期望附加的提示，为空时原样返回错误
+	}{
+		{"libpcap permission text", errors.New("eth0: You don't have permission to capture on that device (socket: Operation not permitted)"), false, true, "没有抓包权限"},
+		{"os.ErrPermission", os.ErrPermission, false, true, "没有抓包权限"},
+		{"wrapped os.ErrPermission", fmt.Errorf("open socket: %w", os.ErrPermission), false, true, "没有抓包权限"},
+		{"syscall EPERM", syscall.EPERM, false, true, "没有抓包权限"},
+		{"syscall EACCES", os.NewSyscallError("socket", syscall.EACCES), false, true, "没有抓包权限"},
+		{"permission with promisc", syscall.EPERM, true, true, "--promiscuous=false"},
+		{"promisc rejected", errors.New("eth0: failed to set promiscuous mode"), true, false, "系统拒绝开启混杂模式"},
+		{"no such device", errors.New("eth9: SIOCETHTOOL(ETHTOOL_GET_TS_INFO) ioctl failed: No such device"), false, false, "接口不存在"},
+		{"no such interface", errors.New("route ip+net: no such network interface"), false, false, "接口不存在"},
+		{"other error", errors.New("eth0: That device is not up"), false, false, ""},
+		{"promisc text without promisc option", errors.New("eth0: failed to set promiscuous mode"), false, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ce := &CaptureEngine{config: &config.Config{Capture: config.CaptureConfig{Promiscuous: tt.promiscuous}}}
+			got := ce.openLiveError(tt.err).Error()
+
+			if !strings.Contains(got, tt.err.Error()) {
+				t.Errorf("openLiveError() = %q, missing original error %q", got, tt.err)
+			}
+			if hasSetcap := strings.Contains(got, "setcap"); hasSetcap != tt.wantSetcap {
+				t.Errorf("openLiveError() = %q, setcap hint = %v, want %v", got, hasSetcap, tt.wantSetcap)
+			}
+			if tt.wantHint == "" {
+				if want := "打开网络接口失败: " + tt.err.Error(); got != want {
+					t.Errorf("openLiveError() = %q, want %q", got, want)
+				}
+			} else if !strings.Contains(got, tt.wantHint) {
+				t.Errorf("openLiveError() = %q, missing hint %q", got, tt.wantHint)
+			}
+		})
+	}
+}
+
+func TestIsPermissionError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"permission denied text", errors.New("socket: permission denied"), true},
+		{"operation not permitted text", errors.New("Operation not permitted"), true},
+		{"os.ErrPermission", os.ErrPermission, true},
+		{"syscall EPERM", syscall.EPERM, true},
+		{"syscall EACCES", syscall.EACCES, true},
+		{"no such device", errors.New("No such device exists"), false},
+		{"promisc", errors.New("failed to set promiscuous mode"), false},
+		{"other", errors.New("That device is not up"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPermissionError(tt.err); got != tt.want {
+				t.Errorf("isPermissionError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
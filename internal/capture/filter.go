@@ -0,0 +1,122 @@
+package capture
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/google/gopacket/pcap"
+)
+
+// protocolFilter 协议对应的BPF过滤表达式
+type protocolFilter struct {
+	expr       string   // 完整的过滤表达式
+	simplified []string // 过滤器超出BPF指令上限时使用的简化表达式，相同的表达式会合并
+}
+
+// protocolFilters 已启用协议对应的过滤表达式，未列出的协议不参与过滤
+var protocolFilters = map[string]protocolFilter{
+	"arp":    {"arp", []string{"arp"}},
+	"dhcp":   {"port 67 or port 68", []string{"udp"}},
+	"dns":    {"port 53 or tcp port 853", []string{"udp", "tcp"}},
+	"http":   {"port 80", []string{"tcp"}},
+	"https":  {"port 443", []string{"tcp"}},
+	"smb":    {"port 445 or port 139", []string{"tcp"}},
+	"mdns":   {"port 5353", []string{"udp"}},
+	"rdp":    {"tcp port 3389", []string{"tcp"}},
+	"llmnr":  {"udp port 5355", []string{"udp"}},
+	"nbns":   {"udp port 137", []string{"udp"}},
+	"vxlan":  {"udp port 4789 or udp port 8472", []string{"udp"}},
+	"stun":   {"udp port 3478", []string{"udp"}},
+	"gre":    {"ip proto 47 or ip proto 4", []string{"ip proto 47 or ip proto 4"}},
+	"igmp":   {"igmp", []string{"igmp"}},
+	"telnet": {"tcp port 23 or tcp port 2323", []string{"tcp"}},
+	"redis":  {"tcp port 6379", []string{"tcp"}},
+}
+
+// setBPFFilter 设置BPF过滤器，只捕获已启用协议的流量，返回设置的过滤器
+func (ce *CaptureEngine) setBPFFilter(handle *pcap.Handle) (string, error) {
+	filter := ce.bpfFilter(handle.CompileBPFFilter)
+	if filter == "" {
+		// 没有可过滤的协议时捕获所有流量
+		return "", nil
+	}
+
+	log.Printf("设置BPF过滤器: %s", filter)
+	return filter, handle.SetBPFFilter(filter)
+}
+
+// bpfFilter 按已启用的协议生成BPF过滤器，没有可过滤的协议时返回空字符串
+// 过滤器经compile编译后超过BPF指令上限（如启用的协议过多）时改用简化的过滤器
+func (ce *CaptureEngine) bpfFilter(compile func(expr string) ([]pcap.BPFInstruction, error)) string {
+	protocols := ce.config.Parser.EnabledProtocols
+	vlan := ce.config.Capture.VLAN
+
+	filter := buildBPFFilter(protocols, vlan, false)
+	if filter == "" {
+		return ""
+	}
+
+	if insns, err := compile(filter); err != nil || len(insns) > pcap.MaxBpfInstructions {
+		simplified := buildBPFFilter(protocols, vlan, true)
+		if err != nil {
+			log.Printf("编译BPF过滤器失败: %v，改用简化的过滤器", err)
+		} else {
+			log.Printf("BPF过滤器编译后有 %d 条指令，超过上限 %d，改用简化的过滤器", len(insns), pcap.MaxBpfInstructions)
+		}
+		filter = simplified
+	}
+	return filter
+}
+
+// buildBPFFilter 按启用的协议生成BPF过滤器，没有可过滤的协议时返回空字符串
+// vlan为true时同时匹配带802.1Q标签的数据包：BPF的vlan关键字会使之后的表达式按标签后的偏移匹配，
+// 不带vlan的表达式无法匹配trunk端口上的流量，因此生成"(过滤器) or (vlan and (过滤器))"
+// simplified为true时按传输层合并端口条件，用于过滤器超过BPF指令上限的情况
+func buildBPFFilter(protocols []string, vlan, simplified bool) string {
+	var filters []string
+	seen := make(map[string]bool)
+
+	for _, protocol := range protocols {
+		pf, ok := protocolFilters[protocol]
+		if !ok {
+			continue
+		}
+
+		exprs := []string{pf.expr}
+		if simplified {
+			exprs = pf.simplified
+		}
+		for _, expr := range exprs {
+			if !seen[expr] {
+				seen[expr] = true
+				filters = append(filters, expr)
+			}
+		}
+	}
+
+	if len(filters) == 0 {
+		return ""
+	}
+
+	filter := fmt.Sprintf("(%s)", joinFilters(filters))
+	if vlan {
+		filter = fmt.Sprintf("%s or (vlan and %s)", filter, filter)
+	}
+	return filter
+}
+
+// joinFilters 连接过滤器
+func joinFilters(filters []string) string {
+	if len(filters) == 0 {
+		return ""
+	}
+	if len(filters) == 1 {
+		return filters[0]
+	}
+
+	result := filters[0]
+	for i := 1; i < len(filters); i++ {
+		result += " or " + filters[i]
+	}
+	return result
+}
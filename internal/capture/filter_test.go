@@ -0,0 +1,62 @@
+package capture
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildBPFFilter(t *testing.T) {
+	tests := []struct {
+		name       string
+		protocols  []string
+		vlan       bool
+		simplified bool
+		want       string
+	}{
+		{"single protocol", []string{"arp"}, false, false, "(arp)"},
+		{"igmp", []string{"igmp"}, false, false, "(igmp)"},
+		{"igmp with ports", []string{"igmp", "mdns", "telnet"}, false, false, "(igmp or port 5353 or tcp port 23 or tcp port 2323)"},
+		{"order kept", []string{"dns", "arp", "dhcp"}, false, false, "(port 53 or tcp port 853 or arp or port 67 or port 68)"},
+		{"unknown protocols skipped", []string{"ssdp", "arp", "bogus"}, false, false, "(arp)"},
+		{"duplicates merged", []string{"http", "http"}, false, false, "(port 80)"},
+		{"no protocols", nil, false, false, ""},
+		{"only unknown protocols", []string{"ssdp"}, true, true, ""},
+		{"vlan tagged and untagged", []string{"arp", "http"}, true, false, "(arp or port 80) or (vlan and (arp or port 80))"},
+		{"vlan igmp", []string{"igmp"}, true, false, "(igmp) or (vlan and (igmp))"},
+		{"simplified merges transports", []string{"arp", "http", "https", "smb", "mdns", "llmnr"}, false, true, "(arp or tcp or udp)"},
+		{"simplified keeps igmp and gre", []string{"igmp", "gre", "dns"}, false, true, "(igmp or ip proto 47 or ip proto 4 or udp or tcp)"},
+		{"simplified vlan", []string{"dhcp", "redis"}, true, true, "(udp or tcp) or (vlan and (udp or tcp))"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildBPFFilter(tt.protocols, tt.vlan, tt.simplified); got != tt.want {
+				t.Errorf("buildBPFFilter(%v, %v, %v) = %q, want %q", tt.protocols, tt.vlan, tt.simplified, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildBPFFilterAllProtocols(t *testing.T) {
+	protocols := make([]string, 0, len(protocolFilters))
+	for protocol := range protocolFilters {
+		protocols = append(protocols, protocol)
+	}
+
+	// 全部协议的简化过滤器只包含少量传输层条件，与启用顺序无关的条件集合固定
+	got := buildBPFFilter(protocols, false, true)
+	for _, expr := range []string{"arp", "udp", "tcp", "igmp", "ip proto 47 or ip proto 4"} {
+		if !containsExpr(got, expr) {
+			t.Errorf("buildBPFFilter(all, simplified) = %q, missing %q", got, expr)
+		}
+	}
+	if full := buildBPFFilter(protocols, false, false); len(got) >= len(full) {
+		t.Errorf("simplified filter length = %d, want shorter than %d", len(got), len(full))
+	}
+}
+
+// containsExpr 检查括号内以" or "分隔的过滤器中是否包含表达式expr
+func containsExpr(filter, expr string) bool {
+	inner := strings.TrimSuffix(strings.TrimPrefix(filter, "("), ")")
+	return strings.Contains(" or "+inner+" or ", " or "+expr+" or ")
+}
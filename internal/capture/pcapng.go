@@ -0,0 +1,194 @@
+package capture
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+// pcapng块类型
+const (
+	ngBlockSectionHeader  = 0x0A0D0D0A
+	ngBlockNameResolution = 0x00000004
+	ngByteOrderMagic      = 0x1A2B3C4D
+)
+
+// ngInterfaceName pcapng中数据包所属接口的名称，随CaptureInfo.AncillaryData传递给工作协程
+type ngInterfaceName string
+
+// isPcapNG 根据文件开头的魔数判断是否为pcapng格式
+func isPcapNG(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	var magic [4]byte
+	if _, err := io.ReadFull(f, magic[:]); err != nil {
+		return false, err
+	}
+	return binary.BigEndian.Uint32(magic[:]) == ngBlockSectionHeader, nil
+}
+
+// ngPackets 从pcapng读取所有接口的数据包，按各接口的链路类型解码并附带接口名称
+// 文件读完或ctx取消时关闭返回的通道
+func ngPackets(ctx context.Context, r *pcapgo.NgReader) chan gopacket.Packet {
+	packets := make(chan gopacket.Packet, 1000)
+
+	go func() {
+		defer close(packets)
+
+		for {
+			data, ci, err := r.ReadPacketData()
+			if err != nil {
+				if err != io.EOF {
+					log.Printf("读取pcapng数据包失败: %v", err)
+				}
+				return
+			}
+
+			linkType := r.LinkType()
+			if len(ci.AncillaryData) > 0 {
+				if t, ok := ci.AncillaryData[0].(layers.LinkType); ok {
+					linkType = t
+				}
+			}
+			if iface, err := r.Interface(ci.InterfaceIndex); err == nil {
+				name := iface.Name
+				if name == "" {
+					name = iface.Description
+				}
+				ci.AncillaryData = append(ci.AncillaryData, ngInterfaceName(name))
+			}
+
+			packet := gopacket.NewPacket(data, linkType, gopacket.Default)
+			m := packet.Metadata()
+			m.CaptureInfo = ci
+			m.Truncated = m.Truncated || ci.CaptureLength < ci.Length
+
+			select {
+			case packets <- packet:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return packets
+}
+
+// packetInterface 返回pcapng数据包所属接口的名称，其他来源的数据包返回空
+func packetInterface(packet gopacket.Packet) string {
+	for _, data := range packet.Metadata().AncillaryData {
+		if name, ok := data.(ngInterfaceName); ok {
+			return string(name)
+		}
+	}
+	return ""
+}
+
+// readNameResolution 读取pcapng中名称解析块(NRB)记录的IP与主机名映射
+// 抓包工具在捕获时解析的名称可以在没有DHCP、NBNS等流量时补全资产主机名
+func readNameResolution(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	names := make(map[string]string)
+	var order binary.ByteOrder = binary.LittleEndian
+	header := make([]byte, 8)
+
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				return names, nil
+			}
+			return names, fmt.Errorf("读取pcapng块失败: %v", err)
+		}
+
+		// 节头部块中的字节序标记决定本节其余块的字节序
+		if binary.BigEndian.Uint32(header) == ngBlockSectionHeader {
+			magic, err := r.Peek(4)
+			if err != nil {
+				return names, fmt.Errorf("读取pcapng节头部失败: %v", err)
+			}
+			if binary.BigEndian.Uint32(magic) == ngByteOrderMagic {
+				order = binary.BigEndian
+			} else {
+				order = binary.LittleEndian
+			}
+		}
+
+		blockType := order.Uint32(header)
+		length := int(order.Uint32(header[4:]))
+		if length < 12 || length%4 != 0 {
+			return names, fmt.Errorf("无效的pcapng块长度: %d", length)
+		}
+
+		body := length - 8
+		if blockType != ngBlockNameResolution {
+			if _, err := r.Discard(body); err != nil {
+				return names, fmt.Errorf("读取pcapng块失败: %v", err)
+			}
+			continue
+		}
+
+		data := make([]byte, body)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return names, fmt.Errorf("读取pcapng名称解析块失败: %v", err)
+		}
+		parseNameRecords(data[:body-4], order, names)
+	}
+}
+
+// parseNameRecords 解析名称解析块中的IPv4(1)和IPv6(2)记录，每个地址只取第一个名称
+func parseNameRecords(data []byte, order binary.ByteOrder, names map[string]string) {
+	for len(data) >= 4 {
+		recordType := order.Uint16(data)
+		valueLen := int(order.Uint16(data[2:]))
+		if recordType == 0 || len(data) < 4+valueLen {
+			return
+		}
+		value := data[4 : 4+valueLen]
+		if padded := 4 + (valueLen+3)&^3; padded < len(data) {
+			data = data[padded:]
+		} else {
+			data = nil
+		}
+
+		addrLen := 0
+		switch recordType {
+		case 1:
+			addrLen = net.IPv4len
+		case 2:
+			addrLen = net.IPv6len
+		default:
+			continue
+		}
+		if len(value) <= addrLen {
+			continue
+		}
+
+		ip := net.IP(value[:addrLen]).String()
+		name := strings.SplitN(string(value[addrLen:]), "\x00", 2)[0]
+		if name != "" {
+			if _, exists := names[ip]; !exists {
+				names[ip] = name
+			}
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+
+	"assets_discovery/internal/config"
+)
+
+// nameRecord pcapng名称解析块中的一条地址记录
+type nameRecord struct {
+	ip   net.IP
+	name string
+}
+
+// nameResolutionBlock 构造小端字节序的名称解析块(NRB)
+func nameResolutionBlock(records ...nameRecord) []byte {
+	var body []byte
+	for _, record := range records {
+		recordType, addr := uint16(1), record.ip.To4()
+		if addr == nil {
+			recordType, addr = 2, record.ip.To16()
+		}
+		value := append(append([]byte(nil), addr...), record.name...)
+		value = append(value, 0)
+
+		body = binary.LittleEndian.AppendUint16(body, recordType)
+		body = binary.LittleEndian.AppendUint16(body, uint16(len(value)))
+		body = append(body, value...)
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+	}
+	body = append(body, 0, 0, 0, 0) // nrb_record_end
+
+	length := uint32(len(body) + 12)
+	block := binary.LittleEndian.AppendUint32(nil, ngBlockNameResolution)
+	block = binary.LittleEndian.AppendUint32(block, length)
+	block = append(block, body...)
+	return binary.LittleEndian.AppendUint32(block, length)
+}
+
+// ngTestPacket pcapng测试文件中的一个数据包
+type ngTestPacket struct {
+	iface int
+	data  []byte
+}
+
+// writePcapNG 写入包含eth0(以太网)和tun0(原始IP)两个接口及名称解析块的pcapng文件
+func writePcapNG(t *testing.T, nrb []byte, packets ...ngTestPacket) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := pcapgo.NewNgWriterInterface(&buf, pcapgo.NgInterface{
+		Name: "eth0", LinkType: layers.LinkTypeEthernet, SnapLength: 65536,
+	}, pcapgo.DefaultNgWriterOptions)
+	if err != nil {
+		t.Fatalf("NewNgWriterInterface() error = %v", err)
+	}
+	if _, err := w.AddInterface(pcapgo.NgInterface{
+		Description: "tun0", LinkType: layers.LinkTypeRaw, SnapLength: 65536,
+	}); err != nil {
+		t.Fatalf("AddInterface() error = %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	buf.Write(nrb)
+
+	ts := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i, p := range packets {
+		ci := gopacket.CaptureInfo{
+			Timestamp:      ts.Add(time.Duration(i) * time.Second),
+			CaptureLength:  len(p.data),
+			Length:         len(p.data),
+			InterfaceIndex: p.iface,
+		}
+		if err := w.WritePacket(ci, p.data); err != nil {
+			t.Fatalf("WritePacket() error = %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "multi.pcapng")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+// serializeLayers 序列化各层为字节
+func serializeLayers(t *testing.T, layerList ...gopacket.SerializableLayer) []byte {
+	t.Helper()
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, layerList...); err != nil {
+		t.Fatalf("SerializeLayers() error = %v", err)
+	}
+	return append([]byte(nil), buf.Bytes()...)
+}
+
+// ngFixture 返回eth0上10.0.0.1的ARP请求和tun0上10.8.0.5回应22端口的SYN-ACK
+func ngFixture(t *testing.T) []ngTestPacket {
+	mac := net.HardwareAddr{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x01}
+	arp := serializeLayers(t,
+		&layers.Ethernet{SrcMAC: mac, DstMAC: layers.EthernetBroadcast, EthernetType: layers.EthernetTypeARP},
+		&layers.ARP{
+			AddrType: layers.LinkTypeEthernet, Protocol: layers.EthernetTypeIPv4,
+			HwAddressSize: 6, ProtAddressSize: 4, Operation: layers.ARPRequest,
+			SourceHwAddress: mac, SourceProtAddress: []byte{10, 0, 0, 1},
+			DstHwAddress: make([]byte, 6), DstProtAddress: []byte{10, 0, 0, 2},
+		})
+
+	ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolTCP, SrcIP: net.IP{10, 8, 0, 5}, DstIP: net.IP{10, 8, 0, 1}}
+	tcp := &layers.TCP{SrcPort: 22, DstPort: 51000, SYN: true, ACK: true, Window: 65535}
+	tcp.SetNetworkLayerForChecksum(ip)
+	synAck := serializeLayers(t, ip, tcp)
+
+	return []ngTestPacket{{0, arp}, {1, synAck}}
+}
+
+func TestIsPcapNG(t *testing.T) {
+	ng := writePcapNG(t, nil, ngFixture(t)...)
+
+	var buf bytes.Buffer
+	w := pcapgo.NewWriter(&buf)
+	if err := w.WriteFileHeader(65536, layers.LinkTypeEthernet); err != nil {
+		t.Fatalf("WriteFileHeader() error = %v", err)
+	}
+	classic := filepath.Join(t.TempDir(), "classic.pcap")
+	if err := os.WriteFile(classic, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	empty := filepath.Join(t.TempDir(), "empty.pcap")
+	if err := os.WriteFile(empty, nil, 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    bool
+		wantErr bool
+	}{
+		{"pcapng", ng, true, false},
+		{"classic pcap", classic, false, false},
+		{"empty file", empty, false, true},
+		{"missing file", filepath.Join(t.TempDir(), "missing.pcap"), false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := isPcapNG(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("isPcapNG() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("isPcapNG() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadNameResolution(t *testing.T) {
+	nrb := nameResolutionBlock(
+		nameRecord{net.ParseIP("10.0.0.1"), "nas.corp.example"},
+		nameRecord{net.ParseIP("2001:db8::5"), "web.corp.example"},
+		nameRecord{net.ParseIP("10.0.0.1"), "alias.corp.example"},
+		nameRecord{net.ParseIP("10.8.0.5"), "vpn-gw"},
+	)
+	path := writePcapNG(t, nrb, ngFixture(t)...)
+
+	names, err := readNameResolution(path)
+	if err != nil {
+		t.Fatalf("readNameResolution() error = %v", err)
+	}
+	// 同一地址只取第一个名称
+	want := map[string]string{
+		"10.0.0.1":    "nas.corp.example",
+		"2001:db8::5": "web.corp.example",
+		"10.8.0.5":    "vpn-gw",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("readNameResolution() = %v, want %v", names, want)
+	}
+}
+
+func TestNgPacketsAllInterfaces(t *testing.T) {
+	path := writePcapNG(t, nameResolutionBlock(nameRecord{net.ParseIP("10.0.0.1"), "nas"}), ngFixture(t)...)
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer f.Close()
+	reader, err := pcapgo.NewNgReader(f, pcapgo.NgReaderOptions{WantMixedLinkType: true, SkipUnknownVersion: true})
+	if err != nil {
+		t.Fatalf("NewNgReader() error = %v", err)
+	}
+
+	var ifaces []string
+	var first []gopacket.LayerType
+	for packet := range ngPackets(context.Background(), reader) {
+		ifaces = append(ifaces, packetInterface(packet))
+		first = append(first, packet.Layers()[0].LayerType())
+	}
+
+	// 每个数据包按所属接口的链路类型解码，接口没有名称时使用描述
+	if want := []string{"eth0", "tun0"}; !reflect.DeepEqual(ifaces, want) {
+		t.Errorf("interfaces = %v, want %v", ifaces, want)
+	}
+	if want := []gopacket.LayerType{layers.LayerTypeEthernet, layers.LayerTypeIPv4}; !reflect.DeepEqual(first, want) {
+		t.Errorf("first layers = %v, want %v", first, want)
+	}
+}
+
+func TestStartOfflineCapturePcapNG(t *testing.T) {
+	nrb := nameResolutionBlock(
+		nameRecord{net.ParseIP("10.0.0.1"), "nas.corp.example"},
+		nameRecord{net.ParseIP("10.8.0.5"), "vpn-gw"},
+	)
+	path := writePcapNG(t, nrb, ngFixture(t)...)
+
+	cfg := &config.Config{}
+	cfg.Storage.NoStore = true
+	cfg.Capture.Workers = 1
+	cfg.Capture.MaxWorkers = 1
+	cfg.Parser.EnabledProtocols = []string{"arp"}
+	ce := NewCaptureEngine(cfg)
+	ce.SetCountOnly(true)
+
+	if err := ce.StartOfflineCapture(context.Background(), path); err != nil {
+		t.Fatalf("StartOfflineCapture() error = %v", err)
+	}
+
+	tests := []struct {
+		id           string
+		wantHostname string
+		wantIface    string
+	}{
+		{"mac_00:1a:2b:3c:4d:01", "nas.corp.example", "eth0"},
+		{"ip_10.8.0.5", "vpn-gw", "tun0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			asset, ok := ce.assetManager.GetAsset(tt.id)
+			if !ok {
+				t.Fatalf("GetAsset(%s) not found", tt.id)
+			}
+			if asset.Hostname != tt.wantHostname || asset.HostnameSource != "pcapng_nrb" {
+				t.Errorf("hostname = %q (%s), want %q (pcapng_nrb)", asset.Hostname, asset.HostnameSource, tt.wantHostname)
+			}
+			if !reflect.DeepEqual(asset.Interfaces, []string{tt.wantIface}) {
+				t.Errorf("interfaces = %v, want [%s]", asset.Interfaces, tt.wantIface)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package capture
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+)
+
+const (
+	// poolScaleInterval 检查数据包积压情况的间隔
+	poolScaleInterval = 500 * time.Millisecond
+	// poolIdleChecks 连续多少次检查没有积压时减少一个工作协程
+	poolIdleChecks = 10
+)
+
+// workerPool 按数据包积压情况在min和max之间伸缩的工作协程池
+type workerPool struct {
+	ctx      context.Context
+	packets  chan gopacket.Packet
+	iface    string // 数据包来源的网络接口
+	min, max int
+
+	// 检查积压的间隔及单个数据包的处理函数，默认为poolScaleInterval和ce.processPacket
+	interval time.Duration
+	handle   func(packet gopacket.Packet, iface string)
+
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	active   int
+	peak     int
+	shrinkCh chan struct{} // 通知一个空闲的工作协程退出
+
+	// 数据包通道关闭（离线文件读完）时关闭
+	drained   chan struct{}
+	drainOnce sync.Once
+}
+
+// newWorkerPool 创建工作协程池，ctx取消时所有协程退出；max不大于min时协程数固定为min
+func newWorkerPool(ctx context.Context, ce *CaptureEngine, packets chan gopacket.Packet, iface string, min, max int) *workerPool {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+
+	return &workerPool{
+		ctx:      ctx,
+		packets:  packets,
+		iface:    iface,
+		min:      min,
+		max:      max,
+		interval: poolScaleInterval,
+		handle:   ce.processPacket,
+		shrinkCh: make(chan struct{}),
+		drained:  make(chan struct{}),
+	}
+}
+
+// start 启动min个工作协程，需要伸缩时同时启动监控协程
+func (p *workerPool) start() {
+	for i := 0; i < p.min; i++ {
+		p.spawn()
+	}
+
+	if p.max > p.min {
+		p.wg.Add(1)
+		go p.monitor()
+	}
+}
+
+// spawn 启动一个工作协程
+func (p *workerPool) spawn() {
+	p.mu.Lock()
+	p.active++
+	if p.active > p.peak {
+		p.peak = p.active
+	}
+	p.mu.Unlock()
+
+	p.wg.Add(1)
+	go p.worker()
+}
+
+// activeWorkers 当前工作协程数
+func (p *workerPool) activeWorkers() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.active
+}
+
+// peakWorkers 运行期间的最大工作协程数
+func (p *workerPool) peakWorkers() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.peak
+}
+
+// wait 等待所有工作协程和监控协程退出
+func (p *workerPool) wait() {
+	p.wg.Wait()
+}
+
+// monitor 积压超过通道容量的1/4时增加工作协程，持续空闲时逐个减少
+func (p *workerPool) monitor() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	idle := 0
+	for {
+		select {
+		case <-ticker.C:
+			backlog := len(p.packets)
+			active := p.activeWorkers()
+
+			switch {
+			case backlog >= cap(p.packets)/4 && active < p.max:
+				idle = 0
+				p.spawn()
+				log.Printf("数据包积压 %d，工作协程增加到 %d", backlog, active+1)
+			case backlog == 0 && active > p.min:
+				idle++
+				if idle < poolIdleChecks {
+					continue
+				}
+				idle = 0
+				// 所有工作协程都在忙时跳过，下一轮再尝试
+				select {
+				case p.shrinkCh <- struct{}{}:
+					log.Printf("流量空闲，工作协程减少到 %d", active-1)
+				default:
+				}
+			default:
+				idle = 0
+			}
+
+		case <-p.drained:
+			return
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// worker 数据包处理工作协程
+func (p *workerPool) worker() {
+	defer p.wg.Done()
+	defer func() {
+		p.mu.Lock()
+		p.active--
+		p.mu.Unlock()
+	}()
+
+	packetsProcessed := 0
+	for {
+		select {
+		case packet, ok := <-p.packets:
+			if !ok {
+				p.drainOnce.Do(func() { close(p.drained) })
+				log.Printf("数据包通道已关闭，工作协程退出. 已处理 %d 个数据包", packetsProcessed)
+				return
+			}
+
+			p.handle(packet, p.iface)
+			packetsProcessed++
+
+		case <-p.shrinkCh:
+			return
+
+		case <-p.ctx.Done():
+			log.Printf("工作协程收到停止信号，已处理 %d 个数据包", packetsProcessed)
+			return
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package capture
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+)
+
+// newTestPool 创建使用自定义处理函数、快速检查积压的工作协程池
+func newTestPool(ctx context.Context, packets chan gopacket.Packet, min, max int, handle func(gopacket.Packet, string)) *workerPool {
+	pool := newWorkerPool(ctx, nil, packets, "eth0", min, max)
+	pool.interval = time.Millisecond
+	pool.handle = handle
+	return pool
+}
+
+// waitFor 在超时前反复检查条件
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWorkerPoolBounds(t *testing.T) {
+	tests := []struct {
+		name             string
+		min, max         int
+		wantMin, wantMax int
+	}{
+		{"fixed", 4, 0, 4, 4},
+		{"scaling", 2, 8, 2, 8},
+		{"max below min", 4, 2, 4, 4},
+		{"min defaults to one", 0, 0, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := newWorkerPool(context.Background(), nil, make(chan gopacket.Packet), "", tt.min, tt.max)
+			if pool.min != tt.wantMin || pool.max != tt.wantMax {
+				t.Errorf("bounds = [%d, %d], want [%d, %d]", pool.min, pool.max, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestWorkerPoolScaleUpAndDown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	packets := make(chan gopacket.Packet, 8)
+	gate := make(chan struct{})
+	var processed int64
+	pool := newTestPool(ctx, packets, 1, 3, func(gopacket.Packet, string) {
+		<-gate
+		atomic.AddInt64(&processed, 1)
+	})
+	pool.start()
+
+	// 处理阻塞时通道积压，协程数增加到上限
+	const total = 20
+	go func() {
+		for i := 0; i < total; i++ {
+			packets <- nil
+		}
+	}()
+	waitFor(t, "scale up to max", func() bool { return pool.activeWorkers() == 3 })
+
+	// 积压消除并持续空闲后逐个减少到下限
+	close(gate)
+	waitFor(t, "backlog drained", func() bool { return atomic.LoadInt64(&processed) == total })
+	waitFor(t, "scale down to min", func() bool { return pool.activeWorkers() == 1 })
+
+	if peak := pool.peakWorkers(); peak != 3 {
+		t.Errorf("peak workers = %d, want 3", peak)
+	}
+
+	cancel()
+	pool.wait()
+	if active := pool.activeWorkers(); active != 0 {
+		t.Errorf("active workers after cancel = %d, want 0", active)
+	}
+}
+
+func TestWorkerPoolDrain(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max int
+		packets  int
+	}{
+		{"single worker", 1, 1, 100},
+		{"fixed workers", 4, 4, 1000},
+		{"scaling workers", 2, 6, 1000},
+		{"empty input", 3, 3, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packets := make(chan gopacket.Packet, 16)
+			var mu sync.Mutex
+			seen := 0
+			pool := newTestPool(context.Background(), packets, tt.min, tt.max, func(gopacket.Packet, string) {
+				mu.Lock()
+				seen++
+				mu.Unlock()
+			})
+			pool.start()
+
+			for i := 0; i < tt.packets; i++ {
+				packets <- nil
+			}
+			close(packets)
+
+			select {
+			case <-pool.drained:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("pool not drained after input closed")
+			}
+			pool.wait()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if seen != tt.packets {
+				t.Errorf("processed %d packets, want %d", seen, tt.packets)
+			}
+			if active := pool.activeWorkers(); active != 0 {
+				t.Errorf("active workers after drain = %d, want 0", active)
+			}
+		})
+	}
+}
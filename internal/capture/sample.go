@@ -0,0 +1,62 @@
+package capture
+
+import (
+	"sync/atomic"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"assets_discovery/internal/assets"
+)
+
+// discoveryUDPPorts 流量小但对资产发现至关重要的UDP端口，抽样时始终保留：DHCP、DHCPv6、mDNS
+var discoveryUDPPorts = map[layers.UDPPort]bool{
+	67: true, 68: true, 546: true, 547: true, 5353: true,
+}
+
+// packetSampler 按1/rate的比例抽样数据包，ARP、DHCP、mDNS数据包不参与抽样、始终保留
+// 抽样按到达顺序确定地保留每rate个数据包中的第一个，多个工作协程并发调用时通过原子计数保证比例
+type packetSampler struct {
+	rate uint64
+
+	seq     uint64 // 参与抽样的数据包序号
+	total   uint64 // 收到的数据包总数
+	sampled uint64 // 保留的数据包数，包括始终保留的发现协议数据包
+}
+
+// newPacketSampler 创建抽样器，rate不大于1时不抽样，返回nil
+func newPacketSampler(rate int) *packetSampler {
+	if rate <= 1 {
+		return nil
+	}
+	return &packetSampler{rate: uint64(rate)}
+}
+
+// keep 判断是否处理该数据包
+func (s *packetSampler) keep(packet gopacket.Packet) bool {
+	atomic.AddUint64(&s.total, 1)
+
+	if !isDiscoveryPacket(packet) && (atomic.AddUint64(&s.seq, 1)-1)%s.rate != 0 {
+		return false
+	}
+	atomic.AddUint64(&s.sampled, 1)
+	return true
+}
+
+// stats 抽样比例及收到和保留的数据包数
+func (s *packetSampler) stats() assets.SamplingStats {
+	return assets.SamplingStats{
+		Rate:    int(s.rate),
+		Total:   atomic.LoadUint64(&s.total),
+		Sampled: atomic.LoadUint64(&s.sampled),
+	}
+}
+
+// isDiscoveryPacket 只检查已解码的ARP和UDP层，不解析载荷
+func isDiscoveryPacket(packet gopacket.Packet) bool {
+	if packet.Layer(layers.LayerTypeARP) != nil {
+		return true
+	}
+	udp, ok := packet.Layer(layers.LayerTypeUDP).(*layers.UDP)
+	return ok && (discoveryUDPPorts[udp.SrcPort] || discoveryUDPPorts[udp.DstPort])
+}
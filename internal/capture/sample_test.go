@@ -0,0 +1,116 @@
+package capture
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// samplePacket 构造以太网数据包，kind为arp、dhcp、mdns或tcp
+func samplePacket(t *testing.T, kind string) gopacket.Packet {
+	t.Helper()
+
+	src := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	eth := &layers.Ethernet{SrcMAC: src, DstMAC: layers.EthernetBroadcast, EthernetType: layers.EthernetTypeIPv4}
+	ip := &layers.IPv4{Version: 4, TTL: 64, SrcIP: net.IP{10, 0, 0, 1}, DstIP: net.IP{10, 0, 0, 2}}
+
+	var stack []gopacket.SerializableLayer
+	switch kind {
+	case "arp":
+		eth.EthernetType = layers.EthernetTypeARP
+		stack = []gopacket.SerializableLayer{eth, &layers.ARP{
+			AddrType: layers.LinkTypeEthernet, Protocol: layers.EthernetTypeIPv4,
+			HwAddressSize: 6, ProtAddressSize: 4, Operation: layers.ARPRequest,
+			SourceHwAddress: src, SourceProtAddress: []byte{10, 0, 0, 1},
+			DstHwAddress: make([]byte, 6), DstProtAddress: []byte{10, 0, 0, 2},
+		}}
+	case "dhcp", "mdns":
+		ip.Protocol = layers.IPProtocolUDP
+		udp := &layers.UDP{SrcPort: 68, DstPort: 67}
+		if kind == "mdns" {
+			udp.SrcPort, udp.DstPort = 5353, 5353
+		}
+		udp.SetNetworkLayerForChecksum(ip)
+		stack = []gopacket.SerializableLayer{eth, ip, udp, gopacket.Payload([]byte{0})}
+	default:
+		ip.Protocol = layers.IPProtocolTCP
+		tcp := &layers.TCP{SrcPort: 40000, DstPort: 443, ACK: true, Window: 1024}
+		tcp.SetNetworkLayerForChecksum(ip)
+		stack = []gopacket.SerializableLayer{eth, ip, tcp, gopacket.Payload([]byte{0})}
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, stack...); err != nil {
+		t.Fatalf("SerializeLayers(%s) error = %v", kind, err)
+	}
+	return gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+}
+
+func TestPacketSampler(t *testing.T) {
+	const rounds = 50
+
+	packets := map[string]gopacket.Packet{}
+	for _, kind := range []string{"arp", "dhcp", "mdns", "tcp"} {
+		packets[kind] = samplePacket(t, kind)
+	}
+
+	tests := []struct {
+		name string
+		rate int
+	}{
+		{"every other packet", 2},
+		{"one in ten", 10},
+		{"one in a hundred", 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newPacketSampler(tt.rate)
+
+			// 每10个数据包中轮流混入一个ARP、DHCP或mDNS数据包
+			discovery := []string{"arp", "dhcp", "mdns"}
+			fed := map[string]int{}
+			kept := map[string]int{}
+			for i := 0; i < tt.rate*rounds*10; i++ {
+				kind := "tcp"
+				if i%10 == 0 {
+					kind = discovery[(i/10)%len(discovery)]
+				}
+				fed[kind]++
+				if s.keep(packets[kind]) {
+					kept[kind]++
+				}
+			}
+
+			for _, kind := range discovery {
+				if kept[kind] != fed[kind] {
+					t.Errorf("kept %d of %d %s packets, want all", kept[kind], fed[kind], kind)
+				}
+			}
+
+			// 参与抽样的数据包按到达顺序确定地保留每rate个中的第一个
+			want := (fed["tcp"] + tt.rate - 1) / tt.rate
+			if kept["tcp"] != want {
+				t.Errorf("kept %d of %d tcp packets, want %d (1/%d)", kept["tcp"], fed["tcp"], want, tt.rate)
+			}
+
+			stats := s.stats()
+			total := fed["tcp"] + fed["arp"] + fed["dhcp"] + fed["mdns"]
+			sampled := kept["tcp"] + kept["arp"] + kept["dhcp"] + kept["mdns"]
+			if stats.Rate != tt.rate || stats.Total != uint64(total) || stats.Sampled != uint64(sampled) {
+				t.Errorf("stats() = %+v, want rate %d, total %d, sampled %d", stats, tt.rate, total, sampled)
+			}
+		})
+	}
+}
+
+func TestNewPacketSamplerDisabled(t *testing.T) {
+	for _, rate := range []int{-1, 0, 1} {
+		if s := newPacketSampler(rate); s != nil {
+			t.Errorf("newPacketSampler(%d) = %+v, want nil", rate, s)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// SimulateOptions 模拟流量的参数
+type SimulateOptions struct {
+	Assets int // 模拟的资产数量
+	Rate   int // 每秒生成的数据包数，0表示不限速
+	Count  int // 生成的数据包总数，0表示持续到时长结束或收到停止信号
+}
+
+// maxSimulateAssets 模拟资产使用10.0.0.1到10.255.255.253，10.255.255.254为对端地址
+const maxSimulateAssets = 1<<24 - 3
+
+// simulatePacketKinds 每个资产依次轮换发送的数据包类型数量：ARP、DHCP、服务端握手、服务Banner
+const simulatePacketKinds = 4
+
+// 模拟数据包的对端地址
+var (
+	simulateGatewayIP  = net.IPv4(10, 255, 255, 254).To4()
+	simulateGatewayMAC = net.HardwareAddr{0x02, 0xff, 0xff, 0xff, 0xff, 0xfe}
+)
+
+// simulateProfile 模拟资产的类型，决定MAC厂商、DHCP厂商标识、TTL和开放的服务
+type simulateProfile struct {
+	oui         []byte
+	vendorClass string
+	ttl         uint8
+	port        uint16
+	banner      string
+}
+
+var simulateProfiles = []simulateProfile{
+	{oui: []byte{0x02, 0x00, 0x00}, vendorClass: "MSFT 5.0", ttl: 128, port: 3389},
+	{oui: []byte{0x02, 0x00, 0x01}, vendorClass: "udhcp 1.30.1", ttl: 64, port: 22, banner: "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6\r\n"},
+	{oui: []byte{0x02, 0x00, 0x02}, vendorClass: "dhcpcd-9.4.1:Linux", ttl: 64, port: 80, banner: "HTTP/1.1 200 OK\r\nServer: nginx/1.24.0\r\nContent-Length: 0\r\n\r\n"},
+	{oui: []byte{0x00, 0x50, 0x56}, vendorClass: "udhcp 1.30.1", ttl: 64, port: 443},
+}
+
+// StartSimulation 按指定速率生成模拟资产的数据包，经解析器、资产管理器写入存储，用于在没有真实流量时压测存储和API
+// 结束后输出资产计数汇总和处理速率
+func (ce *CaptureEngine) StartSimulation(ctx context.Context, opts SimulateOptions) error {
+	if opts.Assets <= 0 {
+		return fmt.Errorf("模拟资产数量必须大于0: %d", opts.Assets)
+	}
+	if opts.Assets > maxSimulateAssets {
+		return fmt.Errorf("模拟资产数量超出10.0.0.0/8地址范围: %d", opts.Assets)
+	}
+
+	ctx, cancel := ce.runContext(ctx)
+	defer cancel()
+
+	if ce.config.Capture.Duration > 0 {
+		log.Printf("模拟将在 %v 后自动停止", ce.config.Capture.Duration)
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, ce.config.Capture.Duration)
+		defer cancelTimeout()
+	}
+
+	rate := "不限速"
+	if opts.Rate > 0 {
+		rate = fmt.Sprintf("%d 包/秒", opts.Rate)
+	}
+	log.Printf("开始模拟流量: %d 个资产，速率 %s", opts.Assets, rate)
+
+	// 模拟资产数量可能很多，结束时只输出计数汇总
+	ce.SetCountOnly(true)
+
+	start := time.Now()
+	err := ce.runOffline(ctx, simulatedPackets(ctx, opts))
+	ce.printSimulation(time.Since(start))
+	return err
+}
+
+// simulatedPackets 生成模拟数据包，达到指定数量或ctx取消时关闭返回的通道
+func simulatedPackets(ctx context.Context, opts SimulateOptions) chan gopacket.Packet {
+	packets := make(chan gopacket.Packet, 1000)
+
+	go func() {
+		defer close(packets)
+
+		// 限速时每10毫秒补发到按已运行时间应发送的数量，避免高速率下逐包定时的开销
+		var tick <-chan time.Time
+		if opts.Rate > 0 {
+			ticker := time.NewTicker(10 * time.Millisecond)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		start := time.Now()
+		sent := 0
+		for {
+			due := -1
+			if tick != nil {
+				select {
+				case <-tick:
+				case <-ctx.Done():
+					return
+				}
+				due = int(time.Since(start).Seconds() * float64(opts.Rate))
+			}
+
+			for (due < 0 || sent < due) && (opts.Count <= 0 || sent < opts.Count) {
+				select {
+				case packets <- simulatedPacket(sent, opts.Assets):
+				case <-ctx.Done():
+					return
+				}
+				sent++
+			}
+			if opts.Count > 0 && sent >= opts.Count {
+				return
+			}
+		}
+	}()
+
+	return packets
+}
+
+// simulatedPacket 生成第seq个模拟数据包，先为所有资产各发一个ARP，再依次发送DHCP、服务端握手和服务Banner
+func simulatedPacket(seq, assets int) gopacket.Packet {
+	n := seq % assets
+	kind := (seq / assets) % simulatePacketKinds
+	profile := simulateProfiles[n%len(simulateProfiles)]
+
+	host := n + 1
+	ip := net.IPv4(10, byte(host>>16), byte(host>>8), byte(host)).To4()
+	mac := append(append(net.HardwareAddr{}, profile.oui...), byte(host>>16), byte(host>>8), byte(host))
+
+	eth := &layers.Ethernet{SrcMAC: mac, DstMAC: simulateGatewayMAC, EthernetType: layers.EthernetTypeIPv4}
+	ipv4 := &layers.IPv4{Version: 4, TTL: profile.ttl, SrcIP: ip, DstIP: simulateGatewayIP}
+
+	var stack []gopacket.SerializableLayer
+	switch kind {
+	case 0:
+		eth.EthernetType = layers.EthernetTypeARP
+		stack = []gopacket.SerializableLayer{eth, &layers.ARP{
+			AddrType:          layers.LinkTypeEthernet,
+			Protocol:          layers.EthernetTypeIPv4,
+			HwAddressSize:     6,
+			ProtAddressSize:   4,
+			Operation:         layers.ARPReply,
+			SourceHwAddress:   mac,
+			SourceProtAddress: ip,
+			DstHwAddress:      simulateGatewayMAC,
+			DstProtAddress:    simulateGatewayIP,
+		}}
+	case 1:
+		ipv4.Protocol = layers.IPProtocolUDP
+		udp := &layers.UDP{SrcPort: 68, DstPort: 67}
+		udp.SetNetworkLayerForChecksum(ipv4)
+		dhcp := &layers.DHCPv4{
+			Operation:    layers.DHCPOpRequest,
+			HardwareType: layers.LinkTypeEthernet,
+			HardwareLen:  6,
+			Xid:          uint32(seq),
+			ClientIP:     ip,
+			ClientHWAddr: mac,
+			Options: layers.DHCPOptions{
+				layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{byte(layers.DHCPMsgTypeRequest)}),
+				layers.NewDHCPOption(layers.DHCPOptHostname, []byte(fmt.Sprintf("sim-host-%06d", host))),
+				layers.NewDHCPOption(layers.DHCPOptClassID, []byte(profile.vendorClass)),
+				layers.NewDHCPOption(layers.DHCPOptEnd, nil),
+			},
+		}
+		stack = []gopacket.SerializableLayer{eth, ipv4, udp, dhcp}
+	default:
+		ipv4.Protocol = layers.IPProtocolTCP
+		tcp := &layers.TCP{
+			SrcPort: layers.TCPPort(profile.port),
+			DstPort: layers.TCPPort(50000 + n%10000),
+			Seq:     1000,
+			Ack:     1,
+			ACK:     true,
+			Window:  65535,
+		}
+		tcp.SetNetworkLayerForChecksum(ipv4)
+		stack = []gopacket.SerializableLayer{eth, ipv4, tcp}
+		if kind == 2 || profile.banner == "" {
+			tcp.SYN = true
+			tcp.Seq = 999
+		} else {
+			tcp.PSH = true
+			stack = append(stack, gopacket.Payload(profile.banner))
+		}
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}, stack...); err != nil {
+		log.Printf("生成模拟数据包失败: %v", err)
+	}
+
+	packet := gopacket.NewPacket(buf.Bytes(), layers.LinkTypeEthernet, gopacket.Default)
+	m := packet.Metadata()
+	m.Timestamp = time.Now()
+	m.CaptureLength = len(buf.Bytes())
+	m.Length = len(buf.Bytes())
+	return packet
+}
+
+// printSimulation 输出模拟运行的处理速率，资产计数由计数汇总输出
+func (ce *CaptureEngine) printSimulation(elapsed time.Duration) {
+	total := atomic.LoadUint64(&ce.totalPackets)
+
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(total) / elapsed.Seconds()
+	}
+	fmt.Printf("模拟运行时长: %s，处理速率: %.0f 包/秒\n", elapsed.Round(time.Millisecond), rate)
+}
@@ -0,0 +1,84 @@
+package capture
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"assets_discovery/internal/config"
+	"assets_discovery/internal/storage"
+)
+
+// closeRecorder 记录Close调用及此时存储中的资产数，不真正关闭，之后仍可读取存储
+type closeRecorder struct {
+	storage.Storage
+	closes       int
+	storedAtStop int
+}
+
+func (c *closeRecorder) Close() error {
+	c.closes++
+	stored, _ := c.Storage.GetAllAssets()
+	c.storedAtStop = len(stored)
+	return nil
+}
+
+func TestSimulationMemoryStorage(t *testing.T) {
+	const simulated = 8
+
+	cfg := &config.Config{}
+	cfg.Storage.Type = "memory"
+	cfg.Capture.Workers = 1
+	cfg.Capture.MaxWorkers = 1
+	cfg.Parser.EnabledProtocols = []string{"arp", "dhcp", "http"}
+	ce := NewCaptureEngine(cfg)
+	recorder := &closeRecorder{Storage: ce.storage}
+	ce.storage = recorder
+
+	// 每个资产依次发送ARP、DHCP、服务端握手和服务Banner
+	opts := SimulateOptions{Assets: simulated, Count: simulated * simulatePacketKinds}
+	var err error
+	output := captureStdout(t, func() {
+		err = ce.StartSimulation(context.Background(), opts)
+	})
+	if err != nil {
+		t.Fatalf("StartSimulation() error = %v", err)
+	}
+
+	for _, want := range []string{"数据包: 32，资产: 8\n", "处理速率: "} {
+		if !strings.Contains(output, want) {
+			t.Errorf("simulation output missing %q:\n%s", want, output)
+		}
+	}
+
+	stats := ce.assetManager.GetStats()
+	if stats.TotalAssets != simulated || stats.NewAssets != simulated {
+		t.Errorf("GetStats() total = %d, new = %d, want %d", stats.TotalAssets, stats.NewAssets, simulated)
+	}
+	for _, protocol := range []string{"arp", "dhcp"} {
+		if got := stats.ParseStats[protocol]; got.Attempts != simulated || got.Errors != 0 {
+			t.Errorf("ParseStats[%s] = %+v, want %d attempts without errors", protocol, got, simulated)
+		}
+	}
+
+	// 资产管理器停止、完成最后一次保存后关闭存储
+	if recorder.closes != 1 || recorder.storedAtStop != simulated {
+		t.Errorf("storage closed %d times with %d assets stored, want once with %d", recorder.closes, recorder.storedAtStop, simulated)
+	}
+	asset, ok := ce.assetManager.GetAssetByIP("10.0.0.2")
+	if !ok || asset.Hostname != "sim-host-000002" || len(asset.OpenPorts) != 1 || asset.OpenPorts[0].Port != 22 {
+		t.Errorf("simulated asset 10.0.0.2 = %+v, want sim-host-000002 with port 22", asset)
+	}
+}
+
+func TestSimulationInvalidAssets(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Storage.NoStore = true
+	ce := NewCaptureEngine(cfg)
+
+	for _, n := range []int{0, -1, maxSimulateAssets + 1} {
+		if err := ce.StartSimulation(context.Background(), SimulateOptions{Assets: n, Count: 1}); err == nil {
+			t.Errorf("StartSimulation(assets=%d) error = nil, want error", n)
+		}
+	}
+}
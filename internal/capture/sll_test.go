@@ -0,0 +1,99 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+
+	"assets_discovery/internal/config"
+)
+
+// sllFrame 在IPv4报文前加上Linux cooked capture头部，addrType为ARPHRD类型，addr为发送方的链路层地址
+func sllFrame(addrType uint16, addr net.HardwareAddr, ipPacket []byte) []byte {
+	header := make([]byte, 16)
+	binary.BigEndian.PutUint16(header[0:2], 0) // 发往本机
+	binary.BigEndian.PutUint16(header[2:4], addrType)
+	binary.BigEndian.PutUint16(header[4:6], uint16(len(addr)))
+	copy(header[6:14], addr)
+	binary.BigEndian.PutUint16(header[14:16], uint16(layers.EthernetTypeIPv4))
+	return append(header, ipPacket...)
+}
+
+// synAck 序列化src的port端口回应的SYN-ACK报文，不含链路层
+func synAck(t *testing.T, src string, port layers.TCPPort) []byte {
+	t.Helper()
+
+	ipv4 := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolTCP, SrcIP: net.ParseIP(src).To4(), DstIP: net.IPv4(10, 0, 0, 1).To4()}
+	tcp := &layers.TCP{SrcPort: port, DstPort: 51000, SYN: true, ACK: true, Window: 65535}
+	tcp.SetNetworkLayerForChecksum(ipv4)
+	return serializeLayers(t, ipv4, tcp)
+}
+
+func TestLinuxSLLCapture(t *testing.T) {
+	var buf bytes.Buffer
+	w := pcapgo.NewWriter(&buf)
+	if err := w.WriteFileHeader(65536, layers.LinkTypeLinuxSLL); err != nil {
+		t.Fatalf("WriteFileHeader() error = %v", err)
+	}
+	frames := [][]byte{
+		// any接口上以太网设备收到的报文，头部中带有发送方MAC
+		sllFrame(1, net.HardwareAddr{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x0a}, synAck(t, "10.0.0.10", 22)),
+		// VPN等没有链路层地址的设备(ARPHRD_NONE)
+		sllFrame(0xfffe, nil, synAck(t, "10.8.0.5", 443)),
+	}
+	ts := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i, data := range frames {
+		ci := gopacket.CaptureInfo{Timestamp: ts.Add(time.Duration(i) * time.Second), CaptureLength: len(data), Length: len(data)}
+		if err := w.WritePacket(ci, data); err != nil {
+			t.Fatalf("WritePacket() error = %v", err)
+		}
+	}
+
+	stdin := os.Stdin
+	os.Stdin = pipeStream(t, buf.Bytes())
+	defer func() { os.Stdin = stdin }()
+
+	cfg := &config.Config{}
+	cfg.Storage.NoStore = true
+	cfg.Capture.Workers = 1
+	ce := NewCaptureEngine(cfg)
+	ce.SetCountOnly(true)
+	if err := ce.StartOfflineCapture(context.Background(), stdinFile); err != nil {
+		t.Fatalf("StartOfflineCapture() error = %v", err)
+	}
+
+	tests := []struct {
+		id        string
+		wantIP    string
+		wantMAC   string
+		wantPorts []int
+	}{
+		{"mac_00:1a:2b:3c:4d:0a", "10.0.0.10", "00:1a:2b:3c:4d:0a", []int{22}},
+		{"ip_10.8.0.5", "10.8.0.5", "", []int{443}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			asset, ok := ce.assetManager.GetAsset(tt.id)
+			if !ok {
+				t.Fatalf("GetAsset(%s) not found, assets = %d", tt.id, len(ce.assetManager.GetAllAssets()))
+			}
+			var ports []int
+			for _, p := range asset.OpenPorts {
+				ports = append(ports, p.Port)
+			}
+			if asset.IPAddress != tt.wantIP || asset.MACAddress != tt.wantMAC || !reflect.DeepEqual(ports, tt.wantPorts) {
+				t.Errorf("asset = %s/%s ports %v, want %s/%s ports %v",
+					asset.IPAddress, asset.MACAddress, ports, tt.wantIP, tt.wantMAC, tt.wantPorts)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package capture
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/pcapgo"
+)
+
+// stdinFile 离线分析时表示从标准输入读取的文件名，例如 tcpdump -w - | assets_discovery offline -f -
+const stdinFile = "-"
+
+// streamPackets 从数据流读取pcap或pcapng格式的数据包，不依赖libpcap
+// 数据流结束或ctx取消时关闭返回的通道
+func streamPackets(ctx context.Context, r io.Reader) (chan gopacket.Packet, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("读取数据流失败: %v", err)
+	}
+
+	if binary.BigEndian.Uint32(magic) == ngBlockSectionHeader {
+		reader, err := pcapgo.NewNgReader(br, pcapgo.NgReaderOptions{WantMixedLinkType: true, SkipUnknownVersion: true})
+		if err != nil {
+			return nil, fmt.Errorf("解析pcapng数据流失败: %v", err)
+		}
+		return ngPackets(ctx, reader), nil
+	}
+
+	reader, err := pcapgo.NewReader(br)
+	if err != nil {
+		return nil, fmt.Errorf("解析pcap数据流失败: %v", err)
+	}
+
+	packets := make(chan gopacket.Packet, 1000)
+	go func() {
+		defer close(packets)
+
+		for {
+			data, ci, err := reader.ReadPacketData()
+			if err != nil {
+				// 上游进程被中断时最后一个数据包可能不完整，同样视为数据流结束
+				if err == io.ErrUnexpectedEOF {
+					log.Printf("数据流在数据包中间结束，忽略不完整的数据包")
+				} else if err != io.EOF {
+					log.Printf("读取数据包失败: %v", err)
+				}
+				return
+			}
+
+			packet := gopacket.NewPacket(data, reader.LinkType(), gopacket.Default)
+			m := packet.Metadata()
+			m.CaptureInfo = ci
+			m.Truncated = m.Truncated || ci.CaptureLength < ci.Length
+
+			select {
+			case packets <- packet:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return packets, nil
+}
@@ -0,0 +1,219 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+
+	"assets_discovery/internal/config"
+)
+
+// pcapStream 将数据包写为pcap格式的字节流
+func pcapStream(t *testing.T, packets ...[]byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := pcapgo.NewWriter(&buf)
+	if err := w.WriteFileHeader(65536, layers.LinkTypeEthernet); err != nil {
+		t.Fatalf("WriteFileHeader() error = %v", err)
+	}
+	ts := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i, data := range packets {
+		ci := gopacket.CaptureInfo{Timestamp: ts.Add(time.Duration(i) * time.Second), CaptureLength: len(data), Length: len(data)}
+		if err := w.WritePacket(ci, data); err != nil {
+			t.Fatalf("WritePacket() error = %v", err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// pipeStream 通过管道分块写入data，模拟上游tcpdump持续输出，返回管道的读端
+func pipeStream(t *testing.T, data []byte) *os.File {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe() error = %v", err)
+	}
+	t.Cleanup(func() { r.Close() })
+
+	go func() {
+		defer w.Close()
+		for len(data) > 0 {
+			n := 7
+			if n > len(data) {
+				n = len(data)
+			}
+			if _, err := w.Write(data[:n]); err != nil {
+				return
+			}
+			data = data[n:]
+			time.Sleep(100 * time.Microsecond)
+		}
+	}()
+	return r
+}
+
+// collect 读取通道中的全部数据包，超时视为数据流结束处理错误
+func collect(t *testing.T, packets chan gopacket.Packet) []gopacket.Packet {
+	t.Helper()
+
+	var result []gopacket.Packet
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case packet, ok := <-packets:
+			if !ok {
+				return result
+			}
+			result = append(result, packet)
+		case <-timeout:
+			t.Fatal("packet channel not closed at end of stream")
+		}
+	}
+}
+
+func TestStreamPackets(t *testing.T) {
+	fixture := ngFixture(t)
+	arp := fixture[0].data
+	stream := pcapStream(t, arp, arp, arp)
+
+	tests := []struct {
+		name      string
+		data      []byte
+		wantCount int
+		wantIface string
+	}{
+		{"pcap", stream, 3, ""},
+		// 上游被中断时最后一个数据包不完整
+		{"truncated last packet", stream[:len(stream)-5], 2, ""},
+		{"header only", stream[:24], 0, ""},
+		{"pcapng", mustReadFile(t, writePcapNG(t, nil, fixture[0])), 1, "eth0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packets, err := streamPackets(context.Background(), pipeStream(t, tt.data))
+			if err != nil {
+				t.Fatalf("streamPackets() error = %v", err)
+			}
+
+			got := collect(t, packets)
+			if len(got) != tt.wantCount {
+				t.Fatalf("packets = %d, want %d", len(got), tt.wantCount)
+			}
+			for _, packet := range got {
+				if packet.Layer(layers.LayerTypeARP) == nil {
+					t.Errorf("packet layers = %v, want ARP", packet.Layers())
+				}
+				if iface := packetInterface(packet); iface != tt.wantIface {
+					t.Errorf("packetInterface() = %q, want %q", iface, tt.wantIface)
+				}
+			}
+			if len(got) > 1 && !got[1].Metadata().Timestamp.After(got[0].Metadata().Timestamp) {
+				t.Errorf("timestamps = %v, %v, want capture order", got[0].Metadata().Timestamp, got[1].Metadata().Timestamp)
+			}
+		})
+	}
+}
+
+func TestStreamPacketsInvalid(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"empty":      nil,
+		"not a pcap": []byte("GET / HTTP/1.1\r\n\r\n..........................."),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := streamPackets(context.Background(), bytes.NewReader(data)); err == nil {
+				t.Error("streamPackets() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestStreamPacketsCancel(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+
+	header := pcapStream(t)
+	record := pcapStream(t, ngFixture(t)[0].data)[len(header):]
+	go func() {
+		// 上游持续输出，直到读端关闭
+		w.Write(header)
+		for {
+			if _, err := w.Write(record); err != nil {
+				return
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	packets, err := streamPackets(ctx, r)
+	if err != nil {
+		t.Fatalf("streamPackets() error = %v", err)
+	}
+	<-packets
+	cancel()
+
+	// 取消后通道关闭，之后只可能收到取消前已缓冲的数据包
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-packets:
+			if !ok {
+				r.Close()
+				return
+			}
+		case <-deadline:
+			t.Fatal("packet channel not closed after cancel")
+		}
+	}
+}
+
+func TestStartOfflineCaptureStdin(t *testing.T) {
+	stdin := os.Stdin
+	os.Stdin = pipeStream(t, pcapStream(t, ngFixture(t)[0].data))
+	defer func() { os.Stdin = stdin }()
+
+	cfg := &config.Config{}
+	cfg.Storage.NoStore = true
+	cfg.Capture.Workers = 1
+	cfg.Parser.EnabledProtocols = []string{"arp"}
+	ce := NewCaptureEngine(cfg)
+	ce.SetCountOnly(true)
+
+	done := make(chan error, 1)
+	go func() { done <- ce.StartOfflineCapture(context.Background(), stdinFile) }()
+
+	// 数据流结束后分析自行结束
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("StartOfflineCapture() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		ce.Stop()
+		t.Fatal("StartOfflineCapture() did not return at end of stream")
+	}
+
+	if _, ok := ce.assetManager.GetAsset("mac_00:1a:2b:3c:4d:01"); !ok {
+		t.Error("asset from stdin stream not found")
+	}
+}
+
+// mustReadFile 读取测试生成的文件
+func mustReadFile(t *testing.T, path string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	return data
+}
@@ -0,0 +1,116 @@
+package capture
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"sync/atomic"
+
+	"assets_discovery/internal/metrics"
+)
+
+// printSummary 输出本次分析发现的资产汇总，用于不保存资产的分析模式
+func (ce *CaptureEngine) printSummary() {
+	stats := ce.assetManager.GetStats()
+
+	fmt.Println("========== 资产分析汇总 ==========")
+	fmt.Printf("开始时间: %s，运行时长: %s\n", stats.StartTime.Format("2006-01-02 15:04:05"), stats.Uptime)
+	fmt.Printf("资产总数: %d，活跃资产: %d\n", stats.TotalAssets, stats.ActiveAssets)
+	printDistribution("设备类型", stats.DeviceTypes)
+	printDistribution("操作系统", stats.OSDistribution)
+
+	all := ce.assetManager.GetAllAssets()
+	summaries := make([]map[string]interface{}, 0, len(all))
+	for _, asset := range all {
+		summaries = append(summaries, asset.GetSummary())
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return compareIP(summaries[i]["ip_address"].(string), summaries[j]["ip_address"].(string)) < 0
+	})
+
+	fmt.Printf("发现的资产 (%d):\n", len(summaries))
+	fmt.Printf("  %-16s %-18s %-24s %-12s %-16s %s\n", "IP地址", "MAC地址", "主机名", "设备类型", "操作系统", "风险")
+	for _, summary := range summaries {
+		fmt.Printf("  %-16v %-18v %-24v %-12v %-16v %.1f\n",
+			summary["ip_address"], summary["mac_address"], summary["hostname"],
+			summary["device_type"], summary["os_family"], summary["risk_score"])
+	}
+}
+
+// pushMetrics 配置了Pushgateway时推送本次运行结束时的资产统计
+func (ce *CaptureEngine) pushMetrics() {
+	cfg := ce.config.Metrics
+	if cfg.PushgatewayURL == "" {
+		return
+	}
+
+	if err := metrics.Push(cfg.PushgatewayURL, cfg.Job, ce.assetManager.GetStats()); err != nil {
+		log.Printf("推送指标到Pushgateway失败: %v", err)
+		return
+	}
+	log.Printf("已推送指标到Pushgateway: %s (job=%s)", cfg.PushgatewayURL, cfg.Job)
+}
+
+// topServices 计数汇总中输出的服务数量
+const topServices = 10
+
+// printCounts 只输出计数汇总，不列出每个资产
+func (ce *CaptureEngine) printCounts() {
+	stats := ce.assetManager.GetStats()
+	tally := ce.assetManager.Tally()
+
+	fmt.Println("========== pcap计数汇总 ==========")
+	fmt.Printf("数据包: %d，资产: %d\n", atomic.LoadUint64(&ce.totalPackets), stats.TotalAssets)
+	fmt.Printf("唯一IP地址: %d，唯一MAC地址: %d\n", tally.UniqueIPs, tally.UniqueMACs)
+	printDistribution("设备类型", stats.DeviceTypes)
+	printTop("服务", tally.Services, topServices)
+	printDistribution("协议", tally.Protocols)
+}
+
+// printDistribution 按数量从多到少输出分布
+func printDistribution(title string, counts map[string]int) {
+	printTop(title, counts, 0)
+}
+
+// printTop 按数量从多到少输出前limit项，limit为0时全部输出
+func printTop(title string, counts map[string]int, limit int) {
+	if len(counts) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	fmt.Printf("%s:\n", title)
+	for _, k := range keys {
+		fmt.Printf("  %-20s %d\n", k, counts[k])
+	}
+}
+
+// compareIP 按地址数值比较IP，无法解析的地址按字符串比较并排在最后
+func compareIP(a, b string) int {
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	switch {
+	case ipA != nil && ipB != nil:
+		return bytes.Compare(ipA.To16(), ipB.To16())
+	case ipA != nil:
+		return -1
+	case ipB != nil:
+		return 1
+	}
+	return bytes.Compare([]byte(a), []byte(b))
+}
@@ -0,0 +1,148 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+
+	"assets_discovery/internal/assets"
+	"assets_discovery/internal/config"
+)
+
+// captureStdout 执行fn并返回其写入标准输出的内容
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe() error = %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+
+	fn()
+	w.Close()
+	return <-output
+}
+
+// triagePcap 写入包含三台主机的pcapng文件：只发送ARP的10.0.0.1、
+// 发送ARP并在22端口提供SSH服务的10.0.0.2，以及回应80端口连接的10.0.0.3
+func triagePcap(t *testing.T) string {
+	t.Helper()
+
+	hosts := []net.HardwareAddr{
+		{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x01},
+		{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x02},
+		{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x03},
+	}
+	arp := func(i int) []byte {
+		return serializeLayers(t,
+			&layers.Ethernet{SrcMAC: hosts[i], DstMAC: layers.EthernetBroadcast, EthernetType: layers.EthernetTypeARP},
+			&layers.ARP{
+				AddrType: layers.LinkTypeEthernet, Protocol: layers.EthernetTypeIPv4,
+				HwAddressSize: 6, ProtAddressSize: 4, Operation: layers.ARPRequest,
+				SourceHwAddress: hosts[i], SourceProtAddress: []byte{10, 0, 0, byte(i + 1)},
+				DstHwAddress: make([]byte, 6), DstProtAddress: []byte{10, 0, 0, 254},
+			})
+	}
+	tcp := func(i int, port layers.TCPPort, syn bool, payload string) []byte {
+		ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolTCP,
+			SrcIP: net.IP{10, 0, 0, byte(i + 1)}, DstIP: net.IP{10, 0, 0, 254}}
+		segment := &layers.TCP{SrcPort: port, DstPort: 51000, SYN: syn, ACK: true, PSH: payload != "", Window: 65535}
+		segment.SetNetworkLayerForChecksum(ip)
+		return serializeLayers(t,
+			&layers.Ethernet{SrcMAC: hosts[i], DstMAC: hosts[0], EthernetType: layers.EthernetTypeIPv4},
+			ip, segment, gopacket.Payload(payload))
+	}
+
+	var buf bytes.Buffer
+	w, err := pcapgo.NewNgWriter(&buf, layers.LinkTypeEthernet)
+	if err != nil {
+		t.Fatalf("NewNgWriter() error = %v", err)
+	}
+	ts := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	packets := [][]byte{
+		arp(0), arp(0), arp(1),
+		tcp(1, 22, true, ""),
+		tcp(1, 22, false, "SSH-2.0-OpenSSH_9.3\r\n"),
+		tcp(2, 80, true, ""),
+	}
+	for i, data := range packets {
+		ci := gopacket.CaptureInfo{Timestamp: ts.Add(time.Duration(i) * time.Second), CaptureLength: len(data), Length: len(data)}
+		if err := w.WritePacket(ci, data); err != nil {
+			t.Fatalf("WritePacket() error = %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "triage.pcapng")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+func TestCountOnlySummary(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Storage.NoStore = true
+	cfg.Capture.Workers = 1
+	cfg.Capture.MaxWorkers = 1
+	cfg.Parser.EnabledProtocols = []string{"arp", "http"}
+	ce := NewCaptureEngine(cfg)
+	ce.SetCountOnly(true)
+
+	path := triagePcap(t)
+	var err error
+	output := captureStdout(t, func() {
+		err = ce.StartOfflineCapture(context.Background(), path)
+	})
+	if err != nil {
+		t.Fatalf("StartOfflineCapture() error = %v", err)
+	}
+
+	tally := ce.assetManager.Tally()
+	wantTally := assets.AssetTally{
+		UniqueIPs:  3,
+		UniqueMACs: 3,
+		Services:   map[string]int{"22/tcp": 1, "80/tcp": 1},
+		Protocols:  map[string]int{"arp": 2, "ipv4": 2, "tcp": 2},
+	}
+	if !reflect.DeepEqual(tally, wantTally) {
+		t.Errorf("Tally() = %+v, want %+v", tally, wantTally)
+	}
+
+	for _, want := range []string{
+		"数据包: 6，资产: 3\n",
+		"唯一IP地址: 3，唯一MAC地址: 3\n",
+		"服务:\n  22/tcp               1\n  80/tcp               1\n",
+		"协议:\n  arp                  2\n  ipv4                 2\n  tcp                  2\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("summary missing %q:\n%s", want, output)
+		}
+	}
+	// 计数汇总不逐条列出资产
+	if strings.Contains(output, "发现的资产") {
+		t.Errorf("count-only summary lists assets:\n%s", output)
+	}
+}
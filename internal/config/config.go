@@ -20,6 +20,63 @@ type Config struct {
 	Storage  StorageConfig  `yaml:"storage" mapstructure:"storage"`
 	Server   ServerConfig   `yaml:"server" mapstructure:"server"`
 	Alerting AlertingConfig `yaml:"alerting" mapstructure:"alerting"`
+
+	Enrichment EnrichmentConfig `yaml:"enrichment" mapstructure:"enrichment"`
+	Logging    LoggingConfig    `yaml:"logging" mapstructure:"logging"`
+	Risk       RiskConfig       `yaml:"risk" mapstructure:"risk"`
+	Metrics    MetricsConfig    `yaml:"metrics" mapstructure:"metrics"`
+	Export     ExportConfig     `yaml:"export" mapstructure:"export"`
+}
+
+// ExportConfig 导出格式配置，用于export命令和API导出
+type ExportConfig struct {
+	// 将os_info、open_ports等嵌套结构展开为扁平字段，csv格式总是展开
+	Flatten bool `yaml:"flatten" mapstructure:"flatten"`
+	// 输出的扁平字段及顺序，为空时json输出全部字段，csv输出默认列
+	Fields []string `yaml:"fields" mapstructure:"fields"`
+	// 扁平字段重命名，键为展开后的字段名，值为输出的字段名
+	FieldNames map[string]string `yaml:"field_names" mapstructure:"field_names"`
+}
+
+// MetricsConfig 指标推送配置，用于无法被抓取的短时离线分析
+type MetricsConfig struct {
+	PushgatewayURL string `yaml:"pushgateway_url" mapstructure:"pushgateway_url"` // Pushgateway地址，为空时不推送
+	Job            string `yaml:"job" mapstructure:"job"`
+}
+
+// RiskConfig 资产风险评分配置，总分上限为10
+type RiskConfig struct {
+	PortScores map[string]float64 `yaml:"port_scores" mapstructure:"port_scores"` // 开放端口号对应的分值
+	EOLOS      []string           `yaml:"eol_os" mapstructure:"eol_os"`           // 已停止支持的操作系统关键字，匹配操作系统类别和版本
+	EOLOSScore float64            `yaml:"eol_os_score" mapstructure:"eol_os_score"`
+	// 在port_scores未覆盖的端口上识别出Telnet协商时的分值，如IoT设备常用的2323端口
+	TelnetScore float64 `yaml:"telnet_score" mapstructure:"telnet_score"`
+}
+
+// defaultRiskPortScores 默认的高风险端口分值：明文管理协议、常被直接暴露的远程桌面/文件共享以及常见无认证部署的数据库
+var defaultRiskPortScores = map[string]float64{
+	"21":    2, // FTP
+	"23":    4, // Telnet
+	"139":   2, // NetBIOS
+	"445":   3, // SMB
+	"1433":  2, // MSSQL
+	"3306":  2, // MySQL
+	"3389":  3, // RDP
+	"5432":  2, // PostgreSQL
+	"5900":  3, // VNC
+	"6379":  4, // Redis
+	"9200":  3, // Elasticsearch
+	"11211": 3, // Memcached
+	"27017": 4, // MongoDB
+}
+
+// defaultEOLOS 默认的已停止支持操作系统关键字，"msft 5.0"为Windows 2000/XP/2003的DHCP厂商标识
+var defaultEOLOS = []string{"windows xp", "windows 2000", "windows server 2003", "windows server 2008", "windows 7", "msft 5.0"}
+
+// LoggingConfig 日志配置
+type LoggingConfig struct {
+	// 资产更新等高频日志的汇总周期，同一资产在周期内只输出一条，其余在周期结束时汇总；0表示不限流
+	SummaryInterval time.Duration `yaml:"summary_interval" mapstructure:"summary_interval"`
 }
 
 // CaptureConfig 流量捕获配置
@@ -29,7 +86,34 @@ type CaptureConfig struct {
 	Promiscuous bool          `yaml:"promiscuous" mapstructure:"promiscuous"`
 	Timeout     time.Duration `yaml:"timeout" mapstructure:"timeout"`
 	BufferSize  int           `yaml:"buffer_size" mapstructure:"buffer_size"`
-	Workers     int           `yaml:"workers" mapstructure:"workers"`
+	Workers     int           `yaml:"workers" mapstructure:"workers"`         // 最少工作协程数
+	MaxWorkers  int           `yaml:"max_workers" mapstructure:"max_workers"` // 积压时最多扩展到的工作协程数，0表示CPU核心数
+	Duration    time.Duration `yaml:"duration" mapstructure:"duration"`       // 捕获时长，0表示持续运行
+	// snap_len小于已启用协议所需长度时自动调大
+	AutoSnapLen bool `yaml:"auto_snap_len" mapstructure:"auto_snap_len"`
+	// 在trunk端口等带802.1Q标签的环境中，BPF过滤器同时匹配带VLAN标签和不带标签的数据包
+	VLAN bool `yaml:"vlan" mapstructure:"vlan"`
+	// 每N个数据包只处理1个，ARP、DHCP、mDNS数据包始终处理；不大于1时处理所有数据包
+	SampleRate int `yaml:"sample_rate" mapstructure:"sample_rate"`
+	// 丢包率持续较高时收紧BPF过滤器，只捕获核心协议
+	AdaptiveFilter AdaptiveFilterConfig `yaml:"adaptive_filter" mapstructure:"adaptive_filter"`
+	// 实时捕获后端：pcap使用libpcap；afpacket在Linux上打开workers个加入同一fanout组的AF_PACKET套接字，其他系统回退到pcap
+	Engine   string `yaml:"engine" mapstructure:"engine"`
+	FanoutID int    `yaml:"fanout_id" mapstructure:"fanout_id"` // afpacket的fanout组ID，0表示按进程号生成；同一主机上的多个实例需使用不同的ID
+	// 启动时枚举本机网卡的MAC和IP地址，源地址属于采集主机的数据包不产生资产
+	ExcludeSelf bool `yaml:"exclude_self" mapstructure:"exclude_self"`
+}
+
+// AdaptiveFilterConfig 自适应BPF过滤器配置，只用于实时捕获
+type AdaptiveFilterConfig struct {
+	Enabled  bool          `yaml:"enabled" mapstructure:"enabled"`
+	Interval time.Duration `yaml:"interval" mapstructure:"interval"` // 检查丢包统计的间隔
+	// 丢包率(0-1)连续sustain个间隔高于high_drop_rate时收紧过滤器，连续sustain个间隔低于low_drop_rate时恢复
+	HighDropRate float64 `yaml:"high_drop_rate" mapstructure:"high_drop_rate"`
+	LowDropRate  float64 `yaml:"low_drop_rate" mapstructure:"low_drop_rate"`
+	Sustain      int     `yaml:"sustain" mapstructure:"sustain"`
+	// 收紧后仍然捕获的协议，只有同时在enabled_protocols中启用的才会保留
+	CoreProtocols []string `yaml:"core_protocols" mapstructure:"core_protocols"`
 }
 
 // ParserConfig 协议解析配置
@@ -37,6 +121,41 @@ type ParserConfig struct {
 	EnabledProtocols []string `yaml:"enabled_protocols" mapstructure:"enabled_protocols"`
 	MaxPackets       int      `yaml:"max_packets" mapstructure:"max_packets"`
 	AssetTimeout     int      `yaml:"asset_timeout" mapstructure:"asset_timeout"` // 资产超时时间(分钟)
+	PurgeAfter       int      `yaml:"purge_after" mapstructure:"purge_after"`     // 非活跃资产保留天数，0表示永不清除
+	PortTimeout      int      `yaml:"port_timeout" mapstructure:"port_timeout"`   // 开放端口超过该时间(分钟)未再出现时标记为closed，0表示不标记
+	// 资产置信度(0-1)达到该值后才保存和告警，低于该值的资产只保留在内存中，0表示不限制
+	MinConfidence float64 `yaml:"min_confidence" mapstructure:"min_confidence"`
+	// 监控的网段(CIDR)，为空时不限制；网段外的IP按out_of_scope处理：ignore不产生资产，tag产生资产并标记为范围外
+	MonitoredNetworks []string `yaml:"monitored_networks" mapstructure:"monitored_networks"`
+	OutOfScope        string   `yaml:"out_of_scope" mapstructure:"out_of_scope"`
+	// 按设备类型覆盖资产超时时间(分钟)，未配置的类型使用asset_timeout
+	DeviceTimeouts map[string]int `yaml:"device_timeouts" mapstructure:"device_timeouts"`
+	// 自定义服务指纹文件，规则优先于内置规则
+	ServiceProbesFile string `yaml:"service_probes_file" mapstructure:"service_probes_file"`
+	// 服务横幅的最大长度(字节)，超出时截断，0表示只受1024字节的报文检查范围限制
+	MaxBannerLength int `yaml:"max_banner_length" mapstructure:"max_banner_length"`
+	// 调试转储文件，解析失败的数据包写入该pcap文件，为空时不转储
+	DebugDump string `yaml:"debug_dump" mapstructure:"debug_dump"`
+	// TCP流重组，HTTP头部和TLS ClientHello跨多个报文时重组后再解析
+	Reassembly ReassemblyConfig `yaml:"reassembly" mapstructure:"reassembly"`
+	// 按802.1Q VLAN ID筛选数据包：include_vlans非空时只处理其中的VLAN，exclude_vlans中的VLAN被丢弃，0表示不带标签的帧
+	IncludeVLANs []int `yaml:"include_vlans" mapstructure:"include_vlans"`
+	ExcludeVLANs []int `yaml:"exclude_vlans" mapstructure:"exclude_vlans"`
+	// 将同一台物理主机的多个资产（绑定网卡、多宿主服务器）归并为主机，通过 /api/hosts 查询
+	HostCorrelation HostCorrelationConfig `yaml:"host_correlation" mapstructure:"host_correlation"`
+}
+
+// HostCorrelationConfig 主机关联配置
+type HostCorrelationConfig struct {
+	Enabled bool   `yaml:"enabled" mapstructure:"enabled"`
+	Key     string `yaml:"key" mapstructure:"key"` // hostname: 按主机名关联；duid: 按DHCP客户端标识中的DUID关联
+}
+
+// ReassemblyConfig TCP流重组配置
+type ReassemblyConfig struct {
+	Enabled      bool          `yaml:"enabled" mapstructure:"enabled"`
+	MaxStreams   int           `yaml:"max_streams" mapstructure:"max_streams"`     // 同时缓存数据的流数量上限，超出的连接逐包解析
+	FlushTimeout time.Duration `yaml:"flush_timeout" mapstructure:"flush_timeout"` // 连接超过该时间没有数据时丢弃其缓存
 }
 
 // StorageConfig 存储配置
@@ -44,6 +163,12 @@ type StorageConfig struct {
 	Type          string     `yaml:"type" mapstructure:"type"` // elasticsearch, file, memory
 	Elasticsearch ESConfig   `yaml:"elasticsearch" mapstructure:"elasticsearch"`
 	File          FileConfig `yaml:"file" mapstructure:"file"`
+	// Kafka输出，启用后资产在写入主存储的同时发布到Kafka
+	Kafka KafkaConfig `yaml:"kafka" mapstructure:"kafka"`
+	// 假名化，启用后MAC地址、主机名等识别信息在写入存储和Kafka前被替换
+	Pseudonymize PseudonymizeConfig `yaml:"pseudonymize" mapstructure:"pseudonymize"`
+	// 只分析不保存：资产仅保存在内存中，结束时输出汇总，不写入任何存储
+	NoStore bool `yaml:"no_store" mapstructure:"no_store"`
 }
 
 // ESConfig Elasticsearch配置
@@ -52,26 +177,120 @@ type ESConfig struct {
 	Username string   `yaml:"username" mapstructure:"username"`
 	Password string   `yaml:"password" mapstructure:"password"`
 	Index    string   `yaml:"index" mapstructure:"index"`
+	// 索引策略：static使用固定索引；daily按UTC日期写入 index-2006.01.02，index作为指向所有日索引的别名
+	IndexStrategy string `yaml:"index_strategy" mapstructure:"index_strategy"`
+
+	// TLS配置
+	CACert     string `yaml:"ca_cert" mapstructure:"ca_cert"`         // CA证书路径
+	ClientCert string `yaml:"client_cert" mapstructure:"client_cert"` // 客户端证书路径
+	ClientKey  string `yaml:"client_key" mapstructure:"client_key"`   // 客户端私钥路径
+	// 跳过服务端证书校验，会使连接易受中间人攻击，仅用于测试环境
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify" mapstructure:"insecure_skip_verify"`
+
+	// 重试配置
+	MaxRetries   int           `yaml:"max_retries" mapstructure:"max_retries"`     // 单次请求的最大重试次数
+	RetryBackoff time.Duration `yaml:"retry_backoff" mapstructure:"retry_backoff"` // 首次重试的等待时间，之后指数增长
 }
 
 // FileConfig 文件存储配置
 type FileConfig struct {
 	OutputDir string `yaml:"output_dir" mapstructure:"output_dir"`
-	Format    string `yaml:"format" mapstructure:"format"` // json, csv
+	Format    string `yaml:"format" mapstructure:"format"` // json, csv, ndjson
+	// 文件滚动策略：none写入单个文件；daily按UTC日期写入 assets-2006-01-02.json，每个文件是当天结束时的完整资产清单
+	Rotation string `yaml:"rotation" mapstructure:"rotation"`
+}
+
+// KafkaConfig Kafka输出配置
+type KafkaConfig struct {
+	Enabled bool     `yaml:"enabled" mapstructure:"enabled"`
+	Brokers []string `yaml:"brokers" mapstructure:"brokers"`
+	Topic   string   `yaml:"topic" mapstructure:"topic"`
+
+	// SASL认证，用户名为空时不认证
+	Username      string `yaml:"username" mapstructure:"username"`
+	Password      string `yaml:"password" mapstructure:"password"`
+	SASLMechanism string `yaml:"sasl_mechanism" mapstructure:"sasl_mechanism"` // plain, scram-sha-256, scram-sha-512
+
+	// TLS配置
+	TLS                bool   `yaml:"tls" mapstructure:"tls"`
+	CACert             string `yaml:"ca_cert" mapstructure:"ca_cert"`
+	ClientCert         string `yaml:"client_cert" mapstructure:"client_cert"`
+	ClientKey          string `yaml:"client_key" mapstructure:"client_key"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify" mapstructure:"insecure_skip_verify"`
+
+	// 批量发送配置
+	BatchSize    int           `yaml:"batch_size" mapstructure:"batch_size"`
+	BatchTimeout time.Duration `yaml:"batch_timeout" mapstructure:"batch_timeout"`
+}
+
+// PseudonymizeConfig 存储假名化配置，用于有数据处理合规要求的部署
+type PseudonymizeConfig struct {
+	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+	// HMAC密钥，同一密钥下同一设备的假名保持不变；更换密钥后所有资产会以新的ID重新保存
+	Key string `yaml:"key" mapstructure:"key"`
 }
 
 // ServerConfig Web服务配置
 type ServerConfig struct {
-	Port    int  `yaml:"port" mapstructure:"port"`
+	Port      int    `yaml:"port" mapstructure:"port"`
+	Enabled   bool   `yaml:"enabled" mapstructure:"enabled"`
+	Bind      string `yaml:"bind" mapstructure:"bind"`             // 监听地址，为空时监听所有网卡
+	AuthToken string `yaml:"auth_token" mapstructure:"auth_token"` // /api 接口的访问令牌，为空时不认证
+
+	// gRPC接口，与REST API共用监听地址和访问令牌
+	GRPC GRPCConfig `yaml:"grpc" mapstructure:"grpc"`
+}
+
+// GRPCConfig gRPC接口配置
+type GRPCConfig struct {
 	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+	Port    int  `yaml:"port" mapstructure:"port"`
 }
 
 // AlertingConfig 告警配置
 type AlertingConfig struct {
-	Enabled    bool     `yaml:"enabled" mapstructure:"enabled"`
-	WebhookURL string   `yaml:"webhook_url" mapstructure:"webhook_url"`
-	EmailTo    []string `yaml:"email_to" mapstructure:"email_to"`
-	AlertRules []string `yaml:"alert_rules" mapstructure:"alert_rules"`
+	Enabled         bool     `yaml:"enabled" mapstructure:"enabled"`
+	WebhookURL      string   `yaml:"webhook_url" mapstructure:"webhook_url"`
+	SlackWebhookURL string   `yaml:"slack_webhook_url" mapstructure:"slack_webhook_url"`
+	TeamsWebhookURL string   `yaml:"teams_webhook_url" mapstructure:"teams_webhook_url"`
+	EmailTo         []string `yaml:"email_to" mapstructure:"email_to"`
+	AlertRules      []string `yaml:"alert_rules" mapstructure:"alert_rules"`
+	KnownAssets     []string `yaml:"known_assets" mapstructure:"known_assets"` // 已知资产的MAC、IP或CIDR，不产生新资产和规则告警
+}
+
+// EnrichmentConfig 公网IP信息补充配置
+type EnrichmentConfig struct {
+	Enabled       bool   `yaml:"enabled" mapstructure:"enabled"`
+	Provider      string `yaml:"provider" mapstructure:"provider"`             // none, maxmind
+	GeoIPDatabase string `yaml:"geoip_database" mapstructure:"geoip_database"` // GeoLite2-City/Country.mmdb路径
+	ASNDatabase   string `yaml:"asn_database" mapstructure:"asn_database"`     // GeoLite2-ASN.mmdb路径
+	// 对新发现的IP主动发起一次PTR查询以补全主机名，会产生DNS流量，与enabled无关单独开启
+	ReverseDNS     bool `yaml:"reverse_dns" mapstructure:"reverse_dns"`
+	ReverseDNSRate int  `yaml:"reverse_dns_rate" mapstructure:"reverse_dns_rate"` // 每秒最多查询次数
+	// 补充流水线，启用后各步骤在独立的工作协程池中按顺序执行，取代资产更新时的同步查询和reverse_dns选项
+	Pipeline EnrichmentPipelineConfig `yaml:"pipeline" mapstructure:"pipeline"`
+}
+
+// EnrichmentPipelineConfig 信息补充流水线配置
+type EnrichmentPipelineConfig struct {
+	Enabled   bool `yaml:"enabled" mapstructure:"enabled"`
+	Workers   int  `yaml:"workers" mapstructure:"workers"`       // 同时处理的IP数
+	QueueSize int  `yaml:"queue_size" mapstructure:"queue_size"` // 等待补充的IP队列长度，队列满时丢弃新请求
+	// 按列表顺序执行的步骤
+	Stages []EnrichmentStageConfig `yaml:"stages" mapstructure:"stages"`
+}
+
+// EnrichmentStageConfig 补充流水线中的单个步骤
+type EnrichmentStageConfig struct {
+	Name    string        `yaml:"name" mapstructure:"name"` // geoip, reverse_dns
+	Enabled bool          `yaml:"enabled" mapstructure:"enabled"`
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"` // 单次查询的超时时间，超时后放弃结果继续执行后续步骤
+}
+
+// defaultEnrichmentStages 默认的补充步骤：geoip查询本地数据库，reverse_dns会产生DNS流量，默认关闭
+var defaultEnrichmentStages = []EnrichmentStageConfig{
+	{Name: "geoip", Enabled: true, Timeout: 500 * time.Millisecond},
+	{Name: "reverse_dns", Enabled: false, Timeout: 2 * time.Second},
 }
 
 // GetConfig 获取全局配置
@@ -101,24 +320,95 @@ func SetDefaults() {
 	viper.SetDefault("capture.timeout", "30s")
 	viper.SetDefault("capture.buffer_size", 2097152) // 2MB
 	viper.SetDefault("capture.workers", 4)
+	viper.SetDefault("capture.max_workers", 0)
+	viper.SetDefault("capture.duration", "0s")
+	viper.SetDefault("capture.auto_snap_len", false)
+	viper.SetDefault("capture.vlan", false)
+	viper.SetDefault("capture.sample_rate", 1)
+	viper.SetDefault("capture.adaptive_filter.enabled", false)
+	viper.SetDefault("capture.adaptive_filter.interval", "10s")
+	viper.SetDefault("capture.adaptive_filter.high_drop_rate", 0.05)
+	viper.SetDefault("capture.adaptive_filter.low_drop_rate", 0.01)
+	viper.SetDefault("capture.adaptive_filter.sustain", 3)
+	viper.SetDefault("capture.adaptive_filter.core_protocols", []string{"arp", "dhcp"})
+	viper.SetDefault("capture.engine", "pcap")
+	viper.SetDefault("capture.fanout_id", 0)
+	viper.SetDefault("capture.exclude_self", false)
 
 	// 解析配置默认值
-	viper.SetDefault("parser.enabled_protocols", []string{"arp", "dhcp", "http", "https", "dns", "smb", "mdns"})
-	viper.SetDefault("parser.max_packets", 0)    // 0表示无限制
-	viper.SetDefault("parser.asset_timeout", 30) // 30分钟
+	viper.SetDefault("parser.enabled_protocols", []string{"arp", "dhcp", "http", "https", "dns", "smb", "mdns", "rdp", "llmnr", "nbns", "vxlan", "gre", "stun", "igmp", "telnet", "redis"})
+	viper.SetDefault("parser.max_packets", 0)     // 0表示无限制
+	viper.SetDefault("parser.asset_timeout", 30)  // 30分钟
+	viper.SetDefault("parser.purge_after", 0)     // 0表示永不清除
+	viper.SetDefault("parser.port_timeout", 1440) // 1天
+	viper.SetDefault("parser.min_confidence", 0.0)
+	viper.SetDefault("parser.monitored_networks", []string{})
+	viper.SetDefault("parser.out_of_scope", "ignore")
+	viper.SetDefault("parser.max_banner_length", 256)
+	viper.SetDefault("parser.device_timeouts", map[string]int{})
+	viper.SetDefault("parser.include_vlans", []int{})
+	viper.SetDefault("parser.exclude_vlans", []int{})
+	viper.SetDefault("parser.reassembly.enabled", true)
+	viper.SetDefault("parser.reassembly.max_streams", 4096)
+	viper.SetDefault("parser.reassembly.flush_timeout", 30*time.Second)
+	viper.SetDefault("parser.host_correlation.enabled", false)
+	viper.SetDefault("parser.host_correlation.key", "hostname")
 
 	// 存储配置默认值
 	viper.SetDefault("storage.type", "file")
+	viper.SetDefault("storage.no_store", false)
 	viper.SetDefault("storage.file.output_dir", "./output")
 	viper.SetDefault("storage.file.format", "json")
+	viper.SetDefault("storage.file.rotation", "none")
 	viper.SetDefault("storage.elasticsearch.index", "assets")
+	viper.SetDefault("storage.elasticsearch.index_strategy", "static")
+	viper.SetDefault("storage.elasticsearch.insecure_skip_verify", false)
+	viper.SetDefault("storage.elasticsearch.max_retries", 3)
+	viper.SetDefault("storage.elasticsearch.retry_backoff", "500ms")
+	viper.SetDefault("storage.kafka.enabled", false)
+	viper.SetDefault("storage.kafka.topic", "assets")
+	viper.SetDefault("storage.kafka.sasl_mechanism", "plain")
+	viper.SetDefault("storage.kafka.batch_size", 100)
+	viper.SetDefault("storage.kafka.batch_timeout", "1s")
+	viper.SetDefault("storage.pseudonymize.enabled", false)
 
 	// 服务配置默认值
 	viper.SetDefault("server.port", 8080)
 	viper.SetDefault("server.enabled", true)
+	viper.SetDefault("server.bind", "")
+	viper.SetDefault("server.auth_token", "")
+	viper.SetDefault("server.grpc.enabled", false)
+	viper.SetDefault("server.grpc.port", 9090)
 
 	// 告警配置默认值
 	viper.SetDefault("alerting.enabled", false)
+	viper.SetDefault("alerting.known_assets", []string{})
+
+	// 信息补充默认值
+	viper.SetDefault("enrichment.enabled", false)
+	viper.SetDefault("enrichment.provider", "none")
+	viper.SetDefault("enrichment.reverse_dns", false)
+	viper.SetDefault("enrichment.reverse_dns_rate", 10)
+	viper.SetDefault("enrichment.pipeline.enabled", false)
+	viper.SetDefault("enrichment.pipeline.workers", 4)
+	viper.SetDefault("enrichment.pipeline.queue_size", 1024)
+	viper.SetDefault("enrichment.pipeline.stages", defaultEnrichmentStages)
+
+	// 日志配置默认值
+	viper.SetDefault("logging.summary_interval", "1m")
+
+	// 指标推送默认配置
+	viper.SetDefault("metrics.pushgateway_url", "")
+	viper.SetDefault("metrics.job", "assets_discovery")
+
+	// 导出格式默认值
+	viper.SetDefault("export.flatten", false)
+
+	// 风险评分默认值
+	viper.SetDefault("risk.port_scores", defaultRiskPortScores)
+	viper.SetDefault("risk.eol_os", defaultEOLOS)
+	viper.SetDefault("risk.eol_os_score", 3)
+	viper.SetDefault("risk.telnet_score", 4)
 }
 
 // getDefaultConfig 获取默认配置
@@ -131,25 +421,94 @@ func getDefaultConfig() *Config {
 			Timeout:     30 * time.Second,
 			BufferSize:  2097152,
 			Workers:     4,
+			MaxWorkers:  0,
+			Duration:    0,
+			AutoSnapLen: false,
+			VLAN:        false,
+			SampleRate:  1,
+			AdaptiveFilter: AdaptiveFilterConfig{
+				Interval:      10 * time.Second,
+				HighDropRate:  0.05,
+				LowDropRate:   0.01,
+				Sustain:       3,
+				CoreProtocols: []string{"arp", "dhcp"},
+			},
+			Engine: "pcap",
 		},
 		Parser: ParserConfig{
-			EnabledProtocols: []string{"arp", "dhcp", "http", "https", "dns", "smb", "mdns"},
+			EnabledProtocols: []string{"arp", "dhcp", "http", "https", "dns", "smb", "mdns", "rdp", "llmnr", "nbns", "vxlan", "gre", "stun", "igmp", "telnet", "redis"},
 			MaxPackets:       0,
 			AssetTimeout:     30,
+			PurgeAfter:       0,
+			PortTimeout:      1440,
+			MinConfidence:    0,
+			OutOfScope:       "ignore",
+			MaxBannerLength:  256,
+			DeviceTimeouts:   map[string]int{},
+			IncludeVLANs:     []int{},
+			ExcludeVLANs:     []int{},
+			Reassembly: ReassemblyConfig{
+				Enabled:      true,
+				MaxStreams:   4096,
+				FlushTimeout: 30 * time.Second,
+			},
+			HostCorrelation: HostCorrelationConfig{
+				Key: "hostname",
+			},
 		},
 		Storage: StorageConfig{
-			Type: "file",
+			Type:    "file",
+			NoStore: false,
+			Elasticsearch: ESConfig{
+				Index:         "assets",
+				IndexStrategy: "static",
+				MaxRetries:    3,
+				RetryBackoff:  500 * time.Millisecond,
+			},
 			File: FileConfig{
 				OutputDir: "./output",
 				Format:    "json",
+				Rotation:  "none",
+			},
+			Kafka: KafkaConfig{
+				Topic:         "assets",
+				SASLMechanism: "plain",
+				BatchSize:     100,
+				BatchTimeout:  time.Second,
 			},
 		},
 		Server: ServerConfig{
 			Port:    8080,
 			Enabled: true,
+			GRPC: GRPCConfig{
+				Port: 9090,
+			},
 		},
 		Alerting: AlertingConfig{
 			Enabled: false,
 		},
+		Enrichment: EnrichmentConfig{
+			Enabled:        false,
+			Provider:       "none",
+			ReverseDNS:     false,
+			ReverseDNSRate: 10,
+			Pipeline: EnrichmentPipelineConfig{
+				Workers:   4,
+				QueueSize: 1024,
+				Stages:    defaultEnrichmentStages,
+			},
+		},
+		Logging: LoggingConfig{
+			SummaryInterval: time.Minute,
+		},
+		Risk: RiskConfig{
+			PortScores:  defaultRiskPortScores,
+			EOLOS:       defaultEOLOS,
+			EOLOSScore:  3,
+			TelnetScore: 4,
+		},
+		Metrics: MetricsConfig{
+			Job: "assets_discovery",
+		},
 	}
 }
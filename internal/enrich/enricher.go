@@ -0,0 +1,59 @@
+package enrich
+
+import (
+	"fmt"
+	"net"
+
+	"assets_discovery/internal/config"
+)
+
+// Enricher 公网IP信息补充接口，例如ASN、地理位置
+type Enricher interface {
+	// 查询IP的补充信息，没有结果时返回nil
+	Enrich(ip string) (map[string]interface{}, error)
+
+	// 释放资源
+	Close() error
+}
+
+// NewEnricher 根据配置创建信息补充器，未启用时返回空实现
+func NewEnricher(cfg *config.EnrichmentConfig) (Enricher, error) {
+	if !cfg.Enabled {
+		return NoopEnricher{}, nil
+	}
+
+	switch cfg.Provider {
+	case "", "none":
+		return NoopEnricher{}, nil
+	case "maxmind":
+		return NewMaxMindEnricher(cfg.GeoIPDatabase, cfg.ASNDatabase)
+	default:
+		return nil, fmt.Errorf("不支持的信息补充提供方: %s", cfg.Provider)
+	}
+}
+
+// NoopEnricher 不做任何补充的默认实现
+type NoopEnricher struct{}
+
+// Enrich 始终返回空结果
+func (NoopEnricher) Enrich(ip string) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+// Close 无需释放资源
+func (NoopEnricher) Close() error {
+	return nil
+}
+
+// IsPublicIP 判断是否为需要补充信息的公网地址
+// 私有地址(RFC1918/ULA)、回环、链路本地、组播等均不属于公网地址
+func IsPublicIP(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	return !(parsed.IsPrivate() || parsed.IsLoopback() || parsed.IsLinkLocalUnicast() ||
+		parsed.IsLinkLocalMulticast() || parsed.IsMulticast() || parsed.IsUnspecified() ||
+		parsed.Equal(net.IPv4bcast))
+}
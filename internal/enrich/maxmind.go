@@ -0,0 +1,410 @@
+package enrich
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"os"
+)
+
+// mmdbMetadataMarker MaxMind DB元数据起始标记
+var mmdbMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// mmdbDataSeparator 搜索树与数据区之间的16字节分隔
+const mmdbDataSeparator = 16
+
+// MaxMindEnricher 基于本地GeoLite2数据库(.mmdb)的信息补充实现
+type MaxMindEnricher struct {
+	readers []*mmdbReader
+}
+
+// NewMaxMindEnricher 加载GeoLite2 City/Country和ASN数据库，路径为空的数据库会被忽略
+func NewMaxMindEnricher(geoPath, asnPath string) (*MaxMindEnricher, error) {
+	me := &MaxMindEnricher{}
+
+	for _, path := range []string{geoPath, asnPath} {
+		if path == "" {
+			continue
+		}
+		reader, err := openMMDB(path)
+		if err != nil {
+			return nil, fmt.Errorf("加载MaxMind数据库失败 %s: %v", path, err)
+		}
+		me.readers = append(me.readers, reader)
+	}
+
+	if len(me.readers) == 0 {
+		return nil, fmt.Errorf("未配置MaxMind数据库路径")
+	}
+
+	return me, nil
+}
+
+// Enrich 查询IP的国家、城市、经纬度和ASN信息
+func (me *MaxMindEnricher) Enrich(ip string) (map[string]interface{}, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil, fmt.Errorf("无效的IP地址: %s", ip)
+	}
+
+	result := make(map[string]interface{})
+	for _, reader := range me.readers {
+		record, err := reader.lookup(parsed)
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			continue
+		}
+
+		if code, ok := lookupPath(record, "country", "iso_code").(string); ok {
+			result["country_code"] = code
+		}
+		if name, ok := lookupPath(record, "country", "names", "en").(string); ok {
+			result["country"] = name
+		}
+		if name, ok := lookupPath(record, "city", "names", "en").(string); ok {
+			result["city"] = name
+		}
+		if lat, ok := lookupPath(record, "location", "latitude").(float64); ok {
+			result["latitude"] = lat
+		}
+		if lon, ok := lookupPath(record, "location", "longitude").(float64); ok {
+			result["longitude"] = lon
+		}
+		if asn, ok := lookupPath(record, "autonomous_system_number").(uint64); ok {
+			result["asn"] = asn
+		}
+		if org, ok := lookupPath(record, "autonomous_system_organization").(string); ok {
+			result["as_org"] = org
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// Close 数据库已全部读入内存，无需释放
+func (me *MaxMindEnricher) Close() error {
+	return nil
+}
+
+// lookupPath 按键路径读取嵌套map中的值
+func lookupPath(record interface{}, keys ...string) interface{} {
+	current := record
+	for _, key := range keys {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+	return current
+}
+
+// mmdbReader MaxMind DB格式的最小实现，只支持查询
+type mmdbReader struct {
+	buffer     []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	dataStart  uint
+	ipv4Start  uint
+}
+
+// openMMDB 读取并解析.mmdb文件的元数据
+func openMMDB(path string) (*mmdbReader, error) {
+	buffer, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	markerPos := bytes.LastIndex(buffer, mmdbMetadataMarker)
+	if markerPos < 0 {
+		return nil, fmt.Errorf("不是有效的MaxMind数据库")
+	}
+
+	metaStart := uint(markerPos + len(mmdbMetadataMarker))
+	meta, _, err := (&mmdbDecoder{buffer: buffer[metaStart:]}).decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("解析元数据失败: %v", err)
+	}
+	metadata, ok := meta.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("元数据格式错误")
+	}
+
+	r := &mmdbReader{buffer: buffer}
+	nodeCount, _ := metadata["node_count"].(uint64)
+	recordSize, _ := metadata["record_size"].(uint64)
+	ipVersion, _ := metadata["ip_version"].(uint64)
+	r.nodeCount, r.recordSize, r.ipVersion = uint(nodeCount), uint(recordSize), uint(ipVersion)
+
+	switch r.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("不支持的记录长度: %d", r.recordSize)
+	}
+
+	treeSize := r.nodeCount * r.recordSize / 4
+	r.dataStart = treeSize + mmdbDataSeparator
+	if r.dataStart > metaStart {
+		return nil, fmt.Errorf("数据库文件已损坏")
+	}
+
+	// IPv6数据库中IPv4地址位于::/96之下
+	if r.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < r.nodeCount; i++ {
+			node = r.readNode(node, 0)
+		}
+		r.ipv4Start = node
+	}
+
+	return r, nil
+}
+
+// lookup 查询IP对应的数据记录
+func (r *mmdbReader) lookup(ip net.IP) (interface{}, error) {
+	node := uint(0)
+	addr := ip.To4()
+	if addr != nil && r.ipVersion == 6 {
+		node = r.ipv4Start
+	} else if addr == nil {
+		if r.ipVersion == 4 {
+			return nil, nil
+		}
+		addr = ip.To16()
+	}
+
+	bitCount := uint(len(addr) * 8)
+	for i := uint(0); i < bitCount && node < r.nodeCount; i++ {
+		bit := uint(addr[i>>3]>>(7-(i%8))) & 1
+		node = r.readNode(node, bit)
+	}
+
+	if node == r.nodeCount {
+		return nil, nil
+	}
+	if node < r.nodeCount {
+		return nil, fmt.Errorf("搜索树结构异常")
+	}
+
+	offset := node - r.nodeCount - mmdbDataSeparator
+	decoder := &mmdbDecoder{buffer: r.buffer[r.dataStart:]}
+	record, _, err := decoder.decode(offset)
+	return record, err
+}
+
+// readNode 读取搜索树节点的左(bit=0)或右(bit=1)记录
+func (r *mmdbReader) readNode(node, bit uint) uint {
+	b := r.buffer[node*r.recordSize/4:]
+
+	switch r.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// mmdbDecoder 数据区解码器
+type mmdbDecoder struct {
+	buffer []byte
+}
+
+// MaxMind DB数据类型
+const (
+	mmdbExtended = iota
+	mmdbPointer
+	mmdbString
+	mmdbDouble
+	mmdbBytes
+	mmdbUint16
+	mmdbUint32
+	mmdbMap
+	mmdbInt32
+	mmdbUint64
+	mmdbUint128
+	mmdbSlice
+	mmdbContainer
+	mmdbEndMarker
+	mmdbBool
+	mmdbFloat32
+)
+
+// decode 解码offset处的值，返回值和下一个字段的偏移
+func (d *mmdbDecoder) decode(offset uint) (interface{}, uint, error) {
+	if offset >= uint(len(d.buffer)) {
+		return nil, 0, fmt.Errorf("数据偏移越界")
+	}
+
+	ctrl := d.buffer[offset]
+	offset++
+	kind := uint(ctrl >> 5)
+
+	if kind == mmdbPointer {
+		pointer, next, err := d.decodePointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decode(pointer)
+		return value, next, err
+	}
+
+	if kind == mmdbExtended {
+		if offset >= uint(len(d.buffer)) {
+			return nil, 0, fmt.Errorf("数据偏移越界")
+		}
+		kind = 7 + uint(d.buffer[offset])
+		offset++
+	}
+
+	size, offset, err := d.decodeSize(ctrl, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch kind {
+	case mmdbMap:
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			keyStr, ok := key.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("map键不是字符串")
+			}
+			value, next, err := d.decode(next)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[keyStr] = value
+			offset = next
+		}
+		return m, offset, nil
+	case mmdbSlice:
+		s := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			value, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			s = append(s, value)
+			offset = next
+		}
+		return s, offset, nil
+	case mmdbBool:
+		return size != 0, offset, nil
+	case mmdbContainer, mmdbEndMarker:
+		return nil, offset, nil
+	}
+
+	end := offset + size
+	if end > uint(len(d.buffer)) {
+		return nil, 0, fmt.Errorf("数据长度越界")
+	}
+	data := d.buffer[offset:end]
+
+	switch kind {
+	case mmdbString:
+		return string(data), end, nil
+	case mmdbBytes:
+		return append([]byte(nil), data...), end, nil
+	case mmdbDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("double长度错误: %d", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), end, nil
+	case mmdbFloat32:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("float长度错误: %d", size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), end, nil
+	case mmdbUint16, mmdbUint32, mmdbUint64:
+		var v uint64
+		for _, b := range data {
+			v = v<<8 | uint64(b)
+		}
+		return v, end, nil
+	case mmdbInt32:
+		var v uint32
+		for _, b := range data {
+			v = v<<8 | uint32(b)
+		}
+		return int64(int32(v)), end, nil
+	case mmdbUint128:
+		// 数值超出uint64范围，按原始字节返回
+		return append([]byte(nil), data...), end, nil
+	default:
+		return nil, 0, fmt.Errorf("未知的数据类型: %d", kind)
+	}
+}
+
+// decodeSize 解析控制字节中的长度字段
+func (d *mmdbDecoder) decodeSize(ctrl byte, offset uint) (uint, uint, error) {
+	size := uint(ctrl & 0x1F)
+	if size < 29 {
+		return size, offset, nil
+	}
+
+	extra := size - 28
+	if offset+extra > uint(len(d.buffer)) {
+		return 0, 0, fmt.Errorf("数据长度越界")
+	}
+
+	var v uint
+	for _, b := range d.buffer[offset : offset+extra] {
+		v = v<<8 | uint(b)
+	}
+
+	switch size {
+	case 29:
+		size = 29 + v
+	case 30:
+		size = 285 + v
+	default:
+		size = 65821 + v
+	}
+
+	return size, offset + extra, nil
+}
+
+// decodePointer 解析指针，返回指向的数据区偏移和下一个字段的偏移
+func (d *mmdbDecoder) decodePointer(ctrl byte, offset uint) (uint, uint, error) {
+	pointerSize := uint((ctrl>>3)&0x3) + 1
+	if offset+pointerSize > uint(len(d.buffer)) {
+		return 0, 0, fmt.Errorf("指针越界")
+	}
+
+	var prefix uint
+	if pointerSize != 4 {
+		prefix = uint(ctrl & 0x7)
+	}
+
+	v := prefix
+	for _, b := range d.buffer[offset : offset+pointerSize] {
+		v = v<<8 | uint(b)
+	}
+
+	switch pointerSize {
+	case 2:
+		v += 2048
+	case 3:
+		v += 526336
+	}
+
+	return v, offset + pointerSize, nil
+}
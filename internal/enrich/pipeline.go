@@ -0,0 +1,225 @@
+package enrich
+
+import (
+	"context"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"assets_discovery/internal/config"
+)
+
+const (
+	// defaultPipelineWorkers 未配置时流水线的工作协程数
+	defaultPipelineWorkers = 4
+	// defaultPipelineQueueSize 未配置时等待补充的IP队列长度，队列满时丢弃新请求
+	defaultPipelineQueueSize = 1024
+	// defaultStageTimeout 未配置超时时间的步骤使用的默认值
+	defaultStageTimeout = 2 * time.Second
+	// maxPipelineCache 已提交IP缓存的最大数量，超出后清空重建
+	maxPipelineCache = 10000
+)
+
+// Stage 补充流水线中的一个步骤
+type Stage interface {
+	// Name 步骤名称，用于结果回写和统计
+	Name() string
+
+	// Enrich 查询IP的补充信息，没有结果时返回nil；步骤超时后ctx被取消
+	Enrich(ctx context.Context, ip string) (map[string]interface{}, error)
+}
+
+// StageStats 单个步骤的执行次数、超时次数、失败次数及累计耗时，超时的执行不计入耗时
+type StageStats struct {
+	Runs     uint64  `json:"runs"`
+	Timeouts uint64  `json:"timeouts"`
+	Errors   uint64  `json:"errors"`
+	Seconds  float64 `json:"seconds"`
+}
+
+// pipelineStage 流水线中的步骤及其超时时间和统计
+type pipelineStage struct {
+	stage   Stage
+	timeout time.Duration
+
+	runs     uint64
+	timeouts uint64
+	errors   uint64
+	nanos    int64
+}
+
+// Pipeline 在有界的工作协程池中对新提交的IP按顺序执行各补充步骤，每个步骤的结果通过回调返回
+// 提交不会阻塞，单个步骤超时后放弃其结果继续执行后续步骤，慢步骤不会拖慢数据包处理
+type Pipeline struct {
+	stages   []*pipelineStage
+	workers  int
+	onResult func(ip, stage string, result map[string]interface{})
+
+	queue chan string
+
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// NewPipeline 创建补充流水线，workers和queueSize不大于0时使用默认值
+func NewPipeline(workers, queueSize int, onResult func(ip, stage string, result map[string]interface{})) *Pipeline {
+	if workers <= 0 {
+		workers = defaultPipelineWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = defaultPipelineQueueSize
+	}
+
+	return &Pipeline{
+		workers:  workers,
+		onResult: onResult,
+		queue:    make(chan string, queueSize),
+		seen:     make(map[string]bool),
+	}
+}
+
+// NewPipelineFromConfig 按配置的顺序创建已启用的步骤，geoip步骤使用enricher查询，未知的步骤被忽略
+func NewPipelineFromConfig(cfg *config.EnrichmentConfig, enricher Enricher, onResult func(ip, stage string, result map[string]interface{})) *Pipeline {
+	p := NewPipeline(cfg.Pipeline.Workers, cfg.Pipeline.QueueSize, onResult)
+
+	for _, sc := range cfg.Pipeline.Stages {
+		if !sc.Enabled {
+			continue
+		}
+
+		switch sc.Name {
+		case GeoIPStageName:
+			p.AddStage(NewGeoIPStage(enricher), sc.Timeout)
+		case ReverseDNSStageName:
+			p.AddStage(NewReverseDNSStage(nil), sc.Timeout)
+		default:
+			log.Printf("忽略未知的信息补充步骤: %s", sc.Name)
+		}
+	}
+	return p
+}
+
+// AddStage 在流水线末尾添加步骤，timeout不大于0时使用默认值，需在Run之前调用
+func (p *Pipeline) AddStage(stage Stage, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = defaultStageTimeout
+	}
+	p.stages = append(p.stages, &pipelineStage{stage: stage, timeout: timeout})
+}
+
+// Stages 按执行顺序返回步骤名称
+func (p *Pipeline) Stages() []string {
+	names := make([]string, len(p.stages))
+	for i, s := range p.stages {
+		names[i] = s.stage.Name()
+	}
+	return names
+}
+
+// Submit 将IP加入补充队列，已提交过的IP和组播、广播等地址会被忽略，不会阻塞
+func (p *Pipeline) Submit(ip string) {
+	parsed := net.ParseIP(ip)
+	if len(p.stages) == 0 || parsed == nil || parsed.IsUnspecified() || parsed.IsMulticast() || parsed.Equal(net.IPv4bcast) {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.seen[ip] {
+		return
+	}
+
+	select {
+	case p.queue <- ip:
+		if len(p.seen) >= maxPipelineCache {
+			p.seen = make(map[string]bool)
+		}
+		p.seen[ip] = true
+	default:
+		// 队列已满，之后再次发现该IP时重试
+	}
+}
+
+// Run 启动工作协程处理补充队列，ctx取消后等待所有工作协程退出再返回
+func (p *Pipeline) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case ip := <-p.queue:
+					p.process(ctx, ip)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// process 按顺序对IP执行各步骤，有结果时立即回调，不等待后续步骤
+func (p *Pipeline) process(ctx context.Context, ip string) {
+	for _, s := range p.stages {
+		if ctx.Err() != nil {
+			return
+		}
+		if result := s.run(ctx, ip); len(result) > 0 {
+			p.onResult(ip, s.stage.Name(), result)
+		}
+	}
+}
+
+// run 在超时时间内执行步骤，超时后不再等待，步骤所在的协程在其返回后自行退出
+func (s *pipelineStage) run(ctx context.Context, ip string) map[string]interface{} {
+	stageCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	type outcome struct {
+		result map[string]interface{}
+		err    error
+	}
+	done := make(chan outcome, 1)
+	start := time.Now()
+	go func() {
+		result, err := s.stage.Enrich(stageCtx, ip)
+		done <- outcome{result, err}
+	}()
+
+	atomic.AddUint64(&s.runs, 1)
+	select {
+	case o := <-done:
+		atomic.AddInt64(&s.nanos, int64(time.Since(start)))
+		if o.err != nil {
+			atomic.AddUint64(&s.errors, 1)
+			log.Printf("信息补充步骤 %s 查询 %s 失败: %v", s.stage.Name(), ip, o.err)
+			return nil
+		}
+		return o.result
+	case <-stageCtx.Done():
+		// 停止流水线导致的取消不计为超时
+		if ctx.Err() == nil {
+			atomic.AddUint64(&s.timeouts, 1)
+		}
+		return nil
+	}
+}
+
+// Stats 按步骤名称返回各步骤的统计
+func (p *Pipeline) Stats() map[string]StageStats {
+	stats := make(map[string]StageStats, len(p.stages))
+	for _, s := range p.stages {
+		stats[s.stage.Name()] = StageStats{
+			Runs:     atomic.LoadUint64(&s.runs),
+			Timeouts: atomic.LoadUint64(&s.timeouts),
+			Errors:   atomic.LoadUint64(&s.errors),
+			Seconds:  time.Duration(atomic.LoadInt64(&s.nanos)).Seconds(),
+		}
+	}
+	return stats
+}
@@ -0,0 +1,128 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubStage 测试用的补充步骤，block不为nil时忽略ctx一直阻塞到block关闭，模拟卡住的慢步骤
+type stubStage struct {
+	name   string
+	result map[string]interface{}
+	err    error
+	block  chan struct{}
+}
+
+func (s stubStage) Name() string {
+	return s.name
+}
+
+func (s stubStage) Enrich(ctx context.Context, ip string) (map[string]interface{}, error) {
+	if s.block != nil {
+		<-s.block
+	}
+	return s.result, s.err
+}
+
+func TestPipelineSlowStageTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	type result struct {
+		ip    string
+		stage string
+	}
+	var mu sync.Mutex
+	var results []result
+	done := make(chan struct{}, 4)
+
+	p := NewPipeline(1, 8, func(ip, stage string, r map[string]interface{}) {
+		mu.Lock()
+		results = append(results, result{ip, stage})
+		mu.Unlock()
+		if stage == "tags" {
+			done <- struct{}{}
+		}
+	})
+	p.AddStage(stubStage{name: "oui", result: map[string]interface{}{"vendor": "Acme"}}, time.Second)
+	p.AddStage(stubStage{name: "slow", result: map[string]interface{}{"never": true}, block: release}, 20*time.Millisecond)
+	p.AddStage(stubStage{name: "broken", err: errors.New("database unavailable")}, time.Second)
+	p.AddStage(stubStage{name: "empty"}, time.Second)
+	p.AddStage(stubStage{name: "tags", result: map[string]interface{}{"tag": "lab"}}, 0)
+
+	if got, want := p.Stages(), []string{"oui", "slow", "broken", "empty", "tags"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Stages() = %v, want %v", got, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(stopped)
+	}()
+
+	start := time.Now()
+	// 重复提交和组播、广播等地址被忽略
+	for _, ip := range []string{"203.0.113.5", "203.0.113.5", "224.0.0.251", "255.255.255.255", "0.0.0.0", "bogus", "198.51.100.7"} {
+		p.Submit(ip)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("pipeline did not finish after slow stage timed out")
+		}
+	}
+	// 卡住的步骤在超时后被放弃，单个工作协程依次处理两个IP，不会等待慢步骤返回
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("pipeline took %v, want slow stage abandoned after its timeout", elapsed)
+	}
+
+	cancel()
+	<-stopped
+
+	mu.Lock()
+	got := append([]result(nil), results...)
+	mu.Unlock()
+	want := []result{
+		{"203.0.113.5", "oui"}, {"203.0.113.5", "tags"},
+		{"198.51.100.7", "oui"}, {"198.51.100.7", "tags"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+
+	stats := p.Stats()
+	wantStats := map[string]StageStats{
+		"oui":    {Runs: 2},
+		"slow":   {Runs: 2, Timeouts: 2},
+		"broken": {Runs: 2, Errors: 2},
+		"empty":  {Runs: 2},
+		"tags":   {Runs: 2},
+	}
+	for name, want := range wantStats {
+		s := stats[name]
+		if s.Runs != want.Runs || s.Timeouts != want.Timeouts || s.Errors != want.Errors {
+			t.Errorf("Stats()[%s] = %+v, want runs %d timeouts %d errors %d", name, s, want.Runs, want.Timeouts, want.Errors)
+		}
+	}
+	// 超时的执行不计入耗时
+	if stats["slow"].Seconds != 0 {
+		t.Errorf("Stats()[slow].Seconds = %v, want 0", stats["slow"].Seconds)
+	}
+}
+
+func TestPipelineSubmitWithoutStages(t *testing.T) {
+	p := NewPipeline(0, 0, func(ip, stage string, r map[string]interface{}) {})
+	p.Submit("203.0.113.5")
+	if len(p.queue) != 0 {
+		t.Errorf("queue length = %d, want 0 without stages", len(p.queue))
+	}
+	if p.workers != defaultPipelineWorkers || cap(p.queue) != defaultPipelineQueueSize {
+		t.Errorf("workers = %d, queue size = %d, want defaults %d, %d", p.workers, cap(p.queue), defaultPipelineWorkers, defaultPipelineQueueSize)
+	}
+}
@@ -0,0 +1,135 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// reverseDNSQueueSize 等待反向解析的IP队列长度，队列满时丢弃新请求
+	reverseDNSQueueSize = 1024
+	// reverseDNSTimeout 单次PTR查询的超时时间
+	reverseDNSTimeout = 2 * time.Second
+	// maxReverseDNSCache 已查询IP缓存的最大数量，超出后清空重建
+	maxReverseDNSCache = 10000
+)
+
+// Resolver 反向解析接口，net.Resolver实现了该接口
+type Resolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// ReverseDNS 在独立协程中对新发现的IP做PTR查询，DNS延迟不会阻塞数据包处理
+// 每个IP只查询一次，查询速率受限，结果通过回调返回
+type ReverseDNS struct {
+	resolver Resolver
+	interval time.Duration
+	onResult func(ip, hostname string)
+
+	queue chan string
+
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// NewReverseDNS 创建反向解析器，rate为每秒最多查询次数，resolver为空时使用系统解析器
+func NewReverseDNS(resolver Resolver, rate int, onResult func(ip, hostname string)) *ReverseDNS {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	if rate <= 0 {
+		rate = 1
+	}
+
+	return &ReverseDNS{
+		resolver: resolver,
+		interval: time.Second / time.Duration(rate),
+		onResult: onResult,
+		queue:    make(chan string, reverseDNSQueueSize),
+		seen:     make(map[string]bool),
+	}
+}
+
+// Lookup 将IP加入查询队列，已查询过的IP和组播、广播等地址会被忽略，不会阻塞
+func (r *ReverseDNS) Lookup(ip string) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil || parsed.IsUnspecified() || parsed.IsMulticast() || parsed.Equal(net.IPv4bcast) {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.seen[ip] {
+		return
+	}
+
+	select {
+	case r.queue <- ip:
+		if len(r.seen) >= maxReverseDNSCache {
+			r.seen = make(map[string]bool)
+		}
+		r.seen[ip] = true
+	default:
+		// 队列已满，之后再次发现该IP时重试
+	}
+}
+
+// Run 按速率限制处理查询队列，ctx取消时退出
+func (r *ReverseDNS) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case ip := <-r.queue:
+			if hostname := r.resolve(ctx, ip); hostname != "" {
+				r.onResult(ip, hostname)
+			}
+		case <-ctx.Done():
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// resolve 查询IP的PTR记录，返回第一个名称
+func (r *ReverseDNS) resolve(ctx context.Context, ip string) string {
+	ctx, cancel := context.WithTimeout(ctx, reverseDNSTimeout)
+	defer cancel()
+
+	name, err := lookupPTR(ctx, r.resolver, ip)
+	if err != nil {
+		log.Printf("反向解析失败 %s: %v", ip, err)
+	}
+	return name
+}
+
+// lookupPTR 查询IP的PTR记录并返回第一个名称，没有记录时返回空字符串且不视为错误
+func lookupPTR(ctx context.Context, resolver Resolver, ip string) (string, error) {
+	names, err := resolver.LookupAddr(ctx, ip)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return "", nil
+		}
+		return "", err
+	}
+
+	for _, name := range names {
+		if name = strings.TrimSuffix(name, "."); name != "" {
+			return name, nil
+		}
+	}
+	return "", nil
+}
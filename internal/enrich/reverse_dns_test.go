@@ -0,0 +1,147 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubResolver 按IP返回预设的PTR结果并记录查询次数
+type stubResolver struct {
+	mu      sync.Mutex
+	names   map[string][]string
+	errs    map[string]error
+	lookups map[string]int
+}
+
+func (r *stubResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lookups[addr]++
+	if err, ok := r.errs[addr]; ok {
+		return nil, err
+	}
+	return r.names[addr], nil
+}
+
+func (r *stubResolver) lookupCount(addr string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lookups[addr]
+}
+
+func TestLookupPTR(t *testing.T) {
+	resolver := &stubResolver{
+		names: map[string][]string{
+			"10.0.0.1": {"printer.corp.example."},
+			"10.0.0.2": {".", "nas.corp.example"},
+			"10.0.0.3": {},
+		},
+		errs: map[string]error{
+			"10.0.0.4": &net.DNSError{Err: "no such host", Name: "10.0.0.4", IsNotFound: true},
+			"10.0.0.5": errors.New("connection refused"),
+		},
+		lookups: map[string]int{},
+	}
+
+	tests := []struct {
+		ip      string
+		want    string
+		wantErr bool
+	}{
+		{"10.0.0.1", "printer.corp.example", false},
+		{"10.0.0.2", "nas.corp.example", false},
+		{"10.0.0.3", "", false},
+		{"10.0.0.4", "", false},
+		{"10.0.0.5", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			got, err := lookupPTR(context.Background(), resolver, tt.ip)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lookupPTR(%s) error = %v, wantErr %v", tt.ip, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("lookupPTR(%s) = %q, want %q", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReverseDNS(t *testing.T) {
+	resolver := &stubResolver{
+		names: map[string][]string{
+			"10.0.0.1": {"printer.corp.example."},
+			"10.0.0.2": {"nas.corp.example."},
+		},
+		lookups: map[string]int{},
+	}
+
+	var mu sync.Mutex
+	results := map[string]string{}
+	r := NewReverseDNS(resolver, 1000, func(ip, hostname string) {
+		mu.Lock()
+		defer mu.Unlock()
+		results[ip] = hostname
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+
+	// 同一IP多次发现只查询一次，组播、广播和无效地址不查询
+	for _, ip := range []string{"10.0.0.1", "10.0.0.1", "10.0.0.2", "10.0.0.3", "224.0.0.251", "255.255.255.255", "0.0.0.0", "bogus", "10.0.0.1"} {
+		r.Lookup(ip)
+	}
+
+	want := map[string]string{"10.0.0.1": "printer.corp.example", "10.0.0.2": "nas.corp.example"}
+	deadline := time.Now().Add(5 * time.Second)
+	for resolver.lookupCount("10.0.0.3") == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for lookups")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	mu.Lock()
+	got := make(map[string]string, len(results))
+	for ip, hostname := range results {
+		got[ip] = hostname
+	}
+	mu.Unlock()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+
+	resolver.mu.Lock()
+	var looked []string
+	for ip, n := range resolver.lookups {
+		if n != 1 {
+			t.Errorf("lookups for %s = %d, want 1", ip, n)
+		}
+		looked = append(looked, ip)
+	}
+	resolver.mu.Unlock()
+	sort.Strings(looked)
+	if wantLooked := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}; !reflect.DeepEqual(looked, wantLooked) {
+		t.Errorf("looked up %v, want %v", looked, wantLooked)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not exit after cancel")
+	}
+}
@@ -0,0 +1,62 @@
+package enrich
+
+import (
+	"context"
+	"net"
+)
+
+// 内置步骤名称
+const (
+	GeoIPStageName      = "geoip"
+	ReverseDNSStageName = "reverse_dns"
+)
+
+// geoIPStage 用信息补充器查询公网IP的ASN、地理位置等信息，私有地址直接跳过
+type geoIPStage struct {
+	enricher Enricher
+}
+
+// NewGeoIPStage 创建ASN、地理位置补充步骤
+func NewGeoIPStage(enricher Enricher) Stage {
+	return geoIPStage{enricher: enricher}
+}
+
+// Name 步骤名称
+func (geoIPStage) Name() string {
+	return GeoIPStageName
+}
+
+// Enrich 查询公网IP的补充信息，信息补充器不支持取消，超时由流水线处理
+func (s geoIPStage) Enrich(ctx context.Context, ip string) (map[string]interface{}, error) {
+	if !IsPublicIP(ip) {
+		return nil, nil
+	}
+	return s.enricher.Enrich(ip)
+}
+
+// reverseDNSStage 查询IP的PTR记录，结果中的hostname为第一个名称
+type reverseDNSStage struct {
+	resolver Resolver
+}
+
+// NewReverseDNSStage 创建反向解析步骤，resolver为空时使用系统解析器
+func NewReverseDNSStage(resolver Resolver) Stage {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return reverseDNSStage{resolver: resolver}
+}
+
+// Name 步骤名称
+func (reverseDNSStage) Name() string {
+	return ReverseDNSStageName
+}
+
+// Enrich 查询PTR记录，没有记录时返回nil
+func (s reverseDNSStage) Enrich(ctx context.Context, ip string) (map[string]interface{}, error) {
+	name, err := lookupPTR(ctx, s.resolver, ip)
+	if err != nil || name == "" {
+		return nil, err
+	}
+	return map[string]interface{}{"hostname": name}, nil
+}
@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Limiter 对重复日志限流：每个key在一个汇总周期内只立即输出第一条，
+// 其余的只计数，周期结束时输出一条汇总，避免高频主机刷屏
+type Limiter struct {
+	interval time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// entry 一个key在当前周期内的日志
+type entry struct {
+	message    string
+	suppressed int
+}
+
+// NewLimiter 创建日志限流器，interval不大于0时不限流
+func NewLimiter(interval time.Duration) *Limiter {
+	return &Limiter{
+		interval: interval,
+		entries:  make(map[string]*entry),
+	}
+}
+
+// Printf 输出日志，同一key在当前周期内已输出过时只计数
+func (l *Limiter) Printf(key, format string, args ...interface{}) {
+	if l.interval <= 0 {
+		log.Printf(format, args...)
+		return
+	}
+
+	l.mu.Lock()
+	if e, ok := l.entries[key]; ok {
+		e.suppressed++
+		l.mu.Unlock()
+		return
+	}
+	message := fmt.Sprintf(format, args...)
+	l.entries[key] = &entry{message: message}
+	l.mu.Unlock()
+
+	log.Print(message)
+}
+
+// Run 按周期输出汇总，ctx取消时退出，剩余的汇总由调用方通过Flush输出
+func (l *Limiter) Run(ctx context.Context) {
+	if l.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.Flush()
+		}
+	}
+}
+
+// Flush 输出当前周期被抑制的日志汇总并开始新的周期
+func (l *Limiter) Flush() {
+	l.mu.Lock()
+	entries := l.entries
+	l.entries = make(map[string]*entry)
+	l.mu.Unlock()
+
+	for _, e := range entries {
+		if e.suppressed > 0 {
+			log.Printf("%s（最近 %v 内另有 %d 条相同日志）", e.message, l.interval, e.suppressed)
+		}
+	}
+}
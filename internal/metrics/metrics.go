@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"assets_discovery/internal/assets"
+	"assets_discovery/internal/enrich"
+)
+
+// ContentType Prometheus文本格式的Content-Type
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// metricPrefix 所有指标名称的前缀
+const metricPrefix = "assets_discovery_"
+
+// Write 以Prometheus文本格式输出资产统计，/metrics接口和Pushgateway推送使用相同的指标定义
+func Write(w io.Writer, stats assets.AssetStats) error {
+	var buf bytes.Buffer
+
+	gauge(&buf, "assets_total", "资产总数", float64(stats.TotalAssets))
+	gauge(&buf, "assets_active", "活跃资产数", float64(stats.ActiveAssets))
+	gauge(&buf, "assets_new", "本次运行新发现的资产数", float64(stats.NewAssets))
+	gauge(&buf, "uptime_seconds", "捕获已运行的秒数", float64(stats.UptimeSeconds))
+	distribution(&buf, "assets_by_device_type", "按设备类型统计的资产数", "device_type", stats.DeviceTypes)
+	distribution(&buf, "assets_by_os", "按操作系统统计的资产数", "os", stats.OSDistribution)
+	gauge(&buf, "pending_writes", "保存失败等待重试的资产数", float64(stats.PendingWrites))
+	counter(&buf, "dropped_writes_total", "重试队列已满时丢弃的资产保存次数", float64(stats.DroppedWrites))
+	parseCounters(&buf, stats.ParseStats)
+	if stats.Sampling != nil {
+		gauge(&buf, "sample_rate", "数据包抽样比例，每N个数据包处理1个", float64(stats.Sampling.Rate))
+		counter(&buf, "packets_received_total", "抽样前收到的数据包数", float64(stats.Sampling.Total))
+		counter(&buf, "packets_sampled_total", "抽样后实际处理的数据包数", float64(stats.Sampling.Sampled))
+	}
+	enrichmentCounters(&buf, stats.Enrichment)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Push 将资产统计推送到Prometheus Pushgateway，同一job的指标会被整体替换
+func Push(pushgatewayURL, job string, stats assets.AssetStats) error {
+	var body bytes.Buffer
+	if err := Write(&body, stats); err != nil {
+		return err
+	}
+
+	target := strings.TrimRight(pushgatewayURL, "/") + "/metrics/job/" + url.PathEscape(job)
+	req, err := http.NewRequest(http.MethodPut, target, &body)
+	if err != nil {
+		return fmt.Errorf("创建推送请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", ContentType)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("响应状态异常: %s", resp.Status)
+	}
+	return nil
+}
+
+// gauge 输出不带标签的gauge指标
+func gauge(buf *bytes.Buffer, name, help string, value float64) {
+	fmt.Fprintf(buf, "# HELP %s%s %s\n", metricPrefix, name, help)
+	fmt.Fprintf(buf, "# TYPE %s%s gauge\n", metricPrefix, name)
+	fmt.Fprintf(buf, "%s%s %g\n", metricPrefix, name, value)
+}
+
+// counter 输出不带标签的counter指标
+func counter(buf *bytes.Buffer, name, help string, value float64) {
+	fmt.Fprintf(buf, "# HELP %s%s %s\n", metricPrefix, name, help)
+	fmt.Fprintf(buf, "# TYPE %s%s counter\n", metricPrefix, name)
+	fmt.Fprintf(buf, "%s%s %g\n", metricPrefix, name, value)
+}
+
+// distribution 输出按标签值分组的gauge指标，标签值按字典序排列
+func distribution(buf *bytes.Buffer, name, help, label string, counts map[string]int) {
+	fmt.Fprintf(buf, "# HELP %s%s %s\n", metricPrefix, name, help)
+	fmt.Fprintf(buf, "# TYPE %s%s gauge\n", metricPrefix, name)
+
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(buf, "%s%s{%s=\"%s\"} %d\n", metricPrefix, name, label, escapeLabel(k), counts[k])
+	}
+}
+
+// parseCounters 输出各协议的解析次数和失败次数counter
+func parseCounters(buf *bytes.Buffer, stats map[string]assets.ProtocolParseStats) {
+	if len(stats) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(buf, "# HELP %sparse_attempts_total 协议解析次数\n", metricPrefix)
+	fmt.Fprintf(buf, "# TYPE %sparse_attempts_total counter\n", metricPrefix)
+	for _, name := range names {
+		fmt.Fprintf(buf, "%sparse_attempts_total{protocol=\"%s\"} %d\n", metricPrefix, escapeLabel(name), stats[name].Attempts)
+	}
+
+	fmt.Fprintf(buf, "# HELP %sparse_errors_total 协议解析失败次数\n", metricPrefix)
+	fmt.Fprintf(buf, "# TYPE %sparse_errors_total counter\n", metricPrefix)
+	for _, name := range names {
+		fmt.Fprintf(buf, "%sparse_errors_total{protocol=\"%s\"} %d\n", metricPrefix, escapeLabel(name), stats[name].Errors)
+	}
+}
+
+// enrichmentCounters 输出信息补充流水线各步骤的执行次数、超时次数、失败次数和累计耗时counter
+func enrichmentCounters(buf *bytes.Buffer, stats map[string]enrich.StageStats) {
+	if len(stats) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	series := []struct {
+		name  string
+		help  string
+		value func(enrich.StageStats) float64
+	}{
+		{"enrichment_runs_total", "信息补充步骤执行次数", func(s enrich.StageStats) float64 { return float64(s.Runs) }},
+		{"enrichment_timeouts_total", "信息补充步骤超时次数", func(s enrich.StageStats) float64 { return float64(s.Timeouts) }},
+		{"enrichment_errors_total", "信息补充步骤失败次数", func(s enrich.StageStats) float64 { return float64(s.Errors) }},
+		{"enrichment_seconds_total", "信息补充步骤未超时执行的累计耗时(秒)", func(s enrich.StageStats) float64 { return s.Seconds }},
+	}
+	for _, m := range series {
+		fmt.Fprintf(buf, "# HELP %s%s %s\n", metricPrefix, m.name, m.help)
+		fmt.Fprintf(buf, "# TYPE %s%s counter\n", metricPrefix, m.name)
+		for _, name := range names {
+			fmt.Fprintf(buf, "%s%s{stage=\"%s\"} %g\n", metricPrefix, m.name, escapeLabel(name), m.value(stats[name]))
+		}
+	}
+}
+
+// escapeLabel 转义标签值中的反斜杠、双引号和换行
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
 	"net"
 	"strings"
@@ -12,10 +14,74 @@ import (
 	"assets_discovery/internal/config"
 )
 
+// maxEncapDepth 隧道封装的最大解析层数，防止恶意构造的多层嵌套
+const maxEncapDepth = 3
+
+// vxlanLinuxPort Linux内核VXLAN默认使用的UDP端口
+const vxlanLinuxPort = 8472
+
+// maxDNSQueries 单个DNS或LLMNR报文中最多记录的查询数量，LLMNR响应的应答数量同样受此限制
+const maxDNSQueries = 10
+
+// maxDNSSDRecords 单个报文中最多记录的DNS-SD服务类型和服务实例数量
+const maxDNSSDRecords = 20
+
+// dnsSDEnumeration DNS-SD服务类型枚举查询的名称前缀
+const dnsSDEnumeration = "_services._dns-sd._udp"
+
+// maxCookieNames 单个HTTP报文中最多记录的Cookie名称数量
+const maxCookieNames = 20
+
+// cookieFrameworks Cookie名称与Web框架的对应关系
+var cookieFrameworks = map[string]string{
+	"PHPSESSID":                  "PHP",
+	"JSESSIONID":                 "Java",
+	"ASP.NET_SessionId":          "ASP.NET",
+	".ASPXAUTH":                  "ASP.NET",
+	"__RequestVerificationToken": "ASP.NET MVC",
+	"csrftoken":                  "Django",
+	"sessionid":                  "Django",
+	"laravel_session":            "Laravel",
+	"ci_session":                 "CodeIgniter",
+	"_rails_session":             "Ruby on Rails",
+	"_session_id":                "Ruby on Rails",
+	"rack.session":               "Ruby Rack",
+	"connect.sid":                "Express",
+	"PLAY_SESSION":               "Play Framework",
+	"CFID":                       "ColdFusion",
+	"CFTOKEN":                    "ColdFusion",
+	"symfony":                    "Symfony",
+}
+
+// cookiePrefixFrameworks 按前缀匹配的Cookie名称
+var cookiePrefixFrameworks = []struct {
+	prefix    string
+	framework string
+}{
+	{"ASPSESSIONID", "ASP"},
+	{"wordpress_", "WordPress"},
+	{"wp-settings-", "WordPress"},
+	{"BIGipServer", "F5 BIG-IP"},
+}
+
 // PacketParser 数据包解析器
 type PacketParser struct {
 	config           *config.Config
 	enabledProtocols map[string]bool
+	protocols        []ProtocolParser // 已启用的协议解析器
+	serviceMatcher   *serviceMatcher
+
+	// 按协议名称统计的解析次数和失败次数，创建后只读
+	counters map[string]*protocolCounters
+
+	// 调试转储，保存解析失败的数据包
+	dump *packetDumper
+
+	// 按VLAN ID筛选数据包，未配置时为nil
+	vlans *vlanFilter
+
+	// 采集主机自身的地址，未启用capture.exclude_self时为nil
+	self *selfFilter
 }
 
 // NewPacketParser 创建新的数据包解析器
@@ -25,35 +91,95 @@ func NewPacketParser(cfg *config.Config) *PacketParser {
 		enabled[protocol] = true
 	}
 
-	return &PacketParser{
+	pp := &PacketParser{
 		config:           cfg,
 		enabledProtocols: enabled,
+		serviceMatcher:   newServiceMatcher(cfg.Parser.ServiceProbesFile),
+		counters:         make(map[string]*protocolCounters),
+		dump:             newPacketDumper(cfg.Parser.DebugDump),
+		vlans:            newVLANFilter(cfg.Parser.IncludeVLANs, cfg.Parser.ExcludeVLANs),
 	}
+	if cfg.Capture.ExcludeSelf {
+		pp.self = newSelfFilter()
+	}
+
+	// 只保留配置中启用的协议解析器
+	for _, protocol := range append(pp.builtinParsers(), registeredParsers()...) {
+		if enabled[protocol.Name()] {
+			pp.protocols = append(pp.protocols, protocol)
+			if pp.counters[protocol.Name()] == nil {
+				pp.counters[protocol.Name()] = &protocolCounters{}
+			}
+		}
+	}
+
+	return pp
 }
 
 // ParsePacket 解析数据包并提取资产信息
 func (pp *PacketParser) ParsePacket(packet gopacket.Packet) *assets.AssetInfo {
+	assetInfo := pp.parsePacket(packet, 0)
+	if assetInfo != nil {
+		assetInfo.Length = wireLength(packet)
+	}
+	return assetInfo
+}
+
+// ParsePacketFrom 解析从指定网络接口捕获的数据包，并在资产信息中记录该接口
+func (pp *PacketParser) ParsePacketFrom(packet gopacket.Packet, iface string) *assets.AssetInfo {
+	assetInfo := pp.ParsePacket(packet)
+	if assetInfo != nil {
+		assetInfo.Interface = iface
+	}
+	return assetInfo
+}
+
+// wireLength 数据包在链路上的长度，隧道封装的流量按外层报文计算；捕获信息中没有长度时使用捕获的数据长度
+func wireLength(packet gopacket.Packet) int {
+	if n := packet.Metadata().Length; n > 0 {
+		return n
+	}
+	return len(packet.Data())
+}
+
+// parsePacket 解析数据包，depth为当前的隧道封装层数
+func (pp *PacketParser) parsePacket(packet gopacket.Packet, depth int) *assets.AssetInfo {
 	if packet == nil {
 		return nil
 	}
 
+	// trunk端口上只处理关心的VLAN，隧道内层的帧不再筛选
+	if depth == 0 && !pp.vlans.allows(packet) {
+		return nil
+	}
+
+	// GRE和IP-in-IP隧道解析内层报文，资产是隧道内通信的主机而不是隧道端点
+	if pp.enabledProtocols["gre"] && depth < maxEncapDepth {
+		if inner, firstLayer, tunnelInfo, ok := pp.decapsulateIPTunnel(packet); ok {
+			return pp.parseTunnelInner(packet, inner, firstLayer, tunnelInfo, depth)
+		}
+	}
+
+	// VXLAN封装的流量解析内层帧，资产是租户网络中的主机而不是VTEP
+	if pp.enabledProtocols["vxlan"] && depth < maxEncapDepth {
+		if inner, vni, ok := pp.decapsulateVXLAN(packet); ok {
+			return pp.parseVXLANInner(packet, inner, vni, depth)
+		}
+	}
+
 	assetInfo := &assets.AssetInfo{
 		Timestamp: packet.Metadata().Timestamp,
 		Protocols: make(map[string]interface{}),
 	}
+	parseVLAN(assetInfo, packet)
 
-	// 解析以太网层
+	// 解析以太网层，在any接口或部分VPN接口上捕获时链路层为Linux cooked capture(SLL)头部
 	if ethLayer := packet.Layer(layers.LayerTypeEthernet); ethLayer != nil {
 		eth, _ := ethLayer.(*layers.Ethernet)
 		pp.parseEthernet(assetInfo, eth)
-	}
-
-	// 解析ARP
-	if pp.enabledProtocols["arp"] {
-		if arpLayer := packet.Layer(layers.LayerTypeARP); arpLayer != nil {
-			arp, _ := arpLayer.(*layers.ARP)
-			pp.parseARP(assetInfo, arp)
-		}
+	} else if sllLayer := packet.Layer(layers.LayerTypeLinuxSLL); sllLayer != nil {
+		sll, _ := sllLayer.(*layers.LinuxSLL)
+		pp.parseLinuxSLL(assetInfo, sll)
 	}
 
 	// 解析IPv4层
@@ -70,9 +196,29 @@ func (pp *PacketParser) ParsePacket(packet gopacket.Packet) *assets.AssetInfo {
 		// 解析UDP层
 		if udpLayer := packet.Layer(layers.LayerTypeUDP); udpLayer != nil {
 			udp, _ := udpLayer.(*layers.UDP)
-			pp.parseUDP(assetInfo, udp, packet.ApplicationLayer())
+			pp.parseUDP(assetInfo, udp)
+		}
+	}
+
+	// 交给各协议解析器处理，启用调试转储时保存解析失败的数据包
+	failed := false
+	for _, protocol := range pp.protocols {
+		if hasLayers(packet, protocol.Layers()) && pp.runProtocol(protocol, packet, assetInfo) {
+			failed = true
 		}
 	}
+	if failed && pp.dump != nil {
+		pp.dump.write(packet)
+	}
+
+	// 根据MAC地址和厂商标记虚拟机和随机MAC
+	assetInfo.IsVirtual = assets.IsVirtualMAC(assetInfo.MACAddress) || assets.IsVirtualVendor(assetInfo.Vendor)
+	assetInfo.IsRandomizedMAC = assets.IsRandomizedMAC(assetInfo.MACAddress)
+
+	// 采集主机自身发出的数据包不产生资产
+	if pp.self.matches(assetInfo) {
+		return nil
+	}
 
 	// 只返回包含有用信息的资产信息
 	if pp.hasUsefulInfo(assetInfo) {
@@ -82,6 +228,125 @@ func (pp *PacketParser) ParsePacket(packet gopacket.Packet) *assets.AssetInfo {
 	return nil
 }
 
+// decapsulateVXLAN 提取VXLAN封装的内层以太网帧
+func (pp *PacketParser) decapsulateVXLAN(packet gopacket.Packet) ([]byte, uint32, bool) {
+	// 标准端口4789由gopacket直接解码
+	if vxlanLayer := packet.Layer(layers.LayerTypeVXLAN); vxlanLayer != nil {
+		vxlan, _ := vxlanLayer.(*layers.VXLAN)
+		return vxlan.Payload, vxlan.VNI, true
+	}
+
+	// Linux内核默认使用的8472端口需要手动解码
+	udpLayer := packet.Layer(layers.LayerTypeUDP)
+	if udpLayer == nil {
+		return nil, 0, false
+	}
+	udp, _ := udpLayer.(*layers.UDP)
+	if udp.DstPort != vxlanLinuxPort || len(udp.Payload) < 8 || udp.Payload[0]&0x08 == 0 {
+		return nil, 0, false
+	}
+
+	vni := uint32(udp.Payload[4])<<16 | uint32(udp.Payload[5])<<8 | uint32(udp.Payload[6])
+	return udp.Payload[8:], vni, true
+}
+
+// parseVXLANInner 解析VXLAN内层帧并标记VNI
+func (pp *PacketParser) parseVXLANInner(outer gopacket.Packet, inner []byte, vni uint32, depth int) *assets.AssetInfo {
+	innerPacket := gopacket.NewPacket(inner, layers.LayerTypeEthernet, gopacket.Default)
+	innerPacket.Metadata().CaptureInfo = outer.Metadata().CaptureInfo
+
+	assetInfo := pp.parsePacket(innerPacket, depth+1)
+	if assetInfo == nil {
+		return nil
+	}
+
+	// 多层封装时保留最内层的VNI
+	if _, exists := assetInfo.Protocols["vxlan"]; !exists {
+		vxlanInfo := map[string]interface{}{
+			"vni": vni,
+		}
+		if ipLayer := outer.Layer(layers.LayerTypeIPv4); ipLayer != nil {
+			ip, _ := ipLayer.(*layers.IPv4)
+			vxlanInfo["vtep_src"] = ip.SrcIP.String()
+			vxlanInfo["vtep_dst"] = ip.DstIP.String()
+		}
+		assetInfo.Protocols["vxlan"] = vxlanInfo
+	}
+
+	return assetInfo
+}
+
+// decapsulateIPTunnel 提取GRE或IP-in-IP封装的内层报文，返回内层数据、内层首层类型和隧道信息
+func (pp *PacketParser) decapsulateIPTunnel(packet gopacket.Packet) ([]byte, gopacket.LayerType, map[string]interface{}, bool) {
+	ipLayer := packet.Layer(layers.LayerTypeIPv4)
+	if ipLayer == nil {
+		return nil, 0, nil, false
+	}
+	ip, _ := ipLayer.(*layers.IPv4)
+
+	// 非首个分片不包含隧道头部
+	if ip.FragOffset != 0 {
+		return nil, 0, nil, false
+	}
+
+	tunnelInfo := map[string]interface{}{
+		"tunnel_src": ip.SrcIP.String(),
+		"tunnel_dst": ip.DstIP.String(),
+	}
+
+	switch ip.Protocol {
+	case layers.IPProtocolGRE:
+		greLayer := packet.Layer(layers.LayerTypeGRE)
+		if greLayer == nil {
+			return nil, 0, nil, false
+		}
+		gre, _ := greLayer.(*layers.GRE)
+
+		var firstLayer gopacket.LayerType
+		switch gre.Protocol {
+		case layers.EthernetTypeIPv4:
+			firstLayer = layers.LayerTypeIPv4
+		case layers.EthernetTypeIPv6:
+			firstLayer = layers.LayerTypeIPv6
+		case layers.EthernetTypeTransparentEthernetBridging:
+			firstLayer = layers.LayerTypeEthernet
+		default:
+			return nil, 0, nil, false
+		}
+
+		tunnelInfo["type"] = "gre"
+		tunnelInfo["inner_protocol"] = gre.Protocol.String()
+		if gre.KeyPresent {
+			tunnelInfo["key"] = gre.Key
+		}
+		return gre.Payload, firstLayer, tunnelInfo, true
+
+	case layers.IPProtocolIPv4:
+		tunnelInfo["type"] = "ipip"
+		return ip.Payload, layers.LayerTypeIPv4, tunnelInfo, true
+	}
+
+	return nil, 0, nil, false
+}
+
+// parseTunnelInner 解析隧道内层报文并记录隧道端点
+func (pp *PacketParser) parseTunnelInner(outer gopacket.Packet, inner []byte, firstLayer gopacket.LayerType, tunnelInfo map[string]interface{}, depth int) *assets.AssetInfo {
+	innerPacket := gopacket.NewPacket(inner, firstLayer, gopacket.Default)
+	innerPacket.Metadata().CaptureInfo = outer.Metadata().CaptureInfo
+
+	assetInfo := pp.parsePacket(innerPacket, depth+1)
+	if assetInfo == nil {
+		return nil
+	}
+
+	// 多层封装时保留最内层的隧道信息
+	if _, exists := assetInfo.Protocols["gre"]; !exists {
+		assetInfo.Protocols["gre"] = tunnelInfo
+	}
+
+	return assetInfo
+}
+
 // parseEthernet 解析以太网层
 func (pp *PacketParser) parseEthernet(assetInfo *assets.AssetInfo, eth *layers.Ethernet) {
 	// 提取源MAC地址
@@ -91,6 +356,22 @@ func (pp *PacketParser) parseEthernet(assetInfo *assets.AssetInfo, eth *layers.E
 	}
 }
 
+// sllAddrTypeEthernet SLL头部中以太网设备的地址类型(ARPHRD_ETHER)
+const sllAddrTypeEthernet = 1
+
+// parseLinuxSLL 解析Linux cooked capture头部，头部中的地址为发送方的链路层地址
+// 只有以太网设备的地址是MAC地址，隧道、PPP等设备的地址为空或不是MAC地址
+func (pp *PacketParser) parseLinuxSLL(assetInfo *assets.AssetInfo, sll *layers.LinuxSLL) {
+	if sll.AddrType != sllAddrTypeEthernet || len(sll.Addr) != 6 {
+		return
+	}
+
+	if !pp.isMulticastMAC(sll.Addr) {
+		assetInfo.MACAddress = sll.Addr.String()
+		assetInfo.Vendor = pp.getVendorFromMAC(sll.Addr)
+	}
+}
+
 // parseARP 解析ARP协议
 func (pp *PacketParser) parseARP(assetInfo *assets.AssetInfo, arp *layers.ARP) {
 	if arp.Operation == layers.ARPRequest || arp.Operation == layers.ARPReply {
@@ -98,17 +379,25 @@ func (pp *PacketParser) parseARP(assetInfo *assets.AssetInfo, arp *layers.ARP) {
 		srcIP := net.IP(arp.SourceProtAddress).String()
 		srcMAC := net.HardwareAddr(arp.SourceHwAddress).String()
 
-		assetInfo.IPAddress = srcIP
+		// ARP探测(RFC 5227)的源IP为0.0.0.0，此时只按MAC地址记录资产
+		probe := net.IP(arp.SourceProtAddress).IsUnspecified()
+		if !probe {
+			assetInfo.IPAddress = srcIP
+		}
 		assetInfo.MACAddress = srcMAC
 		assetInfo.Vendor = pp.getVendorFromMAC(net.HardwareAddr(arp.SourceHwAddress))
 
-		assetInfo.Protocols["arp"] = map[string]interface{}{
+		arpInfo := map[string]interface{}{
 			"operation": arp.Operation,
 			"src_ip":    srcIP,
 			"src_mac":   srcMAC,
 			"dst_ip":    net.IP(arp.DstProtAddress).String(),
 			"dst_mac":   net.HardwareAddr(arp.DstHwAddress).String(),
 		}
+		if probe {
+			arpInfo["probe"] = true
+		}
+		assetInfo.Protocols["arp"] = arpInfo
 	}
 }
 
@@ -117,15 +406,27 @@ func (pp *PacketParser) parseIPv4(assetInfo *assets.AssetInfo, ip *layers.IPv4)
 	assetInfo.IPAddress = ip.SrcIP.String()
 
 	// 基于TTL值推测操作系统
-	assetInfo.OSGuess = pp.guessOSFromTTL(ip.TTL)
+	setOSGuess(assetInfo, pp.guessOSFromTTL(ip.TTL), "ttl_analysis")
 
-	assetInfo.Protocols["ipv4"] = map[string]interface{}{
+	ipv4Info := map[string]interface{}{
 		"src_ip":   ip.SrcIP.String(),
 		"dst_ip":   ip.DstIP.String(),
 		"ttl":      ip.TTL,
 		"protocol": ip.Protocol,
 		"length":   ip.Length,
+		"df":       ip.Flags&layers.IPv4DontFragment != 0,
+	}
+
+	// 分片通常说明路径上存在隧道或较小的MTU，带MF标志的分片长度即发送方使用的MTU
+	if moreFragments := ip.Flags&layers.IPv4MoreFragments != 0; moreFragments || ip.FragOffset != 0 {
+		ipv4Info["fragmented"] = true
+		ipv4Info["frag_offset"] = int(ip.FragOffset) * 8
+		if moreFragments {
+			ipv4Info["mtu_hint"] = int(ip.Length)
+		}
 	}
+
+	assetInfo.Protocols["ipv4"] = ipv4Info
 }
 
 // parseTCP 解析TCP层
@@ -133,24 +434,19 @@ func (pp *PacketParser) parseTCP(assetInfo *assets.AssetInfo, tcp *layers.TCP, a
 	srcPort := int(tcp.SrcPort)
 	dstPort := int(tcp.DstPort)
 
-	// 记录开放的端口
-	if tcp.SYN && tcp.ACK {
-		// SYN-ACK 响应表示端口开放
-		assetInfo.OpenPorts = append(assetInfo.OpenPorts, srcPort)
-	}
+	// 只有发送方是服务端时，端口和服务才归属于当前资产，
+	// 避免把客户端的临时端口记录为开放端口
+	isServer := pp.isServerSide(tcp, srcPort, dstPort)
 
-	// 识别服务
-	service := pp.identifyService(srcPort, dstPort, appLayer)
-	if service != "" {
-		if assetInfo.Services == nil {
-			assetInfo.Services = make(map[string]interface{})
-		}
-		assetInfo.Services[fmt.Sprintf("%d/tcp", srcPort)] = service
+	role := "client"
+	if isServer {
+		role = "server"
 	}
 
-	assetInfo.Protocols["tcp"] = map[string]interface{}{
+	tcpInfo := map[string]interface{}{
 		"src_port": srcPort,
 		"dst_port": dstPort,
+		"role":     role,
 		"flags": map[string]bool{
 			"syn": tcp.SYN,
 			"ack": tcp.ACK,
@@ -158,15 +454,46 @@ func (pp *PacketParser) parseTCP(assetInfo *assets.AssetInfo, tcp *layers.TCP, a
 			"rst": tcp.RST,
 		},
 	}
+	// SYN报文的DF标志与TTL一起构成TCP/IP协议栈指纹的一部分（p0f签名）
+	if ipv4Info, ok := assetInfo.Protocols["ipv4"].(map[string]interface{}); ok && tcp.SYN {
+		tcpInfo["df"] = ipv4Info["df"]
+	}
+	assetInfo.Protocols["tcp"] = tcpInfo
+
+	if isServer {
+		assetInfo.OpenPorts = append(assetInfo.OpenPorts, srcPort)
+
+		// 识别服务
+		if service := pp.identifyService(srcPort, appLayer); service != nil {
+			if assetInfo.Services == nil {
+				assetInfo.Services = make(map[string]interface{})
+			}
+			key := fmt.Sprintf("%d/tcp", srcPort)
+			if service.Banner != "" {
+				assetInfo.Services[key] = map[string]interface{}{
+					"version": service.String(),
+					"banner":  service.Banner,
+				}
+			} else {
+				assetInfo.Services[key] = service.String()
+			}
+			tcpInfo["service"] = service
+		}
+	}
 
-	// 解析HTTP协议
-	if pp.enabledProtocols["http"] && (srcPort == 80 || dstPort == 80) && appLayer != nil {
-		pp.parseHTTP(assetInfo, appLayer.Payload())
+	// 客户端发起连接（SYN）的目的端口是已知服务时，记录为该资产使用的外部服务
+	if tcp.SYN && !tcp.ACK {
+		if name, ok := wellKnownServices[dstPort]; ok {
+			if assetInfo.OutboundServices == nil {
+				assetInfo.OutboundServices = make(map[string]interface{})
+			}
+			assetInfo.OutboundServices[fmt.Sprintf("%d/tcp", dstPort)] = name
+		}
 	}
 }
 
 // parseUDP 解析UDP层
-func (pp *PacketParser) parseUDP(assetInfo *assets.AssetInfo, udp *layers.UDP, appLayer gopacket.ApplicationLayer) {
+func (pp *PacketParser) parseUDP(assetInfo *assets.AssetInfo, udp *layers.UDP) {
 	srcPort := int(udp.SrcPort)
 	dstPort := int(udp.DstPort)
 
@@ -174,33 +501,12 @@ func (pp *PacketParser) parseUDP(assetInfo *assets.AssetInfo, udp *layers.UDP, a
 		"src_port": srcPort,
 		"dst_port": dstPort,
 	}
-
-	// 解析DHCP
-	if pp.enabledProtocols["dhcp"] && (srcPort == 67 || srcPort == 68 || dstPort == 67 || dstPort == 68) {
-		if appLayer != nil {
-			pp.parseDHCP(assetInfo, appLayer.Payload())
-		}
-	}
-
-	// 解析DNS
-	if pp.enabledProtocols["dns"] && (srcPort == 53 || dstPort == 53) {
-		if appLayer != nil {
-			pp.parseDNS(assetInfo, appLayer.Payload())
-		}
-	}
-
-	// 解析mDNS
-	if pp.enabledProtocols["mdns"] && (srcPort == 5353 || dstPort == 5353) {
-		if appLayer != nil {
-			pp.parseMDNS(assetInfo, appLayer.Payload())
-		}
-	}
 }
 
 // parseHTTP 解析HTTP协议
-func (pp *PacketParser) parseHTTP(assetInfo *assets.AssetInfo, payload []byte) {
+func (pp *PacketParser) parseHTTP(assetInfo *assets.AssetInfo, payload []byte) error {
 	if len(payload) == 0 {
-		return
+		return nil
 	}
 
 	httpData := string(payload)
@@ -210,28 +516,161 @@ func (pp *PacketParser) parseHTTP(assetInfo *assets.AssetInfo, payload []byte) {
 		assetInfo.Protocols["http"] = headers
 
 		// 提取关键信息
-		if userAgent, ok := headers["user-agent"]; ok {
-			assetInfo.OSGuess = pp.guessOSFromUserAgent(userAgent.(string))
+		if userAgent, ok := httpHeaderValue(headers, "user-agent"); ok {
+			setOSGuess(assetInfo, pp.guessOSFromUserAgent(userAgent), "user_agent")
+		}
+
+		if host, ok := httpHeaderValue(headers, "host"); ok {
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			setHostname(assetInfo, host, "http")
 		}
 
-		if server, ok := headers["server"]; ok {
+		// 分析Cookie名称以识别后端框架，只保留名称不保留值
+		delete(headers, "cookie")
+		delete(headers, "set-cookie")
+
+		if names := pp.parseCookieNames(httpData, "set-cookie"); len(names) > 0 {
+			// Set-Cookie由服务端下发，框架信息归属于当前资产
+			headers["set_cookie_names"] = names
+			if framework := pp.guessFrameworkFromCookies(names); framework != "" {
+				headers["framework"] = framework
+			}
+		}
+
+		if names := pp.parseCookieNames(httpData, "cookie"); len(names) > 0 {
+			// Cookie由客户端携带，框架信息描述的是对端服务器
+			headers["cookie_names"] = names
+			if framework := pp.guessFrameworkFromCookies(names); framework != "" {
+				headers["peer_framework"] = framework
+			}
+		}
+
+		// 响应头描述的是当前资产提供的HTTP服务，随服务记录一起保存
+		if _, isResponse := headers["status_code"]; isResponse {
+			service := map[string]interface{}{
+				"headers": httpServiceHeaders(headers),
+			}
+			if server, ok := httpHeaderValue(headers, "server"); ok {
+				service["version"] = server
+			}
+			if assetInfo.Services == nil {
+				assetInfo.Services = make(map[string]interface{})
+			}
+			assetInfo.Services["http"] = service
+		} else if server, ok := httpHeaderValue(headers, "server"); ok {
 			if assetInfo.Services == nil {
 				assetInfo.Services = make(map[string]interface{})
 			}
 			assetInfo.Services["http"] = server
 		}
+	}
 
-		if host, ok := headers["host"]; ok {
-			assetInfo.Hostname = host.(string)
+	return nil
+}
+
+// httpVolatileHeaders 每个响应都不同、不能描述服务本身的头部，以及起始行解析出的字段
+var httpVolatileHeaders = map[string]bool{
+	"version":        true,
+	"status_code":    true,
+	"method":         true,
+	"path":           true,
+	"date":           true,
+	"age":            true,
+	"expires":        true,
+	"etag":           true,
+	"last-modified":  true,
+	"content-length": true,
+	"content-range":  true,
+	"connection":     true,
+	"keep-alive":     true,
+}
+
+// httpServiceHeaders 从响应头中提取描述HTTP服务的头部，如Server、Content-Type、X-Powered-By
+func httpServiceHeaders(headers map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(headers))
+	for key, value := range headers {
+		if httpVolatileHeaders[key] {
+			continue
 		}
+		result[key] = value
 	}
+	return result
+}
+
+// parseRDP 解析RDP连接请求(TPKT + X.224)
+func (pp *PacketParser) parseRDP(assetInfo *assets.AssetInfo, payload []byte) error {
+	if len(payload) < 2 {
+		return nil
+	}
+
+	// 协商后的连接直接进入TLS握手，只记录加密方式
+	if payload[0] == 0x16 && payload[1] == 0x03 {
+		assetInfo.Protocols["rdp"] = map[string]interface{}{
+			"tls": true,
+		}
+		return nil
+	}
+
+	// TPKT头: version(1)=3, reserved(1), length(2)
+	if len(payload) < 11 || payload[0] != 0x03 {
+		return nil
+	}
+	tpktLen := int(payload[2])<<8 | int(payload[3])
+	if tpktLen < 11 || tpktLen > len(payload) {
+		return fmt.Errorf("TPKT长度无效: %d", tpktLen)
+	}
+
+	// X.224头: LI(1), code(1), dst-ref(2), src-ref(2), class(1)
+	rdpInfo := make(map[string]interface{})
+	switch payload[5] & 0xF0 {
+	case 0xE0:
+		rdpInfo["type"] = "connection_request"
+	case 0xD0:
+		rdpInfo["type"] = "connection_confirm"
+	default:
+		return nil
+	}
+
+	data := payload[11:tpktLen]
+
+	// 连接请求中可选的cookie: "Cookie: mstshash=NAME\r\n"
+	const cookiePrefix = "Cookie: mstshash="
+	if strings.HasPrefix(string(data), cookiePrefix) {
+		if end := strings.Index(string(data), "\r\n"); end > 0 {
+			cookie := string(data[len(cookiePrefix):end])
+			rdpInfo["cookie"] = cookie
+			data = data[end+2:]
+
+			if pp.looksLikeMachineName(cookie) {
+				setHostname(assetInfo, cookie, "rdp")
+			}
+		}
+	}
+
+	// RDP_NEG_REQ / RDP_NEG_RSP: type(1), flags(1), length(2), protocols(4)
+	if len(data) >= 8 && (data[0] == 0x01 || data[0] == 0x02) {
+		protocols := uint32(data[4]) | uint32(data[5])<<8 | uint32(data[6])<<16 | uint32(data[7])<<24
+		key := "requested_protocols"
+		if data[0] == 0x02 {
+			key = "selected_protocol"
+		}
+		rdpInfo[key] = rdpProtocolNames(protocols)
+		rdpInfo["tls"] = protocols&0x1 != 0 || protocols&0x2 != 0
+		rdpInfo["credssp"] = protocols&0x2 != 0 || protocols&0x8 != 0
+	}
+
+	assetInfo.Protocols["rdp"] = rdpInfo
+
+	return nil
 }
 
 // parseDHCP 解析DHCP协议
-func (pp *PacketParser) parseDHCP(assetInfo *assets.AssetInfo, payload []byte) {
-	// 简化的DHCP解析
+func (pp *PacketParser) parseDHCP(assetInfo *assets.AssetInfo, payload []byte) error {
+	// 简化的DHCP解析，固定头部和魔数共240字节
 	if len(payload) < 240 {
-		return
+		return fmt.Errorf("DHCP报文过短: %d字节", len(payload))
 	}
 
 	// 提取客户端MAC地址 (offset 28, length 6)
@@ -246,57 +685,643 @@ func (pp *PacketParser) parseDHCP(assetInfo *assets.AssetInfo, payload []byte) {
 			assetInfo.Protocols["dhcp"] = options
 
 			if hostname, ok := options["hostname"]; ok {
-				assetInfo.Hostname = hostname.(string)
+				setHostname(assetInfo, hostname.(string), "dhcp")
+			}
+
+			if vendorClass, ok := options["vendor_class"]; ok {
+				setOSGuess(assetInfo, pp.guessOSFromDHCPVendor(vendorClass.(string)), "dhcp_vendor_class")
+			}
+
+			if _, ok := options["wpad_requested"]; ok {
+				assetInfo.Protocols["wpad"] = map[string]interface{}{
+					"source": "dhcp",
+					"query":  "option 252",
+				}
 			}
 		}
 	}
+
+	return nil
 }
 
 // parseDNS 解析DNS协议
-func (pp *PacketParser) parseDNS(assetInfo *assets.AssetInfo, payload []byte) {
+func (pp *PacketParser) parseDNS(assetInfo *assets.AssetInfo, payload []byte) error {
 	// 简化的DNS解析
 	if len(payload) < 12 {
-		return
+		return fmt.Errorf("DNS报文过短: %d字节", len(payload))
 	}
 
-	// 这里可以解析DNS查询和响应，提取域名信息
-	assetInfo.Protocols["dns"] = map[string]interface{}{
+	dnsInfo := map[string]interface{}{
 		"packet_length": len(payload),
 	}
+	assetInfo.Protocols["dns"] = dnsInfo
+
+	dns := &layers.DNS{}
+	if err := dns.DecodeFromBytes(payload, gopacket.NilDecodeFeedback); err != nil {
+		return fmt.Errorf("解码DNS报文失败: %v", err)
+	}
+
+	// 单播DNS-SD，响应中包含服务实例及其目标主机和地址
+	if sdInfo := dnsSDInfo(dns); sdInfo != nil {
+		assetInfo.Protocols["dns_sd"] = sdInfo
+	}
+
+	if dns.QR {
+		// 发送DNS响应的主机是解析服务器
+		setDNSBehavior(assetInfo, "resolver", true)
+		return nil
+	}
+
+	// 记录查询的域名和查询类型
+	queries := make([]string, 0, len(dns.Questions))
+	qtypes := make([]string, 0, len(dns.Questions))
+	for _, q := range dns.Questions {
+		if len(queries) >= maxDNSQueries {
+			break
+		}
+		queries = append(queries, string(q.Name))
+		qtypes = append(qtypes, q.Type.String())
+	}
+	dnsInfo["queries"] = queries
+	dnsInfo["qtypes"] = qtypes
+
+	pp.checkWPAD(assetInfo, "dns", queries)
+
+	return nil
+}
+
+// dnsSDInfo 从DNS报文中提取DNS-SD服务类型和服务实例，mDNS和单播DNS-SD共用
+// 没有DNS-SD内容时返回nil
+func dnsSDInfo(dns *layers.DNS) map[string]interface{} {
+	var serviceTypes []string
+	typeSeen := make(map[string]bool)
+	addType := func(serviceType string) {
+		if serviceType != "" && !typeSeen[serviceType] && len(serviceTypes) < maxDNSSDRecords {
+			typeSeen[serviceType] = true
+			serviceTypes = append(serviceTypes, serviceType)
+		}
+	}
+
+	enumeration := false
+	for _, q := range dns.Questions {
+		name := normalizeDNSName(q.Name)
+		if strings.HasPrefix(name, dnsSDEnumeration) {
+			enumeration = true
+		} else {
+			addType(dnsSDServiceType(name))
+		}
+	}
+
+	records := make([]layers.DNSResourceRecord, 0, len(dns.Answers)+len(dns.Additionals))
+	records = append(records, dns.Answers...)
+	records = append(records, dns.Additionals...)
+
+	// 主机名到地址的映射，用于补全服务实例的地址
+	addresses := make(map[string]string)
+	for _, rr := range records {
+		if (rr.Type == layers.DNSTypeA || rr.Type == layers.DNSTypeAAAA) && rr.IP != nil {
+			addresses[normalizeDNSName(rr.Name)] = rr.IP.String()
+		}
+	}
+
+	var instanceOrder []string
+	instances := make(map[string]map[string]interface{})
+	getInstance := func(rawName []byte, serviceType string) map[string]interface{} {
+		fullName := normalizeDNSName(rawName)
+		if instance, ok := instances[fullName]; ok {
+			return instance
+		}
+		if len(instanceOrder) >= maxDNSSDRecords {
+			return nil
+		}
+
+		// 实例名保留原始大小写，例如 Office Printer
+		displayName := strings.TrimSuffix(string(rawName), ".")
+		if len(displayName) != len(fullName) {
+			displayName = fullName
+		}
+		instance := map[string]interface{}{
+			"name": strings.TrimSuffix(displayName[:strings.Index(fullName, serviceType)], "."),
+			"type": serviceType,
+		}
+		instances[fullName] = instance
+		instanceOrder = append(instanceOrder, fullName)
+		return instance
+	}
+
+	for _, rr := range records {
+		name := normalizeDNSName(rr.Name)
+
+		switch rr.Type {
+		case layers.DNSTypePTR:
+			if strings.HasPrefix(name, dnsSDEnumeration) {
+				enumeration = true
+				addType(dnsSDServiceType(normalizeDNSName(rr.PTR)))
+				continue
+			}
+			serviceType := dnsSDServiceType(name)
+			if serviceType == "" {
+				continue
+			}
+			addType(serviceType)
+
+			// PTR指向服务实例名，例如 Office Printer._ipp._tcp.example.com
+			target := normalizeDNSName(rr.PTR)
+			if dnsSDServiceType(target) == serviceType {
+				getInstance(rr.PTR, serviceType)
+			}
+		case layers.DNSTypeSRV:
+			serviceType := dnsSDServiceType(name)
+			if serviceType == "" {
+				continue
+			}
+			addType(serviceType)
+
+			if instance := getInstance(rr.Name, serviceType); instance != nil {
+				target := normalizeDNSName(rr.SRV.Name)
+				instance["target"] = target
+				instance["port"] = int(rr.SRV.Port)
+				if addr, ok := addresses[target]; ok {
+					instance["address"] = addr
+				}
+			}
+		}
+	}
+
+	if len(serviceTypes) == 0 && !enumeration {
+		return nil
+	}
+
+	instanceList := make([]map[string]interface{}, 0, len(instanceOrder))
+	for _, fullName := range instanceOrder {
+		instanceList = append(instanceList, instances[fullName])
+	}
+
+	return map[string]interface{}{
+		"enumeration":   enumeration,
+		"service_types": serviceTypes,
+		"instances":     instanceList,
+	}
+}
+
+// dnsSDServiceType 从DNS-SD名称中提取服务类型，例如 _ipp._tcp，不是DNS-SD名称时返回空
+func dnsSDServiceType(name string) string {
+	labels := strings.Split(name, ".")
+	for i := 0; i+1 < len(labels); i++ {
+		if strings.HasPrefix(labels[i], "_") && len(labels[i]) > 1 &&
+			(labels[i+1] == "_tcp" || labels[i+1] == "_udp") {
+			return labels[i] + "." + labels[i+1]
+		}
+	}
+	return ""
+}
+
+// setHostname 规范化后设置资产主机名并记录来源协议
+func setHostname(assetInfo *assets.AssetInfo, name, source string) {
+	if hostname := assets.NormalizeHostname(name); hostname != "" {
+		assetInfo.Hostname = hostname
+		assetInfo.HostnameSource = source
+	}
+}
+
+// parseIGMP 解析IGMP成员报告和离开消息，记录主机加入和离开的组播组
+// IPv4成员查询由组播路由器发送，只标记为查询者
+func (pp *PacketParser) parseIGMP(assetInfo *assets.AssetInfo, layer gopacket.Layer) {
+	var joined, left []string
+	igmpInfo := map[string]interface{}{}
+
+	switch igmp := layer.(type) {
+	case *layers.IGMPv1or2:
+		igmpInfo["version"] = igmp.Version
+		switch igmp.Type {
+		case layers.IGMPMembershipReportV1, layers.IGMPMembershipReportV2:
+			joined = append(joined, igmp.GroupAddress.String())
+		case layers.IGMPLeaveGroup:
+			left = append(left, igmp.GroupAddress.String())
+		case layers.IGMPMembershipQuery:
+			igmpInfo["querier"] = true
+		}
+	case *layers.IGMP:
+		igmpInfo["version"] = igmp.Version
+		if igmp.Type == layers.IGMPMembershipQuery {
+			igmpInfo["querier"] = true
+			break
+		}
+		for _, record := range igmp.GroupRecords {
+			group := record.MulticastAddress.String()
+			// 不带源地址的INCLUDE表示离开该组，其余记录表示仍在接收该组流量
+			if (record.Type == layers.IGMPIsIn || record.Type == layers.IGMPToIn) && record.NumberOfSources == 0 {
+				left = append(left, group)
+			} else if record.Type != layers.IGMPBlock {
+				joined = append(joined, group)
+			}
+		}
+	default:
+		return
+	}
+
+	if len(joined) > 0 {
+		igmpInfo["groups"] = joined
+	}
+	if len(left) > 0 {
+		igmpInfo["left"] = left
+	}
+	assetInfo.Protocols["igmp"] = igmpInfo
+}
+
+// setOSGuess 设置操作系统推测结果及检测方法，同一数据包中只采用置信度更高的来源
+func setOSGuess(assetInfo *assets.AssetInfo, family, source string) {
+	if family == "" {
+		return
+	}
+	if assetInfo.OSGuess != "" && assets.OSDetectionConfidence(source) < assets.OSDetectionConfidence(assetInfo.OSSource) {
+		return
+	}
+	assetInfo.OSGuess = family
+	assetInfo.OSSource = source
+}
+
+// normalizeDNSName 转为小写并去掉末尾的点
+func normalizeDNSName(name []byte) string {
+	return strings.TrimSuffix(strings.ToLower(string(name)), ".")
+}
+
+// parseNBNS 解析NetBIOS名称服务查询
+func (pp *PacketParser) parseNBNS(assetInfo *assets.AssetInfo, payload []byte) error {
+	// 头部12字节，之后为长度32的一级编码名称
+	if len(payload) < 12+1+32 || payload[12] != 32 {
+		return nil
+	}
+
+	name := decodeNetBIOSName(payload[13 : 13+32])
+	if name == "" {
+		return fmt.Errorf("NetBIOS名称编码无效")
+	}
+
+	nbnsInfo := map[string]interface{}{
+		"type": "query",
+		"name": name,
+	}
+	if payload[2]&0x80 != 0 {
+		nbnsInfo["type"] = "response"
+	}
+	assetInfo.Protocols["nbns"] = nbnsInfo
+
+	if nbnsInfo["type"] == "query" {
+		pp.checkWPAD(assetInfo, "nbns", []string{name})
+	} else {
+		// 响应方声明自己拥有该名称
+		setHostname(assetInfo, name, "nbns")
+	}
+
+	return nil
+}
+
+// decodeNetBIOSName 解码NetBIOS一级编码名称，去掉末尾的填充和后缀字节
+func decodeNetBIOSName(encoded []byte) string {
+	decoded := make([]byte, 0, 16)
+	for i := 0; i+1 < len(encoded); i += 2 {
+		hi, lo := encoded[i]-'A', encoded[i+1]-'A'
+		if hi > 15 || lo > 15 {
+			return ""
+		}
+		decoded = append(decoded, hi<<4|lo)
+	}
+	if len(decoded) != 16 {
+		return ""
+	}
+
+	return strings.TrimRight(string(decoded[:15]), " \x00")
+}
+
+// stunMagicCookie RFC 5389规定的STUN报文魔数
+const stunMagicCookie = 0x2112A442
+
+// stunMethods STUN/TURN方法名称
+var stunMethods = map[uint16]string{
+	0x001: "binding",
+	0x003: "allocate",
+	0x004: "refresh",
+	0x006: "send",
+	0x007: "data",
+	0x008: "create_permission",
+	0x009: "channel_bind",
+}
+
+// stunClasses STUN报文类别
+var stunClasses = [4]string{"request", "indication", "success_response", "error_response"}
+
+// parseSTUN 解析STUN/TURN报文，记录方法、类别和SOFTWARE属性中的客户端名称
+func (pp *PacketParser) parseSTUN(assetInfo *assets.AssetInfo, payload []byte) error {
+	// 头部20字节: 类型(2) 长度(2) 魔数(4) 事务ID(12)，类型最高两位为0
+	if len(payload) < 20 || payload[0]&0xC0 != 0 {
+		return nil
+	}
+	if binary.BigEndian.Uint32(payload[4:8]) != stunMagicCookie {
+		return nil
+	}
+	msgLen := int(binary.BigEndian.Uint16(payload[2:4]))
+	if msgLen%4 != 0 || 20+msgLen > len(payload) {
+		return fmt.Errorf("STUN报文长度无效: %d", msgLen)
+	}
+
+	msgType := binary.BigEndian.Uint16(payload[0:2])
+	method := msgType&0x000F | (msgType&0x00E0)>>1 | (msgType&0x3E00)>>2
+	class := (msgType&0x0010)>>4 | (msgType&0x0100)>>7
+
+	stunInfo := map[string]interface{}{
+		"class": stunClasses[class],
+	}
+	if name, ok := stunMethods[method]; ok {
+		stunInfo["method"] = name
+		// binding之外的方法属于TURN中继
+		stunInfo["turn"] = method != 0x001
+	} else {
+		stunInfo["method"] = fmt.Sprintf("0x%03x", method)
+	}
+
+	// 属性: 类型(2) 长度(2) 值，按4字节对齐
+	attrs := payload[20 : 20+msgLen]
+	for len(attrs) >= 4 {
+		attrType := binary.BigEndian.Uint16(attrs[0:2])
+		attrLen := int(binary.BigEndian.Uint16(attrs[2:4]))
+		if 4+attrLen > len(attrs) {
+			break
+		}
+		if attrType == 0x8022 { // SOFTWARE
+			if software := strings.TrimSpace(string(attrs[4 : 4+attrLen])); software != "" {
+				stunInfo["software"] = software
+			}
+		}
+
+		next := 4 + (attrLen+3)&^3
+		if next > len(attrs) {
+			break
+		}
+		attrs = attrs[next:]
+	}
+
+	assetInfo.Protocols["stun"] = stunInfo
+
+	return nil
+}
+
+// checkWPAD 检查名称查询中是否包含WPAD代理自动发现
+// 查询wpad的主机会自动获取代理配置，可能遭受WPAD劫持
+func (pp *PacketParser) checkWPAD(assetInfo *assets.AssetInfo, source string, names []string) {
+	for _, name := range names {
+		lower := strings.ToLower(strings.TrimSuffix(name, "."))
+		if lower == "wpad" || strings.HasPrefix(lower, "wpad.") {
+			assetInfo.Protocols["wpad"] = map[string]interface{}{
+				"source": source,
+				"query":  name,
+			}
+			return
+		}
+	}
 }
 
 // parseMDNS 解析mDNS协议
-func (pp *PacketParser) parseMDNS(assetInfo *assets.AssetInfo, payload []byte) {
+func (pp *PacketParser) parseMDNS(assetInfo *assets.AssetInfo, payload []byte) error {
 	// 简化的mDNS解析
 	if len(payload) < 12 {
-		return
+		return fmt.Errorf("mDNS报文过短: %d字节", len(payload))
 	}
 
 	// mDNS通常包含服务发现信息
-	assetInfo.Protocols["mdns"] = map[string]interface{}{
+	mdnsInfo := map[string]interface{}{
 		"packet_length": len(payload),
 	}
+	assetInfo.Protocols["mdns"] = mdnsInfo
+
+	dns := &layers.DNS{}
+	if err := dns.DecodeFromBytes(payload, gopacket.NilDecodeFeedback); err != nil {
+		return fmt.Errorf("解码mDNS报文失败: %v", err)
+	}
+
+	if sdInfo := dnsSDInfo(dns); sdInfo != nil {
+		for k, v := range sdInfo {
+			mdnsInfo[k] = v
+		}
+	}
+
+	// 响应中指向发送方自身地址的A/AAAA记录即为其主机名，例如 macbook.local
+	if dns.QR {
+		for _, answer := range dns.Answers {
+			if answer.IP != nil && answer.IP.String() == assetInfo.IPAddress {
+				setHostname(assetInfo, string(answer.Name), "mdns")
+				break
+			}
+		}
+	}
+
+	return nil
 }
 
-// parseHTTPHeaders 解析HTTP头部
+// parseLLMNR 解析LLMNR协议，报文格式与DNS相同
+func (pp *PacketParser) parseLLMNR(assetInfo *assets.AssetInfo, payload []byte) error {
+	if len(payload) < 12 {
+		return fmt.Errorf("LLMNR报文过短: %d字节", len(payload))
+	}
+
+	dns := &layers.DNS{}
+	if err := dns.DecodeFromBytes(payload, gopacket.NilDecodeFeedback); err != nil {
+		return fmt.Errorf("解码LLMNR报文失败: %v", err)
+	}
+
+	queries := make([]string, 0, len(dns.Questions))
+	for _, q := range dns.Questions {
+		if len(queries) >= maxDNSQueries {
+			break
+		}
+		queries = append(queries, string(q.Name))
+	}
+
+	llmnrInfo := map[string]interface{}{
+		"type":    "query",
+		"queries": queries,
+	}
+
+	if dns.QR {
+		llmnrInfo["type"] = "response"
+
+		answers := make([]string, 0, len(dns.Answers))
+		for _, answer := range dns.Answers {
+			if len(answers) >= maxDNSQueries {
+				break
+			}
+			if answer.IP != nil {
+				answers = append(answers, fmt.Sprintf("%s=%s", answer.Name, answer.IP))
+			}
+		}
+		llmnrInfo["answers"] = answers
+
+		// 响应方声明自己拥有被查询的名称
+		if len(dns.Answers) > 0 && len(dns.Answers[0].Name) > 0 {
+			setHostname(assetInfo, string(dns.Answers[0].Name), "llmnr")
+		}
+	}
+
+	assetInfo.Protocols["llmnr"] = llmnrInfo
+
+	if !dns.QR {
+		pp.checkWPAD(assetInfo, "llmnr", queries)
+	}
+
+	return nil
+}
+
+// parseHTTPHeaders 解析HTTP起始行和头部
+// 重复出现的头部保存为[]string，续行(以空白开头)拼接到上一个头部，头部结束后的内容不再解析
 func (pp *PacketParser) parseHTTPHeaders(httpData string) map[string]interface{} {
 	headers := make(map[string]interface{})
-	lines := strings.Split(httpData, "\r\n")
+	lines := strings.Split(httpData, "\n")
+
+	lastKey := ""
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			if i == 0 {
+				continue
+			}
+			break
+		}
 
-	for _, line := range lines {
-		if strings.Contains(line, ":") {
-			parts := strings.SplitN(line, ":", 2)
-			if len(parts) == 2 {
-				key := strings.ToLower(strings.TrimSpace(parts[0]))
-				value := strings.TrimSpace(parts[1])
-				headers[key] = value
+		if i == 0 {
+			parseHTTPStartLine(headers, line)
+			continue
+		}
+
+		// 折叠的头部续行
+		if line[0] == ' ' || line[0] == '\t' {
+			if lastKey != "" {
+				appendHTTPHeaderFold(headers, lastKey, strings.TrimSpace(line))
 			}
+			continue
 		}
+
+		parts := strings.SplitN(line, ":", 2)
+		key := strings.ToLower(strings.TrimSpace(parts[0]))
+		if len(parts) != 2 || key == "" || strings.ContainsAny(key, " \t") {
+			// 畸形行直接忽略
+			lastKey = ""
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+
+		switch existing := headers[key].(type) {
+		case nil:
+			headers[key] = value
+		case string:
+			headers[key] = []string{existing, value}
+		case []string:
+			headers[key] = append(existing, value)
+		}
+		lastKey = key
 	}
 
 	return headers
 }
 
+// parseHTTPStartLine 解析请求行(GET / HTTP/1.1)或状态行(HTTP/1.1 200 OK)
+func parseHTTPStartLine(headers map[string]interface{}, line string) {
+	fields := strings.SplitN(line, " ", 3)
+	if len(fields) < 2 {
+		return
+	}
+
+	if strings.HasPrefix(fields[0], "HTTP/") {
+		headers["version"] = fields[0]
+		headers["status_code"] = fields[1]
+		return
+	}
+
+	if len(fields) == 3 && strings.HasPrefix(fields[2], "HTTP/") {
+		headers["method"] = fields[0]
+		headers["path"] = fields[1]
+		headers["version"] = fields[2]
+	}
+}
+
+// appendHTTPHeaderFold 将续行内容拼接到头部的最后一个值
+func appendHTTPHeaderFold(headers map[string]interface{}, key, fold string) {
+	switch existing := headers[key].(type) {
+	case string:
+		headers[key] = existing + " " + fold
+	case []string:
+		existing[len(existing)-1] += " " + fold
+	}
+}
+
+// httpHeaderValue 获取头部的第一个值，头部重复出现时取第一次出现的值
+func httpHeaderValue(headers map[string]interface{}, key string) (string, bool) {
+	switch v := headers[key].(type) {
+	case string:
+		return v, true
+	case []string:
+		if len(v) > 0 {
+			return v[0], true
+		}
+	}
+	return "", false
+}
+
+// parseCookieNames 从Cookie或Set-Cookie头部提取Cookie名称
+func (pp *PacketParser) parseCookieNames(httpData, headerName string) []string {
+	names := []string{}
+	seen := make(map[string]bool)
+
+	// 与parseHTTPHeaders一致，兼容只用LF换行的报文，头部结束后不再解析
+	for i, line := range strings.Split(httpData, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" && i > 0 {
+			break
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || strings.ToLower(strings.TrimSpace(parts[0])) != headerName {
+			continue
+		}
+
+		pairs := strings.Split(parts[1], ";")
+		if headerName == "set-cookie" {
+			// Set-Cookie只有第一个键值对是Cookie本身，其余为属性
+			pairs = pairs[:1]
+		}
+
+		for _, pair := range pairs {
+			name := strings.TrimSpace(strings.SplitN(pair, "=", 2)[0])
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+
+			if len(names) >= maxCookieNames {
+				return names
+			}
+		}
+	}
+
+	return names
+}
+
+// guessFrameworkFromCookies 根据Cookie名称推测Web框架或开发语言
+func (pp *PacketParser) guessFrameworkFromCookies(names []string) string {
+	for _, name := range names {
+		if framework, ok := cookieFrameworks[name]; ok {
+			return framework
+		}
+		for _, prefix := range cookiePrefixFrameworks {
+			if strings.HasPrefix(name, prefix.prefix) {
+				return prefix.framework
+			}
+		}
+	}
+
+	return ""
+}
+
 // parseDHCPOptions 解析DHCP选项
 func (pp *PacketParser) parseDHCPOptions(options []byte) map[string]interface{} {
 	result := make(map[string]interface{})
@@ -329,6 +1354,14 @@ func (pp *PacketParser) parseDHCPOptions(options []byte) map[string]interface{}
 			result["domain"] = string(optionData)
 		case 60: // Vendor class identifier
 			result["vendor_class"] = string(optionData)
+		case 61: // Client identifier
+			parseDHCPClientID(optionData, result)
+		case 55: // Parameter request list
+			for _, code := range optionData {
+				if code == 252 { // WPAD URL
+					result["wpad_requested"] = true
+				}
+			}
 		}
 
 		i += 2 + optionLen
@@ -337,6 +1370,24 @@ func (pp *PacketParser) parseDHCPOptions(options []byte) map[string]interface{}
 	return result
 }
 
+// parseDHCPClientID 解析客户端标识：类型1为以太网MAC地址；类型255为RFC 4361格式，
+// 由4字节IAID和DUID组成，同一主机的各网卡IAID不同、DUID相同
+func parseDHCPClientID(data []byte, result map[string]interface{}) {
+	if len(data) < 2 {
+		return
+	}
+	switch {
+	case data[0] == 1 && len(data) == 7:
+		result["client_id"] = net.HardwareAddr(data[1:]).String()
+	case data[0] == 255 && len(data) > 5:
+		result["client_id"] = hex.EncodeToString(data)
+		result["iaid"] = hex.EncodeToString(data[1:5])
+		result["duid"] = hex.EncodeToString(data[5:])
+	default:
+		result["client_id"] = hex.EncodeToString(data)
+	}
+}
+
 // 辅助函数
 func (pp *PacketParser) isMulticastMAC(mac net.HardwareAddr) bool {
 	return len(mac) > 0 && (mac[0]&0x01) != 0
@@ -365,12 +1416,50 @@ func (pp *PacketParser) getVendorFromMAC(mac net.HardwareAddr) string {
 	}
 
 	if vendor, ok := vendors[oui]; ok {
-		return vendor
+		return assets.NormalizeVendor(vendor)
 	}
 
 	return ""
 }
 
+// looksLikeMachineName 判断RDP cookie是否像NetBIOS计算机名而非用户名
+func (pp *PacketParser) looksLikeMachineName(name string) bool {
+	if len(name) == 0 || len(name) > 15 || strings.ToUpper(name) != name {
+		return false
+	}
+
+	for _, c := range name {
+		if !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') && c != '-' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// rdpProtocolNames 将RDP协商的安全协议位转换为名称
+func rdpProtocolNames(protocols uint32) []string {
+	if protocols == 0 {
+		return []string{"rdp"}
+	}
+
+	names := []string{}
+	if protocols&0x1 != 0 {
+		names = append(names, "ssl")
+	}
+	if protocols&0x2 != 0 {
+		names = append(names, "hybrid")
+	}
+	if protocols&0x4 != 0 {
+		names = append(names, "rdstls")
+	}
+	if protocols&0x8 != 0 {
+		names = append(names, "hybrid_ex")
+	}
+
+	return names
+}
+
 func (pp *PacketParser) guessOSFromTTL(ttl uint8) string {
 	// 基于TTL值推测操作系统
 	switch {
@@ -403,39 +1492,108 @@ func (pp *PacketParser) guessOSFromUserAgent(userAgent string) string {
 	return ""
 }
 
-func (pp *PacketParser) identifyService(srcPort, dstPort int, appLayer gopacket.ApplicationLayer) string {
-	// 识别常见服务
-	services := map[int]string{
-		80:    "HTTP",
-		443:   "HTTPS",
-		22:    "SSH",
-		23:    "Telnet",
-		21:    "FTP",
-		25:    "SMTP",
-		110:   "POP3",
-		143:   "IMAP",
-		993:   "IMAPS",
-		995:   "POP3S",
-		3389:  "RDP",
-		5432:  "PostgreSQL",
-		3306:  "MySQL",
-		1433:  "MSSQL",
-		6379:  "Redis",
-		27017: "MongoDB",
+// guessOSFromDHCPVendor 根据DHCP选项60（厂商类别标识）推测操作系统
+func (pp *PacketParser) guessOSFromDHCPVendor(vendorClass string) string {
+	vendorClass = strings.ToLower(vendorClass)
+
+	switch {
+	case strings.HasPrefix(vendorClass, "msft"):
+		return "Windows"
+	case strings.HasPrefix(vendorClass, "android-dhcp"):
+		return "Android"
+	case strings.HasPrefix(vendorClass, "dhcpcd"), strings.HasPrefix(vendorClass, "udhcp"):
+		return "Linux"
+	}
+
+	return ""
+}
+
+// isServerSide 判断TCP报文的发送方是否为监听端
+func (pp *PacketParser) isServerSide(tcp *layers.TCP, srcPort, dstPort int) bool {
+	switch {
+	case tcp.SYN && tcp.ACK:
+		// SYN-ACK 响应表示端口开放
+		return true
+	case tcp.SYN, tcp.RST:
+		// SYN由客户端发起，RST可能来自未开放的端口
+		return false
+	case !tcp.ACK:
+		return false
+	}
+
+	// 已建立的连接中根据端口关系推断服务端口
+	return servicePort(srcPort, dstPort) == srcPort
+}
+
+// servicePort 推断一条TCP连接中的服务端口，无法判断时返回0
+func servicePort(srcPort, dstPort int) int {
+	_, srcKnown := wellKnownServices[srcPort]
+	_, dstKnown := wellKnownServices[dstPort]
+
+	switch {
+	case srcKnown && !dstKnown:
+		return srcPort
+	case dstKnown && !srcKnown:
+		return dstPort
 	}
 
-	if service, ok := services[srcPort]; ok {
-		return service
+	// 均为已知或均未知时取较小的端口，但两端都是临时端口时无法判断
+	port := srcPort
+	if dstPort < srcPort {
+		port = dstPort
 	}
-	if service, ok := services[dstPort]; ok {
-		return service
+	if port >= ephemeralPortStart {
+		return 0
 	}
 
-	return ""
+	return port
+}
+
+// identifyService 识别服务，优先使用报文内容匹配指纹，其次按端口推测
+func (pp *PacketParser) identifyService(port int, appLayer gopacket.ApplicationLayer) *ServiceMatch {
+	if appLayer != nil {
+		if match := pp.serviceMatcher.match(appLayer.Payload()); match != nil {
+			// 只保存匹配到指纹的报文的横幅，其他报文可能是任意的二进制数据
+			match.Banner = serviceBanner(appLayer.Payload(), pp.config.Parser.MaxBannerLength)
+			return match
+		}
+	}
+
+	if service, ok := wellKnownServices[port]; ok {
+		return &ServiceMatch{
+			Name:       service,
+			Confidence: portMatchConfidence,
+		}
+	}
+
+	return nil
+}
+
+// ephemeralPortStart 常见操作系统临时端口范围的起始值
+const ephemeralPortStart = 32768
+
+// wellKnownServices 常见服务端口
+var wellKnownServices = map[int]string{
+	80:    "HTTP",
+	443:   "HTTPS",
+	22:    "SSH",
+	23:    "Telnet",
+	21:    "FTP",
+	25:    "SMTP",
+	110:   "POP3",
+	143:   "IMAP",
+	993:   "IMAPS",
+	995:   "POP3S",
+	3389:  "RDP",
+	5432:  "PostgreSQL",
+	3306:  "MySQL",
+	1433:  "MSSQL",
+	6379:  "Redis",
+	27017: "MongoDB",
 }
 
 func (pp *PacketParser) hasUsefulInfo(assetInfo *assets.AssetInfo) bool {
 	return assetInfo.IPAddress != "" || assetInfo.MACAddress != "" ||
 		assetInfo.Hostname != "" || len(assetInfo.OpenPorts) > 0 ||
-		len(assetInfo.Services) > 0 || len(assetInfo.Protocols) > 0
+		len(assetInfo.Services) > 0 || len(assetInfo.OutboundServices) > 0 || len(assetInfo.Protocols) > 0
 }
@@ -0,0 +1,1277 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"assets_discovery/internal/assets"
+	"assets_discovery/internal/config"
+	"assets_discovery/internal/storage"
+)
+
+// newTestParser 创建只启用指定协议的解析器
+func newTestParser(protocols ...string) *PacketParser {
+	cfg := &config.Config{}
+	cfg.Parser.EnabledProtocols = protocols
+	return NewPacketParser(cfg)
+}
+
+// newTestAssetInfo 创建解析HTTP等应用层协议所需的资产信息
+func newTestAssetInfo() *assets.AssetInfo {
+	return &assets.AssetInfo{
+		IPAddress: "192.168.1.10",
+		Protocols: make(map[string]interface{}),
+	}
+}
+
+// buildPacket 序列化各层并按捕获时间ts解码为数据包
+func buildPacket(t *testing.T, ts time.Time, layerList ...gopacket.SerializableLayer) gopacket.Packet {
+	t.Helper()
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, layerList...); err != nil {
+		t.Fatalf("SerializeLayers() error = %v", err)
+	}
+
+	data := buf.Bytes()
+	packet := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+	packet.Metadata().Timestamp = ts
+	packet.Metadata().CaptureLength = len(data)
+	packet.Metadata().Length = len(data)
+	return packet
+}
+
+// arpPacket 构造mac/ip发出的ARP请求
+func arpPacket(t *testing.T, ts time.Time, mac, ip string) gopacket.Packet {
+	t.Helper()
+
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		t.Fatalf("ParseMAC(%s) error = %v", mac, err)
+	}
+	eth := &layers.Ethernet{
+		SrcMAC:       hw,
+		DstMAC:       net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		EthernetType: layers.EthernetTypeARP,
+	}
+	arp := &layers.ARP{
+		AddrType:          layers.LinkTypeEthernet,
+		Protocol:          layers.EthernetTypeIPv4,
+		HwAddressSize:     6,
+		ProtAddressSize:   4,
+		Operation:         layers.ARPRequest,
+		SourceHwAddress:   hw,
+		SourceProtAddress: net.ParseIP(ip).To4(),
+		DstHwAddress:      make([]byte, 6),
+		DstProtAddress:    net.IPv4(192, 168, 1, 1).To4(),
+	}
+	return buildPacket(t, ts, eth, arp)
+}
+
+func TestOfflineTimestamps(t *testing.T) {
+	base := time.Date(2019, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		offsets       []time.Duration
+		wantFirstSeen time.Duration
+		wantLastSeen  time.Duration
+	}{
+		{"single packet", []time.Duration{0}, 0, 0},
+		{"in order", []time.Duration{0, time.Minute, time.Hour}, 0, time.Hour},
+		{"out of order across workers", []time.Duration{time.Minute, 0, time.Hour, 30 * time.Second}, 0, time.Hour},
+	}
+
+	pp := newTestParser("arp")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Storage.NoStore = true
+			am := assets.NewAssetManager(cfg, storage.NewMemoryStorage())
+
+			for _, offset := range tt.offsets {
+				assetInfo := pp.ParsePacket(arpPacket(t, base.Add(offset), "00:1a:2b:3c:4d:5e", "192.168.1.20"))
+				if assetInfo == nil {
+					t.Fatalf("ParsePacket() returned nil for ARP request")
+				}
+				if !assetInfo.Timestamp.Equal(base.Add(offset)) {
+					t.Fatalf("Timestamp = %v, want packet time %v", assetInfo.Timestamp, base.Add(offset))
+				}
+				am.UpdateAsset(assetInfo)
+			}
+
+			asset, ok := am.GetAsset("mac_00:1a:2b:3c:4d:5e")
+			if !ok {
+				t.Fatalf("asset not created")
+			}
+			data, _ := asset.MarshalJSON()
+			var got struct {
+				FirstSeen time.Time `json:"first_seen"`
+				LastSeen  time.Time `json:"last_seen"`
+			}
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("decode asset: %v", err)
+			}
+			if !got.FirstSeen.Equal(base.Add(tt.wantFirstSeen)) {
+				t.Errorf("FirstSeen = %v, want %v", got.FirstSeen, base.Add(tt.wantFirstSeen))
+			}
+			if !got.LastSeen.Equal(base.Add(tt.wantLastSeen)) {
+				t.Errorf("LastSeen = %v, want %v", got.LastSeen, base.Add(tt.wantLastSeen))
+			}
+		})
+	}
+}
+
+// ipv4Layer 构造携带指定上层协议的IPv4头部
+func ipv4Layer(src, dst string, protocol layers.IPProtocol) *layers.IPv4 {
+	return &layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: protocol,
+		SrcIP:    net.ParseIP(src).To4(),
+		DstIP:    net.ParseIP(dst).To4(),
+	}
+}
+
+// synAckLayers 构造ip的port端口回应的SYN-ACK报文
+func synAckLayers(ip string, port layers.TCPPort) []gopacket.SerializableLayer {
+	ipv4 := ipv4Layer(ip, "192.168.1.10", layers.IPProtocolTCP)
+	tcp := &layers.TCP{SrcPort: port, DstPort: 51000, SYN: true, ACK: true, Window: 65535}
+	tcp.SetNetworkLayerForChecksum(ipv4)
+	return []gopacket.SerializableLayer{ipv4, tcp}
+}
+
+func TestParseIPTunnel(t *testing.T) {
+	routerMAC := net.HardwareAddr{0x00, 0x1a, 0x2b, 0x00, 0x00, 0xfe}
+	outerEth := func() gopacket.SerializableLayer {
+		return &layers.Ethernet{SrcMAC: routerMAC, DstMAC: routerMAC, EthernetType: layers.EthernetTypeIPv4}
+	}
+	innerEth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e},
+		DstMAC:       routerMAC,
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+
+	tests := []struct {
+		name       string
+		protocols  []string
+		layers     []gopacket.SerializableLayer
+		wantIP     string
+		wantMAC    string
+		wantPort   int
+		wantTunnel map[string]interface{}
+	}{
+		{
+			name:      "gre with key",
+			protocols: []string{"gre"},
+			layers: append([]gopacket.SerializableLayer{
+				outerEth(),
+				ipv4Layer("203.0.113.1", "203.0.113.2", layers.IPProtocolGRE),
+				&layers.GRE{KeyPresent: true, Key: 42, Protocol: layers.EthernetTypeIPv4},
+			}, synAckLayers("172.16.0.5", 443)...),
+			wantIP:     "172.16.0.5",
+			wantPort:   443,
+			wantTunnel: map[string]interface{}{"type": "gre", "tunnel_src": "203.0.113.1", "tunnel_dst": "203.0.113.2", "inner_protocol": "IPv4", "key": uint32(42)},
+		},
+		{
+			name:      "ip in ip",
+			protocols: []string{"gre"},
+			layers: append([]gopacket.SerializableLayer{
+				outerEth(),
+				ipv4Layer("203.0.113.1", "203.0.113.2", layers.IPProtocolIPv4),
+			}, synAckLayers("172.16.0.6", 22)...),
+			wantIP:     "172.16.0.6",
+			wantPort:   22,
+			wantTunnel: map[string]interface{}{"type": "ipip", "tunnel_src": "203.0.113.1", "tunnel_dst": "203.0.113.2"},
+		},
+		{
+			name:      "gre transparent ethernet bridging",
+			protocols: []string{"gre"},
+			layers: append([]gopacket.SerializableLayer{
+				outerEth(),
+				ipv4Layer("203.0.113.1", "203.0.113.2", layers.IPProtocolGRE),
+				&layers.GRE{Protocol: layers.EthernetTypeTransparentEthernetBridging},
+				innerEth,
+			}, synAckLayers("172.16.0.7", 80)...),
+			wantIP:     "172.16.0.7",
+			wantMAC:    "00:1a:2b:3c:4d:5e",
+			wantPort:   80,
+			wantTunnel: map[string]interface{}{"type": "gre", "tunnel_src": "203.0.113.1", "tunnel_dst": "203.0.113.2", "inner_protocol": "TransparentEthernetBridging"},
+		},
+		{
+			name:      "nested gre keeps innermost tunnel",
+			protocols: []string{"gre"},
+			layers: append([]gopacket.SerializableLayer{
+				outerEth(),
+				ipv4Layer("203.0.113.1", "203.0.113.2", layers.IPProtocolGRE),
+				&layers.GRE{Protocol: layers.EthernetTypeIPv4},
+				ipv4Layer("10.255.0.1", "10.255.0.2", layers.IPProtocolGRE),
+				&layers.GRE{Protocol: layers.EthernetTypeIPv4},
+			}, synAckLayers("172.16.0.8", 443)...),
+			wantIP:     "172.16.0.8",
+			wantPort:   443,
+			wantTunnel: map[string]interface{}{"type": "gre", "tunnel_src": "10.255.0.1", "tunnel_dst": "10.255.0.2", "inner_protocol": "IPv4"},
+		},
+		{
+			name:      "gre disabled attributes tunnel endpoint",
+			protocols: []string{},
+			layers: append([]gopacket.SerializableLayer{
+				outerEth(),
+				ipv4Layer("203.0.113.1", "203.0.113.2", layers.IPProtocolGRE),
+				&layers.GRE{Protocol: layers.EthernetTypeIPv4},
+			}, synAckLayers("172.16.0.9", 443)...),
+			wantIP:  "203.0.113.1",
+			wantMAC: routerMAC.String(),
+		},
+	}
+
+	ts := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assetInfo := newTestParser(tt.protocols...).ParsePacket(buildPacket(t, ts, tt.layers...))
+			if assetInfo == nil {
+				t.Fatalf("ParsePacket() returned nil")
+			}
+			if assetInfo.IPAddress != tt.wantIP {
+				t.Errorf("IPAddress = %q, want %q", assetInfo.IPAddress, tt.wantIP)
+			}
+			if assetInfo.MACAddress != tt.wantMAC {
+				t.Errorf("MACAddress = %q, want %q", assetInfo.MACAddress, tt.wantMAC)
+			}
+			if tt.wantPort != 0 && !reflect.DeepEqual(assetInfo.OpenPorts, []int{tt.wantPort}) {
+				t.Errorf("OpenPorts = %v, want [%d]", assetInfo.OpenPorts, tt.wantPort)
+			}
+
+			tunnel, ok := assetInfo.Protocols["gre"]
+			if tt.wantTunnel == nil {
+				if ok {
+					t.Errorf("unexpected tunnel info %v", tunnel)
+				}
+				return
+			}
+			if !reflect.DeepEqual(tunnel, tt.wantTunnel) {
+				t.Errorf("tunnel info = %v, want %v", tunnel, tt.wantTunnel)
+			}
+		})
+	}
+}
+
+func TestParseCookieNames(t *testing.T) {
+	response := []string{
+		"HTTP/1.1 200 OK",
+		"Server: nginx",
+		"Set-Cookie: JSESSIONID=abc123; Path=/; HttpOnly",
+		"Set-Cookie: lang=en; Path=/",
+		"",
+		"Set-Cookie: body=ignored",
+	}
+	request := []string{
+		"GET / HTTP/1.1",
+		"Host: app.example.com",
+		"Cookie: csrftoken=x; sessionid=y; csrftoken=z",
+		"",
+		"",
+	}
+
+	tests := []struct {
+		name       string
+		lines      []string
+		headerName string
+		want       []string
+	}{
+		{"set-cookie crlf", response, "set-cookie", []string{"JSESSIONID", "lang"}},
+		{"cookie crlf", request, "cookie", []string{"csrftoken", "sessionid"}},
+		{"set-cookie lf", response, "set-cookie", []string{"JSESSIONID", "lang"}},
+		{"cookie lf", request, "cookie", []string{"csrftoken", "sessionid"}},
+		{"no header", []string{"GET / HTTP/1.1", "Host: a", "", ""}, "cookie", []string{}},
+	}
+
+	pp := newTestParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sep := "\r\n"
+			if strings.HasSuffix(tt.name, " lf") {
+				sep = "\n"
+			}
+			got := pp.parseCookieNames(strings.Join(tt.lines, sep), tt.headerName)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCookieNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCookieNamesCap(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("GET / HTTP/1.1\r\nCookie: ")
+	for i := 0; i < maxCookieNames+5; i++ {
+		b.WriteString("c")
+		b.WriteString(strings.Repeat("x", i))
+		b.WriteString("=1; ")
+	}
+	b.WriteString("\r\n\r\n")
+
+	got := newTestParser().parseCookieNames(b.String(), "cookie")
+	if len(got) != maxCookieNames {
+		t.Errorf("got %d cookie names, want %d", len(got), maxCookieNames)
+	}
+}
+
+func TestParseHTTPCookieFramework(t *testing.T) {
+	tests := []struct {
+		name          string
+		payload       string
+		wantFramework string
+		wantPeer      string
+	}{
+		{
+			name:          "set-cookie crlf",
+			payload:       "HTTP/1.1 200 OK\r\nSet-Cookie: PHPSESSID=secret; path=/\r\n\r\n",
+			wantFramework: "PHP",
+		},
+		{
+			name:          "set-cookie lf",
+			payload:       "HTTP/1.1 200 OK\nSet-Cookie: ASP.NET_SessionId=secret; path=/\n\n",
+			wantFramework: "ASP.NET",
+		},
+		{
+			name:     "cookie lf",
+			payload:  "GET / HTTP/1.1\nHost: app\nCookie: laravel_session=secret\n\n",
+			wantPeer: "Laravel",
+		},
+		{
+			name:          "prefix match",
+			payload:       "HTTP/1.1 302 Found\r\nSet-Cookie: wordpress_logged_in_abc=secret\r\n\r\n",
+			wantFramework: "WordPress",
+		},
+	}
+
+	pp := newTestParser("http")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assetInfo := newTestAssetInfo()
+			if err := pp.parseHTTP(assetInfo, []byte(tt.payload)); err != nil {
+				t.Fatalf("parseHTTP() error = %v", err)
+			}
+
+			headers, ok := assetInfo.Protocols["http"].(map[string]interface{})
+			if !ok {
+				t.Fatalf("http protocol info missing")
+			}
+			if got, _ := headers["framework"].(string); got != tt.wantFramework {
+				t.Errorf("framework = %q, want %q", got, tt.wantFramework)
+			}
+			if got, _ := headers["peer_framework"].(string); got != tt.wantPeer {
+				t.Errorf("peer_framework = %q, want %q", got, tt.wantPeer)
+			}
+			if _, ok := headers["cookie"]; ok {
+				t.Errorf("raw cookie header kept")
+			}
+			if _, ok := headers["set-cookie"]; ok {
+				t.Errorf("raw set-cookie header kept")
+			}
+			// 框架只是HTTP协议信息，不作为监听服务
+			if _, ok := assetInfo.Services["http_framework"]; ok {
+				t.Errorf("framework recorded as service: %v", assetInfo.Services)
+			}
+		})
+	}
+}
+
+// tcpSegment 构造以太网上src:srcPort发往dst:dstPort的TCP报文
+func tcpSegment(t *testing.T, ts time.Time, srcMAC, src string, srcPort layers.TCPPort, dst string, dstPort layers.TCPPort, flags string, payload string) gopacket.Packet {
+	t.Helper()
+
+	hw, err := net.ParseMAC(srcMAC)
+	if err != nil {
+		t.Fatalf("ParseMAC(%s) error = %v", srcMAC, err)
+	}
+	eth := &layers.Ethernet{SrcMAC: hw, DstMAC: net.HardwareAddr{0x00, 0x1a, 0x2b, 0x00, 0x00, 0xfe}, EthernetType: layers.EthernetTypeIPv4}
+	ipv4 := ipv4Layer(src, dst, layers.IPProtocolTCP)
+	tcp := &layers.TCP{
+		SrcPort: srcPort,
+		DstPort: dstPort,
+		SYN:     strings.Contains(flags, "S"),
+		ACK:     strings.Contains(flags, "A"),
+		PSH:     payload != "",
+		Window:  65535,
+	}
+	tcp.SetNetworkLayerForChecksum(ipv4)
+
+	stack := []gopacket.SerializableLayer{eth, ipv4, tcp}
+	if payload != "" {
+		stack = append(stack, gopacket.Payload(payload))
+	}
+	return buildPacket(t, ts, stack...)
+}
+
+// openPorts 返回资产中状态为open的TCP端口
+func openPorts(asset *assets.Asset) []int {
+	ports := []int{}
+	for _, p := range asset.OpenPorts {
+		if p.Protocol == "tcp" && p.State == "open" {
+			ports = append(ports, p.Port)
+		}
+	}
+	return ports
+}
+
+func TestTCPServerAttribution(t *testing.T) {
+	const (
+		clientMAC = "00:1a:2b:3c:4d:01"
+		clientIP  = "192.168.1.20"
+		serverMAC = "00:1a:2b:3c:4d:02"
+		serverIP  = "192.168.1.30"
+	)
+
+	tests := []struct {
+		name       string
+		serverPort layers.TCPPort
+		clientPort layers.TCPPort
+		withSYN    bool
+		request    string
+		response   string
+	}{
+		{"well-known port with handshake", 80, 51515, true, "GET / HTTP/1.1\r\nHost: a\r\n\r\n", "HTTP/1.1 200 OK\r\nServer: nginx\r\n\r\n"},
+		{"unknown low port with handshake", 8123, 50000, true, "ping", "pong"},
+		// 捕获开始时连接已建立，只看到数据报文，按端口关系判断服务端
+		{"well-known port data only", 22, 40022, false, "SSH-2.0-client\r\n", "SSH-2.0-OpenSSH_8.9\r\n"},
+		{"client port below ephemeral range", 443, 1025, false, "\x16\x03\x01", "\x16\x03\x03"},
+		{"unknown low port data only", 9000, 61000, false, "req", "resp"},
+	}
+
+	pp := newTestParser("http")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Storage.NoStore = true
+			am := assets.NewAssetManager(cfg, storage.NewMemoryStorage())
+
+			ts := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+			var packets []gopacket.Packet
+			if tt.withSYN {
+				packets = append(packets,
+					tcpSegment(t, ts, clientMAC, clientIP, tt.clientPort, serverIP, tt.serverPort, "S", ""),
+					tcpSegment(t, ts, serverMAC, serverIP, tt.serverPort, clientIP, tt.clientPort, "SA", ""),
+					tcpSegment(t, ts, clientMAC, clientIP, tt.clientPort, serverIP, tt.serverPort, "A", ""),
+				)
+			}
+			packets = append(packets,
+				tcpSegment(t, ts, clientMAC, clientIP, tt.clientPort, serverIP, tt.serverPort, "A", tt.request),
+				tcpSegment(t, ts, serverMAC, serverIP, tt.serverPort, clientIP, tt.clientPort, "A", tt.response),
+				tcpSegment(t, ts, clientMAC, clientIP, tt.clientPort, serverIP, tt.serverPort, "A", ""),
+			)
+
+			for _, packet := range packets {
+				if assetInfo := pp.ParsePacket(packet); assetInfo != nil {
+					am.UpdateAsset(assetInfo)
+				}
+			}
+
+			server, ok := am.GetAssetByIP(serverIP)
+			if !ok {
+				t.Fatalf("server asset not created")
+			}
+			if got := openPorts(server); !reflect.DeepEqual(got, []int{int(tt.serverPort)}) {
+				t.Errorf("server open ports = %v, want [%d]", got, tt.serverPort)
+			}
+
+			client, ok := am.GetAssetByIP(clientIP)
+			if !ok {
+				t.Fatalf("client asset not created")
+			}
+			if got := openPorts(client); len(got) != 0 {
+				t.Errorf("client open ports = %v, want none (ephemeral port %d)", got, tt.clientPort)
+			}
+		})
+	}
+}
+
+func TestServiceDirection(t *testing.T) {
+	const (
+		clientMAC = "00:1a:2b:3c:4d:01"
+		clientIP  = "192.168.1.20"
+		serverMAC = "00:1a:2b:3c:4d:02"
+		serverIP  = "192.168.1.30"
+	)
+
+	cfg := &config.Config{}
+	cfg.Storage.NoStore = true
+	am := assets.NewAssetManager(cfg, storage.NewMemoryStorage())
+	pp := newTestParser()
+
+	// 客户端连接服务端的SSH端口，双方交换版本横幅
+	ts := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, packet := range []gopacket.Packet{
+		tcpSegment(t, ts, clientMAC, clientIP, 50022, serverIP, 22, "S", ""),
+		tcpSegment(t, ts, serverMAC, serverIP, 22, clientIP, 50022, "SA", ""),
+		tcpSegment(t, ts, clientMAC, clientIP, 50022, serverIP, 22, "A", ""),
+		tcpSegment(t, ts, serverMAC, serverIP, 22, clientIP, 50022, "A", "SSH-2.0-OpenSSH_8.9\r\n"),
+		tcpSegment(t, ts, clientMAC, clientIP, 50022, serverIP, 22, "A", "SSH-2.0-PuTTY_0.78\r\n"),
+	} {
+		if assetInfo := pp.ParsePacket(packet); assetInfo != nil {
+			am.UpdateAsset(assetInfo)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		ip            string
+		wantPorts     map[int]string
+		wantServices  map[string]string
+		wantListening int
+		wantOutbound  int
+	}{
+		{
+			name:          "server",
+			ip:            serverIP,
+			wantPorts:     map[int]string{22: "listening"},
+			wantServices:  map[string]string{"22/tcp": "listening"},
+			wantListening: 1,
+		},
+		{
+			name:         "client",
+			ip:           clientIP,
+			wantPorts:    map[int]string{},
+			wantServices: map[string]string{"22/tcp": "outbound"},
+			wantOutbound: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asset, ok := am.GetAssetByIP(tt.ip)
+			if !ok {
+				t.Fatalf("GetAssetByIP(%s) not found", tt.ip)
+			}
+
+			ports := make(map[int]string)
+			for _, p := range asset.OpenPorts {
+				ports[p.Port] = p.Direction
+			}
+			if !reflect.DeepEqual(ports, tt.wantPorts) {
+				t.Errorf("port directions = %v, want %v", ports, tt.wantPorts)
+			}
+
+			services := make(map[string]string)
+			for _, s := range asset.Services {
+				services[s.Name] = s.Direction
+			}
+			if !reflect.DeepEqual(services, tt.wantServices) {
+				t.Errorf("service directions = %v, want %v", services, tt.wantServices)
+			}
+
+			summary := asset.GetSummary()
+			if got := summary["services_count"]; got != tt.wantListening {
+				t.Errorf("services_count = %v, want %d", got, tt.wantListening)
+			}
+			if got := summary["outbound_services_count"]; got != tt.wantOutbound {
+				t.Errorf("outbound_services_count = %v, want %d", got, tt.wantOutbound)
+			}
+		})
+	}
+}
+
+// serialize 序列化各层为字节
+func serialize(t *testing.T, layerList ...gopacket.SerializableLayer) []byte {
+	t.Helper()
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, layerList...); err != nil {
+		t.Fatalf("SerializeLayers() error = %v", err)
+	}
+	return append([]byte(nil), buf.Bytes()...)
+}
+
+// vxlanFrame 将inner以太网帧封装为vtep发往UDP port的VXLAN报文
+func vxlanFrame(t *testing.T, vtep string, port layers.UDPPort, vni uint32, inner []byte) []byte {
+	t.Helper()
+
+	vtepMAC := net.HardwareAddr{0x00, 0x50, 0x56, 0x00, 0x00, 0x01}
+	eth := &layers.Ethernet{SrcMAC: vtepMAC, DstMAC: vtepMAC, EthernetType: layers.EthernetTypeIPv4}
+	ipv4 := ipv4Layer(vtep, "198.51.100.2", layers.IPProtocolUDP)
+	udp := &layers.UDP{SrcPort: 49152, DstPort: port}
+	udp.SetNetworkLayerForChecksum(ipv4)
+
+	// 标志位I表示VNI有效，VNI占第4-6字节
+	header := []byte{0x08, 0, 0, 0, byte(vni >> 16), byte(vni >> 8), byte(vni), 0}
+	return serialize(t, eth, ipv4, udp, gopacket.Payload(append(header, inner...)))
+}
+
+func TestParseVXLAN(t *testing.T) {
+	tenantMAC := net.HardwareAddr{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e}
+	innerEth := &layers.Ethernet{SrcMAC: tenantMAC, DstMAC: tenantMAC, EthernetType: layers.EthernetTypeIPv4}
+	inner := serialize(t, append([]gopacket.SerializableLayer{innerEth}, synAckLayers("172.16.0.5", 443)...)...)
+
+	// nest 将帧按VXLAN封装n层，最外层VTEP为198.51.100.1
+	nest := func(n int) []byte {
+		frame := inner
+		for i := n; i > 0; i-- {
+			frame = vxlanFrame(t, fmt.Sprintf("198.51.100.%d", i), 4789, uint32(1000+i), frame)
+		}
+		return frame
+	}
+
+	tests := []struct {
+		name      string
+		protocols []string
+		frame     []byte
+		wantIP    string
+		wantMAC   string
+		wantVXLAN map[string]interface{}
+	}{
+		{
+			name:      "standard port",
+			protocols: []string{"vxlan"},
+			frame:     vxlanFrame(t, "198.51.100.1", 4789, 5001, inner),
+			wantIP:    "172.16.0.5",
+			wantMAC:   tenantMAC.String(),
+			wantVXLAN: map[string]interface{}{"vni": uint32(5001), "vtep_src": "198.51.100.1", "vtep_dst": "198.51.100.2"},
+		},
+		{
+			name:      "linux kernel port",
+			protocols: []string{"vxlan"},
+			frame:     vxlanFrame(t, "198.51.100.1", vxlanLinuxPort, 0xabcdef, inner),
+			wantIP:    "172.16.0.5",
+			wantMAC:   tenantMAC.String(),
+			wantVXLAN: map[string]interface{}{"vni": uint32(0xabcdef), "vtep_src": "198.51.100.1", "vtep_dst": "198.51.100.2"},
+		},
+		{
+			name:      "nested keeps innermost vni",
+			protocols: []string{"vxlan"},
+			frame:     nest(maxEncapDepth),
+			wantIP:    "172.16.0.5",
+			wantMAC:   tenantMAC.String(),
+			wantVXLAN: map[string]interface{}{"vni": uint32(1000 + maxEncapDepth), "vtep_src": fmt.Sprintf("198.51.100.%d", maxEncapDepth), "vtep_dst": "198.51.100.2"},
+		},
+		{
+			name:      "nesting beyond limit stops at a vtep",
+			protocols: []string{"vxlan"},
+			frame:     nest(maxEncapDepth + 1),
+			wantIP:    fmt.Sprintf("198.51.100.%d", maxEncapDepth+1),
+			wantMAC:   "00:50:56:00:00:01",
+			wantVXLAN: map[string]interface{}{"vni": uint32(1000 + maxEncapDepth), "vtep_src": fmt.Sprintf("198.51.100.%d", maxEncapDepth), "vtep_dst": "198.51.100.2"},
+		},
+		{
+			name:    "vxlan disabled attributes vtep",
+			frame:   vxlanFrame(t, "198.51.100.1", 4789, 5001, inner),
+			wantIP:  "198.51.100.1",
+			wantMAC: "00:50:56:00:00:01",
+		},
+	}
+
+	ts := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packet := gopacket.NewPacket(tt.frame, layers.LayerTypeEthernet, gopacket.Default)
+			packet.Metadata().Timestamp = ts
+
+			assetInfo := newTestParser(tt.protocols...).ParsePacket(packet)
+			if assetInfo == nil {
+				t.Fatalf("ParsePacket() returned nil")
+			}
+			if assetInfo.IPAddress != tt.wantIP || assetInfo.MACAddress != tt.wantMAC {
+				t.Errorf("asset = %s/%s, want %s/%s", assetInfo.IPAddress, assetInfo.MACAddress, tt.wantIP, tt.wantMAC)
+			}
+
+			vxlan, ok := assetInfo.Protocols["vxlan"]
+			if tt.wantVXLAN == nil {
+				if ok {
+					t.Errorf("unexpected vxlan info %v", vxlan)
+				}
+				return
+			}
+			if !reflect.DeepEqual(vxlan, tt.wantVXLAN) {
+				t.Errorf("vxlan info = %v, want %v", vxlan, tt.wantVXLAN)
+			}
+			if tt.wantIP == "172.16.0.5" && !reflect.DeepEqual(assetInfo.OpenPorts, []int{443}) {
+				t.Errorf("OpenPorts = %v, want [443]", assetInfo.OpenPorts)
+			}
+		})
+	}
+}
+
+func TestParseDNSServiceDiscovery(t *testing.T) {
+	printer := &layers.DNS{
+		QR:        true,
+		AA:        true,
+		Questions: []layers.DNSQuestion{{Name: []byte("_ipp._tcp.example.com"), Type: layers.DNSTypePTR, Class: layers.DNSClassIN}},
+		Answers: []layers.DNSResourceRecord{
+			{Name: []byte("_ipp._tcp.example.com"), Type: layers.DNSTypePTR, Class: layers.DNSClassIN, TTL: 120,
+				PTR: []byte("Office Printer._ipp._tcp.example.com")},
+			{Name: []byte("Office Printer._ipp._tcp.example.com"), Type: layers.DNSTypeSRV, Class: layers.DNSClassIN, TTL: 120,
+				SRV: layers.DNSSRV{Port: 631, Name: []byte("printer.example.com")}},
+			{Name: []byte("Office Printer._ipp._tcp.example.com"), Type: layers.DNSTypeTXT, Class: layers.DNSClassIN, TTL: 120,
+				TXTs: [][]byte{[]byte("txtvers=1"), []byte("rp=ipp/print"), []byte("ty=LaserJet")}},
+		},
+		Additionals: []layers.DNSResourceRecord{
+			{Name: []byte("printer.example.com"), Type: layers.DNSTypeA, Class: layers.DNSClassIN, TTL: 120,
+				IP: net.IPv4(192, 168, 1, 50).To4()},
+		},
+	}
+	enumeration := &layers.DNS{
+		QR:        true,
+		Questions: []layers.DNSQuestion{{Name: []byte("_services._dns-sd._udp.example.com"), Type: layers.DNSTypePTR, Class: layers.DNSClassIN}},
+		Answers: []layers.DNSResourceRecord{
+			{Name: []byte("_services._dns-sd._udp.example.com"), Type: layers.DNSTypePTR, Class: layers.DNSClassIN, TTL: 120,
+				PTR: []byte("_ipp._tcp.example.com")},
+			{Name: []byte("_services._dns-sd._udp.example.com"), Type: layers.DNSTypePTR, Class: layers.DNSClassIN, TTL: 120,
+				PTR: []byte("_airplay._tcp.example.com")},
+		},
+	}
+	plain := &layers.DNS{
+		QR:        true,
+		Questions: []layers.DNSQuestion{{Name: []byte("www.example.com"), Type: layers.DNSTypeA, Class: layers.DNSClassIN}},
+		Answers: []layers.DNSResourceRecord{
+			{Name: []byte("www.example.com"), Type: layers.DNSTypeA, Class: layers.DNSClassIN, TTL: 60,
+				IP: net.IPv4(93, 184, 216, 34).To4()},
+		},
+	}
+
+	tests := []struct {
+		name string
+		dns  *layers.DNS
+		want map[string]interface{}
+	}{
+		{"instance with srv txt and address", printer, map[string]interface{}{
+			"enumeration":   false,
+			"service_types": []string{"_ipp._tcp"},
+			"instances": []map[string]interface{}{{
+				"name":    "Office Printer",
+				"type":    "_ipp._tcp",
+				"target":  "printer.example.com",
+				"port":    631,
+				"address": "192.168.1.50",
+			}},
+		}},
+		{"service type enumeration", enumeration, map[string]interface{}{
+			"enumeration":   true,
+			"service_types": []string{"_ipp._tcp", "_airplay._tcp"},
+			"instances":     []map[string]interface{}{},
+		}},
+		{"plain dns", plain, nil},
+	}
+
+	pp := newTestParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assetInfo := newTestAssetInfo()
+			if err := pp.parseDNS(assetInfo, serialize(t, tt.dns)); err != nil {
+				t.Fatalf("parseDNS() error = %v", err)
+			}
+
+			got, ok := assetInfo.Protocols["dns_sd"]
+			if tt.want == nil {
+				if ok {
+					t.Errorf("dns_sd = %v, want none", got)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dns_sd = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHTTPHeaders(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want map[string]interface{}
+	}{
+		{
+			name: "request line",
+			data: "GET /index.html HTTP/1.1\r\nHost: example.com\r\nUser-Agent: curl/8.0\r\n\r\n",
+			want: map[string]interface{}{
+				"method": "GET", "path": "/index.html", "version": "HTTP/1.1",
+				"host": "example.com", "user-agent": "curl/8.0",
+			},
+		},
+		{
+			name: "status line with duplicate headers",
+			data: "HTTP/1.1 200 OK\r\nSet-Cookie: a=1\r\nServer: nginx\r\nSet-Cookie: b=2\r\nset-cookie: c=3\r\n\r\n",
+			want: map[string]interface{}{
+				"version": "HTTP/1.1", "status_code": "200", "server": "nginx",
+				"set-cookie": []string{"a=1", "b=2", "c=3"},
+			},
+		},
+		{
+			name: "folded header",
+			data: "HTTP/1.1 200 OK\r\nX-Long: first\r\n  second\r\n\tthird\r\nServer: nginx\r\n\r\n",
+			want: map[string]interface{}{
+				"version": "HTTP/1.1", "status_code": "200",
+				"x-long": "first second third", "server": "nginx",
+			},
+		},
+		{
+			name: "folded duplicate header",
+			data: "HTTP/1.1 200 OK\r\nVia: a\r\nVia: b\r\n c\r\n\r\n",
+			want: map[string]interface{}{
+				"version": "HTTP/1.1", "status_code": "200",
+				"via": []string{"a", "b c"},
+			},
+		},
+		{
+			name: "malformed start line",
+			data: "GARBAGE\r\nHost: example.com\r\n\r\n",
+			want: map[string]interface{}{"host": "example.com"},
+		},
+		{
+			name: "start line without version",
+			data: "GET /\r\nHost: example.com\r\n\r\n",
+			want: map[string]interface{}{"host": "example.com"},
+		},
+		{
+			name: "malformed header lines",
+			data: "GET / HTTP/1.0\r\nno colon here\r\n  folded after malformed\r\nBad Key: x\r\n: empty\r\nAccept: */*\r\n\r\n",
+			want: map[string]interface{}{
+				"method": "GET", "path": "/", "version": "HTTP/1.0", "accept": "*/*",
+			},
+		},
+		{
+			name: "bare LF and body ignored",
+			data: "HTTP/1.0 404 Not Found\nContent-Type: text/html\n\nServer: not-a-header\n",
+			want: map[string]interface{}{
+				"version": "HTTP/1.0", "status_code": "404", "content-type": "text/html",
+			},
+		},
+	}
+
+	pp := newTestParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pp.parseHTTPHeaders(tt.data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseHTTPHeaders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHTTPServiceHeaders(t *testing.T) {
+	pp := newTestParser()
+	first := newTestAssetInfo()
+	response := "HTTP/1.1 200 OK\r\nDate: Sat, 01 Jun 2024 12:00:00 GMT\r\nServer: nginx/1.24.0\r\n" +
+		"Content-Type: text/html\r\nX-Powered-By: PHP/8.2\r\nContent-Length: 12\r\n\r\nhello world\n"
+	if err := pp.parseHTTP(first, []byte(response)); err != nil {
+		t.Fatalf("parseHTTP() error = %v", err)
+	}
+
+	service, ok := first.Services["http"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Services[http] = %v, want service with headers", first.Services["http"])
+	}
+	wantHeaders := map[string]interface{}{
+		"server":       "nginx/1.24.0",
+		"content-type": "text/html",
+		"x-powered-by": "PHP/8.2",
+	}
+	if !reflect.DeepEqual(service["headers"], wantHeaders) {
+		t.Errorf("service headers = %v, want %v", service["headers"], wantHeaders)
+	}
+	if service["version"] != "nginx/1.24.0" {
+		t.Errorf("service version = %v, want nginx/1.24.0", service["version"])
+	}
+
+	// 后续响应中的头部合并到同一服务记录
+	second := newTestAssetInfo()
+	if err := pp.parseHTTP(second, []byte("HTTP/1.1 404 Not Found\r\nServer: nginx/1.24.0\r\nX-Frame-Options: DENY\r\n\r\n")); err != nil {
+		t.Fatalf("parseHTTP() error = %v", err)
+	}
+	asset := assets.NewAsset(first)
+	asset.Update(second)
+
+	var got *assets.ServiceInfo
+	for i := range asset.Services {
+		if asset.Services[i].Name == "http" {
+			got = &asset.Services[i]
+		}
+	}
+	if got == nil {
+		t.Fatalf("asset services = %+v, want http", asset.Services)
+	}
+	wantHeaders["x-frame-options"] = "DENY"
+	if !reflect.DeepEqual(got.Headers, wantHeaders) {
+		t.Errorf("merged headers = %v, want %v", got.Headers, wantHeaders)
+	}
+
+	// 请求头描述的是客户端，不记录为服务头部
+	request := newTestAssetInfo()
+	if err := pp.parseHTTP(request, []byte("GET / HTTP/1.1\r\nHost: example.com\r\nAccept: */*\r\n\r\n")); err != nil {
+		t.Fatalf("parseHTTP() error = %v", err)
+	}
+	if _, ok := request.Services["http"]; ok {
+		t.Errorf("request Services[http] = %v, want none", request.Services["http"])
+	}
+}
+
+// igmpPacket 构造主机10.0.0.20发往dst的IGMP报文，message为IGMP报文内容
+func igmpPacket(t *testing.T, dst string, message []byte) gopacket.Packet {
+	t.Helper()
+
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x20},
+		DstMAC:       net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, 0x16},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ipv4 := ipv4Layer("10.0.0.20", dst, layers.IPProtocolIGMP)
+	ipv4.TTL = 1
+	return buildPacket(t, time.Now(), eth, ipv4, gopacket.Payload(message))
+}
+
+func TestParseIGMP(t *testing.T) {
+	// IGMPv3成员报告：加入239.1.1.1(EXCLUDE空源列表)和232.1.1.1(INCLUDE指定源)，离开239.2.2.2(TO_INCLUDE空源列表)
+	v3Report := []byte{
+		0x22, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03,
+		0x04, 0x00, 0x00, 0x00, 239, 1, 1, 1,
+		0x01, 0x00, 0x00, 0x01, 232, 1, 1, 1, 10, 0, 0, 1,
+		0x03, 0x00, 0x00, 0x00, 239, 2, 2, 2,
+	}
+
+	tests := []struct {
+		name    string
+		dst     string
+		message []byte
+		want    map[string]interface{}
+	}{
+		{
+			name:    "v2 membership report",
+			dst:     "239.255.255.250",
+			message: []byte{0x16, 0x00, 0x00, 0x00, 239, 255, 255, 250},
+			want:    map[string]interface{}{"version": uint8(2), "groups": []string{"239.255.255.250"}},
+		},
+		{
+			name:    "v2 leave group",
+			dst:     "224.0.0.2",
+			message: []byte{0x17, 0x00, 0x00, 0x00, 239, 255, 255, 250},
+			want:    map[string]interface{}{"version": uint8(2), "left": []string{"239.255.255.250"}},
+		},
+		{
+			name:    "v3 membership report",
+			dst:     "224.0.0.22",
+			message: v3Report,
+			want: map[string]interface{}{
+				"version": uint8(3),
+				"groups":  []string{"239.1.1.1", "232.1.1.1"},
+				"left":    []string{"239.2.2.2"},
+			},
+		},
+		{
+			name:    "v2 general query",
+			dst:     "224.0.0.1",
+			message: []byte{0x11, 0x64, 0x00, 0x00, 0, 0, 0, 0},
+			want:    map[string]interface{}{"version": uint8(2), "querier": true},
+		},
+	}
+
+	pp := newTestParser("igmp")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assetInfo := pp.ParsePacket(igmpPacket(t, tt.dst, tt.message))
+			if assetInfo == nil {
+				t.Fatal("ParsePacket() = nil")
+			}
+			if got := assetInfo.Protocols["igmp"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("igmp = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIGMPGroupsMerged(t *testing.T) {
+	pp := newTestParser("igmp")
+	report := func(group net.IP) *assets.AssetInfo {
+		return pp.ParsePacket(igmpPacket(t, group.String(), append([]byte{0x16, 0x00, 0x00, 0x00}, group.To4()...)))
+	}
+
+	asset := assets.NewAsset(report(net.IPv4(239, 255, 255, 250)))
+	asset.Update(report(net.IPv4(239, 1, 1, 1)))
+	asset.Update(pp.ParsePacket(igmpPacket(t, "224.0.0.2", []byte{0x17, 0x00, 0x00, 0x00, 239, 255, 255, 250})))
+
+	groups := func() []string {
+		info, _ := asset.Protocols["igmp"].(map[string]interface{})
+		groups, _ := info["groups"].([]string)
+		return groups
+	}
+	if got := groups(); !reflect.DeepEqual(got, []string{"239.1.1.1"}) {
+		t.Errorf("groups after leave = %v, want [239.1.1.1]", got)
+	}
+
+	// 组播组数量有上限，保留最近加入的组
+	for i := 0; i < 40; i++ {
+		asset.Update(report(net.IPv4(239, 2, 0, byte(i))))
+	}
+	got := groups()
+	if len(got) != 32 || got[len(got)-1] != "239.2.0.39" {
+		t.Errorf("groups = %d (last %v), want 32 ending with 239.2.0.39", len(got), got)
+	}
+}
+
+func TestARPOnlyHost(t *testing.T) {
+	const mac = "d4:be:d9:00:00:01"
+	base := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	cfg := &config.Config{}
+	cfg.Storage.NoStore = true
+	am := assets.NewAssetManager(cfg, storage.NewMemoryStorage())
+	pp := newTestParser("arp")
+
+	// ARP探测(源IP为0.0.0.0)只有MAC地址，仍按MAC和厂商记录资产
+	probe := pp.ParsePacket(arpPacket(t, base, mac, "0.0.0.0"))
+	if probe == nil {
+		t.Fatal("ParsePacket(ARP probe) = nil")
+	}
+	if probe.IPAddress != "" || probe.MACAddress != mac || probe.Vendor != "Dell" {
+		t.Fatalf("probe ip = %q, mac = %q, vendor = %q, want \"\", %s, Dell", probe.IPAddress, probe.MACAddress, probe.Vendor, mac)
+	}
+	am.UpdateAsset(probe)
+
+	asset, ok := am.GetAsset("mac_" + mac)
+	if !ok {
+		t.Fatalf("MAC-only asset not created, assets = %d", len(am.GetAllAssets()))
+	}
+	if asset.IPAddress != "" || asset.Vendor != "Dell" || asset.DeviceType != "未知设备" {
+		t.Errorf("asset ip = %q, vendor = %q, device type = %q, want \"\", Dell, 未知设备", asset.IPAddress, asset.Vendor, asset.DeviceType)
+	}
+
+	// 之后的ARP公告补充IP，仍然没有分类依据
+	am.UpdateAsset(pp.ParsePacket(arpPacket(t, base.Add(time.Second), mac, "192.168.1.30")))
+	if asset.IPAddress != "192.168.1.30" || asset.DeviceType != "未知设备" {
+		t.Errorf("after announcement ip = %q, device type = %q, want 192.168.1.30, 未知设备", asset.IPAddress, asset.DeviceType)
+	}
+
+	// 已识别的设备类型不会被没有分类依据的ARP报文改回未知设备
+	am.UpdateAsset(&assets.AssetInfo{IPAddress: "192.168.1.30", MACAddress: mac, OpenPorts: []int{22}, Timestamp: base.Add(2 * time.Second)})
+	am.UpdateAsset(pp.ParsePacket(arpPacket(t, base.Add(3*time.Second), mac, "192.168.1.30")))
+	if asset.DeviceType != "服务器" {
+		t.Errorf("device type after ARP = %q, want 服务器", asset.DeviceType)
+	}
+	for _, change := range asset.Changes {
+		if change.ChangeType == "device_type_change" && change.NewValue == "未知设备" {
+			t.Errorf("device type reverted: %+v", change)
+		}
+	}
+	if len(am.GetAllAssets()) != 1 {
+		t.Errorf("assets = %d, want 1", len(am.GetAllAssets()))
+	}
+}
+
+func TestParseIPv4Fragmentation(t *testing.T) {
+	eth := func() gopacket.SerializableLayer {
+		return &layers.Ethernet{
+			SrcMAC:       net.HardwareAddr{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e},
+			DstMAC:       net.HardwareAddr{0x00, 0x1a, 0x2b, 0x00, 0x00, 0xfe},
+			EthernetType: layers.EthernetTypeIPv4,
+		}
+	}
+	syn := func(flags layers.IPv4Flag) []gopacket.SerializableLayer {
+		ipv4 := ipv4Layer("192.168.1.20", "192.168.1.30", layers.IPProtocolTCP)
+		ipv4.Flags = flags
+		tcp := &layers.TCP{SrcPort: 51000, DstPort: 22, SYN: true, Window: 65535}
+		tcp.SetNetworkLayerForChecksum(ipv4)
+		return []gopacket.SerializableLayer{eth(), ipv4, tcp}
+	}
+	fragment := func(flags layers.IPv4Flag, offset uint16, size int) []gopacket.SerializableLayer {
+		ipv4 := ipv4Layer("192.168.1.20", "192.168.1.30", layers.IPProtocolUDP)
+		ipv4.Flags = flags
+		ipv4.FragOffset = offset
+		return []gopacket.SerializableLayer{eth(), ipv4, gopacket.Payload(make([]byte, size))}
+	}
+
+	tests := []struct {
+		name     string
+		layers   []gopacket.SerializableLayer
+		wantIPv4 map[string]interface{}
+		wantTCP  interface{} // SYN报文中的df，nil表示没有该字段
+	}{
+		{
+			name:     "syn with df",
+			layers:   syn(layers.IPv4DontFragment),
+			wantIPv4: map[string]interface{}{"df": true},
+			wantTCP:  true,
+		},
+		{
+			name:     "syn without df",
+			layers:   syn(0),
+			wantIPv4: map[string]interface{}{"df": false},
+			wantTCP:  false,
+		},
+		{
+			name:     "first fragment",
+			layers:   fragment(layers.IPv4MoreFragments, 0, 1380),
+			wantIPv4: map[string]interface{}{"df": false, "fragmented": true, "frag_offset": 0, "mtu_hint": 1400},
+		},
+		{
+			name:     "last fragment",
+			layers:   fragment(0, 175, 200),
+			wantIPv4: map[string]interface{}{"df": false, "fragmented": true, "frag_offset": 1400},
+		},
+	}
+
+	pp := newTestParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := pp.ParsePacket(buildPacket(t, time.Now(), tt.layers...))
+			if info == nil {
+				t.Fatal("ParsePacket() = nil")
+			}
+			ipv4Info, _ := info.Protocols["ipv4"].(map[string]interface{})
+			for _, key := range []string{"df", "fragmented", "frag_offset", "mtu_hint"} {
+				if got, want := ipv4Info[key], tt.wantIPv4[key]; got != want {
+					t.Errorf("ipv4[%s] = %v, want %v", key, got, want)
+				}
+			}
+
+			tcpInfo, _ := info.Protocols["tcp"].(map[string]interface{})
+			if got := tcpInfo["df"]; got != tt.wantTCP {
+				t.Errorf("tcp[df] = %v, want %v", got, tt.wantTCP)
+			}
+		})
+	}
+}
+
+func TestParseMACFlags(t *testing.T) {
+	tests := []struct {
+		name           string
+		mac            string
+		wantVirtual    bool
+		wantRandomized bool
+	}{
+		{"vmware", "00:50:56:9a:00:01", true, false},
+		{"randomized", "da:a1:19:00:00:01", false, true},
+		{"normal", "d4:be:d9:00:00:01", false, false},
+	}
+
+	pp := newTestParser("arp")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := pp.ParsePacket(arpPacket(t, time.Now(), tt.mac, "192.168.1.40"))
+			if info == nil {
+				t.Fatal("ParsePacket() = nil")
+			}
+			if info.IsVirtual != tt.wantVirtual || info.IsRandomizedMAC != tt.wantRandomized {
+				t.Errorf("IsVirtual = %v, IsRandomizedMAC = %v, want %v, %v",
+					info.IsVirtual, info.IsRandomizedMAC, tt.wantVirtual, tt.wantRandomized)
+			}
+
+			summary := assets.NewAsset(info).GetSummary()
+			if summary["is_virtual"] != tt.wantVirtual || summary["is_randomized_mac"] != tt.wantRandomized {
+				t.Errorf("summary is_virtual = %v, is_randomized_mac = %v, want %v, %v",
+					summary["is_virtual"], summary["is_randomized_mac"], tt.wantVirtual, tt.wantRandomized)
+			}
+		})
+	}
+}
+
+func TestParseDHCPClientID(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want map[string]interface{}
+	}{
+		{
+			name: "ethernet mac",
+			data: []byte{1, 0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x01},
+			want: map[string]interface{}{"client_id": "00:1a:2b:3c:4d:01"},
+		},
+		{
+			name: "rfc 4361 iaid and duid",
+			data: []byte{255, 0, 0, 0, 2, 0x00, 0x01, 0x00, 0x01, 0x2d, 0x6b, 0x1c, 0x2e},
+			want: map[string]interface{}{"client_id": "ff00000002000100012d6b1c2e", "iaid": "00000002", "duid": "000100012d6b1c2e"},
+		},
+		{
+			name: "other type kept as hex",
+			data: []byte{0, 'h', 'o', 's', 't'},
+			want: map[string]interface{}{"client_id": "00686f7374"},
+		},
+		{
+			name: "too short",
+			data: []byte{1},
+			want: map[string]interface{}{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string]interface{})
+			parseDHCPClientID(tt.data, got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDHCPClientID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// llmnrPayload 构造包含queries个查询和answers个A记录应答的LLMNR报文，名称依次为host-N
+func llmnrPayload(t *testing.T, response bool, queries, answers int) []byte {
+	t.Helper()
+
+	dns := &layers.DNS{ID: 0x1234, QR: response}
+	for i := 0; i < queries; i++ {
+		dns.Questions = append(dns.Questions, layers.DNSQuestion{Name: []byte(fmt.Sprintf("host-%d", i)), Type: layers.DNSTypeA, Class: layers.DNSClassIN})
+	}
+	for i := 0; i < answers; i++ {
+		dns.Answers = append(dns.Answers, layers.DNSResourceRecord{
+			Name: []byte(fmt.Sprintf("host-%d", i)), Type: layers.DNSTypeA, Class: layers.DNSClassIN, TTL: 30,
+			IP: net.IPv4(192, 168, 1, byte(10+i)),
+		})
+	}
+	return serialize(t, dns)
+}
+
+func TestParseLLMNR(t *testing.T) {
+	tests := []struct {
+		name         string
+		response     bool
+		queries      int
+		answers      int
+		wantType     string
+		wantQueries  int
+		wantAnswers  int // -1表示查询报文不记录应答
+		wantHostname string
+	}{
+		{"single query", false, 1, 0, "query", 1, -1, ""},
+		{"queries capped", false, maxDNSQueries + 5, 0, "query", maxDNSQueries, -1, ""},
+		{"response names responder", true, 1, 1, "response", 1, 1, "host-0"},
+		{"answers capped", true, 1, maxDNSQueries + 5, "response", 1, maxDNSQueries, "host-0"},
+	}
+
+	pp := newTestParser("llmnr")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assetInfo := newTestAssetInfo()
+			if err := pp.parseLLMNR(assetInfo, llmnrPayload(t, tt.response, tt.queries, tt.answers)); err != nil {
+				t.Fatalf("parseLLMNR() error = %v", err)
+			}
+
+			llmnrInfo, _ := assetInfo.Protocols["llmnr"].(map[string]interface{})
+			if llmnrInfo["type"] != tt.wantType {
+				t.Errorf("type = %v, want %s", llmnrInfo["type"], tt.wantType)
+			}
+			if queries, _ := llmnrInfo["queries"].([]string); len(queries) != tt.wantQueries {
+				t.Errorf("queries = %d, want %d", len(queries), tt.wantQueries)
+			}
+			answers, ok := llmnrInfo["answers"].([]string)
+			if tt.wantAnswers < 0 && ok || tt.wantAnswers >= 0 && len(answers) != tt.wantAnswers {
+				t.Errorf("answers = %v, want %d", llmnrInfo["answers"], tt.wantAnswers)
+			}
+			if assetInfo.Hostname != tt.wantHostname {
+				t.Errorf("Hostname = %q, want %q", assetInfo.Hostname, tt.wantHostname)
+			}
+		})
+	}
+
+	if err := pp.parseLLMNR(newTestAssetInfo(), []byte{0, 1}); err == nil {
+		t.Errorf("parseLLMNR(short) error = nil, want error")
+	}
+}
@@ -0,0 +1,198 @@
+package parser
+
+import (
+	"sync"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"assets_discovery/internal/assets"
+)
+
+// ProtocolParser 协议解析插件
+type ProtocolParser interface {
+	// 协议名称，与配置中的enabled_protocols对应
+	Name() string
+
+	// 解析所需的网络层，数据包包含全部层时才会调用Parse
+	Layers() []gopacket.LayerType
+
+	// 解析数据包并补充资产信息
+	Parse(packet gopacket.Packet, assetInfo *assets.AssetInfo)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   []ProtocolParser
+)
+
+// RegisterProtocolParser 注册协议解析插件，之后创建的PacketParser都会使用它
+func RegisterProtocolParser(p ProtocolParser) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry = append(registry, p)
+}
+
+// registeredParsers 获取已注册的插件
+func registeredParsers() []ProtocolParser {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return append([]ProtocolParser{}, registry...)
+}
+
+// builtinParsers 内置的协议解析器，按执行顺序排列
+func (pp *PacketParser) builtinParsers() []ProtocolParser {
+	return []ProtocolParser{
+		&arpParser{pp: pp},
+		&igmpParser{pp: pp},
+		pp.reassembled(newPortParser("http", layers.LayerTypeTCP, []int{80}, pp.parseHTTP), frameHTTP),
+		newPortParser("rdp", layers.LayerTypeTCP, []int{3389}, pp.parseRDP),
+		newPortParser("telnet", layers.LayerTypeTCP, []int{23, 2323}, pp.parseTelnet),
+		newRedisParser(),
+		newPortParser("dhcp", layers.LayerTypeUDP, []int{67, 68}, pp.parseDHCP),
+		newPortParser("dns", layers.LayerTypeUDP, []int{53}, pp.parseDNS),
+		pp.reassembled(newPortParser("dns", layers.LayerTypeTCP, []int{853}, pp.parseDoT), frameTLS),
+		pp.reassembled(newPortParser("https", layers.LayerTypeTCP, []int{443}, pp.parseTLS), frameTLS),
+		newPortParser("mdns", layers.LayerTypeUDP, []int{5353}, pp.parseMDNS),
+		newPortParser("llmnr", layers.LayerTypeUDP, []int{5355}, pp.parseLLMNR),
+		newPortParser("nbns", layers.LayerTypeUDP, []int{137}, pp.parseNBNS),
+		newPortParser("stun", layers.LayerTypeUDP, []int{3478}, pp.parseSTUN),
+	}
+}
+
+// hasLayers 检查数据包是否包含所有指定的层
+func hasLayers(packet gopacket.Packet, layerTypes []gopacket.LayerType) bool {
+	for _, layerType := range layerTypes {
+		if packet.Layer(layerType) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// arpParser ARP协议解析器
+type arpParser struct {
+	pp *PacketParser
+}
+
+func (p *arpParser) Name() string {
+	return "arp"
+}
+
+func (p *arpParser) Layers() []gopacket.LayerType {
+	return []gopacket.LayerType{layers.LayerTypeARP}
+}
+
+func (p *arpParser) Parse(packet gopacket.Packet, assetInfo *assets.AssetInfo) {
+	arp, _ := packet.Layer(layers.LayerTypeARP).(*layers.ARP)
+	p.pp.parseARP(assetInfo, arp)
+}
+
+// igmpParser IGMP组播成员关系解析器
+type igmpParser struct {
+	pp *PacketParser
+}
+
+func (p *igmpParser) Name() string {
+	return "igmp"
+}
+
+func (p *igmpParser) Layers() []gopacket.LayerType {
+	return []gopacket.LayerType{layers.LayerTypeIPv4, layers.LayerTypeIGMP}
+}
+
+func (p *igmpParser) Parse(packet gopacket.Packet, assetInfo *assets.AssetInfo) {
+	p.pp.parseIGMP(assetInfo, packet.Layer(layers.LayerTypeIGMP))
+}
+
+// portParser 基于IPv4传输层端口识别的应用层协议解析器
+type portParser struct {
+	name      string
+	transport gopacket.LayerType
+	ports     map[int]bool
+	parse     func(assetInfo *assets.AssetInfo, payload []byte) error
+
+	// TCP流重组器，为nil时逐包解析
+	reassembler *streamReassembler
+}
+
+// newPortParser 创建基于端口的解析器，transport为TCP或UDP
+func newPortParser(name string, transport gopacket.LayerType, ports []int, parse func(*assets.AssetInfo, []byte) error) *portParser {
+	portSet := make(map[int]bool, len(ports))
+	for _, port := range ports {
+		portSet[port] = true
+	}
+
+	return &portParser{
+		name:      name,
+		transport: transport,
+		ports:     portSet,
+		parse:     parse,
+	}
+}
+
+// reassembled 启用流重组时为TCP解析器配置重组器，解析器收到按frame切分的完整消息
+func (pp *PacketParser) reassembled(p *portParser, frame frameFunc) *portParser {
+	cfg := pp.config.Parser.Reassembly
+	if cfg.Enabled && p.transport == layers.LayerTypeTCP {
+		p.reassembler = newStreamReassembler(frame, cfg.MaxStreams, cfg.FlushTimeout)
+	}
+	return p
+}
+
+func (p *portParser) Name() string {
+	return p.name
+}
+
+func (p *portParser) Layers() []gopacket.LayerType {
+	return []gopacket.LayerType{layers.LayerTypeIPv4, p.transport}
+}
+
+func (p *portParser) Parse(packet gopacket.Packet, assetInfo *assets.AssetInfo) {
+	p.parseChecked(packet, assetInfo)
+}
+
+// parseChecked 端口匹配时解析应用层数据，返回是否进行了解析及数据格式错误
+// 数据取自传输层载荷：gopacket会把53、67等端口的数据解码为DNS、DHCPv4层，
+// 这些层的Payload()为空，解码失败时也没有应用层，按应用层取数据会使解析器收不到数据
+func (p *portParser) parseChecked(packet gopacket.Packet, assetInfo *assets.AssetInfo) (bool, error) {
+	var srcPort, dstPort int
+	var payload []byte
+	switch transport := packet.Layer(p.transport).(type) {
+	case *layers.TCP:
+		srcPort, dstPort = int(transport.SrcPort), int(transport.DstPort)
+		if p.reassembler != nil && (p.ports[srcPort] || p.ports[dstPort]) {
+			return p.parseReassembled(packet, transport, assetInfo)
+		}
+		payload = transport.LayerPayload()
+	case *layers.UDP:
+		srcPort, dstPort = int(transport.SrcPort), int(transport.DstPort)
+		payload = transport.LayerPayload()
+	default:
+		return false, nil
+	}
+
+	if len(payload) == 0 || (!p.ports[srcPort] && !p.ports[dstPort]) {
+		return false, nil
+	}
+	return true, p.parse(assetInfo, payload)
+}
+
+// parseReassembled 将报文交给流重组器，解析当前报文补全的消息
+// 不带数据的SYN、FIN报文也需要送入重组器以跟踪连接的开始和结束
+func (p *portParser) parseReassembled(packet gopacket.Packet, tcp *layers.TCP, assetInfo *assets.AssetInfo) (bool, error) {
+	messages := p.reassembler.assemble(packet, tcp)
+	if len(messages) == 0 {
+		return false, nil
+	}
+
+	var firstErr error
+	for _, msg := range messages {
+		if err := p.parse(assetInfo, msg); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return true, firstErr
+}
@@ -0,0 +1,136 @@
+package parser
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"assets_discovery/internal/assets"
+	"assets_discovery/internal/config"
+	"assets_discovery/internal/storage"
+)
+
+// dhcpRequest 构造mac发出的DHCP请求，携带主机名和厂商标识选项
+func dhcpRequest(mac net.HardwareAddr, hostname, vendorClass string) []byte {
+	payload := make([]byte, 240)
+	payload[0] = 1 // BOOTREQUEST
+	payload[1] = 1 // 以太网
+	payload[2] = 6
+	copy(payload[28:34], mac)
+	binary.BigEndian.PutUint32(payload[236:240], 0x63825363)
+
+	payload = append(payload, 53, 1, 3)
+	payload = append(payload, 12, byte(len(hostname)))
+	payload = append(payload, hostname...)
+	payload = append(payload, 60, byte(len(vendorClass)))
+	payload = append(payload, vendorClass...)
+	return append(payload, 255)
+}
+
+func TestProvenanceFromParsedProtocols(t *testing.T) {
+	const ip = "192.168.1.50"
+	mac := net.HardwareAddr{0xd4, 0xbe, 0xd9, 0x00, 0x00, 0x01}
+	base := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	eth := func() *layers.Ethernet {
+		return &layers.Ethernet{SrcMAC: mac, DstMAC: net.HardwareAddr{0x00, 0x1a, 0x2b, 0x00, 0x00, 0xfe}, EthernetType: layers.EthernetTypeIPv4}
+	}
+	tcpPacket := func(ts time.Time, syn bool, payload string) gopacket.Packet {
+		ipv4 := ipv4Layer(ip, "192.168.1.1", layers.IPProtocolTCP)
+		ipv4.TTL = 128
+		tcp := &layers.TCP{SrcPort: 51000, DstPort: 80, SYN: syn, ACK: !syn, PSH: payload != "", Window: 65535}
+		tcp.SetNetworkLayerForChecksum(ipv4)
+		stack := []gopacket.SerializableLayer{eth(), ipv4, tcp}
+		if payload != "" {
+			stack = append(stack, gopacket.Payload(payload))
+		}
+		return buildPacket(t, ts, stack...)
+	}
+	dhcpPacket := func(ts time.Time) gopacket.Packet {
+		ipv4 := ipv4Layer("0.0.0.0", "255.255.255.255", layers.IPProtocolUDP)
+		udp := &layers.UDP{SrcPort: 68, DstPort: 67}
+		udp.SetNetworkLayerForChecksum(ipv4)
+		return buildPacket(t, ts, eth(), ipv4, udp, gopacket.Payload(dhcpRequest(mac, "DESKTOP-01", "MSFT 5.0")))
+	}
+
+	cfg := &config.Config{}
+	cfg.Storage.NoStore = true
+	am := assets.NewAssetManager(cfg, storage.NewMemoryStorage())
+	pp := newTestParser("http", "dhcp")
+	id := "mac_" + mac.String()
+
+	tests := []struct {
+		name   string
+		packet gopacket.Packet
+		want   map[string]assets.FieldSource
+	}{
+		{
+			name:   "syn ttl and oui",
+			packet: tcpPacket(base, true, ""),
+			want: map[string]assets.FieldSource{
+				"os_family":   {Value: "Windows", Source: "ttl_analysis", Timestamp: base},
+				"vendor":      {Value: "Dell", Source: "oui", Timestamp: base},
+				"device_type": {Value: "工作站", Source: "os", Timestamp: base},
+			},
+		},
+		{
+			name:   "http user agent",
+			packet: tcpPacket(base.Add(time.Minute), false, "GET / HTTP/1.1\r\nHost: intranet\r\nUser-Agent: Mozilla/5.0 (Windows NT 10.0; Win64; x64)\r\n\r\n"),
+			want: map[string]assets.FieldSource{
+				"hostname":    {Value: "intranet", Source: "http", Timestamp: base.Add(time.Minute)},
+				"os_family":   {Value: "Windows", Source: "user_agent", Timestamp: base.Add(time.Minute)},
+				"vendor":      {Value: "Dell", Source: "oui", Timestamp: base},
+				"device_type": {Value: "工作站", Source: "os", Timestamp: base},
+			},
+		},
+		{
+			name:   "dhcp hostname and vendor class",
+			packet: dhcpPacket(base.Add(2 * time.Minute)),
+			want: map[string]assets.FieldSource{
+				"hostname":    {Value: "desktop-01", Source: "dhcp", Timestamp: base.Add(2 * time.Minute)},
+				"os_family":   {Value: "Windows", Source: "dhcp_vendor_class", Timestamp: base.Add(2 * time.Minute)},
+				"vendor":      {Value: "Dell", Source: "oui", Timestamp: base},
+				"device_type": {Value: "工作站", Source: "os", Timestamp: base},
+			},
+		},
+		{
+			// 可信度更低的TTL推测不会改写来源记录
+			name:   "later ttl keeps stronger source",
+			packet: tcpPacket(base.Add(3*time.Minute), true, ""),
+			want: map[string]assets.FieldSource{
+				"hostname":    {Value: "desktop-01", Source: "dhcp", Timestamp: base.Add(2 * time.Minute)},
+				"os_family":   {Value: "Windows", Source: "dhcp_vendor_class", Timestamp: base.Add(2 * time.Minute)},
+				"vendor":      {Value: "Dell", Source: "oui", Timestamp: base},
+				"device_type": {Value: "工作站", Source: "os", Timestamp: base},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := pp.ParsePacket(tt.packet)
+			if info == nil {
+				t.Fatal("ParsePacket() = nil")
+			}
+			am.UpdateAsset(info)
+
+			asset, ok := am.GetAsset(id)
+			if !ok {
+				t.Fatalf("GetAsset(%s) not found", id)
+			}
+			summary, _ := asset.GetSummary()["provenance"].(map[string]assets.FieldSource)
+			if len(summary) != len(tt.want) {
+				t.Errorf("provenance = %v, want %v", summary, tt.want)
+			}
+			for field, want := range tt.want {
+				if got := summary[field]; got != want {
+					t.Errorf("provenance[%s] = %+v, want %+v", field, got, want)
+				}
+			}
+		})
+	}
+}
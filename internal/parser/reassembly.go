@@ -0,0 +1,232 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/tcpassembly"
+)
+
+// 重组内存上限：每个流最多缓存的消息字节数，以及乱序数据占用的页数（每页约1900字节）
+const (
+	maxStreamBuffer        = 32 * 1024
+	maxPagesPerConnection  = 16
+	maxBufferedPagesTotal  = 8192
+	defaultReassemblyFlush = 30 * time.Second
+)
+
+// frameFunc 判断缓冲区开头是否为完整的应用层消息
+// 返回值大于0为完整消息的长度，0表示需要更多数据，小于0表示不是需要重组的消息，直接交给解析器
+type frameFunc func(data []byte) int
+
+// reassembledMessage 重组完成的消息及其所属的TCP流
+type reassembledMessage struct {
+	net, transport gopacket.Flow
+	data           []byte
+}
+
+// streamReassembler 重组TCP流，使跨多个报文的HTTP头部、TLS ClientHello能被完整解析
+// 多个工作协程共享同一个重组器，组装过程由互斥锁串行化
+type streamReassembler struct {
+	frame        frameFunc
+	maxStreams   int
+	flushTimeout time.Duration
+
+	mu        sync.Mutex
+	assembler *tcpassembly.Assembler
+	streams   int // 正在缓存数据的流数量
+	pending   []reassembledMessage
+	lastFlush time.Time
+}
+
+// newStreamReassembler 创建TCP流重组器，maxStreams限制同时缓存数据的流数量
+func newStreamReassembler(frame frameFunc, maxStreams int, flushTimeout time.Duration) *streamReassembler {
+	if flushTimeout <= 0 {
+		flushTimeout = defaultReassemblyFlush
+	}
+
+	r := &streamReassembler{
+		frame:        frame,
+		maxStreams:   maxStreams,
+		flushTimeout: flushTimeout,
+	}
+	r.assembler = tcpassembly.NewAssembler(tcpassembly.NewStreamPool(r))
+	r.assembler.MaxBufferedPagesPerConnection = maxPagesPerConnection
+	r.assembler.MaxBufferedPagesTotal = maxBufferedPagesTotal
+
+	return r
+}
+
+// New 实现tcpassembly.StreamFactory，超过流数量上限的连接不再缓存，数据逐包交给解析器
+func (r *streamReassembler) New(netFlow, tcpFlow gopacket.Flow) tcpassembly.Stream {
+	stream := &messageStream{r: r, net: netFlow, transport: tcpFlow}
+	if r.maxStreams <= 0 || r.streams < r.maxStreams {
+		stream.buffered = true
+		r.streams++
+	}
+	return stream
+}
+
+// assemble 将TCP报文加入重组，返回该报文所在的流中重组完成的消息
+func (r *streamReassembler) assemble(packet gopacket.Packet, tcp *layers.TCP) [][]byte {
+	network := packet.NetworkLayer()
+	if network == nil {
+		return nil
+	}
+	netFlow := network.NetworkFlow()
+
+	timestamp := packet.Metadata().Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// 按报文时间关闭长时间没有数据的连接，离线分析时与墙上时间无关
+	if r.lastFlush.IsZero() {
+		r.lastFlush = timestamp
+	} else if timestamp.Sub(r.lastFlush) >= r.flushTimeout {
+		r.assembler.FlushOlderThan(timestamp.Add(-r.flushTimeout))
+		r.lastFlush = timestamp
+	}
+
+	r.assembler.AssembleWithTimestamp(netFlow, tcp, timestamp)
+
+	// 刷新其他连接时产生的消息无法归属到当前报文，直接丢弃
+	var messages [][]byte
+	tcpFlow := tcp.TransportFlow()
+	for _, msg := range r.pending {
+		if msg.net == netFlow && msg.transport == tcpFlow {
+			messages = append(messages, msg.data)
+		}
+	}
+	r.pending = r.pending[:0]
+
+	return messages
+}
+
+// messageStream 单方向的TCP流，按消息边界切分重组后的数据
+// 其方法在assembler内部同步调用，此时已持有重组器的锁
+type messageStream struct {
+	r              *streamReassembler
+	net, transport gopacket.Flow
+	buffered       bool
+	buf            []byte
+}
+
+// Reassembled 实现tcpassembly.Stream
+func (s *messageStream) Reassembled(reassembly []tcpassembly.Reassembly) {
+	for _, rs := range reassembly {
+		if len(rs.Bytes) == 0 {
+			continue
+		}
+		if !s.buffered {
+			s.emit(append([]byte(nil), rs.Bytes...))
+			continue
+		}
+
+		// 出现数据缺失时缓存的半条消息已无法补全
+		if rs.Skip != 0 {
+			s.buf = nil
+		}
+		s.buf = append(s.buf, rs.Bytes...)
+		s.split()
+	}
+}
+
+// ReassemblyComplete 实现tcpassembly.Stream，连接结束时交出剩余的数据
+func (s *messageStream) ReassemblyComplete() {
+	if len(s.buf) > 0 {
+		s.emit(s.buf)
+		s.buf = nil
+	}
+	if s.buffered {
+		s.r.streams--
+	}
+}
+
+// split 从缓冲区中切出完整的消息，超过缓存上限时按已有数据交出
+func (s *messageStream) split() {
+	for len(s.buf) > 0 {
+		n := s.r.frame(s.buf)
+		switch {
+		case n < 0:
+			s.emit(s.buf)
+			s.buf = nil
+		case n == 0:
+			if len(s.buf) >= maxStreamBuffer {
+				s.emit(s.buf)
+				s.buf = nil
+			}
+			return
+		default:
+			s.emit(s.buf[:n:n])
+			s.buf = append([]byte(nil), s.buf[n:]...)
+		}
+	}
+	s.buf = nil
+}
+
+func (s *messageStream) emit(data []byte) {
+	s.r.pending = append(s.r.pending, reassembledMessage{net: s.net, transport: s.transport, data: data})
+}
+
+// httpHeaderEnd HTTP头部结束标记
+var httpHeaderEnd = []byte("\r\n\r\n")
+
+// frameHTTP 以空行为界切分HTTP请求和响应头部，消息体不缓存
+func frameHTTP(data []byte) int {
+	if !httpMessageStart(data) {
+		return -1
+	}
+	if i := bytes.Index(data, httpHeaderEnd); i >= 0 {
+		return i + len(httpHeaderEnd)
+	}
+	return 0
+}
+
+// httpMessageStart 判断数据是否以HTTP请求行或状态行开头，数据不足时按可能是处理
+func httpMessageStart(data []byte) bool {
+	const version = "HTTP/"
+	if len(data) < len(version) {
+		if bytes.HasPrefix([]byte(version), data) {
+			return true
+		}
+	} else if bytes.HasPrefix(data, []byte(version)) {
+		return true
+	}
+
+	// 请求方法为3到7个大写字母
+	for i, c := range data {
+		if c == ' ' {
+			return i >= 3
+		}
+		if c < 'A' || c > 'Z' || i >= 7 {
+			return false
+		}
+	}
+	return true
+}
+
+// frameTLS 按TLS记录层长度切分握手记录，其他记录不缓存
+func frameTLS(data []byte) int {
+	if data[0] != 0x16 {
+		return -1
+	}
+	if len(data) < 5 {
+		return 0
+	}
+	if data[1] != 0x03 {
+		return -1
+	}
+	n := 5 + int(binary.BigEndian.Uint16(data[3:5]))
+	if len(data) < n {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,243 @@
+package parser
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+
+	"assets_discovery/internal/config"
+)
+
+// newReassemblyParser 创建只解析HTTP的解析器，enabled控制是否启用流重组
+func newReassemblyParser(enabled bool) *PacketParser {
+	cfg := &config.Config{}
+	cfg.Parser.EnabledProtocols = []string{"http"}
+	cfg.Parser.Reassembly = config.ReassemblyConfig{Enabled: enabled, MaxStreams: 16, FlushTimeout: 30 * time.Second}
+	return NewPacketParser(cfg)
+}
+
+// readPcap 读取testdata下的pcap文件
+func readPcap(t *testing.T, name string) []gopacket.Packet {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("open %s: %v", name, err)
+	}
+	defer f.Close()
+
+	r, err := pcapgo.NewReader(f)
+	if err != nil {
+		t.Fatalf("pcapgo.NewReader() error = %v", err)
+	}
+
+	var packets []gopacket.Packet
+	for {
+		data, ci, err := r.ReadPacketData()
+		if err != nil {
+			break
+		}
+		packet := gopacket.NewPacket(data, r.LinkType(), gopacket.Default)
+		packet.Metadata().CaptureInfo = ci
+		packets = append(packets, packet)
+	}
+	return packets
+}
+
+// httpSegment 构造客户端从srcPort发往80端口的TCP报文
+func httpSegment(t *testing.T, ts time.Time, srcPort layers.TCPPort, seq uint32, syn bool, payload string) (gopacket.Packet, *layers.TCP) {
+	t.Helper()
+
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e},
+		DstMAC:       net.HardwareAddr{0x00, 0x1a, 0x2b, 0x00, 0x00, 0x50},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip := ipv4Layer("192.168.1.10", "192.168.1.20", layers.IPProtocolTCP)
+	tcp := &layers.TCP{SrcPort: srcPort, DstPort: 80, Seq: seq, SYN: syn, ACK: !syn, PSH: payload != "", Window: 64240}
+	tcp.SetNetworkLayerForChecksum(ip)
+
+	packet := buildPacket(t, ts, eth, ip, tcp, gopacket.Payload(payload))
+	return packet, packet.Layer(layers.LayerTypeTCP).(*layers.TCP)
+}
+
+// streamCount 正在缓存数据的流数量
+func (r *streamReassembler) streamCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.streams
+}
+
+func TestHTTPReassemblyFromPcap(t *testing.T) {
+	// 请求头部在第一个报文的User-Agent之后被截断，其余头部在第二个报文中
+	want := []string{"method", "path", "host", "user-agent", "accept", "x-requested-with", "cookie_names"}
+
+	tests := []struct {
+		name         string
+		reassembly   bool
+		wantComplete bool
+	}{
+		{"reassembled", true, true},
+		{"per packet", false, false},
+	}
+
+	packets := readPcap(t, "http_split_request.pcap")
+	if len(packets) != 5 {
+		t.Fatalf("read %d packets, want 5", len(packets))
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pp := newReassemblyParser(tt.reassembly)
+
+			complete := false
+			for _, packet := range packets {
+				assetInfo := pp.ParsePacket(packet)
+				if assetInfo == nil {
+					continue
+				}
+				headers, ok := assetInfo.Protocols["http"].(map[string]interface{})
+				if !ok {
+					continue
+				}
+
+				missing := 0
+				for _, key := range want {
+					if _, ok := headers[key]; !ok {
+						missing++
+					}
+				}
+				if missing == 0 {
+					complete = true
+					if assetInfo.Hostname != "intranet.example" {
+						t.Errorf("Hostname = %q, want intranet.example", assetInfo.Hostname)
+					}
+					if _, ok := headers["cookie"]; ok {
+						t.Errorf("raw cookie header kept")
+					}
+				}
+			}
+
+			if complete != tt.wantComplete {
+				t.Errorf("saw full header set = %v, want %v", complete, tt.wantComplete)
+			}
+		})
+	}
+}
+
+func TestReassemblerMaxStreams(t *testing.T) {
+	const connections = 5
+
+	tests := []struct {
+		name         string
+		maxStreams   int
+		wantBuffered int
+	}{
+		{"unlimited", 0, connections},
+		{"capped", 2, 2},
+		{"cap equals connections", connections, connections},
+	}
+
+	ts := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	partial := "GET / HTTP/1.1\r\nHost: intranet.example\r\n"
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newStreamReassembler(frameHTTP, tt.maxStreams, time.Minute)
+
+			passedThrough := 0
+			for i := 0; i < connections; i++ {
+				port := layers.TCPPort(51000 + i)
+				packet, tcp := httpSegment(t, ts, port, 1000, true, "")
+				r.assemble(packet, tcp)
+
+				// 未完成的头部只在缓存的流中等待，超出上限的流逐包交出数据
+				packet, tcp = httpSegment(t, ts, port, 1001, false, partial)
+				passedThrough += len(r.assemble(packet, tcp))
+			}
+
+			if got := r.streamCount(); got != tt.wantBuffered {
+				t.Errorf("buffered streams = %d, want %d", got, tt.wantBuffered)
+			}
+			if want := connections - tt.wantBuffered; passedThrough != want {
+				t.Errorf("unbuffered messages = %d, want %d", passedThrough, want)
+			}
+		})
+	}
+}
+
+func TestReassemblerFlushTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		idle        time.Duration
+		wantStreams int
+	}{
+		{"within timeout", 10 * time.Second, 2},
+		{"idle stream flushed", 31 * time.Second, 1},
+	}
+
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newStreamReassembler(frameHTTP, 16, 30*time.Second)
+
+			// 第一个连接发送半条请求后不再有数据
+			packet, tcp := httpSegment(t, start, 51000, 1000, true, "")
+			r.assemble(packet, tcp)
+			packet, tcp = httpSegment(t, start, 51000, 1001, false, "GET / HTTP/1.1\r\n")
+			r.assemble(packet, tcp)
+
+			// 其他连接的报文推动重组器的时钟，刷新时丢弃空闲连接的缓存
+			later := start.Add(tt.idle)
+			packet, tcp = httpSegment(t, later, 52000, 1000, true, "")
+			if msgs := r.assemble(packet, tcp); len(msgs) != 0 {
+				t.Errorf("flushed data leaked to another stream: %q", msgs)
+			}
+
+			if got := r.streamCount(); got != tt.wantStreams {
+				t.Errorf("buffered streams = %d, want %d", got, tt.wantStreams)
+			}
+		})
+	}
+}
+
+func TestReassemblerBufferCap(t *testing.T) {
+	r := newStreamReassembler(frameHTTP, 16, time.Minute)
+	ts := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	packet, tcp := httpSegment(t, ts, 51000, 1000, true, "")
+	r.assemble(packet, tcp)
+
+	// 没有头部结束标记的数据达到缓存上限后交出，不再继续增长
+	seq := uint32(1001)
+	line := "GET / HTTP/1.1\r\n"
+	chunk := fmt.Sprintf("%-1000s\r\n", "X-Padding: a")
+	emitted := 0
+	for sent := 0; sent < 2*maxStreamBuffer; {
+		payload := chunk
+		if sent == 0 {
+			payload = line
+		}
+		packet, tcp = httpSegment(t, ts, 51000, seq, false, payload)
+		for _, msg := range r.assemble(packet, tcp) {
+			if len(msg) > maxStreamBuffer+len(chunk) {
+				t.Fatalf("message of %d bytes exceeds buffer cap", len(msg))
+			}
+			emitted++
+		}
+		seq += uint32(len(payload))
+		sent += len(payload)
+	}
+
+	if emitted == 0 {
+		t.Errorf("buffer never flushed at cap")
+	}
+}
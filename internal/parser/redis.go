@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"assets_discovery/internal/assets"
+)
+
+// redisPort Redis默认端口
+const redisPort = 6379
+
+// maxRedisAuthFlows 记录的已认证连接数上限，超过时清空重新记录
+const maxRedisAuthFlows = 4096
+
+// redisVersionPattern INFO响应中的版本号
+var redisVersionPattern = regexp.MustCompile(`redis_version:([\d.]+)`)
+
+// redisParser 解析Redis服务端的响应，记录服务端是否在未认证的情况下响应了INFO命令
+// 客户端在同一连接中先发送AUTH（或带AUTH参数的HELLO）时，INFO响应不说明服务端未启用认证，
+// 因此需要记录发送过AUTH的连接
+type redisParser struct {
+	mu        sync.Mutex
+	authFlows map[string]bool // 客户端地址 -> 服务端地址
+}
+
+func newRedisParser() *redisParser {
+	return &redisParser{authFlows: make(map[string]bool)}
+}
+
+func (p *redisParser) Name() string {
+	return "redis"
+}
+
+func (p *redisParser) Layers() []gopacket.LayerType {
+	return []gopacket.LayerType{layers.LayerTypeTCP}
+}
+
+func (p *redisParser) Parse(packet gopacket.Packet, assetInfo *assets.AssetInfo) {
+	tcp, _ := packet.Layer(layers.LayerTypeTCP).(*layers.TCP)
+	network := packet.NetworkLayer()
+	if tcp == nil || network == nil || len(tcp.Payload) == 0 {
+		return
+	}
+	src, dst := network.NetworkFlow().Endpoints()
+
+	switch {
+	case int(tcp.DstPort) == redisPort:
+		if isRedisAuth(tcp.Payload) {
+			p.markAuthenticated(fmt.Sprintf("%s:%d-%s:%d", src, tcp.SrcPort, dst, tcp.DstPort))
+		}
+	case int(tcp.SrcPort) == redisPort:
+		authenticated := p.authenticated(fmt.Sprintf("%s:%d-%s:%d", dst, tcp.DstPort, src, tcp.SrcPort))
+		if redisInfo := parseRedisReply(tcp.Payload, authenticated); redisInfo != nil {
+			assetInfo.Protocols["redis"] = redisInfo
+		}
+	}
+}
+
+// markAuthenticated 记录客户端在该连接中发送了认证命令
+func (p *redisParser) markAuthenticated(flow string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.authFlows) >= maxRedisAuthFlows {
+		p.authFlows = make(map[string]bool)
+	}
+	p.authFlows[flow] = true
+}
+
+func (p *redisParser) authenticated(flow string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.authFlows[flow]
+}
+
+// isRedisAuth 判断客户端命令是否为AUTH或带AUTH参数的HELLO，支持RESP数组和内联命令两种格式
+func isRedisAuth(payload []byte) bool {
+	upper := bytes.ToUpper(payload)
+	if bytes.HasPrefix(upper, []byte("*")) {
+		// *2\r\n$4\r\nAUTH\r\n... 第一个批量字符串是命令名
+		parts := bytes.SplitN(upper, []byte("\r\n"), 4)
+		if len(parts) < 3 {
+			return false
+		}
+		upper = parts[2]
+		if bytes.Equal(upper, []byte("HELLO")) {
+			return bytes.Contains(bytes.ToUpper(payload), []byte("\r\nAUTH\r\n"))
+		}
+		return bytes.Equal(upper, []byte("AUTH"))
+	}
+	return bytes.HasPrefix(upper, []byte("AUTH ")) ||
+		bytes.HasPrefix(upper, []byte("HELLO ")) && bytes.Contains(upper, []byte(" AUTH "))
+}
+
+// parseRedisReply 解析服务端响应：INFO响应记录版本及连接是否已认证，NOAUTH错误说明服务端要求认证
+func parseRedisReply(payload []byte, authenticated bool) map[string]interface{} {
+	switch {
+	case bytes.Contains(payload, []byte("# Server\r\n")) && bytes.Contains(payload, []byte("redis_version:")):
+		redisInfo := map[string]interface{}{
+			"port":          redisPort,
+			"info":          true,
+			"authenticated": authenticated,
+		}
+		if m := redisVersionPattern.FindSubmatch(payload); m != nil {
+			redisInfo["version"] = string(m[1])
+		}
+		return redisInfo
+	case bytes.HasPrefix(payload, []byte("-NOAUTH")):
+		return map[string]interface{}{
+			"port":          redisPort,
+			"auth_required": true,
+		}
+	}
+	return nil
+}
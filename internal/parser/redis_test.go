@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"assets_discovery/internal/assets"
+	"assets_discovery/internal/config"
+	"assets_discovery/internal/storage"
+)
+
+func TestRedisNoAuthFinding(t *testing.T) {
+	const (
+		clientMAC = "00:1a:2b:3c:4d:01"
+		clientIP  = "192.168.1.20"
+		serverMAC = "00:1a:2b:3c:4d:02"
+		serverIP  = "192.168.1.30"
+		info      = "$120\r\n# Server\r\nredis_version:7.2.4\r\nredis_mode:standalone\r\n"
+	)
+
+	tests := []struct {
+		name         string
+		command      string
+		reply        string
+		wantFindings []string
+	}{
+		{"info without auth", "*1\r\n$4\r\nINFO\r\n", info, []string{"redis_no_auth"}},
+		{"info after resp auth", "*2\r\n$4\r\nAUTH\r\n$6\r\nsecret\r\n", info, nil},
+		{"info after inline auth", "auth secret\r\n", info, nil},
+		{"info after hello auth", "*5\r\n$5\r\nHELLO\r\n$1\r\n3\r\n$4\r\nAUTH\r\n$7\r\ndefault\r\n$6\r\nsecret\r\n", info, nil},
+		{"noauth error", "*1\r\n$4\r\nINFO\r\n", "-NOAUTH Authentication required.\r\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Storage.NoStore = true
+			am := assets.NewAssetManager(cfg, storage.NewMemoryStorage())
+			pp := newTestParser("redis")
+
+			ts := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+			packets := []struct {
+				fromClient bool
+				payload    string
+			}{
+				{true, tt.command},
+				{false, "+OK\r\n"},
+				{true, "*1\r\n$4\r\nINFO\r\n"},
+				{false, tt.reply},
+			}
+			for _, p := range packets {
+				packet := tcpSegment(t, ts, serverMAC, serverIP, redisPort, clientIP, 50379, "A", p.payload)
+				if p.fromClient {
+					packet = tcpSegment(t, ts, clientMAC, clientIP, 50379, serverIP, redisPort, "A", p.payload)
+				}
+				if assetInfo := pp.ParsePacket(packet); assetInfo != nil {
+					am.UpdateAsset(assetInfo)
+				}
+			}
+
+			server, ok := am.GetAssetByIP(serverIP)
+			if !ok {
+				t.Fatalf("server asset not created")
+			}
+			var got []string
+			for _, f := range server.Findings {
+				got = append(got, f.ID)
+			}
+			if !reflect.DeepEqual(got, tt.wantFindings) {
+				t.Errorf("server findings = %v, want %v", got, tt.wantFindings)
+			}
+
+			if client, ok := am.GetAssetByIP(clientIP); ok && len(client.Findings) != 0 {
+				t.Errorf("client findings = %+v, want none", client.Findings)
+			}
+		})
+	}
+}
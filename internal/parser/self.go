@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"log"
+	"net"
+
+	"assets_discovery/internal/assets"
+)
+
+// selfFilter 采集主机自身网卡的MAC和IP地址，源地址属于采集主机的数据包不产生资产
+// 避免采集主机出现在资产清单中并触发新资产告警
+type selfFilter struct {
+	macs map[string]bool
+	ips  map[string]bool
+}
+
+// newSelfFilter 枚举本机网络接口的地址，获取失败时返回nil，不排除任何数据包
+func newSelfFilter() *selfFilter {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		log.Printf("获取本机网络接口失败，不排除采集主机自身的流量: %v", err)
+		return nil
+	}
+
+	var macs []net.HardwareAddr
+	var ips []net.IP
+	for _, iface := range ifaces {
+		if len(iface.HardwareAddr) > 0 {
+			macs = append(macs, iface.HardwareAddr)
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() {
+				ips = append(ips, ipNet.IP)
+			}
+		}
+	}
+
+	f := newSelfFilterFrom(macs, ips)
+	log.Printf("已启用采集主机自身流量排除：%d 个MAC地址，%d 个IP地址", len(f.macs), len(f.ips))
+	return f
+}
+
+// newSelfFilterFrom 按给定的本机地址创建过滤器，地址按解析器输出的字符串形式保存
+func newSelfFilterFrom(macs []net.HardwareAddr, ips []net.IP) *selfFilter {
+	f := &selfFilter{
+		macs: make(map[string]bool, len(macs)),
+		ips:  make(map[string]bool, len(ips)),
+	}
+	for _, mac := range macs {
+		f.macs[mac.String()] = true
+	}
+	for _, ip := range ips {
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		f.ips[ip.String()] = true
+	}
+	return f
+}
+
+// matches 检查资产信息的源MAC或源IP是否属于采集主机，f为nil时不排除
+func (f *selfFilter) matches(assetInfo *assets.AssetInfo) bool {
+	if f == nil {
+		return false
+	}
+	return (assetInfo.MACAddress != "" && f.macs[assetInfo.MACAddress]) ||
+		(assetInfo.IPAddress != "" && f.ips[assetInfo.IPAddress])
+}
@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestExcludeSelf(t *testing.T) {
+	const (
+		localMAC = "00:1a:2b:3c:4d:aa"
+		localIP  = "192.168.1.5"
+		peerMAC  = "00:1a:2b:3c:4d:01"
+		peerIP   = "192.168.1.20"
+	)
+
+	hw, _ := net.ParseMAC(localMAC)
+	self := newSelfFilterFrom([]net.HardwareAddr{hw}, []net.IP{net.ParseIP(localIP)})
+	ts := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		self     *selfFilter
+		mac, ip  string
+		wantSeen bool
+	}{
+		{"local mac and ip", self, localMAC, localIP, false},
+		{"local mac on another address", self, localMAC, "192.168.1.99", false},
+		{"local ip behind another mac", self, peerMAC, localIP, false},
+		{"other host", self, peerMAC, peerIP, true},
+		{"filter disabled", nil, localMAC, localIP, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pp := newTestParser("arp")
+			pp.self = tt.self
+
+			assetInfo := pp.ParsePacket(arpPacket(t, ts, tt.mac, tt.ip))
+			if got := assetInfo != nil; got != tt.wantSeen {
+				t.Errorf("ParsePacket(%s %s) returned asset = %v, want %v", tt.mac, tt.ip, got, tt.wantSeen)
+			}
+		})
+	}
+}
+
+func TestNewSelfFilterFrom(t *testing.T) {
+	hw, _ := net.ParseMAC("00:1a:2b:3c:4d:aa")
+	// net.ParseIP返回16字节形式的IPv4地址，按解析器输出的点分形式匹配
+	f := newSelfFilterFrom([]net.HardwareAddr{hw}, []net.IP{net.ParseIP("10.0.0.5"), net.ParseIP("fe80::21a:2bff:fe3c:4daa")})
+
+	for _, key := range []string{"10.0.0.5", "fe80::21a:2bff:fe3c:4daa"} {
+		if !f.ips[key] {
+			t.Errorf("ips[%s] = false, want true", key)
+		}
+	}
+	if !f.macs["00:1a:2b:3c:4d:aa"] {
+		t.Errorf("macs = %v, want 00:1a:2b:3c:4d:aa", f.macs)
+	}
+}
@@ -0,0 +1,236 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed service_probes.txt
+var defaultServiceProbes string
+
+const (
+	// maxBannerScan 匹配服务指纹时最多检查的报文长度
+	maxBannerScan = 1024
+
+	// 不同识别方式的置信度
+	probeMatchConfidence = 0.9
+	portMatchConfidence  = 0.5
+)
+
+// ServiceMatch 服务识别结果
+type ServiceMatch struct {
+	Name       string  `json:"name"`
+	Product    string  `json:"product,omitempty"`
+	Version    string  `json:"version,omitempty"`
+	Banner     string  `json:"banner,omitempty"` // 按报文内容识别时服务端发送的第一行，已转义不可打印字符
+	Confidence float64 `json:"confidence"`
+}
+
+// String 返回服务描述，例如 "SSH OpenSSH 8.9p1"
+func (m *ServiceMatch) String() string {
+	parts := []string{m.Name}
+	if strings.HasPrefix(m.Product, m.Name) {
+		// 产品名已包含服务名时不再重复，例如 "MySQL 8.0.32"
+		parts = nil
+	}
+	parts = append(parts, m.Product, m.Version)
+
+	return strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+}
+
+// serviceProbe 单条服务指纹规则
+type serviceProbe struct {
+	service string
+	pattern *regexp.Regexp
+	product string
+	version string
+}
+
+// serviceMatcher 基于报文内容的服务指纹匹配器
+type serviceMatcher struct {
+	probes []serviceProbe
+}
+
+// newServiceMatcher 加载内置规则和用户规则，用户规则优先
+func newServiceMatcher(userFile string) *serviceMatcher {
+	sm := &serviceMatcher{}
+
+	if userFile != "" {
+		file, err := os.Open(userFile)
+		if err != nil {
+			log.Printf("加载服务指纹文件失败: %v", err)
+		} else {
+			defer file.Close()
+			if err := sm.load(file); err != nil {
+				log.Printf("解析服务指纹文件失败: %v", err)
+			}
+		}
+	}
+
+	if err := sm.load(strings.NewReader(defaultServiceProbes)); err != nil {
+		log.Printf("解析内置服务指纹失败: %v", err)
+	}
+
+	return sm
+}
+
+// load 读取match规则，无效的规则会被跳过
+func (sm *serviceMatcher) load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		probe, err := parseProbeLine(line)
+		if err != nil {
+			log.Printf("跳过第 %d 行服务指纹: %v", lineNo, err)
+			continue
+		}
+		sm.probes = append(sm.probes, probe)
+	}
+
+	return scanner.Err()
+}
+
+// parseProbeLine 解析 match <服务名> m<d><正则><d>[标志] [p/产品/] [v/版本/]
+func parseProbeLine(line string) (serviceProbe, error) {
+	var probe serviceProbe
+
+	fields := strings.SplitN(line, " ", 3)
+	if len(fields) != 3 || fields[0] != "match" {
+		return probe, fmt.Errorf("格式错误")
+	}
+	probe.service = fields[1]
+
+	rest := fields[2]
+	if len(rest) < 3 || rest[0] != 'm' {
+		return probe, fmt.Errorf("缺少正则表达式")
+	}
+	delim := rest[1]
+	end := strings.IndexByte(rest[2:], delim)
+	if end < 0 {
+		return probe, fmt.Errorf("正则表达式未结束")
+	}
+	expr := rest[2 : 2+end]
+	rest = rest[3+end:]
+
+	// 正则标志
+	flags := ""
+	for len(rest) > 0 && rest[0] != ' ' {
+		switch rest[0] {
+		case 'i', 's':
+			flags += string(rest[0])
+		default:
+			return probe, fmt.Errorf("未知的标志: %c", rest[0])
+		}
+		rest = rest[1:]
+	}
+	if flags != "" {
+		expr = "(?" + flags + ")" + expr
+	}
+
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return probe, err
+	}
+	probe.pattern = pattern
+
+	// 产品和版本模板，内容中可以包含空格
+	for rest = strings.TrimLeft(rest, " "); len(rest) >= 3; rest = strings.TrimLeft(rest, " ") {
+		if rest[1] != '/' {
+			return probe, fmt.Errorf("无法解析的字段: %s", rest)
+		}
+		end := strings.IndexByte(rest[2:], '/')
+		if end < 0 {
+			return probe, fmt.Errorf("字段未结束: %s", rest)
+		}
+
+		value := rest[2 : 2+end]
+		switch rest[0] {
+		case 'p':
+			probe.product = value
+		case 'v':
+			probe.version = value
+		}
+		rest = rest[3+end:]
+	}
+
+	return probe, nil
+}
+
+// match 使用报文开头匹配服务指纹
+func (sm *serviceMatcher) match(payload []byte) *ServiceMatch {
+	if len(payload) == 0 {
+		return nil
+	}
+	if len(payload) > maxBannerScan {
+		payload = payload[:maxBannerScan]
+	}
+
+	for _, probe := range sm.probes {
+		submatches := probe.pattern.FindSubmatchIndex(payload)
+		if submatches == nil {
+			continue
+		}
+
+		return &ServiceMatch{
+			Name:       probe.service,
+			Product:    string(probe.pattern.Expand(nil, []byte(probe.product), payload, submatches)),
+			Version:    string(probe.pattern.Expand(nil, []byte(probe.version), payload, submatches)),
+			Confidence: probeMatchConfidence,
+		}
+	}
+
+	return nil
+}
+
+// serviceBanner 截取服务端报文的第一行（以CRLF结尾，避免二进制协议中的0x0a字节截断横幅）作为服务横幅
+// 不可打印字节转义为\xNN，超过maxLen字节时截断并追加"..."，maxLen为0时只受maxBannerScan限制
+func serviceBanner(payload []byte, maxLen int) string {
+	if len(payload) > maxBannerScan {
+		payload = payload[:maxBannerScan]
+	}
+	if i := bytes.Index(payload, []byte("\r\n")); i >= 0 {
+		payload = payload[:i]
+	}
+
+	var b strings.Builder
+	for len(payload) > 0 {
+		r, size := utf8.DecodeRune(payload)
+
+		var s string
+		switch {
+		case r == '\\':
+			s = `\\`
+		case r == utf8.RuneError && size <= 1, !unicode.IsPrint(r):
+			for _, c := range payload[:size] {
+				s += fmt.Sprintf(`\x%02x`, c)
+			}
+		default:
+			s = string(payload[:size])
+		}
+
+		if maxLen > 0 && b.Len()+len(s) > maxLen {
+			b.WriteString("...")
+			break
+		}
+		b.WriteString(s)
+		payload = payload[size:]
+	}
+
+	return b.String()
+}
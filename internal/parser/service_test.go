@@ -0,0 +1,216 @@
+package parser
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/gopacket"
+)
+
+func TestServiceMatcherBanners(t *testing.T) {
+	tests := []struct {
+		name        string
+		payload     string
+		wantName    string
+		wantProduct string
+		wantVersion string
+		wantString  string
+	}{
+		{"openssh", "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.1\r\n", "SSH", "OpenSSH", "8.9p1", "SSH OpenSSH 8.9p1"},
+		{"dropbear", "SSH-2.0-dropbear_2020.81\r\n", "SSH", "Dropbear sshd", "2020.81", "SSH Dropbear sshd 2020.81"},
+		{"other ssh", "SSH-2.0-Cisco-1.25\r\n", "SSH", "Cisco-1.25", "", "SSH Cisco-1.25"},
+		{"nginx", "HTTP/1.1 200 OK\r\nDate: x\r\nServer: nginx/1.24.0\r\n\r\n", "HTTP", "nginx", "1.24.0", "HTTP nginx 1.24.0"},
+		{"apache without version", "HTTP/1.0 404 Not Found\r\nServer: Apache\r\n\r\n", "HTTP", "Apache httpd", "", "HTTP Apache httpd"},
+		{"iis", "HTTP/1.1 200 OK\r\nServer: Microsoft-IIS/10.0\r\n\r\n", "HTTP", "Microsoft IIS httpd", "10.0", "HTTP Microsoft IIS httpd 10.0"},
+		{"generic server header", "HTTP/1.1 200 OK\r\nServer: Jetty(9.4.z)\r\n\r\n", "HTTP", "Jetty(9.4.z)", "", "HTTP Jetty(9.4.z)"},
+		{"http without server", "HTTP/1.1 301 Moved Permanently\r\nLocation: /\r\n\r\n", "HTTP", "", "", "HTTP"},
+		{"vsftpd", "220 (vsFTPd 3.0.5)\r\n", "FTP", "vsftpd", "3.0.5", "FTP vsftpd 3.0.5"},
+		{"filezilla case insensitive", "220-filezilla server 0.9.60 beta\r\n", "FTP", "FileZilla ftpd", "0.9.60", "FTP FileZilla ftpd 0.9.60"},
+		{"postfix", "220 mail.example.com ESMTP Postfix (Ubuntu)\r\n", "SMTP", "Postfix smtpd", "", "SMTP Postfix smtpd"},
+		{"dovecot imap", "* OK [CAPABILITY IMAP4rev1] Dovecot ready.\r\n", "IMAP", "Dovecot imapd", "", "IMAP Dovecot imapd"},
+		{"mysql handshake", "J\x00\x00\x00\x0a8.0.32\x00rest", "MySQL", "MySQL", "8.0.32", "MySQL 8.0.32"},
+		{"mariadb handshake", "J\x00\x00\x00\x0a5.5.5-10.6.12-MariaDB\x00", "MySQL", "MariaDB", "10.6.12", "MySQL MariaDB 10.6.12"},
+		{"redis error", "-NOAUTH Authentication required.\r\n", "Redis", "", "", "Redis"},
+		{"vnc", "RFB 003.008\n", "VNC", "VNC", "003.008", "VNC 003.008"},
+	}
+
+	sm := newServiceMatcher("")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := sm.match([]byte(tt.payload))
+			if match == nil {
+				t.Fatalf("match(%q) = nil, want %s", tt.payload, tt.wantName)
+			}
+			if match.Name != tt.wantName || match.Product != tt.wantProduct || match.Version != tt.wantVersion {
+				t.Errorf("match() = %q/%q/%q, want %q/%q/%q",
+					match.Name, match.Product, match.Version, tt.wantName, tt.wantProduct, tt.wantVersion)
+			}
+			if got := match.String(); got != tt.wantString {
+				t.Errorf("String() = %q, want %q", got, tt.wantString)
+			}
+			if match.Confidence != probeMatchConfidence {
+				t.Errorf("Confidence = %v, want %v", match.Confidence, probeMatchConfidence)
+			}
+		})
+	}
+}
+
+func TestServiceMatcherNoMatch(t *testing.T) {
+	sm := newServiceMatcher("")
+	for _, payload := range []string{"", "GET / HTTP/1.1\r\n\r\n", "\x16\x03\x01\x02\x00", "hello"} {
+		if match := sm.match([]byte(payload)); match != nil {
+			t.Errorf("match(%q) = %+v, want nil", payload, match)
+		}
+	}
+}
+
+// stubPayload 只提供应用层载荷的ApplicationLayer
+type stubPayload []byte
+
+func (p stubPayload) LayerType() gopacket.LayerType { return gopacket.LayerTypePayload }
+func (p stubPayload) LayerContents() []byte         { return p }
+func (p stubPayload) LayerPayload() []byte          { return nil }
+func (p stubPayload) Payload() []byte               { return p }
+
+func TestIdentifyServiceNonstandardPort(t *testing.T) {
+	tests := []struct {
+		name           string
+		port           int
+		payload        string
+		wantString     string
+		wantConfidence float64
+	}{
+		{"ssh on 2222", 2222, "SSH-2.0-OpenSSH_9.3\r\n", "SSH OpenSSH 9.3", probeMatchConfidence},
+		{"http on 8000", 8000, "HTTP/1.1 200 OK\r\nServer: nginx/1.18.0\r\n\r\n", "HTTP nginx 1.18.0", probeMatchConfidence},
+		{"banner wins over port", 80, "SSH-2.0-OpenSSH_9.3\r\n", "SSH OpenSSH 9.3", probeMatchConfidence},
+		{"port fallback", 22, "\x00\x01binary", "SSH", portMatchConfidence},
+	}
+
+	pp := newTestParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := pp.identifyService(tt.port, stubPayload(tt.payload))
+			if match == nil {
+				t.Fatalf("identifyService(%d) = nil", tt.port)
+			}
+			if got := match.String(); got != tt.wantString || match.Confidence != tt.wantConfidence {
+				t.Errorf("identifyService(%d) = %q (%v), want %q (%v)", tt.port, got, match.Confidence, tt.wantString, tt.wantConfidence)
+			}
+		})
+	}
+
+	if match := pp.identifyService(8000, stubPayload("\x00\x01binary")); match != nil {
+		t.Errorf("identifyService(8000, binary) = %+v, want nil", match)
+	}
+}
+
+func TestServiceMatcherUserFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "probes.txt")
+	rules := "# 自定义规则\n" +
+		"match SSH m|^SSH-2\\.0-OpenSSH_([\\w.]+)| p/Internal bastion/ v/$1/\n" +
+		"match bogus\n" +
+		"match Broken m|(unclosed|\n" +
+		"match Game m|^GAME(\\d+)|i v/$1/\n"
+	if err := os.WriteFile(path, []byte(rules), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	sm := newServiceMatcher(path)
+
+	// 用户规则排在内置规则之前
+	if got := sm.match([]byte("SSH-2.0-OpenSSH_8.9\r\n")).String(); got != "SSH Internal bastion 8.9" {
+		t.Errorf("user rule match = %q, want %q", got, "SSH Internal bastion 8.9")
+	}
+	if got := sm.match([]byte("game42")).String(); got != "Game 42" {
+		t.Errorf("case-insensitive user rule = %q, want %q", got, "Game 42")
+	}
+	// 无效规则被跳过，内置规则仍然加载
+	if match := sm.match([]byte("RFB 003.008\n")); match == nil || match.Name != "VNC" {
+		t.Errorf("built-in rule after user file = %+v, want VNC", match)
+	}
+}
+
+func TestParseProbeLineErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"not a match line", "probe TCP NULL q||"},
+		{"missing pattern", "match SSH"},
+		{"no regex marker", "match SSH x|^SSH|"},
+		{"unterminated regex", "match SSH m|^SSH"},
+		{"unknown flag", "match SSH m|^SSH|x"},
+		{"invalid regex", "match SSH m|^(SSH|"},
+		{"unterminated template", "match SSH m|^SSH| p/OpenSSH"},
+		{"bad template", "match SSH m|^SSH| px/a/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseProbeLine(tt.line); err == nil {
+				t.Errorf("parseProbeLine(%q) error = nil, want error", tt.line)
+			}
+		})
+	}
+}
+
+func TestServiceBanner(t *testing.T) {
+	oversized := append([]byte("SSH-2.0-"), bytes.Repeat([]byte{0x00, 0xff, 'A'}, 2000)...)
+
+	tests := []struct {
+		name    string
+		payload []byte
+		maxLen  int
+		want    string
+	}{
+		{"first line only", []byte("220 (vsFTPd 3.0.5)\r\n230 Login\r\n"), 256, "220 (vsFTPd 3.0.5)"},
+		{"lone lf kept", []byte("J\x00\x00\x00\x0a8.0.32\x00"), 256, `J\x00\x00\x00\x0a8.0.32\x00`},
+		{"backslash escaped", []byte(`220 C:\ftp` + "\r\n"), 256, `220 C:\\ftp`},
+		{"utf8 kept", []byte("220 欢迎\r\n"), 256, "220 欢迎"},
+		{"invalid utf8 escaped", []byte("220 \xe6\xac\r\n"), 256, `220 \xe6\xac`},
+		{"truncated", []byte("SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.1"), 16, "SSH-2.0-OpenSSH_..."},
+		{"escape not split", []byte("ab\x01cd"), 4, "ab..."},
+		{"oversized binary", oversized, 64, "SSH-2.0-" + strings.Repeat(`\x00\xffA`, 6) + "..."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serviceBanner(tt.payload, tt.maxLen); got != tt.want {
+				t.Errorf("serviceBanner() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// 不限制长度时只扫描前maxBannerScan字节（前缀后338组完整的3字节加2个剩余字节），结果仍是可打印的合法UTF-8
+	got := serviceBanner(oversized, 0)
+	if !utf8.ValidString(got) || strings.ContainsFunc(got, func(r rune) bool { return !unicode.IsPrint(r) }) {
+		t.Errorf("serviceBanner() contains non-printable output")
+	}
+	if want := "SSH-2.0-" + strings.Repeat(`\x00\xffA`, 338) + `\x00\xff`; got != want {
+		t.Errorf("serviceBanner() length = %d, want %d", len(got), len(want))
+	}
+}
+
+func TestIdentifyServiceBannerLimit(t *testing.T) {
+	pp := newTestParser()
+	pp.config.Parser.MaxBannerLength = 32
+
+	payload := "HTTP/1.1 200 OK\r\nServer: nginx/1.24.0\r\n\r\n" + strings.Repeat("\x00\x01", 4096)
+	match := pp.identifyService(8080, stubPayload("SSH-2.0-OpenSSH_9.3 "+strings.Repeat("\x00", 4096)))
+	if match == nil || match.Name != "SSH" {
+		t.Fatalf("identifyService() = %+v, want SSH", match)
+	}
+	if len(match.Banner) > 32+len("...") || !strings.HasSuffix(match.Banner, "...") {
+		t.Errorf("Banner = %q, want at most 32 bytes plus ellipsis", match.Banner)
+	}
+
+	match = pp.identifyService(80, stubPayload(payload))
+	if match == nil || match.Banner != "HTTP/1.1 200 OK" {
+		t.Errorf("identifyService(http) banner = %+v, want status line", match)
+	}
+}
@@ -0,0 +1,198 @@
+package parser
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+
+	"assets_discovery/internal/assets"
+)
+
+// protocolCounters 单个协议解析器的解析次数和失败次数，多个工作协程并发累加
+type protocolCounters struct {
+	attempts uint64
+	errors   uint64
+}
+
+// checkedParser 能区分是否实际解析了数据包并报告数据格式错误的解析器
+// 未实现该接口的解析器（包括外部注册的插件）每次调用Parse都计为一次解析，只有panic计为失败
+type checkedParser interface {
+	parseChecked(packet gopacket.Packet, assetInfo *assets.AssetInfo) (bool, error)
+}
+
+// runProtocol 调用协议解析器并统计结果，返回是否解析失败
+// 解析器panic时恢复并计为解析失败，不影响其他解析器和后续数据包
+func (pp *PacketParser) runProtocol(protocol ProtocolParser, packet gopacket.Packet, assetInfo *assets.AssetInfo) (failed bool) {
+	counters := pp.counters[protocol.Name()]
+
+	defer func() {
+		if r := recover(); r != nil {
+			pp.parseFailed(protocol.Name(), counters, fmt.Errorf("解析时发生panic: %v", r))
+			failed = true
+		}
+	}()
+
+	checked, ok := protocol.(checkedParser)
+	if !ok {
+		atomic.AddUint64(&counters.attempts, 1)
+		protocol.Parse(packet, assetInfo)
+		return false
+	}
+
+	parsed, err := checked.parseChecked(packet, assetInfo)
+	if parsed {
+		atomic.AddUint64(&counters.attempts, 1)
+	}
+	if err != nil {
+		pp.parseFailed(protocol.Name(), counters, err)
+		return true
+	}
+	return false
+}
+
+// parseFailed 记录一次解析失败，每个协议只输出第一次失败的原因
+func (pp *PacketParser) parseFailed(name string, counters *protocolCounters, err error) {
+	if atomic.AddUint64(&counters.errors, 1) == 1 {
+		log.Printf("%s协议解析失败（之后的失败只计数）: %v", name, err)
+	}
+}
+
+// ParseStats 返回各启用协议的解析次数和失败次数
+func (pp *PacketParser) ParseStats() map[string]assets.ProtocolParseStats {
+	stats := make(map[string]assets.ProtocolParseStats, len(pp.counters))
+	for name, counters := range pp.counters {
+		stats[name] = assets.ProtocolParseStats{
+			Attempts: atomic.LoadUint64(&counters.attempts),
+			Errors:   atomic.LoadUint64(&counters.errors),
+		}
+	}
+	return stats
+}
+
+// Close 关闭调试转储文件
+func (pp *PacketParser) Close() error {
+	if pp.dump != nil {
+		return pp.dump.close()
+	}
+	return nil
+}
+
+// packetDumper 将解析失败的数据包写入pcap文件，文件在第一次写入时创建
+type packetDumper struct {
+	path string
+
+	mu       sync.Mutex
+	file     *os.File
+	writer   *pcapgo.Writer
+	linkType layers.LinkType
+	failed   bool
+	skipped  int
+}
+
+// newPacketDumper 创建调试转储，path为空时返回nil
+func newPacketDumper(path string) *packetDumper {
+	if path == "" {
+		return nil
+	}
+	return &packetDumper{path: path}
+}
+
+// write 写入数据包，pcap文件只能有一种链路类型，与第一个数据包不同的会被跳过
+func (d *packetDumper) write(packet gopacket.Packet) {
+	linkType, ok := packetLinkType(packet)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.failed {
+		return
+	}
+	if d.writer == nil {
+		if !ok {
+			d.skipped++
+			return
+		}
+		if err := d.open(linkType); err != nil {
+			log.Printf("创建调试转储文件失败，已停止转储: %v", err)
+			d.failed = true
+			return
+		}
+	}
+	if !ok || linkType != d.linkType {
+		d.skipped++
+		return
+	}
+
+	data := packet.Data()
+	ci := packet.Metadata().CaptureInfo
+	ci.CaptureLength = len(data)
+	if ci.Length < ci.CaptureLength {
+		ci.Length = ci.CaptureLength
+	}
+	ci.AncillaryData = nil
+	if err := d.writer.WritePacket(ci, data); err != nil {
+		log.Printf("写入调试转储失败，已停止转储: %v", err)
+		d.failed = true
+	}
+}
+
+// open 创建转储文件并写入pcap文件头，调用方需持有锁
+func (d *packetDumper) open(linkType layers.LinkType) error {
+	f, err := os.Create(d.path)
+	if err != nil {
+		return err
+	}
+
+	w := pcapgo.NewWriter(f)
+	if err := w.WriteFileHeader(65536, linkType); err != nil {
+		f.Close()
+		return err
+	}
+
+	log.Printf("解析失败的数据包将写入: %s", d.path)
+	d.file, d.writer, d.linkType = f, w, linkType
+	return nil
+}
+
+// close 关闭转储文件
+func (d *packetDumper) close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.skipped > 0 {
+		log.Printf("调试转储跳过了 %d 个链路类型不同的数据包", d.skipped)
+	}
+	if d.file == nil {
+		return nil
+	}
+	err := d.file.Close()
+	d.file, d.writer = nil, nil
+	d.failed = true
+	return err
+}
+
+// packetLinkType 根据数据包的第一层推断其链路类型
+func packetLinkType(packet gopacket.Packet) (layers.LinkType, bool) {
+	packetLayers := packet.Layers()
+	if len(packetLayers) == 0 {
+		return 0, false
+	}
+
+	switch packetLayers[0].LayerType() {
+	case layers.LayerTypeEthernet:
+		return layers.LinkTypeEthernet, true
+	case layers.LayerTypeLinuxSLL:
+		return layers.LinkTypeLinuxSLL, true
+	case layers.LayerTypeLoopback:
+		return layers.LinkTypeNull, true
+	case layers.LayerTypeIPv4, layers.LayerTypeIPv6:
+		return layers.LinkTypeRaw, true
+	}
+	return 0, false
+}
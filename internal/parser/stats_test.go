@@ -0,0 +1,158 @@
+package parser
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+
+	"assets_discovery/internal/assets"
+	"assets_discovery/internal/config"
+)
+
+// udpPacket 构造192.168.1.10从随机端口发往dstPort的UDP报文
+func udpPacket(t *testing.T, dstPort layers.UDPPort, payload []byte) gopacket.Packet {
+	t.Helper()
+
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e},
+		DstMAC:       net.HardwareAddr{0x00, 0x1a, 0x2b, 0x00, 0x00, 0xfe},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ipv4 := ipv4Layer("192.168.1.10", "192.168.1.1", layers.IPProtocolUDP)
+	udp := &layers.UDP{SrcPort: 53000, DstPort: dstPort}
+	udp.SetNetworkLayerForChecksum(ipv4)
+	return buildPacket(t, time.Now(), eth, ipv4, udp, gopacket.Payload(payload))
+}
+
+// panicParser 解析时总是panic的插件
+type panicParser struct{}
+
+func (panicParser) Name() string                 { return "test_panic" }
+func (panicParser) Layers() []gopacket.LayerType { return []gopacket.LayerType{layers.LayerTypeUDP} }
+func (panicParser) Parse(gopacket.Packet, *assets.AssetInfo) {
+	panic("malformed plugin input")
+}
+
+func init() {
+	RegisterProtocolParser(panicParser{})
+}
+
+func TestParseErrorCounters(t *testing.T) {
+	validQuery := serialize(t, &layers.DNS{
+		Questions: []layers.DNSQuestion{{Name: []byte("example.com"), Type: layers.DNSTypeA, Class: layers.DNSClassIN}},
+	})
+	// 头部声明1个问题，但问题部分被截断
+	truncated := append([]byte{0x12, 0x34, 0x01, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0}, 0x07, 'e', 'x')
+
+	tests := []struct {
+		name       string
+		protocols  []string
+		packet     func(t *testing.T) gopacket.Packet
+		wantStats  map[string]assets.ProtocolParseStats
+		wantDumped bool
+	}{
+		{
+			name:      "valid dns",
+			protocols: []string{"dns"},
+			packet:    func(t *testing.T) gopacket.Packet { return udpPacket(t, 53, validQuery) },
+			wantStats: map[string]assets.ProtocolParseStats{"dns": {Attempts: 1}},
+		},
+		{
+			name:       "dns too short",
+			protocols:  []string{"dns"},
+			packet:     func(t *testing.T) gopacket.Packet { return udpPacket(t, 53, []byte{1, 2, 3, 4, 5}) },
+			wantStats:  map[string]assets.ProtocolParseStats{"dns": {Attempts: 1, Errors: 1}},
+			wantDumped: true,
+		},
+		{
+			name:       "dns truncated question",
+			protocols:  []string{"dns"},
+			packet:     func(t *testing.T) gopacket.Packet { return udpPacket(t, 53, truncated) },
+			wantStats:  map[string]assets.ProtocolParseStats{"dns": {Attempts: 1, Errors: 1}},
+			wantDumped: true,
+		},
+		{
+			name:       "llmnr too short",
+			protocols:  []string{"llmnr", "dns"},
+			packet:     func(t *testing.T) gopacket.Packet { return udpPacket(t, 5355, []byte{0xff}) },
+			wantStats:  map[string]assets.ProtocolParseStats{"llmnr": {Attempts: 1, Errors: 1}, "dns": {}},
+			wantDumped: true,
+		},
+		{
+			name:       "plugin panic recovered",
+			protocols:  []string{"test_panic", "dns"},
+			packet:     func(t *testing.T) gopacket.Packet { return udpPacket(t, 53, validQuery) },
+			wantStats:  map[string]assets.ProtocolParseStats{"test_panic": {Attempts: 1, Errors: 1}, "dns": {Attempts: 1}},
+			wantDumped: true,
+		},
+		{
+			name:      "other port not attempted",
+			protocols: []string{"dns"},
+			packet:    func(t *testing.T) gopacket.Packet { return udpPacket(t, 9999, []byte{1}) },
+			wantStats: map[string]assets.ProtocolParseStats{"dns": {}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dump := filepath.Join(t.TempDir(), "errors.pcap")
+			cfg := &config.Config{}
+			cfg.Parser.EnabledProtocols = tt.protocols
+			cfg.Parser.DebugDump = dump
+			pp := NewPacketParser(cfg)
+
+			packet := tt.packet(t)
+			if assetInfo := pp.ParsePacket(packet); assetInfo == nil {
+				t.Fatal("ParsePacket() = nil, want asset info despite parse errors")
+			}
+			if err := pp.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			stats := pp.ParseStats()
+			if len(stats) != len(tt.wantStats) {
+				t.Errorf("ParseStats() = %v, want %v", stats, tt.wantStats)
+			}
+			for name, want := range tt.wantStats {
+				if stats[name] != want {
+					t.Errorf("ParseStats()[%s] = %+v, want %+v", name, stats[name], want)
+				}
+			}
+
+			// 解析失败的数据包原样写入调试转储
+			f, err := os.Open(dump)
+			if !tt.wantDumped {
+				if err == nil {
+					f.Close()
+					t.Errorf("debug dump created for packet without parse errors")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("debug dump not created: %v", err)
+			}
+			defer f.Close()
+			r, err := pcapgo.NewReader(f)
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+			data, _, err := r.ReadPacketData()
+			if err != nil {
+				t.Fatalf("ReadPacketData() error = %v", err)
+			}
+			if string(data) != string(packet.Data()) {
+				t.Errorf("dumped packet differs from input")
+			}
+			if _, _, err := r.ReadPacketData(); err != io.EOF {
+				t.Errorf("second ReadPacketData() error = %v, want EOF", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"assets_discovery/internal/assets"
+)
+
+// Telnet命令字节(RFC 854)
+const (
+	telnetIAC  = 0xFF
+	telnetDONT = 0xFE
+	telnetDO   = 0xFD
+	telnetWONT = 0xFC
+	telnetWILL = 0xFB
+	telnetSB   = 0xFA
+	telnetSE   = 0xF0
+)
+
+// telnetVerbs 选项协商命令的名称
+var telnetVerbs = map[byte]string{
+	telnetWILL: "will",
+	telnetWONT: "wont",
+	telnetDO:   "do",
+	telnetDONT: "dont",
+}
+
+// telnetOptionNames 常见的Telnet选项
+var telnetOptionNames = map[byte]string{
+	0:  "binary",
+	1:  "echo",
+	3:  "suppress_go_ahead",
+	5:  "status",
+	24: "terminal_type",
+	31: "window_size",
+	32: "terminal_speed",
+	33: "remote_flow_control",
+	34: "linemode",
+	35: "x_display",
+	36: "environ",
+	39: "new_environ",
+}
+
+// parseTelnet 解析Telnet服务端报文，去除IAC选项协商序列后记录可读的登录横幅
+// 横幅常包含设备厂商和型号（如"User Access Verification"），用于识别网络设备和IoT设备
+func (pp *PacketParser) parseTelnet(assetInfo *assets.AssetInfo, payload []byte) error {
+	// 只有服务端发送的横幅能说明设备类型，客户端的协商报文忽略
+	tcpInfo, _ := assetInfo.Protocols["tcp"].(map[string]interface{})
+	if tcpInfo == nil || tcpInfo["role"] != "server" {
+		return nil
+	}
+	port, _ := tcpInfo["src_port"].(int)
+
+	text, options := stripTelnetCommands(payload)
+	telnetInfo := map[string]interface{}{
+		"port": port,
+	}
+	if len(options) > 0 {
+		telnetInfo["options"] = options
+	}
+
+	banner := telnetBanner(text, pp.config.Parser.MaxBannerLength)
+	if banner != "" {
+		telnetInfo["banner"] = banner
+
+		if assetInfo.Services == nil {
+			assetInfo.Services = make(map[string]interface{})
+		}
+		assetInfo.Services[fmt.Sprintf("%d/tcp", port)] = map[string]interface{}{
+			"version": "Telnet",
+			"banner":  banner,
+		}
+	}
+
+	assetInfo.Protocols["telnet"] = telnetInfo
+	return nil
+}
+
+// stripTelnetCommands 去除IAC命令序列，返回剩余的数据和协商的选项，例如"will echo"
+// 子协商(IAC SB ... IAC SE)整体跳过，IAC IAC是转义的0xFF数据字节
+// 命令可能被拆分到下一个TCP报文中，报文末尾不完整的命令直接丢弃
+func stripTelnetCommands(payload []byte) ([]byte, []string) {
+	var text []byte
+	var options []string
+
+	for i := 0; i < len(payload); i++ {
+		if payload[i] != telnetIAC {
+			text = append(text, payload[i])
+			continue
+		}
+		if i+1 >= len(payload) {
+			return text, options
+		}
+
+		cmd := payload[i+1]
+		switch {
+		case cmd == telnetIAC:
+			text = append(text, telnetIAC)
+			i++
+		case cmd >= telnetWILL && cmd <= telnetDONT:
+			if i+2 >= len(payload) {
+				return text, options
+			}
+			options = append(options, telnetVerbs[cmd]+" "+telnetOptionName(payload[i+2]))
+			i += 2
+		case cmd == telnetSB:
+			end := -1
+			for j := i + 2; j+1 < len(payload); j++ {
+				if payload[j] == telnetIAC && payload[j+1] == telnetSE {
+					end = j + 1
+					break
+				}
+			}
+			if end < 0 {
+				return text, options
+			}
+			i = end
+		default:
+			// NOP、GA等不带参数的命令
+			i++
+		}
+	}
+
+	return text, options
+}
+
+// telnetOptionName 返回选项名称，未知选项使用编号
+func telnetOptionName(option byte) string {
+	if name, ok := telnetOptionNames[option]; ok {
+		return name
+	}
+	return fmt.Sprintf("option_%d", option)
+}
+
+// telnetBanner 将横幅中的非空行以空格连接，不可打印字符转义，超过maxLen时截断
+func telnetBanner(text []byte, maxLen int) string {
+	lines := strings.FieldsFunc(string(text), func(r rune) bool {
+		return r == '\r' || r == '\n' || r == 0
+	})
+
+	var parts []string
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			parts = append(parts, line)
+		}
+	}
+
+	return serviceBanner([]byte(strings.Join(parts, " ")), maxLen)
+}
@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"assets_discovery/internal/assets"
+)
+
+func TestStripTelnetCommands(t *testing.T) {
+	tests := []struct {
+		name        string
+		payload     string
+		wantText    string
+		wantOptions []string
+	}{
+		{
+			name:        "negotiation then banner",
+			payload:     "\xff\xfb\x01\xff\xfb\x03\xff\xfd\x18\xff\xfd\x1f\r\nUser Access Verification\r\n",
+			wantText:    "\r\nUser Access Verification\r\n",
+			wantOptions: []string{"will echo", "will suppress_go_ahead", "do terminal_type", "do window_size"},
+		},
+		{
+			name:        "subnegotiation skipped",
+			payload:     "\xff\xfa\x18\x01\xff\xf0login: ",
+			wantText:    "login: ",
+			wantOptions: nil,
+		},
+		{
+			name:        "escaped iac kept as data",
+			payload:     "a\xff\xffb",
+			wantText:    "a\xffb",
+			wantOptions: nil,
+		},
+		{
+			name:        "nop and go ahead dropped",
+			payload:     "\xff\xf1ok\xff\xf9",
+			wantText:    "ok",
+			wantOptions: nil,
+		},
+		{
+			name:        "unknown option",
+			payload:     "\xff\xfc\xc8",
+			wantText:    "",
+			wantOptions: []string{"wont option_200"},
+		},
+		{
+			name:        "truncated command",
+			payload:     "hi\xff\xfb",
+			wantText:    "hi",
+			wantOptions: nil,
+		},
+		{
+			name:        "unterminated subnegotiation",
+			payload:     "hi\xff\xfa\x18\x01banner",
+			wantText:    "hi",
+			wantOptions: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, options := stripTelnetCommands([]byte(tt.payload))
+			if string(text) != tt.wantText {
+				t.Errorf("stripTelnetCommands() text = %q, want %q", text, tt.wantText)
+			}
+			if !reflect.DeepEqual(options, tt.wantOptions) {
+				t.Errorf("stripTelnetCommands() options = %v, want %v", options, tt.wantOptions)
+			}
+		})
+	}
+}
+
+func TestParseTelnet(t *testing.T) {
+	const (
+		serverMAC = "00:1a:2b:3c:4d:02"
+		serverIP  = "192.168.1.30"
+		clientIP  = "192.168.1.20"
+	)
+	payload := "\xff\xfb\x01\xff\xfb\x03\xff\xfd\x18\xff\xfa\x18\x01\xff\xf0" +
+		"\r\n\r\nUser Access Verification\r\n\r\nUsername: "
+
+	pp := newTestParser("telnet")
+	ts := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	info := pp.ParsePacket(tcpSegment(t, ts, serverMAC, serverIP, 23, clientIP, 51000, "A", payload))
+	if info == nil {
+		t.Fatal("ParsePacket() = nil")
+	}
+	telnetInfo, ok := info.Protocols["telnet"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Protocols[telnet] = %v, want telnet info", info.Protocols["telnet"])
+	}
+	const wantBanner = "User Access Verification Username:"
+	if telnetInfo["banner"] != wantBanner || telnetInfo["port"] != 23 {
+		t.Errorf("telnet banner = %q on port %v, want %q on 23", telnetInfo["banner"], telnetInfo["port"], wantBanner)
+	}
+	if want := []string{"will echo", "will suppress_go_ahead", "do terminal_type"}; !reflect.DeepEqual(telnetInfo["options"], want) {
+		t.Errorf("telnet options = %v, want %v", telnetInfo["options"], want)
+	}
+	service, _ := info.Services["23/tcp"].(map[string]interface{})
+	if service["banner"] != wantBanner {
+		t.Errorf("Services[23/tcp] = %v, want banner %q", info.Services["23/tcp"], wantBanner)
+	}
+
+	// 横幅用于设备分类
+	if asset := assets.NewAsset(info); asset.DeviceType != "网络设备" || asset.DeviceTypeSource != "telnet" {
+		t.Errorf("device type = %s (%s), want 网络设备 (telnet)", asset.DeviceType, asset.DeviceTypeSource)
+	}
+
+	// 客户端发往Telnet端口的协商报文不产生横幅
+	info = pp.ParsePacket(tcpSegment(t, ts, "00:1a:2b:3c:4d:01", clientIP, 51000, serverIP, 23, "A", "\xff\xfd\x01\xff\xfb\x18"))
+	if info != nil && info.Protocols["telnet"] != nil {
+		t.Errorf("client Protocols[telnet] = %v, want none", info.Protocols["telnet"])
+	}
+}
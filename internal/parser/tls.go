@@ -0,0 +1,143 @@
+package parser
+
+import (
+	"encoding/binary"
+	"strings"
+
+	"assets_discovery/internal/assets"
+)
+
+// dohResolverSuffixes 公共DoH解析服务的主机名，按后缀匹配TLS SNI
+var dohResolverSuffixes = []string{
+	"dns.google",
+	"cloudflare-dns.com",
+	"one.one.one.one",
+	"dns.quad9.net",
+	"doh.opendns.com",
+	"dns.adguard.com",
+	"dns.adguard-dns.com",
+	"dns.nextdns.io",
+	"doh.cleanbrowsing.org",
+	"doh.pub",
+	"dns.alidns.com",
+	"doh.360.cn",
+}
+
+// parseTLSClientHello 从TLS ClientHello中提取SNI，报文不是完整的ClientHello时返回false
+func parseTLSClientHello(payload []byte) (string, bool) {
+	// 记录层头部5字节，握手类型1为ClientHello
+	if len(payload) < 9 || payload[0] != 0x16 || payload[5] != 0x01 {
+		return "", false
+	}
+
+	// 跳过握手头部(4)、客户端版本(2)和随机数(32)
+	data := payload[9:]
+	if len(data) < 34 {
+		return "", false
+	}
+	data = data[34:]
+
+	// 会话ID
+	if len(data) < 1 || len(data) < 1+int(data[0]) {
+		return "", false
+	}
+	data = data[1+int(data[0]):]
+
+	// 密码套件
+	if len(data) < 2 {
+		return "", false
+	}
+	n := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+n {
+		return "", false
+	}
+	data = data[2+n:]
+
+	// 压缩方法
+	if len(data) < 1 || len(data) < 1+int(data[0]) {
+		return "", false
+	}
+	data = data[1+int(data[0]):]
+
+	// 没有扩展的ClientHello不带SNI
+	if len(data) < 2 {
+		return "", true
+	}
+	data = data[2:]
+
+	for len(data) >= 4 {
+		extType := binary.BigEndian.Uint16(data)
+		extLen := int(binary.BigEndian.Uint16(data[2:]))
+		if len(data) < 4+extLen {
+			break
+		}
+		ext := data[4 : 4+extLen]
+		data = data[4+extLen:]
+
+		// server_name扩展: 列表长度(2) + 名称类型(1) + 名称长度(2) + 名称
+		if extType != 0 || len(ext) < 5 || ext[2] != 0 {
+			continue
+		}
+		nameLen := int(binary.BigEndian.Uint16(ext[3:]))
+		if len(ext) < 5+nameLen {
+			break
+		}
+		return strings.ToLower(string(ext[5 : 5+nameLen])), true
+	}
+
+	return "", true
+}
+
+// parseTLS 解析HTTPS连接的ClientHello，记录SNI并识别访问公共DoH服务的客户端
+func (pp *PacketParser) parseTLS(assetInfo *assets.AssetInfo, payload []byte) error {
+	sni, ok := parseTLSClientHello(payload)
+	if !ok {
+		return nil
+	}
+
+	tlsInfo := map[string]interface{}{}
+	if sni != "" {
+		tlsInfo["sni"] = sni
+	}
+	assetInfo.Protocols["tls"] = tlsInfo
+
+	if isDoHResolver(sni) {
+		setDNSBehavior(assetInfo, "doh_resolver", sni)
+	}
+
+	return nil
+}
+
+// parseDoT 识别TCP 853上的DNS over TLS客户端
+func (pp *PacketParser) parseDoT(assetInfo *assets.AssetInfo, payload []byte) error {
+	if _, ok := parseTLSClientHello(payload); ok {
+		setDNSBehavior(assetInfo, "dot", true)
+	}
+
+	return nil
+}
+
+// isDoHResolver 判断SNI是否为已知的公共DoH解析服务
+func isDoHResolver(sni string) bool {
+	for _, suffix := range dohResolverSuffixes {
+		if sni == suffix || strings.HasSuffix(sni, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// setDNSBehavior 在 Protocols["dns"]["behavior"] 中记录单个报文体现的DNS行为
+func setDNSBehavior(assetInfo *assets.AssetInfo, key string, value interface{}) {
+	dnsInfo, ok := assetInfo.Protocols["dns"].(map[string]interface{})
+	if !ok {
+		dnsInfo = map[string]interface{}{}
+		assetInfo.Protocols["dns"] = dnsInfo
+	}
+	behavior, ok := dnsInfo["behavior"].(map[string]interface{})
+	if !ok {
+		behavior = map[string]interface{}{}
+		dnsInfo["behavior"] = behavior
+	}
+	behavior[key] = value
+}
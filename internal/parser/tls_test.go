@@ -0,0 +1,100 @@
+package parser
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// clientHello 构造携带SNI的TLS ClientHello记录，sni为空时不带server_name扩展
+func clientHello(sni string) []byte {
+	body := []byte{0x03, 0x03}                  // 客户端版本
+	body = append(body, make([]byte, 32)...)    // 随机数
+	body = append(body, 0)                      // 会话ID
+	body = append(body, 0x00, 0x02, 0x13, 0x01) // 密码套件
+	body = append(body, 0x01, 0x00)             // 压缩方法
+
+	var extensions []byte
+	if sni != "" {
+		name := []byte(sni)
+		ext := binary.BigEndian.AppendUint16(nil, uint16(len(name)+3))
+		ext = append(ext, 0)
+		ext = binary.BigEndian.AppendUint16(ext, uint16(len(name)))
+		ext = append(ext, name...)
+
+		extensions = binary.BigEndian.AppendUint16(extensions, 0)
+		extensions = binary.BigEndian.AppendUint16(extensions, uint16(len(ext)))
+		extensions = append(extensions, ext...)
+	}
+	body = binary.BigEndian.AppendUint16(body, uint16(len(extensions)))
+	body = append(body, extensions...)
+
+	handshake := []byte{0x01, 0, byte(len(body) >> 8), byte(len(body))}
+	handshake = append(handshake, body...)
+
+	record := []byte{0x16, 0x03, 0x01}
+	record = binary.BigEndian.AppendUint16(record, uint16(len(handshake)))
+	return append(record, handshake...)
+}
+
+func TestParseTLSDoH(t *testing.T) {
+	tests := []struct {
+		name         string
+		payload      []byte
+		wantTLS      map[string]interface{}
+		wantBehavior map[string]interface{}
+	}{
+		{"google doh", clientHello("dns.google"),
+			map[string]interface{}{"sni": "dns.google"},
+			map[string]interface{}{"doh_resolver": "dns.google"}},
+		{"cloudflare subdomain", clientHello("Mozilla.Cloudflare-DNS.com"),
+			map[string]interface{}{"sni": "mozilla.cloudflare-dns.com"},
+			map[string]interface{}{"doh_resolver": "mozilla.cloudflare-dns.com"}},
+		{"ordinary https", clientHello("www.example.com"),
+			map[string]interface{}{"sni": "www.example.com"}, nil},
+		{"suffix without dot boundary", clientHello("notdns.google"),
+			map[string]interface{}{"sni": "notdns.google"}, nil},
+		{"no sni", clientHello(""), map[string]interface{}{}, nil},
+		{"not a client hello", []byte("GET / HTTP/1.1\r\n\r\n"), nil, nil},
+	}
+
+	pp := newTestParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assetInfo := newTestAssetInfo()
+			if err := pp.parseTLS(assetInfo, tt.payload); err != nil {
+				t.Fatalf("parseTLS() error = %v", err)
+			}
+
+			if got, _ := assetInfo.Protocols["tls"].(map[string]interface{}); !reflect.DeepEqual(got, tt.wantTLS) {
+				t.Errorf("tls = %v, want %v", got, tt.wantTLS)
+			}
+			dnsInfo, _ := assetInfo.Protocols["dns"].(map[string]interface{})
+			if got, _ := dnsInfo["behavior"].(map[string]interface{}); !reflect.DeepEqual(got, tt.wantBehavior) {
+				t.Errorf("dns behavior = %v, want %v", got, tt.wantBehavior)
+			}
+		})
+	}
+}
+
+func TestParseDoT(t *testing.T) {
+	pp := newTestParser()
+
+	assetInfo := newTestAssetInfo()
+	if err := pp.parseDoT(assetInfo, clientHello("dns.quad9.net")); err != nil {
+		t.Fatalf("parseDoT() error = %v", err)
+	}
+	dnsInfo, _ := assetInfo.Protocols["dns"].(map[string]interface{})
+	if want := map[string]interface{}{"dot": true}; !reflect.DeepEqual(dnsInfo["behavior"], want) {
+		t.Errorf("dns behavior = %v, want %v", dnsInfo["behavior"], want)
+	}
+
+	// 853端口上不是TLS握手的数据不视为DoT
+	other := newTestAssetInfo()
+	if err := pp.parseDoT(other, []byte{0x00, 0x1c, 0x12, 0x34}); err != nil {
+		t.Fatalf("parseDoT() error = %v", err)
+	}
+	if _, ok := other.Protocols["dns"]; ok {
+		t.Errorf("dns = %v, want none", other.Protocols["dns"])
+	}
+}
@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"log"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"assets_discovery/internal/assets"
+)
+
+// untaggedVLAN 不带802.1Q标签的帧在VLAN筛选中使用的ID
+const untaggedVLAN = 0
+
+// vlanFilter 按802.1Q VLAN ID筛选数据包，在创建资产之前丢弃不关心的VLAN上的帧
+// include非空时只处理列表中的VLAN，exclude中的VLAN总是被丢弃；QinQ帧的任一层标签匹配即可
+type vlanFilter struct {
+	include map[uint16]bool
+	exclude map[uint16]bool
+}
+
+// newVLANFilter 创建VLAN筛选，两个列表都为空时返回nil，超出0-4095的ID被忽略
+func newVLANFilter(include, exclude []int) *vlanFilter {
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil
+	}
+
+	return &vlanFilter{
+		include: vlanSet(include, "include_vlans"),
+		exclude: vlanSet(exclude, "exclude_vlans"),
+	}
+}
+
+func vlanSet(ids []int, key string) map[uint16]bool {
+	set := make(map[uint16]bool, len(ids))
+	for _, id := range ids {
+		if id < 0 || id > 4095 {
+			log.Printf("忽略无效的VLAN ID %d (parser.%s)", id, key)
+			continue
+		}
+		set[uint16(id)] = true
+	}
+	return set
+}
+
+// allows 检查数据包所在的VLAN是否需要处理，f为nil时不筛选
+func (f *vlanFilter) allows(packet gopacket.Packet) bool {
+	if f == nil {
+		return true
+	}
+
+	ids := packetVLANs(packet)
+	if len(ids) == 0 {
+		ids = []uint16{untaggedVLAN}
+	}
+
+	for _, id := range ids {
+		if f.exclude[id] {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, id := range ids {
+		if f.include[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// packetVLANs 按从外到内的顺序返回数据包中802.1Q标签的VLAN ID
+func packetVLANs(packet gopacket.Packet) []uint16 {
+	var ids []uint16
+	for _, layer := range packet.Layers() {
+		if dot1q, ok := layer.(*layers.Dot1Q); ok {
+			ids = append(ids, dot1q.VLANIdentifier)
+		}
+	}
+	return ids
+}
+
+// parseVLAN 记录数据包的VLAN ID，QinQ帧的内层标签记录为inner_id
+func parseVLAN(assetInfo *assets.AssetInfo, packet gopacket.Packet) {
+	ids := packetVLANs(packet)
+	if len(ids) == 0 {
+		return
+	}
+
+	vlanInfo := map[string]interface{}{
+		"id": int(ids[0]),
+	}
+	if len(ids) > 1 {
+		vlanInfo["inner_id"] = int(ids[len(ids)-1])
+	}
+	assetInfo.Protocols["vlan"] = vlanInfo
+}
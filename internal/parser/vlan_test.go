@@ -0,0 +1,96 @@
+package parser
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"assets_discovery/internal/config"
+)
+
+// taggedARP 构造带有vlans中各层802.1Q标签（从外到内）的ARP请求，vlans为空时不带标签
+func taggedARP(t *testing.T, vlans ...uint16) gopacket.Packet {
+	t.Helper()
+
+	hw := net.HardwareAddr{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e}
+	stack := []gopacket.SerializableLayer{&layers.Ethernet{
+		SrcMAC:       hw,
+		DstMAC:       net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		EthernetType: layers.EthernetTypeARP,
+	}}
+	if len(vlans) > 0 {
+		stack[0].(*layers.Ethernet).EthernetType = layers.EthernetTypeDot1Q
+	}
+	for i, id := range vlans {
+		next := layers.EthernetTypeARP
+		if i+1 < len(vlans) {
+			next = layers.EthernetTypeDot1Q
+		}
+		stack = append(stack, &layers.Dot1Q{VLANIdentifier: id, Type: next})
+	}
+	stack = append(stack, &layers.ARP{
+		AddrType:          layers.LinkTypeEthernet,
+		Protocol:          layers.EthernetTypeIPv4,
+		HwAddressSize:     6,
+		ProtAddressSize:   4,
+		Operation:         layers.ARPRequest,
+		SourceHwAddress:   hw,
+		SourceProtAddress: net.IPv4(192, 168, 1, 10).To4(),
+		DstHwAddress:      make([]byte, 6),
+		DstProtAddress:    net.IPv4(192, 168, 1, 1).To4(),
+	})
+	return buildPacket(t, time.Now(), stack...)
+}
+
+func TestVLANFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []int
+		exclude []int
+		vlans   []uint16
+		want    bool
+	}{
+		{"no filter tagged", nil, nil, []uint16{10}, true},
+		{"no filter untagged", nil, nil, nil, true},
+		{"include match", []int{10, 20}, nil, []uint16{20}, true},
+		{"include miss", []int{10, 20}, nil, []uint16{30}, false},
+		{"include drops untagged", []int{10}, nil, nil, false},
+		{"include untagged as 0", []int{0, 10}, nil, nil, true},
+		{"exclude match", nil, []int{30}, []uint16{30}, false},
+		{"exclude miss", nil, []int{30}, []uint16{10}, true},
+		{"exclude keeps untagged", nil, []int{30}, nil, true},
+		{"exclude wins over include", []int{10}, []int{10}, []uint16{10}, false},
+		{"qinq inner tag included", []int{20}, nil, []uint16{100, 20}, true},
+		{"qinq outer tag excluded", []int{20}, []int{100}, []uint16{100, 20}, false},
+		{"invalid ids ignored", []int{-1, 5000, 10}, []int{4096}, []uint16{10}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Parser.EnabledProtocols = []string{"arp"}
+			cfg.Parser.IncludeVLANs = tt.include
+			cfg.Parser.ExcludeVLANs = tt.exclude
+			pp := NewPacketParser(cfg)
+
+			info := pp.ParsePacket(taggedARP(t, tt.vlans...))
+			if got := info != nil; got != tt.want {
+				t.Fatalf("ParsePacket(vlans %v) parsed = %v, want %v", tt.vlans, got, tt.want)
+			}
+			if info == nil || len(tt.vlans) == 0 {
+				return
+			}
+
+			vlanInfo, _ := info.Protocols["vlan"].(map[string]interface{})
+			if vlanInfo["id"] != int(tt.vlans[0]) {
+				t.Errorf("vlan id = %v, want %d", vlanInfo["id"], tt.vlans[0])
+			}
+			if len(tt.vlans) > 1 && vlanInfo["inner_id"] != int(tt.vlans[len(tt.vlans)-1]) {
+				t.Errorf("vlan inner_id = %v, want %d", vlanInfo["inner_id"], tt.vlans[len(tt.vlans)-1])
+			}
+		})
+	}
+}
@@ -5,7 +5,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/esapi"
@@ -13,10 +19,31 @@ import (
 	"assets_discovery/internal/config"
 )
 
+const (
+	esMaxBackoff         = 30 * time.Second // 单次重试的最大等待时间
+	esPendingRetryPeriod = 30 * time.Second // 重新写入失败文档的周期
+	esMaxPending         = 10000            // 缓存的失败文档上限
+)
+
 // ElasticsearchStorage Elasticsearch存储实现
 type ElasticsearchStorage struct {
 	client *elasticsearch.Client
-	index  string
+	index  string // 静态索引名，按天滚动时为指向所有日索引的别名
+
+	// 按天滚动时当天写入的索引，以及当天已清理过旧副本的资产ID
+	daily   bool
+	dailyMu sync.Mutex
+	current string
+	written map[string]bool
+
+	// 写入失败的文档，key为资产ID，只保留最新版本，之后写入或删除成功时移除
+	pending   map[string][]byte
+	pendingMu sync.Mutex
+	stopCh    chan struct{}
+	wg        sync.WaitGroup
+
+	// 重试缓存文档时持有写锁，防止旧版本覆盖期间写入成功的新版本
+	writeMu sync.RWMutex
 }
 
 // NewElasticsearchStorage 创建Elasticsearch存储
@@ -30,49 +57,116 @@ func NewElasticsearchStorage(cfg *config.ESConfig) (*ElasticsearchStorage, error
 		esCfg.Password = cfg.Password
 	}
 
+	transport, err := newESTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	esCfg.Transport = transport
+
+	// 瞬时故障时由客户端按指数退避重试
+	esCfg.RetryOnStatus = []int{429, 502, 503, 504}
+	esCfg.MaxRetries = cfg.MaxRetries
+	esCfg.DisableRetry = cfg.MaxRetries <= 0
+	esCfg.RetryBackoff = func(attempt int) time.Duration {
+		return retryBackoff(cfg.RetryBackoff, attempt)
+	}
+
+	switch cfg.IndexStrategy {
+	case "", "static", "daily":
+	default:
+		return nil, fmt.Errorf("不支持的索引策略: %s", cfg.IndexStrategy)
+	}
+
 	client, err := elasticsearch.NewClient(esCfg)
 	if err != nil {
 		return nil, fmt.Errorf("创建Elasticsearch客户端失败: %v", err)
 	}
 
 	es := &ElasticsearchStorage{
-		client: client,
-		index:  cfg.Index,
+		client:  client,
+		index:   cfg.Index,
+		daily:   cfg.IndexStrategy == "daily",
+		pending: make(map[string][]byte),
+		stopCh:  make(chan struct{}),
 	}
 
 	// 创建索引和映射
-	if err := es.createIndex(); err != nil {
+	if _, err := es.writeIndex(); err != nil {
 		return nil, fmt.Errorf("创建索引失败: %v", err)
 	}
 
+	es.wg.Add(1)
+	go es.retryRoutine()
+
 	return es, nil
 }
 
-// SaveAsset 保存资产
-func (es *ElasticsearchStorage) SaveAsset(asset interface{}) error {
-	// 提取资产ID
-	var assetID string
-	if assetMap, ok := asset.(map[string]interface{}); ok {
-		if id, exists := assetMap["id"]; exists {
-			if idStr, ok := id.(string); ok {
-				assetID = idStr
-			}
+// retryBackoff 计算第attempt次重试前的等待时间，指数增长并带随机抖动
+func retryBackoff(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		base = 500 * time.Millisecond
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	backoff := esMaxBackoff
+	if attempt <= 16 {
+		if d := base << uint(attempt-1); d < esMaxBackoff {
+			backoff = d
 		}
 	}
 
-	if assetID == "" {
-		return fmt.Errorf("无法提取资产ID")
+	// 在[backoff/2, backoff]之间随机取值，避免多个写入同时重试
+	half := int64(backoff / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// newESTransport 根据TLS配置构建HTTP传输层
+func newESTransport(cfg *config.ESConfig) (*http.Transport, error) {
+	if cfg.InsecureSkipVerify {
+		log.Println("警告: 已关闭Elasticsearch证书校验，连接可能遭受中间人攻击")
 	}
 
-	// 序列化资产数据
-	assetBytes, err := json.Marshal(asset)
+	tlsConfig, err := loadTLSConfig(cfg.CACert, cfg.ClientCert, cfg.ClientKey, cfg.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return transport, nil
+}
+
+// SaveAsset 保存资产，写入失败的文档会被缓存并在后台重试
+func (es *ElasticsearchStorage) SaveAsset(asset interface{}) error {
+	assetID, assetBytes, err := esDocument(asset)
+	if err != nil {
+		return err
+	}
+
+	es.writeMu.RLock()
+	defer es.writeMu.RUnlock()
+
+	if err := es.indexDocument(assetID, assetBytes); err != nil {
+		es.addPending(assetID, assetBytes)
+		return err
+	}
+
+	es.dropPending(assetID)
+	return nil
+}
+
+// indexDocument 索引单个文档
+func (es *ElasticsearchStorage) indexDocument(assetID string, assetBytes []byte) error {
+	index, err := es.writeIndex()
 	if err != nil {
-		return fmt.Errorf("序列化资产失败: %v", err)
+		return err
 	}
 
-	// 索引文档
 	req := esapi.IndexRequest{
-		Index:      es.index,
+		Index:      index,
 		DocumentID: assetID,
 		Body:       bytes.NewReader(assetBytes),
 		Refresh:    "true",
@@ -88,11 +182,390 @@ func (es *ElasticsearchStorage) SaveAsset(asset interface{}) error {
 		return fmt.Errorf("Elasticsearch错误: %s", res.Status())
 	}
 
+	es.dropOlderCopies(index, []string{assetID})
 	return nil
 }
 
+// esDocument 序列化资产并提取资产ID
+func esDocument(asset interface{}) (string, []byte, error) {
+	assetBytes, err := json.Marshal(asset)
+	if err != nil {
+		return "", nil, fmt.Errorf("序列化资产失败: %v", err)
+	}
+
+	var doc struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(assetBytes, &doc); err != nil || doc.ID == "" {
+		return "", nil, fmt.Errorf("无法提取资产ID")
+	}
+
+	return doc.ID, assetBytes, nil
+}
+
+// addPending 缓存写入失败的文档
+func (es *ElasticsearchStorage) addPending(assetID string, assetBytes []byte) {
+	es.pendingMu.Lock()
+	defer es.pendingMu.Unlock()
+
+	if _, exists := es.pending[assetID]; !exists && len(es.pending) >= esMaxPending {
+		log.Printf("待重试文档已达上限 %d，丢弃资产 %s", esMaxPending, assetID)
+		return
+	}
+	es.pending[assetID] = assetBytes
+}
+
+// dropPending 移除缓存的文档，资产已写入更新的版本或已被删除
+func (es *ElasticsearchStorage) dropPending(ids ...string) {
+	es.pendingMu.Lock()
+	defer es.pendingMu.Unlock()
+
+	for _, id := range ids {
+		delete(es.pending, id)
+	}
+}
+
+// retryRoutine 定期重新写入失败的文档
+func (es *ElasticsearchStorage) retryRoutine() {
+	defer es.wg.Done()
+
+	ticker := time.NewTicker(esPendingRetryPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			es.flushPending()
+		case <-es.stopCh:
+			return
+		}
+	}
+}
+
+// flushPending 重新写入缓存的文档，集群仍不可用时保留剩余文档
+func (es *ElasticsearchStorage) flushPending() {
+	es.pendingMu.Lock()
+	ids := make([]string, 0, len(es.pending))
+	for assetID := range es.pending {
+		ids = append(ids, assetID)
+	}
+	es.pendingMu.Unlock()
+
+	if len(ids) == 0 {
+		return
+	}
+
+	written := 0
+	var lastErr error
+	for _, assetID := range ids {
+		var ok bool
+		if ok, lastErr = es.retryPending(assetID); lastErr != nil {
+			break
+		}
+		if ok {
+			written++
+		}
+	}
+
+	if lastErr != nil {
+		log.Printf("重试写入Elasticsearch失败，成功 %d 个，剩余 %d 个: %v", written, es.pendingCount(), lastErr)
+	} else {
+		log.Printf("重试写入Elasticsearch成功 %d 个资产", written)
+	}
+}
+
+// retryPending 重新写入单个缓存的文档，期间其他写入等待，文档已被更新的写入或删除移除时跳过
+func (es *ElasticsearchStorage) retryPending(assetID string) (bool, error) {
+	es.writeMu.Lock()
+	defer es.writeMu.Unlock()
+
+	es.pendingMu.Lock()
+	assetBytes, exists := es.pending[assetID]
+	es.pendingMu.Unlock()
+	if !exists {
+		return false, nil
+	}
+
+	if err := es.indexDocument(assetID, assetBytes); err != nil {
+		return false, err
+	}
+	es.dropPending(assetID)
+	return true, nil
+}
+
+// pendingCount 缓存的待重试文档数
+func (es *ElasticsearchStorage) pendingCount() int {
+	es.pendingMu.Lock()
+	defer es.pendingMu.Unlock()
+	return len(es.pending)
+}
+
+// SaveAssets 使用Bulk API批量保存资产
+func (es *ElasticsearchStorage) SaveAssets(assets []interface{}) (int, error) {
+	index, err := es.writeIndex()
+	if err != nil {
+		return 0, err
+	}
+
+	var body bytes.Buffer
+	var ids []string
+	count := 0
+
+	for _, asset := range assets {
+		assetID, assetBytes, err := esDocument(asset)
+		if err != nil {
+			continue
+		}
+
+		meta := map[string]interface{}{
+			"index": map[string]interface{}{
+				"_index": index,
+				"_id":    assetID,
+			},
+		}
+		metaBytes, err := json.Marshal(meta)
+		if err != nil {
+			return 0, fmt.Errorf("构建批量请求失败: %v", err)
+		}
+
+		body.Write(metaBytes)
+		body.WriteByte('\n')
+		body.Write(assetBytes)
+		body.WriteByte('\n')
+		ids = append(ids, assetID)
+		count++
+	}
+
+	if count == 0 {
+		return 0, nil
+	}
+
+	es.writeMu.RLock()
+	defer es.writeMu.RUnlock()
+
+	req := esapi.BulkRequest{
+		Index:   index,
+		Body:    &body,
+		Refresh: "true",
+	}
+
+	res, err := req.Do(context.Background(), es.client)
+	if err != nil {
+		return 0, fmt.Errorf("批量索引失败: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return 0, fmt.Errorf("Elasticsearch错误: %s", res.Status())
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	if !result.Errors {
+		es.dropOlderCopies(index, ids)
+		es.dropPending(ids...)
+		return count, nil
+	}
+
+	saved := 0
+	var savedIDs []string
+	for i, item := range result.Items {
+		for _, op := range item {
+			if op.Status < 300 {
+				saved++
+				if i < len(ids) {
+					savedIDs = append(savedIDs, ids[i])
+				}
+			}
+		}
+	}
+	es.dropOlderCopies(index, savedIDs)
+	es.dropPending(savedIDs...)
+
+	return saved, fmt.Errorf("部分资产批量索引失败: %d/%d", count-saved, count)
+}
+
+// esMaxConflictRetries 乐观并发控制冲突时的最大重试次数
+const esMaxConflictRetries = 5
+
+// UpdateAsset 基于_seq_no/_primary_term的条件写入，版本冲突时重新读取并重试
+func (es *ElasticsearchStorage) UpdateAsset(id string, mutate AssetMutator) error {
+	es.writeMu.RLock()
+	defer es.writeMu.RUnlock()
+
+	for attempt := 0; attempt < esMaxConflictRetries; attempt++ {
+		index, err := es.writeIndex()
+		if err != nil {
+			return err
+		}
+
+		current, currentIndex, seqNo, primaryTerm, err := es.getVersioned(id)
+		if err != nil {
+			return err
+		}
+
+		updated, err := mutate(current)
+		if err != nil || updated == nil {
+			return err
+		}
+		updated["id"] = id
+
+		assetBytes, err := json.Marshal(updated)
+		if err != nil {
+			return fmt.Errorf("序列化资产失败: %v", err)
+		}
+
+		req := esapi.IndexRequest{
+			Index:      index,
+			DocumentID: id,
+			Body:       bytes.NewReader(assetBytes),
+			Refresh:    "true",
+		}
+		if current == nil || currentIndex != index {
+			// 文档不存在（或按天滚动时只存在于旧索引）时只允许创建，防止覆盖其他写入方刚创建的文档
+			req.OpType = "create"
+		} else {
+			req.IfSeqNo = &seqNo
+			req.IfPrimaryTerm = &primaryTerm
+		}
+
+		res, err := req.Do(context.Background(), es.client)
+		if err != nil {
+			return fmt.Errorf("索引文档失败: %v", err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode == 409 {
+			continue
+		}
+		if res.IsError() {
+			return fmt.Errorf("Elasticsearch错误: %s", res.Status())
+		}
+		if current != nil && currentIndex != index {
+			// 文档从旧日索引移到了当天的索引
+			if _, err := es.deleteByIDs([]string{id}, index); err != nil {
+				log.Printf("清理旧日索引中的资产副本失败: %v", err)
+			}
+		} else {
+			es.dropOlderCopies(index, []string{id})
+		}
+		es.dropPending(id)
+		return nil
+	}
+
+	return fmt.Errorf("更新资产冲突次数过多: %s", id)
+}
+
+// esVersionedDoc 带版本信息的文档
+type esVersionedDoc struct {
+	Index       string                 `json:"_index"`
+	SeqNo       int                    `json:"_seq_no"`
+	PrimaryTerm int                    `json:"_primary_term"`
+	Source      map[string]interface{} `json:"_source"`
+}
+
+// getVersioned 获取文档及其所在索引和版本信息，文档不存在时返回nil
+func (es *ElasticsearchStorage) getVersioned(id string) (map[string]interface{}, string, int, int, error) {
+	if es.daily {
+		doc, err := es.findDocument(id)
+		if err != nil || doc == nil {
+			return nil, "", 0, 0, err
+		}
+		return doc.Source, doc.Index, doc.SeqNo, doc.PrimaryTerm, nil
+	}
+
+	req := esapi.GetRequest{
+		Index:      es.index,
+		DocumentID: id,
+	}
+
+	res, err := req.Do(context.Background(), es.client)
+	if err != nil {
+		return nil, "", 0, 0, fmt.Errorf("获取文档失败: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, "", 0, 0, nil
+	}
+	if res.IsError() {
+		return nil, "", 0, 0, fmt.Errorf("Elasticsearch错误: %s", res.Status())
+	}
+
+	var result esVersionedDoc
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, "", 0, 0, fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	return result.Source, result.Index, result.SeqNo, result.PrimaryTerm, nil
+}
+
+// findDocument 按ID在别名下的所有日索引中查找文档，别名指向多个索引时不能使用GET接口
+// 存在多个副本时返回最新索引中的副本，文档不存在时返回nil
+func (es *ElasticsearchStorage) findDocument(id string) (*esVersionedDoc, error) {
+	query := map[string]interface{}{
+		"query":               map[string]interface{}{"ids": map[string]interface{}{"values": []string{id}}},
+		"sort":                []interface{}{map[string]interface{}{"_index": "desc"}},
+		"size":                1,
+		"seq_no_primary_term": true,
+	}
+
+	queryBytes, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("构建查询失败: %v", err)
+	}
+
+	req := esapi.SearchRequest{
+		Index: []string{es.index},
+		Body:  bytes.NewReader(queryBytes),
+	}
+
+	res, err := req.Do(context.Background(), es.client)
+	if err != nil {
+		return nil, fmt.Errorf("获取文档失败: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("Elasticsearch错误: %s", res.Status())
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []esVersionedDoc `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	if len(result.Hits.Hits) == 0 {
+		return nil, nil
+	}
+	return &result.Hits.Hits[0], nil
+}
+
 // GetAsset 获取资产
 func (es *ElasticsearchStorage) GetAsset(id string) (interface{}, error) {
+	if es.daily {
+		doc, err := es.findDocument(id)
+		if err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			return nil, fmt.Errorf("资产不存在: %s", id)
+		}
+		return doc.Source, nil
+	}
+
 	req := esapi.GetRequest{
 		Index:      es.index,
 		DocumentID: id,
@@ -174,16 +647,98 @@ func (es *ElasticsearchStorage) GetAllAssets() ([]interface{}, error) {
 	return assets, nil
 }
 
+// esScrollPageSize 流式遍历时每页的资产数量
+const esScrollPageSize = 1000
+
+// esScrollKeepAlive 两次翻页之间滚动上下文的保留时间
+const esScrollKeepAlive = time.Minute
+
+// EachAsset 通过scroll分页遍历全部资产，每次只在内存中保留一页，不受GetAllAssets的10000条限制
+func (es *ElasticsearchStorage) EachAsset(fn func(asset interface{}) error) error {
+	query := `{"query":{"match_all":{}},"sort":["_doc"],"size":` + fmt.Sprint(esScrollPageSize) + `}`
+
+	req := esapi.SearchRequest{
+		Index:  []string{es.index},
+		Body:   strings.NewReader(query),
+		Scroll: esScrollKeepAlive,
+	}
+	res, err := req.Do(context.Background(), es.client)
+	if err != nil {
+		return fmt.Errorf("搜索失败: %v", err)
+	}
+
+	var scrollID string
+	defer func() {
+		if scrollID != "" {
+			clear := esapi.ClearScrollRequest{ScrollID: []string{scrollID}}
+			if res, err := clear.Do(context.Background(), es.client); err == nil {
+				res.Body.Close()
+			}
+		}
+	}()
+
+	for {
+		page, err := decodeScrollPage(res)
+		if err != nil {
+			return err
+		}
+		if page.ScrollID != "" {
+			scrollID = page.ScrollID
+		}
+		if len(page.Hits.Hits) == 0 {
+			return nil
+		}
+
+		for _, hit := range page.Hits.Hits {
+			if err := fn(hit.Source); err != nil {
+				return err
+			}
+		}
+
+		body, err := json.Marshal(map[string]string{
+			"scroll":    esScrollKeepAlive.String(),
+			"scroll_id": scrollID,
+		})
+		if err != nil {
+			return fmt.Errorf("构建翻页请求失败: %v", err)
+		}
+		scroll := esapi.ScrollRequest{Body: bytes.NewReader(body)}
+		if res, err = scroll.Do(context.Background(), es.client); err != nil {
+			return fmt.Errorf("翻页失败: %v", err)
+		}
+	}
+}
+
+// esScrollPage scroll查询返回的一页结果，资产保持原始JSON以避免重复解码
+type esScrollPage struct {
+	ScrollID string `json:"_scroll_id"`
+	Hits     struct {
+		Hits []struct {
+			Source json.RawMessage `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// decodeScrollPage 解析并关闭scroll查询的响应
+func decodeScrollPage(res *esapi.Response) (*esScrollPage, error) {
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("Elasticsearch错误: %s", res.Status())
+	}
+
+	var page esScrollPage
+	if err := json.NewDecoder(res.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+	return &page, nil
+}
+
 // SearchAssets 搜索资产
 func (es *ElasticsearchStorage) SearchAssets(query string) ([]interface{}, error) {
 	searchQuery := map[string]interface{}{
-		"query": map[string]interface{}{
-			"multi_match": map[string]interface{}{
-				"query":  query,
-				"fields": []string{"ip_address", "mac_address", "hostname", "device_type", "os_info.family"},
-			},
-		},
-		"size": 1000,
+		"query": esSearchQuery(query),
+		"size":  1000,
 	}
 
 	queryBytes, err := json.Marshal(searchQuery)
@@ -228,8 +783,139 @@ func (es *ElasticsearchStorage) SearchAssets(query string) ([]interface{}, error
 	return assets, nil
 }
 
-// DeleteAsset 删除资产
+// esTextSearchFields 文本搜索的字段，ip_address为ip类型，不参与文本匹配
+var esTextSearchFields = []string{"mac_address", "hostname", "short_name", "domain", "device_type", "os_info.family"}
+
+// esSearchQuery 根据查询内容构建查询条件：
+// 完整的IPv4/IPv6地址精确匹配，CIDR按网段匹配，其他内容在文本字段中搜索
+func esSearchQuery(query string) map[string]interface{} {
+	query = strings.TrimSpace(query)
+
+	// IPv6链路本地地址可能带有接口标识，例如 fe80::1%eth0，索引中不保存该部分
+	address := query
+	if i := strings.Index(address, "%"); i > 0 && !strings.Contains(address, "/") {
+		address = address[:i]
+	}
+
+	if ip := net.ParseIP(address); ip != nil {
+		return map[string]interface{}{
+			"term": map[string]interface{}{"ip_address": ip.String()},
+		}
+	}
+
+	if _, network, err := net.ParseCIDR(query); err == nil {
+		return map[string]interface{}{
+			"term": map[string]interface{}{"ip_address": network.String()},
+		}
+	}
+
+	return map[string]interface{}{
+		"multi_match": map[string]interface{}{
+			"query":   query,
+			"fields":  esTextSearchFields,
+			"lenient": true,
+		},
+	}
+}
+
+// esAggregateFields 聚合字段与索引字段的对应关系
+var esAggregateFields = map[string]string{
+	"device_type": "device_type",
+	"os_family":   "os_info.family",
+	"vendor":      "vendor.keyword",
+}
+
+// AggregateAssets 使用terms聚合统计资产数量
+func (es *ElasticsearchStorage) AggregateAssets(field string, activeOnly bool) (map[string]int, error) {
+	esField, ok := esAggregateFields[field]
+	if !ok {
+		return nil, fmt.Errorf("不支持的聚合字段: %s", field)
+	}
+
+	query := map[string]interface{}{
+		"match_all": map[string]interface{}{},
+	}
+	if activeOnly {
+		query = map[string]interface{}{
+			"term": map[string]interface{}{
+				"is_active": true,
+			},
+		}
+	}
+
+	aggQuery := map[string]interface{}{
+		"size":  0,
+		"query": query,
+		"aggs": map[string]interface{}{
+			"groups": map[string]interface{}{
+				"terms": map[string]interface{}{
+					"field": esField,
+					"size":  1000,
+				},
+			},
+		},
+	}
+
+	queryBytes, err := json.Marshal(aggQuery)
+	if err != nil {
+		return nil, fmt.Errorf("构建查询失败: %v", err)
+	}
+
+	req := esapi.SearchRequest{
+		Index: []string{es.index},
+		Body:  bytes.NewReader(queryBytes),
+	}
+
+	res, err := req.Do(context.Background(), es.client)
+	if err != nil {
+		return nil, fmt.Errorf("聚合查询失败: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("Elasticsearch错误: %s", res.Status())
+	}
+
+	var result struct {
+		Aggregations struct {
+			Groups struct {
+				Buckets []struct {
+					Key      interface{} `json:"key"`
+					DocCount int         `json:"doc_count"`
+				} `json:"buckets"`
+			} `json:"groups"`
+		} `json:"aggregations"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	counts := make(map[string]int, len(result.Aggregations.Groups.Buckets))
+	for _, bucket := range result.Aggregations.Groups.Buckets {
+		counts[fmt.Sprint(bucket.Key)] = bucket.DocCount
+	}
+
+	return counts, nil
+}
+
+// DeleteAsset 删除资产，同时丢弃缓存的待重试文档，避免重试时恢复已删除的资产
 func (es *ElasticsearchStorage) DeleteAsset(id string) error {
+	es.writeMu.RLock()
+	defer es.writeMu.RUnlock()
+
+	es.dropPending(id)
+
+	if es.daily {
+		deleted, err := es.deleteByIDs([]string{id}, "")
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return fmt.Errorf("资产不存在: %s", id)
+		}
+		return nil
+	}
+
 	req := esapi.DeleteRequest{
 		Index:      es.index,
 		DocumentID: id,
@@ -257,17 +943,142 @@ func (es *ElasticsearchStorage) ExportJSON(assets interface{}) ([]byte, error) {
 	return json.MarshalIndent(assets, "", "  ")
 }
 
-// Close 关闭存储
+// Close 关闭存储，退出前最后尝试写入缓存的文档
 func (es *ElasticsearchStorage) Close() error {
+	close(es.stopCh)
+	es.wg.Wait()
+
+	es.flushPending()
+
+	es.pendingMu.Lock()
+	defer es.pendingMu.Unlock()
+	if len(es.pending) > 0 {
+		return fmt.Errorf("仍有 %d 个资产未写入Elasticsearch", len(es.pending))
+	}
+
 	// Elasticsearch客户端不需要显式关闭
 	return nil
 }
 
-// createIndex 创建索引和映射
-func (es *ElasticsearchStorage) createIndex() error {
+// writeIndex 返回当前写入的索引，按天滚动时日期变化后创建新的日索引并加入别名
+func (es *ElasticsearchStorage) writeIndex() (string, error) {
+	if !es.daily {
+		return es.index, nil
+	}
+
+	index := es.index + "-" + time.Now().UTC().Format(esDailyIndexLayout)
+
+	es.dailyMu.Lock()
+	defer es.dailyMu.Unlock()
+
+	if index == es.current {
+		return index, nil
+	}
+	if err := es.createIndex(index); err != nil {
+		return "", err
+	}
+	if es.current != "" {
+		log.Printf("Elasticsearch索引已滚动到 %s", index)
+	}
+	es.current = index
+	es.written = make(map[string]bool)
+
+	return index, nil
+}
+
+// esDailyIndexLayout 日索引名称中的日期格式，按UTC日期滚动，如 assets-2024.06.01
+const esDailyIndexLayout = "2006.01.02"
+
+// dropOlderCopies 按天滚动时删除资产在旧日索引中的副本，使每个资产只保存在最后写入它的日索引中，
+// 别名查询不会返回重复资产，删除过期的日索引即可清除此后再未出现的资产。每个资产每天只需清理一次
+func (es *ElasticsearchStorage) dropOlderCopies(index string, ids []string) {
+	if !es.daily || len(ids) == 0 {
+		return
+	}
+
+	es.dailyMu.Lock()
+	var fresh []string
+	if index == es.current {
+		for _, id := range ids {
+			if !es.written[id] {
+				es.written[id] = true
+				fresh = append(fresh, id)
+			}
+		}
+	}
+	es.dailyMu.Unlock()
+
+	if len(fresh) == 0 {
+		return
+	}
+
+	if _, err := es.deleteByIDs(fresh, index); err != nil {
+		log.Printf("清理旧日索引中的资产副本失败: %v", err)
+
+		// 下次写入时重试
+		es.dailyMu.Lock()
+		if index == es.current {
+			for _, id := range fresh {
+				delete(es.written, id)
+			}
+		}
+		es.dailyMu.Unlock()
+	}
+}
+
+// deleteByIDs 在别名下的所有日索引中删除指定ID的文档，keep不为空时保留该索引中的文档，返回删除的数量
+func (es *ElasticsearchStorage) deleteByIDs(ids []string, keep string) (int, error) {
+	boolQuery := map[string]interface{}{
+		"filter": []interface{}{
+			map[string]interface{}{"ids": map[string]interface{}{"values": ids}},
+		},
+	}
+	if keep != "" {
+		boolQuery["must_not"] = []interface{}{
+			map[string]interface{}{"term": map[string]interface{}{"_index": keep}},
+		}
+	}
+
+	queryBytes, err := json.Marshal(map[string]interface{}{
+		"query": map[string]interface{}{"bool": boolQuery},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("构建查询失败: %v", err)
+	}
+
+	refresh := true
+	req := esapi.DeleteByQueryRequest{
+		Index:     []string{es.index},
+		Body:      bytes.NewReader(queryBytes),
+		Refresh:   &refresh,
+		Conflicts: "proceed",
+	}
+
+	res, err := req.Do(context.Background(), es.client)
+	if err != nil {
+		return 0, fmt.Errorf("删除文档失败: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return 0, fmt.Errorf("Elasticsearch错误: %s", res.Status())
+	}
+
+	var result struct {
+		Deleted int `json:"deleted"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	return result.Deleted, nil
+}
+
+// createIndex 创建索引和映射，按天滚动时新索引同时加入别名
+func (es *ElasticsearchStorage) createIndex(index string) error {
 	// 检查索引是否存在
 	req := esapi.IndicesExistsRequest{
-		Index: []string{es.index},
+		Index: []string{index},
 	}
 
 	res, err := req.Do(context.Background(), es.client)
@@ -285,6 +1096,9 @@ func (es *ElasticsearchStorage) createIndex() error {
 	mapping := map[string]interface{}{
 		"mappings": map[string]interface{}{
 			"properties": map[string]interface{}{
+				"id": map[string]interface{}{
+					"type": "keyword",
+				},
 				"ip_address": map[string]interface{}{
 					"type": "ip",
 				},
@@ -302,6 +1116,12 @@ func (es *ElasticsearchStorage) createIndex() error {
 				"device_type": map[string]interface{}{
 					"type": "keyword",
 				},
+				"scope": map[string]interface{}{
+					"type": "keyword",
+				},
+				"notes": map[string]interface{}{
+					"type": "text",
+				},
 				"os_info": map[string]interface{}{
 					"properties": map[string]interface{}{
 						"family": map[string]interface{}{
@@ -321,10 +1141,24 @@ func (es *ElasticsearchStorage) createIndex() error {
 				"is_active": map[string]interface{}{
 					"type": "boolean",
 				},
+				"is_virtual": map[string]interface{}{
+					"type": "boolean",
+				},
+				"is_randomized_mac": map[string]interface{}{
+					"type": "boolean",
+				},
+				"pseudonymized": map[string]interface{}{
+					"type": "boolean",
+				},
 			},
 		},
 	}
 
+	if es.daily {
+		// 别名与已存在的同名索引冲突时创建会失败，需要先将旧的静态索引重建索引到日索引中
+		mapping["aliases"] = map[string]interface{}{es.index: map[string]interface{}{}}
+	}
+
 	mappingBytes, err := json.Marshal(mapping)
 	if err != nil {
 		return fmt.Errorf("构建映射失败: %v", err)
@@ -332,7 +1166,7 @@ func (es *ElasticsearchStorage) createIndex() error {
 
 	// 创建索引
 	createReq := esapi.IndicesCreateRequest{
-		Index: es.index,
+		Index: index,
 		Body:  strings.NewReader(string(mappingBytes)),
 	}
 
@@ -343,8 +1177,25 @@ func (es *ElasticsearchStorage) createIndex() error {
 	defer createRes.Body.Close()
 
 	if createRes.IsError() {
+		// 多个采集器同时滚动时索引可能已被其他采集器创建
+		if createRes.StatusCode == 400 && es.daily && es.indexExists(index) {
+			return nil
+		}
 		return fmt.Errorf("创建索引错误: %s", createRes.Status())
 	}
 
 	return nil
 }
+
+// indexExists 检查索引是否存在
+func (es *ElasticsearchStorage) indexExists(index string) bool {
+	req := esapi.IndicesExistsRequest{Index: []string{index}}
+
+	res, err := req.Do(context.Background(), es.client)
+	if err != nil {
+		return false
+	}
+	res.Body.Close()
+
+	return res.StatusCode == 200
+}
@@ -0,0 +1,369 @@
+package storage
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"assets_discovery/internal/config"
+)
+
+// flakyES 模拟Elasticsearch，文档写入在前failures次返回status，之后成功，docs记录最后写入的文档内容
+type flakyES struct {
+	mu       sync.Mutex
+	failures int
+	status   int
+	attempts int
+	docs     map[string]string
+}
+
+func (f *flakyES) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+
+	if strings.HasSuffix(r.URL.Path, "/_bulk") {
+		f.bulk(w, r)
+		return
+	}
+	if !strings.Contains(r.URL.Path, "/_doc/") || (r.Method != http.MethodPut && r.Method != http.MethodDelete) {
+		w.Write([]byte(`{}`))
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	if r.Method == http.MethodDelete {
+		if _, ok := f.docs[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"result":"not_found"}`))
+			return
+		}
+		delete(f.docs, id)
+		w.Write([]byte(`{"result":"deleted"}`))
+		return
+	}
+
+	f.attempts++
+	if f.failures > 0 {
+		f.failures--
+		w.WriteHeader(f.status)
+		w.Write([]byte(`{"error":"unavailable"}`))
+		return
+	}
+	f.docs[id] = readBody(r)
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(`{"result":"created"}`))
+}
+
+// bulk 记录批量请求中index操作写入的文档
+func (f *flakyES) bulk(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	lines := strings.Split(strings.TrimSpace(readBody(r)), "\n")
+	for i := 0; i+1 < len(lines); i += 2 {
+		var meta struct {
+			Index struct {
+				ID string `json:"_id"`
+			} `json:"index"`
+		}
+		if json.Unmarshal([]byte(lines[i]), &meta) == nil && meta.Index.ID != "" {
+			f.docs[meta.Index.ID] = lines[i+1]
+		}
+	}
+	w.Write([]byte(`{"errors":false,"items":[]}`))
+}
+
+func readBody(r *http.Request) string {
+	body, _ := io.ReadAll(r.Body)
+	return string(body)
+}
+
+// recover 之后的写入全部成功
+func (f *flakyES) recover() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = 0
+}
+
+func (f *flakyES) counts() (int, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.attempts, len(f.docs)
+}
+
+// doc 返回最后写入的文档内容
+func (f *flakyES) doc(id string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	body, ok := f.docs[id]
+	return body, ok
+}
+
+func newTestES(t *testing.T, server *flakyES, maxRetries int) *ElasticsearchStorage {
+	t.Helper()
+
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	es, err := NewElasticsearchStorage(&config.ESConfig{
+		URLs:         []string{ts.URL},
+		Index:        "assets",
+		MaxRetries:   maxRetries,
+		RetryBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewElasticsearchStorage() error = %v", err)
+	}
+	t.Cleanup(func() { es.Close() })
+	return es
+}
+
+func TestElasticsearchSaveAssetRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		status       int
+		maxRetries   int
+		wantErr      bool
+		wantAttempts int
+	}{
+		{"succeeds first time", 0, 0, 3, false, 1},
+		{"recovers after transient 503", 2, http.StatusServiceUnavailable, 3, false, 3},
+		{"recovers after 429", 1, http.StatusTooManyRequests, 3, false, 2},
+		{"retries exhausted", 5, http.StatusServiceUnavailable, 2, true, 3},
+		{"retry disabled", 1, http.StatusServiceUnavailable, 0, true, 1},
+		{"client error not retried", 1, http.StatusBadRequest, 3, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &flakyES{failures: tt.failures, status: tt.status, docs: make(map[string]string)}
+			es := newTestES(t, server, tt.maxRetries)
+
+			err := es.SaveAsset(map[string]interface{}{"id": "mac_00:11:22:33:44:55"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SaveAsset() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			attempts, _ := server.counts()
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+
+			wantPending := 0
+			if tt.wantErr {
+				wantPending = 1
+			}
+			if got := es.pendingCount(); got != wantPending {
+				t.Errorf("pending = %d, want %d", got, wantPending)
+			}
+		})
+	}
+}
+
+func TestElasticsearchPendingFlush(t *testing.T) {
+	server := &flakyES{failures: 1000, status: http.StatusServiceUnavailable, docs: make(map[string]string)}
+	es := newTestES(t, server, 1)
+
+	ids := []string{"mac_00:11:22:33:44:01", "mac_00:11:22:33:44:02"}
+	for _, id := range ids {
+		if err := es.SaveAsset(map[string]interface{}{"id": id}); err == nil {
+			t.Fatalf("SaveAsset(%s) succeeded during outage", id)
+		}
+	}
+	if got := es.pendingCount(); got != len(ids) {
+		t.Fatalf("pending = %d, want %d", got, len(ids))
+	}
+
+	// 集群仍不可用时保留缓存的文档
+	es.flushPending()
+	if got := es.pendingCount(); got != len(ids) {
+		t.Fatalf("pending after failed flush = %d, want %d", got, len(ids))
+	}
+
+	server.recover()
+	es.flushPending()
+	if got := es.pendingCount(); got != 0 {
+		t.Errorf("pending after recovery = %d, want 0", got)
+	}
+	if _, docs := server.counts(); docs != len(ids) {
+		t.Errorf("stored documents = %d, want %d", docs, len(ids))
+	}
+}
+
+func TestElasticsearchPendingSuperseded(t *testing.T) {
+	const id = "mac_00:11:22:33:44:55"
+	v1 := map[string]interface{}{"id": id, "hostname": "v1"}
+	v2 := map[string]interface{}{"id": id, "hostname": "v2"}
+
+	tests := []struct {
+		name    string
+		then    func(es *ElasticsearchStorage) error
+		wantDoc string
+	}{
+		{"newer save succeeds", func(es *ElasticsearchStorage) error { return es.SaveAsset(v2) }, "v2"},
+		{"newer bulk save succeeds", func(es *ElasticsearchStorage) error {
+			_, err := es.SaveAssets([]interface{}{v2})
+			return err
+		}, "v2"},
+		{"atomic update succeeds", func(es *ElasticsearchStorage) error {
+			return es.UpdateAsset(id, func(map[string]interface{}) (map[string]interface{}, error) { return v2, nil })
+		}, "v2"},
+		{"asset deleted", func(es *ElasticsearchStorage) error {
+			if err := es.SaveAsset(v2); err != nil {
+				return err
+			}
+			return es.DeleteAsset(id)
+		}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &flakyES{failures: 1, status: http.StatusServiceUnavailable, docs: make(map[string]string)}
+			es := newTestES(t, server, 0)
+
+			if err := es.SaveAsset(v1); err == nil {
+				t.Fatalf("SaveAsset(v1) succeeded during outage")
+			}
+			if err := tt.then(es); err != nil {
+				t.Fatalf("write after outage error = %v", err)
+			}
+			if got := es.pendingCount(); got != 0 {
+				t.Errorf("pending = %d, want 0 after newer write", got)
+			}
+
+			// 重试不能用失败时缓存的v1覆盖之后写入的版本或恢复已删除的资产
+			es.flushPending()
+			body, ok := server.doc(id)
+			if tt.wantDoc == "" {
+				if ok {
+					t.Errorf("deleted asset restored by flush: %s", body)
+				}
+				return
+			}
+			var doc map[string]interface{}
+			json.Unmarshal([]byte(body), &doc)
+			if doc["hostname"] != tt.wantDoc {
+				t.Errorf("stored hostname = %v, want %s", doc["hostname"], tt.wantDoc)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		base    time.Duration
+		attempt int
+		max     time.Duration
+	}{
+		{100 * time.Millisecond, 1, 100 * time.Millisecond},
+		{100 * time.Millisecond, 3, 400 * time.Millisecond},
+		{0, 1, 500 * time.Millisecond},
+		{time.Second, 0, time.Second},
+		{time.Second, 10, esMaxBackoff},
+		{time.Second, 100, esMaxBackoff},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			got := retryBackoff(tt.base, tt.attempt)
+			if got < tt.max/2 || got > tt.max {
+				t.Fatalf("retryBackoff(%v, %d) = %v, want within [%v, %v]", tt.base, tt.attempt, got, tt.max/2, tt.max)
+			}
+		}
+	}
+}
+
+// aggregateES 模拟Elasticsearch的terms聚合，记录最近一次_search请求的查询
+type aggregateES struct {
+	mu      sync.Mutex
+	request map[string]interface{}
+	buckets string
+}
+
+func (a *aggregateES) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+
+	if !strings.HasSuffix(r.URL.Path, "/_search") {
+		w.Write([]byte(`{}`))
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.request = nil
+	json.NewDecoder(r.Body).Decode(&a.request)
+	w.Write([]byte(`{"aggregations":{"groups":{"buckets":` + a.buckets + `}}}`))
+}
+
+// lastRequest 返回最近一次聚合请求的查询条件和terms字段
+func (a *aggregateES) lastRequest() (query map[string]interface{}, field string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	query, _ = a.request["query"].(map[string]interface{})
+	aggs, _ := a.request["aggs"].(map[string]interface{})
+	groups, _ := aggs["groups"].(map[string]interface{})
+	terms, _ := groups["terms"].(map[string]interface{})
+	field, _ = terms["field"].(string)
+	return query, field
+}
+
+func TestElasticsearchAggregateAssets(t *testing.T) {
+	server := &aggregateES{buckets: `[{"key":"服务器","doc_count":3},{"key":"Linux","doc_count":2}]`}
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	es, err := NewElasticsearchStorage(&config.ESConfig{URLs: []string{ts.URL}, Index: "assets"})
+	if err != nil {
+		t.Fatalf("NewElasticsearchStorage() error = %v", err)
+	}
+	t.Cleanup(func() { es.Close() })
+
+	tests := []struct {
+		field      string
+		activeOnly bool
+		wantField  string
+	}{
+		{"device_type", false, "device_type"},
+		{"os_family", false, "os_info.family"},
+		{"vendor", true, "vendor.keyword"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			counts, err := es.AggregateAssets(tt.field, tt.activeOnly)
+			if err != nil {
+				t.Fatalf("AggregateAssets() error = %v", err)
+			}
+			want := map[string]int{"服务器": 3, "Linux": 2}
+			if !reflect.DeepEqual(counts, want) {
+				t.Errorf("AggregateAssets() = %v, want %v", counts, want)
+			}
+
+			query, field := server.lastRequest()
+			if field != tt.wantField {
+				t.Errorf("terms field = %q, want %q", field, tt.wantField)
+			}
+			_, activeFilter := query["term"]
+			if activeFilter != tt.activeOnly {
+				t.Errorf("query = %v, active filter = %v, want %v", query, activeFilter, tt.activeOnly)
+			}
+		})
+	}
+
+	// 网段无法在存储端聚合，由资产管理器在内存中计算
+	if _, err := es.AggregateAssets("subnet", false); err == nil {
+		t.Errorf("AggregateAssets(subnet) error = nil, want unsupported field")
+	}
+}
@@ -1,21 +1,48 @@
 package storage
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"assets_discovery/internal/config"
 )
 
+const (
+	// ndjsonCompactRatio 文件行数超过资产数的倍数时进行压缩
+	ndjsonCompactRatio = 2
+	// ndjsonMinCompactLines 触发压缩的最小行数，避免资产较少时频繁重写
+	ndjsonMinCompactLines = 1000
+	// ndjsonMaxLineSize 单行最大长度
+	ndjsonMaxLineSize = 16 * 1024 * 1024
+	// ndjsonDeletedKey 删除标记行中的字段，加载时移除此前同ID的资产
+	ndjsonDeletedKey = "_deleted"
+
+	// fileDateLayout 按天滚动时文件名中的日期格式，按UTC日期滚动，如 assets-2024-06-01.json
+	fileDateLayout = "2006-01-02"
+)
+
 // FileStorage 文件存储实现
 type FileStorage struct {
 	config   *config.FileConfig
 	data     map[string]interface{}
 	mutex    sync.RWMutex
 	filePath string
+
+	// ndjson格式下的追加写句柄和当前行数
+	appendFile *os.File
+	lines      int
+
+	// 按天滚动时当前文件对应的UTC日期
+	daily bool
+	day   string
 }
 
 // NewFileStorage 创建文件存储
@@ -25,12 +52,33 @@ func NewFileStorage(cfg *config.FileConfig) (*FileStorage, error) {
 		return nil, fmt.Errorf("创建输出目录失败: %v", err)
 	}
 
-	filePath := filepath.Join(cfg.OutputDir, "assets.json")
-
 	fs := &FileStorage{
-		config:   cfg,
-		data:     make(map[string]interface{}),
-		filePath: filePath,
+		config: cfg,
+		data:   make(map[string]interface{}),
+	}
+
+	switch cfg.Rotation {
+	case "", "none":
+		fs.filePath = filepath.Join(cfg.OutputDir, "assets"+fs.fileExt())
+	case "daily":
+		fs.daily = true
+		fs.day = time.Now().UTC().Format(fileDateLayout)
+		fs.filePath = fs.dailyPath(fs.day)
+
+		// 当天的文件不存在时从最近一天的文件加载并写入当天的文件，资产清单跨天延续
+		if _, err := os.Stat(fs.filePath); os.IsNotExist(err) {
+			if latest := fs.latestDailyFile(); latest != "" {
+				fs.filePath = latest
+				fs.loadFromFile()
+				fs.filePath = fs.dailyPath(fs.day)
+				if err := fs.saveToFile(); err != nil {
+					return nil, err
+				}
+				return fs, nil
+			}
+		}
+	default:
+		return nil, fmt.Errorf("不支持的文件滚动策略: %s", cfg.Rotation)
 	}
 
 	// 加载现有数据
@@ -39,6 +87,63 @@ func NewFileStorage(cfg *config.FileConfig) (*FileStorage, error) {
 	return fs, nil
 }
 
+// fileExt 资产文件的扩展名
+func (fs *FileStorage) fileExt() string {
+	if fs.isNDJSON() {
+		return ".ndjson"
+	}
+	return ".json"
+}
+
+// dailyPath 按天滚动时指定日期的文件路径
+func (fs *FileStorage) dailyPath(day string) string {
+	return filepath.Join(fs.config.OutputDir, "assets-"+day+fs.fileExt())
+}
+
+// latestDailyFile 输出目录中日期最新的资产文件，不存在时返回空字符串
+func (fs *FileStorage) latestDailyFile() string {
+	matches, _ := filepath.Glob(filepath.Join(fs.config.OutputDir, "assets-*"+fs.fileExt()))
+
+	var files []string
+	for _, path := range matches {
+		day := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "assets-"), fs.fileExt())
+		if _, err := time.Parse(fileDateLayout, day); err == nil {
+			files = append(files, path)
+		}
+	}
+	if len(files) == 0 {
+		return ""
+	}
+
+	sort.Strings(files)
+	return files[len(files)-1]
+}
+
+// rotate 按天滚动时，日期变化后切换到新日期的文件并写入完整的资产清单，调用方需持有fs.mutex写锁
+// 所有写入都在锁内先滚动再写入，前一天的文件保留滚动前最后一次写入的内容，不会丢失写入
+func (fs *FileStorage) rotate(now time.Time) error {
+	if !fs.daily {
+		return nil
+	}
+
+	day := now.UTC().Format(fileDateLayout)
+	if day == fs.day {
+		return nil
+	}
+
+	// 关闭前一天文件的追加句柄，下次写入时打开新文件
+	if fs.appendFile != nil {
+		fs.appendFile.Close()
+		fs.appendFile = nil
+	}
+
+	fs.day = day
+	fs.filePath = fs.dailyPath(day)
+	log.Printf("资产文件已滚动到 %s", fs.filePath)
+
+	return fs.saveToFile()
+}
+
 // SaveAsset 保存资产
 func (fs *FileStorage) SaveAsset(asset interface{}) error {
 	fs.mutex.Lock()
@@ -76,11 +181,42 @@ func (fs *FileStorage) SaveAsset(asset interface{}) error {
 		return fmt.Errorf("无法提取资产ID")
 	}
 
+	if err := fs.rotate(time.Now()); err != nil {
+		return err
+	}
 	fs.data[assetID] = assetData
+
+	// ndjson格式只追加一行，旧版本在压缩时清理
+	if fs.isNDJSON() {
+		return fs.appendLine(assetData)
+	}
+
 	// 立即写入文件
 	return fs.saveToFile()
 }
 
+// UpdateAsset 在锁内完成读-改-写并写入文件
+func (fs *FileStorage) UpdateAsset(id string, mutate AssetMutator) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	updated, err := mutate(toAssetMap(fs.data[id]))
+	if err != nil || updated == nil {
+		return err
+	}
+
+	if err := fs.rotate(time.Now()); err != nil {
+		return err
+	}
+	updated["id"] = id
+	fs.data[id] = updated
+
+	if fs.isNDJSON() {
+		return fs.appendLine(updated)
+	}
+	return fs.saveToFile()
+}
+
 // GetAsset 获取资产
 func (fs *FileStorage) GetAsset(id string) (interface{}, error) {
 	fs.mutex.RLock()
@@ -112,13 +248,11 @@ func (fs *FileStorage) SearchAssets(query string) ([]interface{}, error) {
 	defer fs.mutex.RUnlock()
 
 	var results []interface{}
+	query = strings.ToLower(query)
 
 	for _, asset := range fs.data {
-		// 简单的字符串匹配搜索
-		if assetBytes, err := json.Marshal(asset); err == nil {
-			if contains(string(assetBytes), query) {
-				results = append(results, asset)
-			}
+		if matchAsset(asset, query) {
+			results = append(results, asset)
 		}
 	}
 
@@ -132,6 +266,13 @@ func (fs *FileStorage) DeleteAsset(id string) error {
 
 	if _, exists := fs.data[id]; exists {
 		delete(fs.data, id)
+		if err := fs.rotate(time.Now()); err != nil {
+			return err
+		}
+		// ndjson格式追加删除标记，不重写整个文件
+		if fs.isNDJSON() {
+			return fs.appendLine(map[string]interface{}{"id": id, ndjsonDeletedKey: true})
+		}
 		return fs.saveToFile()
 	}
 
@@ -148,8 +289,18 @@ func (fs *FileStorage) Close() error {
 	fs.mutex.Lock()
 	defer fs.mutex.Unlock()
 
-	// 最终保存
-	return fs.saveToFile()
+	// 最终保存，跨过午夜时写入新日期的文件
+	err := fs.rotate(time.Now())
+	if err == nil {
+		err = fs.saveToFile()
+	}
+
+	if fs.appendFile != nil {
+		fs.appendFile.Close()
+		fs.appendFile = nil
+	}
+
+	return err
 }
 
 // loadFromFile 从文件加载数据
@@ -159,6 +310,10 @@ func (fs *FileStorage) loadFromFile() error {
 		return nil
 	}
 
+	if fs.isNDJSON() {
+		return fs.loadNDJSON()
+	}
+
 	data, err := os.ReadFile(fs.filePath)
 	if err != nil {
 		return fmt.Errorf("读取文件失败: %v", err)
@@ -173,6 +328,10 @@ func (fs *FileStorage) loadFromFile() error {
 
 // saveToFile 保存数据到文件
 func (fs *FileStorage) saveToFile() error {
+	if fs.isNDJSON() {
+		return fs.compact()
+	}
+
 	data, err := json.MarshalIndent(fs.data, "", "  ")
 	if err != nil {
 		return fmt.Errorf("序列化数据失败: %v", err)
@@ -180,3 +339,113 @@ func (fs *FileStorage) saveToFile() error {
 
 	return os.WriteFile(fs.filePath, data, 0644)
 }
+
+// isNDJSON 是否使用JSON Lines格式
+func (fs *FileStorage) isNDJSON() bool {
+	return fs.config.Format == "ndjson"
+}
+
+// loadNDJSON 逐行读取JSON Lines文件，同一资产以最后一行为准，删除标记之前的记录被丢弃
+func (fs *FileStorage) loadNDJSON() error {
+	file, err := os.Open(fs.filePath)
+	if err != nil {
+		return fmt.Errorf("读取文件失败: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), ndjsonMaxLineSize)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		fs.lines++
+
+		var assetMap map[string]interface{}
+		if err := json.Unmarshal(line, &assetMap); err != nil {
+			log.Printf("跳过无效的资产记录(第 %d 行): %v", fs.lines, err)
+			continue
+		}
+		id, ok := assetMap["id"].(string)
+		if !ok || id == "" {
+			continue
+		}
+		if deleted, _ := assetMap[ndjsonDeletedKey].(bool); deleted {
+			delete(fs.data, id)
+			continue
+		}
+		fs.data[id] = assetMap
+	}
+
+	return scanner.Err()
+}
+
+// appendLine 向JSON Lines文件追加一条资产记录
+func (fs *FileStorage) appendLine(asset interface{}) error {
+	line, err := json.Marshal(asset)
+	if err != nil {
+		return fmt.Errorf("序列化数据失败: %v", err)
+	}
+
+	if fs.appendFile == nil {
+		fs.appendFile, err = os.OpenFile(fs.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("打开文件失败: %v", err)
+		}
+	}
+
+	if _, err := fs.appendFile.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	fs.lines++
+
+	if fs.lines > ndjsonMinCompactLines && fs.lines > ndjsonCompactRatio*len(fs.data) {
+		return fs.compact()
+	}
+
+	return nil
+}
+
+// compact 重写JSON Lines文件，每个资产只保留最新的一行，删除标记不再保留
+func (fs *FileStorage) compact() error {
+	tmpPath := fs.filePath + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %v", err)
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, asset := range fs.data {
+		if err := encoder.Encode(asset); err != nil {
+			file.Close()
+			os.Remove(tmpPath)
+			return fmt.Errorf("序列化数据失败: %v", err)
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+
+	// 替换前关闭追加句柄，下次写入时重新打开
+	if fs.appendFile != nil {
+		fs.appendFile.Close()
+		fs.appendFile = nil
+	}
+
+	if err := os.Rename(tmpPath, fs.filePath); err != nil {
+		return fmt.Errorf("替换文件失败: %v", err)
+	}
+	fs.lines = len(fs.data)
+
+	return nil
+}
@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"assets_discovery/internal/config"
+)
+
+// fileIDs 读取资产文件中的资产ID
+func fileIDs(t *testing.T, cfg *config.FileConfig, path string) []string {
+	t.Helper()
+
+	fs := &FileStorage{config: cfg, data: make(map[string]interface{}), filePath: path}
+	if err := fs.loadFromFile(); err != nil {
+		t.Fatalf("loadFromFile(%s) error = %v", path, err)
+	}
+	ids := make([]string, 0, len(fs.data))
+	for id := range fs.data {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// dirFiles 返回目录中的文件名
+func dirFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+// saveAt 以now为当前时间保存资产，与SaveAsset相同先滚动再写入
+func saveAt(t *testing.T, fs *FileStorage, now time.Time, asset map[string]interface{}) {
+	t.Helper()
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	if err := fs.rotate(now); err != nil {
+		t.Fatalf("rotate() error = %v", err)
+	}
+	fs.data[asset["id"].(string)] = asset
+	var err error
+	if fs.isNDJSON() {
+		err = fs.appendLine(asset)
+	} else {
+		err = fs.saveToFile()
+	}
+	if err != nil {
+		t.Fatalf("save error = %v", err)
+	}
+}
+
+func TestFileRotationNone(t *testing.T) {
+	for _, rotation := range []string{"", "none"} {
+		t.Run("rotation="+rotation, func(t *testing.T) {
+			cfg := &config.FileConfig{OutputDir: t.TempDir(), Format: "json", Rotation: rotation}
+			fs, err := NewFileStorage(cfg)
+			if err != nil {
+				t.Fatalf("NewFileStorage() error = %v", err)
+			}
+			if err := fs.SaveAsset(map[string]interface{}{"id": "a1"}); err != nil {
+				t.Fatalf("SaveAsset() error = %v", err)
+			}
+			fs.Close()
+
+			if got := dirFiles(t, cfg.OutputDir); !reflect.DeepEqual(got, []string{"assets.json"}) {
+				t.Errorf("files = %v, want [assets.json]", got)
+			}
+		})
+	}
+
+	if _, err := NewFileStorage(&config.FileConfig{OutputDir: t.TempDir(), Rotation: "hourly"}); err == nil {
+		t.Error("NewFileStorage(hourly) error = nil, want unsupported rotation")
+	}
+}
+
+func TestFileRotationDaily(t *testing.T) {
+	today := time.Now().UTC().Format(fileDateLayout)
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format(fileDateLayout)
+
+	for _, format := range []string{"json", "ndjson"} {
+		t.Run(format, func(t *testing.T) {
+			cfg := &config.FileConfig{OutputDir: t.TempDir(), Format: format, Rotation: "daily"}
+			fs, err := NewFileStorage(cfg)
+			if err != nil {
+				t.Fatalf("NewFileStorage() error = %v", err)
+			}
+
+			// 前一天写入的资产，ndjson格式的追加句柄仍指向前一天的文件
+			saveAt(t, fs, time.Now().AddDate(0, 0, -1), map[string]interface{}{"id": "a1"})
+
+			// 跨过午夜后并发的写入都写入新日期的文件，新文件包含完整的资产清单
+			var wg sync.WaitGroup
+			for i := 2; i <= 9; i++ {
+				wg.Add(1)
+				go func(id string) {
+					defer wg.Done()
+					if err := fs.SaveAsset(map[string]interface{}{"id": id}); err != nil {
+						t.Errorf("SaveAsset(%s) error = %v", id, err)
+					}
+				}(fmt.Sprintf("a%d", i))
+			}
+			wg.Wait()
+			if err := fs.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			ext := "." + format
+			old := filepath.Join(cfg.OutputDir, "assets-"+yesterday+ext)
+			current := filepath.Join(cfg.OutputDir, "assets-"+today+ext)
+			if got := fileIDs(t, cfg, old); !reflect.DeepEqual(got, []string{"a1"}) {
+				t.Errorf("%s assets = %v, want [a1]", filepath.Base(old), got)
+			}
+			want := []string{"a1", "a2", "a3", "a4", "a5", "a6", "a7", "a8", "a9"}
+			if got := fileIDs(t, cfg, current); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s assets = %v, want %v", filepath.Base(current), got, want)
+			}
+		})
+	}
+}
+
+// fileLines 读取文件中的非空行
+func fileLines(t *testing.T, path string) []string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(%s) error = %v", path, err)
+	}
+	return strings.Fields(string(data))
+}
+
+func TestNDJSONDeleteTombstone(t *testing.T) {
+	cfg := &config.FileConfig{OutputDir: t.TempDir(), Format: "ndjson"}
+	fs, err := NewFileStorage(cfg)
+	if err != nil {
+		t.Fatalf("NewFileStorage() error = %v", err)
+	}
+	for _, id := range []string{"a1", "a2"} {
+		if err := fs.SaveAsset(map[string]interface{}{"id": id}); err != nil {
+			t.Fatalf("SaveAsset(%s) error = %v", id, err)
+		}
+	}
+
+	// 删除只追加一行删除标记，不重写文件
+	if err := fs.DeleteAsset("a1"); err != nil {
+		t.Fatalf("DeleteAsset() error = %v", err)
+	}
+	lines := fileLines(t, fs.filePath)
+	if want := []string{`{"id":"a1"}`, `{"id":"a2"}`, `{"_deleted":true,"id":"a1"}`}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("file lines after delete = %v, want %v", lines, want)
+	}
+	if got := fileIDs(t, cfg, fs.filePath); !reflect.DeepEqual(got, []string{"a2"}) {
+		t.Errorf("reloaded assets = %v, want [a2]", got)
+	}
+
+	// 关闭时压缩，删除标记和被删除的资产不再保留
+	if err := fs.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if lines := fileLines(t, fs.filePath); !reflect.DeepEqual(lines, []string{`{"id":"a2"}`}) {
+		t.Errorf("file lines after close = %v, want [{\"id\":\"a2\"}]", lines)
+	}
+}
+
+func TestFileRotationResume(t *testing.T) {
+	today := time.Now().UTC().Format(fileDateLayout)
+	cfg := &config.FileConfig{OutputDir: t.TempDir(), Format: "json", Rotation: "daily"}
+
+	// 输出目录中只有以前日期的文件时，启动后从最新的一天加载并写入当天的文件
+	older := &FileStorage{config: cfg, data: map[string]interface{}{"a1": map[string]interface{}{"id": "a1"}}}
+	older.filePath = older.dailyPath("2024-05-30")
+	if err := older.saveToFile(); err != nil {
+		t.Fatalf("saveToFile() error = %v", err)
+	}
+	latest := &FileStorage{config: cfg, data: map[string]interface{}{
+		"a1": map[string]interface{}{"id": "a1"},
+		"a2": map[string]interface{}{"id": "a2"},
+	}}
+	latest.filePath = latest.dailyPath("2024-05-31")
+	if err := latest.saveToFile(); err != nil {
+		t.Fatalf("saveToFile() error = %v", err)
+	}
+	// 文件名不是日期的文件被忽略
+	if err := os.WriteFile(filepath.Join(cfg.OutputDir, "assets-backup.json"), []byte(`{"x":{"id":"x"}}`), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	fs, err := NewFileStorage(cfg)
+	if err != nil {
+		t.Fatalf("NewFileStorage() error = %v", err)
+	}
+	defer fs.Close()
+
+	all, _ := fs.GetAllAssets()
+	if got := resultIDs(t, all); !reflect.DeepEqual(got, []string{"a1", "a2"}) {
+		t.Errorf("loaded assets = %v, want [a1 a2]", got)
+	}
+	if got := fileIDs(t, cfg, filepath.Join(cfg.OutputDir, "assets-"+today+".json")); !reflect.DeepEqual(got, []string{"a1", "a2"}) {
+		t.Errorf("today's file assets = %v, want [a1 a2]", got)
+	}
+}
@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"assets_discovery/internal/config"
+)
+
+// flatListSeparator 展开后数组元素之间的分隔符
+const flatListSeparator = ";"
+
+// defaultFlatFields csv格式未配置fields时输出的列
+var defaultFlatFields = []string{
+	"id", "ip_address", "mac_address", "hostname", "vendor", "device_type",
+	"os_family", "os_version", "open_ports", "services", "interfaces",
+	"first_seen", "last_seen", "is_active", "confidence", "risk_score", "notes",
+}
+
+// Flattener 将嵌套的资产结构展开为扁平字段，供不便处理嵌套JSON的下游工具使用
+//
+// 默认展开规则：
+//   - os_info.family等操作系统字段展开为os_family、os_version、os_kernel、os_confidence
+//   - open_ports展开为"22/tcp;80/tcp"，并为每个端口生成port_22_tcp列，值为服务名称和版本
+//   - services展开为"22/tcp;http"这样的服务名称列表（资产作为客户端使用的服务展开为outbound_services），protocols展开为协议名称列表，changes展开为变更记录数量
+//   - 其他嵌套对象以下划线连接字段名，标量数组以分号连接
+type Flattener struct {
+	fields []string
+	names  map[string]string
+}
+
+// NewFlattener 按导出配置创建展开器
+func NewFlattener(cfg *config.ExportConfig) *Flattener {
+	return &Flattener{fields: cfg.Fields, names: cfg.FieldNames}
+}
+
+// Flatten 展开单个资产，配置了fields时只保留其中的字段，字段名按field_names重命名
+func (f *Flattener) Flatten(asset interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(asset)
+	if err != nil {
+		return nil, fmt.Errorf("序列化资产失败: %v", err)
+	}
+
+	var nested map[string]interface{}
+	if err := json.Unmarshal(data, &nested); err != nil {
+		return nil, fmt.Errorf("解析资产失败: %v", err)
+	}
+
+	flat := make(map[string]interface{}, len(nested))
+	for key, value := range nested {
+		flattenField(flat, key, value)
+	}
+
+	if len(f.fields) > 0 {
+		selected := make(map[string]interface{}, len(f.fields))
+		for _, field := range f.fields {
+			if value, ok := flat[field]; ok {
+				selected[field] = value
+			}
+		}
+		flat = selected
+	}
+
+	if len(f.names) == 0 {
+		return flat, nil
+	}
+	renamed := make(map[string]interface{}, len(flat))
+	for key, value := range flat {
+		renamed[f.name(key)] = value
+	}
+	return renamed, nil
+}
+
+// columns csv输出的列，未配置fields时使用默认列
+func (f *Flattener) columns() []string {
+	if len(f.fields) > 0 {
+		return f.fields
+	}
+	return defaultFlatFields
+}
+
+// name 返回字段输出时使用的名称
+func (f *Flattener) name(field string) string {
+	if name, ok := f.names[field]; ok && name != "" {
+		return name
+	}
+	return field
+}
+
+// flattenField 按默认规则将一个顶层字段展开到flat中
+func flattenField(flat map[string]interface{}, key string, value interface{}) {
+	switch key {
+	case "os_info":
+		if osInfo, ok := value.(map[string]interface{}); ok {
+			for k, v := range osInfo {
+				flattenValue(flat, "os_"+k, v)
+			}
+			return
+		}
+	case "open_ports":
+		if ports, ok := value.([]interface{}); ok {
+			flattenPorts(flat, ports)
+			return
+		}
+	case "services":
+		if services, ok := value.([]interface{}); ok {
+			flattenServices(flat, services)
+			return
+		}
+	case "protocols":
+		if protocols, ok := value.(map[string]interface{}); ok {
+			names := make([]string, 0, len(protocols))
+			for name := range protocols {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			flat[key] = strings.Join(names, flatListSeparator)
+			return
+		}
+	case "changes":
+		if changes, ok := value.([]interface{}); ok {
+			flat[key] = len(changes)
+			return
+		}
+	}
+
+	flattenValue(flat, key, value)
+}
+
+// flattenValue 嵌套对象以下划线连接字段名，标量数组以分号连接，对象数组保留为JSON字符串
+func flattenValue(flat map[string]interface{}, key string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			flattenValue(flat, key+"_"+k, child)
+		}
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				data, _ := json.Marshal(v)
+				flat[key] = string(data)
+				return
+			}
+			items = append(items, fmt.Sprint(item))
+		}
+		flat[key] = strings.Join(items, flatListSeparator)
+	default:
+		flat[key] = v
+	}
+}
+
+// flattenPorts 展开开放端口列表（跳过已关闭的端口），并为每个端口生成port_<端口>_<协议>列
+func flattenPorts(flat map[string]interface{}, ports []interface{}) {
+	list := make([]string, 0, len(ports))
+	for _, item := range ports {
+		port, ok := item.(map[string]interface{})
+		if !ok || port["state"] == "closed" {
+			continue
+		}
+
+		id := fmt.Sprintf("%v/%v", port["port"], port["protocol"])
+		list = append(list, id)
+
+		column := "port_" + strings.ReplaceAll(id, "/", "_")
+		if _, exists := flat[column]; !exists {
+			flat[column] = portDescription(port["service"], port["version"])
+		}
+	}
+	flat["open_ports"] = strings.Join(list, flatListSeparator)
+}
+
+// flattenServices 展开服务列表，服务的版本补充到对应端口列中
+// 解析器记录的服务以"22/tcp"这样的端口为名称，此时端口和协议取自名称
+func flattenServices(flat map[string]interface{}, services []interface{}) {
+	list := make([]string, 0, len(services))
+	var outbound []string
+	for _, item := range services {
+		service, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		// 资产作为客户端使用的服务不是其开放的端口，单独列出
+		if service["direction"] == "outbound" {
+			if name, _ := service["name"].(string); name != "" {
+				outbound = append(outbound, name)
+			}
+			continue
+		}
+
+		name, _ := service["name"].(string)
+		proto, _ := service["protocol"].(string)
+		port := ""
+		if p, _ := service["port"].(float64); p > 0 {
+			port = fmt.Sprint(p)
+		}
+
+		if p, pr, found := strings.Cut(name, "/"); found && port == "" {
+			port, proto = p, pr
+			list = append(list, name)
+			name = ""
+		} else if port != "" {
+			list = append(list, name+"/"+port)
+		} else {
+			list = append(list, name)
+		}
+
+		if port == "" || proto == "" {
+			continue
+		}
+		column := "port_" + port + "_" + proto
+		if desc := portDescription(name, service["version"]); desc != "open" {
+			if current, _ := flat[column].(string); current == "" || current == "open" {
+				flat[column] = desc
+			}
+		}
+	}
+	flat["services"] = strings.Join(list, flatListSeparator)
+	if len(outbound) > 0 {
+		flat["outbound_services"] = strings.Join(outbound, flatListSeparator)
+	}
+}
+
+// portDescription 端口列的值：服务名称和版本，都没有时为open
+func portDescription(name, version interface{}) string {
+	parts := make([]string, 0, 2)
+	for _, v := range []interface{}{name, version} {
+		if s, _ := v.(string); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	if len(parts) == 0 {
+		return "open"
+	}
+	return strings.Join(parts, " ")
+}
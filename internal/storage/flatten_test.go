@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"assets_discovery/internal/config"
+)
+
+// nestedAsset 构造含各类嵌套字段的资产
+func nestedAsset() map[string]interface{} {
+	return map[string]interface{}{
+		"id":         "mac_00:1a:2b:3c:4d:5e",
+		"ip_address": "10.0.0.5",
+		"os_info": map[string]interface{}{
+			"family":     "Linux",
+			"version":    "5.15",
+			"confidence": 0.8,
+			"detection":  []interface{}{"ttl_analysis", "user_agent"},
+		},
+		"open_ports": []interface{}{
+			map[string]interface{}{"port": 22, "protocol": "tcp", "state": "open"},
+			map[string]interface{}{"port": 443, "protocol": "tcp", "state": "open"},
+			map[string]interface{}{"port": 23, "protocol": "tcp", "state": "closed"},
+		},
+		"services": []interface{}{
+			map[string]interface{}{"name": "ssh", "port": 22, "protocol": "tcp", "version": "OpenSSH_8.9"},
+			map[string]interface{}{"name": "dns", "direction": "outbound"},
+		},
+		"protocols": map[string]interface{}{
+			"http": map[string]interface{}{"server": "nginx"},
+			"arp":  map[string]interface{}{"src_ip": "10.0.0.5"},
+		},
+		"changes":   []interface{}{map[string]interface{}{}, map[string]interface{}{}},
+		"location":  map[string]interface{}{"country": "CN", "city": "Beijing"},
+		"tags":      []interface{}{"prod", "web"},
+		"is_active": true,
+	}
+}
+
+func TestFlattenDefault(t *testing.T) {
+	flat, err := NewFlattener(&config.ExportConfig{}).Flatten(nestedAsset())
+	if err != nil {
+		t.Fatalf("Flatten() error = %v", err)
+	}
+
+	want := map[string]interface{}{
+		"id":                "mac_00:1a:2b:3c:4d:5e",
+		"ip_address":        "10.0.0.5",
+		"os_family":         "Linux",
+		"os_version":        "5.15",
+		"os_confidence":     0.8,
+		"os_detection":      "ttl_analysis;user_agent",
+		"open_ports":        "22/tcp;443/tcp",
+		"port_22_tcp":       "ssh OpenSSH_8.9",
+		"port_443_tcp":      "open",
+		"services":          "ssh/22",
+		"outbound_services": "dns",
+		"protocols":         "arp;http",
+		"changes":           2,
+		"location_country":  "CN",
+		"location_city":     "Beijing",
+		"tags":              "prod;web",
+		"is_active":         true,
+	}
+	if !reflect.DeepEqual(flat, want) {
+		t.Errorf("Flatten() = %v, want %v", flat, want)
+	}
+}
+
+func TestFlattenFieldsAndNames(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.ExportConfig
+		want map[string]interface{}
+	}{
+		{
+			name: "selected fields",
+			cfg:  config.ExportConfig{Fields: []string{"id", "os_family", "port_22_tcp", "missing"}},
+			want: map[string]interface{}{"id": "mac_00:1a:2b:3c:4d:5e", "os_family": "Linux", "port_22_tcp": "ssh OpenSSH_8.9"},
+		},
+		{
+			name: "renamed fields",
+			cfg: config.ExportConfig{
+				Fields:     []string{"id", "os_family", "open_ports"},
+				FieldNames: map[string]string{"os_family": "os", "open_ports": "ports", "id": ""},
+			},
+			want: map[string]interface{}{"id": "mac_00:1a:2b:3c:4d:5e", "os": "Linux", "ports": "22/tcp;443/tcp"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flat, err := NewFlattener(&tt.cfg).Flatten(nestedAsset())
+			if err != nil {
+				t.Fatalf("Flatten() error = %v", err)
+			}
+			if !reflect.DeepEqual(flat, tt.want) {
+				t.Errorf("Flatten() = %v, want %v", flat, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssetWriterFlatten(t *testing.T) {
+	cfg := &config.ExportConfig{
+		Fields:     []string{"id", "os_family", "open_ports"},
+		FieldNames: map[string]string{"os_family": "OS"},
+	}
+
+	write := func(format string, flatten *Flattener) string {
+		t.Helper()
+		var buf bytes.Buffer
+		aw, err := NewAssetWriter(&buf, format, flatten)
+		if err != nil {
+			t.Fatalf("NewAssetWriter(%s) error = %v", format, err)
+		}
+		if err := aw.Write(nestedAsset()); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if err := aw.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		return buf.String()
+	}
+
+	// csv的表头使用重命名后的字段名
+	if got, want := write("csv", NewFlattener(cfg)), "id,OS,open_ports\nmac_00:1a:2b:3c:4d:5e,Linux,22/tcp;443/tcp\n"; got != want {
+		t.Errorf("csv = %q, want %q", got, want)
+	}
+
+	// 未展开的json保留嵌套结构，展开后只有扁平字段
+	var nested, flat []map[string]interface{}
+	if err := json.Unmarshal([]byte(write("json", nil)), &nested); err != nil || len(nested) != 1 {
+		t.Fatalf("nested json = %v, %v", nested, err)
+	}
+	if osInfo, _ := nested[0]["os_info"].(map[string]interface{}); osInfo["family"] != "Linux" || nested[0]["os_family"] != nil {
+		t.Errorf("nested json os = %v, %v, want os_info.family", nested[0]["os_info"], nested[0]["os_family"])
+	}
+
+	if err := json.Unmarshal([]byte(write("json", NewFlattener(cfg))), &flat); err != nil || len(flat) != 1 {
+		t.Fatalf("flat json = %v, %v", flat, err)
+	}
+	want := map[string]interface{}{"id": "mac_00:1a:2b:3c:4d:5e", "OS": "Linux", "open_ports": "22/tcp;443/tcp"}
+	if !reflect.DeepEqual(flat[0], want) {
+		t.Errorf("flat json = %v, want %v", flat[0], want)
+	}
+}
@@ -1,5 +1,13 @@
 package storage
 
+import (
+	"encoding/json"
+	"log"
+	"strings"
+
+	"assets_discovery/internal/config"
+)
+
 // Storage 存储接口
 type Storage interface {
 	// 保存资产
@@ -23,3 +31,127 @@ type Storage interface {
 	// 关闭存储
 	Close() error
 }
+
+// BulkStorage 支持批量写入的存储
+type BulkStorage interface {
+	// 批量保存资产，返回成功保存的数量
+	SaveAssets(assets []interface{}) (int, error)
+}
+
+// AggregateStorage 支持在存储端聚合统计的存储
+type AggregateStorage interface {
+	// 按字段统计资产数量，不支持该字段时返回错误
+	AggregateAssets(field string, activeOnly bool) (map[string]int, error)
+}
+
+// AssetMutator 根据存储中的当前资产计算要保存的资产，current为nil表示资产尚不存在；
+// 返回nil表示不修改存储中的资产
+type AssetMutator func(current map[string]interface{}) (map[string]interface{}, error)
+
+// AtomicStorage 支持原子读-改-写的存储，多个写入方同时更新同一资产时不会丢失更新
+// memory/file存储在进程内加锁实现，只保证单个采集进程内的原子性；
+// elasticsearch基于_seq_no/_primary_term乐观并发控制，对多个采集器同样有效
+type AtomicStorage interface {
+	// 原子地更新资产，mutate可能因冲突重试而被多次调用
+	UpdateAsset(id string, mutate AssetMutator) error
+}
+
+// NewStorage 根据配置创建存储，启用Kafka时在主存储外包装Kafka输出，
+// 启用假名化时在最外层包装，写入主存储和Kafka的都是假名化后的资产
+func NewStorage(cfg *config.StorageConfig) (Storage, error) {
+	s, err := newOutputStorage(cfg)
+	if err != nil || !cfg.Pseudonymize.Enabled {
+		return s, err
+	}
+
+	ps, err := NewPseudonymStorage(s, &cfg.Pseudonymize)
+	if err != nil {
+		// 假名化是合规要求，不能退回保存原始数据
+		s.Close()
+		return nil, err
+	}
+	return ps, nil
+}
+
+// newOutputStorage 创建主存储，启用Kafka时在主存储外包装Kafka输出
+func newOutputStorage(cfg *config.StorageConfig) (Storage, error) {
+	primary, err := newPrimaryStorage(cfg)
+	if err != nil || !cfg.Kafka.Enabled {
+		return primary, err
+	}
+
+	ks, err := NewKafkaStorage(primary, &cfg.Kafka)
+	if err != nil {
+		// Kafka只是附加输出，初始化失败不影响主存储
+		log.Printf("初始化Kafka输出失败，已禁用: %v", err)
+		return primary, nil
+	}
+	return ks, nil
+}
+
+// newPrimaryStorage 创建保存和查询资产的主存储
+func newPrimaryStorage(cfg *config.StorageConfig) (Storage, error) {
+	switch cfg.Type {
+	case "elasticsearch":
+		return NewElasticsearchStorage(&cfg.Elasticsearch)
+	case "file":
+		return NewFileStorage(&cfg.File)
+	default:
+		return NewMemoryStorage(), nil
+	}
+}
+
+// matchAsset 在资产的字符串字段值中做不区分大小写的子串匹配，query需已转为小写
+// 字段名、数值和JSON的引号、转义不参与匹配
+func matchAsset(asset interface{}, query string) bool {
+	assetBytes, err := json.Marshal(asset)
+	if err != nil {
+		return false
+	}
+	var value interface{}
+	if err := json.Unmarshal(assetBytes, &value); err != nil {
+		return false
+	}
+	return matchValue(value, query)
+}
+
+// matchValue 递归检查对象和数组中的字符串值
+func matchValue(value interface{}, query string) bool {
+	switch v := value.(type) {
+	case string:
+		return strings.Contains(strings.ToLower(v), query)
+	case map[string]interface{}:
+		for _, item := range v {
+			if matchValue(item, query) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if matchValue(item, query) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// toAssetMap 将存储中的资产转换为map，不存在或无法转换时返回nil
+func toAssetMap(asset interface{}) map[string]interface{} {
+	if asset == nil {
+		return nil
+	}
+	if m, ok := asset.(map[string]interface{}); ok {
+		return m
+	}
+
+	assetBytes, err := json.Marshal(asset)
+	if err != nil {
+		return nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(assetBytes, &m); err != nil {
+		return nil
+	}
+	return m
+}
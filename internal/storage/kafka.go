@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+
+	"assets_discovery/internal/config"
+)
+
+// KafkaStorage 在主存储之外将保存的资产发布到Kafka
+// 查询类操作全部由主存储完成，Kafka只作为输出通道；
+// 发布是异步的，Kafka不可用时只记录日志，不影响主存储的写入
+type KafkaStorage struct {
+	primary Storage
+	writer  *kafka.Writer
+
+	// 主存储不支持原子更新时，在进程内加锁模拟
+	updateMu sync.Mutex
+
+	// 发布失败的消息数量
+	dropped uint64
+}
+
+// NewKafkaStorage 创建Kafka输出，primary为实际保存和查询资产的存储
+func NewKafkaStorage(primary Storage, cfg *config.KafkaConfig) (*KafkaStorage, error) {
+	if len(cfg.Brokers) == 0 || cfg.Topic == "" {
+		return nil, fmt.Errorf("Kafka配置缺少brokers或topic")
+	}
+
+	transport := &kafka.Transport{
+		DialTimeout: 10 * time.Second,
+	}
+
+	if cfg.Username != "" {
+		mechanism, err := kafkaSASLMechanism(cfg)
+		if err != nil {
+			return nil, err
+		}
+		transport.SASL = mechanism
+	}
+
+	if cfg.TLS {
+		if cfg.InsecureSkipVerify {
+			log.Println("警告: 已关闭Kafka证书校验，连接可能遭受中间人攻击")
+		}
+		tlsConfig, err := loadTLSConfig(cfg.CACert, cfg.ClientCert, cfg.ClientKey, cfg.InsecureSkipVerify)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLS = tlsConfig
+	}
+
+	ks := &KafkaStorage{primary: primary}
+
+	ks.writer = &kafka.Writer{
+		Addr:         kafka.TCP(cfg.Brokers...),
+		Topic:        cfg.Topic,
+		Balancer:     &kafka.Hash{}, // 同一资产的消息进入同一分区，保证顺序
+		BatchSize:    cfg.BatchSize,
+		BatchTimeout: cfg.BatchTimeout,
+		RequiredAcks: kafka.RequireOne,
+		Async:        true,
+		Transport:    transport,
+		Completion:   ks.completion,
+	}
+
+	log.Printf("Kafka输出已启用: %v, topic=%s", cfg.Brokers, cfg.Topic)
+	return ks, nil
+}
+
+// kafkaSASLMechanism 根据配置创建SASL认证方式
+func kafkaSASLMechanism(cfg *config.KafkaConfig) (sasl.Mechanism, error) {
+	switch cfg.SASLMechanism {
+	case "", "plain":
+		return plain.Mechanism{Username: cfg.Username, Password: cfg.Password}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, cfg.Username, cfg.Password)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, cfg.Username, cfg.Password)
+	default:
+		return nil, fmt.Errorf("不支持的SASL认证方式: %s", cfg.SASLMechanism)
+	}
+}
+
+// kafkaLogEvery Broker持续不可用时，每失败这么多条消息记录一次日志，避免刷屏
+const kafkaLogEvery = 1000
+
+// completion 异步发布完成回调，记录Broker不可用等原因导致的失败
+func (ks *KafkaStorage) completion(messages []kafka.Message, err error) {
+	if err != nil {
+		ks.recordFailure(len(messages), err)
+	}
+}
+
+// recordFailure 累计发布失败的消息数，只在首次失败和每kafkaLogEvery条时记录日志
+func (ks *KafkaStorage) recordFailure(count int, err error) {
+	total := atomic.AddUint64(&ks.dropped, uint64(count))
+	if total == uint64(count) || total/kafkaLogEvery != (total-uint64(count))/kafkaLogEvery {
+		log.Printf("发布资产到Kafka失败 (累计 %d 条): %v", total, err)
+	}
+}
+
+// publish 异步发布资产，以资产ID作为消息键
+func (ks *KafkaStorage) publish(asset interface{}) {
+	assetID, assetBytes, err := esDocument(asset)
+	if err != nil {
+		log.Printf("发布资产到Kafka失败: %v", err)
+		return
+	}
+
+	msg := kafka.Message{
+		Key:   []byte(assetID),
+		Value: assetBytes,
+		Time:  time.Now(),
+	}
+	if err := ks.writer.WriteMessages(context.Background(), msg); err != nil {
+		ks.recordFailure(1, err)
+	}
+}
+
+// SaveAsset 保存到主存储并发布到Kafka
+func (ks *KafkaStorage) SaveAsset(asset interface{}) error {
+	ks.publish(asset)
+	return ks.primary.SaveAsset(asset)
+}
+
+// SaveAssets 批量保存到主存储并发布到Kafka
+func (ks *KafkaStorage) SaveAssets(assets []interface{}) (int, error) {
+	for _, asset := range assets {
+		ks.publish(asset)
+	}
+
+	if bulk, ok := ks.primary.(BulkStorage); ok {
+		return bulk.SaveAssets(assets)
+	}
+
+	saved := 0
+	var lastErr error
+	for _, asset := range assets {
+		if err := ks.primary.SaveAsset(asset); err != nil {
+			lastErr = err
+			continue
+		}
+		saved++
+	}
+	return saved, lastErr
+}
+
+// UpdateAsset 在主存储中原子更新资产，并发布更新后的资产
+func (ks *KafkaStorage) UpdateAsset(id string, mutate AssetMutator) error {
+	var updated map[string]interface{}
+	capture := func(current map[string]interface{}) (map[string]interface{}, error) {
+		result, err := mutate(current)
+		updated = result
+		return result, err
+	}
+
+	if atomicStorage, ok := ks.primary.(AtomicStorage); ok {
+		if err := atomicStorage.UpdateAsset(id, capture); err != nil {
+			return err
+		}
+	} else {
+		ks.updateMu.Lock()
+		current, _ := ks.primary.GetAsset(id)
+		result, err := capture(toAssetMap(current))
+		if err == nil && result != nil {
+			result["id"] = id
+			err = ks.primary.SaveAsset(result)
+		}
+		ks.updateMu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	if updated != nil {
+		ks.publish(updated)
+	}
+	return nil
+}
+
+// EachAsset 由主存储遍历资产
+func (ks *KafkaStorage) EachAsset(fn func(asset interface{}) error) error {
+	return EachAsset(ks.primary, fn)
+}
+
+// AggregateAssets 由主存储完成聚合
+func (ks *KafkaStorage) AggregateAssets(field string, activeOnly bool) (map[string]int, error) {
+	if aggregator, ok := ks.primary.(AggregateStorage); ok {
+		return aggregator.AggregateAssets(field, activeOnly)
+	}
+	return nil, fmt.Errorf("主存储不支持聚合")
+}
+
+// GetAsset 从主存储获取资产
+func (ks *KafkaStorage) GetAsset(id string) (interface{}, error) {
+	return ks.primary.GetAsset(id)
+}
+
+// GetAllAssets 从主存储获取所有资产
+func (ks *KafkaStorage) GetAllAssets() ([]interface{}, error) {
+	return ks.primary.GetAllAssets()
+}
+
+// SearchAssets 在主存储中搜索资产
+func (ks *KafkaStorage) SearchAssets(query string) ([]interface{}, error) {
+	return ks.primary.SearchAssets(query)
+}
+
+// DeleteAsset 从主存储删除资产
+func (ks *KafkaStorage) DeleteAsset(id string) error {
+	return ks.primary.DeleteAsset(id)
+}
+
+// ExportJSON 导出JSON
+func (ks *KafkaStorage) ExportJSON(assets interface{}) ([]byte, error) {
+	return ks.primary.ExportJSON(assets)
+}
+
+// Close 发送缓冲中的消息并关闭主存储
+func (ks *KafkaStorage) Close() error {
+	if err := ks.writer.Close(); err != nil {
+		log.Printf("关闭Kafka输出失败: %v", err)
+	}
+
+	if dropped := atomic.LoadUint64(&ks.dropped); dropped > 0 {
+		log.Printf("警告: 共有 %d 条资产消息未能发布到Kafka", dropped)
+	}
+
+	return ks.primary.Close()
+}
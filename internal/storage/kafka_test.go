@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	metadataAPI "github.com/segmentio/kafka-go/protocol/metadata"
+	produceAPI "github.com/segmentio/kafka-go/protocol/produce"
+
+	"assets_discovery/internal/config"
+)
+
+// fakeKafka 模拟只有一个分区的Kafka集群，记录收到的消息数，unavailable时拒绝所有写入
+type fakeKafka struct {
+	mu          sync.Mutex
+	unavailable bool
+	produced    int
+}
+
+func (f *fakeKafka) RoundTrip(ctx context.Context, addr net.Addr, req kafka.Request) (kafka.Response, error) {
+	switch r := req.(type) {
+	case *metadataAPI.Request:
+		res := &metadataAPI.Response{Brokers: []metadataAPI.ResponseBroker{{NodeID: 0, Host: "localhost", Port: 9092}}}
+		for _, topic := range r.TopicNames {
+			res.Topics = append(res.Topics, metadataAPI.ResponseTopic{
+				Name:       topic,
+				Partitions: []metadataAPI.ResponsePartition{{PartitionIndex: 0, LeaderID: 0}},
+			})
+		}
+		return res, nil
+	case *produceAPI.Request:
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.unavailable {
+			return nil, errors.New("broker unavailable")
+		}
+
+		res := &produceAPI.Response{}
+		for _, topic := range r.Topics {
+			rt := produceAPI.ResponseTopic{Topic: topic.Topic}
+			for _, partition := range topic.Partitions {
+				for {
+					if _, err := partition.RecordSet.Records.ReadRecord(); err != nil {
+						if err != io.EOF {
+							return nil, err
+						}
+						break
+					}
+					f.produced++
+				}
+				rt.Partitions = append(rt.Partitions, produceAPI.ResponsePartition{Partition: partition.Partition})
+			}
+			res.Topics = append(res.Topics, rt)
+		}
+		return res, nil
+	}
+	return nil, errors.New("unexpected request")
+}
+
+func (f *fakeKafka) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.produced
+}
+
+func TestKafkaCloseFlushesPending(t *testing.T) {
+	tests := []struct {
+		name          string
+		unavailable   bool
+		wantPublished int
+		wantDropped   uint64
+	}{
+		{"batch flushed on close", false, 3, 0},
+		{"broker unavailable", true, 0, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 批量未满且超时很长，消息只会在Close时发出
+			ks, err := NewKafkaStorage(NewMemoryStorage(), &config.KafkaConfig{
+				Brokers:      []string{"localhost:9092"},
+				Topic:        "assets",
+				BatchSize:    100,
+				BatchTimeout: time.Hour,
+			})
+			if err != nil {
+				t.Fatalf("NewKafkaStorage() error = %v", err)
+			}
+			broker := &fakeKafka{unavailable: tt.unavailable}
+			ks.writer.Transport = broker
+			ks.writer.MaxAttempts = 1
+
+			for _, id := range []string{"mac_00:11:22:33:44:01", "mac_00:11:22:33:44:02", "mac_00:11:22:33:44:03"} {
+				if err := ks.SaveAsset(map[string]interface{}{"id": id}); err != nil {
+					t.Fatalf("SaveAsset(%s) error = %v", id, err)
+				}
+			}
+			if got := broker.count(); got != 0 {
+				t.Fatalf("published before Close = %d, want 0 (batched)", got)
+			}
+
+			if err := ks.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if got := broker.count(); got != tt.wantPublished {
+				t.Errorf("published after Close = %d, want %d", got, tt.wantPublished)
+			}
+			if dropped := atomic.LoadUint64(&ks.dropped); dropped != tt.wantDropped {
+				t.Errorf("dropped = %d, want %d", dropped, tt.wantDropped)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 )
 
@@ -60,6 +61,21 @@ func (ms *MemoryStorage) SaveAsset(asset interface{}) error {
 	return nil
 }
 
+// UpdateAsset 在锁内完成读-改-写
+func (ms *MemoryStorage) UpdateAsset(id string, mutate AssetMutator) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	updated, err := mutate(toAssetMap(ms.data[id]))
+	if err != nil || updated == nil {
+		return err
+	}
+
+	updated["id"] = id
+	ms.data[id] = updated
+	return nil
+}
+
 // GetAsset 获取资产
 func (ms *MemoryStorage) GetAsset(id string) (interface{}, error) {
 	ms.mutex.RLock()
@@ -91,13 +107,11 @@ func (ms *MemoryStorage) SearchAssets(query string) ([]interface{}, error) {
 	defer ms.mutex.RUnlock()
 
 	var results []interface{}
+	query = strings.ToLower(query)
 
 	for _, asset := range ms.data {
-		// 简单的字符串匹配搜索
-		if assetBytes, err := json.Marshal(asset); err == nil {
-			if contains(string(assetBytes), query) {
-				results = append(results, asset)
-			}
+		if matchAsset(asset, query) {
+			results = append(results, asset)
 		}
 	}
 
@@ -130,19 +144,3 @@ func (ms *MemoryStorage) Close() error {
 	ms.data = nil
 	return nil
 }
-
-// 辅助函数
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(substr) == 0 ||
-		(len(s) > len(substr) && (s[:len(substr)] == substr || s[len(s)-len(substr):] == substr ||
-			containsSubstring(s, substr))))
-}
-
-func containsSubstring(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
-	}
-	return false
-}
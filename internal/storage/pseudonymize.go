@@ -0,0 +1,315 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"assets_discovery/internal/config"
+)
+
+// pseudonymIDPrefix 假名化后资产ID的前缀，带此前缀的ID和带pseudonymized标记的资产不会被重复假名化
+const pseudonymIDPrefix = "anon_"
+
+// minRedactLength 短于此长度的主机名不在协议详情等自由文本中替换，避免误替换其他内容
+const minRedactLength = 3
+
+// macPattern 匹配文本中冒号或连字符分隔的MAC地址
+var macPattern = regexp.MustCompile(`(?i)[0-9a-f]{2}([:-][0-9a-f]{2}){5}`)
+
+// redactedFields 可能包含MAC地址和主机名的嵌套字段：变更记录、字段来源、协议详情和服务横幅
+// 运维人员填写的备注原样保存
+var redactedFields = []string{"changes", "provenance", "protocols", "services"}
+
+// PseudonymStorage 在写入主存储前将资产中的识别信息替换为基于密钥的假名：
+// 资产ID替换为HMAC哈希，MAC地址保留厂商前缀、后三个字节替换为哈希，主机名替换为哈希并保留域名。
+// 同一密钥下同一设备的假名保持不变，多次保存、多个采集器写入时仍按假名ID去重合并；
+// 按原始ID查询和删除时先转换为假名ID，存储中的数据不再包含原始MAC地址和主机名
+type PseudonymStorage struct {
+	primary Storage
+	key     []byte
+
+	// 主存储不支持原子更新时，在进程内加锁模拟
+	updateMu sync.Mutex
+}
+
+// NewPseudonymStorage 创建假名化存储，primary为实际保存和查询资产的存储
+func NewPseudonymStorage(primary Storage, cfg *config.PseudonymizeConfig) (*PseudonymStorage, error) {
+	if cfg.Key == "" {
+		return nil, fmt.Errorf("启用假名化时必须配置storage.pseudonymize.key")
+	}
+
+	log.Println("存储假名化已启用：资产ID、MAC地址和主机名将以假名保存")
+	return &PseudonymStorage{primary: primary, key: []byte(cfg.Key)}, nil
+}
+
+// digest 计算带类别的HMAC，不同类别的相同取值得到不同的哈希
+func (ps *PseudonymStorage) digest(kind, value string) []byte {
+	mac := hmac.New(sha256.New, ps.key)
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// storedID 返回资产在存储中的ID，已经是假名ID时原样返回
+func (ps *PseudonymStorage) storedID(id string) string {
+	if id == "" || strings.HasPrefix(id, pseudonymIDPrefix) {
+		return id
+	}
+	return pseudonymIDPrefix + hex.EncodeToString(ps.digest("id", id)[:8])
+}
+
+// pseudonymMAC 保留MAC地址的厂商前缀（OUI），后三个字节替换为哈希
+func (ps *PseudonymStorage) pseudonymMAC(mac string) string {
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) != 6 {
+		return hex.EncodeToString(ps.digest("mac", strings.ToLower(mac))[:6])
+	}
+	sum := ps.digest("mac", hw.String())
+	return net.HardwareAddr{hw[0], hw[1], hw[2], sum[0], sum[1], sum[2]}.String()
+}
+
+// pseudonymHost 将主机名的第一段替换为哈希，保留域名
+func (ps *PseudonymStorage) pseudonymHost(hostname string) string {
+	short, domain, hasDomain := strings.Cut(hostname, ".")
+	name := "host-" + hex.EncodeToString(ps.digest("host", strings.ToLower(short))[:4])
+	if hasDomain {
+		return name + "." + domain
+	}
+	return name
+}
+
+// pseudonymize 返回假名化后的资产，不修改传入的资产
+func (ps *PseudonymStorage) pseudonymize(asset interface{}) (map[string]interface{}, error) {
+	m := toAssetMap(asset)
+	if m == nil {
+		return nil, fmt.Errorf("无法转换资产")
+	}
+	if done, _ := m["pseudonymized"].(bool); done {
+		return m, nil
+	}
+
+	result := make(map[string]interface{}, len(m)+1)
+	for k, v := range m {
+		result[k] = v
+	}
+
+	// 资产的主机名及变更记录中出现过的主机名，在嵌套字段中一并替换
+	hosts := make(map[string]string)
+	addHost := func(value interface{}) {
+		if name, ok := value.(string); ok && len(name) >= minRedactLength {
+			hosts[strings.ToLower(name)] = ps.pseudonymHost(name)
+			if short, _, ok := strings.Cut(name, "."); ok && len(short) >= minRedactLength {
+				hosts[strings.ToLower(short)] = ps.pseudonymHost(short)
+			}
+		}
+	}
+	addHost(m["hostname"])
+	if changes, ok := m["changes"].([]interface{}); ok {
+		for _, c := range changes {
+			if change, ok := c.(map[string]interface{}); ok && change["change_type"] == "hostname_change" {
+				addHost(change["old_value"])
+				addHost(change["new_value"])
+			}
+		}
+	}
+	redact := ps.redactor(hosts)
+
+	if id, ok := m["id"].(string); ok {
+		result["id"] = ps.storedID(id)
+	}
+	if mac, ok := m["mac_address"].(string); ok && mac != "" {
+		result["mac_address"] = ps.pseudonymMAC(mac)
+	}
+	if hostname, ok := m["hostname"].(string); ok && hostname != "" {
+		result["hostname"] = ps.pseudonymHost(hostname)
+	}
+	if short, ok := m["short_name"].(string); ok && short != "" {
+		result["short_name"] = ps.pseudonymHost(short)
+	}
+	for _, field := range redactedFields {
+		if value, ok := m[field]; ok {
+			result[field] = redactValue(value, redact)
+		}
+	}
+	ps.pseudonymDHCPIDs(result["protocols"])
+	result["pseudonymized"] = true
+
+	return result, nil
+}
+
+// dhcpIDFields DHCP客户端标识中的DUID通常包含MAC地址且不带分隔符，文本替换无法识别，整体替换为哈希
+// 同一DUID的哈希相同，存储中的数据仍可按DUID关联主机
+var dhcpIDFields = []string{"client_id", "duid"}
+
+// pseudonymDHCPIDs 替换已脱敏的协议详情中的DHCP客户端标识，protocols为redactValue返回的副本
+func (ps *PseudonymStorage) pseudonymDHCPIDs(protocols interface{}) {
+	p, _ := protocols.(map[string]interface{})
+	dhcpInfo, ok := p["dhcp"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, field := range dhcpIDFields {
+		if id, ok := dhcpInfo[field].(string); ok && id != "" {
+			dhcpInfo[field] = hex.EncodeToString(ps.digest(field, id)[:8])
+		}
+	}
+}
+
+// redactor 返回替换文本中MAC地址和已知主机名的函数，主机名不区分大小写、优先匹配较长的名称
+func (ps *PseudonymStorage) redactor(hosts map[string]string) func(string) string {
+	var hostPattern *regexp.Regexp
+	if len(hosts) > 0 {
+		names := make([]string, 0, len(hosts))
+		for name := range hosts {
+			names = append(names, regexp.QuoteMeta(name))
+		}
+		sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+		hostPattern = regexp.MustCompile(`(?i)` + strings.Join(names, "|"))
+	}
+
+	return func(s string) string {
+		s = macPattern.ReplaceAllStringFunc(s, ps.pseudonymMAC)
+		if hostPattern != nil {
+			s = hostPattern.ReplaceAllStringFunc(s, func(name string) string {
+				return hosts[strings.ToLower(name)]
+			})
+		}
+		return s
+	}
+}
+
+// redactValue 对嵌套的JSON值中的所有字符串应用redact，map的键不变
+func redactValue(value interface{}, redact func(string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return redact(v)
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			result[k] = redactValue(item, redact)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = redactValue(item, redact)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// SaveAsset 假名化后保存到主存储
+func (ps *PseudonymStorage) SaveAsset(asset interface{}) error {
+	pseudonymized, err := ps.pseudonymize(asset)
+	if err != nil {
+		return err
+	}
+	return ps.primary.SaveAsset(pseudonymized)
+}
+
+// SaveAssets 假名化后批量保存到主存储
+func (ps *PseudonymStorage) SaveAssets(assets []interface{}) (int, error) {
+	pseudonymized := make([]interface{}, 0, len(assets))
+	for _, asset := range assets {
+		if m, err := ps.pseudonymize(asset); err == nil {
+			pseudonymized = append(pseudonymized, m)
+		}
+	}
+
+	if bulk, ok := ps.primary.(BulkStorage); ok {
+		return bulk.SaveAssets(pseudonymized)
+	}
+
+	saved := 0
+	var lastErr error
+	for _, asset := range pseudonymized {
+		if err := ps.primary.SaveAsset(asset); err != nil {
+			lastErr = err
+			continue
+		}
+		saved++
+	}
+	return saved, lastErr
+}
+
+// UpdateAsset 按假名ID原子更新主存储中的资产，mutate的结果假名化后保存
+// mutate收到的是存储中已假名化的资产，首次发现时间、开放端口等合并不受影响
+func (ps *PseudonymStorage) UpdateAsset(id string, mutate AssetMutator) error {
+	storedID := ps.storedID(id)
+	pseudonymizing := func(current map[string]interface{}) (map[string]interface{}, error) {
+		updated, err := mutate(current)
+		if err != nil || updated == nil {
+			return nil, err
+		}
+		return ps.pseudonymize(updated)
+	}
+
+	if atomicStorage, ok := ps.primary.(AtomicStorage); ok {
+		return atomicStorage.UpdateAsset(storedID, pseudonymizing)
+	}
+
+	ps.updateMu.Lock()
+	defer ps.updateMu.Unlock()
+
+	current, _ := ps.primary.GetAsset(storedID)
+	result, err := pseudonymizing(toAssetMap(current))
+	if err != nil || result == nil {
+		return err
+	}
+	result["id"] = storedID
+	return ps.primary.SaveAsset(result)
+}
+
+// EachAsset 由主存储遍历资产
+func (ps *PseudonymStorage) EachAsset(fn func(asset interface{}) error) error {
+	return EachAsset(ps.primary, fn)
+}
+
+// AggregateAssets 由主存储完成聚合
+func (ps *PseudonymStorage) AggregateAssets(field string, activeOnly bool) (map[string]int, error) {
+	if aggregator, ok := ps.primary.(AggregateStorage); ok {
+		return aggregator.AggregateAssets(field, activeOnly)
+	}
+	return nil, fmt.Errorf("主存储不支持聚合")
+}
+
+// GetAsset 按原始ID或假名ID从主存储获取资产
+func (ps *PseudonymStorage) GetAsset(id string) (interface{}, error) {
+	return ps.primary.GetAsset(ps.storedID(id))
+}
+
+// GetAllAssets 从主存储获取所有资产
+func (ps *PseudonymStorage) GetAllAssets() ([]interface{}, error) {
+	return ps.primary.GetAllAssets()
+}
+
+// SearchAssets 在主存储中搜索资产，原始MAC地址和主机名已不在存储中，无法按其搜索
+func (ps *PseudonymStorage) SearchAssets(query string) ([]interface{}, error) {
+	return ps.primary.SearchAssets(query)
+}
+
+// DeleteAsset 按原始ID或假名ID从主存储删除资产
+func (ps *PseudonymStorage) DeleteAsset(id string) error {
+	return ps.primary.DeleteAsset(ps.storedID(id))
+}
+
+// ExportJSON 导出JSON
+func (ps *PseudonymStorage) ExportJSON(assets interface{}) ([]byte, error) {
+	return ps.primary.ExportJSON(assets)
+}
+
+// Close 关闭主存储
+func (ps *PseudonymStorage) Close() error {
+	return ps.primary.Close()
+}
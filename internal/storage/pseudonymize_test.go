@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"assets_discovery/internal/config"
+)
+
+// newTestPseudonym 创建以内存存储为主存储的假名化存储
+func newTestPseudonym(t *testing.T, key string) *PseudonymStorage {
+	t.Helper()
+
+	ps, err := NewPseudonymStorage(NewMemoryStorage(), &config.PseudonymizeConfig{Key: key})
+	if err != nil {
+		t.Fatalf("NewPseudonymStorage() error = %v", err)
+	}
+	return ps
+}
+
+func TestPseudonymMAC(t *testing.T) {
+	ps := newTestPseudonym(t, "k1")
+	want := ps.pseudonymMAC("00:1a:2b:3c:4d:5e")
+
+	tests := []struct {
+		name string
+		mac  string
+	}{
+		{"colon", "00:1a:2b:3c:4d:5e"},
+		{"hyphen", "00-1a-2b-3c-4d-5e"},
+		{"upper case", "00:1A:2B:3C:4D:5E"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ps.pseudonymMAC(tt.mac)
+			if !strings.HasPrefix(got, "00:1a:2b:") {
+				t.Errorf("pseudonymMAC(%s) = %s, OUI not kept", tt.mac, got)
+			}
+			if strings.HasSuffix(got, "3c:4d:5e") {
+				t.Errorf("pseudonymMAC(%s) = %s, device bytes not hashed", tt.mac, got)
+			}
+			// 同一地址的不同写法得到相同假名
+			if got != want {
+				t.Errorf("pseudonymMAC(%s) = %s, want %s", tt.mac, got, want)
+			}
+		})
+	}
+
+	if other := ps.pseudonymMAC("00:1a:2b:3c:4d:5f"); other == want {
+		t.Errorf("different devices share pseudonym %s", other)
+	}
+	if invalid := ps.pseudonymMAC("not-a-mac"); strings.Contains(invalid, "not-a-mac") || len(invalid) != 12 {
+		t.Errorf("pseudonymMAC(invalid) = %q, want 12 hex digits", invalid)
+	}
+}
+
+func TestPseudonymHost(t *testing.T) {
+	ps := newTestPseudonym(t, "k1")
+	hashed := regexp.MustCompile(`^host-[0-9a-f]{8}`)
+
+	tests := []struct {
+		name       string
+		hostname   string
+		wantDomain string
+	}{
+		{"fqdn keeps domain", "web-01.corp.example", ".corp.example"},
+		{"single label", "web-01", ""},
+		{"upper case first label", "WEB-01.corp.example", ".corp.example"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ps.pseudonymHost(tt.hostname)
+			if !hashed.MatchString(got) {
+				t.Errorf("pseudonymHost(%s) = %s, first label not hashed", tt.hostname, got)
+			}
+			if strings.Contains(strings.ToLower(got), "web-01") {
+				t.Errorf("pseudonymHost(%s) = %s, original name kept", tt.hostname, got)
+			}
+			if domain := strings.TrimPrefix(got, hashed.FindString(got)); domain != tt.wantDomain {
+				t.Errorf("pseudonymHost(%s) domain = %q, want %q", tt.hostname, domain, tt.wantDomain)
+			}
+		})
+	}
+
+	// 第一段不区分大小写
+	if a, b := ps.pseudonymHost("web-01.corp.example"), ps.pseudonymHost("WEB-01.corp.example"); a != b {
+		t.Errorf("case changes pseudonym: %s vs %s", a, b)
+	}
+}
+
+func TestPseudonymIDPerKey(t *testing.T) {
+	const id = "mac_00:1a:2b:3c:4d:5e"
+	k1, k1Again, k2 := newTestPseudonym(t, "k1"), newTestPseudonym(t, "k1"), newTestPseudonym(t, "k2")
+
+	tests := []struct {
+		name     string
+		a, b     string
+		wantSame bool
+	}{
+		{"stable for same key", k1.storedID(id), k1Again.storedID(id), true},
+		{"differs across keys", k1.storedID(id), k2.storedID(id), false},
+		{"mac differs across keys", k1.pseudonymMAC("00:1a:2b:3c:4d:5e"), k2.pseudonymMAC("00:1a:2b:3c:4d:5e"), false},
+		{"host differs across keys", k1.pseudonymHost("web-01"), k2.pseudonymHost("web-01"), false},
+		{"pseudonym id not re-hashed", k1.storedID(k1.storedID(id)), k1.storedID(id), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if (tt.a == tt.b) != tt.wantSame {
+				t.Errorf("%s vs %s: same = %v, want %v", tt.a, tt.b, tt.a == tt.b, tt.wantSame)
+			}
+		})
+	}
+
+	if !strings.HasPrefix(k1.storedID(id), pseudonymIDPrefix) {
+		t.Errorf("storedID() = %s, want prefix %s", k1.storedID(id), pseudonymIDPrefix)
+	}
+
+	// 按原始ID保存、查询和删除
+	if err := k1.SaveAsset(map[string]interface{}{"id": id, "mac_address": "00:1a:2b:3c:4d:5e"}); err != nil {
+		t.Fatalf("SaveAsset() error = %v", err)
+	}
+	if _, err := k1.GetAsset(id); err != nil {
+		t.Errorf("GetAsset(original id) error = %v", err)
+	}
+	if err := k1.DeleteAsset(id); err != nil {
+		t.Errorf("DeleteAsset(original id) error = %v", err)
+	}
+	if _, err := k1.GetAsset(id); err == nil {
+		t.Errorf("asset still stored after DeleteAsset")
+	}
+}
+
+func TestPseudonymizeRedactsNestedFields(t *testing.T) {
+	const mac, hostname, oldHostname = "00:1a:2b:3c:4d:5e", "web-01.corp.example", "legacy-web"
+	notes := "web-01 (00:1a:2b:3c:4d:5e) 由 legacy-web 迁移而来"
+
+	asset := map[string]interface{}{
+		"id":          "mac_" + mac,
+		"mac_address": mac,
+		"hostname":    hostname,
+		"short_name":  "web-01",
+		"ip_address":  "10.0.0.1",
+		"changes": []interface{}{
+			map[string]interface{}{"change_type": "hostname_change", "old_value": oldHostname, "new_value": hostname},
+			map[string]interface{}{"change_type": "mac_change", "description": "MAC地址由 00-1A-2B-3C-4D-5E 变更"},
+		},
+		"protocols": map[string]interface{}{
+			"arp":  map[string]interface{}{"src_mac": mac, "src_ip": "10.0.0.1"},
+			"dhcp": map[string]interface{}{"hostname": "WEB-01", "client_id": "01001a2b3c4d5e"},
+		},
+		"services": []interface{}{
+			map[string]interface{}{"name": "ssh", "banner": "SSH-2.0-OpenSSH_8.9 web-01.corp.example"},
+		},
+		"notes": notes,
+	}
+
+	ps := newTestPseudonym(t, "k1")
+	result, err := ps.pseudonymize(asset)
+	if err != nil {
+		t.Fatalf("pseudonymize() error = %v", err)
+	}
+
+	tests := []struct {
+		field     string
+		forbidden []string
+	}{
+		{"id", []string{mac}},
+		{"mac_address", []string{"3c:4d:5e"}},
+		{"hostname", []string{"web-01"}},
+		{"short_name", []string{"web-01"}},
+		{"changes", []string{"web-01", "legacy-web", "3c:4d:5e", "3C-4D-5E"}},
+		{"protocols", []string{"web-01", "3c:4d:5e", "01001a2b3c4d5e"}},
+		{"services", []string{"web-01"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			data, _ := json.Marshal(result[tt.field])
+			for _, s := range tt.forbidden {
+				if strings.Contains(strings.ToLower(string(data)), strings.ToLower(s)) {
+					t.Errorf("%s still contains %q: %s", tt.field, s, data)
+				}
+			}
+		})
+	}
+
+	if result["notes"] != notes {
+		t.Errorf("notes = %v, want kept verbatim", result["notes"])
+	}
+	if result["ip_address"] != "10.0.0.1" {
+		t.Errorf("ip_address = %v, want unchanged", result["ip_address"])
+	}
+	if !strings.HasPrefix(result["mac_address"].(string), "00:1a:2b:") {
+		t.Errorf("mac_address = %v, OUI not kept", result["mac_address"])
+	}
+	if !strings.HasSuffix(result["hostname"].(string), ".corp.example") {
+		t.Errorf("hostname = %v, domain not kept", result["hostname"])
+	}
+	if asset["hostname"] != hostname || asset["mac_address"] != mac {
+		t.Errorf("input asset modified")
+	}
+
+	// 已假名化的资产不会被重复处理
+	again, _ := ps.pseudonymize(result)
+	if again["hostname"] != result["hostname"] || again["id"] != result["id"] {
+		t.Errorf("pseudonymized asset hashed twice")
+	}
+}
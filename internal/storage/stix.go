@@ -0,0 +1,253 @@
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// stixNamespace STIX 2.1规范中生成SCO确定性ID使用的UUIDv5命名空间
+var stixNamespace = [16]byte{0x00, 0xab, 0xed, 0xb4, 0xaa, 0x42, 0x46, 0x6c, 0x9c, 0x01, 0xfe, 0xd2, 0x33, 0x15, 0xa9, 0xb7}
+
+// stixAsset 导出为STIX时使用的资产字段
+type stixAsset struct {
+	ID         string `json:"id"`
+	IPAddress  string `json:"ip_address"`
+	MACAddress string `json:"mac_address"`
+	Hostname   string `json:"hostname"`
+	Vendor     string `json:"vendor"`
+	DeviceType string `json:"device_type"`
+	OSInfo     struct {
+		Family  string `json:"family"`
+		Version string `json:"version"`
+	} `json:"os_info"`
+	OpenPorts []struct {
+		Port      int    `json:"port"`
+		Protocol  string `json:"protocol"`
+		State     string `json:"state"`
+		Direction string `json:"direction"`
+		Service   string `json:"service"`
+	} `json:"open_ports"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// stixWriter 将资产转换为STIX 2.1网络可观测对象(SCO)并逐个写入一个bundle
+// IP地址转换为ipv4-addr/ipv6-addr，MAC地址为mac-addr，主机名为domain-name，开放端口为以资产为目的地址的network-traffic
+// SCO的ID由其值确定性生成，多个资产引用同一地址时只输出一次
+type stixWriter struct {
+	w       io.Writer
+	seen    map[string]bool
+	started bool
+}
+
+func newSTIXWriter(w io.Writer) *stixWriter {
+	return &stixWriter{w: w, seen: make(map[string]bool)}
+}
+
+// write 转换并写出一个资产的SCO
+func (sw *stixWriter) write(asset interface{}) error {
+	data, err := json.Marshal(asset)
+	if err != nil {
+		return fmt.Errorf("序列化资产失败: %v", err)
+	}
+	var a stixAsset
+	if err := json.Unmarshal(data, &a); err != nil {
+		return fmt.Errorf("解析资产失败: %v", err)
+	}
+
+	for _, object := range stixObjects(&a) {
+		if err := sw.writeObject(object); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stixObjects 将资产转换为SCO列表，资产的设备类型等信息以x_前缀的自定义属性记录在IP对象上，没有IP时记录在MAC对象上
+func stixObjects(a *stixAsset) []map[string]interface{} {
+	var objects []map[string]interface{}
+
+	var macObj, ipObj map[string]interface{}
+	if mac := strings.ToLower(a.MACAddress); mac != "" {
+		macObj = stixSCO("mac-addr", map[string]interface{}{"value": mac})
+		objects = append(objects, macObj)
+	}
+
+	ipType := "ipv4-addr"
+	if strings.Contains(a.IPAddress, ":") {
+		ipType = "ipv6-addr"
+	}
+	if a.IPAddress != "" {
+		ipObj = stixSCO(ipType, map[string]interface{}{"value": a.IPAddress})
+		if macObj != nil {
+			ipObj["resolves_to_refs"] = []string{macObj["id"].(string)}
+		}
+		objects = append(objects, ipObj)
+	}
+
+	if target := ipObj; target != nil || macObj != nil {
+		if target == nil {
+			target = macObj
+		}
+		setSTIXAssetProperties(target, a)
+	}
+
+	if ipObj == nil {
+		return objects
+	}
+
+	if a.Hostname != "" {
+		domain := stixSCO("domain-name", map[string]interface{}{"value": a.Hostname})
+		domain["resolves_to_refs"] = []string{ipObj["id"].(string)}
+		objects = append(objects, domain)
+	}
+
+	// 资产提供服务的开放端口，关闭的端口和作为客户端使用的端口不导出
+	layer3 := strings.TrimSuffix(ipType, "-addr")
+	for _, port := range a.OpenPorts {
+		if port.State == "closed" || port.Direction != "" && port.Direction != "listening" {
+			continue
+		}
+		protocols := []string{layer3, strings.ToLower(port.Protocol)}
+		if port.Service != "" && port.Service != "unknown" {
+			protocols = append(protocols, strings.ToLower(port.Service))
+		}
+		objects = append(objects, stixSCO("network-traffic", map[string]interface{}{
+			"dst_ref":   ipObj["id"],
+			"dst_port":  port.Port,
+			"protocols": protocols,
+		}))
+	}
+
+	return objects
+}
+
+// setSTIXAssetProperties 以自定义属性记录资产ID、设备类型、厂商、操作系统和观测时间
+func setSTIXAssetProperties(object map[string]interface{}, a *stixAsset) {
+	properties := map[string]string{
+		"x_asset_id":    a.ID,
+		"x_device_type": a.DeviceType,
+		"x_vendor":      a.Vendor,
+		"x_os_family":   a.OSInfo.Family,
+		"x_os_version":  a.OSInfo.Version,
+	}
+	for key, value := range properties {
+		if value != "" {
+			object[key] = value
+		}
+	}
+	if !a.FirstSeen.IsZero() {
+		object["x_first_seen"] = stixTimestamp(a.FirstSeen)
+	}
+	if !a.LastSeen.IsZero() {
+		object["x_last_seen"] = stixTimestamp(a.LastSeen)
+	}
+}
+
+// stixSCO 创建SCO，ID按规范对参与ID生成的属性做UUIDv5，properties同时作为对象的属性
+func stixSCO(objectType string, properties map[string]interface{}) map[string]interface{} {
+	// json.Marshal按键排序输出map，与规范要求的规范化JSON一致
+	canonical, _ := json.Marshal(properties)
+
+	object := map[string]interface{}{
+		"type":         objectType,
+		"spec_version": "2.1",
+		"id":           objectType + "--" + uuidV5(stixNamespace, canonical),
+	}
+	for key, value := range properties {
+		object[key] = value
+	}
+	return object
+}
+
+// writeObject 写出一个SCO，同一ID的对象只写出一次，第一个对象之前写出bundle头部
+func (sw *stixWriter) writeObject(object map[string]interface{}) error {
+	id := object["id"].(string)
+	if sw.seen[id] {
+		return nil
+	}
+
+	data, err := json.Marshal(object)
+	if err != nil {
+		return fmt.Errorf("序列化STIX对象失败: %v", err)
+	}
+
+	prefix := ",\n"
+	if !sw.started {
+		header, err := stixBundleHeader()
+		if err != nil {
+			return err
+		}
+		prefix = header + "\n"
+		sw.started = true
+	}
+
+	if _, err := io.WriteString(sw.w, prefix); err != nil {
+		return err
+	}
+	if _, err := sw.w.Write(data); err != nil {
+		return err
+	}
+	sw.seen[id] = true
+	return nil
+}
+
+// close 补全bundle结尾，没有任何对象时输出空的bundle
+func (sw *stixWriter) close() error {
+	if !sw.started {
+		header, err := stixBundleHeader()
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(sw.w, header+"]}\n")
+		return err
+	}
+	_, err := io.WriteString(sw.w, "\n]}\n")
+	return err
+}
+
+// stixBundleHeader bundle的开头部分，bundle ID每次导出随机生成
+func stixBundleHeader() (string, error) {
+	id, err := uuidV4()
+	if err != nil {
+		return "", fmt.Errorf("生成bundle ID失败: %v", err)
+	}
+	return fmt.Sprintf(`{"type":"bundle","id":"bundle--%s","objects":[`, id), nil
+}
+
+// stixTimestamp STIX要求的UTC时间戳格式
+func stixTimestamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+// uuidV5 按RFC 4122生成基于SHA-1的UUID
+func uuidV5(namespace [16]byte, name []byte) string {
+	h := sha1.New()
+	h.Write(namespace[:])
+	h.Write(name)
+	var u [16]byte
+	copy(u[:], h.Sum(nil))
+	u[6] = u[6]&0x0f | 0x50
+	u[8] = u[8]&0x3f | 0x80
+	return formatUUID(u)
+}
+
+// uuidV4 生成随机UUID
+func uuidV4() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", err
+	}
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return formatUUID(u), nil
+}
+
+func formatUUID(u [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+)
+
+// stixIDPattern SCO ID的格式：类型--UUIDv5
+var stixIDPattern = regexp.MustCompile(`^([a-z0-9-]+)--[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// stixBundle 导出的STIX bundle
+type stixBundle struct {
+	Type    string                   `json:"type"`
+	ID      string                   `json:"id"`
+	Objects []map[string]interface{} `json:"objects"`
+}
+
+// exportSTIX 以stix格式写出资产并解析得到的bundle
+func exportSTIX(t *testing.T, assets ...map[string]interface{}) stixBundle {
+	t.Helper()
+
+	var buf bytes.Buffer
+	aw, err := NewAssetWriter(&buf, "stix", nil)
+	if err != nil {
+		t.Fatalf("NewAssetWriter(stix) error = %v", err)
+	}
+	for _, asset := range assets {
+		if err := aw.Write(asset); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := aw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	var bundle stixBundle
+	if err := json.Unmarshal(buf.Bytes(), &bundle); err != nil {
+		t.Fatalf("invalid STIX bundle: %v\n%s", err, buf.String())
+	}
+	return bundle
+}
+
+func TestSTIXBundle(t *testing.T) {
+	server := map[string]interface{}{
+		"id":          "mac_00:1a:2b:3c:4d:5e",
+		"ip_address":  "192.168.1.10",
+		"mac_address": "00:1A:2B:3C:4D:5E",
+		"hostname":    "web-1",
+		"vendor":      "Dell",
+		"device_type": "服务器",
+		"os_info":     map[string]interface{}{"family": "Linux", "version": "5.15"},
+		"open_ports": []interface{}{
+			map[string]interface{}{"port": 22, "protocol": "tcp", "state": "open", "direction": "listening", "service": "SSH"},
+			map[string]interface{}{"port": 80, "protocol": "tcp", "state": "open", "service": "unknown"},
+			map[string]interface{}{"port": 8080, "protocol": "tcp", "state": "closed", "direction": "listening"},
+			map[string]interface{}{"port": 443, "protocol": "tcp", "state": "open", "direction": "outbound"},
+		},
+		"first_seen": "2024-06-01T12:00:00+08:00",
+		"last_seen":  "2024-06-02T12:00:00Z",
+	}
+	// 与server共用IP的第二个资产，IP对象只输出一次
+	alias := map[string]interface{}{"id": "ip_192.168.1.10", "ip_address": "192.168.1.10"}
+	ipv6 := map[string]interface{}{"id": "ip_2001:db8::1", "ip_address": "2001:db8::1"}
+	macOnly := map[string]interface{}{"id": "mac_00:1a:2b:3c:4d:99", "mac_address": "00:1a:2b:3c:4d:99", "vendor": "Acme"}
+
+	bundle := exportSTIX(t, server, alias, ipv6, macOnly)
+	if bundle.Type != "bundle" || !regexp.MustCompile(`^bundle--[0-9a-f-]{36}$`).MatchString(bundle.ID) {
+		t.Errorf("bundle type = %q, id = %q, want bundle--<uuid>", bundle.Type, bundle.ID)
+	}
+
+	byID := make(map[string]map[string]interface{})
+	var types []string
+	for _, object := range bundle.Objects {
+		id, _ := object["id"].(string)
+		m := stixIDPattern.FindStringSubmatch(id)
+		if m == nil || m[1] != object["type"] {
+			t.Errorf("object id = %q, want %v--<uuidv5>", id, object["type"])
+		}
+		if object["spec_version"] != "2.1" {
+			t.Errorf("%s spec_version = %v, want 2.1", id, object["spec_version"])
+		}
+		if _, dup := byID[id]; dup {
+			t.Errorf("object %s written twice", id)
+		}
+		byID[id] = object
+		types = append(types, object["type"].(string))
+	}
+	sort.Strings(types)
+	wantTypes := []string{"domain-name", "ipv4-addr", "ipv6-addr", "mac-addr", "mac-addr", "network-traffic", "network-traffic"}
+	if !reflect.DeepEqual(types, wantTypes) {
+		t.Fatalf("object types = %v, want %v", types, wantTypes)
+	}
+
+	// 所有引用都指向bundle中的对象
+	ref := func(object map[string]interface{}, field string) map[string]interface{} {
+		t.Helper()
+		var id string
+		switch v := object[field].(type) {
+		case string:
+			id = v
+		case []interface{}:
+			if len(v) == 1 {
+				id, _ = v[0].(string)
+			}
+		}
+		target, ok := byID[id]
+		if !ok {
+			t.Fatalf("%s %s = %v, want reference to bundle object", object["type"], field, object[field])
+		}
+		return target
+	}
+
+	var ipv4, domain map[string]interface{}
+	var traffic []map[string]interface{}
+	for _, object := range bundle.Objects {
+		switch object["type"] {
+		case "ipv4-addr":
+			ipv4 = object
+		case "domain-name":
+			domain = object
+		case "network-traffic":
+			traffic = append(traffic, object)
+		case "mac-addr":
+			if object["value"] == "00:1a:2b:3c:4d:99" && (object["x_asset_id"] != "mac_00:1a:2b:3c:4d:99" || object["x_vendor"] != "Acme") {
+				t.Errorf("mac-only asset properties = %v, want x_asset_id and x_vendor on mac-addr", object)
+			}
+		}
+	}
+
+	if mac := ref(ipv4, "resolves_to_refs"); mac["value"] != "00:1a:2b:3c:4d:5e" {
+		t.Errorf("ipv4-addr resolves_to = %v, want lowercase server MAC", mac["value"])
+	}
+	wantProps := map[string]interface{}{
+		"value":         "192.168.1.10",
+		"x_asset_id":    "mac_00:1a:2b:3c:4d:5e",
+		"x_device_type": "服务器",
+		"x_vendor":      "Dell",
+		"x_os_family":   "Linux",
+		"x_os_version":  "5.15",
+		"x_first_seen":  "2024-06-01T04:00:00.000Z",
+		"x_last_seen":   "2024-06-02T12:00:00.000Z",
+	}
+	for key, want := range wantProps {
+		if ipv4[key] != want {
+			t.Errorf("ipv4-addr %s = %v, want %v", key, ipv4[key], want)
+		}
+	}
+	if domain["value"] != "web-1" || ref(domain, "resolves_to_refs")["id"] != ipv4["id"] {
+		t.Errorf("domain-name = %v, want web-1 resolving to %s", domain, ipv4["id"])
+	}
+
+	// 只导出资产提供服务的开放端口
+	ports := make(map[float64]interface{})
+	for _, object := range traffic {
+		if ref(object, "dst_ref")["id"] != ipv4["id"] {
+			t.Errorf("network-traffic dst_ref = %v, want %s", object["dst_ref"], ipv4["id"])
+		}
+		ports[object["dst_port"].(float64)] = object["protocols"]
+	}
+	wantPorts := map[float64]interface{}{
+		22: []interface{}{"ipv4", "tcp", "ssh"},
+		80: []interface{}{"ipv4", "tcp"},
+	}
+	if !reflect.DeepEqual(ports, wantPorts) {
+		t.Errorf("network-traffic = %v, want %v", ports, wantPorts)
+	}
+
+	// SCO的ID由值确定，重复导出得到相同的ID，bundle ID每次不同
+	again := exportSTIX(t, server)
+	if again.ID == bundle.ID {
+		t.Errorf("bundle id reused across exports: %s", again.ID)
+	}
+	for _, object := range again.Objects {
+		if _, ok := byID[object["id"].(string)]; !ok {
+			t.Errorf("re-exported %s id %s differs from first export", object["type"], object["id"])
+		}
+	}
+}
+
+func TestSTIXEmptyBundle(t *testing.T) {
+	bundle := exportSTIX(t)
+	if bundle.Type != "bundle" || bundle.Objects == nil || len(bundle.Objects) != 0 {
+		t.Errorf("empty export = %+v, want bundle with empty objects", bundle)
+	}
+}
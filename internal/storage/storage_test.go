@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"assets_discovery/internal/config"
+)
+
+// testBackend 按名称区分的存储实现
+type testBackend struct {
+	name    string
+	storage Storage
+}
+
+// newTestBackends 创建内存存储和写入临时目录的文件存储
+func newTestBackends(t *testing.T) []testBackend {
+	t.Helper()
+
+	backends := []testBackend{{"memory", NewMemoryStorage()}}
+	for _, format := range []string{"json", "ndjson"} {
+		fs, err := NewFileStorage(&config.FileConfig{OutputDir: t.TempDir(), Format: format})
+		if err != nil {
+			t.Fatalf("NewFileStorage(%s) error = %v", format, err)
+		}
+		backends = append(backends, testBackend{"file_" + format, fs})
+	}
+
+	for _, b := range backends {
+		b := b
+		t.Cleanup(func() { b.storage.Close() })
+	}
+	return backends
+}
+
+// resultIDs 返回排序后的资产ID
+func resultIDs(t *testing.T, results []interface{}) []string {
+	t.Helper()
+
+	ids := make([]string, 0, len(results))
+	for _, result := range results {
+		asset := toAssetMap(result)
+		id, _ := asset["id"].(string)
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestSearchAssets(t *testing.T) {
+	assets := []map[string]interface{}{
+		{"id": "a1", "hostname": "web-prod-01.corp.example", "vendor": "Cisco Systems, Inc", "ip_address": "10.1.2.3"},
+		{"id": "a2", "hostname": "db-staging", "vendor": "Dell Inc.", "ip_address": "10.1.20.7"},
+		{"id": "a3", "hostname": "PRINTER-3F", "vendor": "HP", "os_info": map[string]interface{}{"family": "Embedded"}},
+		{"id": "a4", "hostname": "lab<1>", "notes": `say "hi"`, "open_ports": []interface{}{map[string]interface{}{"port": 8443, "service": "https-alt"}}},
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"mid-field substring", "prod", []string{"a1"}},
+		{"mid-field across separator", "b-stag", []string{"a2"}},
+		{"case-insensitive query", "CISCO", []string{"a1"}},
+		{"case-insensitive value", "printer-3f", []string{"a3"}},
+		{"nested field", "embed", []string{"a3"}},
+		{"partial ip", "10.1.2", []string{"a1", "a2"}},
+		{"exact value", "HP", []string{"a3"}},
+		{"prefix", "web", []string{"a1"}},
+		{"suffix", "example", []string{"a1"}},
+		{"no match", "juniper", []string{}},
+		{"html characters", "lab<1", []string{"a4"}},
+		{"quoted value", `"hi"`, []string{"a4"}},
+		{"nested array", "HTTPS-ALT", []string{"a4"}},
+		{"number value", "8443", []string{}},
+		{"field name", "vendor", []string{}},
+		{"json punctuation", `":"`, []string{}},
+	}
+
+	for _, b := range newTestBackends(t) {
+		for _, asset := range assets {
+			if err := b.storage.SaveAsset(asset); err != nil {
+				t.Fatalf("%s: SaveAsset() error = %v", b.name, err)
+			}
+		}
+
+		for _, tt := range tests {
+			t.Run(b.name+"/"+tt.name, func(t *testing.T) {
+				results, err := b.storage.SearchAssets(tt.query)
+				if err != nil {
+					t.Fatalf("SearchAssets(%q) error = %v", tt.query, err)
+				}
+				got := resultIDs(t, results)
+				if len(got) != len(tt.want) {
+					t.Fatalf("SearchAssets(%q) = %v, want %v", tt.query, got, tt.want)
+				}
+				for i := range got {
+					if got[i] != tt.want[i] {
+						t.Fatalf("SearchAssets(%q) = %v, want %v", tt.query, got, tt.want)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestUpdateAssetConcurrent(t *testing.T) {
+	const writers, increments = 16, 25
+
+	for _, b := range newTestBackends(t) {
+		t.Run(b.name, func(t *testing.T) {
+			atomicStorage, ok := b.storage.(AtomicStorage)
+			if !ok {
+				t.Fatalf("%T does not implement AtomicStorage", b.storage)
+			}
+
+			var wg sync.WaitGroup
+			for w := 0; w < writers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for i := 0; i < increments; i++ {
+						err := atomicStorage.UpdateAsset("mac_00:11:22:33:44:55", func(current map[string]interface{}) (map[string]interface{}, error) {
+							if current == nil {
+								current = map[string]interface{}{"seen": float64(0)}
+							}
+							seen, _ := current["seen"].(float64)
+							current["seen"] = seen + 1
+							current[fmt.Sprintf("writer_%d", w)] = true
+							return current, nil
+						})
+						if err != nil {
+							t.Errorf("UpdateAsset() error = %v", err)
+							return
+						}
+					}
+				}(w)
+			}
+			wg.Wait()
+
+			stored, err := b.storage.GetAsset("mac_00:11:22:33:44:55")
+			if err != nil {
+				t.Fatalf("GetAsset() error = %v", err)
+			}
+			asset := toAssetMap(stored)
+			if seen, _ := asset["seen"].(float64); seen != writers*increments {
+				t.Errorf("seen = %v, want %d (lost updates)", seen, writers*increments)
+			}
+			for w := 0; w < writers; w++ {
+				if asset[fmt.Sprintf("writer_%d", w)] != true {
+					t.Errorf("update from writer %d lost", w)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateAssetNilResult(t *testing.T) {
+	noChange := func(map[string]interface{}) (map[string]interface{}, error) { return nil, nil }
+
+	for _, b := range newTestBackends(t) {
+		t.Run(b.name, func(t *testing.T) {
+			atomicStorage := b.storage.(AtomicStorage)
+
+			// 资产不存在时不创建
+			if err := atomicStorage.UpdateAsset("missing", noChange); err != nil {
+				t.Fatalf("UpdateAsset(missing) error = %v", err)
+			}
+			if _, err := b.storage.GetAsset("missing"); err == nil {
+				t.Errorf("nil result created asset")
+			}
+
+			// 已存在的资产保持不变
+			if err := b.storage.SaveAsset(map[string]interface{}{"id": "a1", "hostname": "web"}); err != nil {
+				t.Fatalf("SaveAsset() error = %v", err)
+			}
+			if err := atomicStorage.UpdateAsset("a1", noChange); err != nil {
+				t.Fatalf("UpdateAsset(a1) error = %v", err)
+			}
+			stored, err := b.storage.GetAsset("a1")
+			if err != nil {
+				t.Fatalf("GetAsset() error = %v", err)
+			}
+			if hostname := toAssetMap(stored)["hostname"]; hostname != "web" {
+				t.Errorf("hostname = %v, want web", hostname)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// StreamStorage 支持逐个遍历资产的存储，导出大量资产时不必一次加载全部资产
+type StreamStorage interface {
+	// 按存储顺序逐个回调资产，fn返回错误时停止遍历并返回该错误
+	EachAsset(fn func(asset interface{}) error) error
+}
+
+// EachAsset 遍历存储中的资产，存储不支持流式遍历时退回GetAllAssets
+func EachAsset(s Storage, fn func(asset interface{}) error) error {
+	if stream, ok := s.(StreamStorage); ok {
+		return stream.EachAsset(fn)
+	}
+
+	assets, err := s.GetAllAssets()
+	if err != nil {
+		return err
+	}
+	for _, asset := range assets {
+		if err := fn(asset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssetWriter 逐个写出资产，同一时间只序列化一个资产，内存占用与资产总数无关
+// json格式输出资产数组，ndjson格式每行一个资产，csv格式每行一个展开后的资产，stix格式输出STIX 2.1 bundle
+type AssetWriter struct {
+	w       io.Writer
+	ndjson  bool
+	csv     *csv.Writer
+	stix    *stixWriter
+	flatten *Flattener
+	count   int
+}
+
+// NewAssetWriter 创建资产写出器，format为json、ndjson、csv或stix，stix格式不展开
+// flatten不为nil时按其规则展开资产，csv格式未指定时使用默认展开规则
+func NewAssetWriter(w io.Writer, format string, flatten *Flattener) (*AssetWriter, error) {
+	switch format {
+	case "json", "":
+		return &AssetWriter{w: w, flatten: flatten}, nil
+	case "ndjson":
+		return &AssetWriter{w: w, ndjson: true, flatten: flatten}, nil
+	case "csv":
+		if flatten == nil {
+			flatten = &Flattener{}
+		}
+		return &AssetWriter{w: w, csv: csv.NewWriter(w), flatten: flatten}, nil
+	case "stix":
+		return &AssetWriter{w: w, stix: newSTIXWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("不支持的导出格式: %s", format)
+	}
+}
+
+// Write 写出一个资产
+func (aw *AssetWriter) Write(asset interface{}) error {
+	if aw.stix != nil {
+		if err := aw.stix.write(asset); err != nil {
+			return err
+		}
+		aw.count++
+		return nil
+	}
+
+	if aw.flatten != nil {
+		flat, err := aw.flatten.Flatten(asset)
+		if err != nil {
+			return err
+		}
+		if aw.csv != nil {
+			return aw.writeCSV(flat)
+		}
+		asset = flat
+	}
+
+	data, err := json.Marshal(asset)
+	if err != nil {
+		return fmt.Errorf("序列化资产失败: %v", err)
+	}
+
+	prefix := ""
+	if !aw.ndjson {
+		prefix = ",\n"
+		if aw.count == 0 {
+			prefix = "[\n"
+		}
+	}
+	suffix := ""
+	if aw.ndjson {
+		suffix = "\n"
+	}
+
+	if _, err := io.WriteString(aw.w, prefix); err != nil {
+		return err
+	}
+	if _, err := aw.w.Write(data); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(aw.w, suffix); err != nil {
+		return err
+	}
+
+	aw.count++
+	return nil
+}
+
+// writeCSV 写出一行展开后的资产，第一行之前写出表头
+// 每行写出后立即刷新，API导出时资产可以逐个发送
+func (aw *AssetWriter) writeCSV(flat map[string]interface{}) error {
+	if aw.count == 0 {
+		if err := aw.writeCSVHeader(); err != nil {
+			return err
+		}
+	}
+
+	columns := aw.flatten.columns()
+	record := make([]string, len(columns))
+	for i, column := range columns {
+		if value, ok := flat[aw.flatten.name(column)]; ok && value != nil {
+			record[i] = fmt.Sprint(value)
+		}
+	}
+
+	if err := aw.csv.Write(record); err != nil {
+		return err
+	}
+	aw.csv.Flush()
+	if err := aw.csv.Error(); err != nil {
+		return err
+	}
+
+	aw.count++
+	return nil
+}
+
+func (aw *AssetWriter) writeCSVHeader() error {
+	columns := aw.flatten.columns()
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = aw.flatten.name(column)
+	}
+	return aw.csv.Write(header)
+}
+
+// Close 结束输出，json和stix格式补全数组结尾，csv格式在没有资产时只输出表头，不关闭底层Writer
+func (aw *AssetWriter) Close() error {
+	if aw.stix != nil {
+		return aw.stix.close()
+	}
+	if aw.csv != nil {
+		if aw.count == 0 {
+			if err := aw.writeCSVHeader(); err != nil {
+				return err
+			}
+		}
+		aw.csv.Flush()
+		return aw.csv.Error()
+	}
+	if aw.ndjson {
+		return nil
+	}
+
+	end := "\n]\n"
+	if aw.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(aw.w, end)
+	return err
+}
+
+// Count 已写出的资产数量
+func (aw *AssetWriter) Count() int {
+	return aw.count
+}
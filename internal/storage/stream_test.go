@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"testing"
+)
+
+// benchmarkAsset 构造含端口、服务和协议信息的资产
+func benchmarkAsset(i int) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          fmt.Sprintf("mac_00:1a:2b:%02x:%02x:%02x", i>>16&0xff, i>>8&0xff, i&0xff),
+		"ip_address":  fmt.Sprintf("10.%d.%d.%d", i>>16&0xff, i>>8&0xff, i&0xff),
+		"mac_address": fmt.Sprintf("00:1a:2b:%02x:%02x:%02x", i>>16&0xff, i>>8&0xff, i&0xff),
+		"hostname":    fmt.Sprintf("host-%d.corp.example", i),
+		"vendor":      "Dell Inc.",
+		"device_type": "服务器",
+		"os_info":     map[string]interface{}{"family": "Linux", "version": "5.15"},
+		"open_ports": []interface{}{
+			map[string]interface{}{"port": 22, "protocol": "tcp", "state": "open"},
+			map[string]interface{}{"port": 443, "protocol": "tcp", "state": "open"},
+		},
+		"services": []interface{}{
+			map[string]interface{}{"name": "ssh", "port": 22, "protocol": "tcp", "version": "OpenSSH_8.9"},
+		},
+		"is_active": true,
+	}
+}
+
+// BenchmarkAssetWriter 每次写出n个资产，B/asset在不同规模下应保持不变，
+// 说明写出器的内存占用只与单个资产有关
+func BenchmarkAssetWriter(b *testing.B) {
+	for _, format := range []string{"json", "ndjson", "csv", "stix"} {
+		for _, n := range []int{1000, 10000} {
+			assets := make([]map[string]interface{}, n)
+			for i := range assets {
+				assets[i] = benchmarkAsset(i)
+			}
+
+			b.Run(fmt.Sprintf("%s/%d", format, n), func(b *testing.B) {
+				b.ReportAllocs()
+
+				var before, after runtime.MemStats
+				runtime.ReadMemStats(&before)
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					aw, err := NewAssetWriter(io.Discard, format, nil)
+					if err != nil {
+						b.Fatal(err)
+					}
+					for _, asset := range assets {
+						if err := aw.Write(asset); err != nil {
+							b.Fatal(err)
+						}
+					}
+					if err := aw.Close(); err != nil {
+						b.Fatal(err)
+					}
+				}
+
+				b.StopTimer()
+				runtime.ReadMemStats(&after)
+				b.ReportMetric(float64(after.TotalAlloc-before.TotalAlloc)/float64(b.N*n), "B/asset")
+			})
+		}
+	}
+}